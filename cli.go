@@ -0,0 +1,454 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// command is a subcommand of the CLI
+type command struct {
+	name string
+
+	// synopsis is the usage line of the command, without the program name
+	synopsis string
+
+	// summary describes the command in one line
+	summary string
+
+	// run runs the command with its arguments and returns the exit status
+	run func(args []string) int
+}
+
+// commands returns the subcommands of the CLI, in the order of the usage and
+// the man page
+func commands() []command {
+	return []command{
+		{"report", "report -input <FILE> [OPTIONS]", "Probe every manifest URL of a file and print an aggregate report", runReport},
+		{"selftest", "selftest [-public]", "Probe bundled reference manifests and verify parsing, retries and output formatting", runSelftest},
+		{"load", "load -url <URL> -rps <N> -duration <D> [OPTIONS]", "Generate sustained probe traffic against a manifest URL and report latencies and errors", runLoad},
+		{"explain", "explain [OPTIONS] <URL>", "Print the manifest annotated with the output streams, and why elements were skipped", runExplain},
+		{"tui", "tui [OPTIONS] <URL>", "Browse the periods, variants and streams of a manifest interactively", runTUI},
+		{"consume", "consume -subject <SUBJECT> [OPTIONS]", "Probe the jobs of a NATS subject and publish their results", runConsume},
+		{"monitor", "monitor [-config <FILE>]", "Run the recurring probe jobs of the config file and alert on failures", runMonitor},
+		{"verify", "verify <URL> -expect <FILE> [OPTIONS]", "Compare the output of a manifest with a reference output file", runVerify},
+		{"completion", "completion bash|zsh|fish", "Print the shell completion script", runCompletion},
+		{"man", "man", "Print the man page", runMan},
+	}
+}
+
+// findCommand returns the subcommand named name (nil if there is none)
+func findCommand(name string) *command {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return &cmd
+		}
+	}
+	return nil
+}
+
+// describing makes parseFlags stop the commands once their flags are defined,
+// so that completions and the man page are generated from the definitions
+// the commands parse
+var describing struct {
+	enabled bool
+	flags   *flag.FlagSet
+}
+
+// parseFlags parses the arguments of a command, and reports whether the
+// command should run (false while describing the CLI)
+func parseFlags(flags *flag.FlagSet, args []string) bool {
+	if describing.enabled {
+		describing.flags = flags
+		return false
+	}
+	flags.Parse(args)
+	return true
+}
+
+// commandFlags returns the flags of the main command ("") or a subcommand,
+// sorted by name
+func commandFlags(name string) []*flag.Flag {
+	describing.enabled, describing.flags = true, nil
+	defer func() { describing.enabled, describing.flags = false, nil }()
+
+	if name == "" {
+		runProbe(nil)
+	} else if cmd := findCommand(name); cmd != nil {
+		cmd.run(nil)
+	}
+
+	var flags []*flag.Flag
+	if describing.flags != nil {
+		describing.flags.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, f)
+		})
+	}
+	return flags
+}
+
+// fileFlags are the flags taking a file path
+var fileFlags = map[string]bool{
+	"config": true,
+	"expect": true,
+	"input":  true,
+	"record": true,
+	"replay": true,
+	"sarif":  true,
+}
+
+// flagValues returns the values completed for flags, by flag name
+func flagValues() map[string][]string {
+	values := map[string][]string{
+		"rules": probe.RulePacks(),
+	}
+	for _, order := range probe.StreamOrders() {
+		values["order"] = append(values["order"], string(order))
+	}
+	for _, schema := range probe.OutputSchemas() {
+		values["schema"] = append(values["schema"], string(schema))
+	}
+	for _, mode := range probe.PeriodModes() {
+		values["periods"] = append(values["periods"], string(mode))
+	}
+	return values
+}
+
+// profileFlags are the flags taking the name of an options profile
+var profileFlags = map[string]bool{
+	"profile":      true,
+	"save-profile": true,
+}
+
+// isBoolFlag reports whether a flag takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// flagSummary returns the first line of the usage of a flag
+func flagSummary(f *flag.Flag) string {
+	_, usage := flag.UnquoteUsage(f)
+	summary, _, _ := strings.Cut(usage, "\n")
+	return summary
+}
+
+// runCompletion implements "goprobe completion": it prints the completion
+// script of a shell, generated from the flags of every command. "goprobe
+// completion profiles" prints the profile names the scripts complete.
+func runCompletion(args []string) int {
+	flags := flag.NewFlagSet("completion", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s completion bash|zsh|fish\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nPrints the completion script of a shell, e.g.:\n")
+		fmt.Fprintf(os.Stderr, "  source <(%s completion bash)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s completion zsh > \"${fpath[1]}/_goprobe\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s completion fish > ~/.config/fish/completions/goprobe.fish\n", os.Args[0])
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	switch flags.Arg(0) {
+	case "bash":
+		writeBashCompletion(os.Stdout)
+	case "zsh":
+		writeZshCompletion(os.Stdout)
+	case "fish":
+		writeFishCompletion(os.Stdout)
+	case "profiles":
+		printProfileNames()
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unsupported shell %q (bash, zsh or fish)\n", flags.Arg(0))
+		return 1
+	}
+	return 0
+}
+
+// printProfileNames prints the names of the profiles of the default config
+// file, ignoring errors so that completion never fails
+func printProfileNames() {
+	path, err := probe.DefaultConfigPath()
+	if err != nil {
+		return
+	}
+	config, err := probe.LoadConfig(path)
+	if err != nil {
+		return
+	}
+	for _, name := range config.ProfileNames() {
+		fmt.Println(name)
+	}
+}
+
+// completionCommands returns the names of the subcommands and, last, of the
+// main command ("")
+func completionCommands() []string {
+	var names []string
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+	}
+	return append(names, "")
+}
+
+func writeBashCompletion(w io.Writer) {
+	values := flagValues()
+
+	fmt.Fprintf(w, "# bash completion for goprobe, generated by \"goprobe completion bash\"\n\n")
+	fmt.Fprintf(w, "_goprobe() {\n")
+	fmt.Fprintf(w, "\tlocal cur prev cmd flags\n")
+	fmt.Fprintf(w, "\tcur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(w, "\tprev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "\tcmd=\"\"\n")
+	fmt.Fprintf(w, "\t[[ ${COMP_CWORD} -gt 1 ]] && cmd=\"${COMP_WORDS[1]}\"\n\n")
+
+	// Value flags of every command: flags with the same name take the same values
+	valueFlags := make(map[string]bool)
+	fmt.Fprintf(w, "\tcase \"$cmd\" in\n")
+	for _, name := range completionCommands() {
+		var names []string
+		for _, f := range commandFlags(name) {
+			names = append(names, "-"+f.Name)
+			if !isBoolFlag(f) {
+				valueFlags[f.Name] = true
+			}
+		}
+		if name == "" {
+			fmt.Fprintf(w, "\t*)\n\t\tcmd=\"\"\n")
+		} else {
+			fmt.Fprintf(w, "\t%s)\n", name)
+		}
+		fmt.Fprintf(w, "\t\tflags=%q\n\t\t;;\n", strings.Join(names, " "))
+	}
+	fmt.Fprintf(w, "\tesac\n\n")
+
+	fmt.Fprintf(w, "\tcase \"$prev\" in\n")
+	for _, name := range sortedKeys(valueFlags) {
+		switch {
+		case fileFlags[name]:
+			fmt.Fprintf(w, "\t-%s)\n\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn\n\t\t;;\n", name)
+		case profileFlags[name]:
+			fmt.Fprintf(w, "\t-%s)\n\t\tCOMPREPLY=($(compgen -W \"$(goprobe completion profiles 2>/dev/null)\" -- \"$cur\"))\n\t\treturn\n\t\t;;\n", name)
+		case len(values[name]) > 0:
+			fmt.Fprintf(w, "\t-%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\t\t;;\n", name, strings.Join(values[name], " "))
+		default:
+			fmt.Fprintf(w, "\t-%s)\n\t\treturn\n\t\t;;\n", name)
+		}
+	}
+	fmt.Fprintf(w, "\tesac\n\n")
+
+	var names []string
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+	}
+	fmt.Fprintf(w, "\tif [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "\telif [[ ${COMP_CWORD} -eq 1 ]]; then\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintf(w, "\tfi\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "complete -o default -F _goprobe goprobe\n")
+}
+
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintf(w, "#compdef goprobe\n\n")
+	fmt.Fprintf(w, "# zsh completion for goprobe, generated by \"goprobe completion zsh\"\n\n")
+	fmt.Fprintf(w, "_goprobe() {\n")
+	fmt.Fprintf(w, "\tlocal -a commands\n")
+	fmt.Fprintf(w, "\tcommands=(\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "\t\t%s\n", shellQuote(cmd.name+":"+cmd.summary))
+	}
+	fmt.Fprintf(w, "\t)\n\n")
+
+	fmt.Fprintf(w, "\tif (( CURRENT > 2 )); then\n")
+	fmt.Fprintf(w, "\t\tcase $words[2] in\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "\t\t%s)\n", cmd.name)
+		fmt.Fprintf(w, "\t\t\tshift words\n\t\t\t(( CURRENT-- ))\n")
+		writeZshArguments(w, cmd.name, "\t\t\t")
+		fmt.Fprintf(w, "\t\t\treturn\n\t\t\t;;\n")
+	}
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\tfi\n\n")
+
+	fmt.Fprintf(w, "\tif (( CURRENT == 2 )) && [[ $PREFIX != -* ]]; then\n")
+	fmt.Fprintf(w, "\t\t_describe -t commands 'goprobe command' commands\n")
+	fmt.Fprintf(w, "\t\treturn\n")
+	fmt.Fprintf(w, "\tfi\n")
+	writeZshArguments(w, "", "\t")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "_goprobe \"$@\"\n")
+}
+
+// writeZshArguments writes the _arguments call completing the flags of a command
+func writeZshArguments(w io.Writer, name, indent string) {
+	values := flagValues()
+
+	fmt.Fprintf(w, "%s_arguments -S", indent)
+	for _, f := range commandFlags(name) {
+		spec := "-" + f.Name + "[" + zshEscape(flagSummary(f)) + "]"
+		if !isBoolFlag(f) {
+			valueName, _ := flag.UnquoteUsage(f)
+			switch {
+			case fileFlags[f.Name]:
+				spec += ":file:_files"
+			case profileFlags[f.Name]:
+				spec += ":profile:{compadd -- $(goprobe completion profiles 2>/dev/null)}"
+			case len(values[f.Name]) > 0:
+				spec += ":" + f.Name + ":(" + strings.Join(values[f.Name], " ") + ")"
+			default:
+				spec += ":" + valueName + ":"
+			}
+		}
+		fmt.Fprintf(w, " \\\n%s\t%s", indent, shellQuote(spec))
+	}
+	fmt.Fprintf(w, " \\\n%s\t'*:argument:_default'\n", indent)
+}
+
+func writeFishCompletion(w io.Writer) {
+	values := flagValues()
+
+	var names []string
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+	}
+	subcommands := strings.Join(names, " ")
+
+	fmt.Fprintf(w, "# fish completion for goprobe, generated by \"goprobe completion fish\"\n\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "complete -c goprobe -n 'not __fish_seen_subcommand_from %s' -f -a %s -d %s\n", subcommands, cmd.name, shellQuote(cmd.summary))
+	}
+
+	for _, name := range completionCommands() {
+		condition := "not __fish_seen_subcommand_from " + subcommands
+		if name != "" {
+			condition = "__fish_seen_subcommand_from " + name
+		}
+		fmt.Fprintf(w, "\n")
+		for _, f := range commandFlags(name) {
+			line := fmt.Sprintf("complete -c goprobe -n '%s' -o %s", condition, f.Name)
+			if !isBoolFlag(f) {
+				switch {
+				case fileFlags[f.Name]:
+					line += " -r -F"
+				case profileFlags[f.Name]:
+					line += " -x -a '(goprobe completion profiles 2>/dev/null)'"
+				case len(values[f.Name]) > 0:
+					line += " -x -a " + shellQuote(strings.Join(values[f.Name], " "))
+				default:
+					line += " -x"
+				}
+			}
+			fmt.Fprintf(w, "%s -d %s\n", line, shellQuote(flagSummary(f)))
+		}
+	}
+}
+
+// runMan implements "goprobe man": it prints the man page of goprobe in roff,
+// generated from the flags of every command
+func runMan(args []string) int {
+	flags := flag.NewFlagSet("man", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s man\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nPrints the man page of goprobe, e.g.:\n")
+		fmt.Fprintf(os.Stderr, "  %s man > /usr/local/share/man/man1/goprobe.1\n", os.Args[0])
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+	writeManPage(os.Stdout)
+	return 0
+}
+
+func writeManPage(w io.Writer) {
+	fmt.Fprintf(w, ".TH GOPROBE 1 \"\" \"goprobe %s\" \"User Commands\"\n", roffEscape(probe.BuildInfo().Version))
+	fmt.Fprintf(w, ".SH NAME\n")
+	fmt.Fprintf(w, "goprobe \\- analyze DASH and HLS streaming manifests\n")
+
+	fmt.Fprintf(w, ".SH SYNOPSIS\n")
+	fmt.Fprintf(w, ".B goprobe\n[\\fIOPTIONS\\fR] \\fIURL\\fR\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, ".br\n.B goprobe\n%s\n", roffEscape(cmd.synopsis))
+	}
+
+	fmt.Fprintf(w, ".SH DESCRIPTION\n")
+	fmt.Fprintf(w, "Analyzes streaming manifests (DASH MPD and HLS M3U8) for stream information,\n")
+	fmt.Fprintf(w, "and prints it as JSON in the format of ffprobe.\n")
+
+	fmt.Fprintf(w, ".SH OPTIONS\n")
+	writeManFlags(w, commandFlags(""))
+
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, ".SS %s\n", cmd.name)
+		fmt.Fprintf(w, ".B goprobe\n%s\n.PP\n%s.\n", roffEscape(cmd.synopsis), roffEscape(cmd.summary))
+		writeManFlags(w, commandFlags(cmd.name))
+	}
+
+	fmt.Fprintf(w, ".SH ENVIRONMENT\n")
+	fmt.Fprintf(w, ".TP\n.B %s\n", probe.ConfigEnv)
+	fmt.Fprintf(w, "Path of the config file with the option profiles.\n")
+
+	fmt.Fprintf(w, ".SH EXIT STATUS\n")
+	fmt.Fprintf(w, "0 on success, 1 on errors, 2 when \\fB\\-fail\\-on\\-warning\\fR is set and there are warnings,\n")
+	fmt.Fprintf(w, "and 3 when stream expectations (\\fB\\-min\\-video\\fR, \\fB\\-min\\-audio\\fR, \\fB\\-require\\-subtitles\\fR)\n")
+	fmt.Fprintf(w, "or compliance rules (\\fB\\-rules\\fR) fail.\n")
+}
+
+// writeManFlags writes a tagged paragraph per flag
+func writeManFlags(w io.Writer, flags []*flag.Flag) {
+	for _, f := range flags {
+		valueName, usage := flag.UnquoteUsage(f)
+		fmt.Fprintf(w, ".TP\n\\fB\\-%s\\fR", roffEscape(f.Name))
+		if valueName != "" {
+			fmt.Fprintf(w, " \\fI%s\\fR", roffEscape(valueName))
+		}
+		fmt.Fprintf(w, "\n%s", roffEscape(usage))
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			fmt.Fprintf(w, " (default: %s)", roffEscape(f.DefValue))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+}
+
+// roffEscape escapes backslashes and hyphens, and control characters at
+// the start of lines
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// shellQuote quotes s in single quotes for bash, zsh and fish
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscape escapes the characters special in an _arguments description
+func zshEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// runConsume implements "goprobe consume": it probes the jobs of a NATS
+// subject and publishes their results until interrupted. It returns the
+// process exit status.
+func runConsume(args []string) int {
+	flags := flag.NewFlagSet("consume", flag.ExitOnError)
+	var server = flags.String("server", "nats://127.0.0.1:4222", "NATS server URL (nats://[USER:PASSWORD@|TOKEN@]HOST:PORT or tls://...)")
+	var subject = flags.String("subject", "", "Subject the probe jobs are consumed from")
+	var group = flags.String("group", probe.DefaultQueueGroup, "Queue group shared by the workers (each job goes to one worker)")
+	var reply = flags.String("reply", "", "Subject receiving the results of jobs without a reply subject")
+	var concurrency = flags.Int("concurrency", probe.DefaultQueueConcurrency, "Maximum number of jobs probed at once")
+	var profile = flags.String("profile", "", "Options profile of the config file for jobs without options")
+	var configPath = flags.String("config", "", "Config file with the option profiles (default: $"+probe.ConfigEnv+" or <user config dir>/goprobe/config.json)")
+	var sinks sinkSpecs
+	flags.Var(&sinks, "sink", sinkUsage)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s consume -subject <SUBJECT> [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nConsumes probe jobs ({\"id\": ..., \"url\": ..., \"options\": {...}}) from a NATS\nsubject and publishes the results to the reply subject of each job. Workers\nstarted with the same -group share the jobs.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		flags.PrintDefaults()
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+
+	if *subject == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return 1
+	}
+
+	opts := &probe.ProbeOptions{}
+	if *profile != "" {
+		config, _, err := loadConfig(*configPath)
+		if err == nil {
+			opts, err = config.Profile(*profile)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if len(sinks) > 0 {
+		outputSinks, closeSinks, err := openSinks(sinks)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer closeSinks()
+		opts.Sinks = outputSinks
+	}
+
+	worker, err := probe.NewQueueWorker(probe.NewProber(opts), probe.QueueConfig{
+		URL:          *server,
+		Subject:      *subject,
+		Group:        *group,
+		ReplySubject: *reply,
+		Concurrency:  *concurrency,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Ctrl-C or SIGTERM stops consuming once the jobs in flight are published
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := worker.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+// Command cshared builds goprobe as a C shared library, so that Python, Node
+// and other tooling can probe manifests in-process instead of running the
+// goprobe binary per URL:
+//
+//	go build -buildmode=c-shared -o libgoprobe.so ./cshared
+//
+// The build also writes libgoprobe.h. Strings returned by the library are
+// allocated with malloc and must be released with goprobe_free.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"unsafe"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// maxProbers bounds the probers kept for reuse, one per distinct options JSON
+const maxProbers = 16
+
+var (
+	probersMu sync.Mutex
+	probers   = make(map[string]*probe.Prober)
+)
+
+// prober returns the prober of optionsJSON, creating it on first use so
+// that calls with the same options share connections, DNS and parse caches
+func prober(optionsJSON string) (*probe.Prober, error) {
+	probersMu.Lock()
+	defer probersMu.Unlock()
+
+	if p, ok := probers[optionsJSON]; ok {
+		return p, nil
+	}
+	opts, err := probe.ParseOptionsJSON([]byte(optionsJSON))
+	if err != nil {
+		return nil, err
+	}
+	if len(probers) >= maxProbers {
+		probers = make(map[string]*probe.Prober)
+	}
+	p := probe.NewProber(opts)
+	probers[optionsJSON] = p
+	return p, nil
+}
+
+// probe_manifest probes url with the ProbeOptions of optionsJSON (Go field
+// names, NULL or "" = defaults) and returns the output JSON, or
+// {"error": {...}} on failure
+//
+//export probe_manifest
+func probe_manifest(url, optionsJSON *C.char) *C.char {
+	var options string
+	if optionsJSON != nil {
+		options = C.GoString(optionsJSON)
+	}
+	p, err := prober(options)
+	if err != nil {
+		return C.CString(string(probe.ErrorJSON(err)))
+	}
+	return C.CString(string(p.ProbeJSON(context.Background(), C.GoString(url))))
+}
+
+// goprobe_version returns the build information JSON (see probe.BuildInfo)
+//
+//export goprobe_version
+func goprobe_version() *C.char {
+	data, _ := json.Marshal(probe.BuildInfo())
+	return C.CString(string(data))
+}
+
+// goprobe_free releases a string returned by the library
+//
+//export goprobe_free
+func goprobe_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+func main() {}
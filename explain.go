@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// runExplain implements "goprobe explain": it prints the manifest with the
+// streams each line produced and the elements that were skipped. It returns
+// the process exit status.
+func runExplain(args []string) int {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	var jsonOutput = flags.Bool("json", false, "Print the annotated lines as JSON")
+	var proxyURL = flags.String("proxy", "", "Proxy URL (e.g., http://proxy:8080)")
+	var userAgent = flags.String("ua", "", "Custom User-Agent string")
+	var timeout = flags.Int("timeout", 30, "Timeout in seconds")
+	var disableCompression = flags.Bool("no-compression", false, "Disable gzip/deflate compression")
+	var disableCamouflage = flags.Bool("no-camouflage", false, "Disable browser-like headers")
+	var iframeStreams = flags.Bool("iframes", false, "Report HLS I-frame playlists as \"Video (iframe-only)\" streams")
+	var audioGroups = flags.Bool("audio-groups", false, "Report one audio stream per unique HLS audio rendition")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s explain [OPTIONS] <URL>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nPrints the manifest annotated with the output stream of each\nAdaptationSet/Representation or variant/rendition, and why elements were skipped.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		flags.PrintDefaults()
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	prober := probe.NewProber(&probe.ProbeOptions{
+		ProxyURL:           *proxyURL,
+		UserAgent:          *userAgent,
+		TimeoutSeconds:     *timeout,
+		DisableCompression: *disableCompression,
+		DisableCamouflage:  *disableCamouflage,
+		IFrameStreams:      *iframeStreams,
+		AudioGroups:        *audioGroups,
+	})
+	explanation, err := prober.Explain(context.Background(), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if !*jsonOutput {
+		fmt.Print(explanation.String())
+		return 0
+	}
+
+	jsonData, err := json.MarshalIndent(explanation, "", "    ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(jsonData))
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// runLoad implements "goprobe load": it probes one manifest URL at a fixed
+// rate and prints latency percentiles and error rates. It returns the process
+// exit status.
+func runLoad(args []string) int {
+	flags := flag.NewFlagSet("load", flag.ExitOnError)
+	var manifestURL = flags.String("url", "", "Manifest URL to probe")
+	var rps = flags.Float64("rps", 10, "Probes started per second")
+	var duration = flags.Duration("duration", time.Minute, "How long to generate traffic")
+	var maxInFlight = flags.Int("max-inflight", probe.DefaultLoadMaxInFlight, "Maximum concurrent probes (further probes are dropped)")
+	var maxErrorRate = flags.Float64("max-error-rate", -1, "Exit with status 2 when the error rate exceeds this fraction (e.g. 0.01)")
+	var proxyURL = flags.String("proxy", "", "Proxy URL (e.g., http://proxy:8080)")
+	var userAgent = flags.String("ua", "", "Custom User-Agent string")
+	var timeout = flags.Int("timeout", 30, "Timeout in seconds")
+	var disableCompression = flags.Bool("no-compression", false, "Disable gzip/deflate compression")
+	var disableCamouflage = flags.Bool("no-camouflage", false, "Disable browser-like headers")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s load -url <URL> [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nGenerates sustained probe traffic against a manifest URL and reports\nlatency percentiles and error rates, e.g. to validate a CDN before an event.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		flags.PrintDefaults()
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+
+	if *manifestURL == "" {
+		flags.Usage()
+		return 1
+	}
+
+	// Ctrl-C ends the test early and still prints the result
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	prober := probe.NewProber(&probe.ProbeOptions{
+		ProxyURL:           *proxyURL,
+		UserAgent:          *userAgent,
+		TimeoutSeconds:     *timeout,
+		DisableCompression: *disableCompression,
+		DisableCamouflage:  *disableCamouflage,
+	})
+	result, err := prober.LoadTest(ctx, probe.LoadTest{
+		URL:         *manifestURL,
+		RPS:         *rps,
+		Duration:    *duration,
+		MaxInFlight: *maxInFlight,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(string(jsonData))
+
+	if *maxErrorRate >= 0 && result.ErrorRate > *maxErrorRate {
+		fmt.Fprintf(os.Stderr, "Error rate %.2f%% exceeds %.2f%%\n", result.ErrorRate*100, *maxErrorRate*100)
+		return 2
+	}
+	return 0
+}
@@ -4,19 +4,68 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/erratbi/goprobe/probe"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	runProbe(os.Args[1:])
+}
+
+// runProbe implements the main command, probing the manifest URL of args
+func runProbe(args []string) {
 	var proxyURL = flag.String("proxy", "", "Proxy URL (e.g., http://proxy:8080)")
 	var userAgent = flag.String("ua", "", "Custom User-Agent string")
 	var timeout = flag.Int("timeout", 30, "Timeout in seconds")
 	var disableCompression = flag.Bool("no-compression", false, "Disable gzip/deflate compression")
 	var disableCamouflage = flag.Bool("no-camouflage", false, "Disable browser-like headers")
+	var checkContentType = flag.Bool("check-content-type", false, "Warn when the Content-Type does not match the manifest format")
+	var iframeStreams = flag.Bool("iframes", false, "Report HLS I-frame playlists (EXT-X-I-FRAME-STREAM-INF) as \"Video (iframe-only)\" streams")
+	var audioGroups = flag.Bool("audio-groups", false, "Report one audio stream per unique HLS audio rendition (EXT-X-MEDIA TYPE=AUDIO) instead of one per variant")
+	var streamOrder = flag.String("order", "", "Order of the output streams: manifest, type, language or bandwidth (default: manifest for HLS, video, audio, then subtitles for DASH)")
+	var periods = flag.String("periods", "", "Streams of multi-period DASH manifests: all (default, once per period) or dedupe (streams repeated across periods once)")
+	var schema = flag.String("schema", "", "Stream fields of the JSON output: legacy (default), ffprobe, or dual (legacy fields plus a nested \"ffprobe\" object, for migrating consumers)")
+	var analyzeBitrates = flag.Bool("bitrates", false, "Fetch HLS media playlists and report bitrate statistics")
+	var followVariants = flag.Bool("follow", false, "Fetch HLS variant media playlists and report their segments, container and encryption")
+	var minVideo = flag.Int("min-video", 0, "Require at least N video streams")
+	var minAudio = flag.Int("min-audio", 0, "Require at least N audio streams")
+	var requireSubtitles = flag.Bool("require-subtitles", false, "Require at least one subtitle stream")
+	var warningsStderr = flag.Bool("warnings-stderr", false, "Also print warnings to stderr")
+	var failOnWarning = flag.Bool("fail-on-warning", false, "Exit with status 2 when there are warnings")
+	var excerptBytes = flag.Int("excerpt-bytes", 0, "Include up to N bytes of each stream's manifest source in the output")
+	var deepProbe = flag.Bool("deep", false, "Fetch the first media segment of each stream and check its bitstream against the declared codec")
+	var checkBaseURLs = flag.Bool("check-base-urls", false, "Report the reachability of every DASH BaseURL (implies -deep)")
+	var decodeCmd = flag.String("decode-cmd", "", "External decoder command run on the first video stream ({url}, {segment}, {init}, {manifest}, {stream_id}, {index} placeholders)")
+	var rulePacks = flag.String("rules", "", "Comma-separated compliance rule packs to check (available: "+strings.Join(probe.RulePacks(), ", ")+")")
+	var throttle = flag.String("throttle", "", "Shape download bandwidth (e.g. 3M, 500k bits/s) and estimate the rebuffer risk of one rendition")
+	var throttleBurst = flag.Int64("throttle-burst", 0, "Bytes downloaded at full speed before -throttle applies")
+	var throttleStream = flag.String("throttle-stream", "", "Stream ID of the rebuffer estimate (default: the highest-bitrate video stream)")
+	var sarifFile = flag.String("sarif", "", "Write rule, compatibility and warning findings to FILE as a SARIF 2.1.0 log")
+	var provenance = flag.Bool("provenance", false, "Add a provenance block: probe time, version, options hash, egress IP and request IDs")
+	var proxyLabel = flag.String("proxy-label", "", "Label of the prober's network path in the provenance block (implies -provenance)")
+	var requestIDHeader = flag.String("request-id-header", "", "Send a unique probe ID in this request header (implies -provenance)")
+	var deterministic = flag.Bool("deterministic", false, "Omit volatile fields and sort collections for golden-file comparisons")
+	var recordPath = flag.String("record", "", "Record every HTTP interaction of the probe to a cassette FILE, for reproducing it with -replay")
+	var replayPath = flag.String("replay", "", "Replay the HTTP interactions of a cassette FILE instead of sending requests (offline)")
+	var profile = flag.String("profile", "", "Start from the named options profile of the config file (flags set on the command line override it)")
+	var saveProfile = flag.String("save-profile", "", "Save the options set by the other flags as the named profile of the config file and exit")
+	var configPath = flag.String("config", "", "Config file with the option profiles (default: $"+probe.ConfigEnv+" or <user config dir>/goprobe/config.json)")
+	var showVersion = flag.Bool("version", false, "Print version and build information and exit")
+	var sinks sinkSpecs
+	flag.Var(&sinks, "sink", sinkUsage)
 	
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] <URL>\n", os.Args[0])
+		for _, cmd := range commands() {
+			fmt.Fprintf(os.Stderr, "       %s %s\n", os.Args[0], cmd.synopsis)
+		}
 		fmt.Fprintf(os.Stderr, "\nAnalyzes streaming manifests (DASH MPD and HLS M3U8) for stream information.\n\n")
 		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
 		flag.PrintDefaults()
@@ -24,11 +73,33 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s https://example.com/manifest.mpd\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -proxy http://proxy:8080 https://example.com/manifest.mpd\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -ua \"MyApp/1.0\" -timeout 10 https://example.com/manifest.m3u8\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -check-content-type -fail-on-warning https://example.com/manifest.mpd\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -min-video 1 -min-audio 2 -require-subtitles https://example.com/manifest.mpd\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -rules apple-hls -sarif goprobe.sarif https://example.com/manifest.m3u8\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -throttle 3M https://example.com/manifest.m3u8\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -sink kafka+http://kafka-rest:8082/probe-results https://example.com/manifest.mpd\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -record bug.cassette https://example.com/manifest.mpd\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -replay bug.cassette https://example.com/manifest.mpd\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s report -input urls.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s selftest\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s load -url https://example.com/manifest.m3u8 -rps 50 -duration 2m\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s explain https://example.com/manifest.mpd\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s tui https://example.com/live.m3u8\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  source <(%s completion bash)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s monitor -config lineup.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s verify https://example.com/manifest.mpd -expect expected.json -tolerance bitrate=10%%\n", os.Args[0])
 	}
 	
-	flag.Parse()
+	if !parseFlags(flag.CommandLine, args) {
+		return
+	}
 
-	if flag.NArg() != 1 {
+	if *showVersion {
+		printVersion()
+		return
+	}
+
+	if flag.NArg() != 1 && *saveProfile == "" {
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -42,10 +113,106 @@ func main() {
 		TimeoutSeconds:     *timeout,
 		DisableCompression: *disableCompression,
 		DisableCamouflage:  *disableCamouflage,
+		IFrameStreams:      *iframeStreams,
+		AudioGroups:        *audioGroups,
+		StreamOrder:        probe.StreamOrder(*streamOrder),
+		Periods:            probe.PeriodMode(*periods),
+		Schema:             probe.OutputSchema(*schema),
+		AnalyzeBitrates:    *analyzeBitrates,
+		FollowVariants:     *followVariants,
+		ExcerptBytes:       *excerptBytes,
+		Deterministic:      *deterministic,
+	}
+	if *deepProbe || *checkBaseURLs {
+		opts.DeepProbe = &probe.DeepProbe{CheckBaseURLs: *checkBaseURLs}
+	}
+	if *decodeCmd != "" {
+		opts.DecodeCheck = &probe.DecodeCheck{Command: strings.Fields(*decodeCmd)}
+	}
+	if *checkContentType {
+		opts.ContentTypePolicy = &probe.ContentTypePolicy{}
+	}
+	if *provenance || *proxyLabel != "" || *requestIDHeader != "" {
+		opts.Provenance = &probe.ProvenanceConfig{ProxyLabel: *proxyLabel, RequestIDHeader: *requestIDHeader}
+	}
+	if *rulePacks != "" {
+		opts.RulePacks = strings.Split(*rulePacks, ",")
+	}
+	if *throttle != "" {
+		bitsPerSecond, err := parseBitsPerSecond(*throttle)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -throttle: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Throttle = &probe.Throttle{BitsPerSecond: bitsPerSecond, BurstBytes: *throttleBurst, StreamID: *throttleStream}
+	}
+
+	// Option profiles of the config file
+	if *profile != "" || *saveProfile != "" {
+		config, path, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *profile != "" {
+			base, err := config.Profile(*profile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			opts = overrideProfile(base, opts)
+		}
+		if *saveProfile != "" {
+			if err := config.SetProfile(*saveProfile, opts); err == nil {
+				err = config.Save(path)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving profile: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "Saved profile %q to %s\n", *saveProfile, path)
+			return
+		}
+	}
+
+	// Output sinks (not part of profiles)
+	if len(sinks) > 0 {
+		outputSinks, closeSinks, err := openSinks(sinks)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer closeSinks()
+		opts.Sinks = outputSinks
+	}
+
+	// Record or replay the HTTP interactions (not part of profiles)
+	if *recordPath != "" && *replayPath != "" {
+		fmt.Fprintf(os.Stderr, "Error: -record and -replay are mutually exclusive\n")
+		os.Exit(1)
+	}
+	if *recordPath != "" {
+		opts.Cassette = probe.NewCassette()
+	}
+	if *replayPath != "" {
+		cassette, err := probe.LoadCassette(*replayPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Cassette = cassette
 	}
 
 	// Probe the manifest
 	output, err := probe.ProbeManifest(manifestURL, opts)
+
+	// Save the cassette of failed probes too, they are the ones to reproduce
+	if *recordPath != "" {
+		if err := opts.Cassette.Save(*recordPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving cassette: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -59,4 +226,90 @@ func main() {
 	}
 
 	fmt.Println(string(jsonData))
-}
\ No newline at end of file
+
+	if *sarifFile != "" {
+		sarif, err := probe.ExportSARIF(output)
+		if err == nil {
+			err = os.WriteFile(*sarifFile, sarif, 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing SARIF: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *warningsStderr {
+		for _, warning := range output.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+	}
+
+	if *failOnWarning && len(output.Warnings) > 0 {
+		os.Exit(2)
+	}
+
+	// Check stream count expectations
+	expectations := &probe.Expectations{}
+	if *minVideo > 0 {
+		expectations.Streams = append(expectations.Streams, probe.StreamExpectation{Type: "Video", Min: *minVideo})
+	}
+	if *minAudio > 0 {
+		expectations.Streams = append(expectations.Streams, probe.StreamExpectation{Type: "Audio", Min: *minAudio})
+	}
+	if *requireSubtitles {
+		expectations.Streams = append(expectations.Streams, probe.StreamExpectation{Type: "Subtitle"})
+	}
+
+	failed := probe.FailedFindings(expectations.Evaluate(output))
+	for _, finding := range failed {
+		fmt.Fprintf(os.Stderr, "Expectation failed: %s (%s)\n", finding.Expectation, finding.Message)
+	}
+	failedRules := probe.FailedRules(output.Rules)
+	for _, finding := range failedRules {
+		fmt.Fprintf(os.Stderr, "Rule failed: %s (%s)\n", finding.RuleID, finding.Message)
+	}
+	if len(failed) > 0 || len(failedRules) > 0 {
+		os.Exit(3)
+	}
+}
+
+// parseBitsPerSecond parses a bandwidth in bits per second with an optional
+// k, M or G suffix ("3M", "500k", "2.5Mbps")
+func parseBitsPerSecond(value string) (int64, error) {
+	number := strings.TrimSuffix(strings.TrimSuffix(value, "bps"), "bit/s")
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(number, "k"), strings.HasSuffix(number, "K"):
+		multiplier = 1e3
+	case strings.HasSuffix(number, "M"):
+		multiplier = 1e6
+	case strings.HasSuffix(number, "G"):
+		multiplier = 1e9
+	}
+	if multiplier != 1 {
+		number = number[:len(number)-1]
+	}
+
+	parsed, err := strconv.ParseFloat(number, 64)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("%q is not a positive bandwidth", value)
+	}
+	return int64(parsed * multiplier), nil
+}
+
+// printVersion prints the build information of this binary
+func printVersion() {
+	version := probe.BuildInfo()
+	fmt.Printf("goprobe %s\n", version.Version)
+	if version.Commit != "" {
+		commit := version.Commit
+		if version.Modified {
+			commit += " (modified)"
+		}
+		fmt.Printf("commit: %s\n", commit)
+	}
+	if version.BuildTime != "" {
+		fmt.Printf("built: %s\n", version.BuildTime)
+	}
+	fmt.Printf("go: %s\n", version.GoVersion)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// runMonitor implements "goprobe monitor": it runs the recurring probe jobs
+// of the config file until interrupted, printing one JSON line per run. It
+// returns the process exit status.
+func runMonitor(args []string) int {
+	flags := flag.NewFlagSet("monitor", flag.ExitOnError)
+	var configPath = flags.String("config", "", "Config file with the jobs (default: $"+probe.ConfigEnv+" or <user config dir>/goprobe/config.json)")
+	var quiet = flags.Bool("quiet", false, "Only print runs that did not succeed")
+	var listen = flags.String("listen", "", "Serve GET /streams (job state as JSON) and GET /metrics (Prometheus) on this address, e.g. :9090")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s monitor [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nRuns the recurring probes of the \"jobs\" of the config file: each job probes\nits URL at its interval, checks its expectations, writes its sinks and alerts\nwhen it starts failing and when it recovers.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		flags.PrintDefaults()
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 1
+	}
+
+	config, path, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Jobs report concurrently
+	var mu sync.Mutex
+	encoder := json.NewEncoder(os.Stdout)
+	monitor, err := probe.NewMonitor(config, &probe.MonitorOptions{
+		OnResult: func(result probe.JobResult) {
+			if *quiet && result.Status == probe.JobOK {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			encoder.Encode(result)
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+		return 1
+	}
+	defer monitor.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *listen != "" {
+		server := &http.Server{Addr: *listen, Handler: monitor.Handler()}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				stop()
+			}
+		}()
+		defer server.Close()
+		fmt.Fprintf(os.Stderr, "Serving /streams and /metrics on %s\n", *listen)
+	}
+
+	fmt.Fprintf(os.Stderr, "Monitoring %d jobs of %s (Ctrl-C to stop)\n", len(config.Jobs), path)
+	start := time.Now()
+	monitor.Run(ctx)
+	fmt.Fprintf(os.Stderr, "Stopped after %s\n", time.Since(start).Round(time.Second))
+	return 0
+}
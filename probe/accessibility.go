@@ -0,0 +1,113 @@
+package probe
+
+import "strings"
+
+// Caption channel schemes of DASH Accessibility descriptors (SCTE 214-1)
+const (
+	cea608Scheme = "urn:scte:dash:cc:cea-608:2015"
+	cea708Scheme = "urn:scte:dash:cc:cea-708:2015"
+)
+
+// Accessibility is an Accessibility descriptor of a DASH adaptation set
+type Accessibility struct {
+	SchemeIdUri string `json:"scheme_id_uri"`
+	Value       string `json:"value,omitempty"`
+
+	// Captions are the caption channels of a CEA-608 or CEA-708 descriptor
+	Captions []CaptionChannel `json:"captions,omitempty"`
+}
+
+// CaptionChannel is a closed caption channel embedded in a video stream
+type CaptionChannel struct {
+	// Channel is "CC1"-"CC4" for CEA-608 and "SERVICE1"-"SERVICE63" for
+	// CEA-708, like HLS INSTREAM-ID ("" when the descriptor gives none)
+	Channel string `json:"channel,omitempty"`
+
+	Language string `json:"language,omitempty"`
+}
+
+// Label is a DASH Label element: a human-readable description of an
+// adaptation set, optionally per language
+type Label struct {
+	Text string `xml:",chardata" json:"text"`
+	Lang string `xml:"lang,attr" json:"lang,omitempty"`
+}
+
+// mpdRoles returns the values of the DASH Role descriptors of an adaptation
+// set (main, alternate, commentary, dub, caption, ...)
+func mpdRoles(adaptationSet AdaptationSet) []string {
+	var roles []string
+	for _, role := range adaptationSet.Roles {
+		if role.SchemeIdUri == dashRoleScheme && role.Value != "" {
+			roles = append(roles, role.Value)
+		}
+	}
+	return roles
+}
+
+// mpdAccessibility returns the Accessibility descriptors of an adaptation
+// set, with the caption channels of CEA-608 and CEA-708 descriptors
+func mpdAccessibility(adaptationSet AdaptationSet) []Accessibility {
+	var descriptors []Accessibility
+	for _, descriptor := range adaptationSet.Accessibility {
+		accessibility := Accessibility{SchemeIdUri: descriptor.SchemeIdUri, Value: descriptor.Value}
+		switch descriptor.SchemeIdUri {
+		case cea608Scheme:
+			accessibility.Captions = parseCaptionChannels(descriptor.Value, "CC")
+		case cea708Scheme:
+			accessibility.Captions = parseCaptionChannels(descriptor.Value, "SERVICE")
+		}
+		descriptors = append(descriptors, accessibility)
+	}
+	return descriptors
+}
+
+// parseCaptionChannels parses the value of a caption channel descriptor:
+// "CC1=eng;CC3=spa" or "1=eng;3=spa" for CEA-608, "1=lang:eng;2=lang:deu,war:1"
+// for CEA-708, or a bare language. Channel numbers get prefix.
+func parseCaptionChannels(value, prefix string) []CaptionChannel {
+	var channels []CaptionChannel
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var channel CaptionChannel
+		language := entry
+		if id, rest, ok := strings.Cut(entry, "="); ok {
+			id = strings.TrimSpace(id)
+			if strings.Trim(id, "0123456789") == "" {
+				id = prefix + id
+			}
+			channel.Channel = strings.ToUpper(id)
+			language = rest
+		}
+
+		// CEA-708 services list comma-separated key:value parameters
+		if strings.Contains(language, ":") {
+			params := language
+			language = ""
+			for _, param := range strings.Split(params, ",") {
+				if key, val, ok := strings.Cut(strings.TrimSpace(param), ":"); ok && key == "lang" {
+					language = val
+				}
+			}
+		}
+		channel.Language = strings.TrimSpace(language)
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// mpdLabels returns the non-empty Label elements of an adaptation set
+func mpdLabels(adaptationSet AdaptationSet) []Label {
+	var labels []Label
+	for _, label := range adaptationSet.Labels {
+		label.Text = strings.TrimSpace(label.Text)
+		if label.Text != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
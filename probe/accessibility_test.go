@@ -0,0 +1,97 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCaptionChannels(t *testing.T) {
+	tests := []struct {
+		value    string
+		prefix   string
+		expected []CaptionChannel
+	}{
+		{value: "CC1=eng;CC3=spa", prefix: "CC", expected: []CaptionChannel{{Channel: "CC1", Language: "eng"}, {Channel: "CC3", Language: "spa"}}},
+		{value: "1=eng;3=swe", prefix: "CC", expected: []CaptionChannel{{Channel: "CC1", Language: "eng"}, {Channel: "CC3", Language: "swe"}}},
+		{value: "eng", prefix: "CC", expected: []CaptionChannel{{Language: "eng"}}},
+		{value: "1=lang:eng;2=lang:deu,war:1,er:1", prefix: "SERVICE", expected: []CaptionChannel{{Channel: "SERVICE1", Language: "eng"}, {Channel: "SERVICE2", Language: "deu"}}},
+		{value: "", prefix: "CC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseCaptionChannels(tt.value, tt.prefix); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMPDRolesAccessibilityLabels(t *testing.T) {
+	mpd := `<MPD type="static"><Period>
+<AdaptationSet contentType="video" mimeType="video/mp4" codecs="avc1.640028">
+<Role schemeIdUri="urn:mpeg:dash:role:2011" value="main"/>
+<Accessibility schemeIdUri="urn:scte:dash:cc:cea-608:2015" value="CC1=eng;CC3=spa"/>
+<Label>Main camera</Label>
+<Representation id="v" bandwidth="5000000" width="1920" height="1080"/></AdaptationSet>
+<AdaptationSet contentType="audio" mimeType="audio/mp4" codecs="mp4a.40.2" lang="en">
+<Role schemeIdUri="urn:mpeg:dash:role:2011" value="alternate"/>
+<Role schemeIdUri="urn:mpeg:dash:role:2011" value="commentary"/>
+<Role schemeIdUri="urn:example:role" value="director"/>
+<Accessibility schemeIdUri="urn:tva:metadata:cs:AudioPurposeCS:2007" value="1"/>
+<Label lang="en"> Director's commentary </Label><Label lang="fr">Commentaire du réalisateur</Label><Label/>
+<Representation id="a" bandwidth="128000"/></AdaptationSet>
+<AdaptationSet contentType="text" mimeType="application/mp4" codecs="stpp" lang="en">
+<Representation id="s" bandwidth="1000"/></AdaptationSet>
+</Period></MPD>`
+
+	output, err := parseMPDManifest(mpd, "https://cdn.example.com/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(output.Streams) != 3 {
+		t.Fatalf("Expected 3 streams, got %d", len(output.Streams))
+	}
+
+	tests := []struct {
+		name          string
+		roles         []string
+		accessibility []Accessibility
+		labels        []Label
+	}{
+		{
+			name:  "video",
+			roles: []string{"main"},
+			accessibility: []Accessibility{{
+				SchemeIdUri: cea608Scheme,
+				Value:       "CC1=eng;CC3=spa",
+				Captions:    []CaptionChannel{{Channel: "CC1", Language: "eng"}, {Channel: "CC3", Language: "spa"}},
+			}},
+			labels: []Label{{Text: "Main camera"}},
+		},
+		{
+			name:          "audio",
+			roles:         []string{"alternate", "commentary"},
+			accessibility: []Accessibility{{SchemeIdUri: dashAudioPurposeScheme, Value: "1"}},
+			labels:        []Label{{Text: "Director's commentary", Lang: "en"}, {Text: "Commentaire du réalisateur", Lang: "fr"}},
+		},
+		{
+			name: "subtitle",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := output.Streams[i]
+			if !reflect.DeepEqual(stream.Roles, tt.roles) {
+				t.Errorf("Expected roles %q, got %q", tt.roles, stream.Roles)
+			}
+			if !reflect.DeepEqual(stream.Accessibility, tt.accessibility) {
+				t.Errorf("Expected accessibility %+v, got %+v", tt.accessibility, stream.Accessibility)
+			}
+			if !reflect.DeepEqual(stream.Labels, tt.labels) {
+				t.Errorf("Expected labels %+v, got %+v", tt.labels, stream.Labels)
+			}
+		})
+	}
+}
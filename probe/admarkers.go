@@ -0,0 +1,196 @@
+package probe
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scte35TableID is the table_id of an SCTE-35 splice_info_section
+const scte35TableID = 0xFC
+
+// scte35Commands names the SCTE-35 splice_command_type values
+var scte35Commands = map[byte]string{
+	0x00: "splice_null",
+	0x04: "splice_schedule",
+	0x05: "splice_insert",
+	0x06: "time_signal",
+	0x07: "bandwidth_reservation",
+	0xFF: "private_command",
+}
+
+// AdBreak is an ad break signalled by EXT-X-DATERANGE tags with SCTE35-OUT,
+// SCTE35-IN or SCTE35-CMD attributes. Tags with the same ID are merged, so a
+// cue-out and its cue-in form one break.
+type AdBreak struct {
+	ID        string `json:"id"`
+	Class     string `json:"class,omitempty"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date,omitempty"`
+
+	// Duration is the DURATION, or the time between START-DATE and END-DATE,
+	// in seconds (0 = unknown, e.g. a break still open)
+	Duration float64 `json:"duration,omitempty"`
+
+	// PlannedDuration is the PLANNED-DURATION in seconds, the expected
+	// duration of a break whose end is not known yet
+	PlannedDuration float64 `json:"planned_duration,omitempty"`
+
+	// Offset is the START-DATE as a media time, in seconds from the start
+	// of the playlist, mapped through EXT-X-PROGRAM-DATE-TIME (nil without)
+	Offset *float64 `json:"offset,omitempty"`
+
+	// CueOut, CueIn and Command are the SCTE35-OUT, SCTE35-IN and SCTE35-CMD
+	// splice_info_sections
+	CueOut  *SCTE35Cue `json:"cue_out,omitempty"`
+	CueIn   *SCTE35Cue `json:"cue_in,omitempty"`
+	Command *SCTE35Cue `json:"command,omitempty"`
+
+	// Line is the 1-based line number of the first EXT-X-DATERANGE tag of the break
+	Line int `json:"line"`
+}
+
+// SCTE35Cue is an SCTE-35 splice_info_section carried by an EXT-X-DATERANGE
+type SCTE35Cue struct {
+	// Data is the section in hexadecimal, as in the tag
+	Data string `json:"data"`
+
+	// SpliceCommand is the name of the splice command, e.g. "splice_insert"
+	// or "time_signal" ("" if the section cannot be decoded)
+	SpliceCommand string `json:"splice_command,omitempty"`
+}
+
+// parseHLSAdBreaks extracts the ad breaks of an HLS playlist, with warnings
+// for undecodable SCTE-35 sections and for breaks left open in an ended playlist
+func parseHLSAdBreaks(content string, pdt *ProgramDateTime, ended bool) ([]AdBreak, []string) {
+	var parser adBreakParser
+	for lines := scanLines(content); lines.scan(); {
+		line := strings.TrimSpace(lines.line)
+		if strings.HasPrefix(line, "#EXT-X-DATERANGE:") {
+			parser.parseDateRange(parseHLSAttributes(line), lines.index)
+		}
+	}
+	return parser.finish(pdt, ended)
+}
+
+// adBreakParser collects the ad breaks of the EXT-X-DATERANGE tags of a playlist
+type adBreakParser struct {
+	breaks   []AdBreak
+	warnings []string
+	byID     map[string]int
+}
+
+// parseDateRange parses the attributes of the EXT-X-DATERANGE on the 0-based line index
+func (p *adBreakParser) parseDateRange(attrs map[string]string, index int) {
+	if attrs["SCTE35-OUT"] == "" && attrs["SCTE35-IN"] == "" && attrs["SCTE35-CMD"] == "" {
+		return
+	}
+	if p.byID == nil {
+		p.byID = make(map[string]int)
+	}
+
+	i, ok := p.byID[attrs["ID"]]
+	if !ok {
+		p.breaks = append(p.breaks, AdBreak{ID: attrs["ID"], Line: index + 1})
+		i = len(p.breaks) - 1
+		p.byID[attrs["ID"]] = i
+	}
+	adBreak := &p.breaks[i]
+	setIfEmpty(&adBreak.Class, attrs["CLASS"])
+	setIfEmpty(&adBreak.StartDate, attrs["START-DATE"])
+	setIfEmpty(&adBreak.EndDate, attrs["END-DATE"])
+	if duration, err := strconv.ParseFloat(attrs["DURATION"], 64); err == nil {
+		adBreak.Duration = duration
+	}
+	if planned, err := strconv.ParseFloat(attrs["PLANNED-DURATION"], 64); err == nil {
+		adBreak.PlannedDuration = planned
+	}
+
+	for _, cue := range []struct {
+		attr   string
+		target **SCTE35Cue
+	}{
+		{"SCTE35-OUT", &adBreak.CueOut},
+		{"SCTE35-IN", &adBreak.CueIn},
+		{"SCTE35-CMD", &adBreak.Command},
+	} {
+		data := attrs[cue.attr]
+		if data == "" {
+			continue
+		}
+		command, err := scte35SpliceCommand(data)
+		if err != nil {
+			p.warnings = append(p.warnings, fmt.Sprintf("EXT-X-DATERANGE %q on line %d: %s %v", adBreak.ID, index+1, cue.attr, err))
+		}
+		*cue.target = &SCTE35Cue{Data: data, SpliceCommand: command}
+	}
+}
+
+// finish maps the ad breaks to media time through pdt and returns them with
+// their warnings
+func (p *adBreakParser) finish(pdt *ProgramDateTime, ended bool) ([]AdBreak, []string) {
+	breaks, warnings := p.breaks, p.warnings
+	for i := range breaks {
+		adBreak := &breaks[i]
+		start, startOK := parsePDT(adBreak.StartDate)
+		if end, ok := parsePDT(adBreak.EndDate); ok && startOK && adBreak.Duration == 0 {
+			adBreak.Duration = roundMillis(end.Sub(start).Seconds())
+		}
+		if startOK {
+			adBreak.Offset = mediaOffset(pdt, start)
+		}
+		if ended && adBreak.CueOut != nil && adBreak.CueIn == nil && adBreak.Duration == 0 {
+			warnings = append(warnings, fmt.Sprintf("ad break %q has no SCTE35-IN, END-DATE or DURATION in an ended playlist", adBreak.ID))
+		}
+	}
+	return breaks, warnings
+}
+
+// scte35SpliceCommand decodes the splice command type of a hexadecimal
+// splice_info_section ("0x" prefixed, as in EXT-X-DATERANGE)
+func scte35SpliceCommand(data string) (string, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(data, "0x"), "0X")
+	section, err := hex.DecodeString(digits)
+	if err != nil {
+		return "", fmt.Errorf("is not hexadecimal")
+	}
+	// table_id, section_length, protocol_version, encryption and
+	// pts_adjustment, cw_index, tier and splice_command_length precede the
+	// splice_command_type in byte 13
+	if len(section) < 14 || section[0] != scte35TableID {
+		return "", fmt.Errorf("is not an SCTE-35 splice_info_section")
+	}
+	if command, ok := scte35Commands[section[13]]; ok {
+		return command, nil
+	}
+	return fmt.Sprintf("0x%02x", section[13]), nil
+}
+
+// mediaOffset maps a wall-clock time to seconds from the start of the
+// playlist, through the last program date time anchor at or before it (nil
+// if there is none)
+func mediaOffset(pdt *ProgramDateTime, t time.Time) *float64 {
+	if pdt == nil {
+		return nil
+	}
+	var anchor *PDTAnchor
+	for i := range pdt.Anchors {
+		if !pdt.Anchors[i].Time.After(t) {
+			anchor = &pdt.Anchors[i]
+		}
+	}
+	if anchor == nil {
+		return nil
+	}
+	offset := roundMillis(anchor.Offset + t.Sub(anchor.Time).Seconds())
+	return &offset
+}
+
+// setIfEmpty sets *field to value unless it is already set
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
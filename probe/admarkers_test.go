@@ -0,0 +1,111 @@
+package probe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSCTE35SpliceCommand(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+		valid    bool
+	}{
+		{"0xFC302000000000000000FFF00505FE000000", "splice_insert", true},
+		{"0xfc302000000000000000fff00506fe000000", "time_signal", true},
+		{"FC302000000000000000FFF00500", "splice_null", true},
+		{"0xFC302000000000000000FFF00542", "0x42", true},
+		{"0xFC3020", "", false},
+		{"0x0030200000000000000000F00505FE000000", "", false},
+		{"0xZZ", "", false},
+	}
+
+	for _, tt := range tests {
+		command, err := scte35SpliceCommand(tt.data)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid %v, got error %v", tt.data, tt.valid, err)
+		}
+		if command != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, command)
+		}
+	}
+}
+
+func TestParseHLSAdBreaks(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-PROGRAM-DATE-TIME:2026-10-18T12:00:00Z
+#EXTINF:6.0,
+seg0.ts
+#EXT-X-DATERANGE:ID="break-1",START-DATE="2026-10-18T12:00:06Z",PLANNED-DURATION=30,SCTE35-OUT=0xFC302000000000000000FFF00505FE000000
+#EXTINF:6.0,
+seg1.ts
+#EXT-X-DATERANGE:ID="break-1",START-DATE="2026-10-18T12:00:06Z",END-DATE="2026-10-18T12:00:36.5Z",SCTE35-IN=0xFC302000000000000000FFF00505FE000000
+#EXTINF:6.0,
+seg2.ts
+#EXT-X-DATERANGE:ID="break-2",START-DATE="2026-10-18T12:00:18Z",DURATION=15,SCTE35-CMD=0xFC302000000000000000FFF00506FE000000
+#EXT-X-DATERANGE:ID="break-3",START-DATE="2026-10-18T12:00:20Z",SCTE35-OUT=0x1234
+#EXT-X-DATERANGE:ID="chapter",START-DATE="2026-10-18T12:00:00Z",CLASS="com.example.chapter"
+#EXTINF:6.0,
+seg3.ts
+#EXT-X-ENDLIST
+`
+
+	output, err := parseHLSManifest(playlist, "https://example.com/live.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		id        string
+		duration  float64
+		planned   float64
+		offset    float64
+		line      int
+		commands  []string
+		cueOutSet bool
+		cueInSet  bool
+	}{
+		{"break-1", 30.5, 30, 6, 6, []string{"splice_insert", "splice_insert"}, true, true},
+		{"break-2", 15, 0, 18, 12, []string{"time_signal"}, false, false},
+		{"break-3", 0, 0, 20, 13, []string{""}, true, false},
+	}
+	if len(output.AdBreaks) != len(tests) {
+		t.Fatalf("Expected %d ad breaks, got %+v", len(tests), output.AdBreaks)
+	}
+	for i, tt := range tests {
+		adBreak := output.AdBreaks[i]
+		if adBreak.ID != tt.id || adBreak.Duration != tt.duration || adBreak.PlannedDuration != tt.planned || adBreak.Line != tt.line {
+			t.Errorf("Expected %s of %gs (planned %gs) on line %d, got %+v", tt.id, tt.duration, tt.planned, tt.line, adBreak)
+		}
+		if adBreak.Offset == nil || *adBreak.Offset != tt.offset {
+			t.Errorf("%s: expected offset %g, got %v", tt.id, tt.offset, adBreak.Offset)
+		}
+		if (adBreak.CueOut != nil) != tt.cueOutSet || (adBreak.CueIn != nil) != tt.cueInSet {
+			t.Errorf("%s: expected cue-out %v and cue-in %v, got %+v and %+v", tt.id, tt.cueOutSet, tt.cueInSet, adBreak.CueOut, adBreak.CueIn)
+		}
+		var commands []string
+		for _, cue := range []*SCTE35Cue{adBreak.CueOut, adBreak.CueIn, adBreak.Command} {
+			if cue != nil {
+				commands = append(commands, cue.SpliceCommand)
+			}
+		}
+		if !reflect.DeepEqual(commands, tt.commands) {
+			t.Errorf("%s: expected %q, got %q", tt.id, tt.commands, commands)
+		}
+	}
+
+	var warnings []string
+	for _, warning := range output.Warnings {
+		if strings.Contains(warning, "break-3") {
+			warnings = append(warnings, warning)
+		}
+	}
+	if len(warnings) != 2 {
+		t.Errorf("Expected an invalid section and an open break warning for break-3, got %q", output.Warnings)
+	}
+	if !containsString(output.Features, "scte35") {
+		t.Errorf("Expected the scte35 feature, got %v", output.Features)
+	}
+}
@@ -0,0 +1,283 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// alertTimeout bounds the delivery of a single alert to a sink
+const alertTimeout = 10 * time.Second
+
+// AlertSeverity ranks alerts for routing
+type AlertSeverity string
+
+const (
+	AlertCritical AlertSeverity = "critical"
+	AlertWarning  AlertSeverity = "warning"
+	AlertInfo     AlertSeverity = "info"
+)
+
+// DefaultAlertEvents are the watch events that raise alerts unless
+// WatchOptions.AlertEvents is set
+var DefaultAlertEvents = []EventType{EventErrorOccurred, EventRecovered, EventManifestStale, EventStreamEnded}
+
+// Alert is a notification raised by a watch event
+type Alert struct {
+	// URL is the watched manifest URL, with sensitive parameters redacted
+	URL      string        `json:"url"`
+	Event    EventType     `json:"event"`
+	Severity AlertSeverity `json:"severity"`
+	Summary  string        `json:"summary"`
+	Time     time.Time     `json:"time"`
+
+	// Resolved reports that the alert clears an earlier one (EventRecovered)
+	Resolved bool `json:"resolved,omitempty"`
+}
+
+// AlertSink delivers alerts to an external system. Implementations must be
+// safe for concurrent use since one sink may serve several watchers.
+type AlertSink interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// alert delivers alerts for events to the watch's sinks. A failing manifest
+// raises one alert until it recovers, rather than one per poll.
+func (w *Watcher) alert(ctx context.Context, events []Event) {
+	if len(w.opts.Alerts) == 0 {
+		return
+	}
+
+	selected := w.opts.AlertEvents
+	if selected == nil {
+		selected = DefaultAlertEvents
+	}
+
+	for _, event := range events {
+		if !containsEventType(selected, event.Type) {
+			continue
+		}
+		switch event.Type {
+		case EventErrorOccurred:
+			if w.alerted {
+				continue
+			}
+			w.alerted = true
+		case EventRecovered:
+			if !w.alerted {
+				continue
+			}
+			w.alerted = false
+		}
+
+		alert := w.newAlert(event)
+		for _, sink := range w.opts.Alerts {
+			sendCtx, cancel := context.WithTimeout(ctx, alertTimeout)
+			err := sink.Send(sendCtx, alert)
+			cancel()
+			if err != nil {
+				logWarn(ctx, "Alert delivery failed", map[string]interface{}{
+					"url":   alert.URL,
+					"event": string(alert.Event),
+					"error": w.prober.redactor.redactString(err.Error()),
+				})
+			}
+		}
+	}
+}
+
+// newAlert describes a watch event as an alert
+func (w *Watcher) newAlert(event Event) Alert {
+	alert := Alert{
+		URL:      w.prober.redactor.redactString(event.URL),
+		Event:    event.Type,
+		Severity: AlertInfo,
+		Time:     event.Time,
+	}
+
+	switch event.Type {
+	case EventErrorOccurred:
+		alert.Severity = AlertCritical
+		alert.Summary = "manifest poll failed"
+		if event.Err != nil {
+			alert.Summary += ": " + event.Err.Error()
+		}
+	case EventRecovered:
+		alert.Summary = "manifest recovered"
+		alert.Resolved = true
+	case EventManifestStale:
+		alert.Severity = AlertWarning
+		alert.Summary = "manifest is stale"
+		if event.Cadence != nil {
+			alert.Summary = fmt.Sprintf("manifest has not changed for %s (expected every %s)",
+				event.Cadence.SinceUpdate.Round(time.Second), event.Cadence.Expected)
+		}
+	case EventStreamEnded:
+		alert.Summary = "live stream ended"
+	case EventStreamAdded, EventStreamRemoved:
+		alert.Severity = AlertWarning
+		alert.Summary = string(event.Type)
+		if event.Stream != nil {
+			alert.Summary = fmt.Sprintf("%s: %s %s %s", event.Type, event.Stream.Type, event.Stream.Codec, event.Stream.Resolution)
+		}
+	default:
+		alert.Summary = string(event.Type)
+	}
+
+	return alert
+}
+
+// containsEventType reports whether types contains eventType
+func containsEventType(types []EventType, eventType EventType) bool {
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// postJSON posts a JSON payload and checks for a 2xx response
+func postJSON(ctx context.Context, client *http.Client, endpoint string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	return doAlertRequest(client, request)
+}
+
+// doAlertRequest sends an alert request and checks for a 2xx response
+func doAlertRequest(client *http.Client, request *http.Request) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("alert endpoint returned HTTP %d: %s", response.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// SlackSink posts alerts to a Slack incoming webhook
+type SlackSink struct {
+	WebhookURL string
+
+	// HTTPClient sends the requests (nil = http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// NewSlackSink creates a sink for a Slack incoming webhook URL
+func NewSlackSink(webhookURL string) *SlackSink {
+	return &SlackSink{WebhookURL: webhookURL}
+}
+
+// slackEmoji marks alerts by severity
+var slackEmoji = map[AlertSeverity]string{
+	AlertCritical: ":red_circle:",
+	AlertWarning:  ":warning:",
+	AlertInfo:     ":information_source:",
+}
+
+// Send posts the alert as a Slack message
+func (s *SlackSink) Send(ctx context.Context, alert Alert) error {
+	emoji := slackEmoji[alert.Severity]
+	if alert.Resolved {
+		emoji = ":white_check_mark:"
+	}
+	text := fmt.Sprintf("%s *%s* %s\n%s", emoji, alert.Event, alert.URL, alert.Summary)
+	return postJSON(ctx, s.HTTPClient, s.WebhookURL, map[string]string{"text": text})
+}
+
+// DefaultPagerDutyEndpoint is the PagerDuty Events API v2 endpoint
+const DefaultPagerDutyEndpoint = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutySink sends alerts to the PagerDuty Events API v2. Alerts for a
+// manifest share a dedup key, so a recovery resolves the incident its
+// failure triggered.
+type PagerDutySink struct {
+	RoutingKey string
+
+	// Endpoint is the Events API URL (default: DefaultPagerDutyEndpoint)
+	Endpoint string
+
+	// HTTPClient sends the requests (nil = http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// NewPagerDutySink creates a sink for a PagerDuty integration routing key
+func NewPagerDutySink(routingKey string) *PagerDutySink {
+	return &PagerDutySink{RoutingKey: routingKey, Endpoint: DefaultPagerDutyEndpoint}
+}
+
+// Send triggers (or resolves) a PagerDuty event
+func (s *PagerDutySink) Send(ctx context.Context, alert Alert) error {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultPagerDutyEndpoint
+	}
+
+	action := "trigger"
+	if alert.Resolved {
+		action = "resolve"
+	}
+
+	event := map[string]interface{}{
+		"routing_key":  s.RoutingKey,
+		"event_action": action,
+		"dedup_key":    "goprobe:" + alert.URL,
+	}
+	if !alert.Resolved {
+		event["payload"] = map[string]interface{}{
+			"summary":   alert.Summary,
+			"source":    alert.URL,
+			"severity":  string(alert.Severity),
+			"timestamp": alert.Time.UTC().Format(time.RFC3339),
+			"component": "goprobe",
+			"class":     string(alert.Event),
+		}
+	}
+	return postJSON(ctx, s.HTTPClient, endpoint, event)
+}
+
+// ParseAlertSink creates an alert sink from a spec:
+//
+//	slack+https://hooks.slack.com/...     Slack incoming webhook
+//	pagerduty:ROUTING_KEY                 PagerDuty Events API v2
+//	sns:arn:aws:sns:REGION:ACCOUNT:TOPIC  Amazon SNS (credentials from AWS_* env)
+func ParseAlertSink(spec string) (AlertSink, error) {
+	switch {
+	case strings.HasPrefix(spec, "slack+https://"):
+		return NewSlackSink(strings.TrimPrefix(spec, "slack+")), nil
+	case strings.HasPrefix(spec, "pagerduty:"):
+		key := strings.TrimPrefix(spec, "pagerduty:")
+		if key == "" {
+			return nil, NewValidationError("PagerDuty alert sink needs a routing key (pagerduty:ROUTING_KEY)")
+		}
+		return NewPagerDutySink(key), nil
+	case strings.HasPrefix(spec, "sns:"):
+		sink := NewSNSSink(strings.TrimPrefix(spec, "sns:"))
+		if sink.Region == "" {
+			return nil, NewValidationError(fmt.Sprintf("SNS alert sink %q needs a topic ARN (sns:arn:aws:sns:REGION:ACCOUNT:TOPIC)", spec))
+		}
+		return sink, nil
+	}
+	return nil, NewValidationError(fmt.Sprintf("unknown alert sink %q (slack+https://URL, pagerduty:KEY or sns:ARN)", spec))
+}
@@ -0,0 +1,245 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink collects the alerts it receives
+type recordingSink struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (s *recordingSink) Send(ctx context.Context, alert Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+func (s *recordingSink) received() []Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Alert(nil), s.alerts...)
+}
+
+func TestWatcherAlertsOncePerFailure(t *testing.T) {
+	server := httptest.NewServer(&manifestSequence{
+		responses: []string{watchTestManifest, "", "", "", watchTestManifest},
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sink := &recordingSink{}
+	watcher := NewProber(nil).Watch(ctx, server.URL+"/master.m3u8", &WatchOptions{
+		Interval: 10 * time.Millisecond,
+		Alerts:   []AlertSink{sink},
+	})
+
+	for event := range watcher.Events() {
+		if event.Type == EventRecovered {
+			cancel()
+		}
+	}
+
+	alerts := sink.received()
+	if len(alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %d: %+v", len(alerts), alerts)
+	}
+	if alerts[0].Event != EventErrorOccurred || alerts[0].Severity != AlertCritical || alerts[0].Resolved {
+		t.Errorf("Expected unresolved critical error alert, got %+v", alerts[0])
+	}
+	if alerts[1].Event != EventRecovered || !alerts[1].Resolved {
+		t.Errorf("Expected resolved recovery alert, got %+v", alerts[1])
+	}
+}
+
+func TestWatcherAlertEvents(t *testing.T) {
+	w := newWatcher(NewProber(nil), "https://example.com/master.m3u8", &WatchOptions{
+		Alerts:      []AlertSink{&recordingSink{}},
+		AlertEvents: []EventType{EventStreamAdded},
+	})
+	sink := w.opts.Alerts[0].(*recordingSink)
+
+	w.alert(context.Background(), []Event{
+		{Type: EventErrorOccurred, URL: w.url},
+		{Type: EventStreamAdded, URL: w.url, Stream: &StreamInfo{Type: "video", Codec: "h264", Resolution: "1920x1080"}},
+	})
+
+	alerts := sink.received()
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(alerts))
+	}
+	if expected := "stream_added: video h264 1920x1080"; alerts[0].Summary != expected {
+		t.Errorf("Expected %q, got %q", expected, alerts[0].Summary)
+	}
+}
+
+func TestSlackSink(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	err := NewSlackSink(server.URL).Send(context.Background(), Alert{
+		URL:      "https://example.com/master.m3u8",
+		Event:    EventErrorOccurred,
+		Severity: AlertCritical,
+		Summary:  "manifest poll failed",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(payload["text"], "manifest poll failed") || !strings.Contains(payload["text"], "https://example.com/master.m3u8") {
+		t.Errorf("Expected summary and URL in Slack text, got %q", payload["text"])
+	}
+}
+
+func TestSinkHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewSlackSink(server.URL).Send(context.Background(), Alert{Summary: "test"})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected HTTP 403 error, got %v", err)
+	}
+}
+
+func TestPagerDutySink(t *testing.T) {
+	var events []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sink := NewPagerDutySink("routing-key")
+	sink.Endpoint = server.URL
+
+	alert := Alert{URL: "https://example.com/master.m3u8", Event: EventErrorOccurred, Severity: AlertCritical, Summary: "manifest poll failed"}
+	if err := sink.Send(context.Background(), alert); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	alert.Event, alert.Severity, alert.Resolved = EventRecovered, AlertInfo, true
+	if err := sink.Send(context.Background(), alert); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	tests := []struct {
+		action string
+	}{{"trigger"}, {"resolve"}}
+	for i, tt := range tests {
+		if events[i]["event_action"] != tt.action {
+			t.Errorf("Expected %q, got %q", tt.action, events[i]["event_action"])
+		}
+		if events[i]["routing_key"] != "routing-key" {
+			t.Errorf("Expected %q, got %q", "routing-key", events[i]["routing_key"])
+		}
+		if events[i]["dedup_key"] != events[0]["dedup_key"] {
+			t.Errorf("Expected dedup key %q, got %q", events[0]["dedup_key"], events[i]["dedup_key"])
+		}
+	}
+}
+
+func TestSNSSink(t *testing.T) {
+	var form url.Values
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		form, _ = url.ParseQuery(string(body))
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	sink := NewSNSSink("arn:aws:sns:eu-west-1:123456789012:alerts")
+	sink.Credentials = AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}
+	sink.Endpoint = server.URL
+
+	if sink.Region != "eu-west-1" {
+		t.Errorf("Expected %q, got %q", "eu-west-1", sink.Region)
+	}
+	if err := sink.Send(context.Background(), Alert{Event: EventManifestStale, Severity: AlertWarning, Summary: "manifest is stale"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if form.Get("Action") != "Publish" || form.Get("TopicArn") != sink.TopicARN {
+		t.Errorf("Expected Publish to %s, got %v", sink.TopicARN, form)
+	}
+	if !strings.Contains(form.Get("Message"), "manifest is stale") {
+		t.Errorf("Expected alert in message, got %q", form.Get("Message"))
+	}
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(authorization, "/eu-west-1/sns/aws4_request") {
+		t.Errorf("Expected SigV4 authorization for eu-west-1 sns, got %q", authorization)
+	}
+}
+
+func TestSignV4(t *testing.T) {
+	// AWS Signature Version 4 test suite: get-vanilla
+	request, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	credentials := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signV4(request, nil, credentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := request.Header.Get("Authorization"); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestParseAlertSink(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+		err      bool
+	}{
+		{spec: "slack+https://hooks.slack.com/services/T0/B0/x", expected: "*probe.SlackSink"},
+		{spec: "pagerduty:R0UT1NGKEY", expected: "*probe.PagerDutySink"},
+		{spec: "sns:arn:aws:sns:us-east-1:123456789012:stream-alerts", expected: "*probe.SNSSink"},
+		{spec: "pagerduty:", err: true},
+		{spec: "sns:stream-alerts", err: true},
+		{spec: "https://hooks.slack.com/services/T0/B0/x", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			sink, err := ParseAlertSink(tt.spec)
+			if tt.err {
+				if err == nil {
+					t.Errorf("Expected an error, got %T", sink)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := fmt.Sprintf("%T", sink); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	sink, _ := ParseAlertSink("slack+https://hooks.slack.com/services/T0/B0/x")
+	if webhook := sink.(*SlackSink).WebhookURL; webhook != "https://hooks.slack.com/services/T0/B0/x" {
+		t.Errorf("Expected %q, got %q", "https://hooks.slack.com/services/T0/B0/x", webhook)
+	}
+}
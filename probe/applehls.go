@@ -0,0 +1,352 @@
+package probe
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RulePackAppleHLS selects rules from the Apple HLS Authoring Specification
+// for Apple Devices
+const RulePackAppleHLS = "apple-hls"
+
+// appleTargetDurations are the target durations the authoring specification
+// allows: 6 seconds, or 2 seconds for low-latency streams
+var appleTargetDurations = []int{2, 6}
+
+var appleHLSRules = []Rule{
+	{
+		ID:          "apple-hls/codecs-attribute",
+		Description: "Every EXT-X-STREAM-INF tag has a CODECS attribute",
+		Format:      "HLS",
+		Check:       checkAppleStreamInfAttribute("CODECS", false),
+	},
+	{
+		ID:          "apple-hls/resolution-attribute",
+		Description: "Every video variant has a RESOLUTION attribute",
+		Format:      "HLS",
+		Check:       checkAppleStreamInfAttribute("RESOLUTION", true),
+	},
+	{
+		ID:          "apple-hls/frame-rate-attribute",
+		Description: "Every video variant has a FRAME-RATE attribute",
+		Severity:    SeverityWarning,
+		Format:      "HLS",
+		Check:       checkAppleStreamInfAttribute("FRAME-RATE", true),
+	},
+	{
+		ID:          "apple-hls/average-bandwidth",
+		Description: "Every EXT-X-STREAM-INF tag has an AVERAGE-BANDWIDTH attribute",
+		Format:      "HLS",
+		Check:       checkAppleStreamInfAttribute("AVERAGE-BANDWIDTH", false),
+	},
+	{
+		ID:          "apple-hls/frame-rate-family",
+		Description: "All video variants use frame rates of one family (24, 25 or 30 and their multiples)",
+		Severity:    SeverityWarning,
+		Format:      "HLS",
+		Check:       checkAppleFrameRateFamily,
+	},
+	{
+		ID:          "apple-hls/independent-segments",
+		Description: "The master playlist has EXT-X-INDEPENDENT-SEGMENTS",
+		Severity:    SeverityWarning,
+		Format:      "HLS",
+		Check:       checkAppleIndependentSegments,
+	},
+	{
+		ID:          "apple-hls/iframe-playlists",
+		Description: "Video content has I-frame playlists (EXT-X-I-FRAME-STREAM-INF) for trick play",
+		Format:      "HLS",
+		Check:       checkAppleIFramePlaylists,
+	},
+	{
+		ID:          "apple-hls/audio-only-variant",
+		Description: "The master playlist has an audio-only variant",
+		Severity:    SeverityWarning,
+		Format:      "HLS",
+		Check:       checkAppleAudioOnlyVariant,
+	},
+	{
+		ID:             "apple-hls/target-duration",
+		Description:    "Media playlists have a target duration of 6 seconds (2 seconds for low latency)",
+		Severity:       SeverityWarning,
+		Format:         "HLS",
+		MediaPlaylists: true,
+		Check:          checkAppleTargetDuration,
+	},
+	{
+		ID:             "apple-hls/segment-duration",
+		Description:    "Segment durations, rounded to the nearest second, do not exceed the target duration",
+		Format:         "HLS",
+		MediaPlaylists: true,
+		Check:          checkAppleSegmentDuration,
+	},
+}
+
+// appleVideoVariant reports whether an EXT-X-STREAM-INF tag describes a
+// variant with video. Variants without CODECS count as video unless they are
+// known to be audio-only.
+func appleVideoVariant(inf HLSStreamInf) bool {
+	video, audio := hlsCodecs(inf.Attributes["CODECS"])
+	return len(video) > 0 || len(audio) == 0
+}
+
+// checkAppleStreamInfAttribute returns a check that every EXT-X-STREAM-INF
+// tag (or every video variant) has attribute
+func checkAppleStreamInfAttribute(attribute string, videoOnly bool) func(in *RuleInput) RuleOutcome {
+	return func(in *RuleInput) RuleOutcome {
+		if !in.IsHLSMaster() {
+			return RuleOutcome{NotApplicable: true}
+		}
+
+		var checked, missing int
+		var streamIDs []string
+		for _, inf := range in.HLSStreamInfs() {
+			if videoOnly && !appleVideoVariant(inf) {
+				continue
+			}
+			checked++
+			if inf.Attributes[attribute] == "" {
+				missing++
+				streamIDs = append(streamIDs, inf.StreamIDs...)
+			}
+		}
+		if missing == 0 {
+			return RuleOutcome{}
+		}
+		return RuleOutcome{
+			Message:   fmt.Sprintf("%d of %d variants have no %s attribute", missing, checked, attribute),
+			StreamIDs: streamIDs,
+		}
+	}
+}
+
+// checkAppleFrameRateFamily checks that video variants do not mix frame rate families
+func checkAppleFrameRateFamily(in *RuleInput) RuleOutcome {
+	if !in.IsHLSMaster() {
+		return RuleOutcome{NotApplicable: true}
+	}
+
+	families := make(map[string][]string)
+	for _, inf := range in.HLSStreamInfs() {
+		rate, err := strconv.ParseFloat(inf.Attributes["FRAME-RATE"], 64)
+		if err != nil || !appleVideoVariant(inf) {
+			continue
+		}
+		family := frameRateFamily(rate)
+		families[family] = append(families[family], inf.StreamIDs...)
+	}
+	if len(families) < 2 {
+		return RuleOutcome{}
+	}
+
+	names := make([]string, 0, len(families))
+	var streamIDs []string
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		streamIDs = append(streamIDs, families[name]...)
+	}
+	return RuleOutcome{
+		Message:   fmt.Sprintf("variants mix frame rate families %s", strings.Join(names, ", ")),
+		StreamIDs: streamIDs,
+	}
+}
+
+// frameRateFamily names the family of a frame rate: "24" (23.976, 24, 48),
+// "25" (12.5, 25, 50) or "30" (15, 29.97, 30, 59.94, 60). Other rates are
+// their own family.
+func frameRateFamily(rate float64) string {
+	base := rate
+	for base > 35 {
+		base /= 2
+	}
+	for base > 0 && base < 20 {
+		base *= 2
+	}
+	for _, family := range []float64{24, 25, 30} {
+		// NTSC rates are 1000/1001 of the nominal rate
+		if math.Abs(base-family) < 0.05 || math.Abs(base*1.001-family) < 0.05 {
+			return strconv.FormatFloat(family, 'f', -1, 64)
+		}
+	}
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
+
+// checkAppleIndependentSegments checks for EXT-X-INDEPENDENT-SEGMENTS in a master playlist
+func checkAppleIndependentSegments(in *RuleInput) RuleOutcome {
+	if !in.IsHLSMaster() {
+		return RuleOutcome{NotApplicable: true}
+	}
+	if !strings.Contains(in.Manifest, "#EXT-X-INDEPENDENT-SEGMENTS") {
+		return RuleOutcome{Message: "master playlist has no EXT-X-INDEPENDENT-SEGMENTS tag"}
+	}
+	return RuleOutcome{}
+}
+
+// checkAppleIFramePlaylists checks that a master playlist with video variants
+// has I-frame playlists
+func checkAppleIFramePlaylists(in *RuleInput) RuleOutcome {
+	if !in.IsHLSMaster() {
+		return RuleOutcome{NotApplicable: true}
+	}
+
+	var video []string
+	for _, inf := range in.HLSStreamInfs() {
+		if appleVideoVariant(inf) {
+			video = append(video, inf.StreamIDs...)
+		}
+	}
+	if len(video) == 0 {
+		return RuleOutcome{NotApplicable: true}
+	}
+	if !strings.Contains(in.Manifest, "#EXT-X-I-FRAME-STREAM-INF:") {
+		return RuleOutcome{Message: "master playlist has no I-frame playlists (EXT-X-I-FRAME-STREAM-INF)", StreamIDs: video}
+	}
+	return RuleOutcome{}
+}
+
+// checkAppleAudioOnlyVariant checks that a master playlist has an audio-only variant
+func checkAppleAudioOnlyVariant(in *RuleInput) RuleOutcome {
+	if !in.IsHLSMaster() {
+		return RuleOutcome{NotApplicable: true}
+	}
+	for _, inf := range in.HLSStreamInfs() {
+		if !appleVideoVariant(inf) {
+			return RuleOutcome{}
+		}
+	}
+	return RuleOutcome{Message: "master playlist has no audio-only variant"}
+}
+
+// appleMediaPlaylists returns the media playlists to check by stream ID: the
+// fetched variant playlists, or the probed manifest itself when it is a media playlist
+func appleMediaPlaylists(in *RuleInput) map[string]string {
+	if in.IsHLSMaster() {
+		return in.MediaPlaylists
+	}
+	return map[string]string{"": in.Manifest}
+}
+
+// sortedStreamIDs returns the stream IDs of playlists in order
+func sortedStreamIDs(playlists map[string]string) []string {
+	keys := make([]string, 0, len(playlists))
+	for key := range playlists {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkAppleTargetDuration checks EXT-X-TARGETDURATION of the media playlists
+func checkAppleTargetDuration(in *RuleInput) RuleOutcome {
+	playlists := appleMediaPlaylists(in)
+	if len(playlists) == 0 {
+		return RuleOutcome{NotApplicable: true}
+	}
+
+	var durations []string
+	var streamIDs []string
+	for _, streamID := range sortedStreamIDs(playlists) {
+		target, ok := hlsTargetDuration(playlists[streamID])
+		if ok && containsInt(appleTargetDurations, target) {
+			continue
+		}
+		if ok {
+			durations = append(durations, strconv.Itoa(target))
+		} else {
+			durations = append(durations, "none")
+		}
+		if streamID != "" {
+			streamIDs = append(streamIDs, streamID)
+		}
+	}
+	if len(durations) == 0 {
+		return RuleOutcome{}
+	}
+	return RuleOutcome{
+		Message:   fmt.Sprintf("target duration %s, expected 6 (or 2 for low latency)", strings.Join(durations, ", ")),
+		StreamIDs: streamIDs,
+	}
+}
+
+// checkAppleSegmentDuration checks that no segment exceeds the target duration
+func checkAppleSegmentDuration(in *RuleInput) RuleOutcome {
+	playlists := appleMediaPlaylists(in)
+	if len(playlists) == 0 {
+		return RuleOutcome{NotApplicable: true}
+	}
+
+	var longest float64
+	var streamIDs []string
+	violations := 0
+	for _, streamID := range sortedStreamIDs(playlists) {
+		playlist := playlists[streamID]
+		target, ok := hlsTargetDuration(playlist)
+		if !ok {
+			continue
+		}
+		exceeded := false
+		for _, duration := range hlsSegmentDurations(playlist) {
+			if math.Round(duration) > float64(target) {
+				exceeded = true
+				violations++
+				longest = math.Max(longest, duration)
+			}
+		}
+		if exceeded && streamID != "" {
+			streamIDs = append(streamIDs, streamID)
+		}
+	}
+	if violations == 0 {
+		return RuleOutcome{}
+	}
+	return RuleOutcome{
+		Message:   fmt.Sprintf("%d segments exceed the target duration (longest %.3fs)", violations, longest),
+		StreamIDs: streamIDs,
+	}
+}
+
+// hlsTargetDuration returns the EXT-X-TARGETDURATION of a media playlist
+func hlsTargetDuration(playlist string) (int, bool) {
+	for lines := scanLines(playlist); lines.scan(); {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(lines.line), "#EXT-X-TARGETDURATION:"); ok {
+			target, err := strconv.Atoi(strings.TrimSpace(value))
+			return target, err == nil
+		}
+	}
+	return 0, false
+}
+
+// hlsSegmentDurations returns the EXTINF durations of a media playlist
+func hlsSegmentDurations(playlist string) []float64 {
+	var durations []float64
+	for lines := scanLines(playlist); lines.scan(); {
+		value, ok := strings.CutPrefix(strings.TrimSpace(lines.line), "#EXTINF:")
+		if !ok {
+			continue
+		}
+		if comma := strings.Index(value, ","); comma >= 0 {
+			value = value[:comma]
+		}
+		if duration, err := strconv.ParseFloat(value, 64); err == nil {
+			durations = append(durations, duration)
+		}
+	}
+	return durations
+}
+
+// containsInt reports whether values contains value
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
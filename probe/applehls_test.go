@@ -0,0 +1,172 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const appleTestMaster = `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,FRAME-RATE=25.000,CODECS="avc1.64001f,mp4a.40.2"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,AVERAGE-BANDWIDTH=4500000,RESOLUTION=1920x1080,FRAME-RATE=29.970,CODECS="avc1.640028,mp4a.40.2"
+1080p.m3u8
+`
+
+const appleTestCompliantMaster = `#EXTM3U
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,AVERAGE-BANDWIDTH=1100000,RESOLUTION=1280x720,FRAME-RATE=29.970,CODECS="avc1.64001f,mp4a.40.2"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,AVERAGE-BANDWIDTH=4500000,RESOLUTION=1920x1080,FRAME-RATE=59.940,CODECS="avc1.640028,mp4a.40.2"
+1080p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=64000,AVERAGE-BANDWIDTH=64000,CODECS="mp4a.40.5"
+audio.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=200000,RESOLUTION=1280x720,CODECS="avc1.64001f",URI="720p-iframes.m3u8"
+`
+
+const appleTestMedia6 = `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXTINF:6.006,
+seg1.ts
+#EXTINF:5.995,
+seg2.ts
+#EXT-X-ENDLIST
+`
+
+const appleTestMedia10 = `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXTINF:10.0,
+seg1.ts
+#EXTINF:11.2,
+seg2.ts
+#EXT-X-ENDLIST
+`
+
+func appleTestServer(master string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte(master))
+		case "/1080p.m3u8":
+			w.Write([]byte(appleTestMedia10))
+		default:
+			w.Write([]byte(appleTestMedia6))
+		}
+	}))
+}
+
+func TestAppleHLSRules(t *testing.T) {
+	server := appleTestServer(appleTestMaster)
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{RulePacks: []string{RulePackAppleHLS}}).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]RuleFinding{
+		"apple-hls/codecs-attribute":     {Passed: true},
+		"apple-hls/resolution-attribute": {Passed: true},
+		"apple-hls/frame-rate-attribute": {Passed: true},
+		"apple-hls/average-bandwidth":    {Message: "1 of 2 variants have no AVERAGE-BANDWIDTH attribute", StreamIDs: []string{"0:0", "0:1"}},
+		"apple-hls/frame-rate-family":    {Message: "variants mix frame rate families 25, 30", StreamIDs: []string{"0:0", "0:1", "0:2", "0:3"}},
+		"apple-hls/independent-segments": {Message: "master playlist has no EXT-X-INDEPENDENT-SEGMENTS tag"},
+		"apple-hls/iframe-playlists":     {Message: "master playlist has no I-frame playlists (EXT-X-I-FRAME-STREAM-INF)", StreamIDs: []string{"0:0", "0:1", "0:2", "0:3"}},
+		"apple-hls/audio-only-variant":   {Message: "master playlist has no audio-only variant"},
+		"apple-hls/target-duration":      {Message: "target duration 10, expected 6 (or 2 for low latency)", StreamIDs: []string{"0:2"}},
+		"apple-hls/segment-duration":     {Message: "1 segments exceed the target duration (longest 11.200s)", StreamIDs: []string{"0:2"}},
+	}
+
+	if len(output.Rules) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %+v", len(expected), len(output.Rules), output.Rules)
+	}
+	for _, finding := range output.Rules {
+		want, ok := expected[finding.RuleID]
+		if !ok {
+			t.Errorf("Unexpected finding %q", finding.RuleID)
+			continue
+		}
+		if finding.Description == "" {
+			t.Errorf("%s: Expected a description", finding.RuleID)
+		}
+		if finding.Passed != want.Passed || finding.Message != want.Message || !reflect.DeepEqual(finding.StreamIDs, want.StreamIDs) {
+			t.Errorf("%s: Expected %+v, got %+v", finding.RuleID, want, finding)
+		}
+	}
+}
+
+func TestAppleHLSRulesCompliant(t *testing.T) {
+	server := appleTestServer(appleTestCompliantMaster)
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{RulePacks: []string{RulePackAppleHLS}}).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// 1080p.m3u8 serves the 10s playlist
+	for _, finding := range FailedRules(output.Rules) {
+		if finding.RuleID != "apple-hls/target-duration" && finding.RuleID != "apple-hls/segment-duration" {
+			t.Errorf("Unexpected failed rule %+v", finding)
+		}
+	}
+	if len(output.Rules) != len(appleHLSRules) {
+		t.Errorf("Expected %d findings, got %d", len(appleHLSRules), len(output.Rules))
+	}
+}
+
+func TestAppleHLSRulesMediaPlaylist(t *testing.T) {
+	server := appleTestServer(appleTestMaster)
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{RulePacks: []string{RulePackAppleHLS}}).Probe(context.Background(), server.URL+"/720p.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var ids []string
+	for _, finding := range output.Rules {
+		ids = append(ids, finding.RuleID)
+		if !finding.Passed {
+			t.Errorf("Unexpected failed rule %+v", finding)
+		}
+	}
+	expected := []string{"apple-hls/target-duration", "apple-hls/segment-duration"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %v, got %v", expected, ids)
+	}
+}
+
+func TestFrameRateFamily(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		expected string
+	}{
+		{23.976, "24"},
+		{24, "24"},
+		{48, "24"},
+		{12.5, "25"},
+		{25, "25"},
+		{50, "25"},
+		{15, "30"},
+		{29.97, "30"},
+		{59.94, "30"},
+		{60, "30"},
+		{120, "30"},
+		{20, "20"},
+	}
+
+	for _, tt := range tests {
+		if got := frameRateFamily(tt.rate); got != tt.expected {
+			t.Errorf("%v: Expected %q, got %q", tt.rate, tt.expected, got)
+		}
+	}
+}
+
+func TestUnknownRulePack(t *testing.T) {
+	_, err := NewProber(&ProbeOptions{RulePacks: []string{"nope"}}).Probe(context.Background(), "https://example.com/master.m3u8")
+	if err == nil {
+		t.Error("Expected an error for an unknown rule pack")
+	}
+}
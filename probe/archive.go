@@ -0,0 +1,161 @@
+package probe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveEntry is a fetched manifest with its metadata
+type ArchiveEntry struct {
+	URL    string      `json:"url"`
+	Time   time.Time   `json:"time"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"-"`
+}
+
+// Archiver persists fetched manifests for later forensic analysis
+type Archiver interface {
+	Archive(entry *ArchiveEntry) error
+}
+
+// ArchiveFunc adapts a function to an Archiver
+type ArchiveFunc func(entry *ArchiveEntry) error
+
+// Archive calls f(entry)
+func (f ArchiveFunc) Archive(entry *ArchiveEntry) error {
+	return f(entry)
+}
+
+// DirArchiver writes manifests to a directory, one subdirectory per manifest
+// (scheme, host and path, ignoring the query). Each subdirectory holds the
+// bodies named by fetch time, which Prober.Replay can read directly, and
+// their metadata as JSON in a "meta" subdirectory.
+type DirArchiver struct {
+	// Dir is the archive root directory
+	Dir string
+
+	// MaxFiles keeps only the most recent snapshots per manifest (0 = unlimited)
+	MaxFiles int
+
+	mu  sync.Mutex
+	seq uint64
+}
+
+// NewDirArchiver creates an archiver writing to dir, keeping at most maxFiles
+// snapshots per manifest (0 = unlimited)
+func NewDirArchiver(dir string, maxFiles int) *DirArchiver {
+	return &DirArchiver{Dir: dir, MaxFiles: maxFiles}
+}
+
+// ManifestDir returns the subdirectory holding the snapshots of manifestURL
+func (a *DirArchiver) ManifestDir(manifestURL string) string {
+	key := manifestURL
+	if parsed, err := url.Parse(manifestURL); err == nil {
+		key = parsed.Scheme + "://" + parsed.Host + parsed.Path
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(a.Dir, hex.EncodeToString(sum[:8]))
+}
+
+// Archive writes the entry's body and metadata
+func (a *DirArchiver) Archive(entry *ArchiveEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	dir := a.ManifestDir(entry.URL)
+	if err := os.MkdirAll(filepath.Join(dir, "meta"), 0o755); err != nil {
+		return err
+	}
+
+	// Sortable names keep lexical order chronological for Replay
+	a.seq++
+	ext := ".mpd"
+	if manifestFormat(entry.Body) == "HLS" {
+		ext = ".m3u8"
+	}
+	name := fmt.Sprintf("%s-%06d", entry.Time.UTC().Format("20060102T150405.000000000Z"), a.seq%1000000)
+
+	bodyPath := filepath.Join(dir, name+ext)
+	if err := os.WriteFile(bodyPath, []byte(entry.Body), 0o644); err != nil {
+		return err
+	}
+	if err := os.Chtimes(bodyPath, entry.Time, entry.Time); err != nil {
+		return err
+	}
+
+	meta, err := json.MarshalIndent(entry, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meta", name+".json"), meta, 0o644); err != nil {
+		return err
+	}
+
+	return a.prune(dir)
+}
+
+// prune removes the oldest snapshots of dir beyond MaxFiles
+func (a *DirArchiver) prune(dir string) error {
+	if a.MaxFiles <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for len(names) > a.MaxFiles {
+		name := names[0]
+		names = names[1:]
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		meta := strings.TrimSuffix(name, filepath.Ext(name)) + ".json"
+		if err := os.Remove(filepath.Join(dir, "meta", meta)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// archive passes a fetched manifest to the configured archiver. Failures are
+// logged and do not fail the probe.
+func (p *Prober) archive(ctx context.Context, fetched *fetchResult) {
+	if p.opts == nil || p.opts.Archiver == nil {
+		return
+	}
+
+	entry := &ArchiveEntry{
+		URL:    p.redactor.redactString(fetched.url),
+		Time:   p.clock.Now(),
+		Header: fetched.header,
+		Body:   fetched.body,
+	}
+	if err := p.opts.Archiver.Archive(entry); err != nil {
+		logWarn(ctx, "Manifest archiving failed", map[string]interface{}{
+			"url":   fetched.url,
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirArchiver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	archiver := NewDirArchiver(t.TempDir(), 2)
+	prober := NewProber(&ProbeOptions{DisableCamouflage: true, Archiver: archiver})
+
+	for i := 0; i < 3; i++ {
+		if _, err := prober.Probe(context.Background(), server.URL+"/live.m3u8?token=abc"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	dir := archiver.ManifestDir(server.URL + "/live.m3u8")
+	snapshots, err := filepath.Glob(filepath.Join(dir, "*.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots after pruning, got %d", len(snapshots))
+	}
+
+	metas, _ := filepath.Glob(filepath.Join(dir, "meta", "*.json"))
+	if len(metas) != 2 {
+		t.Fatalf("Expected 2 metadata files after pruning, got %d", len(metas))
+	}
+
+	data, err := os.ReadFile(metas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry ArchiveEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	if expected := server.URL + "/live.m3u8?token=REDACTED"; entry.URL != expected {
+		t.Errorf("Expected %q, got %q", expected, entry.URL)
+	}
+	if ct := entry.Header.Get("Content-Type"); ct != "application/vnd.apple.mpegurl" {
+		t.Errorf("Expected archived Content-Type header, got %q", ct)
+	}
+
+	// The archive can be replayed directly
+	watcher := prober.Replay(context.Background(), os.DirFS(dir), server.URL+"/live.m3u8", nil)
+	var updates int
+	for event := range watcher.Events() {
+		if event.Type == EventManifestUpdated {
+			updates++
+		}
+	}
+	if updates != 1 {
+		t.Errorf("Expected 1 manifest update from replay, got %d", updates)
+	}
+}
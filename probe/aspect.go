@@ -0,0 +1,61 @@
+package probe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// aspectRatios returns the ffprobe-style sample and display aspect ratios
+// ("1:1", "16:9") of a video stream from its storage size and @sar. A missing
+// @sar means square pixels. When the size is unknown, par (the adaptation
+// set's @par) is used as the display aspect ratio.
+func aspectRatios(width, height, sar, par string) (string, string) {
+	sarNum, sarDen, ok := parseRatio(sar)
+	if sar == "" {
+		sarNum, sarDen, ok = 1, 1, true
+	}
+	if !ok {
+		return "", ""
+	}
+	sampleAspect := formatRatio(sarNum, sarDen)
+
+	w, errW := strconv.ParseInt(width, 10, 64)
+	h, errH := strconv.ParseInt(height, 10, 64)
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		if parNum, parDen, ok := parseRatio(par); ok {
+			return sampleAspect, formatRatio(parNum, parDen)
+		}
+		return "", ""
+	}
+
+	return sampleAspect, formatRatio(w*sarNum, h*sarDen)
+}
+
+// parseRatio parses a "num:den" ratio with positive terms
+func parseRatio(value string) (int64, int64, bool) {
+	numText, denText, found := strings.Cut(strings.TrimSpace(value), ":")
+	if !found {
+		return 0, 0, false
+	}
+	num, errNum := strconv.ParseInt(numText, 10, 64)
+	den, errDen := strconv.ParseInt(denText, 10, 64)
+	if errNum != nil || errDen != nil || num <= 0 || den <= 0 {
+		return 0, 0, false
+	}
+	return num, den, true
+}
+
+// formatRatio formats num:den reduced to lowest terms
+func formatRatio(num, den int64) string {
+	divisor := gcd(num, den)
+	return fmt.Sprintf("%d:%d", num/divisor, den/divisor)
+}
+
+// gcd returns the greatest common divisor of two positive integers
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,58 @@
+package probe
+
+import "testing"
+
+func TestAspectRatios(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height string
+		sar, par      string
+		sampleAspect  string
+		displayAspect string
+	}{
+		{"square pixels", "1920", "1080", "", "", "1:1", "16:9"},
+		{"explicit square", "1280", "720", "1:1", "", "1:1", "16:9"},
+		{"anamorphic PAL widescreen", "720", "576", "64:45", "", "64:45", "16:9"},
+		{"anamorphic HDV", "1440", "1080", "4:3", "", "4:3", "16:9"},
+		{"unreduced sar", "720", "480", "32:27", "", "32:27", "16:9"},
+		{"par without size", "", "", "", "16:9", "1:1", "16:9"},
+		{"no size", "", "", "", "", "", ""},
+		{"invalid sar", "1920", "1080", "wide", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampleAspect, displayAspect := aspectRatios(tt.width, tt.height, tt.sar, tt.par)
+			if sampleAspect != tt.sampleAspect {
+				t.Errorf("Expected sample aspect %q, got %q", tt.sampleAspect, sampleAspect)
+			}
+			if displayAspect != tt.displayAspect {
+				t.Errorf("Expected display aspect %q, got %q", tt.displayAspect, displayAspect)
+			}
+		})
+	}
+}
+
+func TestMPDAspectRatios(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD type="static">
+  <Period id="1">
+    <AdaptationSet contentType="video" mimeType="video/mp4" sar="4:3" par="16:9">
+      <Representation id="v1" codecs="avc1.640028" width="1440" height="1080"/>
+      <Representation id="v2" codecs="avc1.64001f" width="1280" height="720" sar="1:1"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://example.com/vod.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := [][2]string{{"4:3", "16:9"}, {"1:1", "16:9"}}
+	for i, stream := range output.Streams {
+		if stream.SampleAspectRatio != expected[i][0] || stream.DisplayAspectRatio != expected[i][1] {
+			t.Errorf("Stream %d: expected %s / %s, got %s / %s", i, expected[i][0], expected[i][1], stream.SampleAspectRatio, stream.DisplayAspectRatio)
+		}
+	}
+}
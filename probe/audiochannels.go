@@ -0,0 +1,111 @@
+package probe
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// DASH AudioChannelConfiguration schemes
+const (
+	// mpegChannelCountScheme has the channel count as value
+	mpegChannelCountScheme = "urn:mpeg:dash:23003:3:audio_channel_configuration:2011"
+
+	// cicpChannelScheme has a ChannelConfiguration of ISO/IEC 23001-8 (CICP) as value
+	cicpChannelScheme = "urn:mpeg:mpegB:cicp:ChannelConfiguration"
+
+	// Dolby schemes have a 16-bit channel mask in hex as value ("F801" is 5.1)
+	dolbyChannelScheme       = "tag:dolby.com,2014:dash:audio_channel_configuration:2011"
+	dolbyLegacyChannelScheme = "urn:dolby:dash:audio_channel_configuration:2011"
+
+	// dolbyExtensionScheme signals E-AC-3 extensions: "JOC" is Dolby Atmos
+	dolbyExtensionScheme = "tag:dolby.com,2018:dash:EC3_ExtensionType:2018"
+)
+
+// cicpChannelLayouts maps CICP ChannelConfiguration values to ffprobe channel layouts
+var cicpChannelLayouts = map[int]string{
+	1:  "mono",
+	2:  "stereo",
+	3:  "3.0",
+	4:  "4.0",
+	5:  "5.0",
+	6:  "5.1",
+	7:  "7.1(wide)",
+	9:  "3.0(back)",
+	10: "quad",
+	11: "6.1",
+	12: "7.1",
+	13: "22.2",
+	14: "5.1.2",
+	16: "5.1.4",
+	19: "7.1.4",
+}
+
+// dolbyChannelPairs are the bits of a Dolby channel mask standing for a pair
+// of channels (Lc/Rc, Lrs/Rrs, Lsd/Rsd, Lw/Rw, Vhl/Vhr and Lts/Rts)
+const dolbyChannelPairs = 1<<10 | 1<<9 | 1<<6 | 1<<5 | 1<<4 | 1<<2
+
+// mpdChannelLayout returns the channel layout of a DASH audio representation
+// from its AudioChannelConfiguration, or the adaptation set's: "5.1", "7.1",
+// or "Atmos/JOC" for E-AC-3 with joint object coding, like HLS CHANNELS. It
+// returns "" when the manifest declares no layout goprobe understands.
+func mpdChannelLayout(adaptationSet AdaptationSet, rep Representation) string {
+	for _, properties := range [][]EssentialProperty{
+		rep.SupplementalProperty, rep.EssentialProperty,
+		adaptationSet.SupplementalProperty, adaptationSet.EssentialProperty,
+	} {
+		for _, property := range properties {
+			if property.SchemeIdUri == dolbyExtensionScheme && property.Value == "JOC" {
+				return "Atmos/JOC"
+			}
+		}
+	}
+
+	configurations := rep.AudioChannels
+	if len(configurations) == 0 {
+		configurations = adaptationSet.AudioChannels
+	}
+	for _, configuration := range configurations {
+		if layout := audioChannelLayout(configuration); layout != "" {
+			return layout
+		}
+	}
+	return ""
+}
+
+// audioChannelLayout describes an AudioChannelConfiguration descriptor ("" if
+// its scheme or value is not understood)
+func audioChannelLayout(configuration Descriptor) string {
+	value := strings.TrimSpace(configuration.Value)
+	switch configuration.SchemeIdUri {
+	case mpegChannelCountScheme:
+		count, err := strconv.Atoi(value)
+		if err != nil || count <= 0 {
+			return ""
+		}
+		return channelCountLayout(count)
+	case cicpChannelScheme:
+		index, err := strconv.Atoi(value)
+		if err != nil {
+			return ""
+		}
+		return cicpChannelLayouts[index]
+	case dolbyChannelScheme, dolbyLegacyChannelScheme:
+		mask, err := strconv.ParseUint(value, 16, 16)
+		if err != nil || mask == 0 {
+			return ""
+		}
+		count := bits.OnesCount16(uint16(mask)) + bits.OnesCount16(uint16(mask&dolbyChannelPairs))
+		return channelCountLayout(count)
+	}
+	return ""
+}
+
+// channelCountLayout returns the ffprobe layout of a channel count ("5.1"
+// for 6), or "N channels" for counts without a common layout
+func channelCountLayout(count int) string {
+	if layout, ok := hlsChannelLayouts[count]; ok {
+		return layout
+	}
+	return strconv.Itoa(count) + " channels"
+}
@@ -0,0 +1,65 @@
+package probe
+
+import "testing"
+
+func TestAudioChannelLayout(t *testing.T) {
+	tests := []struct {
+		scheme   string
+		value    string
+		expected string
+	}{
+		{scheme: mpegChannelCountScheme, value: "2", expected: "stereo"},
+		{scheme: mpegChannelCountScheme, value: "6", expected: "5.1"},
+		{scheme: mpegChannelCountScheme, value: "12", expected: "12 channels"},
+		{scheme: mpegChannelCountScheme, value: "x", expected: ""},
+		{scheme: cicpChannelScheme, value: "6", expected: "5.1"},
+		{scheme: cicpChannelScheme, value: "12", expected: "7.1"},
+		{scheme: cicpChannelScheme, value: "19", expected: "7.1.4"},
+		{scheme: cicpChannelScheme, value: "0", expected: ""},
+		{scheme: dolbyChannelScheme, value: "A000", expected: "stereo"},
+		{scheme: dolbyChannelScheme, value: "F801", expected: "5.1"},
+		{scheme: dolbyLegacyChannelScheme, value: "FA01", expected: "7.1"},
+		{scheme: dolbyChannelScheme, value: "zz", expected: ""},
+		{scheme: "urn:example:channels", value: "6", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme+"="+tt.value, func(t *testing.T) {
+			if got := audioChannelLayout(Descriptor{SchemeIdUri: tt.scheme, Value: tt.value}); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMPDAudioChannels(t *testing.T) {
+	mpd := `<MPD type="static"><Period>
+<AdaptationSet contentType="audio" mimeType="audio/mp4" codecs="ec-3" lang="en">
+<AudioChannelConfiguration schemeIdUri="tag:dolby.com,2014:dash:audio_channel_configuration:2011" value="F801"/>
+<Representation id="surround" bandwidth="384000"/>
+<Representation id="wide" bandwidth="448000">
+<AudioChannelConfiguration schemeIdUri="urn:mpeg:mpegB:cicp:ChannelConfiguration" value="12"/>
+</Representation></AdaptationSet>
+<AdaptationSet contentType="audio" mimeType="audio/mp4" codecs="ec-3" lang="fr">
+<SupplementalProperty schemeIdUri="tag:dolby.com,2018:dash:EC3_ExtensionType:2018" value="JOC"/>
+<AudioChannelConfiguration schemeIdUri="tag:dolby.com,2014:dash:audio_channel_configuration:2011" value="F801"/>
+<Representation id="atmos" bandwidth="768000"/></AdaptationSet>
+<AdaptationSet contentType="audio" mimeType="audio/mp4" codecs="mp4a.40.2" lang="de">
+<Representation id="plain" bandwidth="128000"/></AdaptationSet>
+</Period></MPD>`
+
+	output, err := parseMPDManifest(mpd, "https://cdn.example.com/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"5.1", "7.1", "Atmos/JOC", "stereo"}
+	if len(output.Streams) != len(expected) {
+		t.Fatalf("Expected %d streams, got %d", len(expected), len(output.Streams))
+	}
+	for i, channels := range expected {
+		if got := output.Streams[i].Channels; got != channels {
+			t.Errorf("Expected stream %d channels %q, got %q", i, channels, got)
+		}
+	}
+}
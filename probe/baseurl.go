@@ -0,0 +1,143 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBaseURLCandidates bounds the base URL combinations kept per stream
+const maxBaseURLCandidates = 16
+
+// BaseURLCandidate is one of the alternative base URLs of a DASH stream.
+// MPDs list several BaseURL elements at a level for CDN failover; the
+// candidates combine the alternatives of every level, in player preference
+// order (the first is the one ResolveURLs uses).
+type BaseURLCandidate struct {
+	URL             string `json:"url"`
+	ServiceLocation string `json:"service_location,omitempty"`
+
+	// urls are the stream's first URLs through this base
+	urls []string
+}
+
+// BaseURLCheck reports whether a base URL served a stream's first segment
+// (DeepProbe.CheckBaseURLs)
+type BaseURLCheck struct {
+	BaseURL         string `json:"base_url"`
+	ServiceLocation string `json:"service_location,omitempty"`
+
+	// StreamID and URI identify the stream and segment requested through the base
+	StreamID string `json:"stream_id"`
+	URI      string `json:"uri"`
+
+	Reachable bool          `json:"reachable"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// mpdBaseURLs combines the BaseURL alternatives of the MPD, period,
+// adaptation set and representation (nil if there is no BaseURL)
+func mpdBaseURLs(manifestURL string, mpd *MPD, period Period, adaptationSet AdaptationSet, rep Representation) []BaseURLCandidate {
+	candidates := []BaseURLCandidate{{URL: manifestURL}}
+	found := false
+
+	for _, baseURLs := range [][]BaseURL{mpd.BaseURLs, period.BaseURLs, adaptationSet.BaseURLs, rep.BaseURLs} {
+		if len(baseURLs) == 0 {
+			continue
+		}
+		found = true
+
+		var next []BaseURLCandidate
+		seen := make(map[string]bool)
+		for _, candidate := range candidates {
+			for _, baseURL := range baseURLs {
+				resolved := resolveHLSURI(candidate.URL, strings.TrimSpace(baseURL.URL))
+				if seen[resolved] || len(next) == maxBaseURLCandidates {
+					continue
+				}
+				seen[resolved] = true
+
+				location := baseURL.ServiceLocation
+				if location == "" {
+					location = candidate.ServiceLocation
+				}
+				next = append(next, BaseURLCandidate{URL: resolved, ServiceLocation: location})
+			}
+		}
+		candidates = next
+	}
+
+	if !found {
+		return nil
+	}
+	for i := range candidates {
+		candidates[i].urls = mpdSegmentURLs(candidates[i].URL, true, period, adaptationSet, rep)
+	}
+	return candidates
+}
+
+// checkBaseURLs requests the first segment of a stream through every
+// distinct base URL and reports which bases are reachable. Unreachable bases
+// are also reported as warnings.
+func (p *Prober) checkBaseURLs(ctx context.Context, output *Output) {
+	var checks []BaseURLCheck
+	var requests [][]string
+	seen := make(map[string]bool)
+	for _, stream := range output.Streams {
+		for _, candidate := range stream.BaseURLs {
+			if seen[candidate.URL] || len(candidate.urls) == 0 {
+				continue
+			}
+			seen[candidate.URL] = true
+			checks = append(checks, BaseURLCheck{
+				BaseURL:         candidate.URL,
+				ServiceLocation: candidate.ServiceLocation,
+				StreamID:        stream.StreamID,
+				URI:             candidate.urls[len(candidate.urls)-1],
+			})
+			requests = append(requests, candidate.urls)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(check *BaseURLCheck, urls []string) {
+			defer wg.Done()
+			taskCtx, release, err := p.scheduler.schedule(ctx, SchedulerDeepProbe)
+			if err != nil {
+				check.Error = err.Error()
+				return
+			}
+			defer release()
+
+			start := p.clock.Now()
+			for _, segmentURL := range urls {
+				if _, err = p.fetchSegment(taskCtx, segmentURL, 1); err != nil {
+					break
+				}
+			}
+			check.Duration = p.clock.Now().Sub(start)
+			if err != nil {
+				check.Error = p.redactor.redactError(err).Error()
+				return
+			}
+			check.Reachable = true
+		}(&checks[i], requests[i])
+	}
+	wg.Wait()
+
+	for _, check := range checks {
+		if !check.Reachable {
+			output.Warnings = append(output.Warnings, fmt.Sprintf("base URL %s is unreachable: %s",
+				p.redactor.redactString(check.BaseURL), check.Error))
+			output.addIncomplete(ctx, IncompleteCheck{
+				Check: CheckBaseURL, StreamID: check.StreamID, URI: check.URI, BaseURL: check.BaseURL, Error: check.Error,
+			})
+		}
+	}
+	output.BaseURLChecks = checks
+}
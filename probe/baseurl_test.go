@@ -0,0 +1,103 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMPDBaseURLs(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+  <BaseURL serviceLocation="cdn-a">https://a.example.com/vod/</BaseURL>
+  <BaseURL serviceLocation="cdn-b">https://b.example.com/vod/</BaseURL>
+  <Period id="p0">
+    <AdaptationSet id="1" mimeType="video/mp4" codecs="avc1.64001f">
+      <BaseURL>video/</BaseURL>
+      <SegmentTemplate initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/$Number$.m4s"/>
+      <Representation id="720p" bandwidth="3000000" width="1280" height="720"/>
+    </AdaptationSet>
+    <AdaptationSet id="2" mimeType="audio/mp4" codecs="mp4a.40.2">
+      <BaseURL>https://a.example.com/vod/audio/</BaseURL>
+      <SegmentTemplate initialization="init.mp4" media="$Number$.m4s"/>
+      <Representation id="aac" bandwidth="128000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://origin.example.com/vod/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	video := output.Streams[0]
+	expected := []BaseURLCandidate{
+		{URL: "https://a.example.com/vod/video/", ServiceLocation: "cdn-a"},
+		{URL: "https://b.example.com/vod/video/", ServiceLocation: "cdn-b"},
+	}
+	if len(video.BaseURLs) != len(expected) {
+		t.Fatalf("Expected %d base URLs, got %+v", len(expected), video.BaseURLs)
+	}
+	for i, candidate := range video.BaseURLs {
+		if candidate.URL != expected[i].URL || candidate.ServiceLocation != expected[i].ServiceLocation {
+			t.Errorf("Expected %+v, got %+v", expected[i], candidate)
+		}
+	}
+	if urls := video.BaseURLs[1].urls; !reflect.DeepEqual(urls, []string{"https://b.example.com/vod/video/720p/init.mp4", "https://b.example.com/vod/video/720p/1.m4s"}) {
+		t.Errorf("Unexpected failover URLs %v", urls)
+	}
+
+	urls, _ := output.ResolveURLs("0:0")
+	if urls[0] != "https://a.example.com/vod/video/720p/init.mp4" {
+		t.Errorf("Expected the first base URL to be preferred, got %v", urls)
+	}
+
+	// An absolute BaseURL collapses the alternatives into a single base
+	if audio := output.Streams[1]; audio.BaseURLs != nil {
+		t.Errorf("Expected no alternatives for the audio stream, got %+v", audio.BaseURLs)
+	}
+}
+
+func TestCheckBaseURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/primary/") {
+			w.Write([]byte("segment"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	output := &Output{Streams: []StreamInfo{
+		{StreamID: "0:0", Type: "Video", BaseURLs: []BaseURLCandidate{
+			{URL: server.URL + "/primary/", ServiceLocation: "a", urls: []string{server.URL + "/primary/init.mp4", server.URL + "/primary/1.m4s"}},
+			{URL: server.URL + "/stale/", ServiceLocation: "b", urls: []string{server.URL + "/stale/init.mp4", server.URL + "/stale/1.m4s"}},
+		}},
+		{StreamID: "0:1", Type: "Audio", BaseURLs: []BaseURLCandidate{
+			{URL: server.URL + "/primary/", ServiceLocation: "a", urls: []string{server.URL + "/primary/audio.m4s"}},
+		}},
+	}}
+
+	NewProber(&ProbeOptions{DisableCamouflage: true}).checkBaseURLs(context.Background(), output)
+
+	if len(output.BaseURLChecks) != 2 {
+		t.Fatalf("Expected one check per distinct base URL, got %+v", output.BaseURLChecks)
+	}
+
+	primary := output.BaseURLChecks[0]
+	if !primary.Reachable || primary.StreamID != "0:0" || primary.URI != server.URL+"/primary/1.m4s" || primary.ServiceLocation != "a" {
+		t.Errorf("Unexpected primary check %+v", primary)
+	}
+
+	stale := output.BaseURLChecks[1]
+	if stale.Reachable || stale.Error == "" {
+		t.Errorf("Expected the stale base URL to be unreachable, got %+v", stale)
+	}
+
+	if len(output.Warnings) != 1 || !strings.Contains(output.Warnings[0], "base URL "+server.URL+"/stale/ is unreachable") {
+		t.Errorf("Expected an unreachable base URL warning, got %v", output.Warnings)
+	}
+}
@@ -0,0 +1,68 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBatchConcurrency is the default number of manifests probed at once by ProbeBatch
+const DefaultBatchConcurrency = 8
+
+// BatchResult is the outcome of probing one manifest of a batch
+type BatchResult struct {
+	URL      string        `json:"url"`
+	Output   *Output       `json:"output,omitempty"`
+	Err      error         `json:"-"`
+	Duration time.Duration `json:"duration"`
+}
+
+// ProbeBatch probes manifestURLs with up to concurrency probes in flight
+// (0 = DefaultBatchConcurrency) and returns one result per URL, in input order.
+// Failed probes are reported in BatchResult.Err rather than aborting the batch.
+func (p *Prober) ProbeBatch(ctx context.Context, manifestURLs []string, concurrency int) []BatchResult {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	results := make([]BatchResult, len(manifestURLs))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, manifestURL := range manifestURLs {
+		wg.Add(1)
+		go func(i int, manifestURL string) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[i] = BatchResult{URL: manifestURL, Err: ctx.Err()}
+				return
+			}
+			defer func() { <-slots }()
+
+			taskCtx, release, err := p.scheduler.schedule(ctx, SchedulerBatch)
+			if err != nil {
+				results[i] = BatchResult{URL: manifestURL, Err: err}
+				return
+			}
+			defer release()
+
+			start := p.clock.Now()
+			output, err := p.Probe(taskCtx, manifestURL)
+			results[i] = BatchResult{
+				URL:      manifestURL,
+				Output:   output,
+				Err:      err,
+				Duration: p.clock.Now().Sub(start),
+			}
+			if p.opts != nil && p.opts.Deterministic {
+				results[i].Duration = 0
+			}
+		}(i, manifestURL)
+	}
+
+	wg.Wait()
+	return results
+}
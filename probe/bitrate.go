@@ -0,0 +1,121 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// bitrateMismatchTolerance is the relative deviation from the declared
+// bandwidth above which a variant is flagged as mismatched
+const bitrateMismatchTolerance = 0.1
+
+// BitrateStats summarizes the segment bitrates of an HLS variant's media
+// playlist. Bitrates are in bits per second.
+type BitrateStats struct {
+	StreamID string `json:"stream_id"`
+	URI      string `json:"uri"`
+
+	// DeclaredBandwidth and DeclaredAverage are the variant's BANDWIDTH and AVERAGE-BANDWIDTH
+	DeclaredBandwidth int64 `json:"declared_bandwidth"`
+	DeclaredAverage   int64 `json:"declared_average_bandwidth,omitempty"`
+
+	// Min, Avg and Max are computed from EXT-X-BITRATE tags, or from
+	// EXT-X-BYTERANGE sizes for segments without one. Avg is duration-weighted.
+	Min      int64 `json:"min"`
+	Avg      int64 `json:"avg"`
+	Max      int64 `json:"max"`
+	Segments int   `json:"segments"`
+
+	// Mismatch reports that Max exceeds BANDWIDTH, or Avg deviates from
+	// AVERAGE-BANDWIDTH, by more than 10%
+	Mismatch bool `json:"mismatch,omitempty"`
+}
+
+// analyzeBitrates adds the bitrate statistics of the variant media playlists
+// of output (see fetchVariantPlaylists)
+func (p *Prober) analyzeBitrates(ctx context.Context, output *Output) {
+	for i, fetch := range p.fetchVariantPlaylists(ctx, output) {
+		if fetch.err != nil {
+			continue
+		}
+		stats := computeBitrateStats(output.variants[i], fetch.fetched.body)
+		if stats == nil {
+			continue
+		}
+		output.Bitrates = append(output.Bitrates, *stats)
+		if stats.Mismatch {
+			output.Warnings = append(output.Warnings, fmt.Sprintf("stream %s bitrate (avg %d, max %d) does not match declared bandwidth %d",
+				stats.StreamID, stats.Avg, stats.Max, stats.DeclaredBandwidth))
+		}
+	}
+}
+
+// computeBitrateStats computes segment bitrate statistics of a media playlist
+// (nil if no segment bitrate is known)
+func computeBitrateStats(variant hlsVariantRef, content string) *BitrateStats {
+	stats := &BitrateStats{
+		StreamID:          variant.streamID,
+		URI:               variant.uri,
+		DeclaredBandwidth: variant.bandwidth,
+		DeclaredAverage:   variant.averageBandwidth,
+		Min:               math.MaxInt64,
+	}
+
+	var bitrate int64 // current EXT-X-BITRATE in bits/s (applies until the next tag)
+	var duration float64
+	var byteRange int64
+	var totalBits, totalDuration float64
+
+	for lines := scanLines(content); lines.scan(); {
+		line := strings.TrimSpace(lines.line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-BITRATE:"):
+			if kbps, err := strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-BITRATE:"), 10, 64); err == nil {
+				bitrate = kbps * 1000
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value := strings.TrimPrefix(line, "#EXTINF:")
+			if comma := strings.Index(value, ","); comma >= 0 {
+				value = value[:comma]
+			}
+			duration, _ = strconv.ParseFloat(value, 64)
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+			value := strings.TrimPrefix(line, "#EXT-X-BYTERANGE:")
+			if at := strings.Index(value, "@"); at >= 0 {
+				value = value[:at]
+			}
+			byteRange, _ = strconv.ParseInt(value, 10, 64)
+		case line != "" && !strings.HasPrefix(line, "#"):
+			segmentBitrate := bitrate
+			if segmentBitrate == 0 && byteRange > 0 && duration > 0 {
+				segmentBitrate = int64(float64(byteRange*8) / duration)
+			}
+			if segmentBitrate > 0 {
+				stats.Segments++
+				stats.Min = min(stats.Min, segmentBitrate)
+				stats.Max = max(stats.Max, segmentBitrate)
+				totalBits += float64(segmentBitrate) * duration
+				totalDuration += duration
+			}
+			duration, byteRange = 0, 0
+		}
+	}
+
+	if stats.Segments == 0 {
+		return nil
+	}
+
+	if totalDuration > 0 {
+		stats.Avg = int64(totalBits / totalDuration)
+	}
+	if stats.DeclaredBandwidth > 0 && float64(stats.Max) > float64(stats.DeclaredBandwidth)*(1+bitrateMismatchTolerance) {
+		stats.Mismatch = true
+	}
+	if stats.DeclaredAverage > 0 && math.Abs(float64(stats.Avg-stats.DeclaredAverage)) > float64(stats.DeclaredAverage)*bitrateMismatchTolerance {
+		stats.Mismatch = true
+	}
+	return stats
+}
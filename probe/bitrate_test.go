@@ -0,0 +1,116 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestComputeBitrateStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		variant  hlsVariantRef
+		playlist string
+		expected *BitrateStats
+	}{
+		{
+			name:    "EXT-X-BITRATE tags",
+			variant: hlsVariantRef{streamID: "0:0", bandwidth: 2000000},
+			playlist: `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-BITRATE:1000
+#EXTINF:6.0,
+seg1.ts
+#EXTINF:6.0,
+seg2.ts
+#EXT-X-BITRATE:2000
+#EXTINF:2.0,
+seg3.ts
+`,
+			expected: &BitrateStats{StreamID: "0:0", DeclaredBandwidth: 2000000, Min: 1000000, Avg: 1142857, Max: 2000000, Segments: 3},
+		},
+		{
+			name:    "byte range fallback",
+			variant: hlsVariantRef{streamID: "0:0", bandwidth: 1000000},
+			playlist: `#EXTM3U
+#EXTINF:4.0,
+#EXT-X-BYTERANGE:500000@0
+media.ts
+#EXTINF:4.0,
+#EXT-X-BYTERANGE:1000000
+media.ts
+`,
+			expected: &BitrateStats{StreamID: "0:0", DeclaredBandwidth: 1000000, Min: 1000000, Avg: 1500000, Max: 2000000, Segments: 2, Mismatch: true},
+		},
+		{
+			name:    "average mismatch",
+			variant: hlsVariantRef{streamID: "0:2", bandwidth: 3000000, averageBandwidth: 2000000},
+			playlist: `#EXTM3U
+#EXT-X-BITRATE:1500
+#EXTINF:6.0,
+seg1.ts
+`,
+			expected: &BitrateStats{StreamID: "0:2", DeclaredBandwidth: 3000000, DeclaredAverage: 2000000, Min: 1500000, Avg: 1500000, Max: 1500000, Segments: 1, Mismatch: true},
+		},
+		{
+			name:     "no bitrate information",
+			variant:  hlsVariantRef{streamID: "0:0"},
+			playlist: "#EXTM3U\n#EXTINF:6.0,\nseg1.ts\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := computeBitrateStats(tt.variant, tt.playlist)
+			if tt.expected == nil {
+				if stats != nil {
+					t.Errorf("Expected no stats, got %+v", stats)
+				}
+				return
+			}
+			if stats == nil || *stats != *tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, stats)
+			}
+		})
+	}
+}
+
+func TestAnalyzeBitrates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2"
+1080p.m3u8
+`))
+		case "/720p.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-BITRATE:900\n#EXTINF:6.0,\nseg1.ts\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	opts := &ProbeOptions{DisableCamouflage: true, AnalyzeBitrates: true}
+	output, err := NewProber(opts).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(output.Bitrates) != 1 {
+		t.Fatalf("Expected 1 bitrate entry, got %d", len(output.Bitrates))
+	}
+
+	stats := output.Bitrates[0]
+	if stats.StreamID != "0:0" || stats.URI != server.URL+"/720p.m3u8" || stats.Avg != 900000 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	if len(output.Warnings) != 1 || !strings.Contains(output.Warnings[0], "stream 0:2") {
+		t.Errorf("Expected a warning for the missing 1080p playlist, got %v", output.Warnings)
+	}
+}
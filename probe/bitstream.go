@@ -0,0 +1,695 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// errEncryptedSegment is returned for segments encrypted as a whole, whose
+// bitstream cannot be inspected without the key
+var errEncryptedSegment = errors.New("segment is encrypted with AES-128")
+
+// BitstreamCheck is the result of scanning the start of a stream's first
+// media segment for the markers of its declared codec
+type BitstreamCheck struct {
+	StreamID string `json:"stream_id"`
+	URI      string `json:"uri"`
+
+	// Container is the detected segment format: "mpegts", "fmp4", "webm" or "packed_audio"
+	Container string `json:"container,omitempty"`
+
+	// Declared is the stream's codec; Detected lists the codecs found in the segment
+	Declared string   `json:"declared"`
+	Detected []string `json:"detected,omitempty"`
+
+	// Match reports that Detected contains Declared
+	Match bool `json:"match"`
+
+	// Error describes why the segment could not be checked
+	Error string `json:"error,omitempty"`
+}
+
+// checkBitstreams fetches the first media segment of each audio and video
+// stream and checks its bytes against the declared codec. Mismatches and
+// failures are reported as warnings.
+func (p *Prober) checkBitstreams(ctx context.Context, deep *DeepProbe, output *Output) {
+	type group struct {
+		urls    []string
+		streams []int
+		segment *firstSegment
+		err     error
+	}
+
+	// Streams muxed into the same segment share a single fetch
+	var groups []*group
+	byKey := make(map[string]*group)
+	for i, stream := range output.Streams {
+		if (stream.Type != "Video" && stream.Type != "Audio") || len(stream.urls) == 0 {
+			continue
+		}
+		key := strings.Join(stream.urls, "\n")
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{urls: stream.urls}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.streams = append(g.streams, i)
+	}
+
+	var wg sync.WaitGroup
+	for _, g := range groups {
+		wg.Add(1)
+		go func(g *group) {
+			defer wg.Done()
+			taskCtx, release, err := p.scheduler.schedule(ctx, SchedulerDeepProbe)
+			if err != nil {
+				g.err = err
+				return
+			}
+			defer release()
+			g.segment, g.err = p.fetchFirstSegment(taskCtx, g.urls, deep.segmentBytes())
+		}(g)
+	}
+	wg.Wait()
+
+	checks := make(map[int]BitstreamCheck)
+	for _, g := range groups {
+		var scan *bitstreamScan
+		if g.err == nil {
+			scan = scanBitstream(g.segment.data)
+		}
+		for _, i := range g.streams {
+			check, warning := p.bitstreamCheck(&output.Streams[i], g.urls, g.segment, scan, g.err)
+			if warning != "" {
+				output.Warnings = append(output.Warnings, warning)
+			}
+			if g.err != nil {
+				output.addIncomplete(ctx, IncompleteCheck{
+					Check: CheckBitstream, StreamID: check.StreamID, URI: check.URI, Error: check.Error,
+				})
+			}
+			checks[i] = check
+		}
+	}
+
+	indexes := make([]int, 0, len(checks))
+	for i := range checks {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		output.Bitstream = append(output.Bitstream, checks[i])
+	}
+}
+
+// bitstreamCheck checks stream against the scan of its first segment, fetched
+// from urls or failed with err. It returns the check and the warning to
+// report ("" if none).
+func (p *Prober) bitstreamCheck(stream *StreamInfo, urls []string, segment *firstSegment, scan *bitstreamScan, err error) (BitstreamCheck, string) {
+	check := BitstreamCheck{StreamID: stream.StreamID, URI: urls[len(urls)-1], Declared: stream.Codec}
+	switch {
+	case errors.Is(err, errEncryptedSegment):
+		check.Error = err.Error()
+	case err != nil:
+		check.Error = p.redactor.redactError(err).Error()
+		return check, fmt.Sprintf("bitstream check of stream %s failed: %s", stream.StreamID, check.Error)
+	default:
+		check.URI = segment.uri
+		check.Container = scan.container
+		check.Detected = scan.detected
+		check.Error = scan.problem
+		check.Match = containsString(scan.detected, stream.Codec)
+		if check.Container == "" {
+			return check, fmt.Sprintf("bitstream check of stream %s failed: %s", stream.StreamID, check.Error)
+		}
+		if !check.Match {
+			return check, fmt.Sprintf("stream %s declares %s but its first segment contains %s",
+				stream.StreamID, stream.Codec, describeDetected(scan))
+		}
+	}
+	return check, ""
+}
+
+// CheckStream fetches the first media segment of one stream of output and
+// checks its bytes against the declared codec, like DeepProbe does for every
+// stream (deep nil = default settings). A mismatch is reported in the check,
+// not as an error.
+func (p *Prober) CheckStream(ctx context.Context, output *Output, streamID string, deep *DeepProbe) (*BitstreamCheck, error) {
+	if deep == nil {
+		deep = &DeepProbe{}
+	}
+	var stream *StreamInfo
+	for i := range output.Streams {
+		if output.Streams[i].StreamID == streamID {
+			stream = &output.Streams[i]
+		}
+	}
+	if stream == nil {
+		return nil, NewValidationError(fmt.Sprintf("stream %q not found", streamID))
+	}
+	if len(stream.urls) == 0 {
+		return nil, NewValidationError(fmt.Sprintf("stream %q has no playable URL", streamID))
+	}
+
+	ctx = withLogger(ctx, p.logger)
+	ctx, release, err := p.scheduler.schedule(ctx, SchedulerDeepProbe)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	segment, err := p.fetchFirstSegment(ctx, stream.urls, deep.segmentBytes())
+	var scan *bitstreamScan
+	if err == nil {
+		scan = scanBitstream(segment.data)
+	}
+	check, _ := p.bitstreamCheck(stream, stream.urls, segment, scan, err)
+	return &check, nil
+}
+
+// describeDetected summarizes the codecs found by a scan for a warning
+func describeDetected(scan *bitstreamScan) string {
+	detected := "no recognizable " + scan.container + " bitstream"
+	if len(scan.detected) > 0 {
+		detected = strings.Join(scan.detected, ", ")
+	}
+	if scan.problem != "" {
+		detected += " (" + scan.problem + ")"
+	}
+	return detected
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// bitstreamScan is what was found in the start of a segment
+type bitstreamScan struct {
+	container string
+	detected  []string
+	problem   string
+}
+
+// addCodec records a detected codec once
+func (s *bitstreamScan) addCodec(codec string) {
+	if codec != "" && !containsString(s.detected, codec) {
+		s.detected = append(s.detected, codec)
+	}
+}
+
+// scanBitstream detects the container of a segment prefix and the codecs
+// whose bitstream markers it contains
+func scanBitstream(data []byte) *bitstreamScan {
+	scan := &bitstreamScan{}
+	switch {
+	case isMPEGTS(data):
+		scan.container = "mpegts"
+		scanMPEGTS(data, scan)
+	case isISOBMFF(data):
+		scan.container = "fmp4"
+		scanISOBMFF(data, scan)
+	case bytes.HasPrefix(data, []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		scan.container = "webm"
+		scanMatroska(data, scan)
+	default:
+		audio := skipID3(data)
+		if codec := audioCodecAt(audio); codec != "" {
+			scan.container = "packed_audio"
+			scan.addCodec(codec)
+		} else {
+			scan.problem = "unrecognized container"
+		}
+	}
+	return scan
+}
+
+// MPEG-TS
+
+const tsPacketSize = 188
+
+// esPayloadLimit bounds the elementary stream bytes collected per PID
+const esPayloadLimit = 64 * 1024
+
+// isMPEGTS reports whether data starts with aligned MPEG-TS packets
+func isMPEGTS(data []byte) bool {
+	return len(data) >= tsPacketSize && data[0] == 0x47 &&
+		(len(data) < 2*tsPacketSize || data[tsPacketSize] == 0x47)
+}
+
+// tsVideoStreamTypes and tsAudioStreamTypes classify PMT stream types,
+// including the SAMPLE-AES variants
+var (
+	tsVideoStreamTypes = map[byte]bool{0x01: true, 0x02: true, 0x1b: true, 0x24: true, 0xdb: true}
+	tsAudioStreamTypes = map[byte]bool{0x03: true, 0x04: true, 0x06: true, 0x0f: true, 0x11: true, 0x81: true, 0x87: true, 0xc1: true, 0xc2: true, 0xcf: true}
+)
+
+// scanMPEGTS demuxes the elementary streams announced in the PMT and looks
+// for NAL start codes and audio frame syncs in their payloads
+func scanMPEGTS(data []byte, scan *bitstreamScan) {
+	pmtPID := -1
+	var streamTypes map[int]byte
+	payloads := make(map[int][]byte)
+	var pids []int
+
+	for offset := 0; offset+tsPacketSize <= len(data); offset += tsPacketSize {
+		packet := data[offset : offset+tsPacketSize]
+		if packet[0] != 0x47 {
+			scan.problem = fmt.Sprintf("lost MPEG-TS sync at byte %d", offset)
+			break
+		}
+		start := packet[1]&0x40 != 0
+		pid := int(packet[1]&0x1f)<<8 | int(packet[2])
+		payload := packet[4:]
+		switch (packet[3] >> 4) & 0x03 {
+		case 0, 2:
+			continue
+		case 3:
+			if 1+int(payload[0]) >= len(payload) {
+				continue
+			}
+			payload = payload[1+int(payload[0]):]
+		}
+
+		switch {
+		case pid == 0 && start:
+			pmtPID = parsePAT(payload)
+		case pid == pmtPID && start && streamTypes == nil:
+			streamTypes = parsePMT(payload)
+		default:
+			if _, ok := streamTypes[pid]; !ok {
+				continue
+			}
+			if start {
+				payload = stripPESHeader(payload)
+			}
+			if _, seen := payloads[pid]; !seen {
+				if !start {
+					continue
+				}
+				pids = append(pids, pid)
+			}
+			if len(payloads[pid]) < esPayloadLimit {
+				payloads[pid] = append(payloads[pid], payload...)
+			}
+		}
+	}
+
+	if streamTypes == nil {
+		if scan.problem == "" {
+			scan.problem = "no PMT found"
+		}
+		return
+	}
+
+	for _, pid := range pids {
+		streamType := streamTypes[pid]
+		switch {
+		case tsVideoStreamTypes[streamType]:
+			scan.addCodec(annexBCodec(payloads[pid]))
+		case tsAudioStreamTypes[streamType]:
+			scan.addCodec(scanAudioCodec(payloads[pid]))
+		}
+	}
+}
+
+// psiSection returns the section of a PSI payload, skipping the pointer field
+func psiSection(payload []byte) []byte {
+	if len(payload) == 0 || 1+int(payload[0]) >= len(payload) {
+		return nil
+	}
+	section := payload[1+int(payload[0]):]
+	if len(section) < 3 {
+		return nil
+	}
+	length := int(section[1]&0x0f)<<8 | int(section[2])
+	if 3+length > len(section) || length < 4 {
+		return nil
+	}
+	return section[:3+length-4] // drop the CRC
+}
+
+// parsePAT returns the PMT PID of the first program (-1 if none)
+func parsePAT(payload []byte) int {
+	section := psiSection(payload)
+	for i := 8; i+4 <= len(section); i += 4 {
+		if program := int(section[i])<<8 | int(section[i+1]); program != 0 {
+			return int(section[i+2]&0x1f)<<8 | int(section[i+3])
+		}
+	}
+	return -1
+}
+
+// parsePMT returns the stream type of each elementary PID of a PMT
+func parsePMT(payload []byte) map[int]byte {
+	section := psiSection(payload)
+	if len(section) < 12 {
+		return nil
+	}
+	streamTypes := make(map[int]byte)
+	i := 12 + (int(section[10]&0x0f)<<8 | int(section[11]))
+	for i+5 <= len(section) {
+		pid := int(section[i+1]&0x1f)<<8 | int(section[i+2])
+		streamTypes[pid] = section[i]
+		i += 5 + (int(section[i+3]&0x0f)<<8 | int(section[i+4]))
+	}
+	return streamTypes
+}
+
+// stripPESHeader returns the payload of a packet starting a PES packet
+func stripPESHeader(payload []byte) []byte {
+	if len(payload) < 9 || !bytes.HasPrefix(payload, []byte{0, 0, 1}) {
+		return nil
+	}
+	end := 9 + int(payload[8])
+	if end > len(payload) {
+		return nil
+	}
+	return payload[end:]
+}
+
+// annexBCodec returns the codec of an Annex B byte stream containing an
+// H.264 or H.265 sequence parameter set ("" if none)
+func annexBCodec(data []byte) string {
+	hevc := false
+	for i := 0; i+4 < len(data); i++ {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+		header := data[i+3]
+		if header&0x80 != 0 {
+			continue
+		}
+		if header&0x1f == 7 && header&0x60 != 0 {
+			return "h264"
+		}
+		if (header>>1)&0x3f == 33 && data[i+4] == 0x01 {
+			hevc = true
+		}
+	}
+	if hevc {
+		return "hevc"
+	}
+	return ""
+}
+
+// scanAudioCodec returns the codec of the first audio frame found near the
+// start of an audio elementary stream ("" if none)
+func scanAudioCodec(data []byte) string {
+	limit := min(len(data), 4096)
+	for i := 0; i < limit; i++ {
+		if codec := audioCodecAt(data[i:]); codec != "" {
+			return codec
+		}
+	}
+	return ""
+}
+
+// audioCodecAt returns the codec of an audio frame starting at data[0]
+// ("" if none). Sync words are confirmed by the next frame when it is
+// within data.
+func audioCodecAt(data []byte) string {
+	if len(data) < 7 {
+		return ""
+	}
+	switch {
+	case data[0] == 0xff && data[1]&0xf6 == 0xf0:
+		// ADTS: 13-bit frame length includes the header
+		length := int(data[3]&0x03)<<11 | int(data[4])<<3 | int(data[5])>>5
+		if length >= 7 && confirmSync(data, length, func(next []byte) bool { return next[0] == 0xff && next[1]&0xf6 == 0xf0 }) {
+			return "aac"
+		}
+	case data[0] == 0x0b && data[1] == 0x77:
+		if bsid := data[5] >> 3; bsid <= 10 {
+			return "ac3"
+		} else if bsid <= 16 {
+			return "eac3"
+		}
+	case data[0] == 0xff && data[1]&0xe0 == 0xe0 && data[1]&0x06 != 0 && data[2]&0xf0 != 0xf0:
+		return "mp3"
+	}
+	return ""
+}
+
+// confirmSync checks the frame following a frame of length bytes, if present
+func confirmSync(data []byte, length int, sync func([]byte) bool) bool {
+	if length+2 > len(data) {
+		return true
+	}
+	return sync(data[length:])
+}
+
+// skipID3 skips an ID3v2 tag at the start of packed audio
+func skipID3(data []byte) []byte {
+	if len(data) < 10 || !bytes.HasPrefix(data, []byte("ID3")) {
+		return data
+	}
+	size := int(data[6]&0x7f)<<21 | int(data[7]&0x7f)<<14 | int(data[8]&0x7f)<<7 | int(data[9]&0x7f)
+	if 10+size > len(data) {
+		return nil
+	}
+	return data[10+size:]
+}
+
+// ISOBMFF
+
+// isISOBMFF reports whether data starts with a top-level ISOBMFF box
+func isISOBMFF(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	switch string(data[4:8]) {
+	case "ftyp", "styp", "moov", "moof", "sidx", "emsg", "prft", "free":
+		return true
+	}
+	return false
+}
+
+// sampleEntryCodecs maps ISOBMFF sample entry types to codec names
+var sampleEntryCodecs = map[string]string{
+	"avc1": "h264", "avc3": "h264",
+	"hvc1": "hevc", "hev1": "hevc", "dvh1": "hevc", "dvhe": "hevc",
+	"av01": "av1",
+	"vp09": "vp9",
+	"mp4a": "aac",
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"ac-4": "ac4",
+	"Opus": "opus",
+	"fLaC": "flac",
+	"wvtt": "webvtt",
+	"stpp": "ttml",
+}
+
+// isoTrack is a track sample entry found in a moov box
+type isoTrack struct {
+	codec      string
+	lengthSize int // NAL length prefix size for H.264/H.265 (0 otherwise)
+}
+
+// isoBox is a box header
+type isoBox struct {
+	boxType string
+	payload []byte // truncated to the available data
+	size    int64  // declared size including the header
+}
+
+// readBoxes splits data into boxes, keeping a truncated final box
+func readBoxes(data []byte) []isoBox {
+	var boxes []isoBox
+	for len(data) >= 8 {
+		size := int64(binary.BigEndian.Uint32(data))
+		header := 8
+		switch size {
+		case 0:
+			size = int64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return boxes
+			}
+			size = int64(binary.BigEndian.Uint64(data[8:]))
+			header = 16
+		}
+		if size < int64(header) {
+			return boxes
+		}
+		end := int(min(size, int64(len(data))))
+		boxes = append(boxes, isoBox{boxType: string(data[4:8]), payload: data[header:end], size: size})
+		data = data[end:]
+	}
+	return boxes
+}
+
+// findBox returns the first box of boxType along path below data
+func findBox(data []byte, path ...string) *isoBox {
+	for _, box := range readBoxes(data) {
+		if box.boxType != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return &box
+		}
+		if found := findBox(box.payload, path[1:]...); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// scanISOBMFF reads the sample entries of an initialization segment and
+// checks that the first sample of a single H.264/H.265 track is a valid
+// length-prefixed NAL unit
+func scanISOBMFF(data []byte, scan *bitstreamScan) {
+	var tracks []isoTrack
+	var mdat *isoBox
+	for _, box := range readBoxes(data) {
+		switch box.boxType {
+		case "moov":
+			for _, trak := range readBoxes(box.payload) {
+				if trak.boxType != "trak" {
+					continue
+				}
+				if stsd := findBox(trak.payload, "mdia", "minf", "stbl", "stsd"); stsd != nil {
+					tracks = append(tracks, parseSampleEntries(stsd.payload)...)
+				}
+			}
+		case "mdat":
+			if mdat == nil {
+				mdat = &box
+			}
+		}
+	}
+
+	if len(tracks) == 0 {
+		scan.problem = "no initialization segment"
+		return
+	}
+
+	for _, track := range tracks {
+		scan.addCodec(track.codec)
+	}
+
+	if len(tracks) != 1 || tracks[0].lengthSize == 0 || mdat == nil {
+		return
+	}
+	if !validFirstNAL(mdat, tracks[0]) {
+		scan.detected = nil
+		scan.problem = fmt.Sprintf("first sample is not a length-prefixed %s NAL unit", tracks[0].codec)
+	}
+}
+
+// parseSampleEntries reads the sample entries of an stsd box
+func parseSampleEntries(stsd []byte) []isoTrack {
+	if len(stsd) < 8 {
+		return nil
+	}
+	var tracks []isoTrack
+	for _, entry := range readBoxes(stsd[8:]) {
+		entryType := entry.boxType
+		var children []byte
+		switch {
+		case len(entry.payload) >= 78 && isVisualSampleEntry(entryType):
+			children = entry.payload[78:]
+		case len(entry.payload) >= 28:
+			children = entry.payload[28:]
+		}
+		if entryType == "encv" || entryType == "enca" {
+			if frma := findBox(children, "sinf", "frma"); frma != nil && len(frma.payload) >= 4 {
+				entryType = string(frma.payload[:4])
+			}
+		}
+
+		track := isoTrack{codec: sampleEntryCodecs[entryType]}
+		if track.codec == "" {
+			track.codec = strings.TrimSpace(entryType)
+		}
+		switch track.codec {
+		case "h264":
+			if avcC := findBox(children, "avcC"); avcC != nil && len(avcC.payload) >= 5 {
+				track.lengthSize = int(avcC.payload[4]&0x03) + 1
+			}
+		case "hevc":
+			if hvcC := findBox(children, "hvcC"); hvcC != nil && len(hvcC.payload) >= 22 {
+				track.lengthSize = int(hvcC.payload[21]&0x03) + 1
+			}
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks
+}
+
+// isVisualSampleEntry reports whether a sample entry type is a VisualSampleEntry
+func isVisualSampleEntry(entryType string) bool {
+	switch entryType {
+	case "encv", "avc1", "avc3", "hvc1", "hev1", "dvh1", "dvhe", "av01", "vp09":
+		return true
+	}
+	return false
+}
+
+// validFirstNAL checks the first NAL unit of an mdat against the track's codec
+func validFirstNAL(mdat *isoBox, track isoTrack) bool {
+	data := mdat.payload
+	if len(data) < track.lengthSize+2 {
+		return true // too little data to judge
+	}
+	var length int64
+	for _, b := range data[:track.lengthSize] {
+		length = length<<8 | int64(b)
+	}
+	if length == 0 || length > mdat.size-int64(track.lengthSize) {
+		return false
+	}
+
+	header := data[track.lengthSize]
+	if header&0x80 != 0 {
+		return false
+	}
+	if track.codec == "hevc" {
+		return (header>>1)&0x3f < 48 && data[track.lengthSize+1]&0x07 != 0
+	}
+	nalType := header & 0x1f
+	return nalType >= 1 && nalType <= 23
+}
+
+// Matroska
+
+// matroskaCodecs maps Matroska CodecID prefixes to codec names
+var matroskaCodecs = []struct {
+	id    string
+	codec string
+}{
+	{"V_MPEG4/ISO/AVC", "h264"},
+	{"V_MPEGH/ISO/HEVC", "hevc"},
+	{"V_VP9", "vp9"},
+	{"V_VP8", "vp8"},
+	{"V_AV1", "av1"},
+	{"A_AAC", "aac"},
+	{"A_EAC3", "eac3"},
+	{"A_AC3", "ac3"},
+	{"A_OPUS", "opus"},
+	{"A_VORBIS", "vorbis"},
+}
+
+// scanMatroska looks for track CodecIDs in a WebM initialization segment
+func scanMatroska(data []byte, scan *bitstreamScan) {
+	for _, entry := range matroskaCodecs {
+		if bytes.Contains(data, []byte(entry.id)) {
+			scan.addCodec(entry.codec)
+		}
+	}
+	if len(scan.detected) == 0 {
+		scan.problem = "no track CodecID found"
+	}
+}
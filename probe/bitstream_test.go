@@ -0,0 +1,415 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// tsPacket builds an MPEG-TS packet, padding short payloads with an adaptation field
+func tsPacket(pid int, start bool, payload []byte) []byte {
+	packet := []byte{0x47, byte(pid>>8) & 0x1f, byte(pid), 0x10}
+	if start {
+		packet[1] |= 0x40
+	}
+	if len(payload) < 184 {
+		packet[3] = 0x30
+		stuffing := 183 - len(payload)
+		packet = append(packet, byte(stuffing))
+		if stuffing > 0 {
+			packet = append(packet, 0x00)
+			for i := 1; i < stuffing; i++ {
+				packet = append(packet, 0xff)
+			}
+		}
+	}
+	return append(packet, payload...)
+}
+
+// tsSegment builds a segment with a PAT, a PMT and one PES packet per stream
+func tsSegment(streams map[int]byte, payloads map[int][]byte) []byte {
+	pat := []byte{0x00, 0x00, 0xb0, 13, 0x00, 0x01, 0xc1, 0x00, 0x00, 0x00, 0x01, 0xe1, 0x00, 0, 0, 0, 0}
+	pmt := []byte{0x00, 0x02, 0xb0, 0, 0x00, 0x01, 0xc1, 0x00, 0x00, 0xe1, 0x01, 0xf0, 0x00}
+	for pid := 0x101; pid < 0x110; pid++ {
+		if streamType, ok := streams[pid]; ok {
+			pmt = append(pmt, streamType, 0xe0|byte(pid>>8), byte(pid), 0xf0, 0x00)
+		}
+	}
+	pmt = append(pmt, 0, 0, 0, 0)
+	pmt[3] = byte(len(pmt) - 4)
+
+	segment := append(tsPacket(0, true, pat), tsPacket(0x100, true, pmt)...)
+	for pid := 0x101; pid < 0x110; pid++ {
+		if payload, ok := payloads[pid]; ok {
+			pes := append([]byte{0, 0, 1, 0xe0, 0, 0, 0x80, 0x80, 0x05, 0x21, 0, 1, 0, 1}, payload...)
+			segment = append(segment, tsPacket(pid, true, pes[:min(len(pes), 184)])...)
+		}
+	}
+	return segment
+}
+
+// adtsFrames builds n ADTS frames of 32 bytes
+func adtsFrames(n int) []byte {
+	var frames []byte
+	for i := 0; i < n; i++ {
+		frame := make([]byte, 32)
+		copy(frame, []byte{0xff, 0xf1, 0x50, 0x80, byte(32 >> 3), byte(32&7)<<5 | 0x1f, 0xfc})
+		frames = append(frames, frame...)
+	}
+	return frames
+}
+
+var (
+	h264ES = []byte{0, 0, 0, 1, 0x09, 0xf0, 0, 0, 0, 1, 0x67, 0x64, 0x00, 0x1f, 0xac, 0, 0, 1, 0x65, 0x88}
+	hevcES = []byte{0, 0, 0, 1, 0x46, 0x01, 0x50, 0, 0, 0, 1, 0x40, 0x01, 0x0c, 0, 0, 1, 0x42, 0x01, 0x01, 0x01}
+)
+
+// box builds an ISOBMFF box
+func box(boxType string, children ...[]byte) []byte {
+	var payload []byte
+	for _, child := range children {
+		payload = append(payload, child...)
+	}
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(8+len(payload)))
+	copy(header[4:], boxType)
+	return append(header, payload...)
+}
+
+// fmp4Init builds an initialization segment with one sample entry
+func fmp4Init(entry []byte) []byte {
+	stsd := box("stsd", make([]byte, 4), []byte{0, 0, 0, 1}, entry)
+	return append(box("ftyp", []byte("iso6")), box("moov", box("trak", box("mdia", box("minf", box("stbl", stsd)))))...)
+}
+
+// avc1Entry builds an avc1 sample entry with 4-byte NAL lengths
+func avc1Entry(entryType string, extra ...[]byte) []byte {
+	children := [][]byte{make([]byte, 78), box("avcC", []byte{1, 0x64, 0, 0x1f, 0xff})}
+	return box(entryType, append(children, extra...)...)
+}
+
+func TestScanBitstream(t *testing.T) {
+	mp4a := box("mp4a", make([]byte, 28), box("esds", make([]byte, 4)))
+	validSample := box("mdat", []byte{0, 0, 0, 4, 0x65, 0x88, 0x80, 0x40})
+	invalidSample := box("mdat", []byte{0x47, 0x40, 0x00, 0x10, 0x00, 0x00})
+
+	tests := []struct {
+		name      string
+		data      []byte
+		container string
+		detected  []string
+		problem   string
+	}{
+		{
+			name:      "TS with H.264 and AAC",
+			data:      tsSegment(map[int]byte{0x101: 0x1b, 0x102: 0x0f}, map[int][]byte{0x101: h264ES, 0x102: adtsFrames(3)}),
+			container: "mpegts",
+			detected:  []string{"h264", "aac"},
+		},
+		{
+			name:      "TS with HEVC",
+			data:      tsSegment(map[int]byte{0x101: 0x24}, map[int][]byte{0x101: hevcES}),
+			container: "mpegts",
+			detected:  []string{"hevc"},
+		},
+		{
+			name:      "TS stream type without matching bytes",
+			data:      tsSegment(map[int]byte{0x101: 0x24, 0x102: 0x0f}, map[int][]byte{0x101: h264ES, 0x102: make([]byte, 64)}),
+			container: "mpegts",
+			detected:  []string{"h264"},
+		},
+		{
+			name:      "TS SAMPLE-AES E-AC-3",
+			data:      tsSegment(map[int]byte{0x102: 0xc2}, map[int][]byte{0x102: {0x0b, 0x77, 0x01, 0xff, 0x3f, 0x86, 0xff}}),
+			container: "mpegts",
+			detected:  []string{"eac3"},
+		},
+		{
+			name:      "TS without PMT",
+			data:      tsPacket(0x101, true, h264ES),
+			container: "mpegts",
+			problem:   "no PMT found",
+		},
+		{
+			name:      "fMP4 H.264 with valid sample",
+			data:      append(fmp4Init(avc1Entry("avc1")), append(box("moof"), validSample...)...),
+			container: "fmp4",
+			detected:  []string{"h264"},
+		},
+		{
+			name:      "fMP4 H.264 with invalid sample",
+			data:      append(fmp4Init(avc1Entry("avc1")), append(box("moof"), invalidSample...)...),
+			container: "fmp4",
+			problem:   "first sample is not a length-prefixed h264 NAL unit",
+		},
+		{
+			name:      "fMP4 encrypted",
+			data:      fmp4Init(avc1Entry("encv", box("sinf", box("frma", []byte("hvc1"))))),
+			container: "fmp4",
+			detected:  []string{"hevc"},
+		},
+		{
+			name:      "fMP4 AAC",
+			data:      fmp4Init(mp4a),
+			container: "fmp4",
+			detected:  []string{"aac"},
+		},
+		{
+			name:      "fMP4 media segment without init",
+			data:      append(box("moof"), validSample...),
+			container: "fmp4",
+			problem:   "no initialization segment",
+		},
+		{
+			name:      "WebM",
+			data:      append([]byte{0x1a, 0x45, 0xdf, 0xa3}, []byte("\x86\x85V_VP9\x86\x86A_OPUS")...),
+			container: "webm",
+			detected:  []string{"vp9", "opus"},
+		},
+		{
+			name:      "packed audio with ID3",
+			data:      append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 2, 0, 0}, adtsFrames(2)...),
+			container: "packed_audio",
+			detected:  []string{"aac"},
+		},
+		{
+			name:    "unknown",
+			data:    []byte("<html>Access denied</html>"),
+			problem: "unrecognized container",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan := scanBitstream(tt.data)
+			if scan.container != tt.container {
+				t.Errorf("Expected container %q, got %q", tt.container, scan.container)
+			}
+			if !reflect.DeepEqual(scan.detected, tt.detected) {
+				t.Errorf("Expected detected %v, got %v", tt.detected, scan.detected)
+			}
+			if scan.problem != tt.problem {
+				t.Errorf("Expected problem %q, got %q", tt.problem, scan.problem)
+			}
+		})
+	}
+}
+
+func TestHLSFirstSegmentURLs(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:6.0,
+seg-1.m4s
+#EXTINF:6.0,
+seg-2.m4s`
+
+	urls := hlsFirstSegmentURLs(playlist, "https://example.com/v/720p.m3u8")
+	expected := []string{"https://example.com/v/init.mp4", "https://example.com/v/seg-1.m4s"}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("Expected %v, got %v", expected, urls)
+	}
+
+	if urls := hlsFirstSegmentURLs("#EXTM3U\n#EXT-X-ENDLIST\n", "https://example.com/v/720p.m3u8"); urls != nil {
+		t.Errorf("Expected no URLs for an empty playlist, got %v", urls)
+	}
+}
+
+func TestCheckBitstreams(t *testing.T) {
+	segment := tsSegment(map[int]byte{0x101: 0x1b, 0x102: 0x0f}, map[int][]byte{0x101: h264ES, 0x102: adtsFrames(3)})
+	hevcSegment := tsSegment(map[int]byte{0x101: 0x24, 0x102: 0x0f}, map[int][]byte{0x101: hevcES, 0x102: adtsFrames(3)})
+
+	var mu sync.Mutex
+	var ranges []string
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ranges = append(ranges, r.Header.Get("Range"))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2"
+1080p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=500000,RESOLUTION=640x360,CODECS="avc1.4d401e,mp4a.40.2"
+360p.m3u8
+`))
+		case "/720p.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXTINF:6.0,\nseg1.ts?token=secret\n"))
+		case "/1080p.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXTINF:6.0,\nhevc.ts\n"))
+		case "/360p.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"key\"\n#EXTINF:6.0,\nseg1.ts\n"))
+		case "/seg1.ts":
+			record(r)
+			w.Write(segment)
+		case "/hevc.ts":
+			record(r)
+			w.Write(hevcSegment)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	opts := &ProbeOptions{DisableCamouflage: true, DeepProbe: &DeepProbe{SegmentBytes: 4096}, Deterministic: true}
+	output, err := NewProber(opts).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(output.Bitstream) != 6 {
+		t.Fatalf("Expected 6 bitstream checks, got %+v", output.Bitstream)
+	}
+
+	first := output.Bitstream[0]
+	if first.StreamID != "0:0" || first.Container != "mpegts" || !first.Match || first.URI != server.URL+"/seg1.ts" {
+		t.Errorf("Unexpected check %+v", first)
+	}
+	if !reflect.DeepEqual(first.Detected, []string{"h264", "aac"}) {
+		t.Errorf("Expected [h264 aac], got %v", first.Detected)
+	}
+
+	mismatch := output.Bitstream[2]
+	if mismatch.Declared != "h264" || mismatch.Match || !reflect.DeepEqual(mismatch.Detected, []string{"hevc", "aac"}) {
+		t.Errorf("Expected an H.264/HEVC mismatch, got %+v", mismatch)
+	}
+	if !output.Bitstream[3].Match {
+		t.Errorf("Expected the 1080p audio to match, got %+v", output.Bitstream[3])
+	}
+
+	encrypted := output.Bitstream[4]
+	if encrypted.Error != "segment is encrypted with AES-128" || encrypted.Match {
+		t.Errorf("Expected an encrypted segment error, got %+v", encrypted)
+	}
+
+	if len(output.Warnings) != 1 || output.Warnings[0] != "stream 0:2 declares h264 but its first segment contains hevc, aac" {
+		t.Errorf("Expected a single mismatch warning, got %v", output.Warnings)
+	}
+
+	if expected := "bytes=0-4095,bytes=0-4095"; strings.Join(ranges, ",") != expected {
+		t.Errorf("Expected one bounded Range request per segment %q, got %q", expected, strings.Join(ranges, ","))
+	}
+}
+
+func TestCheckStream(t *testing.T) {
+	segment := tsSegment(map[int]byte{0x101: 0x24, 0x102: 0x0f}, map[int][]byte{0x101: hevcES, 0x102: adtsFrames(3)})
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2"
+720p.m3u8
+`))
+		case "/720p.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXTINF:6.0,\nseg1.ts\n"))
+		case "/seg1.ts":
+			requests++
+			w.Write(segment)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{DisableCamouflage: true})
+	output, err := prober.Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requests != 0 {
+		t.Fatalf("Expected no segment request without DeepProbe, got %d", requests)
+	}
+
+	check, err := prober.CheckStream(context.Background(), output, "0:0", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if check.Container != "mpegts" || check.Match || check.Declared != "h264" || check.URI != server.URL+"/seg1.ts" {
+		t.Errorf("Expected an H.264/HEVC mismatch, got %+v", check)
+	}
+	if !reflect.DeepEqual(check.Detected, []string{"hevc", "aac"}) {
+		t.Errorf("Expected [hevc aac], got %v", check.Detected)
+	}
+	if len(output.Warnings) != 0 {
+		t.Errorf("Expected the output to be unchanged, got warnings %v", output.Warnings)
+	}
+
+	if _, err := prober.CheckStream(context.Background(), output, "9:9", nil); err == nil {
+		t.Errorf("Expected an error for an unknown stream")
+	}
+}
+
+func TestCheckBitstreamsIncomplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2"
+720p.m3u8
+`))
+		case "/720p.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXTINF:6.0,\nmissing.ts\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		cancel  bool
+		partial bool
+	}{
+		{name: "failed check", cancel: false, partial: false},
+		{name: "deadline", cancel: true, partial: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober := NewProber(&ProbeOptions{DisableCamouflage: true})
+			output, err := prober.Probe(context.Background(), server.URL+"/master.m3u8")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+			prober.checkBitstreams(ctx, &DeepProbe{}, output)
+
+			if len(output.Bitstream) != 2 {
+				t.Fatalf("Expected 2 bitstream checks, got %+v", output.Bitstream)
+			}
+			if output.Partial != tt.partial {
+				t.Errorf("Expected partial %v, got %v", tt.partial, output.Partial)
+			}
+			if !tt.partial {
+				if len(output.Incomplete) != 0 {
+					t.Errorf("Expected no incomplete checks, got %+v", output.Incomplete)
+				}
+				return
+			}
+			if len(output.Incomplete) != 2 {
+				t.Fatalf("Expected 2 incomplete checks, got %+v", output.Incomplete)
+			}
+			for i, check := range output.Incomplete {
+				if check.Check != CheckBitstream || check.StreamID != output.Bitstream[i].StreamID || check.Error == "" {
+					t.Errorf("Unexpected incomplete check %+v", check)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package probe
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStaleFactor is the number of expected update periods without a
+// change after which a watched manifest is reported stale
+const defaultStaleFactor = 3
+
+// ManifestTiming holds the live update timing declared by a manifest
+type ManifestTiming struct {
+	// PublishTime is MPD@publishTime
+	PublishTime string `json:"publish_time,omitempty"`
+
+	// MinimumUpdatePeriod is MPD@minimumUpdatePeriod in seconds
+	MinimumUpdatePeriod float64 `json:"minimum_update_period,omitempty"`
+
+	// MediaSequence is the HLS EXT-X-MEDIA-SEQUENCE
+	MediaSequence int64 `json:"media_sequence,omitempty"`
+
+	// TargetDuration is the HLS EXT-X-TARGETDURATION in seconds
+	TargetDuration float64 `json:"target_duration,omitempty"`
+}
+
+// expectedUpdatePeriod returns how often the manifest should change (0 = unknown)
+func (t *ManifestTiming) expectedUpdatePeriod() time.Duration {
+	if t == nil {
+		return 0
+	}
+	if t.MinimumUpdatePeriod > 0 {
+		return time.Duration(t.MinimumUpdatePeriod * float64(time.Second))
+	}
+	return time.Duration(t.TargetDuration * float64(time.Second))
+}
+
+// Cadence describes how often a watched manifest changes
+type Cadence struct {
+	// Updates is the number of content changes observed
+	Updates int `json:"updates"`
+
+	// LastUpdate is when the content last changed
+	LastUpdate time.Time `json:"last_update"`
+
+	// Interval is the time between the last two changes
+	Interval time.Duration `json:"interval,omitempty"`
+
+	// MeanInterval is the mean time between changes
+	MeanInterval time.Duration `json:"mean_interval,omitempty"`
+
+	// PublishTimeDelta is how far MPD@publishTime advanced with the last change
+	PublishTimeDelta time.Duration `json:"publish_time_delta,omitempty"`
+
+	// SequenceRate is the HLS media sequence advancement per second with the last change
+	SequenceRate float64 `json:"sequence_rate,omitempty"`
+
+	// Expected is the declared update period (minimumUpdatePeriod or target duration)
+	Expected time.Duration `json:"expected,omitempty"`
+
+	// SinceUpdate is the time since the last change when the cadence was reported
+	SinceUpdate time.Duration `json:"since_update,omitempty"`
+}
+
+// cadenceTracker accumulates update cadence across observations
+type cadenceTracker struct {
+	cadence     Cadence
+	first       time.Time
+	timing      *ManifestTiming
+	staleFactor float64
+	stale       bool
+}
+
+// update records a content change observed at now
+func (c *cadenceTracker) update(now time.Time, timing *ManifestTiming) Cadence {
+	if c.cadence.Updates == 0 {
+		c.first = now
+	} else {
+		c.cadence.Interval = now.Sub(c.cadence.LastUpdate)
+		c.cadence.MeanInterval = now.Sub(c.first) / time.Duration(c.cadence.Updates)
+	}
+
+	c.cadence.PublishTimeDelta = 0
+	c.cadence.SequenceRate = 0
+	if c.timing != nil && timing != nil {
+		previous, errPrevious := time.Parse(time.RFC3339, c.timing.PublishTime)
+		current, errCurrent := time.Parse(time.RFC3339, timing.PublishTime)
+		if errPrevious == nil && errCurrent == nil {
+			c.cadence.PublishTimeDelta = current.Sub(previous)
+		}
+		if c.cadence.Interval > 0 {
+			c.cadence.SequenceRate = float64(timing.MediaSequence-c.timing.MediaSequence) / c.cadence.Interval.Seconds()
+		}
+	}
+
+	c.cadence.Updates++
+	c.cadence.LastUpdate = now
+	c.cadence.Expected = timing.expectedUpdatePeriod()
+	c.cadence.SinceUpdate = 0
+	c.timing = timing
+	c.stale = false
+	return c.cadence
+}
+
+// restore resumes tracking from a persisted cadence
+func (c *cadenceTracker) restore(cadence *Cadence, timing *ManifestTiming) {
+	if cadence == nil {
+		return
+	}
+	c.cadence = *cadence
+	c.first = cadence.LastUpdate.Add(-cadence.MeanInterval * time.Duration(cadence.Updates-1))
+	c.timing = timing
+}
+
+// checkStale reports the cadence the first time the manifest has gone
+// unchanged for more than staleFactor expected update periods
+func (c *cadenceTracker) checkStale(now time.Time) (Cadence, bool) {
+	if c.stale || c.cadence.Updates == 0 || c.cadence.Expected <= 0 {
+		return Cadence{}, false
+	}
+
+	factor := c.staleFactor
+	if factor <= 0 {
+		factor = defaultStaleFactor
+	}
+
+	since := now.Sub(c.cadence.LastUpdate)
+	if since <= time.Duration(float64(c.cadence.Expected)*factor) {
+		return Cadence{}, false
+	}
+
+	c.stale = true
+	cadence := c.cadence
+	cadence.SinceUpdate = since
+	return cadence, true
+}
+
+// parseHLSTiming extracts the media playlist timing tags (nil if absent)
+func parseHLSTiming(content string) *ManifestTiming {
+	var parser hlsTimingParser
+	for lines := scanLines(content); lines.scan(); {
+		parser.parseLine(strings.TrimSpace(lines.line))
+	}
+	return parser.timing
+}
+
+// hlsTimingParser collects the timing tags of the lines of a playlist
+type hlsTimingParser struct {
+	timing *ManifestTiming
+}
+
+// parseLine parses a line with surrounding whitespace removed
+func (p *hlsTimingParser) parseLine(line string) {
+	switch {
+	case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+		if sequence, err := strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64); err == nil {
+			if p.timing == nil {
+				p.timing = &ManifestTiming{}
+			}
+			p.timing.MediaSequence = sequence
+		}
+	case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+		if duration, err := strconv.ParseFloat(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"), 64); err == nil {
+			if p.timing == nil {
+				p.timing = &ManifestTiming{}
+			}
+			p.timing.TargetDuration = duration
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package probe
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func cadenceTestMPD(publishTime string) string {
+	return fmt.Sprintf(`<?xml version="1.0"?>
+<MPD type="dynamic" minimumUpdatePeriod="PT2S" publishTime="%s">
+  <Period id="1">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <Representation id="v1" width="1280" height="720" codecs="avc1.64001f"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`, publishTime)
+}
+
+func TestWatchStateCadence(t *testing.T) {
+	state := newWatchState("https://example.com/live.mpd")
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, publishTime := range []string{"2026-01-01T12:00:00Z", "2026-01-01T12:00:02Z"} {
+		body := cadenceTestMPD(publishTime)
+		output, err := parseMPDManifest(body, state.url)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		state.update(start.Add(time.Duration(i)*2*time.Second), body, output)
+	}
+
+	cadence := state.cadence.cadence
+	if cadence.Updates != 2 || cadence.Interval != 2*time.Second || cadence.PublishTimeDelta != 2*time.Second || cadence.Expected != 2*time.Second {
+		t.Errorf("Unexpected cadence %+v", cadence)
+	}
+
+	tests := []struct {
+		offset   time.Duration
+		expected bool
+	}{
+		{5 * time.Second, false},  // 3s since update, within 3 x 2s
+		{9 * time.Second, true},   // 7s since update: frozen
+		{10 * time.Second, false}, // reported once
+	}
+
+	for _, tt := range tests {
+		events := state.unchanged(start.Add(tt.offset))
+		stale := len(events) == 1 && events[0].Type == EventManifestStale
+		if stale != tt.expected {
+			t.Errorf("At %v: expected stale %v, got events %+v", tt.offset, tt.expected, events)
+		}
+	}
+}
+
+func TestWatchStateSequenceRate(t *testing.T) {
+	state := newWatchState("https://example.com/720p.m3u8")
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, sequence := range []int{10, 13} {
+		body := fmt.Sprintf("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:%d\n#EXTINF:2.0,\nseg%d.ts\n", sequence, sequence)
+		output, err := parseHLSManifest(body, state.url)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		state.update(start.Add(time.Duration(i)*6*time.Second), body, output)
+	}
+
+	cadence := state.cadence.cadence
+	if cadence.SequenceRate != 0.5 {
+		t.Errorf("Expected sequence rate 0.5, got %v", cadence.SequenceRate)
+	}
+	if cadence.Expected != 2*time.Second {
+		t.Errorf("Expected target duration 2s, got %v", cadence.Expected)
+	}
+}
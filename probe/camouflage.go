@@ -0,0 +1,78 @@
+package probe
+
+import "fmt"
+
+// defaultAcceptLanguage is the Accept-Language header sent with camouflage
+const defaultAcceptLanguage = "en-US,en;q=0.9,fr;q=0.8"
+
+// CamouflagePolicy controls the individual browser-like headers sent when
+// camouflage is enabled. The zero value sends the default set of headers.
+type CamouflagePolicy struct {
+	// DisableOrigin stops sending the Origin header
+	DisableOrigin bool
+
+	// DisableReferer stops sending the Referer header
+	DisableReferer bool
+
+	// DisableDNT stops sending the DNT header
+	DisableDNT bool
+
+	// DisableAcceptLanguage stops sending the Accept-Language header
+	DisableAcceptLanguage bool
+
+	// EnableSecFetch sends Sec-Fetch-Dest/Mode/Site headers like a browser player
+	EnableSecFetch bool
+
+	// Origin overrides the Origin value (default: scheme and host of the manifest URL)
+	Origin string
+
+	// Referer overrides the Referer value (default: Origin + "/")
+	Referer string
+
+	// AcceptLanguage overrides the Accept-Language value
+	AcceptLanguage string
+}
+
+// camouflageHeaders returns the browser-like headers for a target origin
+func camouflageHeaders(scheme, host string, policy *CamouflagePolicy) map[string]string {
+	if policy == nil {
+		policy = &CamouflagePolicy{}
+	}
+
+	origin := policy.Origin
+	if origin == "" {
+		origin = fmt.Sprintf("%s://%s", scheme, host)
+	}
+	referer := policy.Referer
+	if referer == "" {
+		referer = origin + "/"
+	}
+	acceptLanguage := policy.AcceptLanguage
+	if acceptLanguage == "" {
+		acceptLanguage = defaultAcceptLanguage
+	}
+
+	headers := map[string]string{
+		"Accept":                    defaultAcceptHeader,
+		"Connection":                "keep-alive",
+		"Upgrade-Insecure-Requests": "1",
+	}
+	if !policy.DisableAcceptLanguage {
+		headers["Accept-Language"] = acceptLanguage
+	}
+	if !policy.DisableOrigin {
+		headers["Origin"] = origin
+	}
+	if !policy.DisableReferer {
+		headers["Referer"] = referer
+	}
+	if !policy.DisableDNT {
+		headers["DNT"] = "1"
+	}
+	if policy.EnableSecFetch {
+		headers["Sec-Fetch-Dest"] = "empty"
+		headers["Sec-Fetch-Mode"] = "cors"
+		headers["Sec-Fetch-Site"] = "cross-site"
+	}
+	return headers
+}
@@ -0,0 +1,230 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// cassetteDroppedHeaders are the response headers not recorded in cassettes,
+// which are meant to be shared
+var cassetteDroppedHeaders = []string{
+	"Set-Cookie",
+	"Set-Cookie2",
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Api-Key",
+}
+
+// Interaction is an HTTP request of a probe and its response, as recorded
+// in a Cassette
+type Interaction struct {
+	Method string `json:"method"`
+
+	// URL is the redacted request URL, so that cassettes can be shared and
+	// replayed after credentials rotated
+	URL string `json:"url"`
+
+	// Range is the Range request header (sniffing and segment probes)
+	Range string `json:"range,omitempty"`
+
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+
+	// Body is the part of the response body the probe read
+	Body []byte `json:"body,omitempty"`
+
+	// Error is the transport error of a request without a response
+	Error string `json:"error,omitempty"`
+}
+
+// key matches a request to its recorded interactions
+func (i *Interaction) key() string {
+	return i.Method + " " + i.URL + " " + i.Range
+}
+
+// Cassette records the HTTP interactions of probes, or replays recorded
+// interactions instead of sending requests, to reproduce a probe offline
+// (e.g. from a customer's bug report). Create one with NewCassette to
+// record and Save it, or with LoadCassette to replay.
+type Cassette struct {
+	mu           sync.Mutex
+	replay       bool
+	interactions []*Interaction
+	next         map[string]int
+}
+
+// cassetteFile is the JSON file of a cassette
+type cassetteFile struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// NewCassette returns an empty cassette recording the interactions of the
+// probes using it
+func NewCassette() *Cassette {
+	return &Cassette{}
+}
+
+// LoadCassette reads a cassette saved by Cassette.Save for replay
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid cassette %s: %v", path, err))
+	}
+	return &Cassette{replay: true, interactions: file.Interactions, next: make(map[string]int)}, nil
+}
+
+// Replaying reports whether the cassette replays interactions instead of
+// recording them
+func (c *Cassette) Replaying() bool {
+	return c.replay
+}
+
+// Interactions returns the recorded interactions, in request order
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	interactions := make([]Interaction, len(c.interactions))
+	for i, interaction := range c.interactions {
+		interactions[i] = *interaction
+	}
+	return interactions
+}
+
+// Save writes the interactions to path as JSON, readable by the owner only
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0o600)
+}
+
+// wrapper returns the transport middleware recording or replaying requests,
+// with URLs redacted by redactor (the default redaction when nil, since
+// cassettes are meant to be shared)
+func (c *Cassette) wrapper(redactor *redactor) func(http.RoundTripper) http.RoundTripper {
+	if redactor == nil {
+		redactor = newRedactor(nil)
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(request *http.Request) (*http.Response, error) {
+			if c.replay {
+				return c.play(request, redactor)
+			}
+			return c.record(next, request, redactor)
+		})
+	}
+}
+
+// record sends request and appends its interaction, with the response body
+// captured as the probe reads it
+func (c *Cassette) record(next http.RoundTripper, request *http.Request, redactor *redactor) (*http.Response, error) {
+	interaction := &Interaction{
+		Method: request.Method,
+		URL:    redactor.redactString(request.URL.String()),
+		Range:  request.Header.Get("Range"),
+	}
+	c.mu.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.mu.Unlock()
+
+	response, err := next.RoundTrip(request)
+	if err != nil {
+		c.mu.Lock()
+		interaction.Error = redactor.redactString(err.Error())
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	header := response.Header.Clone()
+	for _, name := range cassetteDroppedHeaders {
+		header.Del(name)
+	}
+	c.mu.Lock()
+	interaction.Status = response.StatusCode
+	interaction.Header = header
+	c.mu.Unlock()
+	response.Body = &recordingBody{ReadCloser: response.Body, cassette: c, interaction: interaction}
+	return response, nil
+}
+
+// play returns the next recorded response of request
+func (c *Cassette) play(request *http.Request, redactor *redactor) (*http.Response, error) {
+	key := (&Interaction{
+		Method: request.Method,
+		URL:    redactor.redactString(request.URL.String()),
+		Range:  request.Header.Get("Range"),
+	}).key()
+
+	c.mu.Lock()
+	var interaction *Interaction
+	for i := c.next[key]; i < len(c.interactions); i++ {
+		if c.interactions[i].key() == key {
+			interaction = c.interactions[i]
+			c.next[key] = i + 1
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if interaction == nil {
+		return nil, fmt.Errorf("cassette has no recorded response for %s %s", request.Method, redactor.redactString(request.URL.String()))
+	}
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(interaction.Body)),
+		ContentLength: int64(len(interaction.Body)),
+		Request:       request,
+	}, nil
+}
+
+// recordingBody copies the bytes read from a response body into its
+// interaction
+type recordingBody struct {
+	io.ReadCloser
+	cassette    *Cassette
+	interaction *Interaction
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.cassette.mu.Lock()
+		b.interaction.Body = append(b.interaction.Body, p[:n]...)
+		b.cassette.mu.Unlock()
+	}
+	return n, err
+}
+
+// roundTripFunc is an http.RoundTripper function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
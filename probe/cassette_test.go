@@ -0,0 +1,140 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	server := manifestServer(watchTestManifest)
+
+	recorder := NewCassette()
+	recorded, err := NewProber(&ProbeOptions{Cassette: recorder}).Probe(context.Background(), server.URL+"/manifest.mpd")
+	server.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	interactions := recorder.Interactions()
+	if len(interactions) != 1 {
+		t.Fatalf("Expected 1 interaction, got %d", len(interactions))
+	}
+	if interactions[0].Method != "GET" || interactions[0].Status != 200 {
+		t.Errorf("Expected a recorded GET with status 200, got %+v", interactions[0])
+	}
+	if got := string(interactions[0].Body); got != watchTestManifest {
+		t.Errorf("Expected the manifest body to be recorded, got %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "probe.cassette")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	player, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !player.Replaying() {
+		t.Error("Expected a loaded cassette to replay")
+	}
+
+	// The server is closed: every response comes from the cassette
+	replayed, err := NewProber(&ProbeOptions{Cassette: player}).Probe(context.Background(), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(recorded.Streams, replayed.Streams) {
+		t.Errorf("Expected replayed streams %+v, got %+v", recorded.Streams, replayed.Streams)
+	}
+
+	_, err = NewProber(&ProbeOptions{Cassette: player}).Probe(context.Background(), server.URL+"/other.mpd")
+	if err == nil || !strings.Contains(err.Error(), "cassette has no recorded response") {
+		t.Errorf("Expected a missing interaction error, got %v", err)
+	}
+}
+
+func TestCassetteRedaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-secret"})
+		w.Write([]byte(watchTestManifest))
+	}))
+
+	recorder := NewCassette()
+	_, err := NewProber(&ProbeOptions{Cassette: recorder}).Probe(context.Background(), server.URL+"/master.m3u8?token=secret")
+	server.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "probe.cassette")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected no token or cookie in the cassette, got %s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	// The token rotated since the recording
+	player, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := NewProber(&ProbeOptions{Cassette: player}).Probe(context.Background(), server.URL+"/master.m3u8?token=rotated"); err != nil {
+		t.Errorf("Expected the redacted interaction to replay, got %v", err)
+	}
+}
+
+func TestCassetteRecordsErrors(t *testing.T) {
+	server := manifestServer("")
+	server.Close()
+
+	recorder := NewCassette()
+	if _, err := NewProber(&ProbeOptions{Cassette: recorder}).Probe(context.Background(), server.URL+"/manifest.mpd"); err == nil {
+		t.Fatal("Expected an error for a closed server")
+	}
+	interactions := recorder.Interactions()
+	if len(interactions) == 0 || interactions[0].Error == "" {
+		t.Fatalf("Expected a recorded transport error, got %+v", interactions)
+	}
+
+	player := &Cassette{replay: true, interactions: []*Interaction{&interactions[0]}, next: make(map[string]int)}
+	_, err := NewProber(&ProbeOptions{Cassette: player}).Probe(context.Background(), server.URL+"/manifest.mpd")
+	if err == nil || !strings.Contains(err.Error(), interactions[0].Error) {
+		t.Errorf("Expected the recorded error %q, got %v", interactions[0].Error, err)
+	}
+}
+
+func TestLoadCassette(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "empty.cassette")
+	if err := NewCassette().Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := LoadCassette(path); err != nil {
+		t.Errorf("Expected an empty cassette to load, got %v", err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.cassette")
+	if err := os.WriteFile(invalid, []byte("not json"), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := LoadCassette(invalid); err == nil {
+		t.Error("Expected an error for an invalid cassette")
+	}
+	if _, err := LoadCassette(filepath.Join(t.TempDir(), "missing.cassette")); err == nil {
+		t.Error("Expected an error for a missing cassette")
+	}
+}
@@ -0,0 +1,202 @@
+package probe
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Cache results reported in CacheStatus.Result
+const (
+	// CacheHit is a response served from a CDN cache, including stale and
+	// revalidated responses and hits of a shield tier
+	CacheHit = "hit"
+	// CacheMiss is a response the CDN fetched from the origin, or passed through
+	CacheMiss = "miss"
+)
+
+// cacheStatusHeaders are the response headers in which CDNs and caches
+// report whether a response was a hit, in order of preference
+var cacheStatusHeaders = []string{
+	"Cache-Status",
+	"Cf-Cache-Status",
+	"X-Cache",
+	"X-Cache-Status",
+	"X-Proxy-Cache",
+}
+
+// CacheStatus is the CDN cache behavior reported by the manifest response
+type CacheStatus struct {
+	// Result is CacheHit or CacheMiss ("" when no header says)
+	Result string `json:"result,omitempty"`
+
+	// Header is the header Result was read from, with its value
+	// ("X-Cache: Hit from cloudfront")
+	Header string `json:"header,omitempty"`
+
+	// Age is the Age header: seconds since the cached response was fetched
+	// from the origin (nil when absent)
+	Age *int `json:"age,omitempty"`
+
+	// MaxAge is the TTL of the response in shared caches: the s-maxage of
+	// Cache-Control, else its max-age (nil when absent)
+	MaxAge *int `json:"max_age,omitempty"`
+
+	// CacheControl is the Cache-Control header
+	CacheControl string `json:"cache_control,omitempty"`
+}
+
+// parseCacheStatus reads the cache headers of a response (nil without any)
+func parseCacheStatus(header http.Header) *CacheStatus {
+	status := &CacheStatus{CacheControl: header.Get("Cache-Control")}
+
+	for _, name := range cacheStatusHeaders {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		if result := cacheResult(name, value); result != "" {
+			status.Result = result
+			status.Header = name + ": " + value
+			break
+		}
+	}
+
+	if age, err := strconv.Atoi(strings.TrimSpace(header.Get("Age"))); err == nil && age >= 0 {
+		status.Age = &age
+	}
+	status.MaxAge = cacheMaxAge(status.CacheControl)
+
+	if status.Result == "" && status.Age == nil && status.MaxAge == nil && status.CacheControl == "" {
+		return nil
+	}
+	return status
+}
+
+// cacheResult classifies the value of a cache status header. Multi-tier
+// values ("MISS, HIT" from a shield and an edge) are a hit when any tier
+// hit, since the origin was not asked.
+func cacheResult(name, value string) string {
+	value = strings.ToUpper(value)
+
+	// RFC 9211: "cache; hit" or "cache; fwd=uri-miss"
+	if name == "Cache-Status" {
+		switch {
+		case strings.Contains(value, "; HIT") || strings.Contains(value, ";HIT"):
+			return CacheHit
+		case strings.Contains(value, "FWD="):
+			return CacheMiss
+		}
+		return ""
+	}
+
+	switch {
+	case strings.Contains(value, "HIT"), strings.Contains(value, "STALE"),
+		strings.Contains(value, "UPDATING"), strings.Contains(value, "REVALIDATED"):
+		return CacheHit
+	case strings.Contains(value, "MISS"), strings.Contains(value, "EXPIRED"),
+		strings.Contains(value, "BYPASS"), strings.Contains(value, "DYNAMIC"),
+		strings.Contains(value, "PASS"):
+		return CacheMiss
+	}
+	return ""
+}
+
+// cacheMaxAge returns the s-maxage of a Cache-Control value, else its max-age
+func cacheMaxAge(cacheControl string) *int {
+	var maxAge, sharedMaxAge *int
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		seconds, err := strconv.Atoi(strings.Trim(strings.TrimSpace(value), `"`))
+		if err != nil || seconds < 0 {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			maxAge = &seconds
+		case "s-maxage":
+			sharedMaxAge = &seconds
+		}
+	}
+	if sharedMaxAge != nil {
+		return sharedMaxAge
+	}
+	return maxAge
+}
+
+// CacheStats aggregates the cache behavior of repeated manifest fetches
+type CacheStats struct {
+	// Responses is the number of responses observed, Hits and Misses those
+	// reporting a cache result
+	Responses int `json:"responses"`
+	Hits      int `json:"hits"`
+	Misses    int `json:"misses"`
+
+	// HitRatio is Hits / (Hits + Misses) (0 when no response reported one)
+	HitRatio float64 `json:"hit_ratio"`
+
+	// MaxAge is the TTL declared by the latest response (see CacheStatus.MaxAge)
+	MaxAge *int `json:"max_age,omitempty"`
+
+	// MaxObservedAge is the highest Age observed: how long the CDN actually
+	// keeps the manifest, to compare with MaxAge
+	MaxObservedAge *int `json:"max_observed_age,omitempty"`
+
+	// Refreshes counts the times the Age dropped between consecutive responses
+	// of a URL: the CDN fetched the manifest from the origin again
+	Refreshes int `json:"refreshes,omitempty"`
+
+	// StaleResponses counts responses older than their declared TTL (Age over MaxAge)
+	StaleResponses int `json:"stale_responses,omitempty"`
+}
+
+// observe adds a response to the stats; previous is the cache status of the
+// previous response of the same URL (nil for the first)
+func (s *CacheStats) observe(status, previous *CacheStatus) {
+	s.Responses++
+	if status == nil {
+		return
+	}
+
+	switch status.Result {
+	case CacheHit:
+		s.Hits++
+	case CacheMiss:
+		s.Misses++
+	}
+	if s.Hits+s.Misses > 0 {
+		s.HitRatio = float64(s.Hits) / float64(s.Hits+s.Misses)
+	}
+
+	if status.MaxAge != nil {
+		maxAge := *status.MaxAge
+		s.MaxAge = &maxAge
+	}
+	if status.Age == nil {
+		return
+	}
+	age := *status.Age
+	if s.MaxObservedAge == nil || age > *s.MaxObservedAge {
+		s.MaxObservedAge = &age
+	}
+	if previous != nil && previous.Age != nil && age < *previous.Age {
+		s.Refreshes++
+	}
+	if status.MaxAge != nil && age > *status.MaxAge {
+		s.StaleResponses++
+	}
+}
+
+// clone returns a copy of s that shares no pointers with it
+func (s *CacheStats) clone() *CacheStats {
+	c := *s
+	if s.MaxAge != nil {
+		maxAge := *s.MaxAge
+		c.MaxAge = &maxAge
+	}
+	if s.MaxObservedAge != nil {
+		age := *s.MaxObservedAge
+		c.MaxObservedAge = &age
+	}
+	return &c
+}
@@ -0,0 +1,192 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestParseCacheStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected *CacheStatus
+	}{
+		{
+			name:   "cloudfront hit",
+			header: http.Header{"X-Cache": {"Hit from cloudfront"}, "Age": {"3"}, "Cache-Control": {"max-age=2, s-maxage=4"}},
+			expected: &CacheStatus{
+				Result: CacheHit, Header: "X-Cache: Hit from cloudfront",
+				Age: intPtr(3), MaxAge: intPtr(4), CacheControl: "max-age=2, s-maxage=4",
+			},
+		},
+		{
+			name:     "cloudflare expired",
+			header:   http.Header{"Cf-Cache-Status": {"EXPIRED"}},
+			expected: &CacheStatus{Result: CacheMiss, Header: "Cf-Cache-Status: EXPIRED"},
+		},
+		{
+			name:     "fastly shield hit",
+			header:   http.Header{"X-Cache": {"HIT, MISS"}},
+			expected: &CacheStatus{Result: CacheHit, Header: "X-Cache: HIT, MISS"},
+		},
+		{
+			name:     "rfc 9211",
+			header:   http.Header{"Cache-Status": {"ExampleCDN; fwd=uri-miss; stored"}, "X-Cache": {"HIT"}},
+			expected: &CacheStatus{Result: CacheMiss, Header: "Cache-Status: ExampleCDN; fwd=uri-miss; stored"},
+		},
+		{
+			name:     "age only",
+			header:   http.Header{"Age": {"0"}},
+			expected: &CacheStatus{Age: intPtr(0)},
+		},
+		{
+			name:     "unknown status",
+			header:   http.Header{"X-Cache": {"Error from cloudfront"}, "Cache-Control": {"no-cache"}},
+			expected: &CacheStatus{CacheControl: "no-cache"},
+		},
+		{
+			name:   "no cache headers",
+			header: http.Header{"Content-Type": {"application/dash+xml"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCacheStatus(tt.header); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCacheStatsObserve(t *testing.T) {
+	responses := []*CacheStatus{
+		{Result: CacheMiss, Age: intPtr(0), MaxAge: intPtr(6)},
+		{Result: CacheHit, Age: intPtr(4), MaxAge: intPtr(6)},
+		{Result: CacheHit, Age: intPtr(8), MaxAge: intPtr(6)},
+		nil,
+		{Result: CacheHit, Age: intPtr(1), MaxAge: intPtr(6)},
+	}
+
+	var stats CacheStats
+	var previous *CacheStatus
+	for _, status := range responses {
+		stats.observe(status, previous)
+		previous = status
+	}
+
+	expected := CacheStats{
+		Responses:      5,
+		Hits:           3,
+		Misses:         1,
+		HitRatio:       0.75,
+		MaxAge:         intPtr(6),
+		MaxObservedAge: intPtr(8),
+		StaleResponses: 1,
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+
+	// The Age drop is only a refresh between consecutive responses
+	stats.observe(&CacheStatus{Age: intPtr(0)}, &CacheStatus{Age: intPtr(5)})
+	if stats.Refreshes != 1 {
+		t.Errorf("Expected 1 refresh, got %d", stats.Refreshes)
+	}
+}
+
+func TestReportCacheByHost(t *testing.T) {
+	hit := &Output{Cache: &CacheStatus{Result: CacheHit, Age: intPtr(2)}}
+	miss := &Output{Cache: &CacheStatus{Result: CacheMiss, Age: intPtr(0)}}
+	report := NewReport([]BatchResult{
+		{URL: "https://a.example.com/1.mpd?token=secret", Output: hit},
+		{URL: "https://a.example.com/2.mpd", Output: miss},
+		{URL: "https://a.example.com/1.mpd?token=secret", Output: miss},
+		{URL: "https://b.example.com/1.mpd", Output: &Output{}},
+	})
+
+	byURL := map[string]CacheStats{
+		"https://a.example.com/1.mpd?token=REDACTED": {Responses: 2, Hits: 1, Misses: 1, HitRatio: 0.5, Refreshes: 1},
+		"https://a.example.com/2.mpd":                {Responses: 1, Misses: 1},
+	}
+	if len(report.CacheByURL) != len(byURL) {
+		t.Errorf("Expected cache stats of %d URLs, got %v", len(byURL), report.CacheByURL)
+	}
+	for manifestURL, expected := range byURL {
+		stats := report.CacheByURL[manifestURL]
+		if stats == nil || stats.Responses != expected.Responses || stats.Hits != expected.Hits ||
+			stats.Misses != expected.Misses || stats.HitRatio != expected.HitRatio || stats.Refreshes != expected.Refreshes {
+			t.Errorf("%s: Expected %+v, got %+v", manifestURL, expected, stats)
+		}
+	}
+
+	stats := report.CacheByHost["a.example.com"]
+	if stats == nil || stats.Responses != 3 || stats.Hits != 1 || stats.Misses != 2 || stats.Refreshes != 1 {
+		t.Errorf("Unexpected cache stats %+v", stats)
+	}
+	if _, ok := report.CacheByHost["b.example.com"]; ok {
+		t.Error("Expected no cache stats for a host without cache headers")
+	}
+}
+
+func TestMonitorCacheStats(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		w.Header().Set("Cache-Control", "max-age=10")
+		w.Header().Set("Age", strconv.Itoa(int(n-1)*5))
+		if n == 1 {
+			w.Header().Set("X-Cache", "MISS")
+		} else {
+			w.Header().Set("X-Cache", "HIT")
+		}
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	clock := NewManualClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	monitor, err := NewMonitor(&Config{Jobs: []Job{
+		{Name: "news", URL: server.URL + "/news.m3u8", Interval: "5s"},
+	}}, &MonitorOptions{Clock: clock})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		monitor.jobs[0].run(context.Background())
+	}
+
+	cache := monitor.Health()[0].Cache
+	expected := &CacheStats{
+		Responses:      4,
+		Hits:           3,
+		Misses:         1,
+		HitRatio:       0.75,
+		MaxAge:         intPtr(10),
+		MaxObservedAge: intPtr(15),
+		StaleResponses: 1,
+	}
+	if !reflect.DeepEqual(cache, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, cache)
+	}
+
+	metrics := monitor.metrics()
+	for _, line := range []string{
+		`goprobe_stream_cache_hit_ratio{stream="news"} 0.75`,
+		`goprobe_stream_cache_max_age_seconds{stream="news"} 15`,
+	} {
+		if !strings.Contains(metrics, line) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, metrics)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package probe
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for retries, circuit breakers, watches and
+// archiving. Replace it with a ManualClock to simulate time in tests.
+// Context deadlines are always measured against real time.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After returns a channel that receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+	// NewTicker returns a ticker that ticks every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker
+type Ticker interface {
+	// C returns the channel on which ticks are delivered
+	C() <-chan time.Time
+	// Stop turns off the ticker
+	Stop()
+}
+
+// Rand is the source of randomness for retry jitter. A *rand.Rand created
+// with a fixed seed makes jitter reproducible; calls are serialized, so it
+// does not need to be safe for concurrent use.
+type Rand interface {
+	Float64() float64
+}
+
+// systemClock is the Clock backed by the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+// systemTicker adapts time.Ticker to Ticker
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time { return t.ticker.C }
+func (t systemTicker) Stop()               { t.ticker.Stop() }
+
+// clockOrSystem returns clock, or the system clock when nil
+func clockOrSystem(clock Clock) Clock {
+	if clock == nil {
+		return systemClock{}
+	}
+	return clock
+}
+
+// globalRand is the Rand backed by the math/rand package source
+type globalRand struct{}
+
+func (globalRand) Float64() float64 { return rand.Float64() }
+
+// lockedRand serializes calls to a Rand
+type lockedRand struct {
+	mu     sync.Mutex
+	source Rand
+}
+
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.source.Float64()
+}
+
+// randOrGlobal returns a concurrency-safe wrapper of source, or the math/rand
+// source when nil
+func randOrGlobal(source Rand) Rand {
+	if source == nil {
+		return globalRand{}
+	}
+	return &lockedRand{source: source}
+}
+
+// ManualClock is a Clock that only moves when advanced, for deterministic tests.
+// Timers and tickers that become due fire during Advance. It is safe for
+// concurrent use.
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+// manualTimer is a pending After channel or ticker of a ManualClock
+type manualTimer struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewManualClock creates a manual clock set to start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the clock's current time
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by d
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &manualTimer{at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.ch <- c.now
+		return timer.ch
+	}
+	c.timers = append(c.timers, timer)
+	return timer.ch
+}
+
+// NewTicker returns a ticker that ticks every time the clock is advanced past
+// a multiple of d. Like time.Ticker, it drops ticks for slow receivers.
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for ManualClock.NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &manualTimer{at: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	return &manualTicker{clock: c, timer: timer}
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers that
+// become due
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].at.Before(c.timers[j].at)
+	})
+
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		for !timer.at.After(c.now) {
+			select {
+			case timer.ch <- timer.at:
+			default:
+			}
+			if timer.period == 0 {
+				break
+			}
+			timer.at = timer.at.Add(timer.period)
+		}
+		if timer.period > 0 || timer.at.After(c.now) {
+			pending = append(pending, timer)
+		}
+	}
+	c.timers = pending
+}
+
+// Waiters returns the number of pending After channels and active tickers,
+// which lets tests wait until the code under test is blocked on the clock
+func (c *ManualClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// stop removes timer from the clock
+func (c *ManualClock) stop(timer *manualTimer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, pending := range c.timers {
+		if pending == timer {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// manualTicker is a Ticker driven by a ManualClock
+type manualTicker struct {
+	clock *ManualClock
+	timer *manualTimer
+}
+
+func (t *manualTicker) C() <-chan time.Time { return t.timer.ch }
+func (t *manualTicker) Stop()               { t.clock.stop(t.timer) }
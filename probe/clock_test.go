@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fixedRand always returns the same value
+type fixedRand float64
+
+func (r fixedRand) Float64() float64 { return float64(r) }
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	after := clock.After(time.Second)
+	ticker := clock.NewTicker(400 * time.Millisecond)
+	defer ticker.Stop()
+
+	if clock.Waiters() != 2 {
+		t.Errorf("Expected 2 waiters, got %d", clock.Waiters())
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-after:
+		t.Error("Expected After not to fire before its duration")
+	default:
+	}
+	if tick := <-ticker.C(); !tick.Equal(start.Add(400 * time.Millisecond)) {
+		t.Errorf("Expected tick at %v, got %v", start.Add(400*time.Millisecond), tick)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if fired := <-after; !fired.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected After to fire at %v, got %v", start.Add(time.Second), fired)
+	}
+	if !clock.Now().Equal(start.Add(time.Second)) {
+		t.Errorf("Expected now %v, got %v", start.Add(time.Second), clock.Now())
+	}
+	if clock.Waiters() != 1 {
+		t.Errorf("Expected only the ticker to remain, got %d waiters", clock.Waiters())
+	}
+
+	ticker.Stop()
+	if clock.Waiters() != 0 {
+		t.Errorf("Expected no waiters after Stop, got %d", clock.Waiters())
+	}
+}
+
+func TestCircuitBreakerManualClock(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cb := NewCircuitBreaker(&CircuitBreakerConfig{
+		Enabled:             true,
+		FailureThreshold:    1,
+		ResetTimeout:        time.Minute,
+		HalfOpenMaxRequests: 1,
+		Clock:               clock,
+	})
+	ctx := context.Background()
+
+	cb.Execute(ctx, func() error { return errors.New("failed") })
+
+	clock.Advance(45 * time.Second)
+	var probeErr *ProbeError
+	if err := cb.Execute(ctx, func() error { return nil }); !errors.As(err, &probeErr) || probeErr.RetryAfter != 15*time.Second {
+		t.Errorf("Expected circuit open error retrying after 15s, got %v", err)
+	}
+
+	clock.Advance(16 * time.Second)
+	if err := cb.Execute(ctx, func() error { return nil }); err != nil {
+		t.Errorf("Expected half-open request to succeed, got %v", err)
+	}
+	if cb.GetState() != CircuitStateClosed {
+		t.Errorf("Expected circuit to be closed, got %v", cb.GetState())
+	}
+}
+
+func TestRetryExecutorManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	executor := NewRetryExecutor(&RetryConfig{
+		MaxRetries:        3,
+		InitialDelay:      100 * time.Millisecond,
+		MaxDelay:          time.Second,
+		BackoffMultiplier: 2,
+		JitterStrategy:    JitterFull,
+		RetryableErrors:   []ErrorType{ErrorTypeNetwork},
+		Clock:             clock,
+		Rand:              fixedRand(0.5),
+	}, nil)
+
+	var attempts []time.Duration
+	done := make(chan error)
+	go func() {
+		done <- executor.Execute(context.Background(), func() error {
+			attempts = append(attempts, clock.Now().Sub(start))
+			return NewNetworkError("http://test.com", errors.New("connection failed"))
+		})
+	}()
+
+	var err error
+	for finished := false; !finished; {
+		select {
+		case err = <-done:
+			finished = true
+		default:
+			if clock.Waiters() > 0 {
+				clock.Advance(10 * time.Millisecond)
+			} else {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+
+	if err == nil {
+		t.Fatal("Expected error after retries")
+	}
+	// Full jitter at 0.5 halves the 100ms, 200ms and 400ms exponential delays
+	expected := []time.Duration{0, 50 * time.Millisecond, 150 * time.Millisecond, 350 * time.Millisecond}
+	if len(attempts) != len(expected) {
+		t.Fatalf("Expected %d attempts, got %d", len(expected), len(attempts))
+	}
+	for i := range expected {
+		if attempts[i] != expected[i] {
+			t.Errorf("Expected attempt %d at %v, got %v", i+1, expected[i], attempts[i])
+		}
+	}
+}
@@ -2,30 +2,93 @@ package probe
 
 import "strings"
 
-// parseVideoCodec determines video codec from codec string
-func parseVideoCodec(codecString string) string {
-	if strings.Contains(codecString, "avc1") {
-		return "h264"
+// videoSampleEntries maps the lowercased sample entry (the part before the
+// first dot) of an RFC 6381 codec to its ffprobe codec name. Dolby Vision is
+// reported by the codec of its bitstream, like ffprobe does. It is the only
+// sample entry table: codecFamily and parseCodecList both use it.
+var videoSampleEntries = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"vp08": "vp8",
+	"vp09": "vp9",
+	"av01": "av1",
+	"dvh1": "hevc", // Dolby Vision over HEVC
+	"dvhe": "hevc",
+	"dva1": "h264", // Dolby Vision over AVC
+	"dvav": "h264",
+	"dav1": "av1", // Dolby Vision over AV1
+}
+
+// audioSampleEntries maps the lowercased sample entry of an RFC 6381 codec
+// to its ffprobe codec name (mp4a is resolved by mp4aCodec)
+var audioSampleEntries = map[string]string{
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"ac-4": "ac4",
+	"opus": "opus",
+	"flac": "flac",
+}
+
+// mp4aCodec returns the codec of an mp4a entry from its object type
+// indication and, for MPEG-4 audio, its audio object type
+func mp4aCodec(entry string) string {
+	switch strings.ToLower(entry) {
+	case "mp4a.40.34", "mp4a.69", "mp4a.6b":
+		return "mp3"
+	case "mp4a.a5":
+		return "ac3"
+	case "mp4a.a6":
+		return "eac3"
+	default:
+		return "aac"
 	}
-	if strings.Contains(codecString, "hev1") || strings.Contains(codecString, "hvc1") {
-		return "hevc"
+}
+
+// codecFamily returns the stream type and codec name of an RFC 6381 codec
+// entry such as "avc1.64001f" ("" for unknown and subtitle codecs)
+func codecFamily(entry string) (kind, codec string) {
+	sampleEntry, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(entry)), ".")
+	if sampleEntry == "mp4a" {
+		return "Audio", mp4aCodec(strings.TrimSpace(entry))
 	}
-	if strings.Contains(codecString, "vp09") {
-		return "vp9"
+	if codec, ok := videoSampleEntries[sampleEntry]; ok {
+		return "Video", codec
 	}
-	if strings.Contains(codecString, "av01") {
-		return "av1"
+	if codec, ok := audioSampleEntries[sampleEntry]; ok {
+		return "Audio", codec
+	}
+	return "", ""
+}
+
+// parseCodecList returns the ffprobe names of the first video and audio
+// codecs of a comma-separated RFC 6381 codec list ("" if there is none)
+func parseCodecList(codecString string) (string, string) {
+	var videoCodec, audioCodec string
+	for _, entry := range strings.Split(codecString, ",") {
+		switch kind, codec := codecFamily(entry); {
+		case kind == "Video" && videoCodec == "":
+			videoCodec = codec
+		case kind == "Audio" && audioCodec == "":
+			audioCodec = codec
+		}
+	}
+	return videoCodec, audioCodec
+}
+
+// parseVideoCodec determines video codec from codec string
+func parseVideoCodec(codecString string) string {
+	if videoCodec, _ := parseCodecList(codecString); videoCodec != "" {
+		return videoCodec
 	}
 	return "h264" // default
 }
 
 // parseAudioCodec determines audio codec from codec string
 func parseAudioCodec(codecString string) string {
-	if strings.Contains(codecString, "ec-3") {
-		return "eac3"
-	}
-	if strings.Contains(codecString, "mp4a") {
-		return "aac"
+	if _, audioCodec := parseCodecList(codecString); audioCodec != "" {
+		return audioCodec
 	}
 	return "aac" // default
 }
@@ -33,6 +96,11 @@ func parseAudioCodec(codecString string) string {
 // getPixelFormat determines pixel format based on codec profile information
 func getPixelFormat(codecString string, videoCodec string) string {
 	// Parse codec profile information for pixel format
+	if strings.Contains(codecString, "dvh1") || strings.Contains(codecString, "dvhe") {
+		// Dolby Vision over HEVC (profiles 5, 8) is 10-bit
+		return "yuv420p10le"
+	}
+
 	if strings.Contains(codecString, "avc1") {
 		// H.264 codec profiles
 		if strings.Contains(codecString, "avc1.640028") || strings.Contains(codecString, "avc1.640032") {
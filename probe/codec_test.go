@@ -28,6 +28,11 @@ func TestParseVideoCodec(t *testing.T) {
 			codecString: "vp09.00.10.08",
 			expected:    "vp9",
 		},
+		{
+			name:        "VP8",
+			codecString: "vp08.00.41.08",
+			expected:    "vp8",
+		},
 		{
 			name:        "AV1",
 			codecString: "av01.0.04M.08",
@@ -0,0 +1,150 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OriginComparison is the result of probing the same content from several
+// origins or CDNs
+type OriginComparison struct {
+	// Consistent reports that every origin was probed successfully and is
+	// structurally equal to the reference
+	Consistent bool `json:"consistent"`
+
+	// Reference is the URL the other origins are compared against: the first
+	// one probed successfully
+	Reference string `json:"reference,omitempty"`
+
+	// Origins has one entry per URL, in input order
+	Origins []OriginResult `json:"origins"`
+}
+
+// OriginResult compares one origin to the reference
+type OriginResult struct {
+	URL      string        `json:"url"`
+	Host     string        `json:"host"`
+	Duration time.Duration `json:"duration"`
+	Streams  int           `json:"streams"`
+
+	// Matches reports the same streams and features as the reference
+	Matches bool `json:"matches"`
+
+	// Missing lists reference streams this origin does not have, Extra the
+	// streams only this origin has
+	Missing []StreamInfo `json:"missing,omitempty"`
+	Extra   []StreamInfo `json:"extra,omitempty"`
+
+	// Differences describes other mismatches, such as differing features
+	Differences []string `json:"differences,omitempty"`
+
+	Error  string  `json:"error,omitempty"`
+	Err    error   `json:"-"`
+	Output *Output `json:"-"`
+}
+
+// CompareOrigins probes the same content from several origins with a new
+// Prober using opts (nil = defaults). See Prober.CompareOrigins.
+func CompareOrigins(ctx context.Context, manifestURLs []string, opts *ProbeOptions) (*OriginComparison, error) {
+	return NewProber(opts).CompareOrigins(ctx, manifestURLs)
+}
+
+// CompareOrigins probes manifestURLs concurrently, expecting the same content
+// from each (e.g. one manifest on several CDNs), and reports whether their
+// streams and features are structurally equal along with per-origin
+// latency. Stream IDs, sources, base URLs and excerpts are ignored since they
+// legitimately differ between origins. Failed probes are reported per origin.
+func (p *Prober) CompareOrigins(ctx context.Context, manifestURLs []string) (*OriginComparison, error) {
+	if len(manifestURLs) < 2 {
+		return nil, NewValidationError("at least two manifest URLs are required to compare origins")
+	}
+
+	results := p.ProbeBatch(ctx, manifestURLs, len(manifestURLs))
+
+	comparison := &OriginComparison{Consistent: true}
+	var reference *Output
+	for _, result := range results {
+		if result.Err == nil {
+			comparison.Reference = p.redactor.redactString(result.URL)
+			reference = result.Output
+			break
+		}
+	}
+
+	for _, result := range results {
+		origin := OriginResult{
+			URL:      p.redactor.redactString(result.URL),
+			Host:     resultHost(result.URL),
+			Duration: result.Duration,
+			Err:      result.Err,
+			Output:   result.Output,
+		}
+		if result.Err != nil {
+			origin.Error = p.redactor.redactError(result.Err).Error()
+			comparison.Consistent = false
+			comparison.Origins = append(comparison.Origins, origin)
+			continue
+		}
+
+		origin.Streams = len(result.Output.Streams)
+		origin.Missing, origin.Extra = compareStreams(reference.Streams, result.Output.Streams)
+		if !equalStringSets(reference.Features, result.Output.Features) {
+			origin.Differences = append(origin.Differences, fmt.Sprintf("features [%s], reference has [%s]",
+				strings.Join(result.Output.Features, ", "), strings.Join(reference.Features, ", ")))
+		}
+		origin.Matches = len(origin.Missing) == 0 && len(origin.Extra) == 0 && len(origin.Differences) == 0
+		comparison.Consistent = comparison.Consistent && origin.Matches
+		comparison.Origins = append(comparison.Origins, origin)
+	}
+
+	return comparison, nil
+}
+
+// compareStreams returns the reference streams missing from streams and the
+// streams absent from reference, ignoring origin-specific fields
+func compareStreams(reference, streams []StreamInfo) (missing, extra []StreamInfo) {
+	want := groupOriginStreams(reference)
+	got := groupOriginStreams(streams)
+
+	for _, event := range diffStreams(time.Time{}, "", want, got, EventStreamRemoved) {
+		missing = append(missing, *event.Stream)
+	}
+	for _, event := range diffStreams(time.Time{}, "", got, want, EventStreamAdded) {
+		extra = append(extra, *event.Stream)
+	}
+	return missing, extra
+}
+
+// groupOriginStreams groups streams by their key without the fields that
+// differ between origins serving the same content
+func groupOriginStreams(streams []StreamInfo) map[string][]StreamInfo {
+	grouped := make(map[string][]StreamInfo)
+	for _, stream := range streams {
+		original := stream
+		stream.BaseURLs = nil
+		stream.Excerpt = ""
+		key := streamKey(stream)
+		grouped[key] = append(grouped[key], original)
+	}
+	return grouped
+}
+
+// equalStringSets reports whether a and b contain the same strings, in any order
+func equalStringSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
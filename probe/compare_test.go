@@ -0,0 +1,106 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func manifestServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestCompareOrigins(t *testing.T) {
+	primary := manifestServer(watchTestManifest)
+	defer primary.Close()
+	secondary := manifestServer(watchTestManifest)
+	defer secondary.Close()
+	stale := manifestServer(watchTestManifestUpdated)
+	defer stale.Close()
+	missing := manifestServer("")
+	defer missing.Close()
+
+	comparison, err := CompareOrigins(context.Background(), []string{
+		missing.URL + "/master.m3u8",
+		primary.URL + "/master.m3u8",
+		secondary.URL + "/master.m3u8",
+		stale.URL + "/master.m3u8",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if comparison.Consistent {
+		t.Error("Expected inconsistent origins")
+	}
+	if comparison.Reference != primary.URL+"/master.m3u8" {
+		t.Errorf("Expected reference %q, got %q", primary.URL+"/master.m3u8", comparison.Reference)
+	}
+	if len(comparison.Origins) != 4 {
+		t.Fatalf("Expected 4 origins, got %d", len(comparison.Origins))
+	}
+
+	tests := []struct {
+		name    string
+		origin  OriginResult
+		matches bool
+		extra   int
+		failed  bool
+	}{
+		{"missing", comparison.Origins[0], false, 0, true},
+		{"primary", comparison.Origins[1], true, 0, false},
+		{"secondary", comparison.Origins[2], true, 0, false},
+		{"stale", comparison.Origins[3], false, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.origin.Matches != tt.matches {
+				t.Errorf("Expected matches %v, got %v", tt.matches, tt.origin.Matches)
+			}
+			if len(tt.origin.Extra) != tt.extra {
+				t.Errorf("Expected %d extra streams, got %d", tt.extra, len(tt.origin.Extra))
+			}
+			if len(tt.origin.Missing) != 0 {
+				t.Errorf("Expected no missing streams, got %d", len(tt.origin.Missing))
+			}
+			if (tt.origin.Error != "") != tt.failed {
+				t.Errorf("Expected failed %v, got error %q", tt.failed, tt.origin.Error)
+			}
+			if !tt.failed && tt.origin.Duration <= 0 {
+				t.Errorf("Expected a probe duration, got %v", tt.origin.Duration)
+			}
+		})
+	}
+}
+
+func TestCompareOriginsConsistent(t *testing.T) {
+	primary := manifestServer(watchTestManifest)
+	defer primary.Close()
+	secondary := manifestServer(watchTestManifest)
+	defer secondary.Close()
+
+	comparison, err := NewProber(nil).CompareOrigins(context.Background(), []string{
+		primary.URL + "/master.m3u8",
+		secondary.URL + "/master.m3u8",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !comparison.Consistent {
+		t.Errorf("Expected consistent origins, got %+v", comparison.Origins)
+	}
+}
+
+func TestCompareOriginsValidation(t *testing.T) {
+	_, err := CompareOrigins(context.Background(), []string{"https://example.com/master.m3u8"}, nil)
+	if err == nil {
+		t.Error("Expected an error for a single URL")
+	}
+}
@@ -0,0 +1,284 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Compatibility issues reported in CompatibilityFinding.Issue
+const (
+	// CompatMixedCodecSwitchingSet is a switching set (DASH adaptation sets
+	// linked for switching, or the variants of an HLS pathway) whose streams
+	// use different codecs. Players that cannot reinitialize the decoder
+	// mid-stream may fail or stall when switching between them.
+	CompatMixedCodecSwitchingSet = "mixed_codec_switching_set"
+
+	// CompatMixedCodecRenditionGroup is an HLS rendition group referenced by
+	// variants that declare different codecs for it, so the CODECS of some
+	// variants do not describe the renditions they play
+	CompatMixedCodecRenditionGroup = "mixed_codec_rendition_group"
+)
+
+// adaptationSetSwitchingScheme links adaptation sets players may switch between
+const adaptationSetSwitchingScheme = "urn:mpeg:dash:adaptation-set-switching:2016"
+
+// CompatibilityFinding reports a manifest construct known to break playback
+// on some devices
+type CompatibilityFinding struct {
+	Issue   string   `json:"issue"`
+	Message string   `json:"message"`
+	Codecs  []string `json:"codecs"`
+
+	// StreamIDs are the affected streams
+	StreamIDs []string `json:"stream_ids"`
+
+	// GroupID is the HLS rendition group (mixed_codec_rendition_group only)
+	GroupID string `json:"group_id,omitempty"`
+
+	Severity  Severity          `json:"severity"`
+	Locations []FindingLocation `json:"locations,omitempty"`
+}
+
+// codecGroup collects the codecs and streams of a switching set or rendition group
+type codecGroup struct {
+	codecs    []string
+	streamIDs []string
+}
+
+func (g *codecGroup) add(codec, streamID string) {
+	if !containsString(g.codecs, codec) {
+		g.codecs = append(g.codecs, codec)
+	}
+	if streamID != "" && !containsString(g.streamIDs, streamID) {
+		g.streamIDs = append(g.streamIDs, streamID)
+	}
+}
+
+// mpdCompatibility reports audio and video switching sets with mixed codecs:
+// an adaptation set, together with the adaptation sets it is linked to by an
+// adaptation-set-switching property
+func mpdCompatibility(mpd *MPD, streams []StreamInfo) []CompatibilityFinding {
+	var findings []CompatibilityFinding
+
+	for periodIndex, period := range mpd.Periods {
+		switchingSets := mpdSwitchingSets(period)
+
+		var order []int
+		groups := make(map[int]*codecGroup)
+		for _, stream := range streams {
+			if stream.element.period != periodIndex || (stream.Type != "Video" && stream.Type != "Audio") {
+				continue
+			}
+			set := switchingSets[stream.element.adaptationSet]
+			if groups[set] == nil {
+				groups[set] = &codecGroup{}
+				order = append(order, set)
+			}
+			groups[set].add(stream.Codec, stream.StreamID)
+		}
+
+		for _, set := range order {
+			group := groups[set]
+			if len(group.codecs) < 2 {
+				continue
+			}
+			var ids []string
+			for i, adaptationSet := range period.AdaptationSets {
+				if switchingSets[i] == set {
+					ids = append(ids, mpdAdaptationSetName(adaptationSet, i))
+				}
+			}
+			findings = append(findings, CompatibilityFinding{
+				Issue:    CompatMixedCodecSwitchingSet,
+				Severity: SeverityWarning,
+				Message: fmt.Sprintf("switching set of adaptation sets %s in period %s mixes %s",
+					strings.Join(ids, ", "), mpdPeriodName(period, periodIndex), strings.Join(group.codecs, " and ")),
+				Codecs:    group.codecs,
+				StreamIDs: group.streamIDs,
+			})
+		}
+	}
+
+	return findings
+}
+
+// mpdSwitchingSets maps each adaptation set index of period to the index of
+// the first adaptation set of its switching set
+func mpdSwitchingSets(period Period) []int {
+	sets := make([]int, len(period.AdaptationSets))
+	indexByID := make(map[string]int)
+	for i, adaptationSet := range period.AdaptationSets {
+		sets[i] = i
+		if adaptationSet.ID != "" {
+			indexByID[adaptationSet.ID] = i
+		}
+	}
+
+	find := func(i int) int {
+		for sets[i] != i {
+			i = sets[i]
+		}
+		return i
+	}
+
+	for i, adaptationSet := range period.AdaptationSets {
+		for _, prop := range adaptationSet.SupplementalProperty {
+			if prop.SchemeIdUri != adaptationSetSwitchingScheme {
+				continue
+			}
+			for _, id := range strings.Split(prop.Value, ",") {
+				j, ok := indexByID[strings.TrimSpace(id)]
+				if !ok {
+					continue
+				}
+				a, b := find(i), find(j)
+				if a > b {
+					a, b = b, a
+				}
+				sets[b] = a
+			}
+		}
+	}
+
+	for i := range sets {
+		sets[i] = find(i)
+	}
+	return sets
+}
+
+// mpdAdaptationSetName names an adaptation set by @id, or by position when it has none
+func mpdAdaptationSetName(adaptationSet AdaptationSet, index int) string {
+	if adaptationSet.ID != "" {
+		return adaptationSet.ID
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+// mpdPeriodName names a period by @id, or by position when it has none
+func mpdPeriodName(period Period, index int) string {
+	if period.ID != "" {
+		return period.ID
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+// hlsCompatibility reports HLS pathways whose variants mix video codecs, and
+// rendition groups referenced by variants declaring different codecs for them.
+// Codecs come from the variants' CODECS attributes.
+func hlsCompatibility(variants []HLSVariant, streams []StreamInfo) []CompatibilityFinding {
+	streamsByID := make(map[string]StreamInfo)
+	for _, stream := range streams {
+		streamsByID[stream.StreamID] = stream
+	}
+
+	var findings []CompatibilityFinding
+
+	var pathways []string
+	switchingSets := make(map[string]*codecGroup)
+	var groupNames []string
+	groups := make(map[string]*codecGroup)
+
+	for _, variant := range variants {
+		video, audio := hlsCodecs(variant.Codecs)
+
+		var videoStream, audioStream StreamInfo
+		for _, id := range variant.StreamIDs {
+			switch stream := streamsByID[id]; stream.Type {
+			case "Video":
+				videoStream = stream
+			case "Audio":
+				audioStream = stream
+			}
+		}
+
+		if len(video) > 0 {
+			set := switchingSets[variant.PathwayID]
+			if set == nil {
+				set = &codecGroup{}
+				switchingSets[variant.PathwayID] = set
+				pathways = append(pathways, variant.PathwayID)
+			}
+			for _, codec := range video {
+				set.add(codec, videoStream.StreamID)
+			}
+		}
+
+		for _, rendition := range []struct {
+			kind   string
+			codecs []string
+			stream StreamInfo
+		}{
+			{"VIDEO", video, videoStream},
+			{"AUDIO", audio, audioStream},
+		} {
+			if rendition.stream.Source == nil || rendition.stream.Source.GroupID == "" || len(rendition.codecs) == 0 {
+				continue
+			}
+			name := rendition.kind + " " + rendition.stream.Source.GroupID
+			group := groups[name]
+			if group == nil {
+				group = &codecGroup{}
+				groups[name] = group
+				groupNames = append(groupNames, name)
+			}
+			// A variant declaring several codecs of the kind is compared as one combination
+			group.add(strings.Join(rendition.codecs, "+"), rendition.stream.StreamID)
+		}
+	}
+
+	for _, pathway := range pathways {
+		set := switchingSets[pathway]
+		if len(set.codecs) < 2 {
+			continue
+		}
+		scope := "variants"
+		if pathway != "" {
+			scope = fmt.Sprintf("variants of pathway %s", pathway)
+		}
+		findings = append(findings, CompatibilityFinding{
+			Issue:     CompatMixedCodecSwitchingSet,
+			Severity:  SeverityWarning,
+			Message:   fmt.Sprintf("%s mix %s video", scope, strings.Join(set.codecs, " and ")),
+			Codecs:    set.codecs,
+			StreamIDs: set.streamIDs,
+		})
+	}
+
+	for _, name := range groupNames {
+		group := groups[name]
+		if len(group.codecs) < 2 {
+			continue
+		}
+		kind, groupID, _ := strings.Cut(name, " ")
+		findings = append(findings, CompatibilityFinding{
+			Issue:    CompatMixedCodecRenditionGroup,
+			Severity: SeverityWarning,
+			Message: fmt.Sprintf("%s group %q is shared by variants declaring %s", kind, groupID,
+				strings.Join(group.codecs, " and ")),
+			Codecs:    group.codecs,
+			StreamIDs: group.streamIDs,
+			GroupID:   groupID,
+		})
+	}
+
+	return findings
+}
+
+// hlsCodecs classifies the entries of a CODECS attribute into video and audio
+// codec names, in order and without duplicates
+func hlsCodecs(codecs string) (video, audio []string) {
+	for _, entry := range strings.Split(codecs, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, codec := codecFamily(entry)
+		switch {
+		case kind == "Video" && !containsString(video, codec):
+			video = append(video, codec)
+		case kind == "Audio" && !containsString(audio, codec):
+			audio = append(audio, codec)
+		}
+	}
+	return video, audio
+}
@@ -0,0 +1,188 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHLSCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected []CompatibilityFinding
+	}{
+		{
+			name: "single codec ladder",
+			manifest: `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="aac"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="aac"
+1080p.m3u8
+`,
+		},
+		{
+			name: "mixed video codecs",
+			manifest: `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="aac"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1920x1080,CODECS="hvc1.2.4.L123.B0,mp4a.40.2",AUDIO="aac"
+1080p-hevc.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1920x1080,CODECS="av01.0.08M.08,mp4a.40.2",AUDIO="aac"
+1080p-av1.m3u8
+`,
+			expected: []CompatibilityFinding{{
+				Issue:     CompatMixedCodecSwitchingSet,
+				Severity:  SeverityWarning,
+				Message:   "variants mix h264 and hevc and av1 video",
+				Codecs:    []string{"h264", "hevc", "av1"},
+				StreamIDs: []string{"0:0", "0:2", "0:4"},
+			}},
+		},
+		{
+			name: "pathways compared separately",
+			manifest: `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",PATHWAY-ID="cdn-a"
+a/720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="hvc1.1.6.L93.B0,mp4a.40.2",PATHWAY-ID="cdn-b"
+b/720p.m3u8
+`,
+		},
+		{
+			name: "audio group with different codecs",
+			manifest: `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="English",URI="audio/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="audio"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,ec-3",AUDIO="audio"
+1080p.m3u8
+`,
+			expected: []CompatibilityFinding{{
+				Issue:     CompatMixedCodecRenditionGroup,
+				Severity:  SeverityWarning,
+				Message:   `AUDIO group "audio" is shared by variants declaring aac and eac3`,
+				Codecs:    []string{"aac", "eac3"},
+				StreamIDs: []string{"0:1", "0:3"},
+				GroupID:   "audio",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseHLSManifest(tt.manifest, "https://example.com/master.m3u8")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.Compatibility, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, output.Compatibility)
+			}
+		})
+	}
+}
+
+func TestMPDCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected []CompatibilityFinding
+	}{
+		{
+			name: "separate adaptation sets",
+			manifest: `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+  <Period id="p0">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <Representation id="avc" codecs="avc1.64001f" bandwidth="3000000" width="1280" height="720"/>
+    </AdaptationSet>
+    <AdaptationSet id="2" contentType="video" mimeType="video/mp4">
+      <Representation id="hevc" codecs="hvc1.1.6.L93.B0" bandwidth="2000000" width="1280" height="720"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`,
+		},
+		{
+			name: "mixed codecs in one adaptation set",
+			manifest: `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+  <Period id="p0">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <Representation id="avc" codecs="avc1.64001f" bandwidth="3000000" width="1280" height="720"/>
+      <Representation id="hevc" codecs="hvc1.1.6.L93.B0" bandwidth="2000000" width="1280" height="720"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`,
+			expected: []CompatibilityFinding{{
+				Issue:     CompatMixedCodecSwitchingSet,
+				Severity:  SeverityWarning,
+				Message:   "switching set of adaptation sets 1 in period p0 mixes h264 and hevc",
+				Codecs:    []string{"h264", "hevc"},
+				StreamIDs: []string{"0:0", "0:1"},
+			}},
+		},
+		{
+			name: "adaptation set switching",
+			manifest: `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+  <Period id="p0">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <SupplementalProperty schemeIdUri="urn:mpeg:dash:adaptation-set-switching:2016" value="2"/>
+      <Representation id="avc" codecs="avc1.64001f" bandwidth="3000000" width="1280" height="720"/>
+    </AdaptationSet>
+    <AdaptationSet id="2" contentType="video" mimeType="video/mp4">
+      <Representation id="av1" codecs="av01.0.08M.08" bandwidth="2000000" width="1920" height="1080"/>
+    </AdaptationSet>
+    <AdaptationSet id="3" contentType="audio" mimeType="audio/mp4">
+      <Representation id="aac" codecs="mp4a.40.2" bandwidth="128000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`,
+			expected: []CompatibilityFinding{{
+				Issue:     CompatMixedCodecSwitchingSet,
+				Severity:  SeverityWarning,
+				Message:   "switching set of adaptation sets 1, 2 in period p0 mixes h264 and av1",
+				Codecs:    []string{"h264", "av1"},
+				StreamIDs: []string{"0:0", "0:1"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseMPDManifest(tt.manifest, "https://example.com/manifest.mpd")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.Compatibility, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, output.Compatibility)
+			}
+		})
+	}
+}
+
+func TestCodecFamily(t *testing.T) {
+	tests := []struct {
+		entry string
+		kind  string
+		codec string
+	}{
+		{"avc1.64001f", "Video", "h264"},
+		{"hev1.2.4.L120.B0", "Video", "hevc"},
+		{"dvh1.05.06", "Video", "hevc"},
+		{"av01.0.08M.08", "Video", "av1"},
+		{"mp4a.40.2", "Audio", "aac"},
+		{"mp4a.40.34", "Audio", "mp3"},
+		{"ec-3", "Audio", "eac3"},
+		{"vp08.00.41.08", "Video", "vp8"},
+		{"Opus", "Audio", "opus"},
+		{"fLaC", "Audio", "flac"},
+		{"mp4a.A6", "Audio", "eac3"},
+		{"wvtt", "", ""},
+	}
+
+	for _, tt := range tests {
+		kind, codec := codecFamily(tt.entry)
+		if kind != tt.kind || codec != tt.codec {
+			t.Errorf("%s: Expected %s %s, got %s %s", tt.entry, tt.kind, tt.codec, kind, codec)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package probe
+
+import (
+	"mime"
+	"strings"
+)
+
+// DefaultDASHContentTypes lists the Content-Types accepted for MPD manifests by default
+var DefaultDASHContentTypes = []string{
+	"application/dash+xml",
+	"video/vnd.mpeg.dash.mpd",
+	"application/xml",
+	"text/xml",
+}
+
+// DefaultHLSContentTypes lists the Content-Types accepted for HLS playlists by default
+var DefaultHLSContentTypes = []string{
+	"application/vnd.apple.mpegurl",
+	"application/x-mpegurl",
+	"application/mpegurl",
+	"audio/mpegurl",
+	"audio/x-mpegurl",
+}
+
+// ContentTypePolicy validates that the response Content-Type matches the
+// detected manifest format. Mismatches are reported in Output.Warnings, or
+// fail the probe when Strict is set.
+type ContentTypePolicy struct {
+	// Strict fails the probe on a mismatch instead of adding a warning
+	Strict bool
+
+	// DASHTypes lists accepted Content-Types for MPD manifests (nil = DefaultDASHContentTypes)
+	DASHTypes []string
+
+	// HLSTypes lists accepted Content-Types for HLS playlists (nil = DefaultHLSContentTypes)
+	HLSTypes []string
+}
+
+// allows reports whether contentType is accepted for format ("HLS" or "MPD")
+func (c *ContentTypePolicy) allows(format string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	allowed := c.DASHTypes
+	if allowed == nil {
+		allowed = DefaultDASHContentTypes
+	}
+	if format == "HLS" {
+		allowed = c.HLSTypes
+		if allowed == nil {
+			allowed = DefaultHLSContentTypes
+		}
+	}
+
+	for _, candidate := range allowed {
+		if strings.EqualFold(mediaType, candidate) {
+			return true
+		}
+	}
+	return false
+}
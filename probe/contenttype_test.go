@@ -0,0 +1,79 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContentTypePolicyAllows(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      *ContentTypePolicy
+		format      string
+		contentType string
+		expected    bool
+	}{
+		{"DASH default", &ContentTypePolicy{}, "MPD", "application/dash+xml", true},
+		{"parameters and case", &ContentTypePolicy{}, "MPD", "Application/XML; charset=utf-8", true},
+		{"HLS default", &ContentTypePolicy{}, "HLS", "application/vnd.apple.mpegurl", true},
+		{"HLS type for DASH", &ContentTypePolicy{}, "MPD", "application/vnd.apple.mpegurl", false},
+		{"HTML", &ContentTypePolicy{}, "HLS", "text/html", false},
+		{"missing", &ContentTypePolicy{}, "MPD", "", false},
+		{"custom allowlist", &ContentTypePolicy{HLSTypes: []string{"text/plain"}}, "HLS", "text/plain", true},
+		{"custom allowlist excludes default", &ContentTypePolicy{HLSTypes: []string{"text/plain"}}, "HLS", "application/x-mpegurl", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.policy.allows(tt.format, tt.contentType); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestContentTypeValidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	t.Run("disabled", func(t *testing.T) {
+		output, err := NewProber(&ProbeOptions{DisableCamouflage: true}).Probe(context.Background(), server.URL+"/live.m3u8")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(output.Warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", output.Warnings)
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		opts := &ProbeOptions{DisableCamouflage: true, ContentTypePolicy: &ContentTypePolicy{}}
+		output, err := NewProber(opts).Probe(context.Background(), server.URL+"/live.m3u8")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := `unexpected Content-Type "text/html" for HLS manifest`
+		if len(output.Warnings) != 1 || output.Warnings[0] != expected {
+			t.Errorf("Expected warning %q, got %v", expected, output.Warnings)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		opts := &ProbeOptions{DisableCamouflage: true, ContentTypePolicy: &ContentTypePolicy{Strict: true}}
+		_, err := NewProber(opts).Probe(context.Background(), server.URL+"/live.m3u8")
+
+		var probeErr *ProbeError
+		if !errors.As(err, &probeErr) {
+			t.Fatalf("Expected ProbeError, got %v", err)
+		}
+		if probeErr.Type != ErrorTypeParsing {
+			t.Errorf("Expected %q, got %q", ErrorTypeParsing, probeErr.Type)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RulePackDASHIF selects checks from the DASH-IF Interoperability Points
+const RulePackDASHIF = "dashif-iop"
+
+// maxMinBufferTime is the largest MPD@minBufferTime considered sane. Larger
+// values make players buffer for long before starting playback.
+const maxMinBufferTime = 10 * time.Second
+
+var dashIFRules = []Rule{
+	{
+		ID:          "dashif-iop/profiles",
+		Description: "The MPD declares its profiles (MPD@profiles)",
+		Format:      "MPD",
+		Check:       checkDASHIFProfiles,
+	},
+	{
+		ID:          "dashif-iop/min-buffer-time",
+		Description: "MPD@minBufferTime is present, positive and at most 10 seconds",
+		Severity:    SeverityWarning,
+		Format:      "MPD",
+		Check:       checkDASHIFMinBufferTime,
+	},
+	{
+		ID:          "dashif-iop/segment-alignment",
+		Description: "Audio and video adaptation sets set @segmentAlignment or @subsegmentAlignment",
+		Format:      "MPD",
+		Check:       checkDASHIFSegmentAlignment,
+	},
+	{
+		ID:          "dashif-iop/audio-channel-configuration",
+		Description: "Every audio representation has an AudioChannelConfiguration",
+		Severity:    SeverityWarning,
+		Format:      "MPD",
+		Check:       checkDASHIFAudioChannelConfiguration,
+	},
+	{
+		ID:          "dashif-iop/cmaf-switching-set",
+		Description: "Representations of an adaptation set (a CMAF switching set) share the codec and, for video, the picture aspect ratio",
+		Format:      "MPD",
+		Check:       checkDASHIFSwitchingSets,
+	},
+}
+
+// mpdAdaptationSetKind returns "video" or "audio" for media adaptation sets
+// that are not trick play ("" otherwise)
+func mpdAdaptationSetKind(adaptationSet AdaptationSet) string {
+	switch {
+	case isTrickModeStream(adaptationSet):
+		return ""
+	case isVideoStream(adaptationSet):
+		return "video"
+	case isAudioStream(adaptationSet):
+		return "audio"
+	}
+	return ""
+}
+
+// checkDASHIFProfiles checks that MPD@profiles is set
+func checkDASHIFProfiles(in *RuleInput) RuleOutcome {
+	mpd := in.MPD()
+	if mpd == nil {
+		return RuleOutcome{NotApplicable: true}
+	}
+	if strings.TrimSpace(mpd.Profiles) == "" {
+		return RuleOutcome{Message: "MPD@profiles is missing"}
+	}
+	return RuleOutcome{}
+}
+
+// checkDASHIFMinBufferTime checks MPD@minBufferTime
+func checkDASHIFMinBufferTime(in *RuleInput) RuleOutcome {
+	mpd := in.MPD()
+	if mpd == nil {
+		return RuleOutcome{NotApplicable: true}
+	}
+	if mpd.MinBufferTime == "" {
+		return RuleOutcome{Message: "MPD@minBufferTime is missing"}
+	}
+	minBufferTime, err := parseISODuration(mpd.MinBufferTime)
+	switch {
+	case err != nil:
+		return RuleOutcome{Message: fmt.Sprintf("MPD@minBufferTime %q is not a valid duration", mpd.MinBufferTime)}
+	case minBufferTime <= 0:
+		return RuleOutcome{Message: fmt.Sprintf("MPD@minBufferTime %s is not positive", mpd.MinBufferTime)}
+	case minBufferTime > maxMinBufferTime:
+		return RuleOutcome{Message: fmt.Sprintf("MPD@minBufferTime %s exceeds %s", mpd.MinBufferTime, maxMinBufferTime)}
+	}
+	return RuleOutcome{}
+}
+
+// checkDASHIFSegmentAlignment checks that media adaptation sets declare
+// aligned segments, so players can switch representations at any boundary
+func checkDASHIFSegmentAlignment(in *RuleInput) RuleOutcome {
+	mpd := in.MPD()
+	if mpd == nil {
+		return RuleOutcome{NotApplicable: true}
+	}
+
+	var unaligned []string
+	var streamIDs []string
+	for periodIndex, period := range mpd.Periods {
+		for i, adaptationSet := range period.AdaptationSets {
+			if mpdAdaptationSetKind(adaptationSet) == "" {
+				continue
+			}
+			if mpdAligned(adaptationSet.SegmentAlignment) || mpdAligned(adaptationSet.SubsegmentAlignment) {
+				continue
+			}
+			unaligned = append(unaligned, mpdAdaptationSetName(adaptationSet, i))
+			streamIDs = append(streamIDs, in.MPDStreamIDs(periodIndex, i, -1)...)
+		}
+	}
+	if len(unaligned) == 0 {
+		return RuleOutcome{}
+	}
+	return RuleOutcome{
+		Message:   fmt.Sprintf("adaptation sets %s do not declare segment alignment", strings.Join(unaligned, ", ")),
+		StreamIDs: streamIDs,
+	}
+}
+
+// mpdAligned reports whether a @segmentAlignment or @subsegmentAlignment value
+// declares alignment ("true" or a non-zero alignment group)
+func mpdAligned(value string) bool {
+	return value != "" && value != "false" && value != "0"
+}
+
+// checkDASHIFAudioChannelConfiguration checks that audio representations
+// declare their channel configuration
+func checkDASHIFAudioChannelConfiguration(in *RuleInput) RuleOutcome {
+	mpd := in.MPD()
+	if mpd == nil {
+		return RuleOutcome{NotApplicable: true}
+	}
+
+	var checked, missing int
+	var streamIDs []string
+	for periodIndex, period := range mpd.Periods {
+		for i, adaptationSet := range period.AdaptationSets {
+			if mpdAdaptationSetKind(adaptationSet) != "audio" {
+				continue
+			}
+			for j, rep := range adaptationSet.Representations {
+				checked++
+				if len(rep.AudioChannels) > 0 || len(adaptationSet.AudioChannels) > 0 {
+					continue
+				}
+				missing++
+				streamIDs = append(streamIDs, in.MPDStreamIDs(periodIndex, i, j)...)
+			}
+		}
+	}
+	if checked == 0 {
+		return RuleOutcome{NotApplicable: true}
+	}
+	if missing == 0 {
+		return RuleOutcome{}
+	}
+	return RuleOutcome{
+		Message:   fmt.Sprintf("%d of %d audio representations have no AudioChannelConfiguration", missing, checked),
+		StreamIDs: streamIDs,
+	}
+}
+
+// checkDASHIFSwitchingSets checks the CMAF switching set constraints of media
+// adaptation sets: one codec, and for video one picture aspect ratio
+func checkDASHIFSwitchingSets(in *RuleInput) RuleOutcome {
+	mpd := in.MPD()
+	if mpd == nil {
+		return RuleOutcome{NotApplicable: true}
+	}
+
+	aspectRatios := make(map[mpdElementIndex]string)
+	for _, stream := range in.Output.Streams {
+		if stream.Source != nil && stream.Source.Tag == "Representation" {
+			aspectRatios[stream.element] = stream.DisplayAspectRatio
+		}
+	}
+
+	var problems []string
+	var streamIDs []string
+	for periodIndex, period := range mpd.Periods {
+		for i, adaptationSet := range period.AdaptationSets {
+			kind := mpdAdaptationSetKind(adaptationSet)
+			if kind == "" || len(adaptationSet.Representations) < 2 {
+				continue
+			}
+
+			var codecs, ratios []string
+			for j, rep := range adaptationSet.Representations {
+				codec := getCodecString(rep, adaptationSet)
+				if fourCC, _, _ := strings.Cut(codec, "."); fourCC != "" && !containsString(codecs, fourCC) {
+					codecs = append(codecs, fourCC)
+				}
+				ratio := aspectRatios[mpdElementIndex{periodIndex, i, j}]
+				if kind == "video" && ratio != "" && !containsString(ratios, ratio) {
+					ratios = append(ratios, ratio)
+				}
+			}
+
+			name := mpdAdaptationSetName(adaptationSet, i)
+			if len(codecs) > 1 {
+				problems = append(problems, fmt.Sprintf("adaptation set %s mixes codecs %s", name, strings.Join(codecs, ", ")))
+			}
+			if len(ratios) > 1 {
+				problems = append(problems, fmt.Sprintf("adaptation set %s mixes aspect ratios %s", name, strings.Join(ratios, ", ")))
+			}
+			if len(codecs) > 1 || len(ratios) > 1 {
+				streamIDs = append(streamIDs, in.MPDStreamIDs(periodIndex, i, -1)...)
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return RuleOutcome{}
+	}
+	return RuleOutcome{Message: strings.Join(problems, "; "), StreamIDs: streamIDs}
+}
@@ -0,0 +1,91 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const dashIFTestNonCompliant = `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" minBufferTime="PT30S" mediaPresentationDuration="PT60S">
+  <Period id="p0">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <Representation id="v1" codecs="avc1.64001f" bandwidth="3000000" width="1280" height="720"/>
+      <Representation id="v2" codecs="hvc1.1.6.L120.90" bandwidth="5000000" width="1920" height="1080"/>
+      <Representation id="v3" codecs="avc1.64001e" bandwidth="1000000" width="640" height="480"/>
+    </AdaptationSet>
+    <AdaptationSet id="2" contentType="audio" mimeType="audio/mp4" segmentAlignment="true">
+      <Representation id="a1" codecs="mp4a.40.2" bandwidth="128000"/>
+      <Representation id="a2" codecs="mp4a.40.2" bandwidth="64000">
+        <AudioChannelConfiguration schemeIdUri="urn:mpeg:dash:23003:3:audio_channel_configuration:2011" value="2"/>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+const dashIFTestCompliant = `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" profiles="urn:mpeg:dash:profile:isoff-live:2011" minBufferTime="PT2S" mediaPresentationDuration="PT60S">
+  <Period id="p0">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4" segmentAlignment="true" codecs="avc1.64001f">
+      <Representation id="v1" bandwidth="3000000" width="1280" height="720"/>
+      <Representation id="v2" bandwidth="5000000" width="1920" height="1080"/>
+    </AdaptationSet>
+    <AdaptationSet id="2" contentType="audio" mimeType="audio/mp4" subsegmentAlignment="true">
+      <AudioChannelConfiguration schemeIdUri="urn:mpeg:dash:23003:3:audio_channel_configuration:2011" value="2"/>
+      <Representation id="a1" codecs="mp4a.40.2" bandwidth="128000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+func probeRules(t *testing.T, manifest string, packs ...string) []RuleFinding {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(manifest))
+	}))
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{RulePacks: packs}).Probe(context.Background(), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return output.Rules
+}
+
+func TestDASHIFRules(t *testing.T) {
+	expected := []RuleFinding{
+		{RuleID: "dashif-iop/profiles", Severity: SeverityError, Message: "MPD@profiles is missing"},
+		{RuleID: "dashif-iop/min-buffer-time", Severity: SeverityWarning, Message: "MPD@minBufferTime PT30S exceeds 10s"},
+		{RuleID: "dashif-iop/segment-alignment", Severity: SeverityError, Message: "adaptation sets 1 do not declare segment alignment", StreamIDs: []string{"0:0", "0:1", "0:2"}},
+		{RuleID: "dashif-iop/audio-channel-configuration", Severity: SeverityWarning, Message: "1 of 2 audio representations have no AudioChannelConfiguration", StreamIDs: []string{"0:3"}},
+		{RuleID: "dashif-iop/cmaf-switching-set", Severity: SeverityError, Message: "adaptation set 1 mixes codecs avc1, hvc1; adaptation set 1 mixes aspect ratios 16:9, 4:3", StreamIDs: []string{"0:0", "0:1", "0:2"}},
+	}
+
+	findings := probeRules(t, dashIFTestNonCompliant, RulePackDASHIF)
+	for i := range findings {
+		findings[i].Description = ""
+		findings[i].Locations = nil
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, findings)
+	}
+}
+
+func TestDASHIFRulesCompliant(t *testing.T) {
+	findings := probeRules(t, dashIFTestCompliant, RulePackDASHIF)
+	if len(findings) != len(dashIFRules) {
+		t.Errorf("Expected %d findings, got %d", len(dashIFRules), len(findings))
+	}
+	if failed := FailedRules(findings); len(failed) > 0 {
+		t.Errorf("Expected all rules to pass, got %+v", failed)
+	}
+}
+
+func TestRulePacksByFormat(t *testing.T) {
+	// HLS rules do not apply to an MPD
+	findings := probeRules(t, dashIFTestCompliant, RulePackAppleHLS, RulePackDASHIF)
+	if len(findings) != len(dashIFRules) {
+		t.Errorf("Expected %d findings, got %d", len(dashIFRules), len(findings))
+	}
+}
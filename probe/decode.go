@@ -0,0 +1,263 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDecodeTimeout bounds a single decode check run
+const DefaultDecodeTimeout = 30 * time.Second
+
+// decodeOutputLimit is the number of trailing output bytes kept in a DecodeResult
+const decodeOutputLimit = 1024
+
+// decodeSegmentLimit bounds the segments fetched into a decode input file
+const decodeSegmentLimit = 64 << 20
+
+// DecodeCheck runs an external decoder (such as ffmpeg) against probed streams
+// to confirm they actually decode. goprobe never decodes media itself.
+//
+// Command is the program and its arguments, run without a shell. These
+// placeholders are replaced in each argument:
+//
+//	{manifest}   the manifest URL
+//	{url}        the stream's media input: the HLS media playlist URL, or the
+//	             DASH first media segment URL. A DASH segment with an
+//	             initialization segment is fetched with it into a temporary
+//	             file, and {url} is the path of that file.
+//	{segment}    the stream's DASH first media segment URL
+//	{init}       the stream's DASH initialization segment URL ("" if none)
+//	{stream_id}  the stream ID, e.g. "0:1(en)"
+//	{index}      the stream index, e.g. "1" (for ffmpeg -map 0:{index})
+//
+// URLs come from the manifest, so a stream is only checked when its URLs
+// are absolute http(s) URLs that cannot be mistaken for command options.
+//
+// Example:
+//
+//	&probe.DecodeCheck{Command: []string{"ffmpeg", "-v", "error", "-i", "{url}", "-frames:v", "1", "-f", "null", "-"}}
+type DecodeCheck struct {
+	Command []string
+
+	// Streams lists the IDs of the streams to check (nil = the first video stream)
+	Streams []string
+
+	// Timeout bounds each run (0 = DefaultDecodeTimeout)
+	Timeout time.Duration
+}
+
+// DecodeResult is the outcome of a decode check run for one stream
+type DecodeResult struct {
+	StreamID string        `json:"stream_id"`
+	Success  bool          `json:"success"`
+	Duration time.Duration `json:"duration"`
+
+	// ExitCode is the command's exit status (-1 if it did not exit normally)
+	ExitCode int `json:"exit_code"`
+
+	// Error describes why the check failed
+	Error string `json:"error,omitempty"`
+
+	// Output is the end of the command's combined stdout and stderr
+	Output string `json:"output,omitempty"`
+}
+
+// runDecodeChecks runs the configured decode check for the selected streams of output
+func (p *Prober) runDecodeChecks(ctx context.Context, check *DecodeCheck, manifestURL string, output *Output) {
+	for _, stream := range decodeStreams(check, output) {
+		result := p.runDecodeCheck(ctx, check, manifestURL, output, stream)
+		if !result.Success {
+			logWarn(ctx, "Decode check failed", map[string]interface{}{
+				"url":       manifestURL,
+				"stream_id": result.StreamID,
+				"error":     result.Error,
+			})
+		}
+		output.Decodes = append(output.Decodes, result)
+	}
+}
+
+// decodeStreams returns the streams selected by check
+func decodeStreams(check *DecodeCheck, output *Output) []StreamInfo {
+	if len(check.Streams) == 0 {
+		for _, stream := range output.Streams {
+			if stream.Type == "Video" {
+				return []StreamInfo{stream}
+			}
+		}
+		return nil
+	}
+
+	var selected []StreamInfo
+	for _, streamID := range check.Streams {
+		found := false
+		for _, stream := range output.Streams {
+			if stream.StreamID == streamID {
+				selected = append(selected, stream)
+				found = true
+				break
+			}
+		}
+		if !found {
+			selected = append(selected, StreamInfo{StreamID: streamID})
+		}
+	}
+	return selected
+}
+
+// runDecodeCheck runs the decode command for a single stream
+func (p *Prober) runDecodeCheck(ctx context.Context, check *DecodeCheck, manifestURL string, output *Output, stream StreamInfo) DecodeResult {
+	result := DecodeResult{StreamID: stream.StreamID, ExitCode: -1}
+
+	if len(check.Command) == 0 {
+		result.Error = "no decode command configured"
+		return result
+	}
+
+	urls, err := output.ResolveURLs(stream.StreamID)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, resourceURL := range append([]string{manifestURL}, urls...) {
+		if err := checkDecodeURL(resourceURL); err != nil {
+			result.Error = p.redactor.redactString(err.Error())
+			return result
+		}
+	}
+
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDecodeTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	input := urls[len(urls)-1]
+	if len(urls) > 1 {
+		inputPath, err := p.writeDecodeInput(runCtx, urls)
+		if err != nil {
+			result.Error = p.redactor.redactString("segment fetch failed: " + err.Error())
+			return result
+		}
+		defer os.Remove(inputPath)
+		input = inputPath
+	}
+
+	args := expandDecodeCommand(check.Command, manifestURL, input, urls, stream)
+	cmd := exec.CommandContext(runCtx, args[0], args[1:]...)
+	var combined bytes.Buffer
+	cmd.Stdout = &combined
+	cmd.Stderr = &combined
+	// Children of the decoder may keep its output open after it is killed
+	cmd.WaitDelay = time.Second
+
+	start := p.clock.Now()
+	err = cmd.Run()
+	result.Duration = p.clock.Now().Sub(start)
+	result.Output = p.redactor.redactString(tailString(combined.String(), decodeOutputLimit))
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.Success = true
+		result.ExitCode = 0
+	case runCtx.Err() == context.DeadlineExceeded:
+		result.Error = "decode command timed out after " + timeout.String()
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = "decode command exited with status " + strconv.Itoa(result.ExitCode)
+	default:
+		result.Error = p.redactor.redactString(err.Error())
+	}
+
+	return result
+}
+
+// checkDecodeURL rejects URLs that are not absolute http(s) URLs, such as
+// file: URIs or values starting with "-" that a decoder would read as options
+func checkDecodeURL(resourceURL string) error {
+	if strings.HasPrefix(resourceURL, "-") {
+		return fmt.Errorf("URL %q starts with \"-\"", resourceURL)
+	}
+	parsed, err := url.Parse(resourceURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("URL %q is not an absolute http(s) URL", resourceURL)
+	}
+	return nil
+}
+
+// writeDecodeInput fetches the initialization and first media segment of a
+// stream into a temporary file, since the media segment alone does not
+// decode, and returns its path
+func (p *Prober) writeDecodeInput(ctx context.Context, urls []string) (string, error) {
+	segment, err := p.fetchFirstSegment(ctx, urls, decodeSegmentLimit)
+	if err != nil {
+		return "", err
+	}
+
+	ext := path.Ext(strings.SplitN(segment.uri, "?", 2)[0])
+	file, err := os.CreateTemp("", "goprobe-decode-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(segment.data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// expandDecodeCommand substitutes the placeholders of a decode command
+// template, with input as {url}
+func expandDecodeCommand(command []string, manifestURL, input string, urls []string, stream StreamInfo) []string {
+	media := urls[len(urls)-1]
+	init := ""
+	if len(urls) > 1 {
+		init = urls[0]
+	}
+
+	index := stream.StreamID
+	if i := strings.Index(index, ":"); i >= 0 {
+		index = index[i+1:]
+	}
+	if i := strings.Index(index, "("); i >= 0 {
+		index = index[:i]
+	}
+
+	replacer := strings.NewReplacer(
+		"{manifest}", manifestURL,
+		"{url}", input,
+		"{segment}", media,
+		"{init}", init,
+		"{stream_id}", stream.StreamID,
+		"{index}", index,
+	)
+
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = replacer.Replace(arg)
+	}
+	return args
+}
+
+// tailString returns the last limit bytes of s
+func tailString(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return s[len(s)-limit:]
+}
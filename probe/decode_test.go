@@ -0,0 +1,175 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandDecodeCommand(t *testing.T) {
+	command := []string{"ffmpeg", "-i", "{manifest}", "-map", "0:{index}", "{init}|{segment}", "{url}", "{stream_id}"}
+	stream := StreamInfo{StreamID: "0:3(en)"}
+	urls := []string{"https://cdn.example.com/init.mp4", "https://cdn.example.com/seg-1.m4s"}
+
+	args := expandDecodeCommand(command, "https://example.com/manifest.mpd", "/tmp/input.m4s", urls, stream)
+	expected := []string{"ffmpeg", "-i", "https://example.com/manifest.mpd", "-map", "0:3",
+		"https://cdn.example.com/init.mp4|https://cdn.example.com/seg-1.m4s", "/tmp/input.m4s", "0:3(en)"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	args = expandDecodeCommand([]string{"{init}", "{url}"}, "", urls[1], urls[1:], stream)
+	if !reflect.DeepEqual(args, []string{"", urls[1]}) {
+		t.Errorf("Expected empty init for single URL, got %v", args)
+	}
+}
+
+func TestRunDecodeChecks(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	manifest := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2"
+720p.m3u8`
+
+	tests := []struct {
+		name     string
+		check    DecodeCheck
+		success  bool
+		exitCode int
+		error    string
+		output   string
+	}{
+		{
+			name:    "success with placeholders",
+			check:   DecodeCheck{Command: []string{"sh", "-c", `test "$0" = "https://example.com/live/720p.m3u8" && echo frame`, "{url}"}},
+			success: true,
+			output:  "frame\n",
+		},
+		{
+			name:     "non-zero exit",
+			check:    DecodeCheck{Command: []string{"sh", "-c", "echo broken >&2; exit 3"}},
+			exitCode: 3,
+			error:    "decode command exited with status 3",
+			output:   "broken\n",
+		},
+		{
+			name:     "timeout",
+			check:    DecodeCheck{Command: []string{"sh", "-c", "sleep 5"}, Timeout: 50 * time.Millisecond},
+			exitCode: -1,
+			error:    "decode command timed out after 50ms",
+		},
+		{
+			name:     "unknown stream",
+			check:    DecodeCheck{Command: []string{"true"}, Streams: []string{"0:9"}},
+			exitCode: -1,
+			error:    "not found",
+		},
+		{
+			name:     "missing command",
+			check:    DecodeCheck{},
+			exitCode: -1,
+			error:    "no decode command configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseHLSManifest(manifest, "https://example.com/live/master.m3u8")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			p := NewProber(nil)
+			p.runDecodeChecks(context.Background(), &tt.check, "https://example.com/live/master.m3u8", output)
+
+			if len(output.Decodes) != 1 {
+				t.Fatalf("Expected 1 decode result, got %d", len(output.Decodes))
+			}
+			result := output.Decodes[0]
+			if result.Success != tt.success {
+				t.Errorf("Expected success %v, got %v (%s)", tt.success, result.Success, result.Error)
+			}
+			if result.ExitCode != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.exitCode, result.ExitCode)
+			}
+			if !strings.Contains(result.Error, tt.error) || (tt.error == "" && result.Error != "") {
+				t.Errorf("Expected error containing %q, got %q", tt.error, result.Error)
+			}
+			if tt.output != "" && result.Output != tt.output {
+				t.Errorf("Expected output %q, got %q", tt.output, result.Output)
+			}
+		})
+	}
+}
+
+func TestDecodeStreamsDefaultsToFirstVideo(t *testing.T) {
+	output := &Output{Streams: []StreamInfo{
+		{StreamID: "0:0", Type: "Audio"},
+		{StreamID: "0:1", Type: "Video"},
+		{StreamID: "0:2", Type: "Video"},
+	}}
+
+	streams := decodeStreams(&DecodeCheck{}, output)
+	if len(streams) != 1 || streams[0].StreamID != "0:1" {
+		t.Errorf("Expected first video stream 0:1, got %v", streams)
+	}
+
+	if streams := decodeStreams(&DecodeCheck{}, &Output{Streams: []StreamInfo{{StreamID: "0:0", Type: "Audio"}}}); len(streams) != 0 {
+		t.Errorf("Expected no streams without video, got %v", streams)
+	}
+}
+
+func TestRunDecodeCheckURLs(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/init.mp4":
+			w.Write([]byte("INIT"))
+		case "/seg-1.m4s":
+			w.Write([]byte("SEGMENT"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		urls   []string
+		output string
+		error  string
+	}{
+		{name: "DASH init and media segment", urls: []string{server.URL + "/init.mp4", server.URL + "/seg-1.m4s"}, output: "INITSEGMENT"},
+		{name: "file URI", urls: []string{"file:///etc/passwd"}, error: "not an absolute http(s) URL"},
+		{name: "relative URI", urls: []string{"media/720p.m3u8"}, error: "not an absolute http(s) URL"},
+		{name: "option", urls: []string{"-f"}, error: `starts with "-"`},
+	}
+
+	check := &DecodeCheck{Command: []string{"sh", "-c", `cat "$0"`, "{url}"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &Output{Streams: []StreamInfo{{StreamID: "0:0", Type: "Video", urls: tt.urls}}}
+			NewProber(&ProbeOptions{DisableCamouflage: true}).runDecodeChecks(context.Background(), check, server.URL+"/manifest.mpd", output)
+
+			result := output.Decodes[0]
+			if tt.error != "" {
+				if result.Success || !strings.Contains(result.Error, tt.error) {
+					t.Errorf("Expected error containing %q, got %+v", tt.error, result)
+				}
+				return
+			}
+			if !result.Success || result.Output != tt.output {
+				t.Errorf("Expected output %q, got %+v", tt.output, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeltaUpdate reports the playlist delta update (EXT-X-SKIP) a Watcher
+// requested for an LL-HLS media playlist advertising CAN-SKIP-UNTIL
+type DeltaUpdate struct {
+	// Skip is the _HLS_skip delivery directive requested
+	Skip string `json:"skip"`
+
+	// Supported reports that the origin answered with a delta update rather
+	// than the full playlist
+	Supported bool `json:"supported"`
+
+	// SkippedSegments is the number of segments the delta update skipped,
+	// restored from the previous playlist
+	SkippedSegments int `json:"skipped_segments,omitempty"`
+
+	// Error is why the delta update could not be applied to the previous
+	// playlist; the full playlist was fetched instead
+	Error string `json:"error,omitempty"`
+}
+
+// hlsPlaylistTags are the media playlist tags that are not part of a media
+// segment: the header, and the tags following the last segment
+var hlsPlaylistTags = []string{
+	"#EXTM3U",
+	"#EXT-X-VERSION",
+	"#EXT-X-TARGETDURATION",
+	"#EXT-X-MEDIA-SEQUENCE",
+	"#EXT-X-DISCONTINUITY-SEQUENCE",
+	"#EXT-X-PLAYLIST-TYPE",
+	"#EXT-X-SERVER-CONTROL",
+	"#EXT-X-PART-INF",
+	"#EXT-X-INDEPENDENT-SEGMENTS",
+	"#EXT-X-START",
+	"#EXT-X-DEFINE",
+	"#EXT-X-I-FRAMES-ONLY",
+	"#EXT-X-ALLOW-CACHE",
+	"#EXT-X-SKIP",
+	"#EXT-X-ENDLIST",
+	"#EXT-X-PRELOAD-HINT",
+	"#EXT-X-RENDITION-REPORT",
+}
+
+// isHLSPlaylistTag reports whether line is one of hlsPlaylistTags
+func isHLSPlaylistTag(line string) bool {
+	for _, tag := range hlsPlaylistTags {
+		if line == tag || strings.HasPrefix(line, tag+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// hlsSegmentLines splits a media playlist into the lines of each media
+// segment: its tags and URI. Partial segments after the last URI are left out.
+func hlsSegmentLines(content string) [][]string {
+	var segments [][]string
+	var segment []string
+	for lines := scanLines(content); lines.scan(); {
+		line := strings.TrimSpace(lines.line)
+		switch {
+		case line == "" || isHLSPlaylistTag(line):
+		case strings.HasPrefix(line, "#"):
+			segment = append(segment, line)
+		default:
+			segments = append(segments, append(segment, line))
+			segment = nil
+		}
+	}
+	return segments
+}
+
+// hlsMediaSequence returns the EXT-X-MEDIA-SEQUENCE of a media playlist (0 when absent)
+func hlsMediaSequence(content string) int64 {
+	for lines := scanLines(content); lines.scan(); {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(lines.line), "#EXT-X-MEDIA-SEQUENCE:"); ok {
+			sequence, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return sequence
+		}
+	}
+	return 0
+}
+
+// applyDeltaUpdate reconstructs the full playlist of a delta update by
+// replacing its EXT-X-SKIP with the skipped segments of previous, the last
+// full playlist. It returns the number of skipped segments, or 0 and delta
+// itself when delta is not a delta update.
+func applyDeltaUpdate(previous, delta string) (string, int, error) {
+	var skipped int
+	found := false
+	for lines := scanLines(delta); lines.scan(); {
+		line := strings.TrimSpace(lines.line)
+		if strings.HasPrefix(line, "#EXT-X-SKIP:") {
+			var err error
+			skipped, err = strconv.Atoi(parseHLSAttributes(line)["SKIPPED-SEGMENTS"])
+			if err != nil || skipped < 0 {
+				return "", 0, fmt.Errorf("invalid EXT-X-SKIP: %s", line)
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return delta, 0, nil
+	}
+
+	// The media sequence of a delta update is that of its first skipped segment
+	first := hlsMediaSequence(delta) - hlsMediaSequence(previous)
+	segments := hlsSegmentLines(previous)
+	if first < 0 || first+int64(skipped) > int64(len(segments)) {
+		return "", 0, fmt.Errorf("skipped segments %d-%d are not in the previous playlist",
+			hlsMediaSequence(delta), hlsMediaSequence(delta)+int64(skipped)-1)
+	}
+
+	var full strings.Builder
+	for lines := scanLines(delta); lines.scan(); {
+		line := lines.line
+		if strings.HasPrefix(strings.TrimSpace(line), "#EXT-X-SKIP:") {
+			for _, segment := range segments[first : first+int64(skipped)] {
+				for _, segmentLine := range segment {
+					full.WriteString(segmentLine)
+					full.WriteByte('\n')
+				}
+			}
+			continue
+		}
+		full.WriteString(line)
+		if !lines.done {
+			full.WriteByte('\n')
+		}
+	}
+	return full.String(), skipped, nil
+}
+
+// withSkipDirective appends the _HLS_skip delivery directive to a playlist
+// URL, leaving the existing query as is since signed URLs (e.g. Akamai
+// hdnts tokens) break when their parameters are reordered or re-escaped
+func withSkipDirective(playlistURL, skip string) string {
+	base, fragment, hasFragment := strings.Cut(playlistURL, "#")
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+		if strings.HasSuffix(base, "?") || strings.HasSuffix(base, "&") {
+			separator = ""
+		}
+	}
+	directed := base + separator + "_HLS_skip=" + url.QueryEscape(skip)
+	if hasFragment {
+		directed += "#" + fragment
+	}
+	return directed
+}
+
+// skipDirective returns the _HLS_skip directive to request at now, or "" when
+// the last playlist did not advertise delta updates or is too old: a client
+// may only request one with a playlist younger than half the skip boundary.
+// Date ranges are not skipped (YES rather than v2), so they need no merging.
+func (s *watchState) skipDirective(now time.Time) string {
+	if s.playlist == "" || s.canSkipUntil <= 0 {
+		return ""
+	}
+	if now.Sub(s.playlistTime) > time.Duration(s.canSkipUntil/2*float64(time.Second)) {
+		return ""
+	}
+	return "YES"
+}
+
+// fetchPlaylist fetches the watched manifest, requesting a delta update when
+// the last playlist advertised them, and returns the full playlist with a
+// report of the delta update (nil when none was requested)
+func (w *Watcher) fetchPlaylist(ctx context.Context, state *watchState, now time.Time) (*fetchResult, *DeltaUpdate, error) {
+	skip := state.skipDirective(now)
+	if skip == "" {
+		fetched, err := w.prober.fetch(ctx, w.url)
+		return fetched, nil, err
+	}
+
+	fetched, err := w.prober.fetch(ctx, withSkipDirective(w.url, skip))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	delta := &DeltaUpdate{Skip: skip}
+	full, skipped, err := applyDeltaUpdate(state.playlist, fetched.body)
+	if err == nil {
+		delta.Supported = strings.Contains(fetched.body, "#EXT-X-SKIP:")
+		delta.SkippedSegments = skipped
+		reconstructed := *fetched
+		reconstructed.body = full
+		return &reconstructed, delta, nil
+	}
+
+	logWarn(ctx, "Playlist delta update not applied", map[string]interface{}{
+		"url":   w.prober.redactor.redactString(w.url),
+		"error": err.Error(),
+	})
+	delta.Supported = true
+	delta.Error = err.Error()
+	fetched, err = w.prober.fetch(ctx, w.url)
+	return fetched, delta, err
+}
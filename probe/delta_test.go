@@ -0,0 +1,186 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+const deltaTestPlaylist = `#EXTM3U
+#EXT-X-VERSION:9
+#EXT-X-TARGETDURATION:4
+#EXT-X-SERVER-CONTROL:CAN-SKIP-UNTIL=24.0
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:4.0,
+seg10.m4s
+#EXTINF:4.0,
+seg11.m4s
+#EXT-X-DISCONTINUITY
+#EXTINF:4.0,
+seg12.m4s
+#EXTINF:4.0,
+seg13.m4s
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="seg14.part0.m4s"
+`
+
+const deltaTestUpdate = `#EXTM3U
+#EXT-X-VERSION:9
+#EXT-X-TARGETDURATION:4
+#EXT-X-SERVER-CONTROL:CAN-SKIP-UNTIL=24.0
+#EXT-X-MEDIA-SEQUENCE:11
+#EXT-X-SKIP:SKIPPED-SEGMENTS=2
+#EXTINF:4.0,
+seg13.m4s
+#EXTINF:4.0,
+seg14.m4s
+`
+
+const deltaTestReconstructed = `#EXTM3U
+#EXT-X-VERSION:9
+#EXT-X-TARGETDURATION:4
+#EXT-X-SERVER-CONTROL:CAN-SKIP-UNTIL=24.0
+#EXT-X-MEDIA-SEQUENCE:11
+#EXTINF:4.0,
+seg11.m4s
+#EXT-X-DISCONTINUITY
+#EXTINF:4.0,
+seg12.m4s
+#EXTINF:4.0,
+seg13.m4s
+#EXTINF:4.0,
+seg14.m4s
+`
+
+func TestApplyDeltaUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		delta    string
+		expected string
+		skipped  int
+		wantErr  bool
+	}{
+		{name: "delta update", previous: deltaTestPlaylist, delta: deltaTestUpdate, expected: deltaTestReconstructed, skipped: 2},
+		{name: "full playlist", previous: deltaTestPlaylist, delta: deltaTestPlaylist, expected: deltaTestPlaylist},
+		{
+			name:     "skipped segments missing",
+			previous: deltaTestReconstructed,
+			delta:    "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:10\n#EXT-X-SKIP:SKIPPED-SEGMENTS=2\n#EXTINF:4.0,\nseg12.m4s\n",
+			wantErr:  true,
+		},
+		{name: "invalid skip", previous: deltaTestPlaylist, delta: "#EXTM3U\n#EXT-X-SKIP:SKIPPED-SEGMENTS=x\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			full, skipped, err := applyDeltaUpdate(tt.previous, tt.delta)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if full != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, full)
+			}
+			if skipped != tt.skipped {
+				t.Errorf("Expected %d skipped segments, got %d", tt.skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestWithSkipDirective(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://example.com/live.m3u8", "https://example.com/live.m3u8?_HLS_skip=YES"},
+		{"https://example.com/live.m3u8?", "https://example.com/live.m3u8?_HLS_skip=YES"},
+		{"https://example.com/live.m3u8?b=2&a=1", "https://example.com/live.m3u8?b=2&a=1&_HLS_skip=YES"},
+		{"https://example.com/live.m3u8?hdnts=exp=1~acl=/*~hmac=ab12", "https://example.com/live.m3u8?hdnts=exp=1~acl=/*~hmac=ab12&_HLS_skip=YES"},
+		{"https://example.com/live.m3u8#t=1", "https://example.com/live.m3u8?_HLS_skip=YES#t=1"},
+	}
+
+	for _, tt := range tests {
+		if got := withSkipDirective(tt.url, "YES"); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestMediaPlaylistDeltaUpdateTags(t *testing.T) {
+	playlist := parseHLSMediaPlaylist(deltaTestUpdate, "https://cdn.example.com/live.m3u8")
+	if playlist.CanSkipUntil != 24 {
+		t.Errorf("Expected CAN-SKIP-UNTIL 24, got %v", playlist.CanSkipUntil)
+	}
+	if playlist.SkippedSegments != 2 || playlist.Segments != 2 {
+		t.Errorf("Expected 2 skipped and 2 listed segments, got %d and %d", playlist.SkippedSegments, playlist.Segments)
+	}
+	if features := detectHLSFeatures(deltaTestUpdate); !containsString(features, "ll-hls delta-updates") {
+		t.Errorf("Expected the ll-hls delta-updates feature, got %q", features)
+	}
+}
+
+func TestWatcherDeltaUpdates(t *testing.T) {
+	var mu sync.Mutex
+	var skips []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		skips = append(skips, r.URL.Query().Get("_HLS_skip"))
+		mu.Unlock()
+
+		if r.URL.Query().Get("_HLS_skip") == "YES" {
+			w.Write([]byte(deltaTestUpdate))
+			return
+		}
+		w.Write([]byte(deltaTestPlaylist))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watcher := NewProber(nil).Watch(ctx, server.URL+"/live.m3u8", &WatchOptions{Interval: 10 * time.Millisecond})
+
+	var updates []*Output
+	for len(updates) < 2 {
+		select {
+		case event := <-watcher.Events():
+			if event.Type == EventManifestUpdated {
+				updates = append(updates, event.Output)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Timed out after %d updates", len(updates))
+		}
+	}
+	cancel()
+	for range watcher.Events() {
+	}
+
+	mu.Lock()
+	if len(skips) < 2 || skips[0] != "" || skips[1] != "YES" {
+		t.Errorf("Expected a full request then a delta update request, got %q", skips)
+	}
+	mu.Unlock()
+
+	if updates[0].MediaPlaylist.DeltaUpdate != nil {
+		t.Errorf("Expected no delta update for the first fetch, got %+v", updates[0].MediaPlaylist.DeltaUpdate)
+	}
+	playlist := updates[1].MediaPlaylist
+	expected := &DeltaUpdate{Skip: "YES", Supported: true, SkippedSegments: 2}
+	if !reflect.DeepEqual(playlist.DeltaUpdate, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, playlist.DeltaUpdate)
+	}
+	if playlist.MediaSequence != 11 || playlist.Segments != 4 || playlist.SkippedSegments != 0 {
+		t.Errorf("Expected the reconstructed playlist 11-14, got %+v", playlist)
+	}
+}
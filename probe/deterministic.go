@@ -0,0 +1,117 @@
+package probe
+
+import (
+	"net/url"
+	"sort"
+	"time"
+)
+
+// makeDeterministic zeroes the fields of o that vary between probes of the
+// same manifest (timing, effective URL query strings, build info) and sorts its
+// collections. Streams keep manifest order since stream IDs follow it.
+func (o *Output) makeDeterministic() {
+	o.Timing = nil
+	o.ProgramDateTime = nil
+	o.ProgramVersion = nil
+	o.Cache = nil
+	if o.Provenance != nil {
+		o.Provenance.ProbedAt = time.Time{}
+		o.Provenance.Version = ""
+		o.Provenance.EgressIP = ""
+		o.Provenance.RequestID = ""
+		o.Provenance.ResponseIDs = nil
+	}
+	if o.Event != nil {
+		o.Event.StartsIn = 0
+	}
+	if o.Format != nil {
+		o.Format.LiveEdge = nil
+	}
+	if o.Speculation != nil {
+		o.Speculation.URL = stripQuery(o.Speculation.URL)
+	}
+	for i := range o.Decodes {
+		o.Decodes[i].Duration = 0
+	}
+
+	for i := range o.BaseURLChecks {
+		o.BaseURLChecks[i].URI = stripQuery(o.BaseURLChecks[i].URI)
+		o.BaseURLChecks[i].Duration = 0
+	}
+	for i := range o.Incomplete {
+		o.Incomplete[i].URI = stripQuery(o.Incomplete[i].URI)
+	}
+	for i := range o.Bitstream {
+		o.Bitstream[i].URI = stripQuery(o.Bitstream[i].URI)
+	}
+	for i := range o.Bitrates {
+		o.Bitrates[i].URI = stripQuery(o.Bitrates[i].URI)
+	}
+	if o.Rebuffer != nil {
+		o.Rebuffer.Throughput = 0
+		for i := range o.Rebuffer.Segments {
+			o.Rebuffer.Segments[i].URI = stripQuery(o.Rebuffer.Segments[i].URI)
+			o.Rebuffer.Segments[i].DownloadDuration = 0
+		}
+	}
+	for i := range o.Rules {
+		stripLocationQueries(o.Rules[i].Locations)
+	}
+	for i := range o.Compatibility {
+		stripLocationQueries(o.Compatibility[i].Locations)
+	}
+	sort.SliceStable(o.Bitrates, func(i, j int) bool {
+		return o.Bitrates[i].StreamID < o.Bitrates[j].StreamID
+	})
+
+	for i := range o.Variants {
+		o.Variants[i].URI = stripQuery(o.Variants[i].URI)
+		if playlist := o.Variants[i].Playlist; playlist != nil {
+			playlist.InitSegment = stripQuery(playlist.InitSegment)
+			for j := range playlist.Encryption {
+				playlist.Encryption[j].URI = stripQuery(playlist.Encryption[j].URI)
+			}
+		}
+	}
+
+	for _, entries := range o.Metadata {
+		for i := range entries {
+			entries[i].URI = stripQuery(entries[i].URI)
+		}
+	}
+
+	for i := range o.Interstitials {
+		o.Interstitials[i].AssetURI = stripQuery(o.Interstitials[i].AssetURI)
+		o.Interstitials[i].AssetList = stripQuery(o.Interstitials[i].AssetList)
+	}
+	sort.SliceStable(o.Interstitials, func(i, j int) bool {
+		a, b := o.Interstitials[i], o.Interstitials[j]
+		if a.StartDate != b.StartDate {
+			return a.StartDate < b.StartDate
+		}
+		return a.ID < b.ID
+	})
+
+	sort.Strings(o.Warnings)
+	sort.Strings(o.Features)
+}
+
+// stripQuery removes the query string and fragment of rawURL, where tokens
+// and signatures usually live
+func stripQuery(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	parsed.RawQuery = ""
+	parsed.ForceQuery = false
+	parsed.Fragment = ""
+	return parsed.String()
+}
+
+// stripLocationQueries removes the query strings of finding locations
+func stripLocationQueries(locations []FindingLocation) {
+	for i := range locations {
+		locations[i].URL = stripQuery(locations[i].URL)
+	}
+}
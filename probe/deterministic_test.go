@@ -0,0 +1,96 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMakeDeterministic(t *testing.T) {
+	liveEdge := time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)
+	isLive := true
+	output := &Output{
+		Streams:  []StreamInfo{{StreamID: "0:1"}, {StreamID: "0:0"}},
+		Warnings: []string{"b warning", "a warning"},
+		Features: []string{"xlink", "cmaf"},
+		Timing:   &ManifestTiming{MediaSequence: 42},
+		Format:   &Format{IsLive: &isLive, LiveEdge: &liveEdge},
+		Bitrates: []BitrateStats{
+			{StreamID: "0:1", URI: "https://cdn.example.com/1080p.m3u8?token=abc&exp=123"},
+			{StreamID: "0:0", URI: "https://cdn.example.com/720p.m3u8?token=def#frag"},
+		},
+		Interstitials: []Interstitial{
+			{ID: "ad2", StartDate: "2024-01-01T00:10:00Z", AssetURI: "https://ads.example.com/2.m3u8?session=x"},
+			{ID: "ad1", StartDate: "2024-01-01T00:05:00Z", AssetList: "https://ads.example.com/list.json?session=y"},
+		},
+	}
+
+	output.makeDeterministic()
+
+	if output.Timing != nil {
+		t.Errorf("Expected timing to be omitted, got %+v", output.Timing)
+	}
+	if output.Format.LiveEdge != nil || output.Format.IsLive == nil {
+		t.Errorf("Expected the live edge to be omitted, got %+v", output.Format)
+	}
+	if output.Streams[0].StreamID != "0:1" {
+		t.Errorf("Expected streams to keep manifest order, got %v", output.Streams)
+	}
+	if expected := []string{"a warning", "b warning"}; !reflect.DeepEqual(output.Warnings, expected) {
+		t.Errorf("Expected %v, got %v", expected, output.Warnings)
+	}
+	if expected := []string{"cmaf", "xlink"}; !reflect.DeepEqual(output.Features, expected) {
+		t.Errorf("Expected %v, got %v", expected, output.Features)
+	}
+	if got := output.Bitrates[0].URI; got != "https://cdn.example.com/720p.m3u8" {
+		t.Errorf("Expected %q, got %q", "https://cdn.example.com/720p.m3u8", got)
+	}
+	if got := output.Bitrates[1].URI; got != "https://cdn.example.com/1080p.m3u8" {
+		t.Errorf("Expected %q, got %q", "https://cdn.example.com/1080p.m3u8", got)
+	}
+	if output.Interstitials[0].ID != "ad1" || output.Interstitials[0].AssetList != "https://ads.example.com/list.json" {
+		t.Errorf("Unexpected first interstitial: %+v", output.Interstitials[0])
+	}
+	if got := output.Interstitials[1].AssetURI; got != "https://ads.example.com/2.m3u8" {
+		t.Errorf("Expected %q, got %q", "https://ads.example.com/2.m3u8", got)
+	}
+}
+
+func TestDeterministicProbe(t *testing.T) {
+	publishTimes := []string{"2024-01-01T00:00:00Z", "2024-01-01T00:00:02Z"}
+	served := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		publishTime := publishTimes[served%len(publishTimes)]
+		served++
+		w.Write([]byte(`<?xml version="1.0"?>
+<MPD type="dynamic" publishTime="` + publishTime + `" minimumUpdatePeriod="PT2S">
+  <Period id="1">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <Representation id="v1" codecs="avc1.64001f" width="1280" height="720" bandwidth="3000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`))
+	}))
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{Deterministic: true})
+	results := prober.ProbeBatch(context.Background(), []string{server.URL + "/live.mpd?token=a", server.URL + "/live.mpd?token=b"}, 1)
+
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("Unexpected error: %v", result.Err)
+		}
+		if result.Duration != 0 {
+			t.Errorf("Expected zero duration, got %v", result.Duration)
+		}
+	}
+
+	first, _ := results[0].Output.OutputJSON()
+	second, _ := results[1].Output.OutputJSON()
+	if string(first) != string(second) {
+		t.Errorf("Expected identical outputs, got:\n%s\n%s", first, second)
+	}
+}
@@ -0,0 +1,96 @@
+package probe
+
+import "strings"
+
+// DASH descriptor schemes mapped onto dispositions
+const (
+	dashRoleScheme         = "urn:mpeg:dash:role:2011"
+	dashAudioPurposeScheme = "urn:tva:metadata:cs:AudioPurposeCS:2007"
+)
+
+// Disposition mirrors the ffprobe stream disposition flags relevant to
+// accessibility and alternate audio
+type Disposition struct {
+	Original        bool `json:"original"`
+	Dub             bool `json:"dub"`
+	Comment         bool `json:"comment"`
+	HearingImpaired bool `json:"hearing_impaired"`
+	VisualImpaired  bool `json:"visual_impaired"`
+}
+
+// dispositionOrNil returns d, or nil when no flag is set
+func dispositionOrNil(d Disposition) *Disposition {
+	if d == (Disposition{}) {
+		return nil
+	}
+	return &d
+}
+
+// mpdDisposition maps the Role and Accessibility descriptors of an adaptation set
+func mpdDisposition(adaptationSet AdaptationSet) *Disposition {
+	var d Disposition
+
+	descriptors := append(append([]Descriptor{}, adaptationSet.Roles...), adaptationSet.Accessibility...)
+	for _, descriptor := range descriptors {
+		switch descriptor.SchemeIdUri {
+		case dashRoleScheme:
+			switch descriptor.Value {
+			case "main":
+				d.Original = true
+			case "dub":
+				d.Dub = true
+			case "commentary":
+				d.Comment = true
+			case "caption", "enhanced-audio-intelligibility":
+				d.HearingImpaired = true
+			case "description":
+				d.VisualImpaired = true
+			}
+		case dashAudioPurposeScheme:
+			switch descriptor.Value {
+			case "1":
+				d.VisualImpaired = true
+			case "2":
+				d.HearingImpaired = true
+			}
+		}
+	}
+
+	return dispositionOrNil(d)
+}
+
+// hlsDisposition maps the CHARACTERISTICS attribute of an EXT-X-MEDIA rendition
+func hlsDisposition(characteristics string) *Disposition {
+	var d Disposition
+
+	for _, characteristic := range strings.Split(characteristics, ",") {
+		switch strings.TrimSpace(characteristic) {
+		case "public.accessibility.describes-video":
+			d.VisualImpaired = true
+		case "public.accessibility.transcribes-spoken-dialog", "public.accessibility.describes-music-and-sound":
+			d.HearingImpaired = true
+		}
+	}
+
+	return dispositionOrNil(d)
+}
+
+// has reports whether the named flag (its JSON name, e.g. "visual_impaired") is set
+func (d *Disposition) has(flag string) bool {
+	if d == nil {
+		return false
+	}
+	switch flag {
+	case "original":
+		return d.Original
+	case "dub":
+		return d.Dub
+	case "comment":
+		return d.Comment
+	case "hearing_impaired":
+		return d.HearingImpaired
+	case "visual_impaired":
+		return d.VisualImpaired
+	}
+	return false
+}
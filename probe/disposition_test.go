@@ -0,0 +1,83 @@
+package probe
+
+import "testing"
+
+func TestMPDDisposition(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD type="static">
+  <Period id="1">
+    <AdaptationSet contentType="audio" mimeType="audio/mp4" lang="en">
+      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="main"/>
+      <Representation id="a1" codecs="mp4a.40.2"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="audio" mimeType="audio/mp4" lang="en">
+      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="alternate"/>
+      <Accessibility schemeIdUri="urn:tva:metadata:cs:AudioPurposeCS:2007" value="1"/>
+      <Representation id="a2" codecs="mp4a.40.2"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="audio" mimeType="audio/mp4" lang="fr">
+      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="dub"/>
+      <Representation id="a3" codecs="mp4a.40.2"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="audio" mimeType="audio/mp4" lang="en">
+      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="commentary"/>
+      <Representation id="a4" codecs="mp4a.40.2"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="text" mimeType="application/mp4" lang="en">
+      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="subtitle"/>
+      <Accessibility schemeIdUri="urn:mpeg:dash:role:2011" value="caption"/>
+      <Representation id="t1" codecs="stpp"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="text" mimeType="application/mp4" lang="de">
+      <Representation id="t2" codecs="stpp"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://example.com/vod.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []*Disposition{
+		{Original: true},
+		{VisualImpaired: true},
+		{Dub: true},
+		{Comment: true},
+		{HearingImpaired: true},
+		nil,
+	}
+
+	if len(output.Streams) != len(expected) {
+		t.Fatalf("Expected %d streams, got %d", len(expected), len(output.Streams))
+	}
+
+	for i, stream := range output.Streams {
+		if (stream.Disposition == nil) != (expected[i] == nil) ||
+			(stream.Disposition != nil && *stream.Disposition != *expected[i]) {
+			t.Errorf("Stream %s: expected disposition %+v, got %+v", stream.StreamID, expected[i], stream.Disposition)
+		}
+	}
+}
+
+func TestHLSDisposition(t *testing.T) {
+	tests := []struct {
+		name            string
+		characteristics string
+		expected        *Disposition
+	}{
+		{"audio description", "public.accessibility.describes-video", &Disposition{VisualImpaired: true}},
+		{"captions", "public.accessibility.transcribes-spoken-dialog,public.accessibility.describes-music-and-sound", &Disposition{HearingImpaired: true}},
+		{"none", "", nil},
+		{"unrelated", "public.easy-to-read", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := hlsDisposition(tt.characteristics)
+			if (result == nil) != (tt.expected == nil) || (result != nil && *result != *tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDNSCacheTTL is how long lookups are cached when the resolver does
+// not report record TTLs
+const DefaultDNSCacheTTL = time.Minute
+
+// Resolver looks up host addresses. *net.Resolver implements it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// TTLResolver is a Resolver that also reports the TTL of the records it
+// returns (e.g. one backed by a DNS client library). The standard library
+// resolver does not expose TTLs.
+type TTLResolver interface {
+	Resolver
+	LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error)
+}
+
+// DNSCacheConfig configures the DNS cache shared by a Prober's HTTP clients.
+// Caching lookups keeps high-QPS probing from amplifying DNS traffic.
+type DNSCacheConfig struct {
+	// TTL is how long lookups are cached when the resolver does not report
+	// record TTLs (0 = DefaultDNSCacheTTL)
+	TTL time.Duration
+
+	// MinTTL and MaxTTL bound how long any lookup is cached (0 = unbounded)
+	MinTTL time.Duration
+	MaxTTL time.Duration
+
+	// Resolver performs lookups (nil = net.DefaultResolver)
+	Resolver Resolver
+}
+
+// DNSCacheStats counts DNS cache activity
+type DNSCacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Errors  uint64 `json:"errors"`
+	Entries int    `json:"entries"`
+}
+
+// dnsEntry is a cached lookup
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// dnsLookup is a lookup in progress that concurrent misses wait for
+type dnsLookup struct {
+	done  chan struct{}
+	addrs []net.IPAddr
+	err   error
+}
+
+// dnsCache caches host lookups for dialing
+type dnsCache struct {
+	config   DNSCacheConfig
+	resolver Resolver
+	clock    Clock
+	dialer   *net.Dialer
+
+	mu       sync.Mutex
+	entries  map[string]dnsEntry
+	inflight map[string]*dnsLookup
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+// newDNSCache creates a DNS cache for config (nil when disabled)
+func newDNSCache(config *DNSCacheConfig, clock Clock) *dnsCache {
+	if config == nil {
+		return nil
+	}
+	resolver := config.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &dnsCache{
+		config:   *config,
+		resolver: resolver,
+		clock:    clock,
+		dialer:   &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		entries:  make(map[string]dnsEntry),
+		inflight: make(map[string]*dnsLookup),
+	}
+}
+
+// lookup returns the addresses of host, from the cache when fresh
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[host]; ok && c.clock.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return entry.addrs, nil
+	}
+	if pending, ok := c.inflight[host]; ok {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		select {
+		case <-pending.done:
+			return pending.addrs, pending.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	pending := &dnsLookup{done: make(chan struct{})}
+	c.inflight[host] = pending
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	addrs, ttl, err := c.resolve(ctx, host)
+
+	c.mu.Lock()
+	delete(c.inflight, host)
+	if err == nil {
+		c.entries[host] = dnsEntry{addrs: addrs, expires: c.clock.Now().Add(c.cacheTTL(ttl))}
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		c.errors.Add(1)
+	}
+	pending.addrs, pending.err = addrs, err
+	close(pending.done)
+	return addrs, err
+}
+
+// resolve looks up host, reporting the record TTL when the resolver knows it (0 otherwise)
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	if resolver, ok := c.resolver.(TTLResolver); ok {
+		return resolver.LookupIPAddrTTL(ctx, host)
+	}
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	return addrs, 0, err
+}
+
+// cacheTTL returns how long to cache a lookup with record TTL ttl (0 = unknown)
+func (c *dnsCache) cacheTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		ttl = c.config.TTL
+		if ttl <= 0 {
+			ttl = DefaultDNSCacheTTL
+		}
+	}
+	if c.config.MinTTL > 0 && ttl < c.config.MinTTL {
+		ttl = c.config.MinTTL
+	}
+	if c.config.MaxTTL > 0 && ttl > c.config.MaxTTL {
+		ttl = c.config.MaxTTL
+	}
+	return ttl
+}
+
+// dialContext dials addr through the cached addresses of its host, trying
+// each address in turn
+func (c *dnsCache) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+
+	var errs []error
+	for _, ip := range addrs {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// stats returns the cache counters
+func (c *dnsCache) stats() DNSCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+	return DNSCacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Errors:  c.errors.Load(),
+		Entries: entries,
+	}
+}
+
+// DNSCacheStats returns the DNS cache counters (zero when ProbeOptions.DNSCache is nil)
+func (p *Prober) DNSCacheStats() DNSCacheStats {
+	if p.dnsCache == nil {
+		return DNSCacheStats{}
+	}
+	return p.dnsCache.stats()
+}
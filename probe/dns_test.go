@@ -0,0 +1,144 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResolver resolves every host to a fixed address and counts lookups
+type fakeResolver struct {
+	mu      sync.Mutex
+	lookups int
+	addr    net.IPAddr
+	ttl     time.Duration
+	err     error
+}
+
+func (r *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []net.IPAddr{r.addr}, nil
+}
+
+// fakeTTLResolver also reports a record TTL
+type fakeTTLResolver struct {
+	fakeResolver
+}
+
+func (r *fakeTTLResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	addrs, err := r.LookupIPAddr(ctx, host)
+	return addrs, r.ttl, err
+}
+
+func TestDNSCacheTTL(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   DNSCacheConfig
+		ttl      time.Duration
+		expected time.Duration
+	}{
+		{name: "default", expected: DefaultDNSCacheTTL},
+		{name: "configured", config: DNSCacheConfig{TTL: 5 * time.Minute}, expected: 5 * time.Minute},
+		{name: "record TTL", ttl: 30 * time.Second, expected: 30 * time.Second},
+		{name: "raised to min", config: DNSCacheConfig{MinTTL: 10 * time.Second}, ttl: time.Second, expected: 10 * time.Second},
+		{name: "capped at max", config: DNSCacheConfig{MaxTTL: time.Minute}, ttl: time.Hour, expected: time.Minute},
+		{name: "default capped at max", config: DNSCacheConfig{MaxTTL: 20 * time.Second}, expected: 20 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newDNSCache(&tt.config, NewManualClock(time.Unix(0, 0)))
+			if got := cache.cacheTTL(tt.ttl); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDNSCacheLookup(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	resolver := &fakeTTLResolver{fakeResolver{addr: net.IPAddr{IP: net.IPv4(192, 0, 2, 1)}, ttl: 30 * time.Second}}
+	cache := newDNSCache(&DNSCacheConfig{Resolver: resolver, MinTTL: time.Minute}, clock)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		addrs, err := cache.lookup(ctx, "cdn.example.com")
+		if err != nil || len(addrs) != 1 || !addrs[0].IP.Equal(net.IPv4(192, 0, 2, 1)) {
+			t.Fatalf("Unexpected lookup result %v, %v", addrs, err)
+		}
+	}
+	if resolver.lookups != 1 {
+		t.Errorf("Expected 1 lookup, got %d", resolver.lookups)
+	}
+
+	// The 30s record TTL is raised to MinTTL
+	clock.Advance(45 * time.Second)
+	cache.lookup(ctx, "cdn.example.com")
+	if resolver.lookups != 1 {
+		t.Errorf("Expected the entry to live for MinTTL, got %d lookups", resolver.lookups)
+	}
+
+	clock.Advance(30 * time.Second)
+	cache.lookup(ctx, "cdn.example.com")
+	if resolver.lookups != 2 {
+		t.Errorf("Expected an expired entry to be looked up again, got %d lookups", resolver.lookups)
+	}
+
+	resolver.err = errors.New("SERVFAIL")
+	if _, err := cache.lookup(ctx, "other.example.com"); err == nil {
+		t.Error("Expected a lookup error")
+	}
+	cache.lookup(ctx, "other.example.com")
+	if resolver.lookups != 4 {
+		t.Errorf("Expected failures not to be cached, got %d lookups", resolver.lookups)
+	}
+
+	expected := DNSCacheStats{Hits: 3, Misses: 4, Errors: 2, Entries: 1}
+	if stats := cache.stats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestProberDNSCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720\n720p.m3u8\n"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	resolver := &fakeResolver{addr: net.IPAddr{IP: net.ParseIP(serverURL.Hostname())}}
+	prober := NewProber(&ProbeOptions{DisableCamouflage: true, DNSCache: &DNSCacheConfig{Resolver: resolver}})
+
+	// The host only resolves through the fake resolver
+	manifestURL := "http://probe-dns.invalid:" + serverURL.Port() + "/manifest.m3u8"
+	if _, err := prober.Probe(context.Background(), manifestURL); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if stats := prober.DNSCacheStats(); stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Expected one cached lookup, got %+v", stats)
+	}
+
+	if stats := NewProber(nil).DNSCacheStats(); stats != (DNSCacheStats{}) {
+		t.Errorf("Expected zero stats without a cache, got %+v", stats)
+	}
+}
+
+func TestValidateDNSCacheOptions(t *testing.T) {
+	err := validateProbeOptions(&ProbeOptions{DNSCache: &DNSCacheConfig{MinTTL: time.Hour, MaxTTL: time.Minute}})
+	var probeErr *ProbeError
+	if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeValidation {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package probe
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf16"
+)
+
+// mp4ProtectionScheme is the schemeIdUri of the ContentProtection signalling
+// the common encryption scheme and default key ID, independent of DRM system
+const mp4ProtectionScheme = "urn:mpeg:dash:mp4protection:2011"
+
+// playReadyHeaderRecord is the PlayReady Object record type of a PlayReady Header
+const playReadyHeaderRecord = 1
+
+// ContentProtection is an MPD ContentProtection element
+type ContentProtection struct {
+	SchemeIdUri string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+
+	// DefaultKID is the cenc:default_KID key ID
+	DefaultKID string `xml:"default_KID,attr"`
+
+	// PSSH is the base64 cenc:pssh box
+	PSSH string `xml:"pssh"`
+
+	// PRO is the base64 mspr:pro PlayReady Object
+	PRO string `xml:"pro"`
+}
+
+// DRMInfo is the content protection of a DASH manifest
+type DRMInfo struct {
+	// Schemes are the common encryption schemes ("cenc", "cbcs") of the
+	// urn:mpeg:dash:mp4protection:2011 descriptors
+	Schemes []string `json:"schemes,omitempty"`
+
+	// DefaultKIDs are the cenc:default_KID key IDs
+	DefaultKIDs []string `json:"default_kids,omitempty"`
+
+	// Systems are the DRM systems, one per distinct system and payload
+	Systems []DRMSystem `json:"systems,omitempty"`
+}
+
+// DRMSystem is a DRM system signalled by ContentProtection elements
+type DRMSystem struct {
+	// Name is "widevine", "playready", "fairplay" or "clearkey" ("" for other systems)
+	Name string `json:"name,omitempty"`
+
+	// SystemID is the lowercase system UUID of the schemeIdUri (urn:uuid:...)
+	SystemID string `json:"system_id"`
+
+	// PSSH is the base64 cenc:pssh box
+	PSSH string `json:"pssh,omitempty"`
+
+	// PRO is the base64 mspr:pro PlayReady Object, and LicenseURL the
+	// LA_URL of its PlayReady Header
+	PRO        string `json:"pro,omitempty"`
+	LicenseURL string `json:"license_url,omitempty"`
+
+	// StreamIDs are the streams the system protects
+	StreamIDs []string `json:"stream_ids"`
+}
+
+// mpdDRM collects the ContentProtection elements of the adaptation sets and
+// representations of streams (nil if there are none), with warnings for
+// payloads that do not decode or do not match their system
+func mpdDRM(mpd *MPD, streams []StreamInfo) (*DRMInfo, []string) {
+	var info DRMInfo
+	var warnings []string
+	systems := make(map[string]int)
+	seen := make(map[string]bool)
+	warned := make(map[string]bool)
+
+	addUnique := func(list []string, value string) []string {
+		if value == "" || seen[value] {
+			return list
+		}
+		seen[value] = true
+		return append(list, value)
+	}
+
+	for _, stream := range streams {
+		element := stream.element
+		adaptationSet := mpd.Periods[element.period].AdaptationSets[element.adaptationSet]
+		rep := adaptationSet.Representations[element.representation]
+
+		for _, protection := range append(append([]ContentProtection(nil), adaptationSet.ContentProtections...), rep.ContentProtections...) {
+			scheme := strings.ToLower(strings.TrimSpace(protection.SchemeIdUri))
+			info.DefaultKIDs = addUnique(info.DefaultKIDs, strings.ToLower(strings.TrimSpace(protection.DefaultKID)))
+
+			if scheme == mp4ProtectionScheme {
+				info.Schemes = addUnique(info.Schemes, strings.TrimSpace(protection.Value))
+				continue
+			}
+			systemID, ok := strings.CutPrefix(scheme, "urn:uuid:")
+			if !ok {
+				continue
+			}
+
+			system := DRMSystem{
+				Name:     strings.TrimPrefix(drmSystems[systemID], "drm:"),
+				SystemID: systemID,
+				PSSH:     strings.Join(strings.Fields(protection.PSSH), ""),
+				PRO:      strings.Join(strings.Fields(protection.PRO), ""),
+			}
+			key := system.SystemID + " " + system.PSSH + " " + system.PRO
+			if i, ok := systems[key]; ok {
+				if ids := info.Systems[i].StreamIDs; ids[len(ids)-1] != stream.StreamID {
+					info.Systems[i].StreamIDs = append(ids, stream.StreamID)
+				}
+				continue
+			}
+
+			var problems []string
+			if system.PSSH != "" {
+				if err := checkPSSH(system.PSSH, systemID); err != nil {
+					problems = append(problems, fmt.Sprintf("cenc:pssh of %s: %v", systemID, err))
+				}
+			}
+			if system.PRO != "" {
+				licenseURL, err := playReadyLicenseURL(system.PRO)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("mspr:pro of %s: %v", systemID, err))
+				}
+				system.LicenseURL = licenseURL
+			}
+			for _, problem := range problems {
+				if !warned[problem] {
+					warned[problem] = true
+					warnings = append(warnings, problem)
+				}
+			}
+
+			system.StreamIDs = []string{stream.StreamID}
+			systems[key] = len(info.Systems)
+			info.Systems = append(info.Systems, system)
+		}
+	}
+
+	if len(info.Schemes) == 0 && len(info.DefaultKIDs) == 0 && len(info.Systems) == 0 {
+		return nil, warnings
+	}
+	return &info, warnings
+}
+
+// checkPSSH checks that a base64 pssh box decodes and belongs to systemID
+func checkPSSH(payload, systemID string) error {
+	box, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("invalid base64: %v", err)
+	}
+	if len(box) < 28 || string(box[4:8]) != "pssh" {
+		return fmt.Errorf("not a pssh box")
+	}
+	if id := formatUUID(box[12:28]); id != systemID {
+		return fmt.Errorf("box is for system %s", id)
+	}
+	return nil
+}
+
+// formatUUID formats 16 bytes as a lowercase UUID
+func formatUUID(b []byte) string {
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// playReadyLicenseURL returns the LA_URL of the PlayReady Header of a base64
+// PlayReady Object ("" if the header has none)
+func playReadyLicenseURL(payload string) (string, error) {
+	object, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %v", err)
+	}
+	if len(object) < 6 {
+		return "", fmt.Errorf("truncated PlayReady Object")
+	}
+
+	// Length (4 bytes), record count (2), then records of type (2), length (2) and value
+	records := binary.LittleEndian.Uint16(object[4:6])
+	data := object[6:]
+	for i := 0; i < int(records); i++ {
+		if len(data) < 4 {
+			return "", fmt.Errorf("truncated PlayReady Object")
+		}
+		recordType := binary.LittleEndian.Uint16(data[0:2])
+		length := int(binary.LittleEndian.Uint16(data[2:4]))
+		if len(data) < 4+length {
+			return "", fmt.Errorf("truncated PlayReady Object")
+		}
+		value := data[4 : 4+length]
+		data = data[4+length:]
+		if recordType != playReadyHeaderRecord {
+			continue
+		}
+
+		header := decodeUTF16LE(value)
+		start := strings.Index(header, "<LA_URL>")
+		end := strings.Index(header, "</LA_URL>")
+		if start < 0 || end < start {
+			return "", nil
+		}
+		return html.UnescapeString(strings.TrimSpace(header[start+len("<LA_URL>") : end])), nil
+	}
+	return "", nil
+}
+
+// decodeUTF16LE decodes little-endian UTF-16 text
+func decodeUTF16LE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return strings.TrimPrefix(string(utf16.Decode(units)), "\ufeff")
+}
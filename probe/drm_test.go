@@ -0,0 +1,163 @@
+package probe
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const (
+	widevineID  = "edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"
+	playReadyID = "9a04f079-9840-4286-ab92-e65be0885f95"
+)
+
+// testPSSH builds a base64 pssh box for systemID
+func testPSSH(systemID string) string {
+	id, _ := hex.DecodeString(strings.ReplaceAll(systemID, "-", ""))
+	data := []byte{0x12, 0x10, 0x01, 0x02}
+	box := binary.BigEndian.AppendUint32(nil, uint32(32+len(data)))
+	box = append(box, "pssh"...)
+	box = append(box, 0, 0, 0, 0)
+	box = append(box, id...)
+	box = binary.BigEndian.AppendUint32(box, uint32(len(data)))
+	return base64.StdEncoding.EncodeToString(append(box, data...))
+}
+
+// testPRO builds a base64 PlayReady Object with a PlayReady Header
+func testPRO(header string) string {
+	var record []byte
+	for _, unit := range utf16.Encode([]rune(header)) {
+		record = binary.LittleEndian.AppendUint16(record, unit)
+	}
+	object := binary.LittleEndian.AppendUint32(nil, uint32(10+len(record)))
+	object = binary.LittleEndian.AppendUint16(object, 1)
+	object = binary.LittleEndian.AppendUint16(object, playReadyHeaderRecord)
+	object = binary.LittleEndian.AppendUint16(object, uint16(len(record)))
+	return base64.StdEncoding.EncodeToString(append(object, record...))
+}
+
+func TestMPDDRM(t *testing.T) {
+	pssh := testPSSH(widevineID)
+	pro := testPRO(`<WRMHEADER version="4.0.0.0"><DATA><LA_URL>https://license.example.com/rightsmanager.asmx?a=1&amp;b=2</LA_URL></DATA></WRMHEADER>`)
+
+	tests := []struct {
+		name     string
+		mpd      string
+		expected *DRMInfo
+		warnings []string
+	}{
+		{
+			name: "widevine and playready on the adaptation set",
+			mpd: `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:cenc="urn:mpeg:cenc:2013" xmlns:mspr="urn:microsoft:playready" type="static"><Period>
+<AdaptationSet contentType="video">
+<ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc" cenc:default_KID="10000000-1000-1000-1000-100000000001"/>
+<ContentProtection schemeIdUri="urn:uuid:EDEF8BA9-79D6-4ACE-A3C8-27DCD51D21ED"><cenc:pssh>` + pssh + `</cenc:pssh></ContentProtection>
+<ContentProtection schemeIdUri="urn:uuid:9a04f079-9840-4286-ab92-e65be0885f95"><mspr:pro>` + pro + `</mspr:pro></ContentProtection>
+<Representation id="v1" bandwidth="1000000"/><Representation id="v2" bandwidth="2000000"/></AdaptationSet></Period></MPD>`,
+			expected: &DRMInfo{
+				Schemes:     []string{"cenc"},
+				DefaultKIDs: []string{"10000000-1000-1000-1000-100000000001"},
+				Systems: []DRMSystem{
+					{Name: "widevine", SystemID: widevineID, PSSH: pssh, StreamIDs: []string{"0:0", "0:1"}},
+					{Name: "playready", SystemID: playReadyID, PRO: pro, LicenseURL: "https://license.example.com/rightsmanager.asmx?a=1&b=2", StreamIDs: []string{"0:0", "0:1"}},
+				},
+			},
+		},
+		{
+			name: "representation payloads and systems without data",
+			mpd: `<MPD type="static"><Period><AdaptationSet contentType="audio">
+<ContentProtection schemeIdUri="urn:uuid:94ce86fb-07ff-4f43-adb8-93d2fa968ca2"/>
+<Representation id="a1" bandwidth="128000"><ContentProtection schemeIdUri="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"><pssh>` + pssh + `</pssh></ContentProtection></Representation>
+<Representation id="a2" bandwidth="64000"><ContentProtection schemeIdUri="urn:uuid:e2719d58-a985-b3c9-781a-b030af78d30e"/></Representation>
+</AdaptationSet></Period></MPD>`,
+			expected: &DRMInfo{
+				Systems: []DRMSystem{
+					{Name: "fairplay", SystemID: "94ce86fb-07ff-4f43-adb8-93d2fa968ca2", StreamIDs: []string{"0:0", "0:1"}},
+					{Name: "widevine", SystemID: widevineID, PSSH: pssh, StreamIDs: []string{"0:0"}},
+					{Name: "clearkey", SystemID: "e2719d58-a985-b3c9-781a-b030af78d30e", StreamIDs: []string{"0:1"}},
+				},
+			},
+		},
+		{
+			name: "mismatched and invalid payloads",
+			mpd: `<MPD type="static"><Period><AdaptationSet contentType="video">
+<ContentProtection schemeIdUri="urn:uuid:9a04f079-9840-4286-ab92-e65be0885f95"><pssh>` + pssh + `</pssh><pro>not base64!</pro></ContentProtection>
+<Representation id="v" bandwidth="1000000"/></AdaptationSet></Period></MPD>`,
+			expected: &DRMInfo{
+				Systems: []DRMSystem{
+					{Name: "playready", SystemID: playReadyID, PSSH: pssh, PRO: "notbase64!", StreamIDs: []string{"0:0"}},
+				},
+			},
+			warnings: []string{
+				"cenc:pssh of " + playReadyID + ": box is for system " + widevineID,
+				"mspr:pro of " + playReadyID + ": invalid base64: illegal base64 data at input byte 9",
+			},
+		},
+		{
+			name: "unprotected",
+			mpd: `<MPD type="static"><Period><AdaptationSet contentType="video">
+<Representation id="v" bandwidth="1000000"/></AdaptationSet></Period></MPD>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseMPDManifest(tt.mpd, "https://cdn.example.com/manifest.mpd")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.DRM, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, output.DRM)
+			}
+			if !reflect.DeepEqual(output.Warnings, tt.warnings) {
+				t.Errorf("Expected warnings %q, got %q", tt.warnings, output.Warnings)
+			}
+		})
+	}
+}
+
+func TestPlayReadyLicenseURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected string
+		err      string
+	}{
+		{
+			name:     "header with LA_URL",
+			payload:  testPRO(`<WRMHEADER><DATA><LA_URL> https://pr.example.com/license </LA_URL></DATA></WRMHEADER>`),
+			expected: "https://pr.example.com/license",
+		},
+		{
+			name:    "header without LA_URL",
+			payload: testPRO(`<WRMHEADER><DATA><KID>abc</KID></DATA></WRMHEADER>`),
+		},
+		{
+			name:    "truncated object",
+			payload: base64.StdEncoding.EncodeToString([]byte{10, 0, 0, 0, 1, 0, 1, 0}),
+			err:     "truncated PlayReady Object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := playReadyLicenseURL(tt.payload)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package probe
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"strings"
+	"time"
 )
 
 // ErrorType represents different categories of errors
@@ -19,14 +23,36 @@ const (
 	ErrorTypeTimeout ErrorType = "timeout"
 	// ErrorTypeAuth indicates authentication/authorization errors
 	ErrorTypeAuth ErrorType = "auth"
+	// ErrorTypeCircuitOpen indicates a request rejected by an open circuit breaker
+	ErrorTypeCircuitOpen ErrorType = "circuit_open"
 )
 
-// ProbeError represents a structured error with context
+// Sentinel errors for errors.Is checks. They are matched through the
+// returned error's chain, so they work on ProbeErrors as well.
+var (
+	// ErrCircuitOpen is returned when the circuit breaker rejects a request
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrManifestTooLarge is returned when a manifest exceeds the body size limit
+	ErrManifestTooLarge = errors.New("manifest too large")
+	// ErrUnsupportedFormat is returned when content is neither an HLS playlist nor an MPD
+	ErrUnsupportedFormat = errors.New("unsupported manifest format")
+	// ErrNotFound is returned when the server reports the manifest does not exist (HTTP 404/410)
+	ErrNotFound = errors.New("manifest not found")
+)
+
+// ProbeError represents a structured error with context.
+// Errors returned by a Prober carry a scrubbed URL; use RawURL to get the original.
 type ProbeError struct {
 	Type    ErrorType `json:"type"`
 	Message string    `json:"message"`
 	URL     string    `json:"url,omitempty"`
 	Cause   error     `json:"-"`
+
+	// RetryAfter is the time until the circuit breaker allows a half-open attempt (circuit_open only)
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+
+	rawURL   string
+	sentinel error
 }
 
 // Error implements the error interface
@@ -42,6 +68,20 @@ func (e *ProbeError) Unwrap() error {
 	return e.Cause
 }
 
+// Is reports whether the error matches target, one of the package's sentinel errors
+func (e *ProbeError) Is(target error) bool {
+	return e.sentinel != nil && e.sentinel == target
+}
+
+// RawURL returns the unredacted URL the error refers to.
+// Avoid logging it: it may contain tokens, signatures, or credentials.
+func (e *ProbeError) RawURL() string {
+	if e.rawURL != "" {
+		return e.rawURL
+	}
+	return e.URL
+}
+
 // IsType checks if the error is of a specific type
 func (e *ProbeError) IsType(errorType ErrorType) bool {
 	return e.Type == errorType
@@ -67,6 +107,19 @@ func NewParsingError(url string, format string, cause error) *ProbeError {
 	}
 }
 
+// NewContentTypeError creates an error for a response Content-Type that does
+// not match the detected manifest format
+func NewContentTypeError(url string, format string, contentType string) *ProbeError {
+	if contentType == "" {
+		contentType = "none"
+	}
+	return &ProbeError{
+		Type:    ErrorTypeParsing,
+		Message: fmt.Sprintf("unexpected Content-Type %q for %s manifest", contentType, format),
+		URL:     url,
+	}
+}
+
 // NewValidationError creates a new validation-related error
 func NewValidationError(message string) *ProbeError {
 	return &ProbeError{
@@ -84,6 +137,23 @@ func NewTimeoutError(url string, timeoutSeconds int) *ProbeError {
 	}
 }
 
+// newDeadlineError creates the timeout error of a retry loop giving up
+// because no attempt fits before the deadline. It matches
+// context.DeadlineExceeded and wraps the error of the last attempt.
+func newDeadlineError(lastErr error) *ProbeError {
+	err := &ProbeError{
+		Type:     ErrorTypeTimeout,
+		Message:  "not enough time left before the deadline to retry",
+		Cause:    lastErr,
+		sentinel: context.DeadlineExceeded,
+	}
+	var probeErr *ProbeError
+	if errors.As(lastErr, &probeErr) {
+		err.URL, err.rawURL = probeErr.URL, probeErr.rawURL
+	}
+	return err
+}
+
 // NewAuthError creates a new authentication-related error
 func NewAuthError(url string, statusCode int) *ProbeError {
 	return &ProbeError{
@@ -93,6 +163,32 @@ func NewAuthError(url string, statusCode int) *ProbeError {
 	}
 }
 
+// NewNotFoundError creates an error for a manifest the server reports as missing.
+// It matches ErrNotFound and, like other client errors, is not retried.
+func NewNotFoundError(url string, statusCode int) *ProbeError {
+	return &ProbeError{
+		Type:     ErrorTypeAuth,
+		Message:  fmt.Sprintf("manifest not found (HTTP %d)", statusCode),
+		URL:      url,
+		sentinel: ErrNotFound,
+	}
+}
+
+// NewCircuitOpenError creates an error for a request rejected by the circuit breaker.
+// It matches ErrCircuitOpen and is never retried by default.
+func NewCircuitOpenError(retryAfter time.Duration) *ProbeError {
+	message := "circuit breaker is open"
+	if retryAfter > 0 {
+		message = fmt.Sprintf("circuit breaker is open (next attempt in %s)", retryAfter.Round(time.Millisecond))
+	}
+	return &ProbeError{
+		Type:       ErrorTypeCircuitOpen,
+		Message:    message,
+		RetryAfter: retryAfter,
+		sentinel:   ErrCircuitOpen,
+	}
+}
+
 // validateURL validates and normalizes a URL
 func validateURL(rawURL string) (*url.URL, error) {
 	if rawURL == "" {
@@ -125,6 +221,13 @@ func validateProbeOptions(opts *ProbeOptions) error {
 		if _, err := url.Parse(opts.ProxyURL); err != nil {
 			return NewValidationError(fmt.Sprintf("invalid proxy URL: %v", err))
 		}
+		if fetchTransport {
+			return NewValidationError("proxies are not supported by the browser Fetch API")
+		}
+	}
+
+	if opts.DNSCache != nil && fetchTransport {
+		return NewValidationError("DNS caching is not supported by the browser Fetch API")
 	}
 
 	if opts.TimeoutSeconds < 0 {
@@ -135,5 +238,67 @@ func validateProbeOptions(opts *ProbeOptions) error {
 		return NewValidationError("timeout cannot exceed 300 seconds")
 	}
 
+	if err := validateHostOverrides(opts.PerHostOverrides); err != nil {
+		return err
+	}
+
+	if !validStreamOrder(opts.StreamOrder) {
+		return NewValidationError(fmt.Sprintf("unknown stream order %q", opts.StreamOrder))
+	}
+
+	if !validPeriodMode(opts.Periods) {
+		return NewValidationError(fmt.Sprintf("unknown period mode %q", opts.Periods))
+	}
+
+	if !validOutputSchema(opts.Schema) {
+		return NewValidationError(fmt.Sprintf("unknown output schema %q", opts.Schema))
+	}
+
+	if opts.SniffBytes < 0 {
+		return NewValidationError("sniff size cannot be negative")
+	}
+
+	for _, pack := range opts.RulePacks {
+		if _, ok := RulePack(pack); !ok {
+			return NewValidationError(fmt.Sprintf("unknown rule pack: %s (available: %s)", pack, strings.Join(RulePacks(), ", ")))
+		}
+	}
+
+	if throttle := opts.Throttle; throttle != nil {
+		if throttle.BitsPerSecond <= 0 {
+			return NewValidationError("throttle bandwidth must be positive")
+		}
+		if throttle.BurstBytes < 0 || throttle.Segments < 0 {
+			return NewValidationError("throttle burst and segment count cannot be negative")
+		}
+	}
+
+	if dns := opts.DNSCache; dns != nil {
+		if dns.TTL < 0 || dns.MinTTL < 0 || dns.MaxTTL < 0 {
+			return NewValidationError("DNS cache TTLs cannot be negative")
+		}
+		if dns.MaxTTL > 0 && dns.MinTTL > dns.MaxTTL {
+			return NewValidationError("DNS cache MinTTL cannot exceed MaxTTL")
+		}
+	}
+
+	if cache := opts.ParseCache; cache != nil && cache.MaxEntries < 0 {
+		return NewValidationError("parse cache size cannot be negative")
+	}
+
+	if scheduler := opts.Scheduler; scheduler != nil && scheduler.Workers < 0 {
+		return NewValidationError("scheduler workers cannot be negative")
+	}
+
+	if err := validateProvenance(opts.Provenance); err != nil {
+		return err
+	}
+
+	if limits := opts.Limits; limits != nil {
+		if limits.MaxConnsPerHost < 0 || limits.MaxIdleConns < 0 || limits.MaxConcurrentSubRequests < 0 || limits.MaxBodyBytes < 0 {
+			return NewValidationError("limits cannot be negative")
+		}
+	}
+
 	return nil
 }
\ No newline at end of file
@@ -2,7 +2,10 @@ package probe
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestProbeError(t *testing.T) {
@@ -42,6 +45,12 @@ func TestProbeError(t *testing.T) {
 			expected: "auth: authentication failed (HTTP 401)",
 			isType: ErrorTypeAuth,
 		},
+		{
+			name: "circuit open error",
+			error: NewCircuitOpenError(1500 * time.Millisecond),
+			expected: "circuit_open: circuit breaker is open (next attempt in 1.5s)",
+			isType: ErrorTypeCircuitOpen,
+		},
 	}
 
 	for _, tt := range tests {
@@ -172,6 +181,14 @@ func TestValidateProbeOptions(t *testing.T) {
 			expectError: true,
 			errorType:   ErrorTypeValidation,
 		},
+		{
+			name: "negative limits",
+			opts: &ProbeOptions{
+				Limits: &Limits{MaxConnsPerHost: -1},
+			},
+			expectError: true,
+			errorType:   ErrorTypeValidation,
+		},
 	}
 
 	for _, tt := range tests {
@@ -200,4 +217,55 @@ func TestValidateProbeOptions(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestSentinelErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.mpd":
+			http.NotFound(w, r)
+		case "/page.mpd":
+			w.Write([]byte("not a manifest"))
+		default:
+			w.Write([]byte(watchTestManifest))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		opts     *ProbeOptions
+		expected error
+	}{
+		{
+			name:     "not found",
+			path:     "/missing.mpd",
+			expected: ErrNotFound,
+		},
+		{
+			name:     "unsupported format",
+			path:     "/page.mpd",
+			expected: ErrUnsupportedFormat,
+		},
+		{
+			name:     "too large",
+			path:     "/live.m3u8",
+			opts:     &ProbeOptions{Limits: &Limits{MaxBodyBytes: 10}},
+			expected: ErrManifestTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ProbeManifest(server.URL+tt.path, tt.opts)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+
+			var probeErr *ProbeError
+			if !errors.As(err, &probeErr) {
+				t.Errorf("Expected ProbeError, got %T", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package probe
+
+import (
+	"strings"
+	"time"
+)
+
+// EventStateUpcoming reports a live event that has not started yet
+const EventStateUpcoming = "upcoming"
+
+// Reasons an event is reported upcoming
+const (
+	// EventReasonAvailabilityStartTime: a dynamic MPD's availabilityStartTime
+	// (plus the first Period@start) is in the future
+	EventReasonAvailabilityStartTime = "availability_start_time"
+	// EventReasonEmptyEventPlaylist: an HLS EVENT playlist has no segments yet
+	EventReasonEmptyEventPlaylist = "empty_event_playlist"
+)
+
+// EventStatus reports a live event that has not started yet, so pre-event
+// monitoring can tell it apart from a broken manifest with no streams
+type EventStatus struct {
+	// State is EventStateUpcoming
+	State string `json:"state"`
+
+	// Reason is what indicated the state (EventReason*)
+	Reason string `json:"reason"`
+
+	// StartsAt is the scheduled start, when the manifest declares it
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+
+	// StartsIn is the time from the probe until StartsAt
+	StartsIn time.Duration `json:"starts_in,omitempty"`
+}
+
+// mpdScheduledStart returns when a dynamic MPD's first period becomes
+// available (zero if unknown)
+func mpdScheduledStart(mpd *MPD) time.Time {
+	if mpd.Type != "dynamic" || mpd.AvailabilityStartTime == "" {
+		return time.Time{}
+	}
+	start, err := time.Parse(time.RFC3339, strings.TrimSpace(mpd.AvailabilityStartTime))
+	if err != nil {
+		return time.Time{}
+	}
+	if len(mpd.Periods) > 0 && mpd.Periods[0].Start != "" {
+		if offset, err := parseISODuration(mpd.Periods[0].Start); err == nil {
+			start = start.Add(offset)
+		}
+	}
+	return start
+}
+
+// detectEventStatus reports an upcoming event at now (nil if the
+// presentation has started or is not a live event)
+func detectEventStatus(output *Output, body string, now time.Time) *EventStatus {
+	if !output.scheduledStart.IsZero() && output.scheduledStart.After(now) {
+		startsAt := output.scheduledStart
+		return &EventStatus{
+			State:    EventStateUpcoming,
+			Reason:   EventReasonAvailabilityStartTime,
+			StartsAt: &startsAt,
+			StartsIn: startsAt.Sub(now),
+		}
+	}
+
+	if isEmptyEventPlaylist(body) {
+		return &EventStatus{State: EventStateUpcoming, Reason: EventReasonEmptyEventPlaylist}
+	}
+
+	return nil
+}
+
+// isEmptyEventPlaylist reports whether content is an HLS EVENT media
+// playlist that has not published any segment yet
+func isEmptyEventPlaylist(content string) bool {
+	event := false
+	for lines := scanLines(content); lines.scan(); {
+		line := strings.TrimSpace(lines.line)
+		switch {
+		case line == "#EXT-X-PLAYLIST-TYPE:EVENT":
+			event = true
+		case strings.HasPrefix(line, "#EXT-X-ENDLIST"), strings.HasPrefix(line, "#EXT-X-STREAM-INF:"), strings.HasPrefix(line, "#EXTINF:"):
+			return false
+		case line != "" && !strings.HasPrefix(line, "#"):
+			return false
+		}
+	}
+	return event
+}
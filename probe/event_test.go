@@ -0,0 +1,115 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDetectEventStatus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		manifest string
+		reason   string
+		startsAt string
+		startsIn time.Duration
+	}{
+		{
+			name:     "MPD before availabilityStartTime",
+			manifest: `<MPD type="dynamic" availabilityStartTime="2024-06-01T13:00:00Z"><Period id="p0" start="PT0S"/></MPD>`,
+			reason:   EventReasonAvailabilityStartTime,
+			startsAt: "2024-06-01T13:00:00Z",
+			startsIn: time.Hour,
+		},
+		{
+			name:     "MPD before first period start",
+			manifest: `<MPD type="dynamic" availabilityStartTime="2024-06-01T11:00:00Z"><Period id="p0" start="PT1H30M"/></MPD>`,
+			reason:   EventReasonAvailabilityStartTime,
+			startsAt: "2024-06-01T12:30:00Z",
+			startsIn: 30 * time.Minute,
+		},
+		{
+			name:     "MPD already started",
+			manifest: `<MPD type="dynamic" availabilityStartTime="2024-06-01T11:00:00Z"><Period id="p0"/></MPD>`,
+		},
+		{
+			name:     "static MPD",
+			manifest: `<MPD type="static" availabilityStartTime="2024-06-01T13:00:00Z"><Period id="p0"/></MPD>`,
+		},
+		{
+			name:     "empty HLS event playlist",
+			manifest: "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:0\n",
+			reason:   EventReasonEmptyEventPlaylist,
+		},
+		{
+			name:     "HLS event playlist with segments",
+			manifest: "#EXTM3U\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg0.ts\n",
+		},
+		{
+			name:     "empty HLS live playlist",
+			manifest: "#EXTM3U\n#EXT-X-TARGETDURATION:6\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output *Output
+			var err error
+			if manifestFormat(tt.manifest) == "HLS" {
+				output, err = parseHLSManifest(tt.manifest, "https://example.com/live.m3u8")
+			} else {
+				output, err = parseMPDManifest(tt.manifest, "https://example.com/live.mpd")
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			status := detectEventStatus(output, tt.manifest, now)
+			if tt.reason == "" {
+				if status != nil {
+					t.Errorf("Expected no event status, got %+v", status)
+				}
+				return
+			}
+			if status == nil || status.State != EventStateUpcoming || status.Reason != tt.reason {
+				t.Fatalf("Expected upcoming (%s), got %+v", tt.reason, status)
+			}
+			if tt.startsAt == "" {
+				if status.StartsAt != nil {
+					t.Errorf("Expected no start time, got %v", status.StartsAt)
+				}
+				return
+			}
+			if status.StartsAt == nil || status.StartsAt.Format(time.RFC3339) != tt.startsAt {
+				t.Errorf("Expected start %s, got %v", tt.startsAt, status.StartsAt)
+			}
+			if status.StartsIn != tt.startsIn {
+				t.Errorf("Expected starts in %v, got %v", tt.startsIn, status.StartsIn)
+			}
+		})
+	}
+}
+
+func TestProbeUpcomingEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<MPD type="dynamic" availabilityStartTime="2024-06-01T12:10:00Z" minimumUpdatePeriod="PT10S"/>`))
+	}))
+	defer server.Close()
+
+	clock := NewManualClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	output, err := NewProber(&ProbeOptions{DisableCamouflage: true, Clock: clock}).Probe(context.Background(), server.URL+"/live.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if output.Event == nil || output.Event.StartsIn != 10*time.Minute {
+		t.Errorf("Expected an event starting in 10m, got %+v", output.Event)
+	}
+	if len(output.Streams) != 0 {
+		t.Errorf("Expected no streams before the event, got %d", len(output.Streams))
+	}
+}
@@ -0,0 +1,131 @@
+package probe
+
+import (
+	"encoding/xml"
+	"strings"
+	"unicode/utf8"
+)
+
+// mpdElementIndex locates a Representation by its position in the MPD
+type mpdElementIndex struct {
+	period         int
+	adaptationSet  int
+	representation int
+}
+
+// addExcerpts sets the Excerpt of each stream to the manifest source it was
+// derived from, truncated to limit bytes
+func addExcerpts(output *Output, body string, limit int) {
+	if manifestFormat(body) == "HLS" {
+		lines := strings.Split(body, "\n")
+		for i := range output.Streams {
+			output.Streams[i].Excerpt = truncateExcerpt(hlsExcerpt(lines, output.Streams[i].Source), limit)
+		}
+		return
+	}
+
+	excerpts := mpdExcerpts(body)
+	for i := range output.Streams {
+		output.Streams[i].Excerpt = truncateExcerpt(excerpts[output.Streams[i].element], limit)
+	}
+}
+
+// hlsExcerpt returns the tag line of source, followed by the URI line for a variant
+func hlsExcerpt(lines []string, source *SourceRef) string {
+	if source == nil || source.Line < 1 || source.Line > len(lines) {
+		return ""
+	}
+
+	excerpt := []string{strings.TrimRight(lines[source.Line-1], "\r")}
+	if source.Tag == "EXT-X-STREAM-INF" {
+		for _, line := range lines[source.Line:] {
+			line = strings.TrimRight(line, "\r")
+			excerpt = append(excerpt, line)
+			if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				break
+			}
+		}
+	}
+	return strings.Join(excerpt, "\n")
+}
+
+// mpdExcerpts returns the raw XML of every Representation, preceded by the
+// start tag of its AdaptationSet
+func mpdExcerpts(content string) map[mpdElementIndex]string {
+	excerpts := make(map[mpdElementIndex]string)
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	index := mpdElementIndex{period: -1, adaptationSet: -1, representation: -1}
+	var adaptationSetTag string
+	var representationStart int64
+
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return excerpts
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "Period":
+				index.period++
+				index.adaptationSet = -1
+			case "AdaptationSet":
+				index.adaptationSet++
+				index.representation = -1
+				adaptationSetTag = content[start:decoder.InputOffset()]
+			case "Representation":
+				index.representation++
+				representationStart = start
+			}
+		case xml.EndElement:
+			if element.Name.Local == "Representation" {
+				excerpts[index] = adaptationSetTag + "\n" + content[representationStart:decoder.InputOffset()]
+			}
+		}
+	}
+}
+
+// mpdRepresentationOffsets returns the byte offset of every Representation start tag
+func mpdRepresentationOffsets(content string) map[mpdElementIndex]int64 {
+	offsets := make(map[mpdElementIndex]int64)
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	index := mpdElementIndex{period: -1, adaptationSet: -1, representation: -1}
+
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return offsets
+		}
+
+		if element, ok := token.(xml.StartElement); ok {
+			switch element.Name.Local {
+			case "Period":
+				index.period++
+				index.adaptationSet = -1
+			case "AdaptationSet":
+				index.adaptationSet++
+				index.representation = -1
+			case "Representation":
+				index.representation++
+				offsets[index] = start
+			}
+		}
+	}
+}
+
+// truncateExcerpt shortens excerpt to at most limit bytes, marking the cut with "..."
+func truncateExcerpt(excerpt string, limit int) string {
+	if len(excerpt) <= limit {
+		return excerpt
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(excerpt[cut]) {
+		cut--
+	}
+	return excerpt[:cut] + "..."
+}
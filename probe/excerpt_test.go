@@ -0,0 +1,79 @@
+package probe
+
+import "testing"
+
+func TestMPDExcerpts(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD type="static">
+  <Period id="1">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <Representation id="v1" codecs="avc1.64001f" width="1280" height="720"/>
+      <Representation id="v2" codecs="avc1.640028" width="1920" height="1080">
+        <BaseURL>1080p/</BaseURL>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet id="2" contentType="audio" mimeType="audio/mp4" lang="en">
+      <Representation id="a1" codecs="mp4a.40.2"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://example.com/vod.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	addExcerpts(output, manifest, 1000)
+
+	expected := []string{
+		`<AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+<Representation id="v1" codecs="avc1.64001f" width="1280" height="720"/>`,
+		`<AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+<Representation id="v2" codecs="avc1.640028" width="1920" height="1080">
+        <BaseURL>1080p/</BaseURL>
+      </Representation>`,
+		`<AdaptationSet id="2" contentType="audio" mimeType="audio/mp4" lang="en">
+<Representation id="a1" codecs="mp4a.40.2"/>`,
+	}
+	for i, stream := range output.Streams {
+		if stream.Excerpt != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], stream.Excerpt)
+		}
+	}
+}
+
+func TestHLSExcerpts(t *testing.T) {
+	manifest := "#EXTM3U\r\n" +
+		"#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID=\"angles\",NAME=\"Wide\",URI=\"wide.m3u8\"\r\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS=\"avc1.64001f,mp4a.40.2\",VIDEO=\"angles\"\r\n" +
+		"720p.m3u8\r\n"
+
+	output, err := parseHLSManifest(manifest, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	addExcerpts(output, manifest, 40)
+
+	variant := "#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESO..."
+	expected := []string{variant, variant, `#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="angles...`}
+	for i, stream := range output.Streams {
+		if stream.Excerpt != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], stream.Excerpt)
+		}
+	}
+
+	addExcerpts(output, manifest, 1000)
+	full := "#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS=\"avc1.64001f,mp4a.40.2\",VIDEO=\"angles\"\n720p.m3u8"
+	if output.Streams[0].Excerpt != full {
+		t.Errorf("Expected %q, got %q", full, output.Streams[0].Excerpt)
+	}
+}
+
+func TestTruncateExcerpt(t *testing.T) {
+	if got := truncateExcerpt("short", 10); got != "short" {
+		t.Errorf("Expected %q, got %q", "short", got)
+	}
+	// Never split a multi-byte character
+	if got := truncateExcerpt("ab€cd", 3); got != "ab..." {
+		t.Errorf("Expected %q, got %q", "ab...", got)
+	}
+}
@@ -0,0 +1,201 @@
+package probe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expectations declares requirements on probe output, replacing ad-hoc QC
+// scripts. Evaluate checks them and reports one finding per requirement.
+type Expectations struct {
+	// Streams lists stream count requirements
+	Streams []StreamExpectation `json:"streams,omitempty"`
+
+	// SubtitlesForAudio requires a subtitle stream for every audio language
+	SubtitlesForAudio bool `json:"subtitles_for_audio,omitempty"`
+}
+
+// StreamExpectation requires a minimum number of matching streams. When
+// Languages is set, the requirement applies to each language separately.
+type StreamExpectation struct {
+	// Type is the stream type: "Video", "Audio" or "Subtitle" ("" = any)
+	Type string `json:"type,omitempty"`
+
+	// Languages lists languages that each need matching streams (nil = any language).
+	// "en" matches "en" and "en-US".
+	Languages []string `json:"languages,omitempty"`
+
+	// Disposition requires a disposition flag, e.g. "visual_impaired" ("" = any)
+	Disposition string `json:"disposition,omitempty"`
+
+	// MinHeight requires a vertical resolution of at least this many lines (0 = any)
+	MinHeight int `json:"min_height,omitempty"`
+
+	// Min is the minimum number of matching streams (0 = 1)
+	Min int `json:"min,omitempty"`
+}
+
+// Finding is the result of evaluating a single requirement
+type Finding struct {
+	Expectation string `json:"expectation"`
+	Passed      bool   `json:"passed"`
+	Message     string `json:"message,omitempty"`
+}
+
+// Evaluate checks output against the expectations. Before an upcoming live
+// event starts, requirements it does not meet yet are skipped (passed, with a
+// message) rather than failed.
+func (e *Expectations) Evaluate(output *Output) []Finding {
+	var findings []Finding
+
+	for _, expectation := range e.Streams {
+		if len(expectation.Languages) == 0 {
+			findings = append(findings, expectation.evaluate(output.Streams, ""))
+			continue
+		}
+		for _, language := range expectation.Languages {
+			findings = append(findings, expectation.evaluate(output.Streams, language))
+		}
+	}
+
+	if e.SubtitlesForAudio {
+		findings = append(findings, evaluateSubtitlesForAudio(output.Streams)...)
+	}
+
+	if output.Event != nil && output.Event.State == EventStateUpcoming {
+		for i := range findings {
+			if !findings[i].Passed {
+				findings[i].Passed = true
+				findings[i].Message = "skipped: event has not started"
+			}
+		}
+	}
+
+	return findings
+}
+
+// FailedFindings returns the findings that did not pass
+func FailedFindings(findings []Finding) []Finding {
+	var failed []Finding
+	for _, finding := range findings {
+		if !finding.Passed {
+			failed = append(failed, finding)
+		}
+	}
+	return failed
+}
+
+// evaluate checks the expectation for one language ("" = any)
+func (s StreamExpectation) evaluate(streams []StreamInfo, language string) Finding {
+	min := s.Min
+	if min <= 0 {
+		min = 1
+	}
+
+	count := 0
+	for _, stream := range streams {
+		if s.matches(stream, language) {
+			count++
+		}
+	}
+
+	finding := Finding{Expectation: s.describe(min, language), Passed: count >= min}
+	if !finding.Passed {
+		finding.Message = fmt.Sprintf("found %d", count)
+	}
+	return finding
+}
+
+// matches reports whether stream satisfies the expectation for language ("" = any)
+func (s StreamExpectation) matches(stream StreamInfo, language string) bool {
+	if s.Type != "" && !strings.EqualFold(stream.Type, s.Type) {
+		return false
+	}
+	if language != "" && !languageMatches(stream.Language, language) {
+		return false
+	}
+	if s.Disposition != "" && !stream.Disposition.has(s.Disposition) {
+		return false
+	}
+	if s.MinHeight > 0 && resolutionHeight(stream.Resolution) < s.MinHeight {
+		return false
+	}
+	return true
+}
+
+// describe renders the expectation for language as readable text
+func (s StreamExpectation) describe(min int, language string) string {
+	kind := "stream"
+	if s.Type != "" {
+		kind = s.Type + " stream"
+	}
+	if min != 1 {
+		kind += "s"
+	}
+
+	var qualifiers []string
+	if language != "" {
+		qualifiers = append(qualifiers, fmt.Sprintf("language %q", language))
+	}
+	if s.Disposition != "" {
+		qualifiers = append(qualifiers, "disposition "+s.Disposition)
+	}
+	if s.MinHeight > 0 {
+		qualifiers = append(qualifiers, fmt.Sprintf("height >= %d", s.MinHeight))
+	}
+
+	text := fmt.Sprintf("at least %d %s", min, kind)
+	if len(qualifiers) > 0 {
+		text += " with " + strings.Join(qualifiers, ", ")
+	}
+	return text
+}
+
+// evaluateSubtitlesForAudio requires a subtitle stream for every audio language
+func evaluateSubtitlesForAudio(streams []StreamInfo) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+
+	for _, audio := range streams {
+		language := strings.ToLower(audio.Language)
+		if audio.Type != "Audio" || language == "" || seen[language] {
+			continue
+		}
+		seen[language] = true
+
+		finding := Finding{Expectation: fmt.Sprintf("subtitles for audio language %q", audio.Language)}
+		for _, subtitle := range streams {
+			if subtitle.Type == "Subtitle" && languageMatches(subtitle.Language, audio.Language) {
+				finding.Passed = true
+				break
+			}
+		}
+		if !finding.Passed {
+			finding.Message = "no subtitle stream"
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+// languageMatches reports whether a stream language satisfies a required
+// language, ignoring case and region subtags
+func languageMatches(streamLanguage, required string) bool {
+	if strings.EqualFold(streamLanguage, required) {
+		return true
+	}
+	primary, _, found := strings.Cut(streamLanguage, "-")
+	return found && strings.EqualFold(primary, required)
+}
+
+// resolutionHeight extracts the height of a "WIDTHxHEIGHT" resolution (0 if unknown)
+func resolutionHeight(resolution string) int {
+	_, height, ok := strings.Cut(resolution, "x")
+	if !ok {
+		return 0
+	}
+	value, _ := strconv.Atoi(height)
+	return value
+}
@@ -0,0 +1,63 @@
+package probe
+
+import "testing"
+
+func TestExpectationsEvaluate(t *testing.T) {
+	output := &Output{Streams: []StreamInfo{
+		{StreamID: "0:0", Type: "Video", Resolution: "1920x1080"},
+		{StreamID: "0:1", Type: "Video", Resolution: "1280x720"},
+		{StreamID: "0:2(en)", Type: "Audio", Language: "en"},
+		{StreamID: "0:3(fr-CA)", Type: "Audio", Language: "fr-CA"},
+		{StreamID: "0:4(en)", Type: "Audio", Language: "en", Disposition: &Disposition{VisualImpaired: true}},
+		{StreamID: "0:5(en)", Type: "Subtitle", Language: "en"},
+	}}
+
+	expectations := &Expectations{
+		Streams: []StreamExpectation{
+			{Type: "Audio", Languages: []string{"en", "fr", "es"}},
+			{Type: "Video", MinHeight: 1080},
+			{Type: "Video", MinHeight: 2160},
+			{Type: "Audio", Languages: []string{"en"}, Disposition: "visual_impaired"},
+			{Type: "Audio", Min: 4},
+		},
+		SubtitlesForAudio: true,
+	}
+
+	expected := []Finding{
+		{Expectation: `at least 1 Audio stream with language "en"`, Passed: true},
+		{Expectation: `at least 1 Audio stream with language "fr"`, Passed: true},
+		{Expectation: `at least 1 Audio stream with language "es"`, Passed: false, Message: "found 0"},
+		{Expectation: "at least 1 Video stream with height >= 1080", Passed: true},
+		{Expectation: "at least 1 Video stream with height >= 2160", Passed: false, Message: "found 0"},
+		{Expectation: `at least 1 Audio stream with language "en", disposition visual_impaired`, Passed: true},
+		{Expectation: "at least 4 Audio streams", Passed: false, Message: "found 3"},
+		{Expectation: `subtitles for audio language "en"`, Passed: true},
+		{Expectation: `subtitles for audio language "fr-CA"`, Passed: false, Message: "no subtitle stream"},
+	}
+
+	findings := expectations.Evaluate(output)
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+
+	for i, finding := range findings {
+		if finding != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], finding)
+		}
+	}
+
+	if failed := FailedFindings(findings); len(failed) != 4 {
+		t.Errorf("Expected 4 failed findings, got %d", len(failed))
+	}
+}
+
+func TestExpectationsSkippedBeforeEvent(t *testing.T) {
+	expectations := &Expectations{Streams: []StreamExpectation{{Type: "Video"}}}
+	output := &Output{Event: &EventStatus{State: EventStateUpcoming, Reason: EventReasonEmptyEventPlaylist}}
+
+	findings := expectations.Evaluate(output)
+	expected := Finding{Expectation: "at least 1 Video stream", Passed: true, Message: "skipped: event has not started"}
+	if len(findings) != 1 || findings[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, findings)
+	}
+}
@@ -0,0 +1,275 @@
+package probe
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Explanation note kinds
+const (
+	// ExplainStream is a stream of the output produced by the line
+	ExplainStream = "stream"
+	// ExplainSkipped is a manifest element that produced no stream, with the reason
+	ExplainSkipped = "skipped"
+	// ExplainInfo is other output derived from the line
+	ExplainInfo = "info"
+)
+
+// Explanation is a manifest annotated with the output goprobe derived from
+// each line, answering "why did goprobe report X" questions
+type Explanation struct {
+	URL    string        `json:"url"`
+	Format string        `json:"format"`
+	Lines  []ExplainLine `json:"lines"`
+
+	// Warnings are the warnings of the probe
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ExplainLine is a manifest line with its annotations
+type ExplainLine struct {
+	// Number is the 1-based line number
+	Number int           `json:"number"`
+	Text   string        `json:"text"`
+	Notes  []ExplainNote `json:"notes,omitempty"`
+}
+
+// ExplainNote annotates a manifest line
+type ExplainNote struct {
+	// Kind is ExplainStream, ExplainSkipped or ExplainInfo
+	Kind string `json:"kind"`
+
+	Text string `json:"text"`
+
+	// StreamID is the stream of ExplainStream notes
+	StreamID string `json:"stream_id,omitempty"`
+}
+
+// Explain probes a manifest like Probe and annotates its lines with the
+// streams they produced and the elements that were skipped
+func (p *Prober) Explain(ctx context.Context, manifestURL string) (*Explanation, error) {
+	ctx = withLogger(ctx, p.logger)
+
+	fetched, err := p.fetch(ctx, manifestURL)
+	if err != nil {
+		return nil, p.redactor.redactError(err)
+	}
+	output, err := p.analyze(ctx, fetched)
+	if err != nil {
+		return nil, p.redactor.redactError(err)
+	}
+	return explain(output, fetched.body), nil
+}
+
+// explain annotates body with output
+func explain(output *Output, body string) *Explanation {
+	explanation := &Explanation{
+		URL:      output.url,
+		Format:   manifestFormat(body),
+		Warnings: append([]string(nil), output.Warnings...),
+	}
+
+	var notes map[int][]ExplainNote
+	if explanation.Format == "MPD" {
+		notes = explainMPD(output, body)
+	} else {
+		notes = explainHLS(output, body)
+	}
+
+	for lines := scanLines(body); lines.scan(); {
+		number := lines.index + 1
+		explanation.Lines = append(explanation.Lines, ExplainLine{
+			Number: number,
+			Text:   strings.TrimRight(lines.line, "\r"),
+			Notes:  notes[number],
+		})
+	}
+	// The newline ending the last line does not start another one
+	if last := len(explanation.Lines) - 1; last >= 0 && explanation.Lines[last].Text == "" {
+		explanation.Lines = explanation.Lines[:last]
+	}
+	return explanation
+}
+
+// explainHLS returns the notes of the lines of an HLS playlist
+func explainHLS(output *Output, body string) map[int][]ExplainNote {
+	notes := make(map[int][]ExplainNote)
+	streams := make(map[int][]ExplainNote)
+	for i := range output.Streams {
+		if source := output.Streams[i].Source; source != nil && source.Line > 0 {
+			streams[source.Line] = append(streams[source.Line], streamNote(&output.Streams[i]))
+		}
+	}
+
+	if output.MediaPlaylist != nil {
+		notes[1] = append(notes[1], ExplainNote{Kind: ExplainInfo, Text: "media playlist: segments are summarized under media_playlist, it has no streams"})
+	}
+
+	referencedVideoGroups := make(map[string]bool)
+	for lines := scanLines(body); lines.scan(); {
+		if line := strings.TrimSpace(lines.line); strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+			if group := parseHLSAttributes(line)["VIDEO"]; group != "" {
+				referencedVideoGroups[group] = true
+			}
+		}
+	}
+
+	for lines := scanLines(body); lines.scan(); {
+		number := lines.index + 1
+		line := strings.TrimSpace(lines.line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			notes[number] = append(notes[number], streams[number]...)
+			if parseHLSAttributes(line)["RESOLUTION"] == "" {
+				notes[number] = append(notes[number], ExplainNote{Kind: ExplainInfo, Text: "no RESOLUTION: only an audio stream is reported for the variant"})
+			}
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			if len(streams[number]) > 0 {
+				notes[number] = append(notes[number], streams[number]...)
+				continue
+			}
+			attrs := parseHLSAttributes(line)
+			reason := fmt.Sprintf("renditions of TYPE %s are not reported", attrs["TYPE"])
+			switch attrs["TYPE"] {
+			case "AUDIO":
+				reason = "audio renditions are reported through the audio streams of the variants (their CODECS), or as streams with AudioGroups (-audio-groups)"
+			case "VIDEO":
+				if !referencedVideoGroups[attrs["GROUP-ID"]] {
+					reason = fmt.Sprintf("GROUP-ID %q is not referenced by the VIDEO attribute of any variant", attrs["GROUP-ID"])
+				}
+			}
+			notes[number] = append(notes[number], ExplainNote{Kind: ExplainSkipped, Text: reason})
+		case strings.HasPrefix(line, "#EXT-X-I-FRAME-STREAM-INF:"):
+			if len(streams[number]) > 0 {
+				notes[number] = append(notes[number], streams[number]...)
+				continue
+			}
+			notes[number] = append(notes[number], ExplainNote{Kind: ExplainSkipped, Text: "I-frame playlists are only reported as streams with IFrameStreams (-iframes)"})
+		case strings.HasPrefix(line, "#EXT-X-SESSION-DATA:"):
+			if dataID := parseHLSAttributes(line)["DATA-ID"]; dataID != "" {
+				notes[number] = append(notes[number], ExplainNote{Kind: ExplainInfo, Text: fmt.Sprintf("metadata %q", dataID)})
+			} else {
+				notes[number] = append(notes[number], ExplainNote{Kind: ExplainSkipped, Text: "no DATA-ID"})
+			}
+		case strings.HasPrefix(line, "#EXT-X-DATERANGE:"):
+			if attrs := parseHLSAttributes(line); attrs["CLASS"] == hlsInterstitialClass {
+				notes[number] = append(notes[number], ExplainNote{Kind: ExplainInfo, Text: fmt.Sprintf("interstitial %q", attrs["ID"])})
+			} else if attrs["SCTE35-OUT"] != "" || attrs["SCTE35-IN"] != "" || attrs["SCTE35-CMD"] != "" {
+				notes[number] = append(notes[number], ExplainNote{Kind: ExplainInfo, Text: fmt.Sprintf("ad break %q", attrs["ID"])})
+			}
+		case strings.HasPrefix(line, "#EXT-X-START:"):
+			notes[number] = append(notes[number], ExplainNote{Kind: ExplainInfo, Text: "start position"})
+		}
+	}
+	return notes
+}
+
+// explainMPD returns the notes of the lines of an MPD
+func explainMPD(output *Output, body string) map[int][]ExplainNote {
+	notes := make(map[int][]ExplainNote)
+
+	var mpd MPD
+	if err := xml.Unmarshal([]byte(body), &mpd); err != nil {
+		return notes
+	}
+
+	lines := mpdStartLines(body)
+	for periodIndex, period := range mpd.Periods {
+		for adaptationSetIndex, adaptationSet := range period.AdaptationSets {
+			line, ok := lines[mpdElementIndex{periodIndex, adaptationSetIndex, -1}]
+			if !ok {
+				continue
+			}
+			switch {
+			case isTrickModeStream(adaptationSet):
+				notes[line] = append(notes[line], ExplainNote{Kind: ExplainSkipped, Text: "trick-mode AdaptationSet (EssentialProperty http://dashif.org/guidelines/trickmode)"})
+			case !isVideoStream(adaptationSet) && !isAudioStream(adaptationSet) && !isSubtitleStream(adaptationSet):
+				notes[line] = append(notes[line], ExplainNote{Kind: ExplainSkipped, Text: fmt.Sprintf("contentType %q and mimeType %q are not video, audio or text", adaptationSet.ContentType, adaptationSet.MimeType)})
+			}
+		}
+	}
+
+	for i := range output.Streams {
+		stream := &output.Streams[i]
+		if stream.Source == nil || stream.Source.Tag != "Representation" {
+			continue
+		}
+		if line, ok := lines[stream.element]; ok {
+			notes[line] = append(notes[line], streamNote(stream))
+		}
+	}
+	return notes
+}
+
+// mpdStartLines returns the line of every AdaptationSet start tag (indexed
+// with representation -1) and Representation start tag
+func mpdStartLines(content string) map[mpdElementIndex]int {
+	lines := make(map[mpdElementIndex]int)
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	index := mpdElementIndex{period: -1, adaptationSet: -1, representation: -1}
+
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return lines
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch element.Name.Local {
+		case "Period":
+			index.period++
+			index.adaptationSet = -1
+			continue
+		case "AdaptationSet":
+			index.adaptationSet++
+			index.representation = -1
+		case "Representation":
+			index.representation++
+		default:
+			continue
+		}
+		lines[index] = strings.Count(content[:start], "\n") + 1
+	}
+}
+
+// streamNote describes a stream of the output
+func streamNote(stream *StreamInfo) ExplainNote {
+	parts := []string{stream.StreamID, stream.Type}
+	language := stream.Language
+	if strings.HasSuffix(stream.StreamID, "("+language+")") {
+		language = "" // already in the stream ID
+	}
+	for _, part := range []string{stream.Codec, stream.Resolution, stream.BitRate, language, stream.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return ExplainNote{Kind: ExplainStream, Text: strings.Join(parts, " "), StreamID: stream.StreamID}
+}
+
+// String renders the explanation as the annotated manifest
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s (%s)\n", e.URL, e.Format)
+	for _, line := range e.Lines {
+		fmt.Fprintf(&b, "%5d  %s\n", line.Number, line.Text)
+		for _, note := range line.Notes {
+			marker := "--"
+			if note.Kind == ExplainStream {
+				marker = "->"
+			}
+			fmt.Fprintf(&b, "       %s %s: %s\n", marker, note.Kind, note.Text)
+		}
+	}
+	for _, warning := range e.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", warning)
+	}
+	return b.String()
+}
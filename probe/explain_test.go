@@ -0,0 +1,135 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const explainHLSManifest = `#EXTM3U
+#EXT-X-SESSION-DATA:DATA-ID="com.example.title",VALUE="News"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",URI="audio/en.m3u8"
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="unused",NAME="Angle",URI="angle.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",LANGUAGE="en",URI="subs/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",AUDIO="aac",SUBTITLES="subs"
+video/720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=64000,CODECS="mp4a.40.2"
+audio/only.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=86000,CODECS="avc1.64001f",URI="iframe.m3u8"
+`
+
+// explainKinds returns the note kinds of each annotated line
+func explainKinds(explanation *Explanation) map[int][]string {
+	kinds := make(map[int][]string)
+	for _, line := range explanation.Lines {
+		for _, note := range line.Notes {
+			kinds[line.Number] = append(kinds[line.Number], note.Kind)
+		}
+	}
+	return kinds
+}
+
+func TestExplainHLS(t *testing.T) {
+	output, err := parseHLSManifest(explainHLSManifest, "https://cdn.example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	explanation := explain(output, explainHLSManifest)
+
+	if explanation.Format != "HLS" || len(explanation.Lines) != 10 {
+		t.Fatalf("Expected 10 HLS lines, got %s with %d", explanation.Format, len(explanation.Lines))
+	}
+
+	expected := map[int][]string{
+		2:  {ExplainInfo},
+		3:  {ExplainSkipped},
+		4:  {ExplainSkipped},
+		5:  {ExplainStream},
+		6:  {ExplainStream, ExplainStream},
+		8:  {ExplainStream, ExplainInfo},
+		10: {ExplainSkipped},
+	}
+	if kinds := explainKinds(explanation); !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("Expected %v, got %v", expected, kinds)
+	}
+
+	notes := explanation.Lines[5].Notes
+	if notes[0].StreamID != "0:0" || notes[0].Text != "0:0 Video h264 1280x720 1280 kb/s" {
+		t.Errorf("Expected %q, got %q", "0:0 Video h264 1280x720 1280 kb/s", notes[0].Text)
+	}
+	if reason := explanation.Lines[3].Notes[0].Text; !strings.Contains(reason, `"unused"`) {
+		t.Errorf("Expected the unreferenced group in %q", reason)
+	}
+}
+
+func TestExplainMPD(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+  <Period id="p0">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <Representation id="v1" codecs="avc1.64001f" width="1280" height="720" bandwidth="3000000"/>
+      <Representation id="v2" codecs="avc1.64001f" width="640" height="360" bandwidth="800000"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <EssentialProperty schemeIdUri="http://dashif.org/guidelines/trickmode" value="1"/>
+      <Representation id="trick" codecs="avc1.64001f" width="320" height="180" bandwidth="100000"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="image" mimeType="image/jpeg">
+      <Representation id="thumbs" bandwidth="10000"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="audio" mimeType="audio/mp4" lang="en">
+      <Representation id="a1" codecs="mp4a.40.2" bandwidth="128000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>
+`
+	output, err := parseMPDManifest(manifest, "https://cdn.example.com/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	explanation := explain(output, manifest)
+
+	expected := map[int][]string{
+		5:  {ExplainStream},
+		6:  {ExplainStream},
+		8:  {ExplainSkipped},
+		12: {ExplainSkipped},
+		16: {ExplainStream},
+	}
+	if kinds := explainKinds(explanation); !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("Expected %v, got %v", expected, kinds)
+	}
+	if note := explanation.Lines[15].Notes[0]; note.StreamID != "0:2(en)" {
+		t.Errorf("Expected %q, got %q", "0:2(en)", note.StreamID)
+	}
+}
+
+func TestProberExplain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(explainHLSManifest))
+	}))
+	defer server.Close()
+
+	explanation, err := NewProber(&ProbeOptions{IFrameStreams: true}).Explain(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if notes := explanation.Lines[9].Notes; len(notes) != 1 || notes[0].Kind != ExplainStream {
+		t.Errorf("Expected the I-frame stream, got %+v", notes)
+	}
+
+	rendered := explanation.String()
+	for _, expected := range []string{
+		"# " + server.URL + "/master.m3u8 (HLS)\n",
+		"    6  #EXT-X-STREAM-INF:",
+		"       -> stream: 0:0 Video h264 1280x720 1280 kb/s\n",
+		"       -- skipped: audio renditions",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, rendered)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package probe
+
+import (
+	"sort"
+	"strings"
+)
+
+// drmSystems maps DRM system IDs (DASH ContentProtection and HLS KEYFORMAT) to feature names
+var drmSystems = map[string]string{
+	"edef8ba9-79d6-4ace-a3c8-27dcd51d21ed": "drm:widevine",
+	"9a04f079-9840-4286-ab92-e65be0885f95": "drm:playready",
+	"94ce86fb-07ff-4f43-adb8-93d2fa968ca2": "drm:fairplay",
+	"e2719d58-a985-b3c9-781a-b030af78d30e": "drm:clearkey",
+	"1077efec-c0b2-4d02-ace3-3c1e52e2fb4b": "drm:clearkey",
+	"com.apple.streamingkeydelivery":       "drm:fairplay",
+	"com.microsoft.playready":              "drm:playready",
+}
+
+// mpdElementFeatures maps MPD elements and attributes to feature names
+var mpdElementFeatures = map[string]string{
+	"<SegmentTimeline":                 "segment-timeline",
+	"<SegmentTemplate":                 "segment-template",
+	"<SegmentList":                     "segment-list",
+	"<SegmentBase":                     "byte-range",
+	"xlink:href":                       "xlink",
+	"<EventStream":                     "event-stream",
+	"<InbandEventStream":               "inband-events",
+	"<ServiceDescription":              "ll-dash",
+	"availabilityTimeOffset":           "ll-dash",
+	"<ContentSteering":                 "content-steering",
+	"<Preselection":                    "preselection",
+	"<FramePacking":                    "frame-packing",
+	"urn:mpeg:mpegB:cicp:PF":           "projection",
+	"urn:mpeg:mpegI:omaf:2017:pf":      "projection",
+	"<SupplementalProperty":            "supplemental-property",
+	"urn:mpeg:dash:profile:cmaf":       "cmaf",
+	"urn:mpeg:dash:mp4protection:2011": "cenc",
+}
+
+// hlsTagFeatures maps HLS tags to feature names
+var hlsTagFeatures = map[string]string{
+	"#EXT-X-PART":                 "ll-hls parts",
+	"#EXT-X-PRELOAD-HINT":         "ll-hls preload-hints",
+	"#EXT-X-RENDITION-REPORT":     "ll-hls rendition-reports",
+	"#EXT-X-SKIP":                 "ll-hls delta-updates",
+	"#EXT-X-BYTERANGE":            "byte-range",
+	"#EXT-X-MAP":                  "fmp4",
+	"#EXT-X-I-FRAME-STREAM-INF":   "i-frames",
+	"#EXT-X-DISCONTINUITY":        "discontinuity",
+	"#EXT-X-DATERANGE":            "daterange",
+	"#EXT-X-CONTENT-STEERING":     "content-steering",
+	"#EXT-X-DEFINE":               "variable-substitution",
+	"#EXT-X-SESSION-DATA":         "session-data",
+	"#EXT-X-INDEPENDENT-SEGMENTS": "independent-segments",
+	"#EXT-X-PROGRAM-DATE-TIME":    "program-date-time",
+	"#EXT-X-ALLOW-CACHE":          "allow-cache",
+}
+
+// hlsAttributeFeatures maps HLS tag attributes to feature names
+var hlsAttributeFeatures = map[string]string{
+	"CAN-BLOCK-RELOAD=YES": "ll-hls blocking-reload",
+	"CAN-SKIP-UNTIL=":      "ll-hls delta-updates",
+	"BYTERANGE=":           "byte-range",
+	"METHOD=AES-128":       "aes-128",
+	"METHOD=SAMPLE-AES":    "sample-aes",
+	"SCTE35-OUT=":          "scte35",
+	"SCTE35-IN=":           "scte35",
+	"SCTE35-CMD=":          "scte35",
+}
+
+// detectMPDFeatures lists the features an MPD uses
+func detectMPDFeatures(content string, mpd *MPD) []string {
+	features := make(map[string]bool)
+
+	for token, feature := range mpdElementFeatures {
+		if strings.Contains(content, token) {
+			features[feature] = true
+		}
+	}
+
+	if len(mpd.Periods) > 1 {
+		features["multi-period"] = true
+	}
+	if mpd.Type == "dynamic" {
+		features["live"] = true
+	}
+
+	addDRMFeatures(features, content)
+	return sortedFeatures(features)
+}
+
+// detectHLSFeatures lists the features an HLS playlist uses
+func detectHLSFeatures(content string) []string {
+	parser := hlsFeatureParser{features: make(map[string]bool)}
+	for lines := scanLines(content); lines.scan(); {
+		parser.parseLine(strings.TrimSpace(lines.line))
+	}
+	return parser.finish()
+}
+
+// hlsFeatureParser collects the features used by the lines of a playlist
+type hlsFeatureParser struct {
+	features      map[string]bool
+	mediaSequence bool
+	ended         bool
+}
+
+// parseLine parses a line with surrounding whitespace removed
+func (p *hlsFeatureParser) parseLine(line string) {
+	if !strings.HasPrefix(line, "#EXT") {
+		return
+	}
+
+	tag, _, _ := strings.Cut(line, ":")
+	if feature, ok := hlsTagFeatures[tag]; ok {
+		p.features[feature] = true
+	}
+	switch tag {
+	case "#EXT-X-KEY", "#EXT-X-SESSION-KEY":
+		addDRMFeatures(p.features, line)
+	case "#EXT-X-MEDIA-SEQUENCE":
+		p.mediaSequence = true
+	case "#EXT-X-ENDLIST":
+		p.ended = true
+	}
+
+	// Every attribute feature has an "=", which most media playlist tags lack
+	if !strings.Contains(line, "=") {
+		return
+	}
+	for attribute, feature := range hlsAttributeFeatures {
+		if strings.Contains(line, attribute) {
+			p.features[feature] = true
+		}
+	}
+}
+
+// finish returns the sorted features of the parsed lines
+func (p *hlsFeatureParser) finish() []string {
+	if p.mediaSequence && !p.ended {
+		p.features["live"] = true
+	}
+	return sortedFeatures(p.features)
+}
+
+// addDRMFeatures adds a feature for every known DRM system referenced in content
+func addDRMFeatures(features map[string]bool, content string) {
+	for id, feature := range drmSystems {
+		if containsFold(content, id) {
+			features[feature] = true
+		}
+	}
+}
+
+// sortedFeatures returns the feature set as a sorted list (nil if empty)
+func sortedFeatures(features map[string]bool) []string {
+	if len(features) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(features))
+	for feature := range features {
+		list = append(list, feature)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,89 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMPDFeatures(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD xmlns:xlink="http://www.w3.org/1999/xlink" type="dynamic" profiles="urn:mpeg:dash:profile:cmaf:2019">
+  <Period id="1">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc"/>
+      <ContentProtection schemeIdUri="urn:uuid:EDEF8BA9-79D6-4ACE-A3C8-27DCD51D21ED"/>
+      <SegmentTemplate media="$Time$.m4s">
+        <SegmentTimeline><S t="0" d="2000" r="9"/></SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="v1" codecs="avc1.64001f" width="1280" height="720"/>
+    </AdaptationSet>
+  </Period>
+  <Period id="2" xlink:href="https://example.com/ad.xml" xlink:actuate="onLoad"/>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://example.com/live.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"cenc", "cmaf", "drm:widevine", "live", "multi-period", "segment-template", "segment-timeline", "xlink"}
+	if !reflect.DeepEqual(output.Features, expected) {
+		t.Errorf("Expected %v, got %v", expected, output.Features)
+	}
+}
+
+func TestHLSFeatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected []string
+	}{
+		{
+			name: "low latency fmp4",
+			manifest: `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1.0
+#EXT-X-PART-INF:PART-TARGET=0.33334
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXTINF:4.0,
+seg100.m4s
+#EXT-X-PART:DURATION=0.33334,URI="seg101.0.m4s"
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="seg101.1.m4s"`,
+			expected: []string{"drm:fairplay", "fmp4", "live", "ll-hls blocking-reload", "ll-hls parts", "ll-hls preload-hints", "sample-aes"},
+		},
+		{
+			name: "byte-range vod",
+			manifest: `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:0
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:1000@0
+media.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:1000@1000
+media.ts
+#EXT-X-ENDLIST`,
+			expected: []string{"byte-range", "discontinuity"},
+		},
+		{
+			name: "plain playlist",
+			manifest: `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXTINF:6.0,
+seg.ts
+#EXT-X-ENDLIST`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectHLSFeatures(tt.manifest); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ParseOptionsJSON decodes ProbeOptions from JSON with their Go field names,
+// as stored in config profiles (empty data = default options). Unknown
+// fields are rejected so that misspelled options are not silently ignored.
+func ParseOptionsJSON(data []byte) (*ProbeOptions, error) {
+	opts := &ProbeOptions{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return opts, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(opts); err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid options JSON: %v", err))
+	}
+	return opts, nil
+}
+
+// ProbeJSON probes manifestURL like Probe and returns the output as JSON, or
+// the error as ErrorJSON, for callers across a language boundary (see the
+// cshared package)
+func (p *Prober) ProbeJSON(ctx context.Context, manifestURL string) []byte {
+	output, err := p.Probe(ctx, manifestURL)
+	if err != nil {
+		return ErrorJSON(err)
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return ErrorJSON(err)
+	}
+	return data
+}
+
+// errorJSON is a ProbeError with its cause as a string
+type errorJSON struct {
+	*ProbeError
+	Cause string `json:"cause,omitempty"`
+}
+
+// ErrorJSON returns err as {"error": {"type": ..., "message": ..., "url": ...,
+// "cause": ...}}. Errors other than ProbeErrors have the type "internal".
+func ErrorJSON(err error) []byte {
+	var probeErr *ProbeError
+	if !errors.As(err, &probeErr) {
+		probeErr = &ProbeError{Type: "internal", Message: err.Error()}
+	}
+	body := errorJSON{ProbeError: probeErr}
+	if probeErr.Cause != nil {
+		body.Cause = probeErr.Cause.Error()
+	}
+
+	data, marshalErr := json.Marshal(struct {
+		Error errorJSON `json:"error"`
+	}{body})
+	if marshalErr != nil {
+		return []byte(`{"error":{"type":"internal","message":"error not encodable"}}`)
+	}
+	return data
+}
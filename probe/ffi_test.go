@@ -0,0 +1,94 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseOptionsJSON(t *testing.T) {
+	opts, err := ParseOptionsJSON([]byte(`{"TimeoutSeconds": 5, "DisableCamouflage": true, "DeepProbe": {"SegmentBytes": 4096}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts.TimeoutSeconds != 5 || !opts.DisableCamouflage || opts.DeepProbe == nil || opts.DeepProbe.SegmentBytes != 4096 {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+
+	if opts, err := ParseOptionsJSON([]byte("  ")); err != nil || opts == nil {
+		t.Errorf("Expected default options for empty JSON, got %+v, %v", opts, err)
+	}
+
+	_, err = ParseOptionsJSON([]byte(`{"TimeoutSecs": 5}`))
+	var probeErr *ProbeError
+	if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeValidation {
+		t.Errorf("Expected a validation error for an unknown field, got %v", err)
+	}
+}
+
+func TestProbeJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/master.m3u8" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS=\"avc1.4d401f,mp4a.40.2\"\n720p.m3u8\n"))
+	}))
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{DisableCamouflage: true})
+
+	var output struct {
+		Streams []StreamInfo `json:"streams"`
+	}
+	if err := json.Unmarshal(prober.ProbeJSON(context.Background(), server.URL+"/master.m3u8"), &output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(output.Streams) != 2 || output.Streams[0].Codec != "h264" {
+		t.Errorf("Unexpected streams %+v", output.Streams)
+	}
+
+	var failure struct {
+		Error struct {
+			Type    ErrorType `json:"type"`
+			Message string    `json:"message"`
+			URL     string    `json:"url"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(prober.ProbeJSON(context.Background(), server.URL+"/missing.m3u8"), &failure); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if failure.Error.Type != ErrorTypeAuth || failure.Error.URL != server.URL+"/missing.m3u8" {
+		t.Errorf("Expected an auth error for the missing manifest, got %+v", failure.Error)
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "probe error with cause",
+			err:      NewParsingError("https://example.com/a.mpd", "MPD", errors.New("unexpected EOF")),
+			expected: `{"error":{"type":"parsing","message":"failed to parse MPD manifest","url":"https://example.com/a.mpd","cause":"unexpected EOF"}}`,
+		},
+		{
+			name:     "other error",
+			err:      errors.New("boom"),
+			expected: `{"error":{"type":"internal","message":"boom"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(ErrorJSON(tt.err)); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity ranks findings, using SARIF's levels
+type Severity string
+
+const (
+	// SeverityError is a violation of a requirement (MUST)
+	SeverityError Severity = "error"
+	// SeverityWarning is a violation of a recommendation (SHOULD) or a likely problem
+	SeverityWarning Severity = "warning"
+	// SeverityNote is informational
+	SeverityNote Severity = "note"
+)
+
+// FindingLocation locates a finding in a manifest
+type FindingLocation struct {
+	URL string `json:"url"`
+
+	// Line is the 1-based line of the manifest element (0 if unknown)
+	Line int `json:"line,omitempty"`
+
+	// Element names the manifest element, such as "EXT-X-STREAM-INF" or
+	// "Period[p0]/AdaptationSet[1]/Representation[v1]"
+	Element string `json:"element,omitempty"`
+
+	StreamID string `json:"stream_id,omitempty"`
+}
+
+// locateFindings sets the locations of the rule and compatibility findings
+// of output from the streams they refer to
+func locateFindings(output *Output, body string) {
+	manifestURL := output.url
+	var lines map[mpdElementIndex]int
+	if manifestFormat(body) == "MPD" {
+		lines = mpdElementLines(body)
+	}
+
+	locate := func(streamIDs []string) []FindingLocation {
+		var locations []FindingLocation
+		for _, id := range streamIDs {
+			for _, stream := range output.Streams {
+				if stream.StreamID == id {
+					locations = append(locations, streamLocation(stream, manifestURL, lines))
+					break
+				}
+			}
+		}
+		if len(locations) == 0 {
+			locations = []FindingLocation{{URL: manifestURL}}
+		}
+		return locations
+	}
+
+	for i := range output.Rules {
+		if !output.Rules[i].Passed {
+			output.Rules[i].Locations = locate(output.Rules[i].StreamIDs)
+		}
+	}
+	for i := range output.Compatibility {
+		output.Compatibility[i].Locations = locate(output.Compatibility[i].StreamIDs)
+	}
+}
+
+// streamLocation locates the manifest element a stream was derived from
+func streamLocation(stream StreamInfo, manifestURL string, mpdLines map[mpdElementIndex]int) FindingLocation {
+	location := FindingLocation{URL: manifestURL, StreamID: stream.StreamID}
+	source := stream.Source
+	if source == nil {
+		return location
+	}
+
+	if source.Tag != "Representation" {
+		location.Line = source.Line
+		location.Element = source.Tag
+		return location
+	}
+
+	location.Line = mpdLines[stream.element]
+	location.Element = fmt.Sprintf("Period[%s]/AdaptationSet[%s]/Representation[%s]",
+		elementName(source.PeriodID, stream.element.period),
+		elementName(source.AdaptationSetID, stream.element.adaptationSet),
+		elementName(source.RepresentationID, stream.element.representation))
+	return location
+}
+
+// elementName names an MPD element by @id, or by 1-based position with a "#" prefix
+func elementName(id string, index int) string {
+	if id != "" {
+		return id
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+// mpdElementLines returns the 1-based line of every Representation start tag
+func mpdElementLines(content string) map[mpdElementIndex]int {
+	lines := make(map[mpdElementIndex]int)
+	for index, offset := range mpdRepresentationOffsets(content) {
+		lines[index] = strings.Count(content[:offset], "\n") + 1
+	}
+	return lines
+}
@@ -0,0 +1,128 @@
+package probe
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLocateFindingsHLS(t *testing.T) {
+	server := appleTestServer(appleTestMaster)
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{RulePacks: []string{RulePackAppleHLS}}).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var finding *RuleFinding
+	for i := range output.Rules {
+		if output.Rules[i].RuleID == "apple-hls/average-bandwidth" {
+			finding = &output.Rules[i]
+		}
+	}
+	if finding == nil {
+		t.Fatalf("Expected an apple-hls/average-bandwidth finding")
+	}
+
+	// The variant without AVERAGE-BANDWIDTH yields a video and an audio stream
+	expected := []FindingLocation{
+		{URL: server.URL + "/master.m3u8", Line: 2, Element: "EXT-X-STREAM-INF", StreamID: "0:0"},
+		{URL: server.URL + "/master.m3u8", Line: 2, Element: "EXT-X-STREAM-INF", StreamID: "0:1"},
+	}
+	if !reflect.DeepEqual(finding.Locations, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, finding.Locations)
+	}
+}
+
+func TestLocateFindingsRedacted(t *testing.T) {
+	server := appleTestServer(appleTestMaster)
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{RulePacks: []string{RulePackAppleHLS}}).Probe(context.Background(), server.URL+"/master.m3u8?token=secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, finding := range output.Rules {
+		for _, location := range finding.Locations {
+			if strings.Contains(location.URL, "secret") {
+				t.Errorf("Expected a redacted location URL, got %q", location.URL)
+			}
+		}
+	}
+	sarif, err := ExportSARIF(output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(string(sarif), "secret") {
+		t.Errorf("Expected redacted artifact URIs, got %s", sarif)
+	}
+}
+
+func TestLocateFindingsMPD(t *testing.T) {
+	server := manifestServer(dashIFTestNonCompliant)
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{RulePacks: []string{RulePackDASHIF}}).Probe(context.Background(), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	manifestURL := server.URL + "/manifest.mpd"
+
+	tests := []struct {
+		ruleID   string
+		expected []FindingLocation
+	}{
+		{
+			ruleID:   "dashif-iop/profiles",
+			expected: []FindingLocation{{URL: manifestURL}},
+		},
+		{
+			ruleID: "dashif-iop/audio-channel-configuration",
+			expected: []FindingLocation{
+				{URL: manifestURL, Line: 10, Element: "Period[p0]/AdaptationSet[2]/Representation[a1]", StreamID: "0:3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ruleID, func(t *testing.T) {
+			for _, finding := range output.Rules {
+				if finding.RuleID != tt.ruleID {
+					continue
+				}
+				if !reflect.DeepEqual(finding.Locations, tt.expected) {
+					t.Errorf("Expected %+v, got %+v", tt.expected, finding.Locations)
+				}
+				return
+			}
+			t.Errorf("Expected a %s finding", tt.ruleID)
+		})
+	}
+
+	for _, finding := range output.Compatibility {
+		if len(finding.Locations) != len(finding.StreamIDs) {
+			t.Errorf("Expected %d compatibility locations, got %d", len(finding.StreamIDs), len(finding.Locations))
+		}
+	}
+}
+
+func TestElementName(t *testing.T) {
+	tests := []struct {
+		id       string
+		index    int
+		expected string
+	}{
+		{"v1", 0, "v1"},
+		{"", 0, "#1"},
+		{"", 2, "#3"},
+	}
+
+	for _, tt := range tests {
+		if result := elementName(tt.id, tt.index); result != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, result)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package probe
+
+import "time"
+
+// Format names of Format.FormatName, as ffprobe names the demuxers
+const (
+	FormatHLS  = "hls"
+	FormatDASH = "dash"
+)
+
+// Format describes the presentation as a whole, like the format section of ffprobe
+type Format struct {
+	// FormatName is FormatHLS or FormatDASH
+	FormatName string `json:"format_name"`
+
+	// IsLive reports a presentation still being published: an HLS media
+	// playlist without EXT-X-ENDLIST (and not of type VOD), or a dynamic MPD.
+	// HLS master playlists carry no such tags: IsLive is taken from their
+	// media playlists with ProbeOptions.FollowVariants, and nil without.
+	IsLive *bool `json:"is_live,omitempty"`
+
+	// PlaylistType is the EXT-X-PLAYLIST-TYPE of an HLS media playlist, or of
+	// the followed media playlists of a master playlist when they agree
+	PlaylistType string `json:"playlist_type,omitempty"`
+
+	// LiveEdge is the wall-clock time of the end of the last segment of a live
+	// HLS media playlist: its last EXT-X-PROGRAM-DATE-TIME plus the EXTINF
+	// durations from there (nil without program date time)
+	LiveEdge *time.Time `json:"live_edge,omitempty"`
+}
+
+// describeFormat returns the format block of an output parsed from a
+// manifest of the given format ("HLS" or "MPD", see manifestFormat)
+func describeFormat(output *Output, format string) *Format {
+	if format != "HLS" {
+		live := !output.ended
+		return &Format{FormatName: FormatDASH, IsLive: &live}
+	}
+
+	info := &Format{FormatName: FormatHLS}
+	if playlist := output.MediaPlaylist; playlist != nil {
+		live := !playlist.Ended && playlist.PlaylistType != MediaPlaylistVOD
+		info.IsLive = &live
+		info.PlaylistType = playlist.PlaylistType
+		if live {
+			info.LiveEdge = liveEdge(output.ProgramDateTime, playlist.TotalDuration)
+		}
+		return info
+	}
+
+	// Master playlist: the media playlists tell, when they were followed
+	var followed []*VariantPlaylist
+	for _, variant := range output.Variants {
+		if variant.Playlist != nil {
+			followed = append(followed, variant.Playlist)
+		}
+	}
+	if len(followed) == 0 {
+		return info
+	}
+	live := false
+	info.PlaylistType = followed[0].PlaylistType
+	for _, playlist := range followed {
+		live = live || (!playlist.Ended && playlist.PlaylistType != MediaPlaylistVOD)
+		if playlist.PlaylistType != info.PlaylistType {
+			info.PlaylistType = ""
+		}
+	}
+	info.IsLive = &live
+	return info
+}
+
+// liveEdge returns the wall-clock time of the end of a media playlist whose
+// EXTINF durations sum to totalDuration seconds (nil without anchors)
+func liveEdge(pdt *ProgramDateTime, totalDuration float64) *time.Time {
+	if pdt == nil || len(pdt.Anchors) == 0 {
+		return nil
+	}
+	last := pdt.Anchors[len(pdt.Anchors)-1]
+	edge := last.Time.Add(time.Duration((totalDuration - last.Offset) * float64(time.Second)))
+	return &edge
+}
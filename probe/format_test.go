@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDescribeFormat(t *testing.T) {
+	files := map[string]string{
+		"/live.m3u8": `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-PROGRAM-DATE-TIME:2026-10-18T12:00:00Z
+#EXTINF:6.0,
+seg100.ts
+#EXTINF:6.0,
+seg101.ts
+#EXTINF:4.5,
+seg102.ts
+`,
+		"/event.m3u8": `#EXTM3U
+#EXT-X-PLAYLIST-TYPE:EVENT
+#EXT-X-TARGETDURATION:6
+#EXTINF:6.0,
+seg0.ts
+`,
+		"/vod.m3u8": `#EXTM3U
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-TARGETDURATION:6
+#EXT-X-PROGRAM-DATE-TIME:2026-10-18T12:00:00Z
+#EXTINF:6.0,
+seg0.ts
+#EXT-X-ENDLIST
+`,
+		"/master.m3u8": `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=640x360
+event.m3u8
+`,
+		"/dynamic.mpd": `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic" availabilityStartTime="2026-10-18T00:00:00Z">
+  <Period id="p0" start="PT0S">
+    <AdaptationSet mimeType="video/mp4" contentType="video">
+      <Representation id="v1" codecs="avc1.640028" width="1920" height="1080" bandwidth="5000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(files[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	live, vod := true, false
+	edge := time.Date(2026, 10, 18, 12, 0, 16, 500000000, time.UTC)
+
+	tests := []struct {
+		name         string
+		path         string
+		follow       bool
+		formatName   string
+		isLive       *bool
+		playlistType string
+		liveEdge     *time.Time
+	}{
+		{"live media playlist", "/live.m3u8", false, FormatHLS, &live, "", &edge},
+		{"event media playlist", "/event.m3u8", false, FormatHLS, &live, MediaPlaylistEvent, nil},
+		{"VOD media playlist", "/vod.m3u8", false, FormatHLS, &vod, MediaPlaylistVOD, nil},
+		{"master playlist", "/master.m3u8", false, FormatHLS, nil, "", nil},
+		{"followed master playlist", "/master.m3u8", true, FormatHLS, &live, MediaPlaylistEvent, nil},
+		{"dynamic MPD", "/dynamic.mpd", false, FormatDASH, &live, "", nil},
+	}
+
+	for _, tt := range tests {
+		output, err := NewProber(&ProbeOptions{FollowVariants: tt.follow}).Probe(context.Background(), server.URL+tt.path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		format := output.Format
+		if format == nil {
+			t.Fatalf("%s: expected a format block", tt.name)
+		}
+		if format.FormatName != tt.formatName {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.formatName, format.FormatName)
+		}
+		if (format.IsLive == nil) != (tt.isLive == nil) || (format.IsLive != nil && *format.IsLive != *tt.isLive) {
+			t.Errorf("%s: expected is_live %v, got %v", tt.name, boolString(tt.isLive), boolString(format.IsLive))
+		}
+		if format.PlaylistType != tt.playlistType {
+			t.Errorf("%s: expected playlist type %q, got %q", tt.name, tt.playlistType, format.PlaylistType)
+		}
+		if (format.LiveEdge == nil) != (tt.liveEdge == nil) || (format.LiveEdge != nil && !format.LiveEdge.Equal(*tt.liveEdge)) {
+			t.Errorf("%s: expected live edge %v, got %v", tt.name, tt.liveEdge, format.LiveEdge)
+		}
+	}
+}
+
+func boolString(b *bool) string {
+	if b == nil {
+		return "nil"
+	}
+	if *b {
+		return "true"
+	}
+	return "false"
+}
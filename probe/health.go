@@ -0,0 +1,85 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// HealthState is the circuit breaker state of the origins a Prober has
+// contacted. Restoring it at startup keeps a currently broken origin
+// protected across restarts of a probing fleet.
+type HealthState struct {
+	// Origins maps an origin ("https://cdn.example.com") to its circuit breaker state
+	Origins map[string]CircuitSnapshot `json:"origins"`
+
+	// SavedAt is when the state was exported
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// HealthState returns the circuit breaker state of every origin the prober
+// has a client for (no origins without ProbeOptions.RetryConfig and
+// CircuitBreakerConfig)
+func (p *Prober) HealthState() *HealthState {
+	state := &HealthState{Origins: make(map[string]CircuitSnapshot), SavedAt: p.clock.Now()}
+
+	p.clientsMu.Lock()
+	defer p.clientsMu.Unlock()
+	for origin, client := range p.clients {
+		if client.retryExecutor == nil || client.retryExecutor.circuitBreaker == nil {
+			continue
+		}
+		state.Origins[origin] = client.retryExecutor.circuitBreaker.Snapshot()
+	}
+	return state
+}
+
+// RestoreHealthState restores the circuit breakers of the origins in state,
+// creating their clients. Origins are restored in sorted order; those the
+// prober has no circuit breaker for (e.g. after a configuration change) are
+// skipped.
+func (p *Prober) RestoreHealthState(state *HealthState) error {
+	if state == nil {
+		return nil
+	}
+
+	origins := make([]string, 0, len(state.Origins))
+	for origin, snapshot := range state.Origins {
+		if snapshot.State < CircuitStateClosed || snapshot.State > CircuitStateHalfOpen {
+			return NewValidationError(fmt.Sprintf("invalid circuit state %d for origin %s", snapshot.State, origin))
+		}
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+
+	for _, origin := range origins {
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") {
+			return NewValidationError(fmt.Sprintf("invalid origin %q", origin))
+		}
+		client, err := p.httpClient(parsed)
+		if err != nil {
+			return err
+		}
+		if client.retryExecutor != nil && client.retryExecutor.circuitBreaker != nil {
+			client.retryExecutor.circuitBreaker.Restore(state.Origins[origin])
+		}
+	}
+	return nil
+}
+
+// ExportHealth renders HealthState as JSON
+func (p *Prober) ExportHealth() ([]byte, error) {
+	return json.MarshalIndent(p.HealthState(), "", "  ")
+}
+
+// ImportHealth restores circuit breaker state exported by ExportHealth
+func (p *Prober) ImportHealth(data []byte) error {
+	var state HealthState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return NewParsingError("", "health state", err)
+	}
+	return p.RestoreHealthState(&state)
+}
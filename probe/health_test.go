@@ -0,0 +1,103 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func healthTestOptions(clock Clock) *ProbeOptions {
+	return &ProbeOptions{
+		RetryConfig: &RetryConfig{MaxRetries: 0, RetryableErrors: []ErrorType{ErrorTypeNetwork}},
+		CircuitBreakerConfig: &CircuitBreakerConfig{
+			Enabled:             true,
+			FailureThreshold:    1,
+			ResetTimeout:        time.Minute,
+			HalfOpenMaxRequests: 1,
+		},
+		Clock: clock,
+	}
+}
+
+func TestHealthStateRoundTrip(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	prober := NewProber(healthTestOptions(clock))
+	if _, err := prober.Probe(context.Background(), server.URL+"/manifest.mpd"); err == nil {
+		t.Fatal("Expected an error from the failing origin")
+	}
+
+	data, err := prober.ExportHealth()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A restarted prober keeps the origin's circuit open
+	restarted := NewProber(healthTestOptions(clock))
+	if err := restarted.ImportHealth(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	before := requests.Load()
+	_, err = restarted.Probe(context.Background(), server.URL+"/manifest.mpd")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected %v, got %v", ErrCircuitOpen, err)
+	}
+	if requests.Load() != before {
+		t.Errorf("Expected no request to the origin while its circuit is open")
+	}
+
+	state := restarted.HealthState()
+	snapshot, ok := state.Origins[server.URL]
+	if !ok || snapshot.State != CircuitStateOpen || !snapshot.LastFailTime.Equal(clock.Now()) {
+		t.Errorf("Expected an open circuit for %s, got %+v", server.URL, state.Origins)
+	}
+}
+
+func TestRestoreHealthStateValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		state  CircuitState
+	}{
+		{"path", "https://cdn.example.com/live", CircuitStateOpen},
+		{"scheme", "ftp://cdn.example.com", CircuitStateOpen},
+		{"host", "https://", CircuitStateOpen},
+		{"state", "https://cdn.example.com", CircuitState(7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prober := NewProber(healthTestOptions(nil))
+			err := prober.RestoreHealthState(&HealthState{Origins: map[string]CircuitSnapshot{tt.origin: {State: tt.state}}})
+			var probeErr *ProbeError
+			if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeValidation {
+				t.Errorf("Expected a validation error, got %v", err)
+			}
+		})
+	}
+
+	if err := NewProber(healthTestOptions(nil)).ImportHealth([]byte("not json")); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestHealthStateWithoutCircuitBreaker(t *testing.T) {
+	prober := NewProber(nil)
+	state := &HealthState{Origins: map[string]CircuitSnapshot{"https://cdn.example.com": {State: CircuitStateOpen}}}
+	if err := prober.RestoreHealthState(state); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if origins := prober.HealthState().Origins; len(origins) != 0 {
+		t.Errorf("Expected no origins without circuit breakers, got %+v", origins)
+	}
+}
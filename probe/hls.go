@@ -2,44 +2,650 @@ package probe
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-// parseHLSManifest parses an HLS M3U8 manifest and returns stream information
+// IFrameStreamType is the stream type of HLS I-frame playlists (see ProbeOptions.IFrameStreams)
+const IFrameStreamType = "Video (iframe-only)"
+
+// hlsInterstitialClass is the EXT-X-DATERANGE CLASS of Apple HLS interstitials
+const hlsInterstitialClass = "com.apple.hls.interstitial"
+
+// Interstitial is an HLS interstitial scheduled with EXT-X-DATERANGE
+type Interstitial struct {
+	ID        string  `json:"id"`
+	StartDate string  `json:"start_date"`
+	Duration  float64 `json:"duration,omitempty"`
+	Cue       string  `json:"cue,omitempty"`
+	AssetURI  string  `json:"asset_uri,omitempty"`
+	AssetList string  `json:"asset_list,omitempty"`
+}
+
+// HLSVariant describes an EXT-X-STREAM-INF variant of a multivariant playlist
+type HLSVariant struct {
+	URI              string `json:"uri"`
+	Bandwidth        int64  `json:"bandwidth"`
+	AverageBandwidth int64  `json:"average_bandwidth,omitempty"`
+
+	// HDCPLevel is the variant's HDCP-LEVEL: "TYPE-0", "TYPE-1" or "NONE"
+	HDCPLevel string `json:"hdcp_level,omitempty"`
+
+	// Codecs is the variant's CODECS attribute
+	Codecs string `json:"codecs,omitempty"`
+
+	// Score is the variant's SCORE, its preference relative to other variants (nil if absent)
+	Score *float64 `json:"score,omitempty"`
+
+	// StableVariantID identifies the variant across playlist reloads and pathways
+	StableVariantID string `json:"stable_variant_id,omitempty"`
+
+	// PathwayID is the content steering pathway the variant belongs to
+	PathwayID string `json:"pathway_id,omitempty"`
+
+	// StreamIDs are the streams reported for the variant
+	StreamIDs []string `json:"stream_ids"`
+
+	// Playlist summarizes the variant's media playlist (with ProbeOptions.FollowVariants)
+	Playlist *VariantPlaylist `json:"playlist,omitempty"`
+}
+
+// hlsRendition is an EXT-X-MEDIA rendition
+type hlsRendition struct {
+	Type              string
+	GroupID           string
+	Name              string
+	Language          string
+	URI               string
+	Characteristics   string
+	StableRenditionID string
+
+	// Channels is the CHANNELS attribute of an AUDIO rendition ("6", "16/JOC")
+	Channels string
+
+	// InstreamID is the caption channel of a CLOSED-CAPTIONS rendition ("CC1", "SERVICE1")
+	InstreamID string
+
+	// Line is the 1-based line number of the EXT-X-MEDIA tag
+	Line int
+}
+
+// hlsVariant holds the EXT-X-STREAM-INF attributes relevant to renditions
+type hlsVariant struct {
+	videoCodec string
+	codecs     string
+	resolution string
+	frameRate  string
+	bandwidth  string
+}
+
+// hlsVariantRef locates the media playlist of an EXT-X-STREAM-INF variant
+type hlsVariantRef struct {
+	streamID         string
+	uri              string
+	bandwidth        int64
+	averageBandwidth int64
+}
+
+// parseHLSManifest parses an HLS M3U8 manifest and returns stream information,
+// in a single pass over its lines
 func parseHLSManifest(content string, manifestURL string) (*Output, error) {
 	var streams []StreamInfo
 	streamIndex := 0
 
-	lines := strings.Split(content, "\n")
+	var start *StartInfo
+	var interstitials []Interstitial
+	var videoRenditions []hlsRendition
+	var textRenditions []hlsRendition
+	var audioRenditions []hlsRendition
+	audioGroupChannels := make(map[string]string)
+	var iframes []StreamInfo
+	var sessionData []SessionData
+	videoGroupVariants := make(map[string]hlsVariant)
+	subtitleGroupCodecs := make(map[string]string)
+	audioGroupVariants := make(map[string][]int)
+	audioGroupCodecs := make(map[string]string)
+	var variants []hlsVariantRef
+	var pendingVariant *hlsVariantRef
+	var variantInfos []HLSVariant
+	var pendingInfo *HLSVariant
+	var pendingStreams []int
+	var pendingAudioGroup string
+
+	// The tags of media playlists are parsed in the same pass
+	var timing hlsTimingParser
+	var pdt pdtParser
+	features := hlsFeatureParser{features: make(map[string]bool)}
+	var adBreaks adBreakParser
+	mediaPlaylist := newMediaPlaylistParser(manifestURL)
+
+	for lines := scanLines(content); lines.scan(); {
+		line, lineIndex := lines.line, lines.index
+
+		trimmed := strings.TrimSpace(line)
+		timing.parseLine(trimmed)
+		pdt.parseLine(trimmed)
+		features.parseLine(trimmed)
+		mediaPlaylist.parseLine(trimmed)
+
+		// The URI line following EXT-X-STREAM-INF locates the variant's media playlist
+		if uri := strings.TrimSpace(line); pendingVariant != nil && uri != "" && !strings.HasPrefix(uri, "#") {
+			pendingVariant.uri = resolveHLSURI(manifestURL, uri)
+			variants = append(variants, *pendingVariant)
+			pendingInfo.URI = pendingVariant.uri
+			variantInfos = append(variantInfos, *pendingInfo)
+			if pendingAudioGroup != "" {
+				audioGroupVariants[pendingAudioGroup] = append(audioGroupVariants[pendingAudioGroup], len(variantInfos)-1)
+			}
+			for _, i := range pendingStreams {
+				streams[i].urls = []string{pendingVariant.uri}
+				streams[i].Source.URI = uri
+				streams[i].NativeID = pendingInfo.StableVariantID
+				if streams[i].NativeID == "" {
+					streams[i].NativeID = uri
+				}
+			}
+			pendingVariant, pendingInfo, pendingStreams = nil, nil, nil
+			continue
+		}
+
+		if strings.HasPrefix(line, "#EXT-X-START:") {
+			start = parseHLSStart(line)
+			continue
+		}
+
+		if strings.HasPrefix(line, "#EXT-X-SESSION-DATA:") {
+			sessionData = append(sessionData, parseHLSSessionData(line, manifestURL))
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "#EXT-X-DATERANGE:") {
+			attrs := parseHLSAttributes(trimmed)
+			if attrs["CLASS"] == hlsInterstitialClass {
+				interstitials = append(interstitials, createHLSInterstitial(attrs, manifestURL))
+			}
+			adBreaks.parseDateRange(attrs, lineIndex)
+			continue
+		}
+
+		if strings.HasPrefix(line, "#EXT-X-MEDIA:") {
+			rendition := parseHLSRendition(line)
+			rendition.Line = lineIndex + 1
+			switch rendition.Type {
+			case "AUDIO":
+				if _, ok := audioGroupChannels[rendition.GroupID]; !ok && rendition.Channels != "" {
+					audioGroupChannels[rendition.GroupID] = rendition.Channels
+				}
+				audioRenditions = append(audioRenditions, rendition)
+			case "VIDEO":
+				videoRenditions = append(videoRenditions, rendition)
+			case "SUBTITLES", "CLOSED-CAPTIONS":
+				textRenditions = append(textRenditions, rendition)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "#EXT-X-I-FRAME-STREAM-INF:") {
+			iframes = append(iframes, createHLSIFrameStream(parseHLSAttributes(line), lineIndex+1, manifestURL))
+			continue
+		}
 
-	for _, line := range lines {
 		if strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
 			// Parse stream info line
-			bandwidth := extractHLSParam(line, "BANDWIDTH")
-			resolution := extractHLSParam(line, "RESOLUTION")
-			frameRate := extractHLSParam(line, "FRAME-RATE")
-			codecs := extractHLSParam(line, "CODECS")
+			attrs := parseHLSAttributes(line)
+			bandwidth := attrs["BANDWIDTH"]
+			resolution := attrs["RESOLUTION"]
+			frameRate := attrs["FRAME-RATE"]
+			codecs := attrs["CODECS"]
+			videoGroup := attrs["VIDEO"]
 
 			// Extract video and audio codecs
 			videoCodec, audioCodec := parseHLSCodecs(codecs)
 
+			// Remember the first variant of each video group for its angles
+			if _, ok := videoGroupVariants[videoGroup]; videoGroup != "" && !ok {
+				videoGroupVariants[videoGroup] = hlsVariant{
+					videoCodec: videoCodec,
+					codecs:     codecs,
+					resolution: resolution,
+					frameRate:  frameRate,
+					bandwidth:  bandwidth,
+				}
+			}
+
+			// Remember the CODECS of the first variant of each subtitle group for its format
+			if group := attrs["SUBTITLES"]; group != "" {
+				if _, ok := subtitleGroupCodecs[group]; !ok {
+					subtitleGroupCodecs[group] = codecs
+				}
+			}
+
+			// Remember the audio codec of the first variant of each audio group
+			if group := attrs["AUDIO"]; group != "" {
+				if _, ok := audioGroupCodecs[group]; !ok {
+					audioGroupCodecs[group] = audioCodec
+				}
+			}
+			pendingAudioGroup = attrs["AUDIO"]
+
 			// Add video stream
+			pendingVariant = &hlsVariantRef{streamID: fmt.Sprintf("0:%d", streamIndex)}
+			pendingInfo = createHLSVariant(attrs)
 			if resolution != "" {
 				videoStream := createHLSVideoStream(streamIndex, videoCodec, resolution, frameRate, bandwidth, codecs)
+				videoStream.GroupID = videoGroup
+				setHLSVariantAttributes(&videoStream, pendingInfo)
+				videoStream.Source = &SourceRef{Tag: "EXT-X-STREAM-INF", Line: lineIndex + 1, GroupID: videoGroup, StableVariantID: pendingInfo.StableVariantID}
 				streams = append(streams, videoStream)
+				pendingInfo.StreamIDs = append(pendingInfo.StreamIDs, videoStream.StreamID)
+				pendingStreams = append(pendingStreams, len(streams)-1)
 				streamIndex++
 			}
+			pendingVariant.bandwidth = pendingInfo.Bandwidth
+			pendingVariant.averageBandwidth = pendingInfo.AverageBandwidth
 
 			// Add audio stream
 			audioStream := createHLSAudioStream(streamIndex, audioCodec)
+			setHLSVariantAttributes(&audioStream, pendingInfo)
+			audioStream.Source = &SourceRef{Tag: "EXT-X-STREAM-INF", Line: lineIndex + 1, GroupID: attrs["AUDIO"], StableVariantID: pendingInfo.StableVariantID}
 			streams = append(streams, audioStream)
+			pendingInfo.StreamIDs = append(pendingInfo.StreamIDs, audioStream.StreamID)
+			pendingStreams = append(pendingStreams, len(streams)-1)
 			streamIndex++
 		}
 	}
 
-	return &Output{Streams: streams}, nil
+	// Describe the channels of variant audio from the CHANNELS of its audio
+	// group, which may be declared after the variant
+	for i := range streams {
+		if streams[i].Type != "Audio" || streams[i].Source == nil {
+			continue
+		}
+		if channels := hlsChannelLayout(audioGroupChannels[streams[i].Source.GroupID]); channels != "" {
+			streams[i].Channels = channels
+		}
+	}
+
+	// Add alternative video renditions (camera angles) of referenced groups.
+	// A rendition without URI is the video of the variant itself.
+	for _, rendition := range videoRenditions {
+		variant, ok := videoGroupVariants[rendition.GroupID]
+		if !ok || rendition.URI == "" {
+			continue
+		}
+		stream := createHLSAngleStream(streamIndex, rendition, variant)
+		stream.urls = []string{resolveHLSURI(manifestURL, rendition.URI)}
+		streams = append(streams, stream)
+		streamIndex++
+	}
+
+	// Add subtitle and closed caption renditions
+	for _, rendition := range textRenditions {
+		stream := createHLSSubtitleStream(streamIndex, rendition, subtitleGroupCodecs[rendition.GroupID])
+		if rendition.URI != "" {
+			stream.urls = []string{resolveHLSURI(manifestURL, rendition.URI)}
+		}
+		streams = append(streams, stream)
+		streamIndex++
+	}
+
+	// I-frame playlists are numbered last so that including them leaves the
+	// IDs of the other streams unchanged
+	for i := range iframes {
+		iframes[i].StreamID = fmt.Sprintf("0:%d", streamIndex)
+		streamIndex++
+	}
+
+	// Audio renditions are numbered after the I-frame playlists, for the same reason
+	renditionStreams, audioGroups := createHLSAudioRenditionStreams(streamIndex, audioRenditions, audioGroupVariants, audioGroupCodecs, manifestURL)
+
+	output := &Output{
+		Streams:         streams,
+		Start:           start,
+		Interstitials:   interstitials,
+		Warnings:        validateHLSStableIDs(variantInfos, append(append(videoRenditions, textRenditions...), audioRenditions...)),
+		Timing:          timing.timing,
+		ProgramDateTime: pdt.finish(),
+		Features:        features.finish(),
+		Variants:        variantInfos,
+		Compatibility:   hlsCompatibility(variantInfos, streams),
+		variants:        variants,
+		iframes:         iframes,
+		audioRenditions: renditionStreams,
+		audioGroups:     audioGroups,
+		ended:           mediaPlaylist.playlist.Ended,
+	}
+	output.Warnings = append(output.Warnings, validateHLSHDCPLevels(variantInfos)...)
+	if warning := pdtWarning(output.ProgramDateTime); warning != "" {
+		output.Warnings = append(output.Warnings, warning)
+	}
+	breaks, adWarnings := adBreaks.finish(output.ProgramDateTime, output.ended)
+	output.AdBreaks = breaks
+	output.Warnings = append(output.Warnings, adWarnings...)
+	metadata, warnings := sessionMetadata(sessionData)
+	output.Metadata = metadata
+	output.Warnings = append(output.Warnings, warnings...)
+	if mediaPlaylist.isMediaPlaylist() {
+		output.MediaPlaylist = mediaPlaylist.finish()
+	}
+
+	return output, nil
+}
+
+// createHLSVariant creates a variant from EXT-X-STREAM-INF attributes
+func createHLSVariant(attrs map[string]string) *HLSVariant {
+	variant := &HLSVariant{
+		Codecs:          attrs["CODECS"],
+		HDCPLevel:       attrs["HDCP-LEVEL"],
+		StableVariantID: attrs["STABLE-VARIANT-ID"],
+		PathwayID:       attrs["PATHWAY-ID"],
+	}
+	variant.Bandwidth, _ = strconv.ParseInt(attrs["BANDWIDTH"], 10, 64)
+	variant.AverageBandwidth, _ = strconv.ParseInt(attrs["AVERAGE-BANDWIDTH"], 10, 64)
+	if score, err := strconv.ParseFloat(attrs["SCORE"], 64); err == nil {
+		variant.Score = &score
+	}
+	return variant
+}
+
+// setHLSVariantAttributes copies the ladder attributes of a variant to one of its streams
+func setHLSVariantAttributes(stream *StreamInfo, variant *HLSVariant) {
+	stream.AverageBandwidth = variant.AverageBandwidth
+	stream.HDCPLevel = variant.HDCPLevel
+	stream.StableVariantID = variant.StableVariantID
+	if variant.Score != nil {
+		score := *variant.Score
+		stream.Score = &score
+	}
+}
+
+// hdcpLevels are the valid HDCP-LEVEL values
+var hdcpLevels = map[string]bool{"TYPE-0": true, "TYPE-1": true, "NONE": true}
+
+// validateHLSHDCPLevels reports variants with an invalid HDCP-LEVEL
+func validateHLSHDCPLevels(variants []HLSVariant) []string {
+	var warnings []string
+	for _, variant := range variants {
+		if variant.HDCPLevel != "" && !hdcpLevels[variant.HDCPLevel] {
+			warnings = append(warnings, fmt.Sprintf("HDCP-LEVEL %q of %s is not TYPE-0, TYPE-1 or NONE", variant.HDCPLevel, variant.URI))
+		}
+	}
+	return warnings
+}
+
+// stableIDPattern matches the characters allowed in STABLE-VARIANT-ID and STABLE-RENDITION-ID
+var stableIDPattern = regexp.MustCompile(`^[a-zA-Z0-9+/=._-]+$`)
+
+// validateHLSStableIDs reports stable IDs with invalid characters, and
+// STABLE-VARIANT-IDs used by more than one variant of the same pathway
+func validateHLSStableIDs(variants []HLSVariant, renditions []hlsRendition) []string {
+	var warnings []string
+
+	seen := make(map[[2]string]bool)
+	for _, variant := range variants {
+		if variant.StableVariantID == "" {
+			continue
+		}
+		if !stableIDPattern.MatchString(variant.StableVariantID) {
+			warnings = append(warnings, fmt.Sprintf("STABLE-VARIANT-ID %q of %s contains invalid characters", variant.StableVariantID, variant.URI))
+		}
+		key := [2]string{variant.PathwayID, variant.StableVariantID}
+		if seen[key] {
+			warnings = append(warnings, fmt.Sprintf("STABLE-VARIANT-ID %q is used by more than one variant of pathway %q", variant.StableVariantID, variant.PathwayID))
+		}
+		seen[key] = true
+	}
+
+	for _, rendition := range renditions {
+		if rendition.StableRenditionID != "" && !stableIDPattern.MatchString(rendition.StableRenditionID) {
+			warnings = append(warnings, fmt.Sprintf("STABLE-RENDITION-ID %q of rendition %q contains invalid characters", rendition.StableRenditionID, rendition.Name))
+		}
+	}
+
+	return warnings
+}
+
+// createHLSInterstitial creates an interstitial from EXT-X-DATERANGE attributes
+func createHLSInterstitial(attrs map[string]string, manifestURL string) Interstitial {
+	interstitial := Interstitial{
+		ID:        attrs["ID"],
+		StartDate: attrs["START-DATE"],
+		Cue:       attrs["CUE"],
+		AssetURI:  resolveHLSURI(manifestURL, attrs["X-ASSET-URI"]),
+		AssetList: resolveHLSURI(manifestURL, attrs["X-ASSET-LIST"]),
+	}
+	if duration, err := strconv.ParseFloat(attrs["DURATION"], 64); err == nil {
+		interstitial.Duration = duration
+	}
+	return interstitial
+}
+
+// resolveHLSURI resolves a playlist URI against the manifest URL
+func resolveHLSURI(manifestURL, uri string) string {
+	if uri == "" {
+		return ""
+	}
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return uri
+	}
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// parseHLSRendition parses an EXT-X-MEDIA tag
+func parseHLSRendition(line string) hlsRendition {
+	attrs := parseHLSAttributes(line)
+	return hlsRendition{
+		Type:              attrs["TYPE"],
+		GroupID:           attrs["GROUP-ID"],
+		Name:              attrs["NAME"],
+		Language:          attrs["LANGUAGE"],
+		URI:               attrs["URI"],
+		Characteristics:   attrs["CHARACTERISTICS"],
+		StableRenditionID: attrs["STABLE-RENDITION-ID"],
+		InstreamID:        attrs["INSTREAM-ID"],
+		Channels:          attrs["CHANNELS"],
+	}
+}
+
+// source returns the SourceRef of the rendition's EXT-X-MEDIA tag
+func (r hlsRendition) source() *SourceRef {
+	return &SourceRef{Tag: "EXT-X-MEDIA", Line: r.Line, GroupID: r.GroupID, URI: r.URI}
+}
+
+// nativeID returns the most stable manifest identifier of the rendition
+func (r hlsRendition) nativeID() string {
+	switch {
+	case r.StableRenditionID != "":
+		return r.StableRenditionID
+	case r.URI != "":
+		return r.URI
+	case r.InstreamID != "":
+		return r.InstreamID
+	default:
+		return r.GroupID + "/" + r.Name
+	}
+}
+
+// createHLSAngleStream creates a video stream for an alternative VIDEO rendition,
+// using the codec and resolution of the variants referencing its group
+func createHLSAngleStream(streamIndex int, rendition hlsRendition, variant hlsVariant) StreamInfo {
+	stream := createHLSVideoStream(streamIndex, variant.videoCodec, variant.resolution, variant.frameRate, variant.bandwidth, variant.codecs)
+	stream.Name = rendition.Name
+	stream.GroupID = rendition.GroupID
+	stream.Language = rendition.Language
+	stream.Disposition = hlsDisposition(rendition.Characteristics)
+	stream.StableRenditionID = rendition.StableRenditionID
+	stream.Source = rendition.source()
+	stream.NativeID = rendition.nativeID()
+	return stream
+}
+
+// createHLSSubtitleStream creates a subtitle stream for a SUBTITLES or
+// CLOSED-CAPTIONS rendition. Subtitles are WebVTT unless the CODECS of the
+// variants referencing their group declare IMSC (stpp); closed captions are
+// CEA-608 for CC1-CC4 channels and CEA-708 for SERVICE channels.
+func createHLSSubtitleStream(streamIndex int, rendition hlsRendition, variantCodecs string) StreamInfo {
+	codec := "webvtt"
+	switch {
+	case rendition.Type == "CLOSED-CAPTIONS" && strings.HasPrefix(rendition.InstreamID, "SERVICE"):
+		codec = "cea-708"
+	case rendition.Type == "CLOSED-CAPTIONS":
+		codec = "cea-608"
+	case strings.Contains(variantCodecs, "stpp"):
+		codec = "stpp"
+	}
+
+	return StreamInfo{
+		StreamID:          fmt.Sprintf("0:%d", streamIndex),
+		Type:              "Subtitle",
+		Codec:             codec,
+		Language:          rendition.Language,
+		Name:              rendition.Name,
+		GroupID:           rendition.GroupID,
+		Disposition:       hlsDisposition(rendition.Characteristics),
+		StableRenditionID: rendition.StableRenditionID,
+		NativeID:          rendition.nativeID(),
+		Source:            rendition.source(),
+	}
+}
+
+// createHLSAudioRenditionStreams creates one audio stream per unique AUDIO
+// rendition of the groups referenced by variants, numbered from streamIndex.
+// Renditions are the same when they have the same URI (or, without one, the
+// same group and name), so that a rendition listed in several groups is
+// reported once. It also returns the stream IDs of the renditions of each group.
+func createHLSAudioRenditionStreams(streamIndex int, renditions []hlsRendition, groupVariants map[string][]int, groupCodecs map[string]string, manifestURL string) ([]StreamInfo, map[string][]string) {
+	var streams []StreamInfo
+	groups := make(map[string][]string)
+	seen := make(map[string]int)
+	for _, rendition := range renditions {
+		variants, ok := groupVariants[rendition.GroupID]
+		if !ok {
+			continue
+		}
+		key := rendition.URI
+		if key == "" {
+			key = rendition.GroupID + "/" + rendition.Name
+		}
+		i, ok := seen[key]
+		if !ok {
+			stream := createHLSAudioStream(streamIndex, groupCodecs[rendition.GroupID])
+			stream.Name = rendition.Name
+			stream.GroupID = rendition.GroupID
+			stream.Language = rendition.Language
+			stream.Disposition = hlsDisposition(rendition.Characteristics)
+			stream.StableRenditionID = rendition.StableRenditionID
+			if channels := hlsChannelLayout(rendition.Channels); channels != "" {
+				stream.Channels = channels
+			}
+			stream.Source = rendition.source()
+			stream.NativeID = rendition.nativeID()
+			if rendition.URI != "" {
+				stream.urls = []string{resolveHLSURI(manifestURL, rendition.URI)}
+			}
+			streams = append(streams, stream)
+			streamIndex++
+			i = len(streams) - 1
+			seen[key] = i
+		}
+		if !containsString(groups[rendition.GroupID], streams[i].StreamID) {
+			groups[rendition.GroupID] = append(groups[rendition.GroupID], streams[i].StreamID)
+		}
+		for _, variant := range variants {
+			if !containsInt(streams[i].VariantIndexes, variant) {
+				streams[i].VariantIndexes = append(streams[i].VariantIndexes, variant)
+			}
+		}
+	}
+	for i := range streams {
+		sort.Ints(streams[i].VariantIndexes)
+	}
+	return streams, groups
+}
+
+// groupAudio replaces the audio streams of the variants of AUDIO groups with
+// renditions by the streams of the renditions, appended after the other streams
+func (o *Output) groupAudio() {
+	if len(o.audioRenditions) == 0 {
+		return
+	}
+	replaced := make(map[string][]string)
+	var streams []StreamInfo
+	for _, stream := range o.Streams {
+		if stream.Type == "Audio" && stream.Source != nil && stream.Source.Tag == "EXT-X-STREAM-INF" {
+			if ids, ok := o.audioGroups[stream.Source.GroupID]; ok {
+				replaced[stream.StreamID] = ids
+				continue
+			}
+		}
+		streams = append(streams, stream)
+	}
+	o.Streams = append(streams, o.audioRenditions...)
+
+	for i := range o.Variants {
+		o.Variants[i].StreamIDs = expandStreamIDs(o.Variants[i].StreamIDs, replaced)
+	}
+	for i := range o.Compatibility {
+		o.Compatibility[i].StreamIDs = expandStreamIDs(o.Compatibility[i].StreamIDs, replaced)
+	}
+}
+
+// expandStreamIDs replaces each ID of ids by its replacements, without duplicates
+func expandStreamIDs(ids []string, replacements map[string][]string) []string {
+	result := []string{}
+	for _, id := range ids {
+		with, ok := replacements[id]
+		if !ok {
+			with = []string{id}
+		}
+		for _, replacement := range with {
+			if !containsString(result, replacement) {
+				result = append(result, replacement)
+			}
+		}
+	}
+	return result
+}
+
+// createHLSIFrameStream creates a stream for an EXT-X-I-FRAME-STREAM-INF tag,
+// whose URI attribute locates the I-frame media playlist
+func createHLSIFrameStream(attrs map[string]string, line int, manifestURL string) StreamInfo {
+	videoCodec, _ := parseHLSCodecs(attrs["CODECS"])
+	stream := createHLSVideoStream(0, videoCodec, attrs["RESOLUTION"], "", attrs["BANDWIDTH"], attrs["CODECS"])
+	stream.Type = IFrameStreamType
+	stream.FrameRate = ""
+	stream.GroupID = attrs["VIDEO"]
+	stream.Source = &SourceRef{Tag: "EXT-X-I-FRAME-STREAM-INF", Line: line, GroupID: attrs["VIDEO"], StableVariantID: attrs["STABLE-VARIANT-ID"], URI: attrs["URI"]}
+	stream.NativeID = attrs["STABLE-VARIANT-ID"]
+	if stream.NativeID == "" {
+		stream.NativeID = attrs["URI"]
+	}
+	setHLSVariantAttributes(&stream, createHLSVariant(attrs))
+	if uri := attrs["URI"]; uri != "" {
+		stream.urls = []string{resolveHLSURI(manifestURL, uri)}
+	}
+	return stream
+}
+
+// parseHLSStart parses an EXT-X-START tag (nil if TIME-OFFSET is missing or invalid)
+func parseHLSStart(line string) *StartInfo {
+	offset, err := strconv.ParseFloat(extractHLSParam(line, "TIME-OFFSET"), 64)
+	if err != nil {
+		return nil
+	}
+	return &StartInfo{
+		Source:  "EXT-X-START",
+		Offset:  offset,
+		Precise: extractHLSParam(line, "PRECISE") == "YES",
+	}
 }
 
 func createHLSVideoStream(streamIndex int, videoCodec, resolution, frameRate, bandwidth, codecs string) StreamInfo {
@@ -79,25 +685,82 @@ func createHLSAudioStream(streamIndex int, audioCodec string) StreamInfo {
 	}
 }
 
-func extractHLSParam(line, param string) string {
-	re := regexp.MustCompile(param + `=([^,\s]+)`)
-	matches := re.FindStringSubmatch(line)
-	if len(matches) > 1 {
-		return strings.Trim(matches[1], `"`)
+// hlsChannelLayouts maps HLS channel counts to ffprobe channel layouts
+var hlsChannelLayouts = map[int]string{
+	1: "mono",
+	2: "stereo",
+	3: "2.1",
+	4: "4.0",
+	5: "5.0",
+	6: "5.1",
+	7: "6.1",
+	8: "7.1",
+}
+
+// hlsChannelLayout describes an EXT-X-MEDIA CHANNELS attribute: the channel
+// count as an ffprobe layout ("6" is "5.1"), and Dolby Atmos carried as
+// E-AC-3 joint object coding ("16/JOC") as "Atmos/JOC". It returns "" when
+// channels is empty or invalid.
+func hlsChannelLayout(channels string) string {
+	params := strings.Split(channels, "/")
+	count, err := strconv.Atoi(params[0])
+	if err != nil || count <= 0 {
+		return ""
 	}
-	return ""
+	if len(params) > 1 && strings.Contains(params[1], "JOC") {
+		return "Atmos/JOC"
+	}
+	return channelCountLayout(count)
 }
 
-func parseHLSCodecs(codecs string) (string, string) {
-	videoCodec := "h264"
-	audioCodec := "aac"
+func extractHLSParam(line, param string) string {
+	return parseHLSAttributes(line)[param]
+}
+
+// parseHLSAttributes parses the attribute list of a tag line into a map,
+// handling quoted values that contain commas. Quotes are stripped.
+func parseHLSAttributes(line string) map[string]string {
+	attrs := make(map[string]string)
 
-	if strings.Contains(codecs, "avc1") {
-		videoCodec = "h264"
+	if i := strings.Index(line, ":"); i >= 0 {
+		line = line[i+1:]
 	}
-	if strings.Contains(codecs, "mp4a") {
-		audioCodec = "aac"
+
+	for len(line) > 0 {
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			break
+		}
+		name := strings.TrimSpace(line[:eq])
+		line = line[eq+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := strings.Index(line[1:], `"`)
+			if end < 0 {
+				value, line = line[1:], ""
+			} else {
+				value, line = line[1:end+1], line[end+2:]
+			}
+			if comma := strings.Index(line, ","); comma >= 0 {
+				line = line[comma+1:]
+			} else {
+				line = ""
+			}
+		} else if comma := strings.Index(line, ","); comma >= 0 {
+			value, line = line[:comma], line[comma+1:]
+		} else {
+			value, line = line, ""
+		}
+
+		attrs[name] = strings.TrimSpace(value)
 	}
 
-	return videoCodec, audioCodec
+	return attrs
+}
+
+// parseHLSCodecs returns the video and audio codecs of a CODECS attribute,
+// defaulting to h264 and aac when it does not declare one
+func parseHLSCodecs(codecs string) (string, string) {
+	return parseVideoCodec(codecs), parseAudioCodec(codecs)
 }
\ No newline at end of file
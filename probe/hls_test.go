@@ -0,0 +1,700 @@
+package probe
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseHLSStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected *StartInfo
+	}{
+		{
+			name: "negative precise offset",
+			manifest: "#EXTM3U\n" +
+				"#EXT-X-START:TIME-OFFSET=-12.5,PRECISE=YES\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720\n" +
+				"720p.m3u8\n",
+			expected: &StartInfo{Source: "EXT-X-START", Offset: -12.5, Precise: true},
+		},
+		{
+			name: "positive offset",
+			manifest: "#EXTM3U\n" +
+				"#EXT-X-START:TIME-OFFSET=30\n",
+			expected: &StartInfo{Source: "EXT-X-START", Offset: 30},
+		},
+		{
+			name: "missing offset",
+			manifest: "#EXTM3U\n" +
+				"#EXT-X-START:PRECISE=YES\n",
+			expected: nil,
+		},
+		{
+			name:     "no start tag",
+			manifest: "#EXTM3U\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseHLSManifest(tt.manifest, "https://example.com/master.m3u8")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tt.expected == nil {
+				if output.Start != nil {
+					t.Errorf("Expected no start info, got %+v", output.Start)
+				}
+				return
+			}
+
+			if output.Start == nil {
+				t.Fatal("Expected start info but got none")
+			}
+
+			if *output.Start != *tt.expected {
+				t.Errorf("Expected %+v, got %+v", *tt.expected, *output.Start)
+			}
+		})
+	}
+}
+
+func TestParseHLSAttributes(t *testing.T) {
+	line := `#EXT-X-STREAM-INF:BANDWIDTH=2000000,AVERAGE-BANDWIDTH=1500000,CODECS="avc1.64001f,mp4a.40.2",RESOLUTION=1280x720,VIDEO="cam"`
+	attrs := parseHLSAttributes(line)
+
+	expected := map[string]string{
+		"BANDWIDTH":         "2000000",
+		"AVERAGE-BANDWIDTH": "1500000",
+		"CODECS":            "avc1.64001f,mp4a.40.2",
+		"RESOLUTION":        "1280x720",
+		"VIDEO":             "cam",
+	}
+
+	if len(attrs) != len(expected) {
+		t.Errorf("Expected %d attributes, got %d: %v", len(expected), len(attrs), attrs)
+	}
+
+	for name, value := range expected {
+		if attrs[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, attrs[name])
+		}
+	}
+}
+
+func TestParseHLSVideoAngles(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="cam",NAME="Main Camera",DEFAULT=YES
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="cam",NAME="Goal, Left",URI="left/index.m3u8"
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="unused",NAME="Orphan",URI="orphan/index.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080,FRAME-RATE=50,VIDEO="cam"
+main/index.m3u8
+`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var angles []StreamInfo
+	for _, stream := range output.Streams {
+		if stream.Type == "Video" && stream.Name != "" {
+			angles = append(angles, stream)
+		}
+	}
+
+	// The URI-less rendition is the variant's own video, not another angle
+	if len(angles) != 1 {
+		t.Fatalf("Expected 1 angle stream, got %d: %+v", len(angles), output.Streams)
+	}
+
+	expectedNames := []string{"Goal, Left"}
+	for i, angle := range angles {
+		if angle.Name != expectedNames[i] {
+			t.Errorf("Expected angle name %q, got %q", expectedNames[i], angle.Name)
+		}
+		if angle.GroupID != "cam" {
+			t.Errorf("Expected group %q, got %q", "cam", angle.GroupID)
+		}
+		if angle.Resolution != "1920x1080" || angle.FrameRate != "50" {
+			t.Errorf("Expected angle to inherit variant format, got %s @ %s", angle.Resolution, angle.FrameRate)
+		}
+	}
+
+	if output.Streams[0].GroupID != "cam" {
+		t.Errorf("Expected variant video stream to reference group %q, got %q", "cam", output.Streams[0].GroupID)
+	}
+}
+
+func TestParseHLSSubtitleRenditions(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",LANGUAGE="en",NAME="English",URI="subs/en.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",LANGUAGE="fr",NAME="Français (SDH)",CHARACTERISTICS="public.accessibility.transcribes-spoken-dialog",URI="subs/fr.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="imsc",LANGUAGE="de",NAME="Deutsch",URI="imsc/de.m3u8"
+#EXT-X-MEDIA:TYPE=CLOSED-CAPTIONS,GROUP-ID="cc",LANGUAGE="en",NAME="English CC",INSTREAM-ID="CC1"
+#EXT-X-MEDIA:TYPE=CLOSED-CAPTIONS,GROUP-ID="cc",LANGUAGE="es",NAME="Español",INSTREAM-ID="SERVICE2"
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080,SUBTITLES="subs",CLOSED-CAPTIONS="cc"
+main/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,CODECS="avc1.640028,mp4a.40.2,stpp.ttml.im1t",RESOLUTION=1920x1080,SUBTITLES="imsc"
+imsc/index.m3u8
+`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var subtitles []StreamInfo
+	for _, stream := range output.Streams {
+		if stream.Type == "Subtitle" {
+			subtitles = append(subtitles, stream)
+		}
+	}
+
+	tests := []struct {
+		codec    string
+		language string
+		name     string
+		groupID  string
+		url      string
+		line     int
+	}{
+		{"webvtt", "en", "English", "subs", "https://example.com/subs/en.m3u8", 2},
+		{"webvtt", "fr", "Français (SDH)", "subs", "https://example.com/subs/fr.m3u8", 3},
+		{"stpp", "de", "Deutsch", "imsc", "https://example.com/imsc/de.m3u8", 4},
+		{"cea-608", "en", "English CC", "cc", "", 5},
+		{"cea-708", "es", "Español", "cc", "", 6},
+	}
+	if len(subtitles) != len(tests) {
+		t.Fatalf("Expected %d subtitle streams, got %d: %+v", len(tests), len(subtitles), output.Streams)
+	}
+
+	for i, tt := range tests {
+		stream := subtitles[i]
+		if stream.Codec != tt.codec || stream.Language != tt.language || stream.Name != tt.name || stream.GroupID != tt.groupID {
+			t.Errorf("Expected %s %s %q in group %q, got %s %s %q in group %q",
+				tt.codec, tt.language, tt.name, tt.groupID, stream.Codec, stream.Language, stream.Name, stream.GroupID)
+		}
+		var url string
+		if len(stream.urls) > 0 {
+			url = stream.urls[0]
+		}
+		if url != tt.url {
+			t.Errorf("Expected URL %q, got %q", tt.url, url)
+		}
+		if stream.Source == nil || stream.Source.Tag != "EXT-X-MEDIA" || stream.Source.Line != tt.line {
+			t.Errorf("Expected EXT-X-MEDIA source on line %d, got %+v", tt.line, stream.Source)
+		}
+	}
+
+	if !subtitles[1].Disposition.has("hearing_impaired") {
+		t.Errorf("Expected SDH subtitles to be hearing impaired, got %+v", subtitles[1].Disposition)
+	}
+}
+
+func TestParseHLSIFrameStreams(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080
+main/index.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=300000,CODECS="avc1.640028",RESOLUTION=1920x1080,URI="main/iframes.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",LANGUAGE="en",NAME="English",URI="subs/en.m3u8"
+`
+	server := manifestServer(manifest)
+	defer server.Close()
+
+	tests := []struct {
+		include  bool
+		expected []string
+	}{
+		{false, []string{"0:0 Video", "0:1 Audio", "0:2 Subtitle"}},
+		{true, []string{"0:0 Video", "0:1 Audio", "0:2 Subtitle", "0:3 " + IFrameStreamType}},
+	}
+
+	for _, tt := range tests {
+		output, err := NewProber(&ProbeOptions{IFrameStreams: tt.include}).Probe(context.Background(), server.URL+"/master.m3u8")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var streams []string
+		for _, stream := range output.Streams {
+			streams = append(streams, stream.StreamID+" "+stream.Type)
+		}
+		if !reflect.DeepEqual(streams, tt.expected) {
+			t.Errorf("Expected %q, got %q", tt.expected, streams)
+		}
+		if !containsString(output.Features, "i-frames") {
+			t.Errorf("Expected the i-frames feature, got %v", output.Features)
+		}
+	}
+
+	output, err := parseHLSManifest(manifest, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	iframe := output.iframes[0]
+	if iframe.Resolution != "1920x1080" || iframe.BitRate != "300 kb/s" || iframe.FrameRate != "" {
+		t.Errorf("Expected 1920x1080 at 300 kb/s without frame rate, got %s at %s (%q)", iframe.Resolution, iframe.BitRate, iframe.FrameRate)
+	}
+	if len(iframe.urls) != 1 || iframe.urls[0] != "https://example.com/main/iframes.m3u8" {
+		t.Errorf("Expected %q, got %q", "https://example.com/main/iframes.m3u8", iframe.urls)
+	}
+	if iframe.Source == nil || iframe.Source.Tag != "EXT-X-I-FRAME-STREAM-INF" || iframe.Source.Line != 4 {
+		t.Errorf("Expected EXT-X-I-FRAME-STREAM-INF source on line 4, got %+v", iframe.Source)
+	}
+}
+
+func TestParseHLSAudioGroups(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac-lo",LANGUAGE="en",NAME="English",DEFAULT=YES,URI="audio/en.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac-lo",LANGUAGE="fr",NAME="Francais",CHARACTERISTICS="public.accessibility.describes-video",URI="audio/fr.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac-hi",LANGUAGE="en",NAME="English",DEFAULT=YES,URI="audio/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=640x360,AUDIO="aac-lo"
+low/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1280x720,AUDIO="aac-lo"
+mid/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=6000000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080,AUDIO="aac-hi"
+high/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=8000000,CODECS="avc1.640028,ec-3",RESOLUTION=1920x1080
+muxed/index.m3u8
+`
+	server := manifestServer(manifest)
+	defer server.Close()
+
+	tests := []struct {
+		grouped  bool
+		expected []string
+		variants [][]string
+	}{
+		{
+			false,
+			[]string{"0:0 Video", "0:1 Audio", "0:2 Video", "0:3 Audio", "0:4 Video", "0:5 Audio", "0:6 Video", "0:7 Audio"},
+			[][]string{{"0:0", "0:1"}, {"0:2", "0:3"}, {"0:4", "0:5"}, {"0:6", "0:7"}},
+		},
+		{
+			true,
+			[]string{"0:0 Video", "0:2 Video", "0:4 Video", "0:6 Video", "0:7 Audio", "0:8 Audio", "0:9 Audio"},
+			[][]string{{"0:0", "0:8", "0:9"}, {"0:2", "0:8", "0:9"}, {"0:4", "0:8"}, {"0:6", "0:7"}},
+		},
+	}
+
+	for _, tt := range tests {
+		output, err := NewProber(&ProbeOptions{AudioGroups: tt.grouped}).Probe(context.Background(), server.URL+"/master.m3u8")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var streams []string
+		for _, stream := range output.Streams {
+			streams = append(streams, stream.StreamID+" "+stream.Type)
+		}
+		if !reflect.DeepEqual(streams, tt.expected) {
+			t.Errorf("Expected %q, got %q", tt.expected, streams)
+		}
+		var variants [][]string
+		for _, variant := range output.Variants {
+			variants = append(variants, variant.StreamIDs)
+		}
+		if !reflect.DeepEqual(variants, tt.variants) {
+			t.Errorf("Expected variant streams %q, got %q", tt.variants, variants)
+		}
+	}
+
+	output, err := parseHLSManifest(manifest, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output.audioGroups, map[string][]string{"aac-lo": {"0:8", "0:9"}, "aac-hi": {"0:8"}}) {
+		t.Errorf("Expected the audio group streams, got %v", output.audioGroups)
+	}
+
+	renditions := []struct {
+		language string
+		groupID  string
+		url      string
+		line     int
+		variants []int
+	}{
+		{"en", "aac-lo", "https://example.com/audio/en.m3u8", 2, []int{0, 1, 2}},
+		{"fr", "aac-lo", "https://example.com/audio/fr.m3u8", 3, []int{0, 1}},
+	}
+	if len(output.audioRenditions) != len(renditions) {
+		t.Fatalf("Expected %d audio renditions, got %d", len(renditions), len(output.audioRenditions))
+	}
+	for i, tt := range renditions {
+		stream := output.audioRenditions[i]
+		if stream.Language != tt.language || stream.GroupID != tt.groupID || stream.Codec != "aac" {
+			t.Errorf("Expected %s aac in %s, got %s %s in %s", tt.language, tt.groupID, stream.Language, stream.Codec, stream.GroupID)
+		}
+		if len(stream.urls) != 1 || stream.urls[0] != tt.url {
+			t.Errorf("Expected %q, got %q", tt.url, stream.urls)
+		}
+		if stream.Source == nil || stream.Source.Tag != "EXT-X-MEDIA" || stream.Source.Line != tt.line {
+			t.Errorf("Expected EXT-X-MEDIA source on line %d, got %+v", tt.line, stream.Source)
+		}
+		if !reflect.DeepEqual(stream.VariantIndexes, tt.variants) {
+			t.Errorf("Expected variants %v, got %v", tt.variants, stream.VariantIndexes)
+		}
+	}
+	if !output.audioRenditions[1].Disposition.has("visual_impaired") {
+		t.Errorf("Expected described video audio to be visual impaired, got %+v", output.audioRenditions[1].Disposition)
+	}
+}
+
+func TestParseHLSInterstitials(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T10:00:00.000Z
+#EXT-X-DATERANGE:ID="ad1",CLASS="com.apple.hls.interstitial",START-DATE="2024-05-01T10:00:00.000Z",DURATION=15.0,X-ASSET-URI="ads/preroll.m3u8",CUE="PRE"
+#EXT-X-DATERANGE:ID="chapter",CLASS="com.example.chapter",START-DATE="2024-05-01T10:00:06.000Z"
+#EXTINF:6.0,
+seg0.ts
+#EXT-X-DATERANGE:ID="ad2",CLASS="com.apple.hls.interstitial",START-DATE="2024-05-01T10:00:06.000Z",X-ASSET-LIST="https://ads.example.com/list.json"
+#EXTINF:6.0,
+seg1.ts
+`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/live/media.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Interstitial{
+		{
+			ID:        "ad1",
+			StartDate: "2024-05-01T10:00:00.000Z",
+			Duration:  15,
+			Cue:       "PRE",
+			AssetURI:  "https://example.com/live/ads/preroll.m3u8",
+		},
+		{
+			ID:        "ad2",
+			StartDate: "2024-05-01T10:00:06.000Z",
+			AssetList: "https://ads.example.com/list.json",
+		},
+	}
+
+	if len(output.Interstitials) != len(expected) {
+		t.Fatalf("Expected %d interstitials, got %d", len(expected), len(output.Interstitials))
+	}
+
+	for i, interstitial := range output.Interstitials {
+		if interstitial != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], interstitial)
+		}
+	}
+}
+
+func TestParseHLSSteeringAttributes(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-CONTENT-STEERING:SERVER-URI="https://steering.example.com/manifest.json"
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="angles",NAME="Main",URI="main.m3u8",STABLE-RENDITION-ID="main-cam"
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="angles",NAME="Wide",URI="wide.m3u8",STABLE-RENDITION-ID="wide cam"
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,AVERAGE-BANDWIDTH=4500000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",SCORE=2.0,STABLE-VARIANT-ID="hd",PATHWAY-ID="CDN-A",VIDEO="angles"
+https://cdn-a.example.com/1080p.m3u8?token=abc
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",SCORE=2.0,STABLE-VARIANT-ID="hd",PATHWAY-ID="CDN-B"
+https://cdn-b.example.com/1080p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=640x360,CODECS="avc1.64001e,mp4a.40.2",STABLE-VARIANT-ID="hd",PATHWAY-ID="CDN-B"
+https://cdn-b.example.com/360p.m3u8
+`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(output.Variants) != 3 {
+		t.Fatalf("Expected 3 variants, got %d", len(output.Variants))
+	}
+
+	first := output.Variants[0]
+	if first.URI != "https://cdn-a.example.com/1080p.m3u8?token=abc" {
+		t.Errorf("Expected %q, got %q", "https://cdn-a.example.com/1080p.m3u8?token=abc", first.URI)
+	}
+	if first.Score == nil || *first.Score != 2.0 {
+		t.Errorf("Expected score 2.0, got %v", first.Score)
+	}
+	if first.StableVariantID != "hd" || first.PathwayID != "CDN-A" {
+		t.Errorf("Expected stable variant ID %q on pathway %q, got %q on %q", "hd", "CDN-A", first.StableVariantID, first.PathwayID)
+	}
+	if first.AverageBandwidth != 4500000 {
+		t.Errorf("Expected average bandwidth 4500000, got %d", first.AverageBandwidth)
+	}
+	if len(first.StreamIDs) != 2 || first.StreamIDs[0] != "0:0" || first.StreamIDs[1] != "0:1" {
+		t.Errorf("Expected stream IDs [0:0 0:1], got %v", first.StreamIDs)
+	}
+	if output.Variants[2].Score != nil {
+		t.Errorf("Expected no score, got %v", *output.Variants[2].Score)
+	}
+
+	var stableRenditionIDs []string
+	for _, stream := range output.Streams {
+		if stream.StableRenditionID != "" {
+			stableRenditionIDs = append(stableRenditionIDs, stream.StableRenditionID)
+		}
+	}
+	if len(stableRenditionIDs) != 2 || stableRenditionIDs[0] != "main-cam" {
+		t.Errorf("Expected stable rendition IDs on angle streams, got %v", stableRenditionIDs)
+	}
+
+	expectedWarnings := []string{
+		`STABLE-VARIANT-ID "hd" is used by more than one variant of pathway "CDN-B"`,
+		`STABLE-RENDITION-ID "wide cam" of rendition "Wide" contains invalid characters`,
+	}
+	if len(output.Warnings) != len(expectedWarnings) {
+		t.Fatalf("Expected %d warnings, got %v", len(expectedWarnings), output.Warnings)
+	}
+	for i, warning := range output.Warnings {
+		if warning != expectedWarnings[i] {
+			t.Errorf("Expected %q, got %q", expectedWarnings[i], warning)
+		}
+	}
+}
+
+func TestParseHLSSourceRefs(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="angles",NAME="Wide",URI="wide.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",VIDEO="angles",AUDIO="aac"
+720p.m3u8`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []SourceRef{
+		{Tag: "EXT-X-STREAM-INF", Line: 3, GroupID: "angles", URI: "720p.m3u8"},
+		{Tag: "EXT-X-STREAM-INF", Line: 3, GroupID: "aac", URI: "720p.m3u8"},
+		{Tag: "EXT-X-MEDIA", Line: 2, GroupID: "angles", URI: "wide.m3u8"},
+	}
+	if len(output.Streams) != len(expected) {
+		t.Fatalf("Expected %d streams, got %d", len(expected), len(output.Streams))
+	}
+	for i, stream := range output.Streams {
+		if stream.Source == nil || *stream.Source != expected[i] {
+			t.Errorf("Expected source %+v, got %+v", expected[i], stream.Source)
+		}
+	}
+}
+
+func TestParseHLSNativeIDs(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="cam",NAME="Main",DEFAULT=YES
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="cam",NAME="Wide",URI="wide.m3u8",STABLE-RENDITION-ID="wide-cam"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",LANGUAGE="en",URI="subs/en.m3u8?token=abc"
+#EXT-X-MEDIA:TYPE=CLOSED-CAPTIONS,GROUP-ID="cc",NAME="English CC",INSTREAM-ID="CC1"
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",VIDEO="cam",STABLE-VARIANT-ID="hd"
+1080p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2"
+720p/index.m3u8
+`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var ids []string
+	for _, stream := range output.Streams {
+		ids = append(ids, stream.Type+" "+stream.NativeID)
+	}
+	expected := []string{
+		"Video hd",
+		"Audio hd",
+		"Video 720p/index.m3u8",
+		"Audio 720p/index.m3u8",
+		"Video wide-cam",
+		"Subtitle subs/en.m3u8?token=abc",
+		"Subtitle CC1",
+	}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %q, got %q", expected, ids)
+	}
+	if output.Streams[0].Source.StableVariantID != "hd" {
+		t.Errorf("Expected STABLE-VARIANT-ID %q in the source, got %+v", "hd", output.Streams[0].Source)
+	}
+}
+
+func TestParseHLSCodecs(t *testing.T) {
+	tests := []struct {
+		codecs        string
+		expectedVideo string
+		expectedAudio string
+	}{
+		{"avc1.64001f,mp4a.40.2", "h264", "aac"},
+		{"avc3.640028,mp4a.40.5", "h264", "aac"},
+		{"hvc1.2.4.L123.B0,mp4a.40.2", "hevc", "aac"},
+		{"hev1.1.6.L93.B0,ec-3", "hevc", "eac3"},
+		{"av01.0.08M.10,opus", "av1", "opus"},
+		{"vp09.02.10.10,fLaC", "vp9", "flac"},
+		{"dvh1.05.06,ec-3", "hevc", "eac3"},
+		{"dvhe.08.07,ac-3", "hevc", "ac3"},
+		{"dvav.09.05,mp4a.40.2", "h264", "aac"},
+		{"avc1.64001f,mp4a.40.34", "h264", "mp3"},
+		{"avc1.64001f,mp4a.6B", "h264", "mp3"},
+		{"avc1.64001f,mp4a.a6", "h264", "eac3"},
+		{"avc1.64001f, Opus", "h264", "opus"},
+		{"ac-4.02.01.01", "h264", "ac4"},
+		{"ec-3,mp4a.40.2", "h264", "eac3"}, // first audio codec wins
+		{"", "h264", "aac"},
+	}
+
+	for _, tt := range tests {
+		videoCodec, audioCodec := parseHLSCodecs(tt.codecs)
+		if videoCodec != tt.expectedVideo || audioCodec != tt.expectedAudio {
+			t.Errorf("parseHLSCodecs(%q): expected %q %q, got %q %q", tt.codecs, tt.expectedVideo, tt.expectedAudio, videoCodec, audioCodec)
+		}
+	}
+}
+
+func TestParseHLSDolbyVisionVariant(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=12000000,RESOLUTION=3840x2160,CODECS="dvh1.05.06,ec-3"
+dv/2160p.m3u8
+`
+	output, err := parseHLSManifest(manifest, "https://cdn.example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	video, audio := output.Streams[0], output.Streams[1]
+	if video.Codec != "hevc" || video.PixFmt != "yuv420p10le" {
+		t.Errorf("Expected %q %q, got %q %q", "hevc", "yuv420p10le", video.Codec, video.PixFmt)
+	}
+	if audio.Codec != "eac3" {
+		t.Errorf("Expected %q, got %q", "eac3", audio.Codec)
+	}
+}
+
+func TestParseHLSVariantLadderAttributes(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=8000000,AVERAGE-BANDWIDTH=6000000,RESOLUTION=3840x2160,CODECS="hvc1.2.4.L150.B0,mp4a.40.2",HDCP-LEVEL=TYPE-1,STABLE-VARIANT-ID="uhd",SCORE=2.5
+2160p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2",HDCP-LEVEL=TYPE-2
+720p.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=300000,AVERAGE-BANDWIDTH=250000,RESOLUTION=1280x720,CODECS="avc1.4d401f",HDCP-LEVEL=NONE,URI="720p-iframes.m3u8"
+`
+	output, err := parseHLSManifest(manifest, "https://cdn.example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, stream := range output.Streams[:2] {
+		if stream.AverageBandwidth != 6000000 || stream.HDCPLevel != "TYPE-1" || stream.StableVariantID != "uhd" {
+			t.Errorf("Unexpected attributes of stream %s: %+v", stream.StreamID, stream)
+		}
+		if stream.Score == nil || *stream.Score != 2.5 {
+			t.Errorf("Expected score 2.5 for stream %s, got %v", stream.StreamID, stream.Score)
+		}
+	}
+	if output.Streams[0].Score == output.Streams[1].Score {
+		t.Errorf("Expected the streams of a variant not to share a score")
+	}
+
+	hd := output.Streams[2]
+	if hd.AverageBandwidth != 0 || hd.Score != nil || hd.StableVariantID != "" || hd.HDCPLevel != "TYPE-2" {
+		t.Errorf("Unexpected attributes of stream %s: %+v", hd.StreamID, hd)
+	}
+	if output.Variants[0].HDCPLevel != "TYPE-1" {
+		t.Errorf("Expected %q, got %q", "TYPE-1", output.Variants[0].HDCPLevel)
+	}
+
+	expected := `HDCP-LEVEL "TYPE-2" of https://cdn.example.com/720p.m3u8 is not TYPE-0, TYPE-1 or NONE`
+	if len(output.Warnings) != 1 || output.Warnings[0] != expected {
+		t.Errorf("Expected [%s], got %v", expected, output.Warnings)
+	}
+
+	if len(output.iframes) != 1 || output.iframes[0].AverageBandwidth != 250000 || output.iframes[0].HDCPLevel != "NONE" {
+		t.Errorf("Unexpected I-frame stream %+v", output.iframes)
+	}
+}
+
+func TestParseHLSAudioChannels(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=6000000,RESOLUTION=1920x1080,CODECS="avc1.640028,ec-3",AUDIO="atmos"
+1080p-atmos.m3u8
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="atmos",NAME="English",LANGUAGE="en",CHANNELS="16/JOC",URI="audio/atmos.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="surround",NAME="English",LANGUAGE="en",CHANNELS="6",URI="audio/51.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="surround",NAME="French",LANGUAGE="fr",CHANNELS="8",URI="audio/71.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="many",NAME="English",CHANNELS="12",URI="audio/12.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",URI="audio/aac.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,ac-3",AUDIO="surround"
+1080p-51.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2",AUDIO="many"
+1080p-12.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2",AUDIO="aac"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=640x360,CODECS="avc1.4d401f,mp4a.40.2"
+360p.m3u8
+`
+	output, err := parseHLSManifest(manifest, "https://cdn.example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"Atmos/JOC", "5.1", "12 channels", "stereo", "stereo"}
+	var channels []string
+	for _, stream := range output.Streams {
+		if stream.Type == "Audio" {
+			channels = append(channels, stream.Channels)
+		}
+	}
+	if !reflect.DeepEqual(channels, expected) {
+		t.Errorf("Expected %q, got %q", expected, channels)
+	}
+}
+
+func TestHLSChannelLayout(t *testing.T) {
+	tests := []struct {
+		channels string
+		expected string
+	}{
+		{channels: "1", expected: "mono"},
+		{channels: "2", expected: "stereo"},
+		{channels: "6", expected: "5.1"},
+		{channels: "8", expected: "7.1"},
+		{channels: "16/JOC", expected: "Atmos/JOC"},
+		{channels: "2/-/BINAURAL", expected: "stereo"},
+		{channels: "24", expected: "24 channels"},
+		{channels: "", expected: ""},
+		{channels: "six", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channels, func(t *testing.T) {
+			if got := hlsChannelLayout(tt.channels); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseHLSAudioGroupChannels(t *testing.T) {
+	server := manifestServer(`#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",LANGUAGE="en",NAME="Stereo",CHANNELS="2",URI="audio/stereo.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",LANGUAGE="en",NAME="Atmos",CHANNELS="16/JOC",URI="audio/atmos.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=6000000,CODECS="avc1.640028,ec-3",RESOLUTION=1920x1080,AUDIO="aud"
+high/index.m3u8
+`)
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{AudioGroups: true}).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var channels []string
+	for _, stream := range output.Streams {
+		if stream.Type == "Audio" {
+			channels = append(channels, stream.Channels)
+		}
+	}
+	expected := []string{"stereo", "Atmos/JOC"}
+	if !reflect.DeepEqual(channels, expected) {
+		t.Errorf("Expected %q, got %q", expected, channels)
+	}
+}
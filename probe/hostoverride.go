@@ -0,0 +1,115 @@
+package probe
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// HostOverride replaces the request options of ProbeOptions for the hosts
+// matching its pattern in ProbeOptions.PerHostOverrides. Unset fields inherit
+// the prober's options.
+type HostOverride struct {
+	// CustomHeaders are added to ProbeOptions.CustomHeaders, replacing headers of the same name
+	CustomHeaders map[string]string
+
+	// ProxyURL replaces ProbeOptions.ProxyURL ("" = inherit)
+	ProxyURL string
+
+	// UserAgent replaces ProbeOptions.UserAgent ("" = inherit)
+	UserAgent string
+
+	// TimeoutSeconds replaces ProbeOptions.TimeoutSeconds (0 = inherit)
+	TimeoutSeconds int
+
+	// DisableCamouflage disables browser-like headers for the host
+	DisableCamouflage bool
+
+	// Camouflage replaces ProbeOptions.Camouflage (nil = inherit)
+	Camouflage *CamouflagePolicy
+}
+
+// hostOverride returns the override of PerHostOverrides matching host (nil if
+// none). Patterns are a host name ("cdn.example.com") or a wildcard matching
+// its subdomains ("*.example.com"); an exact name wins over wildcards, and
+// the longest wildcard wins over shorter ones.
+func hostOverride(overrides map[string]*HostOverride, host string) *HostOverride {
+	host = strings.ToLower(host)
+	if override, ok := overrides[host]; ok {
+		return override
+	}
+
+	var match *HostOverride
+	matchLen := 0
+	for pattern, override := range overrides {
+		suffix, ok := strings.CutPrefix(strings.ToLower(pattern), "*")
+		if !ok || !strings.HasPrefix(suffix, ".") || !strings.HasSuffix(host, suffix) {
+			continue
+		}
+		if len(suffix) > matchLen {
+			match, matchLen = override, len(suffix)
+		}
+	}
+	return match
+}
+
+// hostOptions returns opts with the override matching host applied (opts
+// itself if none matches)
+func hostOptions(opts *ProbeOptions, host string) *ProbeOptions {
+	if opts == nil || len(opts.PerHostOverrides) == 0 {
+		return opts
+	}
+	override := hostOverride(opts.PerHostOverrides, host)
+	if override == nil {
+		return opts
+	}
+
+	merged := *opts
+	if len(override.CustomHeaders) > 0 {
+		merged.CustomHeaders = make(map[string]string, len(opts.CustomHeaders)+len(override.CustomHeaders))
+		for name, value := range opts.CustomHeaders {
+			merged.CustomHeaders[name] = value
+		}
+		for name, value := range override.CustomHeaders {
+			merged.CustomHeaders[name] = value
+		}
+	}
+	if override.ProxyURL != "" {
+		merged.ProxyURL = override.ProxyURL
+	}
+	if override.UserAgent != "" {
+		merged.UserAgent = override.UserAgent
+	}
+	if override.TimeoutSeconds > 0 {
+		merged.TimeoutSeconds = override.TimeoutSeconds
+	}
+	if override.DisableCamouflage {
+		merged.DisableCamouflage = true
+	}
+	if override.Camouflage != nil {
+		merged.Camouflage = override.Camouflage
+	}
+	return &merged
+}
+
+// validateHostOverrides validates the patterns and options of PerHostOverrides
+func validateHostOverrides(overrides map[string]*HostOverride) error {
+	for pattern, override := range overrides {
+		name := strings.TrimPrefix(pattern, "*.")
+		if name == "" || strings.ContainsAny(name, "*/:") {
+			return NewValidationError(fmt.Sprintf("invalid host override pattern %q", pattern))
+		}
+		if override == nil {
+			return NewValidationError(fmt.Sprintf("host override %q is nil", pattern))
+		}
+		if override.ProxyURL != "" {
+			if _, err := url.Parse(override.ProxyURL); err != nil {
+				return NewValidationError(fmt.Sprintf("invalid proxy URL for host %q: %v", pattern, err))
+			}
+		}
+		if override.TimeoutSeconds < 0 || override.TimeoutSeconds > 300 {
+			return NewValidationError(fmt.Sprintf("timeout for host %q must be between 0 and 300 seconds", pattern))
+		}
+	}
+	return nil
+}
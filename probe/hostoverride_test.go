@@ -0,0 +1,154 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHostOverrideMatching(t *testing.T) {
+	overrides := map[string]*HostOverride{
+		"cdn.example.com":     {UserAgent: "exact"},
+		"*.example.com":       {UserAgent: "domain"},
+		"*.video.example.com": {UserAgent: "subdomain"},
+	}
+
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"cdn.example.com", "exact"},
+		{"CDN.Example.com", "exact"},
+		{"origin.example.com", "domain"},
+		{"edge.video.example.com", "subdomain"},
+		{"example.com", ""},
+		{"notexample.com", ""},
+	}
+
+	for _, tt := range tests {
+		var result string
+		if override := hostOverride(overrides, tt.host); override != nil {
+			result = override.UserAgent
+		}
+		if result != tt.expected {
+			t.Errorf("Expected %q for %s, got %q", tt.expected, tt.host, result)
+		}
+	}
+}
+
+func TestHostOptions(t *testing.T) {
+	opts := &ProbeOptions{
+		UserAgent:      "default",
+		TimeoutSeconds: 30,
+		CustomHeaders:  map[string]string{"X-Team": "video", "Authorization": "Bearer default"},
+		PerHostOverrides: map[string]*HostOverride{
+			"*.partner.com": {
+				CustomHeaders:     map[string]string{"Authorization": "Bearer partner"},
+				ProxyURL:          "http://proxy.partner.com:8080",
+				TimeoutSeconds:    5,
+				DisableCamouflage: true,
+			},
+		},
+	}
+
+	if merged := hostOptions(opts, "cdn.example.com"); merged != opts {
+		t.Errorf("Expected the options of an unmatched host to be unchanged")
+	}
+
+	merged := hostOptions(opts, "cdn.partner.com")
+	if merged.UserAgent != "default" || merged.TimeoutSeconds != 5 || merged.ProxyURL != "http://proxy.partner.com:8080" || !merged.DisableCamouflage {
+		t.Errorf("Expected the override to replace proxy, timeout and camouflage, got %+v", merged)
+	}
+	if merged.CustomHeaders["X-Team"] != "video" || merged.CustomHeaders["Authorization"] != "Bearer partner" {
+		t.Errorf("Expected merged headers, got %v", merged.CustomHeaders)
+	}
+	if opts.CustomHeaders["Authorization"] != "Bearer default" {
+		t.Errorf("Expected the prober's headers to be unchanged, got %v", opts.CustomHeaders)
+	}
+
+	if redacted := newRedactor(opts).redactString("auth failed: Bearer partner"); strings.Contains(redacted, "Bearer partner") {
+		t.Errorf("Expected the override's Authorization header to be redacted, got %q", redacted)
+	}
+}
+
+func TestPerHostOverrides(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]*http.Request)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[strings.Split(r.Host, ":")[0]] = r
+		mu.Unlock()
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{
+		UserAgent:     "default-agent",
+		CustomHeaders: map[string]string{"X-Team": "video"},
+		PerHostOverrides: map[string]*HostOverride{
+			"localhost": {
+				UserAgent:         "partner-agent",
+				CustomHeaders:     map[string]string{"X-Partner-Key": "secret"},
+				DisableCamouflage: true,
+			},
+		},
+	})
+
+	for _, manifestURL := range []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)} {
+		if _, err := prober.Probe(context.Background(), manifestURL+"/manifest.mpd"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		host      string
+		userAgent string
+		partner   string
+		origin    bool
+	}{
+		{"127.0.0.1", "default-agent", "", true},
+		{"localhost", "partner-agent", "secret", false},
+	}
+
+	for _, tt := range tests {
+		r := received[tt.host]
+		if r == nil {
+			t.Fatalf("Expected a request to %s", tt.host)
+		}
+		if r.UserAgent() != tt.userAgent {
+			t.Errorf("Expected %q, got %q", tt.userAgent, r.UserAgent())
+		}
+		if r.Header.Get("X-Team") != "video" || r.Header.Get("X-Partner-Key") != tt.partner {
+			t.Errorf("Expected X-Team %q and X-Partner-Key %q, got %v", "video", tt.partner, r.Header)
+		}
+		if origin := r.Header.Get("Origin") != ""; origin != tt.origin {
+			t.Errorf("Expected Origin header %v for %s, got %v", tt.origin, tt.host, origin)
+		}
+	}
+}
+
+func TestValidateHostOverrides(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		override *HostOverride
+		valid    bool
+	}{
+		{"cdn.example.com", &HostOverride{TimeoutSeconds: 10}, true},
+		{"*.example.com", &HostOverride{}, true},
+		{"cdn.example.com:8080", &HostOverride{}, false},
+		{"*", &HostOverride{}, false},
+		{"cdn.*.com", &HostOverride{}, false},
+		{"cdn.example.com", nil, false},
+		{"cdn.example.com", &HostOverride{TimeoutSeconds: 301}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateProbeOptions(&ProbeOptions{PerHostOverrides: map[string]*HostOverride{tt.pattern: tt.override}})
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected valid=%v for %q, got %v", tt.valid, tt.pattern, err)
+		}
+	}
+}
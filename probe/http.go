@@ -1,19 +1,63 @@
 package probe
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/imroc/req/v3"
 )
 
+// defaultAcceptHeader is the browser-like Accept header sent with camouflage
+const defaultAcceptHeader = "application/dash+xml,application/vnd.ms-sstr+xml,application/vnd.apple.mpegurl,application/x-mpegURL,application/vnd.ms-playready.media.pya,application/vnd.ms-playready.media.pyv,video/mp4,audio/mp4,*/*"
+
+// Accept headers used by format negotiation
+const (
+	dashAcceptHeader = "application/dash+xml"
+	hlsAcceptHeader  = "application/vnd.apple.mpegurl,application/x-mpegURL"
+)
+
 // HTTPClient wraps the req client with manifest-specific configuration
 type HTTPClient struct {
 	client         *req.Client
 	retryExecutor  *RetryExecutor
+	maxBodyBytes   int64
+	acceptHeader   string
+	negotiate      bool
+	sniffBytes     int64
+	shaper         *bandwidthShaper
+}
+
+// withClock returns the retry and circuit breaker configs of opts with the
+// prober-wide Clock and Rand filled in where they do not set their own
+func withClock(opts *ProbeOptions) (*RetryConfig, *CircuitBreakerConfig) {
+	retryConfig, cbConfig := opts.RetryConfig, opts.CircuitBreakerConfig
+
+	if retryConfig.Clock == nil && opts.Clock != nil || retryConfig.Rand == nil && opts.Rand != nil {
+		copied := *retryConfig
+		if copied.Clock == nil {
+			copied.Clock = opts.Clock
+		}
+		if copied.Rand == nil {
+			copied.Rand = opts.Rand
+		}
+		retryConfig = &copied
+	}
+
+	if cbConfig != nil && cbConfig.Clock == nil && opts.Clock != nil {
+		copied := *cbConfig
+		copied.Clock = opts.Clock
+		cbConfig = &copied
+	}
+
+	return retryConfig, cbConfig
 }
 
 // NewHTTPClient creates a new HTTP client configured for manifest fetching
@@ -28,12 +72,17 @@ func NewHTTPClient(targetURL string, opts *ProbeOptions) (*HTTPClient, error) {
 	// Setup retry executor if retry config is provided
 	var retryExecutor *RetryExecutor
 	if opts != nil && opts.RetryConfig != nil {
-		retryExecutor = NewRetryExecutor(opts.RetryConfig, opts.CircuitBreakerConfig)
+		retryConfig, cbConfig := withClock(opts)
+		retryExecutor = NewRetryExecutor(retryConfig, cbConfig)
 	}
 	
 	return &HTTPClient{
 		client:        client,
 		retryExecutor: retryExecutor,
+		maxBodyBytes:  maxBodyBytes(opts),
+		acceptHeader:  acceptHeader(opts),
+		negotiate:     opts != nil && opts.NegotiateFormat,
+		sniffBytes:    sniffBytes(opts),
 	}, nil
 }
 
@@ -44,55 +93,172 @@ func (h *HTTPClient) FetchManifest(manifestURL string) (string, error) {
 
 // FetchManifestWithContext fetches the manifest content with context support
 func (h *HTTPClient) FetchManifestWithContext(ctx context.Context, manifestURL string) (string, error) {
-	var result string
+	result, err := h.fetch(ctx, manifestURL)
+	if err != nil {
+		return "", err
+	}
+	return result.body, nil
+}
+
+// fetchResult is a fetched manifest with its response metadata
+type fetchResult struct {
+	body   string
+	url    string
+	header http.Header
+}
+
+// fetch fetches the manifest, retrying if configured
+func (h *HTTPClient) fetch(ctx context.Context, manifestURL string) (*fetchResult, error) {
+	var result *fetchResult
 	
-	wrappedOperation := func() error {
-		body, err := h.fetchOnce(ctx, manifestURL)
+	wrappedOperation := func(attemptCtx context.Context) error {
+		fetched, err := h.fetchOnce(attemptCtx, manifestURL)
 		if err != nil {
 			return err
 		}
-		result = body
+		result = fetched
 		return nil
 	}
 	
 	// Use retry executor if available
 	if h.retryExecutor != nil {
-		err := h.retryExecutor.Execute(ctx, wrappedOperation)
+		err := h.retryExecutor.ExecuteWithAttemptContext(ctx, wrappedOperation)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		return result, nil
 	}
 	
 	// No retry, execute once
-	body, err := h.fetchOnce(ctx, manifestURL)
-	return body, err
+	return h.fetchOnce(ctx, manifestURL)
 }
 
-// fetchOnce performs a single HTTP request
-func (h *HTTPClient) fetchOnce(ctx context.Context, manifestURL string) (string, error) {
-	resp, err := h.client.R().SetContext(ctx).Get(manifestURL)
+// fetchOnce performs a single fetch, sniffing the format first when configured
+func (h *HTTPClient) fetchOnce(ctx context.Context, manifestURL string) (*fetchResult, error) {
+	if h.sniffBytes > 0 {
+		result, err := h.sniff(ctx, manifestURL)
+		if err != nil || result != nil {
+			return result, err
+		}
+	}
+
+	resp, err := h.get(ctx, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := h.readBody(resp, manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	return &fetchResult{body: body, url: manifestURL, header: resp.Header}, nil
+}
+
+// sniff fetches the first sniffBytes of the manifest with a Range request and
+// checks that they look like a manifest. It returns a result when the body is
+// the whole manifest (it fit in the range, or the server ignored Range), and
+// nil when the full manifest still has to be downloaded.
+func (h *HTTPClient) sniff(ctx context.Context, manifestURL string) (*fetchResult, error) {
+	resp, err := h.get(ctx, manifestURL, map[string]string{
+		"Range":           fmt.Sprintf("bytes=0-%d", h.sniffBytes-1),
+		"Accept-Encoding": "identity", // a compressed prefix cannot be decoded
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Range not honored: the full body is already on its way
+	if resp.StatusCode != http.StatusPartialContent {
+		body, err := h.readBody(resp, manifestURL)
+		if err != nil {
+			return nil, err
+		}
+		return &fetchResult{body: body, url: manifestURL, header: resp.Header}, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, h.sniffBytes))
+	if err != nil {
+		return nil, NewNetworkError(manifestURL, err)
+	}
+
+	if !looksLikeManifest(data) {
+		return nil, NewParsingError(manifestURL, "unknown", fmt.Errorf("%w: first %d bytes do not look like a manifest", ErrUnsupportedFormat, len(data)))
+	}
+
+	if total, ok := contentRangeTotal(resp.Header.Get("Content-Range")); ok && total <= int64(len(data)) {
+		return &fetchResult{body: string(data), url: manifestURL, header: resp.Header}, nil
+	}
+
+	return nil, nil
+}
+
+// get performs a GET request with extra headers and checks the status code
+func (h *HTTPClient) get(ctx context.Context, manifestURL string, headers map[string]string) (*req.Response, error) {
+	request := h.client.R().DisableAutoReadResponse()
+	recorder := provenanceFrom(ctx)
+	if recorder != nil {
+		ctx = recorder.trace(ctx)
+		if recorder.requestID != "" {
+			request.SetHeader(recorder.header, recorder.requestID)
+		}
+	}
+	request.SetContext(ctx)
+	if accept := h.acceptFor(manifestURL); accept != "" {
+		request.SetHeader("Accept", accept)
+	}
+	request.SetHeaders(headers)
+
+	resp, err := request.Get(manifestURL)
 	if err != nil {
 		// Check if it's a timeout error
 		if isTimeoutError(err) {
-			return "", NewTimeoutError(manifestURL, 30) // Default timeout
+			return nil, NewTimeoutError(manifestURL, 30) // Default timeout
 		}
-		return "", NewNetworkError(manifestURL, err)
+		return nil, NewNetworkError(manifestURL, err)
+	}
+
+	if recorder != nil {
+		recorder.recordResponse(resp.Header)
 	}
 
 	// Check HTTP status code
 	statusCode := resp.StatusCode
+	if statusCode == 200 || (statusCode == http.StatusPartialContent && headers["Range"] != "") {
+		if h.shaper != nil {
+			resp.Body = h.shaper.wrap(ctx, resp.Body)
+		}
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	if statusCode == http.StatusNotFound || statusCode == http.StatusGone {
+		return nil, NewNotFoundError(manifestURL, statusCode)
+	}
 	if statusCode >= 400 && statusCode < 500 {
-		return "", NewAuthError(manifestURL, statusCode)
+		return nil, NewAuthError(manifestURL, statusCode)
 	}
 	if statusCode >= 500 {
-		return "", NewNetworkError(manifestURL, fmt.Errorf("server error: HTTP %d", statusCode))
-	}
-	if statusCode != 200 {
-		return "", NewNetworkError(manifestURL, fmt.Errorf("unexpected status code: %d", statusCode))
+		return nil, NewNetworkError(manifestURL, fmt.Errorf("server error: HTTP %d", statusCode))
 	}
+	return nil, NewNetworkError(manifestURL, fmt.Errorf("unexpected status code: %d", statusCode))
+}
 
-	body := resp.String()
+// readBody reads a full manifest body, enforcing the size limit
+func (h *HTTPClient) readBody(resp *req.Response, manifestURL string) (string, error) {
+	// Read at most one byte past the limit so oversized bodies are detected
+	data, err := io.ReadAll(io.LimitReader(resp.Body, h.maxBodyBytes+1))
+	if err != nil {
+		if isTimeoutError(err) {
+			return "", NewTimeoutError(manifestURL, 30) // Default timeout
+		}
+		return "", NewNetworkError(manifestURL, err)
+	}
+	if int64(len(data)) > h.maxBodyBytes {
+		return "", NewParsingError(manifestURL, "unknown", fmt.Errorf("%w (more than %d bytes)", ErrManifestTooLarge, h.maxBodyBytes))
+	}
+	body := string(data)
 	
 	// Basic content validation
 	if len(body) == 0 {
@@ -102,12 +268,74 @@ func (h *HTTPClient) fetchOnce(ctx context.Context, manifestURL string) (string,
 	return body, nil
 }
 
+// looksLikeManifest reports whether data starts like an HLS playlist or an XML document
+func looksLikeManifest(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return bytes.HasPrefix(data, []byte("#EXTM3U")) || bytes.HasPrefix(data, []byte("<"))
+}
+
+// contentRangeTotal extracts the complete length from a Content-Range header
+func contentRangeTotal(contentRange string) (int64, bool) {
+	slash := strings.LastIndex(contentRange, "/")
+	if slash < 0 {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(contentRange[slash+1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return total, true
+}
+
+// sniffBytes returns the configured sniff size for opts (0 = disabled)
+func sniffBytes(opts *ProbeOptions) int64 {
+	if opts == nil || opts.SniffBytes <= 0 {
+		return 0
+	}
+	return opts.SniffBytes
+}
+
+// acceptHeader returns the configured Accept override for opts
+func acceptHeader(opts *ProbeOptions) string {
+	if opts == nil {
+		return ""
+	}
+	return opts.AcceptHeader
+}
+
+// acceptFor returns the Accept header to send for manifestURL ("" = client default).
+// An explicit AcceptHeader wins over format negotiation.
+func (h *HTTPClient) acceptFor(manifestURL string) string {
+	if h.acceptHeader != "" || !h.negotiate {
+		return h.acceptHeader
+	}
+
+	parsedURL, err := url.Parse(manifestURL)
+	if err != nil {
+		return ""
+	}
+
+	switch strings.ToLower(path.Ext(parsedURL.Path)) {
+	case ".mpd":
+		return dashAcceptHeader
+	case ".m3u8", ".m3u":
+		return hlsAcceptHeader
+	default:
+		return ""
+	}
+}
+
 // isTimeoutError checks if an error is timeout-related
 func isTimeoutError(err error) bool {
 	return strings.Contains(strings.ToLower(err.Error()), "timeout") ||
 		   strings.Contains(strings.ToLower(err.Error()), "deadline exceeded")
 }
 
+// fetchModeHeader is the request header setting the Fetch API mode of req's
+// js/wasm transport ("same-origin" by default)
+const fetchModeHeader = "js.fetch:mode"
+
 // createConfiguredClient creates a req client with all necessary headers and settings
 func createConfiguredClient(parsedURL *url.URL, opts *ProbeOptions) *req.Client {
 	// Set defaults
@@ -128,25 +356,23 @@ func createConfiguredClient(parsedURL *url.URL, opts *ProbeOptions) *req.Client
 		SetTimeout(time.Duration(timeoutSeconds) * time.Second).
 		EnableAutoReadResponse()
 
-	// Configure compression
-	if opts == nil || !opts.DisableCompression {
+	// Configure compression (the browser negotiates it for the Fetch API)
+	if (opts == nil || !opts.DisableCompression) && !fetchTransport {
 		client.EnableCompression()
 	}
 
+	// Fetch manifests of other origins in CORS mode, as they allow
+	if fetchTransport {
+		client.SetCommonHeader(fetchModeHeader, "cors")
+	}
+
 	// Configure camouflage headers
 	if opts == nil || !opts.DisableCamouflage {
-		origin := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
-		referer := origin + "/"
-		
-		client.SetCommonHeaders(map[string]string{
-			"Accept":          "application/dash+xml,application/vnd.ms-sstr+xml,application/vnd.apple.mpegurl,application/x-mpegURL,application/vnd.ms-playready.media.pya,application/vnd.ms-playready.media.pyv,video/mp4,audio/mp4,*/*",
-			"Accept-Language": "en-US,en;q=0.9,fr;q=0.8",
-			"Origin":          origin,
-			"Referer":         referer,
-			"DNT":             "1",
-			"Connection":      "keep-alive",
-			"Upgrade-Insecure-Requests": "1",
-		})
+		var policy *CamouflagePolicy
+		if opts != nil {
+			policy = opts.Camouflage
+		}
+		client.SetCommonHeaders(camouflageHeaders(parsedURL.Scheme, parsedURL.Host, policy))
 	}
 
 	// Add custom headers
@@ -159,5 +385,15 @@ func createConfiguredClient(parsedURL *url.URL, opts *ProbeOptions) *req.Client
 		client.SetProxyURL(opts.ProxyURL)
 	}
 
+	// Configure connection limits
+	if opts != nil && opts.Limits != nil {
+		if opts.Limits.MaxConnsPerHost > 0 {
+			client.GetTransport().SetMaxConnsPerHost(opts.Limits.MaxConnsPerHost)
+		}
+		if opts.Limits.MaxIdleConns > 0 {
+			client.GetTransport().SetMaxIdleConns(opts.Limits.MaxIdleConns)
+		}
+	}
+
 	return client
 }
\ No newline at end of file
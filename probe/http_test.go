@@ -0,0 +1,281 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcceptHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *ProbeOptions
+		path     string
+		expected string
+	}{
+		{
+			name:     "camouflage default",
+			opts:     nil,
+			path:     "/manifest.mpd",
+			expected: defaultAcceptHeader,
+		},
+		{
+			name:     "no camouflage",
+			opts:     &ProbeOptions{DisableCamouflage: true},
+			path:     "/manifest.mpd",
+			expected: "",
+		},
+		{
+			name:     "explicit override without camouflage",
+			opts:     &ProbeOptions{DisableCamouflage: true, AcceptHeader: "application/xml"},
+			path:     "/manifest.mpd",
+			expected: "application/xml",
+		},
+		{
+			name:     "negotiated DASH",
+			opts:     &ProbeOptions{NegotiateFormat: true},
+			path:     "/live/Manifest.MPD",
+			expected: dashAcceptHeader,
+		},
+		{
+			name:     "negotiated HLS",
+			opts:     &ProbeOptions{NegotiateFormat: true},
+			path:     "/live/master.m3u8",
+			expected: hlsAcceptHeader,
+		},
+		{
+			name:     "negotiation falls back for unknown extension",
+			opts:     &ProbeOptions{NegotiateFormat: true},
+			path:     "/live/manifest",
+			expected: defaultAcceptHeader,
+		},
+		{
+			name:     "override wins over negotiation",
+			opts:     &ProbeOptions{NegotiateFormat: true, AcceptHeader: "*/*"},
+			path:     "/live/master.m3u8",
+			expected: "*/*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Get("Accept")
+				w.Write([]byte(watchTestManifest))
+			}))
+			defer server.Close()
+
+			if _, err := NewProber(tt.opts).Probe(context.Background(), server.URL+tt.path); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if received != tt.expected {
+				t.Errorf("Expected Accept %q, got %q", tt.expected, received)
+			}
+		})
+	}
+}
+
+func TestCamouflageHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *CamouflagePolicy
+		expected map[string]string
+	}{
+		{
+			name:   "defaults",
+			policy: nil,
+			expected: map[string]string{
+				"Origin":          "https://cdn.example.com",
+				"Referer":         "https://cdn.example.com/",
+				"DNT":             "1",
+				"Accept-Language": defaultAcceptLanguage,
+				"Sec-Fetch-Mode":  "",
+			},
+		},
+		{
+			name: "custom origin and referer",
+			policy: &CamouflagePolicy{
+				Origin:  "https://player.example.com",
+				Referer: "https://player.example.com/watch/123",
+			},
+			expected: map[string]string{
+				"Origin":  "https://player.example.com",
+				"Referer": "https://player.example.com/watch/123",
+			},
+		},
+		{
+			name:   "custom origin derives referer",
+			policy: &CamouflagePolicy{Origin: "https://player.example.com"},
+			expected: map[string]string{
+				"Referer": "https://player.example.com/",
+			},
+		},
+		{
+			name: "individual toggles",
+			policy: &CamouflagePolicy{
+				DisableOrigin:         true,
+				DisableDNT:            true,
+				DisableAcceptLanguage: true,
+				EnableSecFetch:        true,
+			},
+			expected: map[string]string{
+				"Origin":          "",
+				"Referer":         "https://cdn.example.com/",
+				"DNT":             "",
+				"Accept-Language": "",
+				"Sec-Fetch-Mode":  "cors",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := camouflageHeaders("https", "cdn.example.com", tt.policy)
+			for name, value := range tt.expected {
+				if headers[name] != value {
+					t.Errorf("Expected %s %q, got %q", name, value, headers[name])
+				}
+			}
+		})
+	}
+}
+
+func TestSniffBytes(t *testing.T) {
+	largeManifest := watchTestManifest + strings.Repeat("#EXT-X-COMMENT\n", 100)
+	junk := strings.Repeat("x", 4096)
+
+	tests := []struct {
+		name          string
+		body          string
+		ignoreRange   bool
+		expectError   bool
+		expectedReqs  int
+		expectedRange string
+	}{
+		{
+			name:         "junk rejected after sniff",
+			body:         junk,
+			expectError:  true,
+			expectedReqs: 1,
+		},
+		{
+			name:         "large manifest fetched in full",
+			body:         largeManifest,
+			expectedReqs: 2,
+		},
+		{
+			name:         "small manifest fits in sniffed range",
+			body:         watchTestManifest,
+			expectedReqs: 1,
+		},
+		{
+			name:         "range ignored by server",
+			body:         largeManifest,
+			ignoreRange:  true,
+			expectedReqs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				if tt.ignoreRange {
+					w.Write([]byte(tt.body))
+					return
+				}
+				http.ServeContent(w, r, "manifest", time.Time{}, strings.NewReader(tt.body))
+			}))
+			defer server.Close()
+
+			prober := NewProber(&ProbeOptions{SniffBytes: 256})
+			output, err := prober.Probe(context.Background(), server.URL+"/master.m3u8")
+
+			if tt.expectError {
+				var probeErr *ProbeError
+				if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeParsing {
+					t.Errorf("Expected parsing error, got %v", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(output.Streams) != 2 {
+					t.Errorf("Expected 2 streams, got %d", len(output.Streams))
+				}
+			}
+
+			if int(requests) != tt.expectedReqs {
+				t.Errorf("Expected %d requests, got %d", tt.expectedReqs, requests)
+			}
+		})
+	}
+}
+
+func TestLooksLikeManifest(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected bool
+	}{
+		{"#EXTM3U\n#EXT-X-VERSION:3", true},
+		{"\xef\xbb\xbf#EXTM3U", true},
+		{"  \n<?xml version=\"1.0\"?><MPD>", true},
+		{"<MPD xmlns=\"urn:mpeg:dash:schema:mpd:2011\">", true},
+		{"GIF89a", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if result := looksLikeManifest([]byte(tt.data)); result != tt.expected {
+			t.Errorf("looksLikeManifest(%q): expected %v, got %v", tt.data, tt.expected, result)
+		}
+	}
+}
+
+func TestFetchPerAttemptTimeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	opts := &ProbeOptions{
+		DisableCamouflage: true,
+		RetryConfig: &RetryConfig{
+			MaxRetries:        1,
+			InitialDelay:      time.Millisecond,
+			MaxDelay:          time.Millisecond,
+			BackoffMultiplier: 2.0,
+			RetryableErrors:   []ErrorType{ErrorTypeTimeout},
+			PerAttemptTimeout: 50 * time.Millisecond,
+		},
+	}
+
+	start := time.Now()
+	if _, err := ProbeManifest(server.URL+"/live.m3u8", opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the stalled attempt to be abandoned quickly, took %v", elapsed)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+}
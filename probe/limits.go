@@ -0,0 +1,61 @@
+package probe
+
+import "context"
+
+// DefaultMaxBodyBytes is the default manifest size limit (50MB)
+const DefaultMaxBodyBytes = 50 * 1024 * 1024
+
+// Limits bounds the resources a Prober may use. Connection limits apply per
+// origin since a Prober keeps one HTTP client per scheme and host.
+type Limits struct {
+	// MaxConnsPerHost limits connections per host, including dials in progress (0 = unlimited)
+	MaxConnsPerHost int
+
+	// MaxIdleConns limits idle keep-alive connections (0 = transport default)
+	MaxIdleConns int
+
+	// MaxConcurrentSubRequests limits concurrent HTTP requests issued by the Prober (0 = unlimited)
+	MaxConcurrentSubRequests int
+
+	// MaxBodyBytes limits the size of a manifest body; reading stops once exceeded (0 = DefaultMaxBodyBytes)
+	MaxBodyBytes int64
+}
+
+// maxBodyBytes returns the effective manifest size limit for opts
+func maxBodyBytes(opts *ProbeOptions) int64 {
+	if opts != nil && opts.Limits != nil && opts.Limits.MaxBodyBytes > 0 {
+		return opts.Limits.MaxBodyBytes
+	}
+	return DefaultMaxBodyBytes
+}
+
+// requestSlots bounds concurrent requests (nil = unlimited)
+type requestSlots chan struct{}
+
+// newRequestSlots creates request slots for opts (nil when unlimited)
+func newRequestSlots(opts *ProbeOptions) requestSlots {
+	if opts == nil || opts.Limits == nil || opts.Limits.MaxConcurrentSubRequests <= 0 {
+		return nil
+	}
+	return make(requestSlots, opts.Limits.MaxConcurrentSubRequests)
+}
+
+// acquire waits for a free slot or ctx cancellation
+func (s requestSlots) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire
+func (s requestSlots) release() {
+	if s != nil {
+		<-s
+	}
+}
@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(watchTestManifest + strings.Repeat("#", 1024)))
+	}))
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{Limits: &Limits{MaxBodyBytes: 512}})
+	_, err := prober.Probe(context.Background(), server.URL+"/master.m3u8")
+	if err == nil {
+		t.Fatal("Expected error but got none")
+	}
+
+	var probeErr *ProbeError
+	if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeParsing {
+		t.Errorf("Expected parsing error, got %v", err)
+	}
+
+	prober = NewProber(&ProbeOptions{Limits: &Limits{MaxBodyBytes: 4096}})
+	if _, err := prober.Probe(context.Background(), server.URL+"/master.m3u8"); err != nil {
+		t.Errorf("Expected no error but got: %v", err)
+	}
+}
+
+func TestMaxConcurrentSubRequests(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{Limits: &Limits{MaxConcurrentSubRequests: 2}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := prober.Probe(context.Background(), server.URL+"/master.m3u8"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", peak)
+	}
+}
+
+func TestRequestSlotsCancellation(t *testing.T) {
+	slots := newRequestSlots(&ProbeOptions{Limits: &Limits{MaxConcurrentSubRequests: 1}})
+	if err := slots.acquire(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := slots.acquire(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	slots.release()
+}
@@ -0,0 +1,58 @@
+package probe
+
+import "strings"
+
+// lineScanner iterates over the lines of a manifest like ranging over
+// strings.Split(content, "\n"), without allocating a slice of every line.
+// Live media playlists can have tens of thousands of lines.
+type lineScanner struct {
+	rest  string
+	done  bool
+	line  string
+	index int
+}
+
+// scanLines returns a scanner over the lines of content
+func scanLines(content string) lineScanner {
+	return lineScanner{rest: content, index: -1}
+}
+
+// scan advances to the next line, reporting false after the last one
+func (s *lineScanner) scan() bool {
+	if s.done {
+		return false
+	}
+	s.index++
+	line, rest, found := strings.Cut(s.rest, "\n")
+	s.line, s.rest, s.done = line, rest, !found
+	return true
+}
+
+// containsFold reports whether s contains substr, a lowercase ASCII string,
+// ignoring case and without allocating a lowercased copy of s
+func containsFold(s, substr string) bool {
+	if substr == "" {
+		return true
+	}
+
+	// Candidates start with either case of the first byte
+	first := substr[0]
+	starts := []byte{first}
+	if 'a' <= first && first <= 'z' {
+		starts = append(starts, first-('a'-'A'))
+	}
+
+	for _, start := range starts {
+		for i := 0; i+len(substr) <= len(s); i++ {
+			next := strings.IndexByte(s[i:len(s)-len(substr)+1], start)
+			if next < 0 {
+				break
+			}
+			i += next
+			if strings.EqualFold(s[i:i+len(substr)], substr) {
+				return true
+			}
+		}
+	}
+	return false
+}
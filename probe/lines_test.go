@@ -0,0 +1,112 @@
+package probe
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanLines(t *testing.T) {
+	tests := []string{
+		"",
+		"#EXTM3U",
+		"#EXTM3U\n",
+		"#EXTM3U\r\n#EXTINF:6.0,\r\nseg.ts\r\n",
+		"\n\nseg.ts",
+	}
+
+	for _, content := range tests {
+		var lines []string
+		for scanner := scanLines(content); scanner.scan(); {
+			if scanner.index != len(lines) {
+				t.Errorf("Expected index %d, got %d", len(lines), scanner.index)
+			}
+			lines = append(lines, scanner.line)
+		}
+		if expected := strings.Split(content, "\n"); !reflect.DeepEqual(lines, expected) {
+			t.Errorf("Expected %q, got %q", expected, lines)
+		}
+	}
+}
+
+func TestContainsFold(t *testing.T) {
+	tests := []struct {
+		s        string
+		substr   string
+		expected bool
+	}{
+		{`KEYFORMAT="com.apple.streamingkeydelivery"`, "com.apple.streamingkeydelivery", true},
+		{`KEYFORMAT="COM.APPLE.StreamingKeyDelivery"`, "com.apple.streamingkeydelivery", true},
+		{`schemeIdUri="urn:uuid:EDEF8BA9-79D6-4ACE-A3C8-27DCD51D21ED"`, "edef8ba9-79d6-4ace-a3c8-27dcd51d21ed", true},
+		{`schemeIdUri="urn:uuid:9A04F079-9840-4286-AB92-E65BE0885F95"`, "9a04f079-9840-4286-ab92-e65be0885f95", true},
+		{"com.apple.streamingkeydeliver", "com.apple.streamingkeydelivery", false},
+		{"", "com", false},
+		{"anything", "", true},
+	}
+
+	for _, tt := range tests {
+		if result := containsFold(tt.s, tt.substr); result != tt.expected {
+			t.Errorf("Expected %v for %q in %q, got %v", tt.expected, tt.substr, tt.s, result)
+		}
+	}
+}
+
+// largeMediaPlaylist returns a live media playlist with a program date time
+// and bitrate on every segment
+func largeMediaPlaylist(segments int) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:1000\n")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < segments; i++ {
+		pdt := start.Add(time.Duration(i) * 6 * time.Second).Format("2006-01-02T15:04:05.000Z")
+		fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n#EXTINF:6.000,\n#EXT-X-BITRATE:2500\nsegment_%d.ts\n", pdt, i)
+	}
+	return b.String()
+}
+
+func TestLargeMediaPlaylistAllocations(t *testing.T) {
+	playlist := largeMediaPlaylist(10000)
+
+	tests := []struct {
+		name string
+		max  float64
+		run  func()
+	}{
+		{"parseHLSTiming", 1, func() { parseHLSTiming(playlist) }},
+		{"computeBitrateStats", 1, func() { computeBitrateStats(hlsVariantRef{streamID: "0:0"}, playlist) }},
+		{"hlsSegmentDurations", 30, func() { hlsSegmentDurations(playlist) }},
+		{"detectHLSFeatures", 10, func() { detectHLSFeatures(playlist) }},
+		// the program date time anchors of the segments grow by doubling
+		{"parseHLSManifest", 50, func() { parseHLSManifest(playlist, "https://example.com/live/media.m3u8") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(5, tt.run); allocs > tt.max {
+				t.Errorf("Expected at most %v allocations, got %v", tt.max, allocs)
+			}
+		})
+	}
+}
+
+func BenchmarkParseHLSMediaPlaylist(b *testing.B) {
+	playlist := largeMediaPlaylist(10000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(playlist)))
+	for b.Loop() {
+		if _, err := parseHLSManifest(playlist, "https://example.com/live/media.m3u8"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkComputeBitrateStats(b *testing.B) {
+	playlist := largeMediaPlaylist(10000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(playlist)))
+	for b.Loop() {
+		computeBitrateStats(hlsVariantRef{streamID: "0:0"}, playlist)
+	}
+}
@@ -0,0 +1,214 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLoadMaxInFlight is the default limit of concurrent probes in a load test
+const DefaultLoadMaxInFlight = 256
+
+// LoadTest configures sustained probe traffic against one manifest URL
+type LoadTest struct {
+	URL string
+
+	// RPS is the rate at which probes are started, per second
+	RPS float64
+
+	// Duration is how long probes are started for. The test then waits for
+	// probes in flight to finish.
+	Duration time.Duration
+
+	// MaxInFlight caps concurrent probes (0 = DefaultLoadMaxInFlight). Probes
+	// due while at the cap are skipped and counted as dropped, so a slow
+	// origin shows up as a lower achieved rate rather than unbounded goroutines.
+	MaxInFlight int
+}
+
+// LoadResult summarizes a load test
+type LoadResult struct {
+	URL       string        `json:"url"`
+	Duration  time.Duration `json:"duration"`
+	Requests  int           `json:"requests"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+
+	// Dropped counts probes skipped because MaxInFlight was reached
+	Dropped int `json:"dropped,omitempty"`
+
+	ErrorRate   float64 `json:"error_rate"`
+	AchievedRPS float64 `json:"achieved_rps"`
+
+	// Latency summarizes the duration of all probes, failed ones included
+	Latency LatencyStats `json:"latency"`
+
+	// ErrorsByType counts failed probes by ProbeError type
+	ErrorsByType map[string]int `json:"errors_by_type,omitempty"`
+}
+
+// LatencyStats are the distribution of probe durations
+type LatencyStats struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// LoadTest probes test.URL at test.RPS for test.Duration, reusing the
+// prober's clients, proxy and camouflage settings, and reports latency
+// percentiles and error rates. Cancelling ctx stops the test early; the
+// result covers the probes started until then.
+func (p *Prober) LoadTest(ctx context.Context, test LoadTest) (*LoadResult, error) {
+	if _, err := validateURL(test.URL); err != nil {
+		return nil, err
+	}
+	if test.RPS <= 0 {
+		return nil, NewValidationError("load test RPS must be positive")
+	}
+	if test.Duration <= 0 {
+		return nil, NewValidationError("load test duration must be positive")
+	}
+	maxInFlight := test.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultLoadMaxInFlight
+	}
+
+	interval := time.Duration(float64(time.Second) / test.RPS)
+	if interval <= 0 {
+		return nil, NewValidationError(fmt.Sprintf("load test RPS too high: %g", test.RPS))
+	}
+
+	logInfo(ctx, "Starting load test", map[string]interface{}{
+		"url":      test.URL,
+		"rps":      test.RPS,
+		"duration": test.Duration.String(),
+	})
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		failures  = make(map[string]int)
+		dropped   int
+	)
+	slots := make(chan struct{}, maxInFlight)
+
+	probe := func() {
+		defer wg.Done()
+		defer func() { <-slots }()
+
+		start := p.clock.Now()
+		_, err := p.Probe(ctx, test.URL)
+		elapsed := p.clock.Now().Sub(start)
+
+		mu.Lock()
+		defer mu.Unlock()
+		latencies = append(latencies, elapsed)
+		if err != nil {
+			failures[resultErrorType(err)]++
+		}
+	}
+
+	start := p.clock.Now()
+	ticker := p.clock.NewTicker(interval)
+	deadline := p.clock.After(test.Duration)
+
+	launch := func() {
+		select {
+		case slots <- struct{}{}:
+			wg.Add(1)
+			go probe()
+		default:
+			mu.Lock()
+			dropped++
+			mu.Unlock()
+		}
+	}
+
+	launch()
+loop:
+	for {
+		select {
+		case <-ticker.C():
+			launch()
+		case <-deadline:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	ticker.Stop()
+	elapsed := p.clock.Now().Sub(start)
+	wg.Wait()
+
+	result := &LoadResult{
+		URL:      p.redactor.redactString(test.URL),
+		Duration: elapsed,
+		Requests: len(latencies),
+		Dropped:  dropped,
+		Latency:  latencyStats(latencies),
+	}
+	for errorType, count := range failures {
+		result.Failed += count
+		if result.ErrorsByType == nil {
+			result.ErrorsByType = make(map[string]int)
+		}
+		result.ErrorsByType[errorType] = count
+	}
+	result.Succeeded = result.Requests - result.Failed
+	if result.Requests > 0 {
+		result.ErrorRate = float64(result.Failed) / float64(result.Requests)
+	}
+	if elapsed > 0 {
+		result.AchievedRPS = float64(result.Requests) / elapsed.Seconds()
+	}
+
+	logInfo(ctx, "Load test finished", map[string]interface{}{
+		"url":        result.URL,
+		"requests":   result.Requests,
+		"error_rate": result.ErrorRate,
+		"p99":        result.Latency.P99.String(),
+	})
+
+	return result, nil
+}
+
+// latencyStats computes the distribution of latencies (nearest-rank percentiles)
+func latencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+
+	percentile := func(p float64) time.Duration {
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return sorted[rank]
+	}
+
+	return LatencyStats{
+		Min:  sorted[0],
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P95:  percentile(0.95),
+		P99:  percentile(0.99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
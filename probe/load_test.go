@@ -0,0 +1,131 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLatencyStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	stats := latencyStats(latencies)
+	tests := []struct {
+		name     string
+		got      time.Duration
+		expected time.Duration
+	}{
+		{"min", stats.Min, time.Millisecond},
+		{"mean", stats.Mean, 50500 * time.Microsecond},
+		{"p50", stats.P50, 50 * time.Millisecond},
+		{"p90", stats.P90, 90 * time.Millisecond},
+		{"p95", stats.P95, 95 * time.Millisecond},
+		{"p99", stats.P99, 99 * time.Millisecond},
+		{"max", stats.Max, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.expected, tt.got)
+		}
+	}
+
+	if stats := latencyStats(nil); stats != (LatencyStats{}) {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
+
+func TestLoadTest(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1)%4 == 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	result, err := NewProber(nil).LoadTest(context.Background(), LoadTest{
+		URL:      server.URL + "/master.m3u8",
+		RPS:      200,
+		Duration: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.Requests < 10 || int64(result.Requests) != requests.Load() {
+		t.Errorf("Expected at least 10 requests matching the server's %d, got %d", requests.Load(), result.Requests)
+	}
+	if result.Failed != int(requests.Load()/4) {
+		t.Errorf("Expected %d failures, got %d", requests.Load()/4, result.Failed)
+	}
+	if result.ErrorsByType["network"] != result.Failed {
+		t.Errorf("Expected %d network errors, got %v", result.Failed, result.ErrorsByType)
+	}
+	if result.Succeeded+result.Failed != result.Requests {
+		t.Errorf("Expected succeeded + failed = %d, got %d + %d", result.Requests, result.Succeeded, result.Failed)
+	}
+	if result.Latency.Max <= 0 || result.Latency.P50 > result.Latency.Max {
+		t.Errorf("Expected latency percentiles, got %+v", result.Latency)
+	}
+}
+
+func TestLoadTestMaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		release <- struct{}{}
+	}()
+
+	result, err := NewProber(nil).LoadTest(context.Background(), LoadTest{
+		URL:         server.URL + "/master.m3u8",
+		RPS:         100,
+		Duration:    100 * time.Millisecond,
+		MaxInFlight: 1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Requests != 1 {
+		t.Errorf("Expected 1 request, got %d", result.Requests)
+	}
+	if result.Dropped == 0 {
+		t.Error("Expected dropped probes while at MaxInFlight")
+	}
+}
+
+func TestLoadTestValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		test LoadTest
+	}{
+		{"invalid URL", LoadTest{URL: "ftp://example.com/a.m3u8", RPS: 1, Duration: time.Second}},
+		{"zero RPS", LoadTest{URL: "https://example.com/a.m3u8", Duration: time.Second}},
+		{"zero duration", LoadTest{URL: "https://example.com/a.m3u8", RPS: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProber(nil).LoadTest(context.Background(), tt.test)
+			var probeErr *ProbeError
+			if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeValidation {
+				t.Errorf("Expected validation error, got %v", err)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -96,31 +98,128 @@ func (NoopLogger) Warn(ctx context.Context, msg string, fields map[string]interf
 func (NoopLogger) Error(ctx context.Context, msg string, fields map[string]interface{}) {}
 
 // Global logger instance
-var globalLogger Logger = &NoopLogger{}
+var (
+	globalLogger   Logger = &NoopLogger{}
+	globalLoggerMu sync.RWMutex
+)
 
-// SetLogger sets the global logger
+// SetLogger sets the global logger. It is safe for concurrent use.
+//
+// Deprecated: the global logger is shared by every caller in the process.
+// Set ProbeOptions.Logger (or use a Prober) to configure logging per probe.
 func SetLogger(logger Logger) {
+	if logger == nil {
+		logger = &NoopLogger{}
+	}
+	globalLoggerMu.Lock()
+	defer globalLoggerMu.Unlock()
 	globalLogger = logger
 }
 
 // GetLogger returns the current global logger
+//
+// Deprecated: see SetLogger.
 func GetLogger() Logger {
+	globalLoggerMu.RLock()
+	defer globalLoggerMu.RUnlock()
+	return globalLogger
+}
+
+// globalLoggerRef resolves the global logger on every call so SetLogger
+// keeps affecting probers created without their own logger
+type globalLoggerRef struct{}
+
+func (globalLoggerRef) Debug(ctx context.Context, msg string, fields map[string]interface{}) {
+	GetLogger().Debug(ctx, msg, fields)
+}
+
+func (globalLoggerRef) Info(ctx context.Context, msg string, fields map[string]interface{}) {
+	GetLogger().Info(ctx, msg, fields)
+}
+
+func (globalLoggerRef) Warn(ctx context.Context, msg string, fields map[string]interface{}) {
+	GetLogger().Warn(ctx, msg, fields)
+}
+
+func (globalLoggerRef) Error(ctx context.Context, msg string, fields map[string]interface{}) {
+	GetLogger().Error(ctx, msg, fields)
+}
+
+// probeLogger applies debug sampling and redaction before delegating to another logger
+type probeLogger struct {
+	next       Logger
+	sampleRate uint64
+	debugCount atomic.Uint64
+	redactor   *redactor
+}
+
+// newProbeLogger wraps the logger configured in opts (nil = global logger)
+func newProbeLogger(opts *ProbeOptions, r *redactor) *probeLogger {
+	l := &probeLogger{next: globalLoggerRef{}, redactor: r}
+	if opts != nil {
+		if opts.Logger != nil {
+			l.next = opts.Logger
+		}
+		if opts.LogConfig != nil && opts.LogConfig.DebugSampleRate > 1 {
+			l.sampleRate = uint64(opts.LogConfig.DebugSampleRate)
+		}
+	}
+	return l
+}
+
+func (l *probeLogger) Debug(ctx context.Context, msg string, fields map[string]interface{}) {
+	if l.sampleRate > 1 && (l.debugCount.Add(1)-1)%l.sampleRate != 0 {
+		return
+	}
+	l.next.Debug(ctx, msg, l.redactor.redactFields(fields))
+}
+
+func (l *probeLogger) Info(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.next.Info(ctx, msg, l.redactor.redactFields(fields))
+}
+
+func (l *probeLogger) Warn(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.next.Warn(ctx, msg, l.redactor.redactFields(fields))
+}
+
+func (l *probeLogger) Error(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.next.Error(ctx, msg, l.redactor.redactFields(fields))
+}
+
+// loggerContextKey is the context key for a per-probe logger
+type loggerContextKey struct{}
+
+// withLogger returns a context carrying logger (nil leaves ctx unchanged)
+func withLogger(ctx context.Context, logger Logger) context.Context {
+	if logger == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// loggerFromContext returns the per-probe logger, falling back to the global one
+func loggerFromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(Logger); ok {
+		return logger
+	}
+	globalLoggerMu.RLock()
+	defer globalLoggerMu.RUnlock()
 	return globalLogger
 }
 
 // Helper functions for logging
 func logDebug(ctx context.Context, msg string, fields map[string]interface{}) {
-	globalLogger.Debug(ctx, msg, fields)
+	loggerFromContext(ctx).Debug(ctx, msg, fields)
 }
 
 func logInfo(ctx context.Context, msg string, fields map[string]interface{}) {
-	globalLogger.Info(ctx, msg, fields)
+	loggerFromContext(ctx).Info(ctx, msg, fields)
 }
 
 func logWarn(ctx context.Context, msg string, fields map[string]interface{}) {
-	globalLogger.Warn(ctx, msg, fields)
+	loggerFromContext(ctx).Warn(ctx, msg, fields)
 }
 
 func logError(ctx context.Context, msg string, fields map[string]interface{}) {
-	globalLogger.Error(ctx, msg, fields)
-}
\ No newline at end of file
+	loggerFromContext(ctx).Error(ctx, msg, fields)
+}
@@ -0,0 +1,98 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// recordingLogger captures log messages for assertions
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) record(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, msg)
+}
+
+func (l *recordingLogger) Debug(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.record(msg)
+}
+
+func (l *recordingLogger) Info(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.record(msg)
+}
+
+func (l *recordingLogger) Warn(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.record(msg)
+}
+
+func (l *recordingLogger) Error(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.record(msg)
+}
+
+func (l *recordingLogger) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.messages)
+}
+
+func TestProberLoggerIsolation(t *testing.T) {
+	global := &recordingLogger{}
+	SetLogger(global)
+	defer SetLogger(nil)
+
+	local := &recordingLogger{}
+	prober := NewProber(&ProbeOptions{Logger: local})
+
+	if _, err := prober.Probe(context.Background(), "ftp://example.com/manifest.mpd"); err == nil {
+		t.Fatal("Expected validation error but got none")
+	}
+
+	if local.count() == 0 {
+		t.Error("Expected prober logger to receive messages")
+	}
+
+	if global.count() != 0 {
+		t.Errorf("Expected global logger to receive no messages, got %d", global.count())
+	}
+}
+
+func TestGlobalLoggerFallback(t *testing.T) {
+	global := &recordingLogger{}
+	SetLogger(global)
+	defer SetLogger(nil)
+
+	if _, err := ProbeManifest("", nil); err == nil {
+		t.Fatal("Expected validation error but got none")
+	}
+
+	if global.count() == 0 {
+		t.Error("Expected global logger to receive messages")
+	}
+}
+
+func TestSetLoggerConcurrent(t *testing.T) {
+	defer SetLogger(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetLogger(&recordingLogger{})
+		}()
+		go func() {
+			defer wg.Done()
+			logDebug(context.Background(), "concurrent", nil)
+		}()
+	}
+	wg.Wait()
+
+	if GetLogger() == nil {
+		t.Error("Expected a logger to be set")
+	}
+}
@@ -0,0 +1,239 @@
+package probe
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Media playlist types reported in MediaPlaylist.Type
+const (
+	// MediaPlaylistVOD is a complete playlist (EXT-X-PLAYLIST-TYPE:VOD, or EXT-X-ENDLIST without a type)
+	MediaPlaylistVOD = "VOD"
+	// MediaPlaylistEvent is a playlist that only grows (EXT-X-PLAYLIST-TYPE:EVENT)
+	MediaPlaylistEvent = "EVENT"
+	// MediaPlaylistLive is a sliding window playlist (no type and no EXT-X-ENDLIST)
+	MediaPlaylistLive = "LIVE"
+)
+
+// MediaPlaylist summarizes an HLS media playlist, which lists segments
+// rather than variants
+type MediaPlaylist struct {
+	// Type is MediaPlaylistVOD, MediaPlaylistEvent or MediaPlaylistLive
+	Type string `json:"type"`
+
+	// PlaylistType is the EXT-X-PLAYLIST-TYPE ("" without one)
+	PlaylistType string `json:"playlist_type,omitempty"`
+
+	// Ended reports EXT-X-ENDLIST
+	Ended bool `json:"ended"`
+
+	// TargetDuration is the EXT-X-TARGETDURATION in seconds
+	TargetDuration float64 `json:"target_duration"`
+
+	// MediaSequence is the EXT-X-MEDIA-SEQUENCE of the first segment
+	MediaSequence int64 `json:"media_sequence"`
+
+	// Segments is the number of media segments
+	Segments int `json:"segments"`
+
+	// TotalDuration is the sum of the EXTINF durations in seconds
+	TotalDuration float64 `json:"total_duration"`
+
+	// MinSegmentDuration and MaxSegmentDuration bound the EXTINF durations in seconds
+	MinSegmentDuration float64 `json:"min_segment_duration"`
+	MaxSegmentDuration float64 `json:"max_segment_duration"`
+
+	// Discontinuities is the number of EXT-X-DISCONTINUITY tags
+	Discontinuities int `json:"discontinuities,omitempty"`
+
+	// Encryption lists the EXT-X-KEY keys of the segments
+	Encryption []MediaPlaylistKey `json:"encryption,omitempty"`
+
+	// EncryptedSegments is the number of segments with at least one key
+	EncryptedSegments int `json:"encrypted_segments,omitempty"`
+
+	// ByteRangeSegments is the number of segments addressed with EXT-X-BYTERANGE
+	ByteRangeSegments int `json:"byte_range_segments,omitempty"`
+
+	// SingleFile reports that every segment is a byte range of the same
+	// resource, which requires Range request support from the origin and CDN
+	SingleFile bool `json:"single_file,omitempty"`
+
+	// CanSkipUntil is the CAN-SKIP-UNTIL of EXT-X-SERVER-CONTROL in seconds:
+	// the origin serves delta updates (_HLS_skip) skipping older segments
+	CanSkipUntil float64 `json:"can_skip_until,omitempty"`
+
+	// CanSkipDateRanges reports CAN-SKIP-DATERANGES=YES (_HLS_skip=v2)
+	CanSkipDateRanges bool `json:"can_skip_dateranges,omitempty"`
+
+	// SkippedSegments is the SKIPPED-SEGMENTS of an EXT-X-SKIP: the playlist
+	// is a delta update, and Segments only counts the segments it lists
+	SkippedSegments int `json:"skipped_segments,omitempty"`
+
+	// DeltaUpdate reports the delta update a Watcher requested for this
+	// playlist (nil when none was requested)
+	DeltaUpdate *DeltaUpdate `json:"delta_update,omitempty"`
+}
+
+// MediaPlaylistKey is an EXT-X-KEY of a media playlist
+type MediaPlaylistKey struct {
+	Method    string `json:"method"`
+	URI       string `json:"uri,omitempty"`
+	KeyFormat string `json:"key_format,omitempty"`
+
+	// IV reports an explicit initialization vector (otherwise the media sequence number is used)
+	IV bool `json:"iv,omitempty"`
+
+	// Segments is the number of segments the key applies to
+	Segments int `json:"segments"`
+}
+
+// isHLSMediaPlaylist reports whether content is an HLS media playlist
+func isHLSMediaPlaylist(content string) bool {
+	return strings.Contains(content, "#EXTINF") && !strings.Contains(content, "#EXT-X-STREAM-INF")
+}
+
+// parseHLSMediaPlaylist summarizes the segments of a media playlist, resolving key URIs against playlistURL
+func parseHLSMediaPlaylist(content, playlistURL string) *MediaPlaylist {
+	parser := newMediaPlaylistParser(playlistURL)
+	for lines := scanLines(content); lines.scan(); {
+		parser.parseLine(strings.TrimSpace(lines.line))
+	}
+	return parser.finish()
+}
+
+// mediaPlaylistParser summarizes the segments of the lines of a media playlist
+type mediaPlaylistParser struct {
+	playlistURL  string
+	playlist     *MediaPlaylist
+	playlistType string
+
+	// keys are the indexes in Encryption of the keys of the next segment. A
+	// run of EXT-X-KEY tags (one per KEYFORMAT) replaces the previous keys.
+	keys        []int
+	keysApplied bool
+
+	// byteRange reports an EXT-X-BYTERANGE for the next segment URI
+	byteRange                  bool
+	segmentURIs, byteRangeURIs int
+	firstURI                   string
+	sameURI                    bool
+
+	// segmentTag and variantTag report EXTINF and EXT-X-STREAM-INF tags,
+	// which tell media playlists from master playlists
+	segmentTag, variantTag bool
+}
+
+// newMediaPlaylistParser returns a parser resolving key URIs against playlistURL
+func newMediaPlaylistParser(playlistURL string) *mediaPlaylistParser {
+	return &mediaPlaylistParser{playlistURL: playlistURL, playlist: &MediaPlaylist{}, sameURI: true}
+}
+
+// parseLine parses a line with surrounding whitespace removed
+func (p *mediaPlaylistParser) parseLine(line string) {
+	p.segmentTag = p.segmentTag || strings.HasPrefix(line, "#EXTINF")
+	p.variantTag = p.variantTag || strings.HasPrefix(line, "#EXT-X-STREAM-INF")
+
+	playlist := p.playlist
+	switch {
+	case strings.HasPrefix(line, "#EXT-X-PLAYLIST-TYPE:"):
+		p.playlistType = strings.ToUpper(strings.TrimPrefix(line, "#EXT-X-PLAYLIST-TYPE:"))
+	case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+		playlist.TargetDuration, _ = strconv.ParseFloat(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"), 64)
+	case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+		playlist.MediaSequence, _ = strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+	case strings.HasPrefix(line, "#EXT-X-SERVER-CONTROL:"):
+		attrs := parseHLSAttributes(line)
+		playlist.CanSkipUntil, _ = strconv.ParseFloat(attrs["CAN-SKIP-UNTIL"], 64)
+		playlist.CanSkipDateRanges = attrs["CAN-SKIP-DATERANGES"] == "YES"
+	case strings.HasPrefix(line, "#EXT-X-SKIP:"):
+		playlist.SkippedSegments, _ = strconv.Atoi(parseHLSAttributes(line)["SKIPPED-SEGMENTS"])
+	case strings.HasPrefix(line, "#EXT-X-ENDLIST"):
+		playlist.Ended = true
+	case strings.HasPrefix(line, "#EXT-X-DISCONTINUITY") && !strings.HasPrefix(line, "#EXT-X-DISCONTINUITY-SEQUENCE"):
+		playlist.Discontinuities++
+	case strings.HasPrefix(line, "#EXT-X-KEY:"):
+		if p.keysApplied {
+			p.keys, p.keysApplied = nil, false
+		}
+		attrs := parseHLSAttributes(line)
+		if attrs["METHOD"] == "NONE" {
+			p.keys = nil
+			return
+		}
+		key := MediaPlaylistKey{
+			Method:    attrs["METHOD"],
+			URI:       resolveHLSURI(p.playlistURL, attrs["URI"]),
+			KeyFormat: attrs["KEYFORMAT"],
+			IV:        attrs["IV"] != "",
+		}
+		p.keys = append(p.keys, playlist.keyIndex(key))
+	case strings.HasPrefix(line, "#EXTINF:"):
+		value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+		duration, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return
+		}
+		if playlist.Segments == 0 || duration < playlist.MinSegmentDuration {
+			playlist.MinSegmentDuration = duration
+		}
+		playlist.MaxSegmentDuration = max(playlist.MaxSegmentDuration, duration)
+		playlist.TotalDuration += duration
+		playlist.Segments++
+
+		for _, i := range p.keys {
+			playlist.Encryption[i].Segments++
+		}
+		if len(p.keys) > 0 {
+			playlist.EncryptedSegments++
+		}
+		p.keysApplied = true
+	case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+		p.byteRange = true
+	case line != "" && !strings.HasPrefix(line, "#"):
+		p.segmentURIs++
+		if p.byteRange {
+			p.byteRangeURIs++
+		}
+		if p.firstURI == "" {
+			p.firstURI = line
+		}
+		p.sameURI = p.sameURI && line == p.firstURI
+		p.byteRange = false
+	}
+}
+
+// isMediaPlaylist reports whether the parsed lines are an HLS media
+// playlist, as isHLSMediaPlaylist
+func (p *mediaPlaylistParser) isMediaPlaylist() bool {
+	return p.segmentTag && !p.variantTag
+}
+
+// finish returns the summary of the parsed lines
+func (p *mediaPlaylistParser) finish() *MediaPlaylist {
+	playlist := p.playlist
+	playlist.ByteRangeSegments = p.byteRangeURIs
+	playlist.SingleFile = p.segmentURIs > 0 && p.byteRangeURIs == p.segmentURIs && p.sameURI
+
+	playlist.PlaylistType = p.playlistType
+	switch {
+	case p.playlistType == MediaPlaylistEvent:
+		playlist.Type = MediaPlaylistEvent
+	case p.playlistType == MediaPlaylistVOD || playlist.Ended:
+		playlist.Type = MediaPlaylistVOD
+	default:
+		playlist.Type = MediaPlaylistLive
+	}
+	return playlist
+}
+
+// keyIndex returns the index of key in Encryption, adding it if new
+func (p *MediaPlaylist) keyIndex(key MediaPlaylistKey) int {
+	for i, existing := range p.Encryption {
+		if existing.Method == key.Method && existing.URI == key.URI && existing.KeyFormat == key.KeyFormat && existing.IV == key.IV {
+			return i
+		}
+	}
+	p.Encryption = append(p.Encryption, key)
+	return len(p.Encryption) - 1
+}
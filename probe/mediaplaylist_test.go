@@ -0,0 +1,167 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHLSMediaPlaylist(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected *MediaPlaylist
+	}{
+		{
+			name: "vod",
+			content: `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-TARGETDURATION:6
+#EXTINF:6.000,
+seg0.ts
+#EXTINF:5.500,
+seg1.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:4.000,title
+seg2.ts
+#EXT-X-ENDLIST
+`,
+			expected: &MediaPlaylist{
+				Type:               MediaPlaylistVOD,
+				PlaylistType:       MediaPlaylistVOD,
+				Ended:              true,
+				TargetDuration:     6,
+				Segments:           3,
+				TotalDuration:      15.5,
+				MinSegmentDuration: 4,
+				MaxSegmentDuration: 6,
+				Discontinuities:    1,
+			},
+		},
+		{
+			name: "event",
+			content: `#EXTM3U
+#EXT-X-PLAYLIST-TYPE:EVENT
+#EXT-X-TARGETDURATION:4
+#EXTINF:4.0,
+seg0.ts
+`,
+			expected: &MediaPlaylist{
+				Type:               MediaPlaylistEvent,
+				PlaylistType:       MediaPlaylistEvent,
+				TargetDuration:     4,
+				Segments:           1,
+				TotalDuration:      4,
+				MinSegmentDuration: 4,
+				MaxSegmentDuration: 4,
+			},
+		},
+		{
+			name: "live with key rotation",
+			content: `#EXTM3U
+#EXT-X-TARGETDURATION:2
+#EXT-X-MEDIA-SEQUENCE:1200
+#EXTINF:2.0,
+clear.ts
+#EXT-X-KEY:METHOD=AES-128,URI="keys/1.key"
+#EXTINF:2.0,
+enc1.ts
+#EXTINF:2.0,
+enc2.ts
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://asset",KEYFORMAT="com.apple.streamingkeydelivery",IV=0x00000000000000000000000000000001
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:text/plain;base64,AAAA",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"
+#EXTINF:2.0,
+drm.ts
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:2.0,
+clear2.ts
+`,
+			expected: &MediaPlaylist{
+				Type:               MediaPlaylistLive,
+				TargetDuration:     2,
+				MediaSequence:      1200,
+				Segments:           5,
+				TotalDuration:      10,
+				MinSegmentDuration: 2,
+				MaxSegmentDuration: 2,
+				Encryption: []MediaPlaylistKey{
+					{Method: "AES-128", URI: "https://example.com/live/keys/1.key", Segments: 2},
+					{Method: "SAMPLE-AES", URI: "skd://asset", KeyFormat: "com.apple.streamingkeydelivery", IV: true, Segments: 1},
+					{Method: "SAMPLE-AES", URI: "data:text/plain;base64,AAAA", KeyFormat: "urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed", Segments: 1},
+				},
+				EncryptedSegments: 3,
+			},
+		},
+		{
+			name: "single file byte ranges",
+			content: `#EXTM3U
+#EXT-X-VERSION:4
+#EXT-X-TARGETDURATION:6
+#EXT-X-MAP:URI="main.mp4",BYTERANGE="720@0"
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:500000@720
+main.mp4
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:480000
+main.mp4
+#EXT-X-ENDLIST
+`,
+			expected: &MediaPlaylist{
+				Type:               MediaPlaylistVOD,
+				Ended:              true,
+				TargetDuration:     6,
+				Segments:           2,
+				TotalDuration:      12,
+				MinSegmentDuration: 6,
+				MaxSegmentDuration: 6,
+				ByteRangeSegments:  2,
+				SingleFile:         true,
+			},
+		},
+		{
+			name: "byte ranges of several files",
+			content: `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-BYTERANGE:500000@0
+#EXTINF:6.0,
+part1.ts
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:500000@0
+part2.ts
+#EXTINF:6.0,
+seg3.ts
+`,
+			expected: &MediaPlaylist{
+				Type:               MediaPlaylistLive,
+				TargetDuration:     6,
+				Segments:           3,
+				TotalDuration:      18,
+				MinSegmentDuration: 6,
+				MaxSegmentDuration: 6,
+				ByteRangeSegments:  2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseHLSManifest(tt.content, "https://example.com/live/media.m3u8")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.MediaPlaylist, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, output.MediaPlaylist)
+			}
+		})
+	}
+}
+
+func TestParseHLSMasterPlaylistIsNotMedia(t *testing.T) {
+	output, err := parseHLSManifest(speculationTestMaster, "https://example.com/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.MediaPlaylist != nil {
+		t.Errorf("Expected no media playlist summary for a master playlist, got %+v", output.MediaPlaylist)
+	}
+}
@@ -0,0 +1,450 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Job defaults
+const (
+	// DefaultJobInterval is the interval of jobs that do not set one
+	DefaultJobInterval = time.Minute
+	// DefaultJobFailureThreshold is the number of consecutive probe failures
+	// that open a job's circuit
+	DefaultJobFailureThreshold = 3
+	// DefaultJobCircuitReset is how long an open job circuit skips runs
+	DefaultJobCircuitReset = 5 * time.Minute
+)
+
+// EventExpectationsFailed is the event of the alert raised when a monitor job
+// probes its manifest but the output misses its expectations
+const EventExpectationsFailed EventType = "expectations_failed"
+
+// Job is a recurring probe of the config file, run by a Monitor
+type Job struct {
+	// Name identifies the job in results and logs (default: the URL)
+	Name string `json:"name,omitempty"`
+
+	URL string `json:"url"`
+
+	// Profile names the options profile of the config file used to probe
+	// the URL ("" = default options)
+	Profile string `json:"profile,omitempty"`
+
+	// Interval between probes as a Go duration, e.g. "30s" ("" = DefaultJobInterval)
+	Interval string `json:"interval,omitempty"`
+
+	// Expectations are checked against every probe output (nil = none)
+	Expectations *Expectations `json:"expectations,omitempty"`
+
+	// Alerts are alert sink specs (see ParseAlertSink) notified when the job
+	// starts failing and when it recovers
+	Alerts []string `json:"alerts,omitempty"`
+
+	// Sinks are output sink specs (see ParseSink) receiving every probe record
+	Sinks []string `json:"sinks,omitempty"`
+
+	// FailureThreshold is the number of consecutive probe failures that open
+	// the job's circuit (0 = DefaultJobFailureThreshold)
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+
+	// CircuitReset is how long an open circuit skips runs before one trial
+	// probe, as a Go duration ("" = DefaultJobCircuitReset)
+	CircuitReset string `json:"circuit_reset,omitempty"`
+}
+
+// JobStatus is the outcome of a job run
+type JobStatus string
+
+const (
+	// JobOK is a run whose probe succeeded and met the expectations
+	JobOK JobStatus = "ok"
+	// JobFailed is a run whose probe failed
+	JobFailed JobStatus = "failed"
+	// JobExpectationsFailed is a run whose output missed expectations
+	JobExpectationsFailed JobStatus = "expectations_failed"
+	// JobSkipped is a run skipped because the job's circuit is open
+	JobSkipped JobStatus = "skipped"
+)
+
+// JobResult is the outcome of one run of a job
+type JobResult struct {
+	Job string `json:"job"`
+	// URL is the job URL with its secrets redacted
+	URL      string        `json:"url"`
+	Status   JobStatus     `json:"status"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Findings are the expectation findings that did not pass (JobExpectationsFailed)
+	Findings []Finding `json:"findings,omitempty"`
+
+	// Error is the probe failure (JobFailed), or why the run was skipped (JobSkipped)
+	Error string `json:"error,omitempty"`
+}
+
+// MonitorOptions configures a Monitor
+type MonitorOptions struct {
+	// Clock schedules the runs and times the job circuits (nil = system clock)
+	Clock Clock
+
+	// Rand randomizes the start of each job within its first interval, so
+	// that jobs loaded together do not probe at once (nil = math/rand)
+	Rand Rand
+
+	// OnResult is called after every run (nil = none). Jobs run
+	// concurrently, so it must be safe for concurrent use.
+	OnResult func(JobResult)
+}
+
+// Monitor runs the jobs of a config file, so that a single process can
+// monitor a small channel lineup: each job probes its URL at its interval,
+// checks its expectations, writes its sinks and alerts on failure and
+// recovery. A job whose probes keep failing opens its circuit and skips runs
+// until its reset timeout, instead of hammering a dead origin.
+type Monitor struct {
+	jobs  []*monitorJob
+	clock Clock
+	rand  Rand
+	opts  MonitorOptions
+}
+
+// monitorJob is a job with its parsed settings and run state
+type monitorJob struct {
+	job      Job
+	name     string
+	interval time.Duration
+	prober   *Prober
+	breaker  *CircuitBreaker
+	alerts   []AlertSink
+	sinks    []OutputSink
+	clock    Clock
+
+	mu   sync.Mutex
+	last JobResult
+
+	// lastSuccess is the time of the latest successful probe, lastFailure
+	// the latest failed run
+	lastSuccess time.Time
+	lastFailure JobResult
+
+	// runs counts the runs by status
+	runs map[JobStatus]uint64
+
+	// cache aggregates the cache status of the manifest responses, the latest in lastCache
+	cache     CacheStats
+	lastCache *CacheStatus
+
+	// alerted is the event of the open alert until recovery ("" = none)
+	alerted EventType
+}
+
+// NewMonitor creates a monitor of the jobs of config. Jobs sharing a profile
+// share a Prober.
+func NewMonitor(config *Config, opts *MonitorOptions) (*Monitor, error) {
+	if config == nil || len(config.Jobs) == 0 {
+		return nil, NewValidationError("config has no jobs")
+	}
+
+	m := &Monitor{}
+	if opts != nil {
+		m.opts = *opts
+	}
+	m.clock = clockOrSystem(m.opts.Clock)
+	m.rand = randOrGlobal(m.opts.Rand)
+
+	probers := make(map[string]*Prober)
+	names := make(map[string]bool)
+	for i, job := range config.Jobs {
+		j, err := newMonitorJob(config, job, probers, m.clock)
+		if err != nil {
+			m.Close()
+			message := err.Error()
+			var probeErr *ProbeError
+			if errors.As(err, &probeErr) {
+				message = probeErr.Message
+			}
+			return nil, NewValidationError(fmt.Sprintf("job %d: %s", i+1, message))
+		}
+		if names[j.name] {
+			j.close()
+			m.Close()
+			return nil, NewValidationError(fmt.Sprintf("job %d: duplicate job name %q", i+1, j.name))
+		}
+		names[j.name] = true
+		m.jobs = append(m.jobs, j)
+	}
+	return m, nil
+}
+
+// newMonitorJob parses the settings of job
+func newMonitorJob(config *Config, job Job, probers map[string]*Prober, clock Clock) (*monitorJob, error) {
+	if job.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	j := &monitorJob{job: job, name: job.Name, clock: clock}
+
+	var err error
+	if j.interval, err = jobDuration(job.Interval, DefaultJobInterval); err != nil {
+		return nil, fmt.Errorf("invalid interval: %w", err)
+	}
+	reset, err := jobDuration(job.CircuitReset, DefaultJobCircuitReset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid circuit_reset: %w", err)
+	}
+	threshold := job.FailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultJobFailureThreshold
+	}
+	j.breaker = NewCircuitBreaker(&CircuitBreakerConfig{
+		Enabled:             true,
+		FailureThreshold:    threshold,
+		ResetTimeout:        reset,
+		HalfOpenMaxRequests: 1,
+		Clock:               clock,
+	})
+
+	if j.prober = probers[job.Profile]; j.prober == nil {
+		var opts *ProbeOptions
+		if job.Profile != "" {
+			if opts, err = config.Profile(job.Profile); err != nil {
+				return nil, err
+			}
+		}
+		j.prober = NewProber(opts)
+		probers[job.Profile] = j.prober
+	}
+
+	// The name is served by the health endpoints and in alerts, so a job
+	// without one is named after its redacted URL
+	if j.name == "" {
+		j.name = j.prober.redactor.redactString(job.URL)
+	}
+
+	for _, spec := range job.Alerts {
+		sink, err := ParseAlertSink(spec)
+		if err != nil {
+			return nil, err
+		}
+		j.alerts = append(j.alerts, sink)
+	}
+	for _, spec := range job.Sinks {
+		sink, err := ParseSink(spec)
+		if err != nil {
+			j.close()
+			return nil, err
+		}
+		j.sinks = append(j.sinks, sink)
+	}
+	return j, nil
+}
+
+// jobDuration parses a job duration, returning fallback when value is empty
+func jobDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s is not positive", value)
+	}
+	return d, nil
+}
+
+// Run runs the jobs until ctx is cancelled and returns ctx.Err(). The first
+// run of each job starts at a random point of its first interval.
+func (m *Monitor) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, j := range m.jobs {
+		wg.Add(1)
+		go func(j *monitorJob, delay time.Duration) {
+			defer wg.Done()
+			m.runJob(withLogger(ctx, j.prober.logger), j, delay)
+		}(j, time.Duration(m.rand.Float64()*float64(j.interval)))
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// runJob runs j after delay, then at its interval until ctx is cancelled
+func (m *Monitor) runJob(ctx context.Context, j *monitorJob, delay time.Duration) {
+	logInfo(ctx, "Scheduling monitor job", map[string]interface{}{
+		"job":      j.name,
+		"url":      j.prober.redactor.redactString(j.job.URL),
+		"interval": j.interval,
+		"delay":    delay,
+	})
+
+	select {
+	case <-m.clock.After(delay):
+	case <-ctx.Done():
+		return
+	}
+
+	ticker := m.clock.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		result := j.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if m.opts.OnResult != nil {
+			m.opts.OnResult(result)
+		}
+
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Results returns the latest result of each job, in config order. Jobs that
+// have not run yet have a zero Time and no Status.
+func (m *Monitor) Results() []JobResult {
+	results := make([]JobResult, len(m.jobs))
+	for i, j := range m.jobs {
+		j.mu.Lock()
+		results[i] = j.last
+		j.mu.Unlock()
+		if results[i].Job == "" {
+			results[i].Job, results[i].URL = j.name, j.prober.redactor.redactString(j.job.URL)
+		}
+	}
+	return results
+}
+
+// Close closes the sinks of the jobs that hold files
+func (m *Monitor) Close() {
+	for _, j := range m.jobs {
+		j.close()
+	}
+}
+
+// close closes the job's sinks that hold files
+func (j *monitorJob) close() {
+	for _, sink := range j.sinks {
+		if closer, ok := sink.(interface{ Close() error }); ok {
+			closer.Close()
+		}
+	}
+}
+
+// run probes the job's URL once through its circuit, then records, writes
+// and alerts the result
+func (j *monitorJob) run(ctx context.Context) JobResult {
+	result := JobResult{Job: j.name, URL: j.prober.redactor.redactString(j.job.URL), Time: j.clock.Now()}
+
+	var output *Output
+	ran := false
+	err := j.breaker.Execute(ctx, func() error {
+		ran = true
+		var err error
+		output, err = j.prober.Probe(ctx, j.job.URL)
+		return err
+	})
+	if ran {
+		result.Duration = j.clock.Now().Sub(result.Time)
+	}
+
+	switch {
+	case !ran:
+		result.Status = JobSkipped
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = JobFailed
+		result.Error = j.prober.redactor.redactString(err.Error())
+	default:
+		result.Status = JobOK
+		if j.job.Expectations != nil {
+			if failed := FailedFindings(j.job.Expectations.Evaluate(output)); len(failed) > 0 {
+				result.Status = JobExpectationsFailed
+				result.Findings = failed
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return result
+	}
+
+	j.record(result)
+	if output != nil {
+		j.observeCache(output.Cache)
+	}
+
+	if result.Status != JobSkipped {
+		record := probeRecord(j.prober.redactor.redactString(j.job.URL), result.Time, result.Duration, output, nil)
+		record.Error = result.Error
+		writeSinks(ctx, j.sinks, j.prober.redactor, record)
+		j.alert(ctx, result)
+	}
+
+	logInfo(ctx, "Monitor job ran", map[string]interface{}{
+		"job":    j.name,
+		"status": string(result.Status),
+	})
+	return result
+}
+
+// alert notifies the job's alert sinks when it starts failing, fails
+// differently, or recovers. A failing job raises one alert until it
+// recovers, rather than one per run.
+func (j *monitorJob) alert(ctx context.Context, result JobResult) {
+	if len(j.alerts) == 0 {
+		return
+	}
+
+	alert := Alert{
+		URL:  j.prober.redactor.redactString(j.job.URL),
+		Time: result.Time,
+	}
+	switch result.Status {
+	case JobFailed:
+		alert.Event, alert.Severity = EventErrorOccurred, AlertCritical
+		alert.Summary = fmt.Sprintf("job %s: probe failed: %s", j.name, result.Error)
+	case JobExpectationsFailed:
+		alert.Event, alert.Severity = EventExpectationsFailed, AlertWarning
+		alert.Summary = fmt.Sprintf("job %s: %d expectations failed", j.name, len(result.Findings))
+		if len(result.Findings) == 1 {
+			alert.Summary = fmt.Sprintf("job %s: %s: %s", j.name, result.Findings[0].Expectation, result.Findings[0].Message)
+		}
+	case JobOK:
+		if j.alerted == "" {
+			return
+		}
+		alert.Event, alert.Severity, alert.Resolved = EventRecovered, AlertInfo, true
+		alert.Summary = fmt.Sprintf("job %s: recovered", j.name)
+	default:
+		return
+	}
+
+	if alert.Event == j.alerted {
+		return
+	}
+	j.alerted = alert.Event
+	if alert.Resolved {
+		j.alerted = ""
+	}
+
+	for _, sink := range j.alerts {
+		sendCtx, cancel := context.WithTimeout(ctx, alertTimeout)
+		err := sink.Send(sendCtx, alert)
+		cancel()
+		if err != nil {
+			logWarn(ctx, "Alert delivery failed", map[string]interface{}{
+				"job":   j.name,
+				"event": string(alert.Event),
+				"error": j.prober.redactor.redactString(err.Error()),
+			})
+		}
+	}
+}
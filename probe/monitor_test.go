@@ -0,0 +1,202 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const monitorSubtitledManifest = `#EXTM3U
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",LANGUAGE="en",URI="subs/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2",SUBTITLES="subs"
+720p.m3u8
+`
+
+func TestMonitorJobRun(t *testing.T) {
+	var body atomic.Value
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		manifest := body.Load().(string)
+		if manifest == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(manifest))
+	}))
+	defer server.Close()
+
+	clock := NewManualClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	config := &Config{Jobs: []Job{{
+		Name:             "news",
+		URL:              server.URL + "/master.m3u8",
+		Expectations:     &Expectations{Streams: []StreamExpectation{{Type: "Subtitle"}}},
+		FailureThreshold: 2,
+		CircuitReset:     "1m",
+	}}}
+	monitor, err := NewMonitor(config, &MonitorOptions{Clock: clock})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	job := monitor.jobs[0]
+	sink := &recordingSink{}
+	job.alerts = []AlertSink{sink}
+
+	steps := []struct {
+		manifest string
+		advance  time.Duration
+		status   JobStatus
+		probed   bool
+	}{
+		{manifest: watchTestManifest, status: JobExpectationsFailed, probed: true},
+		{manifest: "", status: JobFailed, probed: true},
+		{manifest: "", status: JobFailed, probed: true},
+		{manifest: monitorSubtitledManifest, status: JobSkipped},
+		{manifest: monitorSubtitledManifest, advance: 2 * time.Minute, status: JobOK, probed: true},
+		{manifest: monitorSubtitledManifest, status: JobOK, probed: true},
+	}
+	for i, step := range steps {
+		body.Store(step.manifest)
+		clock.Advance(step.advance)
+		before := requests.Load()
+		result := job.run(context.Background())
+		if result.Status != step.status {
+			t.Errorf("Run %d: expected %q, got %q (%s)", i, step.status, result.Status, result.Error)
+		}
+		if probed := requests.Load() != before; probed != step.probed {
+			t.Errorf("Run %d: expected probed %v, got %v", i, step.probed, probed)
+		}
+	}
+
+	alerts := sink.received()
+	expected := []EventType{EventExpectationsFailed, EventErrorOccurred, EventRecovered}
+	if len(alerts) != len(expected) {
+		t.Fatalf("Expected %d alerts, got %+v", len(expected), alerts)
+	}
+	for i, event := range expected {
+		if alerts[i].Event != event {
+			t.Errorf("Alert %d: expected %q, got %q", i, event, alerts[i].Event)
+		}
+	}
+	if alerts[0].Severity != AlertWarning || !strings.Contains(alerts[0].Summary, "job news") {
+		t.Errorf("Unexpected expectations alert %+v", alerts[0])
+	}
+	if !alerts[2].Resolved {
+		t.Errorf("Expected the recovery alert to be resolved")
+	}
+
+	if results := monitor.Results(); results[0].Status != JobOK || results[0].Job != "news" {
+		t.Errorf("Unexpected results %+v", results)
+	}
+}
+
+func TestMonitorJobRedaction(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	config := &Config{Jobs: []Job{{URL: server.URL + "/master.m3u8?token=secret"}}}
+	monitor, err := NewMonitor(config, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	job := monitor.jobs[0]
+	sink := &recordingOutputSink{}
+	job.sinks = []OutputSink{sink}
+
+	if results := monitor.Results(); strings.Contains(results[0].URL, "secret") {
+		t.Errorf("Expected a redacted URL before the first run, got %q", results[0].URL)
+	}
+	result := job.run(context.Background())
+	if result.Status != JobFailed {
+		t.Fatalf("Expected %q, got %q", JobFailed, result.Status)
+	}
+	if strings.Contains(result.URL, "secret") || !strings.Contains(result.URL, "/master.m3u8") {
+		t.Errorf("Expected a redacted result URL, got %q", result.URL)
+	}
+	if results := monitor.Results(); strings.Contains(results[0].URL, "secret") {
+		t.Errorf("Expected a redacted result URL, got %q", results[0].URL)
+	}
+	if strings.Contains(job.name, "secret") {
+		t.Errorf("Expected a redacted default job name, got %q", job.name)
+	}
+	records := sink.recorded()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if strings.Contains(records[0].URL, "secret") || strings.Contains(records[0].Error, "secret") || records[0].Error == "" {
+		t.Errorf("Expected a redacted record with an error, got %+v", records[0])
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	config := &Config{Jobs: []Job{
+		{Name: "one", URL: server.URL + "/one.m3u8", Interval: "10ms"},
+		{Name: "two", URL: server.URL + "/two.m3u8", Interval: "10ms"},
+	}}
+	monitor, err := NewMonitor(config, &MonitorOptions{OnResult: func(result JobResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs[result.Job]++
+		if runs["one"] >= 3 && runs["two"] >= 3 {
+			cancel()
+		}
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer monitor.Close()
+
+	if err := monitor.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the jobs to run until cancelled, got %v", err)
+	}
+	for _, result := range monitor.Results() {
+		if result.Status != JobOK || result.Time.IsZero() {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	}
+}
+
+func TestNewMonitorValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		jobs     []Job
+		expected string
+	}{
+		{name: "no jobs", expected: "config has no jobs"},
+		{name: "missing URL", jobs: []Job{{Name: "a"}}, expected: "job 1: url is required"},
+		{name: "invalid interval", jobs: []Job{{URL: "https://example.com/a.m3u8", Interval: "often"}}, expected: "job 1: invalid interval"},
+		{name: "negative reset", jobs: []Job{{URL: "https://example.com/a.m3u8", CircuitReset: "-1m"}}, expected: "job 1: invalid circuit_reset: -1m is not positive"},
+		{name: "unknown profile", jobs: []Job{{URL: "https://example.com/a.m3u8", Profile: "staging"}}, expected: `validation: job 1: unknown profile "staging"`},
+		{name: "unknown alert sink", jobs: []Job{{URL: "https://example.com/a.m3u8", Alerts: []string{"irc://ops"}}}, expected: `unknown alert sink "irc://ops"`},
+		{
+			name:     "duplicate name",
+			jobs:     []Job{{URL: "https://example.com/a.m3u8"}, {URL: "https://example.com/a.m3u8"}},
+			expected: `job 2: duplicate job name "https://example.com/a.m3u8"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMonitor(&Config{Jobs: tt.jobs}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
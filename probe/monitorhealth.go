@@ -0,0 +1,232 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// freshIntervals is the number of job intervals after which a manifest
+// without a successful probe is no longer fresh
+const freshIntervals = 2
+
+// circuitStateNames names circuit states in health reports
+var circuitStateNames = map[CircuitState]string{
+	CircuitStateClosed:   "closed",
+	CircuitStateOpen:     "open",
+	CircuitStateHalfOpen: "half_open",
+}
+
+// JobHealth is the state of a monitor job, as served on /streams
+type JobHealth struct {
+	Job string `json:"job"`
+
+	// URL is the manifest URL, with sensitive parameters redacted
+	URL      string        `json:"url"`
+	Interval time.Duration `json:"interval"`
+
+	// Status is the status of the latest run ("" before the first run)
+	Status   JobStatus     `json:"status,omitempty"`
+	LastRun  time.Time     `json:"last_run,omitzero"`
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Findings are the failed expectations of the latest run
+	Findings []Finding `json:"findings,omitempty"`
+
+	// Circuit is the state of the job's circuit: closed, open or half_open
+	Circuit string `json:"circuit"`
+
+	// LastSuccess is the time of the latest successful probe, and Age the
+	// time since then. A manifest is fresh when it was probed successfully
+	// within two intervals.
+	LastSuccess time.Time     `json:"last_success,omitzero"`
+	Age         time.Duration `json:"age,omitempty"`
+	Fresh       bool          `json:"fresh"`
+
+	// LastError is the latest probe failure, kept after recovery
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorTime time.Time `json:"last_error_time,omitzero"`
+
+	// Runs counts the runs by status
+	Runs map[JobStatus]uint64 `json:"runs,omitempty"`
+
+	// Cache is the CDN cache behavior of the manifest over the successful
+	// probes: hit ratio and TTL
+	Cache *CacheStats `json:"cache,omitempty"`
+}
+
+// record stores result as the job's latest run
+func (j *monitorJob) record(result JobResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.last = result
+	if j.runs == nil {
+		j.runs = make(map[JobStatus]uint64)
+	}
+	j.runs[result.Status]++
+	switch result.Status {
+	case JobOK, JobExpectationsFailed:
+		j.lastSuccess = result.Time
+	case JobFailed:
+		j.lastFailure = result
+	}
+}
+
+// observeCache adds the cache status of a manifest response to the job's stats
+func (j *monitorJob) observeCache(status *CacheStatus) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.cache.observe(status, j.lastCache)
+	j.lastCache = status
+}
+
+// health returns the state of the job at now
+func (j *monitorJob) health(now time.Time) JobHealth {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	health := JobHealth{
+		Job:           j.name,
+		URL:           j.prober.redactor.redactString(j.job.URL),
+		Interval:      j.interval,
+		Status:        j.last.Status,
+		LastRun:       j.last.Time,
+		Duration:      j.last.Duration,
+		Findings:      j.last.Findings,
+		Circuit:       circuitStateNames[j.breaker.GetState()],
+		LastSuccess:   j.lastSuccess,
+		LastError:     j.lastFailure.Error,
+		LastErrorTime: j.lastFailure.Time,
+	}
+	if !j.lastSuccess.IsZero() {
+		health.Age = now.Sub(j.lastSuccess)
+		health.Fresh = health.Age <= freshIntervals*j.interval
+	}
+	if len(j.runs) > 0 {
+		health.Runs = make(map[JobStatus]uint64, len(j.runs))
+		for status, count := range j.runs {
+			health.Runs[status] = count
+		}
+	}
+	if j.cache.Responses > 0 {
+		health.Cache = j.cache.clone()
+	}
+	return health
+}
+
+// Health returns the state of each job, in config order
+func (m *Monitor) Health() []JobHealth {
+	now := m.clock.Now()
+	health := make([]JobHealth, len(m.jobs))
+	for i, j := range m.jobs {
+		health[i] = j.health(now)
+	}
+	return health
+}
+
+// Handler serves the state of the jobs for dashboards:
+//
+//	GET /streams   the Health of every job as JSON
+//	GET /metrics   the same state in the Prometheus text format
+func (m *Monitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /streams", func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"time":    m.clock.Now(),
+			"streams": m.Health(),
+		}, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(m.metrics()))
+	})
+	return mux
+}
+
+// metrics renders the state of the jobs in the Prometheus text format. Jobs
+// are labelled "stream" since Prometheus reserves the "job" label.
+func (m *Monitor) metrics() string {
+	health := m.Health()
+	var b strings.Builder
+
+	gauge := func(name, help string, value func(h JobHealth) (float64, bool)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, h := range health {
+			if v, ok := value(h); ok {
+				fmt.Fprintf(&b, "%s{stream=%s} %g\n", name, promLabel(h.Job), v)
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "# HELP goprobe_stream_info Monitored manifest of each stream.\n# TYPE goprobe_stream_info gauge\n")
+	for _, h := range health {
+		fmt.Fprintf(&b, "goprobe_stream_info{stream=%s,url=%s} 1\n", promLabel(h.Job), promLabel(h.URL))
+	}
+	gauge("goprobe_stream_up", "Whether the latest probe of the stream succeeded.", func(h JobHealth) (float64, bool) {
+		return promBool(h.Status == JobOK || h.Status == JobExpectationsFailed), h.Status != ""
+	})
+	gauge("goprobe_stream_expectations_met", "Whether the latest probe output met the stream's expectations.", func(h JobHealth) (float64, bool) {
+		return promBool(h.Status == JobOK), h.Status == JobOK || h.Status == JobExpectationsFailed
+	})
+	gauge("goprobe_stream_fresh", "Whether the stream was probed successfully within two intervals.", func(h JobHealth) (float64, bool) {
+		return promBool(h.Fresh), true
+	})
+	gauge("goprobe_stream_last_success_timestamp_seconds", "Time of the latest successful probe of the stream.", func(h JobHealth) (float64, bool) {
+		return float64(h.LastSuccess.UnixNano()) / 1e9, !h.LastSuccess.IsZero()
+	})
+	gauge("goprobe_stream_probe_duration_seconds", "Duration of the latest probe of the stream.", func(h JobHealth) (float64, bool) {
+		return h.Duration.Seconds(), h.Duration > 0
+	})
+	gauge("goprobe_stream_cache_hit_ratio", "CDN cache hit ratio of the stream's manifest responses.", func(h JobHealth) (float64, bool) {
+		if h.Cache == nil || h.Cache.Hits+h.Cache.Misses == 0 {
+			return 0, false
+		}
+		return h.Cache.HitRatio, true
+	})
+	gauge("goprobe_stream_cache_max_age_seconds", "Highest Age of the stream's manifest responses.", func(h JobHealth) (float64, bool) {
+		if h.Cache == nil || h.Cache.MaxObservedAge == nil {
+			return 0, false
+		}
+		return float64(*h.Cache.MaxObservedAge), true
+	})
+	gauge("goprobe_stream_circuit_open", "Whether the stream's circuit is open and its runs are skipped.", func(h JobHealth) (float64, bool) {
+		return promBool(h.Circuit == "open"), true
+	})
+
+	fmt.Fprintf(&b, "# HELP goprobe_stream_runs_total Runs of the stream by status.\n# TYPE goprobe_stream_runs_total counter\n")
+	for _, h := range health {
+		statuses := make([]string, 0, len(h.Runs))
+		for status := range h.Runs {
+			statuses = append(statuses, string(status))
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "goprobe_stream_runs_total{stream=%s,status=%s} %d\n", promLabel(h.Job), promLabel(status), h.Runs[JobStatus(status)])
+		}
+	}
+	return b.String()
+}
+
+// promLabel quotes a Prometheus label value
+func promLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// promBool returns 1 for true and 0 for false
+func promBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMonitorHealth(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	clock := NewManualClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	config := &Config{Jobs: []Job{
+		{Name: "news", URL: server.URL + "/news.m3u8?token=secret", Interval: "10s"},
+		{Name: "sports", URL: server.URL + "/sports.m3u8", Interval: "10s"},
+	}}
+	monitor, err := NewMonitor(config, &MonitorOptions{Clock: clock})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	news := monitor.jobs[0]
+	news.run(context.Background())
+	clock.Advance(15 * time.Second)
+	failing.Store(true)
+	news.run(context.Background())
+	clock.Advance(10 * time.Second)
+
+	health := monitor.Health()
+	if len(health) != 2 {
+		t.Fatalf("Expected 2 jobs, got %+v", health)
+	}
+
+	h := health[0]
+	if h.Status != JobFailed || h.Circuit != "closed" || h.LastError == "" {
+		t.Errorf("Unexpected health %+v", h)
+	}
+	if h.Age != 25*time.Second || h.Fresh {
+		t.Errorf("Expected a stale manifest probed 25s ago, got %+v", h)
+	}
+	if h.Runs[JobOK] != 1 || h.Runs[JobFailed] != 1 {
+		t.Errorf("Unexpected runs %v", h.Runs)
+	}
+	if strings.Contains(h.URL, "secret") {
+		t.Errorf("Expected a redacted URL, got %q", h.URL)
+	}
+
+	if pending := health[1]; pending.Status != "" || pending.Fresh || !pending.LastSuccess.IsZero() {
+		t.Errorf("Expected a job that has not run, got %+v", pending)
+	}
+
+	failing.Store(false)
+	news.run(context.Background())
+	if h := monitor.Health()[0]; !h.Fresh || h.Age != 0 || h.LastError == "" {
+		t.Errorf("Expected a fresh manifest keeping its last error, got %+v", h)
+	}
+}
+
+func TestMonitorHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	clock := NewManualClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	config := &Config{Jobs: []Job{
+		{Name: `news "HD"`, URL: server.URL + "/news.m3u8", Expectations: &Expectations{Streams: []StreamExpectation{{Type: "Subtitle"}}}},
+		{Name: "sports", URL: server.URL + "/sports.m3u8"},
+	}}
+	monitor, err := NewMonitor(config, &MonitorOptions{Clock: clock})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	monitor.jobs[0].run(context.Background())
+
+	handler := httptest.NewServer(monitor.Handler())
+	defer handler.Close()
+
+	response, err := http.Get(handler.URL + "/streams")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var streams struct {
+		Streams []JobHealth `json:"streams"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&streams)
+	response.Body.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(streams.Streams) != 2 || streams.Streams[0].Status != JobExpectationsFailed || len(streams.Streams[0].Findings) != 1 {
+		t.Errorf("Unexpected streams %+v", streams.Streams)
+	}
+
+	response, err = http.Get(handler.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	metrics := string(body)
+
+	expected := []string{
+		"# TYPE goprobe_stream_up gauge",
+		`goprobe_stream_up{stream="news \"HD\""} 1`,
+		`goprobe_stream_expectations_met{stream="news \"HD\""} 0`,
+		`goprobe_stream_fresh{stream="news \"HD\""} 1`,
+		`goprobe_stream_fresh{stream="sports"} 0`,
+		`goprobe_stream_circuit_open{stream="sports"} 0`,
+		`goprobe_stream_runs_total{stream="news \"HD\"",status="expectations_failed"} 1`,
+		`goprobe_stream_info{stream="sports",url="` + server.URL + `/sports.m3u8"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, metrics)
+		}
+	}
+	if strings.Contains(metrics, `goprobe_stream_up{stream="sports"}`) {
+		t.Errorf("Expected no up metric for a job that has not run")
+	}
+
+	if response, err := http.Post(handler.URL+"/streams", "application/json", nil); err != nil || response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected POST /streams to be rejected, got %v", err)
+	}
+}
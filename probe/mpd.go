@@ -3,57 +3,156 @@ package probe
 import (
 	"encoding/xml"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // MPD XML structures
 type MPD struct {
-	XMLName                xml.Name `xml:"MPD"`
-	Type                   string   `xml:"type,attr"`
-	AvailabilityStartTime  string   `xml:"availabilityStartTime,attr"`
-	PublishTime            string   `xml:"publishTime,attr"`
-	MinimumUpdatePeriod    string   `xml:"minimumUpdatePeriod,attr"`
-	MinBufferTime          string   `xml:"minBufferTime,attr"`
-	TimeShiftBufferDepth   string   `xml:"timeShiftBufferDepth,attr"`
-	MaxSegmentDuration     string   `xml:"maxSegmentDuration,attr"`
-	Periods                []Period `xml:"Period"`
+	XMLName                    xml.Name  `xml:"MPD"`
+	Type                       string    `xml:"type,attr"`
+	Profiles                   string    `xml:"profiles,attr"`
+	AvailabilityStartTime      string    `xml:"availabilityStartTime,attr"`
+	PublishTime                string    `xml:"publishTime,attr"`
+	MinimumUpdatePeriod        string    `xml:"minimumUpdatePeriod,attr"`
+	MinBufferTime              string    `xml:"minBufferTime,attr"`
+	MediaPresentationDuration  string    `xml:"mediaPresentationDuration,attr"`
+	TimeShiftBufferDepth       string    `xml:"timeShiftBufferDepth,attr"`
+	MaxSegmentDuration         string    `xml:"maxSegmentDuration,attr"`
+	SuggestedPresentationDelay string    `xml:"suggestedPresentationDelay,attr"`
+	BaseURLs                   []BaseURL `xml:"BaseURL"`
+	Periods                    []Period  `xml:"Period"`
 }
 
 type Period struct {
-	ID             string          `xml:"id,attr"`
-	Start          string          `xml:"start,attr"`
-	AdaptationSets []AdaptationSet `xml:"AdaptationSet"`
+	ID              string           `xml:"id,attr"`
+	Start           string           `xml:"start,attr"`
+	Duration        string           `xml:"duration,attr"`
+	BaseURLs        []BaseURL        `xml:"BaseURL"`
+	SegmentBase     *SegmentBase     `xml:"SegmentBase"`
+	SegmentTemplate *SegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *SegmentList     `xml:"SegmentList"`
+	AdaptationSets  []AdaptationSet  `xml:"AdaptationSet"`
 }
 
 type AdaptationSet struct {
-	ID                 string             `xml:"id,attr"`
-	Group              string             `xml:"group,attr"`
-	MimeType           string             `xml:"mimeType,attr"`
-	Lang               string             `xml:"lang,attr"`
-	ContentType        string             `xml:"contentType,attr"`
-	SegmentAlignment   string             `xml:"segmentAlignment,attr"`
-	MaxFrameRate       string             `xml:"maxFrameRate,attr"`
-	FrameRate          string             `xml:"frameRate,attr"`
-	Codecs             string             `xml:"codecs,attr"`
-	EssentialProperty  []EssentialProperty `xml:"EssentialProperty"`
-	Representations    []Representation    `xml:"Representation"`
+	ID                   string              `xml:"id,attr"`
+	Group                string              `xml:"group,attr"`
+	MimeType             string              `xml:"mimeType,attr"`
+	Lang                 string              `xml:"lang,attr"`
+	ContentType          string              `xml:"contentType,attr"`
+	SegmentAlignment     string              `xml:"segmentAlignment,attr"`
+	SubsegmentAlignment  string              `xml:"subsegmentAlignment,attr"`
+	MaxFrameRate         string              `xml:"maxFrameRate,attr"`
+	FrameRate            string              `xml:"frameRate,attr"`
+	Codecs               string              `xml:"codecs,attr"`
+	SAR                  string              `xml:"sar,attr"`
+	PAR                  string              `xml:"par,attr"`
+	EssentialProperty    []EssentialProperty `xml:"EssentialProperty"`
+	SupplementalProperty []EssentialProperty `xml:"SupplementalProperty"`
+	FramePacking         []Descriptor        `xml:"FramePacking"`
+	Roles                []Descriptor        `xml:"Role"`
+	Accessibility        []Descriptor        `xml:"Accessibility"`
+	Labels               []Label             `xml:"Label"`
+	AudioChannels        []Descriptor        `xml:"AudioChannelConfiguration"`
+	ContentProtections   []ContentProtection `xml:"ContentProtection"`
+	BaseURLs             []BaseURL           `xml:"BaseURL"`
+	SegmentBase          *SegmentBase        `xml:"SegmentBase"`
+	SegmentTemplate      *SegmentTemplate    `xml:"SegmentTemplate"`
+	SegmentList          *SegmentList        `xml:"SegmentList"`
+	Representations      []Representation    `xml:"Representation"`
 }
 
 type EssentialProperty struct {
 	SchemeIdUri string `xml:"schemeIdUri,attr"`
 	Value       string `xml:"value,attr"`
+
+	// ProjectionType is the omaf:projection_type of an OMAF projection format property
+	ProjectionType string `xml:"projection_type,attr"`
+}
+
+// Descriptor is a DASH descriptor element such as Role or Accessibility
+type Descriptor struct {
+	SchemeIdUri string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
 }
 
 type Representation struct {
-	ID                 string `xml:"id,attr"`
-	Bandwidth          string `xml:"bandwidth,attr"`
-	Width              string `xml:"width,attr"`
-	Height             string `xml:"height,attr"`
-	FrameRate          string `xml:"frameRate,attr"`
-	Codecs             string `xml:"codecs,attr"`
-	AudioSamplingRate  string `xml:"audioSamplingRate,attr"`
-	SAR                string `xml:"sar,attr"`
+	ID                   string              `xml:"id,attr"`
+	Bandwidth            string              `xml:"bandwidth,attr"`
+	Width                string              `xml:"width,attr"`
+	Height               string              `xml:"height,attr"`
+	FrameRate            string              `xml:"frameRate,attr"`
+	Codecs               string              `xml:"codecs,attr"`
+	AudioSamplingRate    string              `xml:"audioSamplingRate,attr"`
+	SAR                  string              `xml:"sar,attr"`
+	EssentialProperty    []EssentialProperty `xml:"EssentialProperty"`
+	SupplementalProperty []EssentialProperty `xml:"SupplementalProperty"`
+	FramePacking         []Descriptor        `xml:"FramePacking"`
+	AudioChannels        []Descriptor        `xml:"AudioChannelConfiguration"`
+	ContentProtections   []ContentProtection `xml:"ContentProtection"`
+	BaseURLs             []BaseURL           `xml:"BaseURL"`
+	SegmentBase          *SegmentBase        `xml:"SegmentBase"`
+	SegmentTemplate      *SegmentTemplate    `xml:"SegmentTemplate"`
+	SegmentList          *SegmentList        `xml:"SegmentList"`
+}
+
+// BaseURL is an MPD BaseURL element
+type BaseURL struct {
+	URL             string `xml:",chardata"`
+	ServiceLocation string `xml:"serviceLocation,attr"`
+}
+
+// SegmentTemplate is an MPD SegmentTemplate element
+type SegmentTemplate struct {
+	Initialization  string           `xml:"initialization,attr"`
+	Media           string           `xml:"media,attr"`
+	StartNumber     string           `xml:"startNumber,attr"`
+	Duration        string           `xml:"duration,attr"`
+	Timescale       string           `xml:"timescale,attr"`
+	SegmentTimeline *SegmentTimeline `xml:"SegmentTimeline"`
+}
+
+// SegmentTimeline lists the segments of a SegmentTemplate
+type SegmentTimeline struct {
+	Segments []TimelineSegment `xml:"S"`
+}
+
+// TimelineSegment is an S element of a SegmentTimeline
+type TimelineSegment struct {
+	T string `xml:"t,attr"`
+	D string `xml:"d,attr"`
+	R string `xml:"r,attr"`
+}
+
+// SegmentList is an MPD SegmentList element
+type SegmentList struct {
+	Duration       string       `xml:"duration,attr"`
+	Timescale      string       `xml:"timescale,attr"`
+	Initialization *URLType     `xml:"Initialization"`
+	SegmentURLs    []SegmentURL `xml:"SegmentURL"`
+}
+
+// SegmentBase is an MPD SegmentBase element: a single media resource
+// indexed by a Segment Index box (sidx) at IndexRange
+type SegmentBase struct {
+	Timescale      string   `xml:"timescale,attr"`
+	IndexRange     string   `xml:"indexRange,attr"`
+	Initialization *URLType `xml:"Initialization"`
+}
+
+// URLType is an MPD element referencing a resource, such as Initialization
+type URLType struct {
+	SourceURL string `xml:"sourceURL,attr"`
+	Range     string `xml:"range,attr"`
+}
+
+// SegmentURL is a media segment of a SegmentList
+type SegmentURL struct {
+	Media      string `xml:"media,attr"`
+	MediaRange string `xml:"mediaRange,attr"`
 }
 
 // parseMPDManifest parses an MPD manifest and returns stream information
@@ -68,25 +167,72 @@ func parseMPDManifest(content string, manifestURL string) (*Output, error) {
 	var audioStreams []StreamInfo
 	var subtitleStreams []StreamInfo
 
-	for _, period := range mpd.Periods {
-		for _, adaptationSet := range period.AdaptationSets {
+	for periodIndex, period := range mpd.Periods {
+		for adaptationSetIndex, adaptationSet := range period.AdaptationSets {
 			// Skip trick-play streams
 			if isTrickModeStream(adaptationSet) {
 				continue
 			}
 
-			for _, rep := range adaptationSet.Representations {
+			disposition := mpdDisposition(adaptationSet)
+			roles := mpdRoles(adaptationSet)
+			accessibility := mpdAccessibility(adaptationSet)
+			labels := mpdLabels(adaptationSet)
+
+			for representationIndex, rep := range adaptationSet.Representations {
+				element := mpdElementIndex{periodIndex, adaptationSetIndex, representationIndex}
+				urls, baseURLs := mpdStreamURLs(manifestURL, &mpd, period, adaptationSet, rep)
+				source := &SourceRef{
+					Tag:              "Representation",
+					PeriodID:         period.ID,
+					AdaptationSetID:  adaptationSet.ID,
+					RepresentationID: rep.ID,
+				}
+				segments := mpdSegmentInfo(&mpd, periodIndex, adaptationSet, rep)
+
 				switch {
 				case isVideoStream(adaptationSet):
 					stream := createVideoStream(adaptationSet, rep)
+					stream.NativeID = rep.ID
+					stream.Disposition = disposition
+					stream.Roles = roles
+					stream.Accessibility = accessibility
+					stream.Labels = labels
+					stream.Projection = mpdProjection(adaptationSet, rep)
+					stream.StereoMode = mpdStereoMode(adaptationSet, rep)
+					stream.Source = source
+					stream.element = element
+					stream.urls = urls
+					stream.BaseURLs = baseURLs
+					stream.Segments = segments
 					videoStreams = append(videoStreams, stream)
 
 				case isAudioStream(adaptationSet):
 					stream := createAudioStream(adaptationSet, rep)
+					stream.NativeID = rep.ID
+					stream.Disposition = disposition
+					stream.Roles = roles
+					stream.Accessibility = accessibility
+					stream.Labels = labels
+					stream.Source = source
+					stream.element = element
+					stream.urls = urls
+					stream.BaseURLs = baseURLs
+					stream.Segments = segments
 					audioStreams = append(audioStreams, stream)
 
 				case isSubtitleStream(adaptationSet):
 					stream := createSubtitleStream(adaptationSet, rep)
+					stream.NativeID = rep.ID
+					stream.Disposition = disposition
+					stream.Roles = roles
+					stream.Accessibility = accessibility
+					stream.Labels = labels
+					stream.Source = source
+					stream.element = element
+					stream.urls = urls
+					stream.BaseURLs = baseURLs
+					stream.Segments = segments
 					subtitleStreams = append(subtitleStreams, stream)
 				}
 			}
@@ -99,7 +245,34 @@ func parseMPDManifest(content string, manifestURL string) (*Output, error) {
 	streams = append(streams, assignStreamIDs(audioStreams, &streamIndex)...)
 	streams = append(streams, assignStreamIDs(subtitleStreams, &streamIndex)...)
 
-	return &Output{Streams: streams}, nil
+	output := &Output{
+		Streams:        streams,
+		Features:       detectMPDFeatures(content, &mpd),
+		Compatibility:  mpdCompatibility(&mpd, streams),
+		scheduledStart: mpdScheduledStart(&mpd),
+		ended:          mpd.Type != "dynamic",
+	}
+	output.Periods = mpdPeriods(&mpd, streams)
+	output.DRM, output.Warnings = mpdDRM(&mpd, streams)
+
+	if mpd.PublishTime != "" || mpd.MinimumUpdatePeriod != "" {
+		output.Timing = &ManifestTiming{PublishTime: mpd.PublishTime}
+		if period, err := parseISODuration(mpd.MinimumUpdatePeriod); err == nil {
+			output.Timing.MinimumUpdatePeriod = period.Seconds()
+		}
+	}
+
+	// Live players start suggestedPresentationDelay behind the live edge
+	if mpd.SuggestedPresentationDelay != "" {
+		if delay, err := parseISODuration(mpd.SuggestedPresentationDelay); err == nil {
+			output.Start = &StartInfo{
+				Source: "suggestedPresentationDelay",
+				Offset: -delay.Seconds(),
+			}
+		}
+	}
+
+	return output, nil
 }
 
 // Helper functions
@@ -135,12 +308,20 @@ func createVideoStream(adaptationSet AdaptationSet, rep Representation) StreamIn
 	videoCodec := parseVideoCodec(codecString)
 	pixFmt := getPixelFormat(codecString, videoCodec)
 
+	sar := rep.SAR
+	if sar == "" {
+		sar = adaptationSet.SAR
+	}
+	sampleAspect, displayAspect := aspectRatios(rep.Width, rep.Height, sar, adaptationSet.PAR)
+
 	return StreamInfo{
-		Type:       "Video",
-		Codec:      videoCodec,
-		PixFmt:     pixFmt,
-		Resolution: resolution,
-		FrameRate:  frameRate,
+		Type:               "Video",
+		Codec:              videoCodec,
+		PixFmt:             pixFmt,
+		Resolution:         resolution,
+		FrameRate:          frameRate,
+		SampleAspectRatio:  sampleAspect,
+		DisplayAspectRatio: displayAspect,
 	}
 }
 
@@ -161,11 +342,16 @@ func createAudioStream(adaptationSet AdaptationSet, rep Representation) StreamIn
 		}
 	}
 
+	channels := mpdChannelLayout(adaptationSet, rep)
+	if channels == "" {
+		channels = "stereo"
+	}
+
 	return StreamInfo{
 		Type:       "Audio",
 		Codec:      codec,
 		BitRate:    bitRateKbps,
-		Channels:   "stereo",
+		Channels:   channels,
 		SampleFmt:  "fltp",
 		SampleRate: sampleRate,
 		Language:   adaptationSet.Lang,
@@ -233,4 +419,34 @@ func assignStreamIDs(streams []StreamInfo, streamIndex *int) []StreamInfo {
 		*streamIndex++
 	}
 	return streams
-}
\ No newline at end of file
+}
+
+// isoDurationPattern matches xs:duration values such as "PT1H2M3.5S" or "P1DT12H"
+var isoDurationPattern = regexp.MustCompile(`^(-)?P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration parses an ISO 8601 duration as used by MPD attributes.
+// Year and month components are not supported since their length is ambiguous.
+func parseISODuration(value string) (time.Duration, error) {
+	matches := isoDurationPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil || matches[2]+matches[3]+matches[4]+matches[5] == "" {
+		return 0, fmt.Errorf("invalid duration: %q", value)
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var total float64
+	for i, unit := range units {
+		if matches[i+2] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(matches[i+2], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %q", value)
+		}
+		total += n * float64(unit)
+	}
+
+	if matches[1] == "-" {
+		total = -total
+	}
+	return time.Duration(total), nil
+}
@@ -0,0 +1,137 @@
+package probe
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    time.Duration
+		expectError bool
+	}{
+		{
+			name:     "seconds",
+			value:    "PT10S",
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "fractional seconds",
+			value:    "PT1.5S",
+			expected: 1500 * time.Millisecond,
+		},
+		{
+			name:     "hours minutes seconds",
+			value:    "PT1H2M3S",
+			expected: time.Hour + 2*time.Minute + 3*time.Second,
+		},
+		{
+			name:     "days",
+			value:    "P1DT12H",
+			expected: 36 * time.Hour,
+		},
+		{
+			name:     "negative",
+			value:    "-PT5S",
+			expected: -5 * time.Second,
+		},
+		{
+			name:        "empty designator",
+			value:       "PT",
+			expectError: true,
+		},
+		{
+			name:        "months unsupported",
+			value:       "P1M",
+			expectError: true,
+		},
+		{
+			name:        "garbage",
+			value:       "10s",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseISODuration(tt.value)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got %v", result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseMPDSuggestedPresentationDelay(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD type="dynamic" suggestedPresentationDelay="PT6S">
+  <Period id="1">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <Representation id="v1" width="1920" height="1080" codecs="avc1.640028"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://example.com/live.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if output.Start == nil {
+		t.Fatal("Expected start info but got none")
+	}
+
+	expected := StartInfo{Source: "suggestedPresentationDelay", Offset: -6}
+	if *output.Start != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *output.Start)
+	}
+}
+
+func TestParseMPDSourceRefs(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD type="static">
+  <Period id="p0">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <Representation id="video-720" codecs="avc1.64001f" width="1280" height="720"/>
+    </AdaptationSet>
+    <AdaptationSet id="2" contentType="audio" mimeType="audio/mp4" lang="en">
+      <Representation id="audio-en" codecs="mp4a.40.2"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://example.com/vod.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []SourceRef{
+		{Tag: "Representation", PeriodID: "p0", AdaptationSetID: "1", RepresentationID: "video-720"},
+		{Tag: "Representation", PeriodID: "p0", AdaptationSetID: "2", RepresentationID: "audio-en"},
+	}
+	if len(output.Streams) != len(expected) {
+		t.Fatalf("Expected %d streams, got %d", len(expected), len(output.Streams))
+	}
+	for i, stream := range output.Streams {
+		if stream.Source == nil || *stream.Source != expected[i] {
+			t.Errorf("Expected source %+v, got %+v", expected[i], stream.Source)
+		}
+		if stream.NativeID != expected[i].RepresentationID {
+			t.Errorf("Expected native ID %q, got %q", expected[i].RepresentationID, stream.NativeID)
+		}
+	}
+}
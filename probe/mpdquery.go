@@ -0,0 +1,280 @@
+package probe
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MPDNode is an element of an MPD with its raw attributes, for the fields
+// goprobe does not model. Namespaced attributes are keyed with the prefix
+// declared in the MPD ("cenc:default_KID").
+type MPDNode struct {
+	Name     string            `json:"name"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	Children []*MPDNode        `json:"children,omitempty"`
+}
+
+// ParseMPDTree parses an MPD into a tree of MPDNode, rooted at the MPD element
+func ParseMPDTree(content string) (*MPDNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	prefixes := map[string]string{}
+
+	var root *MPDNode
+	var stack []*MPDNode
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					prefixes[attr.Value] = attr.Name.Local
+				}
+			}
+			node := &MPDNode{Name: t.Name.Local}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+					continue
+				}
+				if node.Attrs == nil {
+					node.Attrs = make(map[string]string)
+				}
+				node.Attrs[attributeName(attr.Name, prefixes)] = attr.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				node := stack[len(stack)-1]
+				node.Text = strings.TrimSpace(node.Text)
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return root, nil
+}
+
+// attributeName returns the key of an attribute: its local name, prefixed
+// when it is in a namespace
+func attributeName(name xml.Name, prefixes map[string]string) string {
+	switch {
+	case name.Space == "":
+		return name.Local
+	case name.Space == "xml":
+		return "xml:" + name.Local
+	case prefixes[name.Space] != "":
+		return prefixes[name.Space] + ":" + name.Local
+	default:
+		// Undeclared prefixes are reported as written
+		return name.Space + ":" + name.Local
+	}
+}
+
+// mpdStep is a step of an MPD selector: an element name ("*" for any) and
+// its predicates
+type mpdStep struct {
+	name       string
+	predicates []mpdPredicate
+}
+
+// mpdPredicate filters the elements of a step: by 0-based position among
+// the matching children of each parent, attribute value, or attribute presence
+type mpdPredicate struct {
+	index    int
+	hasIndex bool
+	attr     string
+	value    string
+	hasValue bool
+}
+
+// matches reports whether node satisfies an attribute predicate
+func (p mpdPredicate) matches(node *MPDNode) bool {
+	value, ok := node.Attrs[p.attr]
+	return ok && (!p.hasValue || value == p.value)
+}
+
+// parseMPDSelector parses a selector such as
+// "Period[0].AdaptationSet[contentType=audio].Representation"
+func parseMPDSelector(selector string) ([]mpdStep, error) {
+	invalid := func(reason string) error {
+		return NewValidationError(fmt.Sprintf("invalid MPD selector %q: %s", selector, reason))
+	}
+
+	var steps []mpdStep
+	rest := strings.TrimSpace(selector)
+	for rest != "" {
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		step := mpdStep{name: strings.TrimSpace(rest[:end])}
+		if step.name == "" {
+			return nil, invalid("missing element name")
+		}
+		rest = rest[end:]
+
+		for strings.HasPrefix(rest, "[") {
+			closing := strings.IndexByte(rest, ']')
+			if quote := strings.IndexAny(rest, `"'`); quote >= 0 && quote < closing {
+				// Quoted values may contain ']'
+				if next := strings.IndexByte(rest[quote+1:], rest[quote]); next >= 0 {
+					if c := strings.IndexByte(rest[quote+1+next:], ']'); c >= 0 {
+						closing = quote + 1 + next + c
+					}
+				}
+			}
+			if closing < 0 {
+				return nil, invalid("missing ]")
+			}
+			predicate, err := parseMPDPredicate(strings.TrimSpace(rest[1:closing]))
+			if err != nil {
+				return nil, invalid(err.Error())
+			}
+			step.predicates = append(step.predicates, predicate)
+			rest = rest[closing+1:]
+		}
+
+		steps = append(steps, step)
+		if rest == "" {
+			break
+		}
+		if !strings.HasPrefix(rest, ".") {
+			return nil, invalid(fmt.Sprintf("unexpected %q", rest))
+		}
+		rest = rest[1:]
+		if rest == "" {
+			return nil, invalid("trailing .")
+		}
+	}
+	if len(steps) == 0 {
+		return nil, invalid("empty selector")
+	}
+	return steps, nil
+}
+
+// parseMPDPredicate parses the inside of [...]: an index, attr=value or attr
+func parseMPDPredicate(text string) (mpdPredicate, error) {
+	if text == "" {
+		return mpdPredicate{}, fmt.Errorf("empty predicate")
+	}
+	if index, err := strconv.Atoi(text); err == nil {
+		if index < 0 {
+			return mpdPredicate{}, fmt.Errorf("negative index %d", index)
+		}
+		return mpdPredicate{index: index, hasIndex: true}, nil
+	}
+
+	attr, value, hasValue := strings.Cut(text, "=")
+	attr = strings.TrimPrefix(strings.TrimSpace(attr), "@")
+	if attr == "" {
+		return mpdPredicate{}, fmt.Errorf("missing attribute name in [%s]", text)
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return mpdPredicate{attr: attr, value: value, hasValue: hasValue}, nil
+}
+
+// Select returns the elements matching selector, in document order. A
+// selector is a dot-separated path of element names below n ("*" for any
+// element; a leading "MPD" step naming the root is optional), each with
+// optional predicates: [N] for the N-th match (0-based) within each parent,
+// [attr=value] (quotes optional, needed for values with "]"), or [attr] for
+// elements having the attribute. For example:
+//
+//	Period[0].AdaptationSet[contentType=audio].Representation
+//	Period.AdaptationSet.ContentProtection[cenc:default_KID]
+func (n *MPDNode) Select(selector string) ([]*MPDNode, error) {
+	steps, err := parseMPDSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	if steps[0].name == n.Name && len(steps[0].predicates) == 0 && len(steps) > 1 {
+		steps = steps[1:]
+	}
+
+	nodes := []*MPDNode{n}
+	for _, step := range steps {
+		var matched []*MPDNode
+		for _, parent := range nodes {
+			var children []*MPDNode
+			for _, child := range parent.Children {
+				if step.name == "*" || child.Name == step.name {
+					children = append(children, child)
+				}
+			}
+			for _, predicate := range step.predicates {
+				var filtered []*MPDNode
+				for i, child := range children {
+					if predicate.hasIndex && i == predicate.index || !predicate.hasIndex && predicate.matches(child) {
+						filtered = append(filtered, child)
+					}
+				}
+				children = filtered
+			}
+			matched = append(matched, children...)
+		}
+		nodes = matched
+	}
+	return nodes, nil
+}
+
+// Get returns the attributes of the elements matching selector (see
+// Select), for example the @bandwidth and @codecs of audio representations
+func (n *MPDNode) Get(selector string) ([]map[string]string, error) {
+	nodes, err := n.Select(selector)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make([]map[string]string, len(nodes))
+	for i, node := range nodes {
+		attrs[i] = make(map[string]string, len(node.Attrs))
+		for key, value := range node.Attrs {
+			attrs[i][key] = value
+		}
+	}
+	return attrs, nil
+}
+
+// MPDTree fetches a DASH manifest and parses it into a tree of MPDNode
+func (p *Prober) MPDTree(ctx context.Context, manifestURL string) (*MPDNode, error) {
+	ctx = withLogger(ctx, p.logger)
+
+	fetched, err := p.fetch(ctx, manifestURL)
+	if err != nil {
+		return nil, p.redactor.redactError(err)
+	}
+	if manifestFormat(fetched.body) != "MPD" {
+		return nil, NewValidationError("not a DASH manifest")
+	}
+	tree, err := ParseMPDTree(fetched.body)
+	if err != nil {
+		return nil, p.redactor.redactError(NewParsingError(fetched.url, "MPD", err))
+	}
+	return tree, nil
+}
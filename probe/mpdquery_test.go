@@ -0,0 +1,137 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const queryTestMPD = `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:cenc="urn:mpeg:cenc:2013" type="static">
+<Period id="p0">
+<AdaptationSet contentType="video" mimeType="video/mp4">
+<ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc" cenc:default_KID="10000000-1000-1000-1000-100000000001"/>
+<Representation id="v1" bandwidth="1000000" codecs="avc1.64001f"/>
+<Representation id="v2" bandwidth="3000000" codecs="avc1.640028"/>
+</AdaptationSet>
+<AdaptationSet contentType="audio" lang="en">
+<Label>Stereo [2.0]</Label>
+<Representation id="a1" bandwidth="128000" codecs="mp4a.40.2"/>
+</AdaptationSet>
+<AdaptationSet contentType="audio" lang="fr">
+<Representation id="a2" bandwidth="96000" codecs="mp4a.40.5"/>
+</AdaptationSet>
+</Period>
+<Period id="p1">
+<AdaptationSet contentType="audio" lang="en">
+<Representation id="a3" bandwidth="64000"/>
+</AdaptationSet>
+</Period>
+</MPD>`
+
+func TestMPDNodeSelect(t *testing.T) {
+	tree, err := ParseMPDTree(queryTestMPD)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{selector: "Period[0].AdaptationSet[contentType=audio].Representation", expected: []string{"a1", "a2"}},
+		{selector: "MPD.Period.AdaptationSet[lang='en'].Representation", expected: []string{"a1", "a3"}},
+		{selector: "Period.AdaptationSet.Representation[0]", expected: []string{"v1", "a1", "a2", "a3"}},
+		{selector: "Period[1].*.Representation", expected: []string{"a3"}},
+		{selector: "Period.AdaptationSet[contentType=audio][1].Representation", expected: []string{"a2"}},
+		{selector: "Period.AdaptationSet.Representation[codecs]", expected: []string{"v1", "v2", "a1", "a2"}},
+		{selector: "Period[2].AdaptationSet", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			nodes, err := tree.Select(tt.selector)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var ids []string
+			for _, node := range nodes {
+				ids = append(ids, node.Attrs["id"])
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestMPDNodeGet(t *testing.T) {
+	tree, err := ParseMPDTree(queryTestMPD)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	attrs, err := tree.Get("Period.AdaptationSet.ContentProtection[cenc:default_KID]")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []map[string]string{{
+		"schemeIdUri":      "urn:mpeg:dash:mp4protection:2011",
+		"value":            "cenc",
+		"cenc:default_KID": "10000000-1000-1000-1000-100000000001",
+	}}
+	if !reflect.DeepEqual(attrs, expected) {
+		t.Errorf("Expected %v, got %v", expected, attrs)
+	}
+
+	// The returned maps are copies
+	attrs[0]["value"] = "changed"
+	if again, _ := tree.Get("Period.AdaptationSet.ContentProtection"); again[0]["value"] != "cenc" {
+		t.Errorf("Expected Get to return copies, got %q", again[0]["value"])
+	}
+
+	labels, err := tree.Select("Period.AdaptationSet.Label")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(labels) != 1 || labels[0].Text != "Stereo [2.0]" {
+		t.Errorf("Expected the label text, got %+v", labels)
+	}
+}
+
+func TestMPDSelectorErrors(t *testing.T) {
+	tree, err := ParseMPDTree(queryTestMPD)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, selector := range []string{"", "Period.", ".Period", "Period[0", "Period[]", "Period[-1]", "Period[=audio]", "Period[0]x"} {
+		t.Run(selector, func(t *testing.T) {
+			_, err := tree.Select(selector)
+			var probeErr *ProbeError
+			if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeValidation {
+				t.Errorf("Expected a validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProberMPDTree(t *testing.T) {
+	server := manifestServer(queryTestMPD)
+	defer server.Close()
+
+	tree, err := NewProber(nil).MPDTree(context.Background(), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tree.Name != "MPD" || tree.Attrs["type"] != "static" {
+		t.Errorf("Expected the MPD root, got %s %v", tree.Name, tree.Attrs)
+	}
+
+	hls := manifestServer("#EXTM3U\n#EXT-X-ENDLIST\n")
+	defer hls.Close()
+	if _, err := NewProber(nil).MPDTree(context.Background(), hls.URL+"/index.m3u8"); err == nil {
+		t.Error("Expected an error for an HLS playlist")
+	}
+}
@@ -0,0 +1,100 @@
+package probe
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StreamOrder selects the order of Output.Streams (see ProbeOptions.StreamOrder)
+type StreamOrder string
+
+const (
+	// StreamOrderManifest lists streams in manifest order, DASH streams by
+	// Period, AdaptationSet and Representation. The default ("") keeps the
+	// parser's order, which for DASH groups video, audio and subtitles.
+	StreamOrderManifest StreamOrder = "manifest"
+	// StreamOrderType lists video, then audio, then subtitle streams, like ffprobe
+	StreamOrderType StreamOrder = "type"
+	// StreamOrderLanguage groups streams by language, in order of first appearance
+	StreamOrderLanguage StreamOrder = "language"
+	// StreamOrderBandwidth lists streams by type, highest bitrate first within each type
+	StreamOrderBandwidth StreamOrder = "bandwidth"
+)
+
+// StreamOrders returns the supported stream orders
+func StreamOrders() []StreamOrder {
+	return []StreamOrder{StreamOrderManifest, StreamOrderType, StreamOrderLanguage, StreamOrderBandwidth}
+}
+
+// validStreamOrder reports whether order is supported ("" = StreamOrderManifest)
+func validStreamOrder(order StreamOrder) bool {
+	if order == "" {
+		return true
+	}
+	for _, supported := range StreamOrders() {
+		if order == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// streamTypeRank orders stream types like ffprobe: video, audio, subtitles, then others
+func streamTypeRank(streamType string) int {
+	switch streamType {
+	case "Video":
+		return 0
+	case "Audio":
+		return 1
+	case "Subtitle":
+		return 2
+	default:
+		return 3
+	}
+}
+
+// SortStreams reorders Streams. Stream IDs are unchanged, so they still
+// identify the manifest elements the streams come from; the sort is stable.
+func (o *Output) SortStreams(order StreamOrder) error {
+	if !validStreamOrder(order) {
+		return NewValidationError(fmt.Sprintf("unknown stream order %q", order))
+	}
+
+	streams := o.Streams
+	switch order {
+	case StreamOrderManifest:
+		// HLS streams share the zero element and keep their order
+		sort.SliceStable(streams, func(i, j int) bool {
+			a, b := streams[i].element, streams[j].element
+			if a.period != b.period {
+				return a.period < b.period
+			}
+			if a.adaptationSet != b.adaptationSet {
+				return a.adaptationSet < b.adaptationSet
+			}
+			return a.representation < b.representation
+		})
+	case StreamOrderType:
+		sort.SliceStable(streams, func(i, j int) bool {
+			return streamTypeRank(streams[i].Type) < streamTypeRank(streams[j].Type)
+		})
+	case StreamOrderLanguage:
+		groups := make(map[string]int)
+		for _, stream := range streams {
+			if _, ok := groups[stream.Language]; !ok {
+				groups[stream.Language] = len(groups)
+			}
+		}
+		sort.SliceStable(streams, func(i, j int) bool {
+			return groups[streams[i].Language] < groups[streams[j].Language]
+		})
+	case StreamOrderBandwidth:
+		sort.SliceStable(streams, func(i, j int) bool {
+			if rankI, rankJ := streamTypeRank(streams[i].Type), streamTypeRank(streams[j].Type); rankI != rankJ {
+				return rankI < rankJ
+			}
+			return streamBitRate(&streams[i]) > streamBitRate(&streams[j])
+		})
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package probe
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSortStreams(t *testing.T) {
+	streams := []StreamInfo{
+		{StreamID: "0:0", Type: "Video", BitRate: "1000 kb/s"},
+		{StreamID: "0:1", Type: "Audio", Language: "en", BitRate: "128 kb/s"},
+		{StreamID: "0:2", Type: "Subtitle", Language: "fr"},
+		{StreamID: "0:3", Type: "Video", BitRate: "5000 kb/s"},
+		{StreamID: "0:4", Type: "Audio", Language: "fr", BitRate: "192 kb/s"},
+		{StreamID: "0:5", Type: "Subtitle", Language: "en"},
+	}
+
+	tests := []struct {
+		order    StreamOrder
+		expected []string
+	}{
+		{StreamOrderManifest, []string{"0:0", "0:1", "0:2", "0:3", "0:4", "0:5"}},
+		{StreamOrderType, []string{"0:0", "0:3", "0:1", "0:4", "0:2", "0:5"}},
+		{StreamOrderLanguage, []string{"0:0", "0:3", "0:1", "0:5", "0:2", "0:4"}},
+		{StreamOrderBandwidth, []string{"0:3", "0:0", "0:4", "0:1", "0:2", "0:5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			output := &Output{Streams: append([]StreamInfo(nil), streams...)}
+			if err := output.SortStreams(tt.order); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var ids []string
+			for _, stream := range output.Streams {
+				ids = append(ids, stream.StreamID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, ids)
+			}
+		})
+	}
+
+	if err := (&Output{}).SortStreams("random"); err == nil {
+		t.Error("Expected an error for an unknown order")
+	}
+}
+
+func TestProbeStreamOrder(t *testing.T) {
+	server := manifestServer(speculationTestMaster)
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{StreamOrder: StreamOrderType}).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 1; i < len(output.Streams); i++ {
+		if streamTypeRank(output.Streams[i-1].Type) > streamTypeRank(output.Streams[i].Type) {
+			t.Errorf("Expected streams ordered by type, got %s before %s", output.Streams[i-1].Type, output.Streams[i].Type)
+		}
+	}
+
+	if _, err := NewProber(&ProbeOptions{StreamOrder: "random"}).Probe(context.Background(), server.URL+"/master.m3u8"); err == nil {
+		t.Error("Expected a validation error for an unknown order")
+	}
+}
+
+func TestProbeStreamOrderManifestDASH(t *testing.T) {
+	server := manifestServer(`<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT10S">
+  <Period id="p0">
+    <AdaptationSet contentType="audio" mimeType="audio/mp4" lang="en">
+      <Representation id="audio" codecs="mp4a.40.2" bandwidth="128000" audioSamplingRate="48000"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <Representation id="720p" codecs="avc1.4d401f" bandwidth="3000000" width="1280" height="720"/>
+      <Representation id="360p" codecs="avc1.4d401e" bandwidth="800000" width="640" height="360"/>
+    </AdaptationSet>
+  </Period>
+  <Period id="p1">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <Representation id="ad" codecs="avc1.4d401f" bandwidth="3000000" width="1280" height="720"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`)
+	defer server.Close()
+
+	tests := []struct {
+		order    StreamOrder
+		expected []string
+	}{
+		{"", []string{"720p", "360p", "ad", "audio"}},
+		{StreamOrderManifest, []string{"audio", "720p", "360p", "ad"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			output, err := NewProber(&ProbeOptions{StreamOrder: tt.order}).Probe(context.Background(), server.URL+"/manifest.mpd")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var ids []string
+			for _, stream := range output.Streams {
+				ids = append(ids, stream.NativeID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, ids)
+			}
+		})
+	}
+}
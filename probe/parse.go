@@ -0,0 +1,44 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+)
+
+// ParseMPD parses the content of a DASH MPD without fetching anything.
+// manifestURL resolves the relative URLs of the manifest ("" = keep them relative).
+func ParseMPD(content, manifestURL string) (*Output, error) {
+	if manifestFormat(content) != "MPD" || !looksLikeManifest([]byte(content)) {
+		return nil, NewParsingError(manifestURL, "MPD", fmt.Errorf("%w: content is not an MPD", ErrUnsupportedFormat))
+	}
+	return parseMPDManifest(content, manifestURL)
+}
+
+// ParseHLS parses the content of an HLS playlist without fetching anything.
+// manifestURL resolves the relative URLs of the playlist ("" = keep them relative).
+func ParseHLS(content, manifestURL string) (*Output, error) {
+	if manifestFormat(content) != "HLS" {
+		return nil, NewParsingError(manifestURL, "HLS", fmt.Errorf("%w: content is not an HLS playlist", ErrUnsupportedFormat))
+	}
+	return parseHLSManifest(content, manifestURL)
+}
+
+// ProbeContent analyzes manifest content fetched by the caller as if Probe
+// had fetched it from manifestURL, applying the prober's options. Response
+// checks (ContentTypePolicy) see no headers. It lets clients that fetch
+// manifests themselves, such as a browser console, share the backend's output.
+func (p *Prober) ProbeContent(ctx context.Context, manifestURL, content string) (*Output, error) {
+	ctx = withLogger(ctx, p.logger)
+	if err := validateProbeOptions(p.opts); err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return nil, NewParsingError(manifestURL, "unknown", fmt.Errorf("empty manifest content"))
+	}
+
+	output, err := p.analyze(ctx, &fetchResult{body: content, url: manifestURL})
+	if err != nil {
+		return nil, p.redactor.redactError(err)
+	}
+	return output, nil
+}
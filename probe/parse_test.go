@@ -0,0 +1,78 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseMPDAndHLS(t *testing.T) {
+	mpd := `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT10S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4" contentType="video">
+      <Representation id="v1" codecs="avc1.640028" width="1920" height="1080" bandwidth="5000000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+	hls := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=640x360
+low/index.m3u8
+`
+
+	tests := []struct {
+		name    string
+		parse   func(content, manifestURL string) (*Output, error)
+		content string
+		streams int
+	}{
+		{"MPD", ParseMPD, mpd, 1},
+		{"HLS", ParseHLS, hls, 2},
+		{"MPD given HLS", ParseMPD, hls, -1},
+		{"HLS given MPD", ParseHLS, mpd, -1},
+		{"MPD given text", ParseMPD, "not a manifest", -1},
+	}
+
+	for _, tt := range tests {
+		output, err := tt.parse(tt.content, "https://example.com/manifest")
+		if tt.streams < 0 {
+			if !errors.Is(err, ErrUnsupportedFormat) {
+				t.Errorf("%s: expected ErrUnsupportedFormat, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(output.Streams) != tt.streams {
+			t.Errorf("%s: expected %d streams, got %d", tt.name, tt.streams, len(output.Streams))
+		}
+	}
+}
+
+func TestProbeContent(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080
+main/index.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=300000,CODECS="avc1.640028",RESOLUTION=1920x1080,URI="main/iframes.m3u8"
+`
+	prober := NewProber(&ProbeOptions{IFrameStreams: true})
+	output, err := prober.ProbeContent(context.Background(), "https://example.com/master.m3u8", manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(output.Streams) != 3 || output.Streams[2].Type != IFrameStreamType {
+		t.Errorf("Expected the I-frame playlist as the third stream, got %+v", output.Streams)
+	}
+	urls, err := output.ResolveURLs("0:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(urls) != 1 || urls[0] != "https://example.com/main/index.m3u8" {
+		t.Errorf("Expected %q, got %q", "https://example.com/main/index.m3u8", urls)
+	}
+
+	if _, err := prober.ProbeContent(context.Background(), "https://example.com/master.m3u8", ""); err == nil {
+		t.Errorf("Expected an error for empty content")
+	}
+}
@@ -0,0 +1,334 @@
+package probe
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultParseCacheEntries is the default number of parsed manifests kept by
+// the parse cache
+const DefaultParseCacheEntries = 256
+
+// ParseCacheConfig configures the cache of parsed manifests of a Prober.
+// Fleets of channels often serve byte-identical manifests behind different
+// tokens; probing them again skips parsing. Entries are keyed by the SHA-256
+// of the manifest body and the manifest URL without its query string, since
+// relative URIs resolve the same whatever the token.
+type ParseCacheConfig struct {
+	// MaxEntries bounds the cached manifests, evicting the least recently
+	// used (0 = DefaultParseCacheEntries)
+	MaxEntries int
+}
+
+// ParseCacheStats counts parse cache activity
+type ParseCacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// parseCacheEntry is a cached parse result
+type parseCacheEntry struct {
+	key    string
+	output *Output
+}
+
+// parseCache is an LRU of parse results
+type parseCache struct {
+	max int
+
+	mu      sync.Mutex
+	order   *list.List // front = most recently used
+	entries map[string]*list.Element
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// newParseCache returns the parse cache of config (nil if config is nil)
+func newParseCache(config *ParseCacheConfig) *parseCache {
+	if config == nil {
+		return nil
+	}
+	max := config.MaxEntries
+	if max <= 0 {
+		max = DefaultParseCacheEntries
+	}
+	return &parseCache{max: max, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// parseCacheKey returns the cache key of a manifest body fetched from manifestURL
+func parseCacheKey(body, manifestURL string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:]) + " " + stripQuery(manifestURL)
+}
+
+// get returns a copy of the cached output of key (nil on a miss)
+func (c *parseCache) get(key string) *Output {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(element)
+	return element.Value.(*parseCacheEntry).output.clone()
+}
+
+// add caches a copy of output under key, evicting the least recently used entry when full
+func (c *parseCache) add(key string, output *Output) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*parseCacheEntry).output = output.clone()
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&parseCacheEntry{key: key, output: output.clone()})
+	if c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*parseCacheEntry).key)
+		c.evictions.Add(1)
+	}
+}
+
+func (c *parseCache) stats() ParseCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+	return ParseCacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// ParseCacheStats returns the parse cache counters (zero when ProbeOptions.ParseCache is nil)
+func (p *Prober) ParseCacheStats() ParseCacheStats {
+	if p.parseCache == nil {
+		return ParseCacheStats{}
+	}
+	return p.parseCache.stats()
+}
+
+// clone returns a deep copy of an output, so that the stages after parsing
+// cannot modify a cached output. TestOutputCloneSharesNoMemory fails when a
+// field is left shared.
+func (o *Output) clone() *Output {
+	c := *o
+
+	c.Streams = cloneStreams(o.Streams)
+	c.iframes = cloneStreams(o.iframes)
+	c.audioRenditions = cloneStreams(o.audioRenditions)
+
+	c.Start = clonePointer(o.Start)
+	c.Timing = clonePointer(o.Timing)
+	if o.ProgramDateTime != nil {
+		pdt := *o.ProgramDateTime
+		pdt.Anchors = append([]PDTAnchor(nil), pdt.Anchors...)
+		c.ProgramDateTime = &pdt
+	}
+	if o.MediaPlaylist != nil {
+		playlist := cloneMediaPlaylist(*o.MediaPlaylist)
+		c.MediaPlaylist = &playlist
+	}
+
+	c.Interstitials = append([]Interstitial(nil), o.Interstitials...)
+
+	c.AdBreaks = nil
+	for _, adBreak := range o.AdBreaks {
+		if adBreak.Offset != nil {
+			offset := *adBreak.Offset
+			adBreak.Offset = &offset
+		}
+		for _, cue := range []**SCTE35Cue{&adBreak.CueOut, &adBreak.CueIn, &adBreak.Command} {
+			if *cue != nil {
+				copied := **cue
+				*cue = &copied
+			}
+		}
+		c.AdBreaks = append(c.AdBreaks, adBreak)
+	}
+	c.Metadata = cloneMetadata(o.Metadata)
+	c.Warnings = append([]string(nil), o.Warnings...)
+	c.Features = append([]string(nil), o.Features...)
+	c.Periods = nil
+	for _, period := range o.Periods {
+		period.Start = clonePointer(period.Start)
+		period.StreamIDs = append([]string{}, period.StreamIDs...)
+		c.Periods = append(c.Periods, period)
+	}
+	if o.DRM != nil {
+		drm := *o.DRM
+		drm.Schemes = append([]string(nil), drm.Schemes...)
+		drm.DefaultKIDs = append([]string(nil), drm.DefaultKIDs...)
+		drm.Systems = nil
+		for _, system := range o.DRM.Systems {
+			system.StreamIDs = append([]string(nil), system.StreamIDs...)
+			drm.Systems = append(drm.Systems, system)
+		}
+		c.DRM = &drm
+	}
+	c.variants = append([]hlsVariantRef(nil), o.variants...)
+	c.variantPlaylists = nil
+	for _, fetch := range o.variantPlaylists {
+		if fetch.fetched != nil {
+			fetched := *fetch.fetched
+			fetched.header = fetched.header.Clone()
+			fetch.fetched = &fetched
+		}
+		c.variantPlaylists = append(c.variantPlaylists, fetch)
+	}
+	if o.audioGroups != nil {
+		c.audioGroups = make(map[string][]string, len(o.audioGroups))
+		for group, ids := range o.audioGroups {
+			c.audioGroups[group] = append([]string(nil), ids...)
+		}
+	}
+
+	c.Variants = nil
+	for _, variant := range o.Variants {
+		if variant.Score != nil {
+			score := *variant.Score
+			variant.Score = &score
+		}
+		variant.StreamIDs = append([]string(nil), variant.StreamIDs...)
+		if variant.Playlist != nil {
+			playlist := *variant.Playlist
+			playlist.MediaPlaylist = cloneMediaPlaylist(playlist.MediaPlaylist)
+			variant.Playlist = &playlist
+		}
+		c.Variants = append(c.Variants, variant)
+	}
+
+	c.Compatibility = nil
+	for _, finding := range o.Compatibility {
+		finding.Codecs = append([]string(nil), finding.Codecs...)
+		finding.StreamIDs = append([]string(nil), finding.StreamIDs...)
+		finding.Locations = append([]FindingLocation(nil), finding.Locations...)
+		c.Compatibility = append(c.Compatibility, finding)
+	}
+
+	c.Bitrates = append([]BitrateStats(nil), o.Bitrates...)
+	c.Decodes = append([]DecodeResult(nil), o.Decodes...)
+	c.Bitstream = nil
+	for _, check := range o.Bitstream {
+		check.Detected = append([]string(nil), check.Detected...)
+		c.Bitstream = append(c.Bitstream, check)
+	}
+	c.BaseURLChecks = append([]BaseURLCheck(nil), o.BaseURLChecks...)
+	c.Incomplete = append([]IncompleteCheck(nil), o.Incomplete...)
+	c.Rules = nil
+	for _, finding := range o.Rules {
+		finding.StreamIDs = append([]string(nil), finding.StreamIDs...)
+		finding.Locations = append([]FindingLocation(nil), finding.Locations...)
+		c.Rules = append(c.Rules, finding)
+	}
+	if o.Rebuffer != nil {
+		rebuffer := *o.Rebuffer
+		rebuffer.Segments = append([]SegmentDownload(nil), rebuffer.Segments...)
+		c.Rebuffer = &rebuffer
+	}
+	c.Speculation = clonePointer(o.Speculation)
+	if o.Event != nil {
+		event := *o.Event
+		event.StartsAt = clonePointer(event.StartsAt)
+		c.Event = &event
+	}
+	if o.Format != nil {
+		format := *o.Format
+		format.IsLive = clonePointer(format.IsLive)
+		format.LiveEdge = clonePointer(format.LiveEdge)
+		c.Format = &format
+	}
+	c.ProgramVersion = clonePointer(o.ProgramVersion)
+	if o.Provenance != nil {
+		provenance := *o.Provenance
+		provenance.ResponseIDs = append([]string(nil), provenance.ResponseIDs...)
+		c.Provenance = &provenance
+	}
+	if o.Cache != nil {
+		cache := *o.Cache
+		cache.Age = clonePointer(cache.Age)
+		cache.MaxAge = clonePointer(cache.MaxAge)
+		c.Cache = &cache
+	}
+
+	return &c
+}
+
+// cloneMediaPlaylist returns a deep copy of playlist
+func cloneMediaPlaylist(playlist MediaPlaylist) MediaPlaylist {
+	playlist.Encryption = append([]MediaPlaylistKey(nil), playlist.Encryption...)
+	playlist.DeltaUpdate = clonePointer(playlist.DeltaUpdate)
+	return playlist
+}
+
+// clonePointer returns a pointer to a copy of *p (nil if p is nil)
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	return &copied
+}
+
+// cloneStreams returns a deep copy of streams
+func cloneStreams(streams []StreamInfo) []StreamInfo {
+	if streams == nil {
+		return nil
+	}
+	cloned := make([]StreamInfo, len(streams))
+	for i, stream := range streams {
+		if stream.Disposition != nil {
+			disposition := *stream.Disposition
+			stream.Disposition = &disposition
+		}
+		stream.Roles = append([]string(nil), stream.Roles...)
+		stream.Labels = append([]Label(nil), stream.Labels...)
+		if stream.Accessibility != nil {
+			accessibility := make([]Accessibility, len(stream.Accessibility))
+			for j, descriptor := range stream.Accessibility {
+				descriptor.Captions = append([]CaptionChannel(nil), descriptor.Captions...)
+				accessibility[j] = descriptor
+			}
+			stream.Accessibility = accessibility
+		}
+		if stream.Source != nil {
+			source := *stream.Source
+			stream.Source = &source
+		}
+		if stream.Score != nil {
+			score := *stream.Score
+			stream.Score = &score
+		}
+		if stream.Segments != nil {
+			segments := *stream.Segments
+			stream.Segments = &segments
+		}
+		if stream.BaseURLs != nil {
+			baseURLs := make([]BaseURLCandidate, len(stream.BaseURLs))
+			for j, candidate := range stream.BaseURLs {
+				candidate.urls = append([]string(nil), candidate.urls...)
+				baseURLs[j] = candidate
+			}
+			stream.BaseURLs = baseURLs
+		}
+		stream.VariantIndexes = append([]int(nil), stream.VariantIndexes...)
+		stream.urls = append([]string(nil), stream.urls...)
+		cloned[i] = stream
+	}
+	return cloned
+}
@@ -0,0 +1,201 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestParseCache(t *testing.T) {
+	server := manifestServer(speculationTestMaster)
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{ParseCache: &ParseCacheConfig{}})
+
+	first, err := prober.Probe(context.Background(), server.URL+"/master.m3u8?token=a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	first.Streams[0].Codec = "modified"
+	first.Variants[0].StreamIDs[0] = "modified"
+
+	// Same body behind another token: served from the cache
+	second, err := prober.Probe(context.Background(), server.URL+"/master.m3u8?token=b")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second.Streams[0].Codec == "modified" || second.Variants[0].StreamIDs[0] == "modified" {
+		t.Errorf("Expected the cached output to be unaffected by changes to a previous output")
+	}
+
+	// Another path resolves relative URIs differently: parsed again
+	if _, err := prober.Probe(context.Background(), server.URL+"/other/master.m3u8"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := ParseCacheStats{Hits: 1, Misses: 2, Entries: 2}
+	if stats := prober.ParseCacheStats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestParseCacheMatchesParse(t *testing.T) {
+	server := manifestServer(dashIFTestNonCompliant)
+	defer server.Close()
+
+	uncached, err := NewProber(nil).Probe(context.Background(), server.URL+"/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	prober := NewProber(&ProbeOptions{ParseCache: &ParseCacheConfig{}})
+	for i := 0; i < 2; i++ {
+		output, err := prober.Probe(context.Background(), server.URL+"/manifest.mpd")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		output.ProgramVersion, uncached.ProgramVersion = nil, nil
+		if !reflect.DeepEqual(output, uncached) {
+			t.Errorf("Expected %+v, got %+v", uncached, output)
+		}
+	}
+}
+
+func TestParseCacheEviction(t *testing.T) {
+	cache := newParseCache(&ParseCacheConfig{MaxEntries: 2})
+	cache.add("a", &Output{Features: []string{"a"}})
+	cache.add("b", &Output{Features: []string{"b"}})
+	cache.get("a") // a is now more recently used than b
+	cache.add("c", &Output{Features: []string{"c"}})
+
+	tests := []struct {
+		key    string
+		cached bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if cached := cache.get(tt.key) != nil; cached != tt.cached {
+			t.Errorf("Expected %s cached %v, got %v", tt.key, tt.cached, cached)
+		}
+	}
+
+	expected := ParseCacheStats{Hits: 3, Misses: 1, Evictions: 1, Entries: 2}
+	if stats := cache.stats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestParseCacheDisabled(t *testing.T) {
+	if stats := NewProber(nil).ParseCacheStats(); stats != (ParseCacheStats{}) {
+		t.Errorf("Expected zero stats, got %+v", stats)
+	}
+}
+
+// TestOutputCloneSharesNoMemory fills every pointer, slice and map of an
+// Output, exported or not, and checks that its clone shares none of them
+func TestOutputCloneSharesNoMemory(t *testing.T) {
+	var original Output
+	fillValue(reflect.ValueOf(&original).Elem(), 0)
+	cloned := original.clone()
+
+	if !reflect.DeepEqual(&original, cloned) {
+		t.Fatal("Expected the clone to equal the original")
+	}
+	originals := map[uintptr]string{}
+	collectAddresses(reflect.ValueOf(original), "Output", originals)
+	clones := map[uintptr]string{}
+	collectAddresses(reflect.ValueOf(*cloned), "Output", clones)
+	for address, path := range clones {
+		if _, ok := originals[address]; ok {
+			t.Errorf("Expected %s of the clone not to share memory with the original", path)
+		}
+	}
+}
+
+// fillValue sets v and everything it points to to non-zero values, including
+// unexported fields (interfaces and funcs are left nil)
+func fillValue(v reflect.Value, depth int) {
+	if depth > 10 {
+		return
+	}
+	if !v.CanSet() {
+		v = reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+	}
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(time.Unix(1, 0).UTC()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillValue(v.Elem(), depth+1)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillValue(v.Index(0), depth+1)
+	case reflect.Map:
+		key, value := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+		fillValue(key, depth+1)
+		fillValue(value, depth+1)
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, value)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fillValue(v.Field(i), depth+1)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fillValue(v.Index(i), depth+1)
+		}
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	}
+}
+
+// collectAddresses records the memory referenced by v (pointer targets, slice
+// arrays and maps) by the path of the field referencing it
+func collectAddresses(v reflect.Value, path string, addresses map[uintptr]string) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			addresses[v.Pointer()] = path
+			collectAddresses(v.Elem(), path, addresses)
+		}
+	case reflect.Slice:
+		if v.Cap() > 0 {
+			addresses[v.Pointer()] = path
+		}
+		for i := 0; i < v.Len(); i++ {
+			collectAddresses(v.Index(i), fmt.Sprintf("%s[%d]", path, i), addresses)
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			addresses[v.Pointer()] = path
+		}
+		for iter := v.MapRange(); iter.Next(); {
+			collectAddresses(iter.Key(), path+"[key]", addresses)
+			collectAddresses(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), addresses)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			collectAddresses(v.Field(i), path+"."+v.Type().Field(i).Name, addresses)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectAddresses(v.Index(i), fmt.Sprintf("%s[%d]", path, i), addresses)
+		}
+	}
+}
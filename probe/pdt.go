@@ -0,0 +1,151 @@
+package probe
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pdtDriftTolerance is the drift between EXT-X-PROGRAM-DATE-TIME anchors and
+// EXTINF durations above which a warning is reported
+const pdtDriftTolerance = time.Second
+
+// pdtLayouts are the accepted EXT-X-PROGRAM-DATE-TIME formats
+var pdtLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"}
+
+// PDTAnchor maps a media segment to wall-clock time
+type PDTAnchor struct {
+	// Sequence is the segment's media sequence number
+	Sequence int64 `json:"sequence"`
+
+	// Time is the segment's EXT-X-PROGRAM-DATE-TIME
+	Time time.Time `json:"time"`
+
+	// Offset is the sum of the EXTINF durations before the segment, in seconds
+	Offset float64 `json:"offset"`
+}
+
+// ProgramDateTime summarizes the EXT-X-PROGRAM-DATE-TIME anchors of a media playlist
+type ProgramDateTime struct {
+	// First and Last are the earliest and latest anchors
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+
+	// Drift is the wall-clock time elapsed between anchors minus the EXTINF
+	// durations between them, in seconds, summed over consecutive anchors
+	// that are not separated by a discontinuity. Positive values mean the
+	// program date time advances faster than the media.
+	Drift float64 `json:"drift"`
+
+	// MaxDrift is the largest absolute drift between consecutive anchors, in seconds
+	MaxDrift float64 `json:"max_drift"`
+
+	Anchors []PDTAnchor `json:"anchors"`
+}
+
+// parseHLSProgramDateTime extracts the program date time anchors of a media
+// playlist (nil if it has none)
+func parseHLSProgramDateTime(content string) *ProgramDateTime {
+	var parser pdtParser
+	for lines := scanLines(content); lines.scan(); {
+		parser.parseLine(strings.TrimSpace(lines.line))
+	}
+	return parser.finish()
+}
+
+// pdtParser collects the program date time anchors of the lines of a playlist
+type pdtParser struct {
+	anchors       []PDTAnchor
+	discontinuous []bool
+
+	sequence         int64
+	offset, duration float64
+	pending          time.Time
+	hasPending       bool
+	discontinuity    bool
+}
+
+// parseLine parses a line with surrounding whitespace removed
+func (p *pdtParser) parseLine(line string) {
+	switch {
+	case line == "":
+	case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+		p.sequence, _ = strconv.ParseInt(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+	case strings.HasPrefix(line, "#EXTINF:"):
+		value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+		p.duration, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+	case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+		if pdt, ok := parsePDT(strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:")); ok {
+			p.pending, p.hasPending = pdt, true
+		}
+	case line == "#EXT-X-DISCONTINUITY":
+		p.discontinuity = true
+	case strings.HasPrefix(line, "#"):
+	default:
+		// A URI line ends the segment
+		if p.hasPending {
+			p.anchors = append(p.anchors, PDTAnchor{Sequence: p.sequence, Time: p.pending, Offset: p.offset})
+			p.discontinuous = append(p.discontinuous, p.discontinuity)
+			p.discontinuity = false
+		}
+		p.sequence++
+		p.offset += p.duration
+		p.duration = 0
+		p.hasPending = false
+	}
+}
+
+// finish summarizes the anchors of the parsed lines (nil if there are none)
+func (p *pdtParser) finish() *ProgramDateTime {
+	anchors := p.anchors
+	if len(anchors) == 0 {
+		return nil
+	}
+
+	info := &ProgramDateTime{First: anchors[0].Time, Last: anchors[0].Time, Anchors: anchors}
+	for i, anchor := range anchors {
+		if anchor.Time.Before(info.First) {
+			info.First = anchor.Time
+		}
+		if anchor.Time.After(info.Last) {
+			info.Last = anchor.Time
+		}
+		if i == 0 || p.discontinuous[i] {
+			continue
+		}
+
+		previous := anchors[i-1]
+		drift := anchor.Time.Sub(previous.Time).Seconds() - (anchor.Offset - previous.Offset)
+		info.Drift += drift
+		info.MaxDrift = math.Max(info.MaxDrift, math.Abs(drift))
+	}
+
+	info.Drift = roundMillis(info.Drift)
+	info.MaxDrift = roundMillis(info.MaxDrift)
+	return info
+}
+
+// parsePDT parses an EXT-X-PROGRAM-DATE-TIME value
+func parsePDT(value string) (time.Time, bool) {
+	for _, layout := range pdtLayouts {
+		if pdt, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return pdt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// roundMillis rounds seconds to millisecond precision, hiding float noise
+func roundMillis(seconds float64) float64 {
+	return math.Round(seconds*1000) / 1000
+}
+
+// pdtWarning describes excessive program date time drift ("" if within tolerance)
+func pdtWarning(info *ProgramDateTime) string {
+	if info == nil || info.MaxDrift <= pdtDriftTolerance.Seconds() {
+		return ""
+	}
+	return fmt.Sprintf("EXT-X-PROGRAM-DATE-TIME drifts up to %.3fs from EXTINF durations (total %.3fs)", info.MaxDrift, info.Drift)
+}
@@ -0,0 +1,106 @@
+package probe
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHLSProgramDateTime(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-ALLOW-CACHE:NO
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T10:00:00.000Z
+#EXTINF:6.000,
+seg100.ts
+#EXTINF:6.000,
+seg101.ts
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T10:00:12.500+00:00
+#EXTINF:6.000,
+seg102.ts
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T11:00:00.000Z
+#EXTINF:4.000,
+seg103.ts
+#EXTINF:4.000,
+seg104.ts
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T11:00:08.000Z
+#EXTINF:4.000,
+seg105.ts
+`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/live/media.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pdt := output.ProgramDateTime
+	if pdt == nil {
+		t.Fatal("Expected program date time info")
+	}
+
+	expectedAnchors := []PDTAnchor{
+		{Sequence: 100, Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Offset: 0},
+		{Sequence: 102, Time: time.Date(2024, 5, 1, 10, 0, 12, 500000000, time.UTC), Offset: 12},
+		{Sequence: 103, Time: time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC), Offset: 18},
+		{Sequence: 105, Time: time.Date(2024, 5, 1, 11, 0, 8, 0, time.UTC), Offset: 26},
+	}
+	if len(pdt.Anchors) != len(expectedAnchors) {
+		t.Fatalf("Expected %d anchors, got %d", len(expectedAnchors), len(pdt.Anchors))
+	}
+	for i, anchor := range pdt.Anchors {
+		expected := expectedAnchors[i]
+		if anchor.Sequence != expected.Sequence || !anchor.Time.Equal(expected.Time) || anchor.Offset != expected.Offset {
+			t.Errorf("Expected anchor %+v, got %+v", expected, anchor)
+		}
+	}
+
+	if !pdt.First.Equal(expectedAnchors[0].Time) || !pdt.Last.Equal(expectedAnchors[3].Time) {
+		t.Errorf("Expected first %v and last %v, got %v and %v", expectedAnchors[0].Time, expectedAnchors[3].Time, pdt.First, pdt.Last)
+	}
+
+	// The jump across the discontinuity is not drift
+	if pdt.Drift != 0.5 || pdt.MaxDrift != 0.5 {
+		t.Errorf("Expected drift 0.5 and max drift 0.5, got %v and %v", pdt.Drift, pdt.MaxDrift)
+	}
+	if len(output.Warnings) != 0 {
+		t.Errorf("Expected no warnings within tolerance, got %v", output.Warnings)
+	}
+
+	found := false
+	for _, feature := range output.Features {
+		found = found || feature == "allow-cache"
+	}
+	if !found {
+		t.Errorf("Expected allow-cache feature, got %v", output.Features)
+	}
+}
+
+func TestProgramDateTimeDriftWarning(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T10:00:00Z
+#EXTINF:6.0,
+seg0.ts
+#EXT-X-PROGRAM-DATE-TIME:2024-05-01T10:00:08Z
+#EXTINF:6.0,
+seg1.ts
+`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/live/media.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(output.Warnings) != 1 || !strings.Contains(output.Warnings[0], "drifts up to 2.000s") {
+		t.Errorf("Expected a drift warning, got %v", output.Warnings)
+	}
+}
+
+func TestParseHLSProgramDateTimeAbsent(t *testing.T) {
+	if pdt := parseHLSProgramDateTime("#EXTM3U\n#EXTINF:6.0,\nseg0.ts\n"); pdt != nil {
+		t.Errorf("Expected nil, got %+v", pdt)
+	}
+}
@@ -0,0 +1,165 @@
+package probe
+
+import "encoding/json"
+
+// PeriodMode selects how the streams of multi-period DASH manifests are
+// reported (see ProbeOptions.Periods)
+type PeriodMode string
+
+const (
+	// PeriodsAll reports the streams of every period (the default), so
+	// streams repeated across periods, as in SSAI manifests, are reported
+	// once per period
+	PeriodsAll PeriodMode = "all"
+	// PeriodsDedupe reports streams identical to a stream of an earlier
+	// period once: Output.Periods lists the kept stream in each period
+	PeriodsDedupe PeriodMode = "dedupe"
+)
+
+// PeriodModes returns the supported period modes
+func PeriodModes() []PeriodMode {
+	return []PeriodMode{PeriodsAll, PeriodsDedupe}
+}
+
+// validPeriodMode reports whether mode is supported ("" = PeriodsAll)
+func validPeriodMode(mode PeriodMode) bool {
+	if mode == "" {
+		return true
+	}
+	for _, supported := range PeriodModes() {
+		if mode == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// PeriodInfo is a period of a multi-period DASH manifest
+type PeriodInfo struct {
+	// ID is the Period@id
+	ID string `json:"id,omitempty"`
+
+	// Start is the start of the period in the presentation, in seconds (nil
+	// when it cannot be resolved, as for a dynamic period without @start)
+	Start *float64 `json:"start,omitempty"`
+
+	// Duration is the duration of the period in seconds (0 = unknown, e.g. the
+	// last period of a live MPD)
+	Duration float64 `json:"duration,omitempty"`
+
+	// StreamIDs are the streams of the period
+	StreamIDs []string `json:"stream_ids"`
+}
+
+// mpdPeriods describes the periods of a multi-period MPD with their streams
+// (nil for a single period)
+func mpdPeriods(mpd *MPD, streams []StreamInfo) []PeriodInfo {
+	if len(mpd.Periods) < 2 {
+		return nil
+	}
+
+	periods := make([]PeriodInfo, len(mpd.Periods))
+	var previousEnd *float64
+	for i, period := range mpd.Periods {
+		periods[i].ID = period.ID
+		periods[i].StreamIDs = []string{}
+
+		var start *float64
+		if d, err := parseISODuration(period.Start); err == nil {
+			seconds := d.Seconds()
+			start = &seconds
+		} else if i == 0 && mpd.Type != "dynamic" {
+			start = new(float64)
+		} else {
+			start = previousEnd
+		}
+		periods[i].Start = start
+
+		duration, bounded := mpdPeriodDuration(mpd, i)
+		if bounded {
+			periods[i].Duration = duration
+		}
+		previousEnd = nil
+		if start != nil && bounded {
+			end := *start + duration
+			previousEnd = &end
+		}
+	}
+
+	for _, stream := range streams {
+		period := &periods[stream.element.period]
+		period.StreamIDs = append(period.StreamIDs, stream.StreamID)
+	}
+	return periods
+}
+
+// dedupePeriods removes the streams identical to a stream of an earlier
+// period, replacing their stream IDs with the kept stream's in the periods,
+// DRM systems and compatibility findings
+func (o *Output) dedupePeriods() {
+	if len(o.Periods) < 2 {
+		return
+	}
+
+	// Streams identical within a period are distinct representations
+	kept := make(map[string]StreamInfo)
+	replaced := make(map[string]string)
+	streams := o.Streams[:0]
+	for _, stream := range o.Streams {
+		key := periodStreamKey(stream)
+		if first, ok := kept[key]; !ok {
+			kept[key] = stream
+		} else if first.element.period < stream.element.period {
+			replaced[stream.StreamID] = first.StreamID
+			continue
+		}
+		streams = append(streams, stream)
+	}
+	if len(replaced) == 0 {
+		return
+	}
+	o.Streams = streams
+
+	for i := range o.Periods {
+		o.Periods[i].StreamIDs = replaceStreamIDs(o.Periods[i].StreamIDs, replaced)
+	}
+	if o.DRM != nil {
+		for i := range o.DRM.Systems {
+			o.DRM.Systems[i].StreamIDs = replaceStreamIDs(o.DRM.Systems[i].StreamIDs, replaced)
+		}
+	}
+	for i := range o.Compatibility {
+		o.Compatibility[i].StreamIDs = replaceStreamIDs(o.Compatibility[i].StreamIDs, replaced)
+	}
+}
+
+// periodStreamKey identifies a stream independently of its period: the
+// representation IDs, URLs and segments of repeated content differ between
+// periods
+func periodStreamKey(stream StreamInfo) string {
+	stream.StreamID = ""
+	stream.Source = nil
+	stream.NativeID = ""
+	stream.BaseURLs = nil
+	stream.Segments = nil
+	stream.Excerpt = ""
+	key, _ := json.Marshal(stream)
+	return string(key)
+}
+
+// replaceStreamIDs replaces the stream IDs of ids found in replaced,
+// dropping duplicates
+func replaceStreamIDs(ids []string, replaced map[string]string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if replacement, ok := replaced[id]; ok {
+			id = replacement
+		}
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
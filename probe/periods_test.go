@@ -0,0 +1,117 @@
+package probe
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// ssaiMPD is a multi-period MPD with the same content around an ad period
+const ssaiMPD = `<MPD type="static" mediaPresentationDuration="PT70S">
+<Period id="content-1" start="PT0S">
+<AdaptationSet contentType="video" mimeType="video/mp4" codecs="avc1.640028">
+<Representation id="v-1" bandwidth="5000000" width="1920" height="1080"/>
+<Representation id="v-2" bandwidth="5000000" width="1920" height="1080"/></AdaptationSet>
+<AdaptationSet contentType="audio" mimeType="audio/mp4" codecs="mp4a.40.2" lang="en">
+<Representation id="a-1" bandwidth="128000"/></AdaptationSet></Period>
+<Period id="ad-1" start="PT30S" duration="PT10S">
+<AdaptationSet contentType="video" mimeType="video/mp4" codecs="avc1.64001f">
+<Representation id="ad-v" bandwidth="3000000" width="1280" height="720"/></AdaptationSet></Period>
+<Period id="content-2" start="PT40S">
+<AdaptationSet contentType="video" mimeType="video/mp4" codecs="avc1.640028">
+<Representation id="v-3" bandwidth="5000000" width="1920" height="1080"/></AdaptationSet>
+<AdaptationSet contentType="audio" mimeType="audio/mp4" codecs="mp4a.40.2" lang="en">
+<Representation id="a-2" bandwidth="128000"/></AdaptationSet></Period>
+</MPD>`
+
+func TestMPDPeriods(t *testing.T) {
+	start := func(seconds float64) *float64 { return &seconds }
+
+	tests := []struct {
+		name     string
+		mpd      string
+		expected []PeriodInfo
+	}{
+		{
+			name: "starts and durations",
+			mpd:  ssaiMPD,
+			expected: []PeriodInfo{
+				{ID: "content-1", Start: start(0), Duration: 30, StreamIDs: []string{"0:0", "0:1", "0:4(en)"}},
+				{ID: "ad-1", Start: start(30), Duration: 10, StreamIDs: []string{"0:2"}},
+				{ID: "content-2", Start: start(40), Duration: 30, StreamIDs: []string{"0:3", "0:5(en)"}},
+			},
+		},
+		{
+			name: "starts from the previous period",
+			mpd: `<MPD type="static"><Period id="p0" duration="PT30S"><AdaptationSet contentType="audio">
+<Representation id="a" bandwidth="128000"/></AdaptationSet></Period>
+<Period id="p1" duration="PT15S"/><Period id="p2" duration="PT5S"/></MPD>`,
+			expected: []PeriodInfo{
+				{ID: "p0", Start: start(0), Duration: 30, StreamIDs: []string{"0:0"}},
+				{ID: "p1", Start: start(30), Duration: 15, StreamIDs: []string{}},
+				{ID: "p2", Start: start(45), Duration: 5, StreamIDs: []string{}},
+			},
+		},
+		{
+			name: "single period",
+			mpd: `<MPD type="static"><Period><AdaptationSet contentType="audio">
+<Representation id="a" bandwidth="128000"/></AdaptationSet></Period></MPD>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseMPDManifest(tt.mpd, "https://cdn.example.com/manifest.mpd")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.Periods, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, output.Periods)
+			}
+		})
+	}
+}
+
+func TestDedupePeriods(t *testing.T) {
+	output, err := parseMPDManifest(ssaiMPD, "https://cdn.example.com/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output.dedupePeriods()
+
+	var ids []string
+	for _, stream := range output.Streams {
+		ids = append(ids, stream.StreamID)
+	}
+	// v-2 repeats v-1 within its period, so both are kept; v-3 and a-2 repeat them
+	if expected := []string{"0:0", "0:1", "0:2", "0:4(en)"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected streams %q, got %q", expected, ids)
+	}
+
+	var periods [][]string
+	for _, period := range output.Periods {
+		periods = append(periods, period.StreamIDs)
+	}
+	if expected := [][]string{{"0:0", "0:1", "0:4(en)"}, {"0:2"}, {"0:0", "0:4(en)"}}; !reflect.DeepEqual(periods, expected) {
+		t.Errorf("Expected period streams %q, got %q", expected, periods)
+	}
+}
+
+func TestProbePeriods(t *testing.T) {
+	server := manifestServer(ssaiMPD)
+	defer server.Close()
+
+	for mode, expected := range map[PeriodMode]int{"": 6, PeriodsAll: 6, PeriodsDedupe: 4} {
+		output, err := NewProber(&ProbeOptions{Periods: mode}).Probe(context.Background(), server.URL+"/manifest.mpd")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(output.Streams) != expected {
+			t.Errorf("Expected %d streams with %q, got %d", expected, mode, len(output.Streams))
+		}
+	}
+
+	if _, err := NewProber(&ProbeOptions{Periods: "split"}).Probe(context.Background(), server.URL+"/manifest.mpd"); err == nil {
+		t.Error("Expected a validation error for an unknown period mode")
+	}
+}
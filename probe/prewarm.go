@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Prewarm opens connections to the origins of hosts ahead of probing, so the
+// first probe of each origin does not pay for DNS, TCP and TLS setup. Hosts
+// are origins ("https://cdn.example.com"), bare host names (https is
+// assumed) or any URL on the origin. Connections are cached in the
+// Prober's per-origin clients and reused by later probes while they stay
+// idle within the transport's idle timeout.
+//
+// Prewarm sends a HEAD request to each origin's root; any HTTP response
+// counts as success. It returns the failures joined, or nil.
+func (p *Prober) Prewarm(ctx context.Context, hosts []string) error {
+	ctx = withLogger(ctx, p.logger)
+
+	var origins []string
+	seen := make(map[string]bool)
+	var errs []error
+	for _, host := range hosts {
+		origin, err := prewarmOrigin(host)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+
+	failures := make([]error, len(origins))
+	var wg sync.WaitGroup
+	for i, origin := range origins {
+		wg.Add(1)
+		go func(i int, origin string) {
+			defer wg.Done()
+			failures[i] = p.prewarm(ctx, origin)
+		}(i, origin)
+	}
+	wg.Wait()
+
+	for _, err := range failures {
+		if err != nil {
+			errs = append(errs, p.redactor.redactError(err))
+		}
+	}
+
+	logInfo(ctx, "Connection prewarm completed", map[string]interface{}{
+		"origins": len(origins),
+		"failed":  len(errs),
+	})
+
+	return errors.Join(errs...)
+}
+
+// prewarmOrigin normalizes a Prewarm host to an origin URL
+func prewarmOrigin(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if host != "" && !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	parsedURL, err := validateURL(host)
+	if err != nil {
+		return "", err
+	}
+	return parsedURL.Scheme + "://" + parsedURL.Host, nil
+}
+
+// prewarm opens a connection to origin through its client
+func (p *Prober) prewarm(ctx context.Context, origin string) error {
+	parsedURL, err := validateURL(origin + "/")
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := p.httpClient(parsedURL)
+	if err != nil {
+		return err
+	}
+
+	if err := p.slots.acquire(ctx); err != nil {
+		return err
+	}
+	defer p.slots.release()
+
+	start := p.clock.Now()
+	resp, err := httpClient.client.R().SetContext(ctx).Head(parsedURL.String())
+	if err != nil {
+		if isTimeoutError(err) {
+			return NewTimeoutError(parsedURL.String(), 30) // Default timeout
+		}
+		return NewNetworkError(parsedURL.String(), fmt.Errorf("prewarm failed: %w", err))
+	}
+
+	logDebug(ctx, "Connection prewarmed", map[string]interface{}{
+		"origin":   origin,
+		"status":   resp.StatusCode,
+		"duration": p.clock.Now().Sub(start),
+	})
+	return nil
+}
@@ -0,0 +1,95 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPrewarmOrigin(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+		invalid  bool
+	}{
+		{host: "cdn.example.com", expected: "https://cdn.example.com"},
+		{host: "http://cdn.example.com:8080", expected: "http://cdn.example.com:8080"},
+		{host: "https://cdn.example.com/live/manifest.mpd?token=abc", expected: "https://cdn.example.com"},
+		{host: "", invalid: true},
+		{host: "ftp://cdn.example.com", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			origin, err := prewarmOrigin(tt.host)
+			if tt.invalid {
+				if err == nil {
+					t.Errorf("Expected an error, got %q", origin)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if origin != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, origin)
+			}
+		})
+	}
+}
+
+func TestPrewarmReusesConnection(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manifest.m3u8" {
+			w.Write([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720\n720p.m3u8\n"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{DisableCamouflage: true})
+	if err := prober.Prewarm(context.Background(), []string{server.URL, server.URL + "/other"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := connections.Load(); got != 1 {
+		t.Fatalf("Expected 1 connection after prewarm, got %d", got)
+	}
+
+	if _, err := prober.Probe(context.Background(), server.URL+"/manifest.m3u8"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := connections.Load(); got != 1 {
+		t.Errorf("Expected the probe to reuse the prewarmed connection, got %d connections", got)
+	}
+}
+
+func TestPrewarmFailures(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	closedURL := server.URL
+	server.Close()
+
+	err := NewProber(nil).Prewarm(context.Background(), []string{"ftp://cdn.example.com", closedURL})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	var probeErr *ProbeError
+	if !errors.As(err, &probeErr) {
+		t.Errorf("Expected a ProbeError, got %v", err)
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("Expected 2 joined errors, got %v", err)
+	}
+}
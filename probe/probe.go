@@ -7,7 +7,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,11 +26,200 @@ type StreamInfo struct {
 	SampleFmt  string `json:"sample_fmt,omitempty"`
 	SampleRate string `json:"sample_rate,omitempty"`
 	Language   string `json:"language,omitempty"`
+	Name       string `json:"name,omitempty"`
+	GroupID    string `json:"group_id,omitempty"`
+
+	// StableRenditionID is the STABLE-RENDITION-ID of an HLS rendition
+	StableRenditionID string `json:"stable_rendition_id,omitempty"`
+
+	// VariantIndexes are the indexes in Output.Variants of the variants
+	// using an HLS audio rendition (see ProbeOptions.AudioGroups)
+	VariantIndexes []int `json:"variant_indexes,omitempty"`
+
+	// AverageBandwidth, HDCPLevel, StableVariantID and Score are the
+	// AVERAGE-BANDWIDTH (bits/s), HDCP-LEVEL, STABLE-VARIANT-ID and SCORE of
+	// the HLS variant of the stream, for ladder auditing
+	AverageBandwidth int64    `json:"average_bandwidth,omitempty"`
+	HDCPLevel        string   `json:"hdcp_level,omitempty"`
+	StableVariantID  string   `json:"stable_variant_id,omitempty"`
+	Score            *float64 `json:"score,omitempty"`
+
+	// NativeID identifies the stream's manifest element across probes, for
+	// joining results with packager configs and player logs: the DASH
+	// Representation@id; the HLS STABLE-VARIANT-ID or URI of a variant (shared
+	// by its video and audio streams); or the STABLE-RENDITION-ID, URI or
+	// INSTREAM-ID of a rendition, falling back to "GROUP-ID/NAME"
+	NativeID string `json:"native_id,omitempty"`
+
+	Disposition *Disposition `json:"disposition,omitempty"`
+
+	// Roles, Accessibility and Labels are the DASH Role values, Accessibility
+	// descriptors (with their caption channels) and Label elements of the
+	// stream's adaptation set, for accessibility audits
+	Roles         []string        `json:"roles,omitempty"`
+	Accessibility []Accessibility `json:"accessibility,omitempty"`
+	Labels        []Label         `json:"labels,omitempty"`
+
+	// SampleAspectRatio and DisplayAspectRatio are reported like ffprobe
+	// ("1:1", "16:9"). Anamorphic streams have a non-square sample aspect ratio.
+	SampleAspectRatio  string `json:"sample_aspect_ratio,omitempty"`
+	DisplayAspectRatio string `json:"display_aspect_ratio,omitempty"`
+
+	// Projection is the 360° video projection ("equirectangular", "cubemap")
+	Projection string `json:"projection,omitempty"`
+
+	// StereoMode is the stereoscopic frame packing, named like ffprobe's
+	// stereo3d side data ("side by side", "top and bottom", ...)
+	StereoMode string `json:"stereo_mode,omitempty"`
+
+	// Source locates the manifest element the stream was derived from
+	Source *SourceRef `json:"source,omitempty"`
+
+	// BaseURLs lists the alternative base URLs of a DASH stream with more than one
+	BaseURLs []BaseURLCandidate `json:"base_urls,omitempty"`
+
+	// Segments describes the segment addressing of a DASH stream
+	Segments *SegmentInfo `json:"segments,omitempty"`
+
+	// Excerpt is the raw manifest source of the stream (with ProbeOptions.ExcerptBytes)
+	Excerpt string `json:"excerpt,omitempty"`
+
+	// element locates the stream's Representation in an MPD
+	element mpdElementIndex
+
+	// urls are the first URLs a player fetches for the stream (see Output.ResolveURLs)
+	urls []string
+}
+
+// SourceRef locates the manifest element a stream was derived from, for
+// mapping probe results back to the manifest
+type SourceRef struct {
+	// Tag is the MPD element ("Representation") or HLS tag ("EXT-X-STREAM-INF", "EXT-X-MEDIA")
+	Tag string `json:"tag"`
+
+	// PeriodID, AdaptationSetID and RepresentationID are the MPD @id attributes
+	PeriodID         string `json:"period_id,omitempty"`
+	AdaptationSetID  string `json:"adaptation_set_id,omitempty"`
+	RepresentationID string `json:"representation_id,omitempty"`
+
+	// Line is the 1-based line number of the HLS tag
+	Line int `json:"line,omitempty"`
+
+	// GroupID is the HLS rendition group (GROUP-ID, or the variant's VIDEO/AUDIO group)
+	GroupID string `json:"group_id,omitempty"`
+
+	// StableVariantID is the STABLE-VARIANT-ID of an HLS variant
+	StableVariantID string `json:"stable_variant_id,omitempty"`
+
+	// URI is the HLS variant or rendition URI as written in the manifest
+	URI string `json:"uri,omitempty"`
 }
 
 // Output represents the complete probe output
 type Output struct {
-	Streams []StreamInfo `json:"streams"`
+	Streams         []StreamInfo     `json:"streams"`
+	Start           *StartInfo       `json:"start,omitempty"`
+	Interstitials   []Interstitial   `json:"interstitials,omitempty"`
+	AdBreaks        []AdBreak        `json:"ad_breaks,omitempty"`
+	Warnings        []string         `json:"warnings,omitempty"`
+	Bitrates        []BitrateStats   `json:"bitrates,omitempty"`
+	Timing          *ManifestTiming  `json:"timing,omitempty"`
+	ProgramDateTime *ProgramDateTime `json:"program_date_time,omitempty"`
+	MediaPlaylist   *MediaPlaylist   `json:"media_playlist,omitempty"`
+	Features        []string         `json:"features,omitempty"`
+	Periods         []PeriodInfo     `json:"periods,omitempty"`
+	DRM             *DRMInfo         `json:"drm,omitempty"`
+	Variants        []HLSVariant     `json:"variants,omitempty"`
+	Decodes         []DecodeResult   `json:"decodes,omitempty"`
+	Bitstream       []BitstreamCheck `json:"bitstream,omitempty"`
+	BaseURLChecks   []BaseURLCheck   `json:"base_url_checks,omitempty"`
+
+	// Partial reports that the probe deadline cut deep-probe checks short, so
+	// that their absence from Bitstream or BaseURLChecks is not a pass;
+	// Incomplete lists them
+	Partial    bool              `json:"partial,omitempty"`
+	Incomplete []IncompleteCheck `json:"incomplete,omitempty"`
+
+	// Metadata maps the DATA-ID of HLS EXT-X-SESSION-DATA entries to the
+	// entries, one per language, in manifest order
+	Metadata map[string][]SessionData `json:"metadata,omitempty"`
+
+	// Compatibility lists codec mixes that break playback on some devices
+	Compatibility []CompatibilityFinding `json:"compatibility,omitempty"`
+
+	// Rules are the findings of the rule packs selected in ProbeOptions.RulePacks
+	Rules []RuleFinding `json:"rules,omitempty"`
+
+	// Rebuffer is the rebuffer risk estimate of ProbeOptions.Throttle
+	Rebuffer *RebufferEstimate `json:"rebuffer,omitempty"`
+
+	// Speculation reports the speculative fetch of ProbeOptions.Speculation
+	Speculation *SpeculationResult `json:"speculation,omitempty"`
+
+	// Event is set for live events that have not started yet
+	Event *EventStatus `json:"event,omitempty"`
+
+	// Format describes the presentation as a whole: its format, whether it is
+	// live and its live edge
+	Format *Format `json:"format,omitempty"`
+
+	// ProgramVersion records the goprobe build that produced the output
+	ProgramVersion *ProgramVersion `json:"program_version,omitempty"`
+
+	// Provenance records how the output was produced (nil without ProbeOptions.Provenance)
+	Provenance *Provenance `json:"provenance,omitempty"`
+
+	// Cache is the CDN cache behavior reported by the manifest response
+	// (nil when it has no cache headers)
+	Cache *CacheStatus `json:"cache,omitempty"`
+
+	// url is the manifest URL after redirects, redacted, as reported in
+	// finding locations, SARIF logs and explanations
+	url string
+
+	// schema is the JSON schema of Streams (see ProbeOptions.Schema)
+	schema OutputSchema
+
+	// variants locates HLS variant media playlists for sub-requests
+	variants []hlsVariantRef
+
+	// variantPlaylists are the fetched media playlists of variants, shared by
+	// the stages that need them (nil until fetchVariantPlaylists)
+	variantPlaylists []variantPlaylistFetch
+
+	// iframes are the HLS I-frame playlist streams, numbered after Streams
+	// and appended to them with ProbeOptions.IFrameStreams
+	iframes []StreamInfo
+
+	// audioRenditions are the HLS audio rendition streams, numbered after
+	// iframes, that replace the variant audio streams of their groups with
+	// ProbeOptions.AudioGroups
+	audioRenditions []StreamInfo
+
+	// audioGroups are the IDs of the audioRenditions streams of each AUDIO group
+	audioGroups map[string][]string
+
+	// scheduledStart is when a dynamic MPD becomes available (zero if unknown)
+	scheduledStart time.Time
+
+	// ended reports a finished presentation: an HLS playlist with
+	// EXT-X-ENDLIST or a static MPD
+	ended bool
+}
+
+// StartInfo describes the player start position intended by the manifest
+type StartInfo struct {
+	// Source is the manifest element the position comes from
+	// ("EXT-X-START" or "suggestedPresentationDelay")
+	Source string `json:"source"`
+
+	// Offset is the start position in seconds. Positive values count from the
+	// beginning of the presentation, negative values back from its end or live edge.
+	Offset float64 `json:"offset"`
+
+	// Precise reports EXT-X-START PRECISE=YES (start exactly at Offset rather
+	// than at the enclosing segment boundary)
+	Precise bool `json:"precise,omitempty"`
 }
 
 // ProbeOptions contains configuration for probing manifests
@@ -50,12 +241,299 @@ type ProbeOptions struct {
 	
 	// DisableCamouflage disables browser-like headers (origin, referer, etc.)
 	DisableCamouflage bool
+
+	// Camouflage toggles individual camouflage headers (nil = all defaults)
+	Camouflage *CamouflagePolicy
+
+	// PerHostOverrides replaces headers, proxy, timeout and camouflage for
+	// requests to matching hosts, keyed by host name or "*.domain" pattern, so
+	// one Prober can serve origins that need special handling (nil = none)
+	PerHostOverrides map[string]*HostOverride
+
+	// AcceptHeader overrides the Accept header, independently of camouflage
+	AcceptHeader string
+
+	// NegotiateFormat sends only DASH types for .mpd URLs and only HLS types
+	// for .m3u8 URLs (ignored when AcceptHeader is set)
+	NegotiateFormat bool
+
+	// ContentTypePolicy validates the response Content-Type against the
+	// detected format (nil = no validation)
+	ContentTypePolicy *ContentTypePolicy
+
+	// IFrameStreams reports HLS I-frame playlists (EXT-X-I-FRAME-STREAM-INF)
+	// as streams of type IFrameStreamType after the other streams. Without it
+	// they are excluded, and only reported by the "i-frames" feature.
+	IFrameStreams bool
+
+	// AudioGroups reports one audio stream per unique HLS audio rendition
+	// (EXT-X-MEDIA TYPE=AUDIO) instead of one per variant, with the variants
+	// using it. Variants of groups without renditions keep their audio stream.
+	AudioGroups bool
+
+	// StreamOrder reorders Output.Streams in manifest order or by type,
+	// language or bandwidth, keeping their stream IDs ("" = the parser's order)
+	StreamOrder StreamOrder
+
+	// Periods selects how the streams of multi-period DASH manifests are
+	// reported: PeriodsAll, or PeriodsDedupe to report streams repeated
+	// across periods once ("" = PeriodsAll)
+	Periods PeriodMode
+
+	// Schema selects the stream fields of JSON output: SchemaLegacy,
+	// SchemaFFprobe, or SchemaDual with both while consumers migrate to the
+	// ffprobe names ("" = SchemaLegacy)
+	Schema OutputSchema
+
+	// AnalyzeBitrates fetches HLS variant media playlists and reports their
+	// EXT-X-BITRATE statistics in Output.Bitrates
+	AnalyzeBitrates bool
+
+	// FollowVariants fetches HLS variant media playlists concurrently and
+	// reports their segment count, duration, container and encryption in
+	// Output.Variants
+	FollowVariants bool
+
+	// DeepProbe fetches the start of each stream's first media segment and
+	// checks its bitstream against the declared codec in Output.Bitstream
+	// (nil = disabled)
+	DeepProbe *DeepProbe
+
+	// DecodeCheck runs an external decoder against selected streams and
+	// reports the outcome in Output.Decodes (nil = disabled)
+	DecodeCheck *DecodeCheck
+
+	// RulePacks selects compliance rule packs by name (e.g. RulePackAppleHLS),
+	// reported in Output.Rules (nil = none). See RulePacks for the available packs.
+	RulePacks []string
+
+	// Throttle shapes the download bandwidth of every fetch and estimates the
+	// rebuffer risk of one rendition in Output.Rebuffer (nil = disabled)
+	Throttle *Throttle
+
+	// Speculation fetches the likely top variant of an HLS master playlist in
+	// parallel with the master playlist, reported in Output.Speculation (nil = disabled)
+	Speculation *Speculation
+
+	// ExcerptBytes includes up to N bytes of the manifest source of each
+	// stream (the Representation XML or M3U8 lines) in StreamInfo.Excerpt
+	// (0 = disabled)
+	ExcerptBytes int
+
+	// SniffBytes fetches only the first N bytes with a Range request to check
+	// the format before downloading the full manifest (0 = disabled)
+	SniffBytes int64
 	
 	// RetryConfig configures retry behavior (nil = no retries)
 	RetryConfig *RetryConfig
 	
 	// CircuitBreakerConfig configures circuit breaker (nil = disabled)
 	CircuitBreakerConfig *CircuitBreakerConfig
+
+	// Logger receives log output for probes using these options (nil = global logger)
+	Logger Logger
+
+	// LogConfig configures log sampling and redaction (nil = no sampling, default redaction)
+	LogConfig *LogConfig
+
+	// Archiver persists every fetched manifest with its metadata (nil = disabled)
+	Archiver Archiver
+
+	// Sinks receive a Record for every probe, including the probes of
+	// ProbeBatch (nil = none). Sinks cannot be stored in config profiles.
+	Sinks []OutputSink `json:"-"`
+
+	// Cassette records every HTTP interaction of the probes, or replays a
+	// recorded cassette without network access (nil = disabled). Cassettes
+	// cannot be stored in config profiles.
+	Cassette *Cassette `json:"-"`
+
+	// Limits bounds connections, concurrency, and body size (nil = defaults)
+	Limits *Limits
+
+	// DNSCache caches host lookups across the Prober's clients, with TTL
+	// bounds (nil = no caching beyond the system resolver)
+	DNSCache *DNSCacheConfig
+
+	// ParseCache caches parsed manifests by content hash across the Prober's
+	// probes (nil = disabled)
+	ParseCache *ParseCacheConfig
+
+	// Scheduler bounds the concurrency of batch probes, watch polls and
+	// deep-probe segment fetches with one shared worker pool, reporting queue
+	// wait times in Prober.SchedulerStats (nil = each feature bounds its own)
+	Scheduler *SchedulerConfig
+
+	// Clock is the source of time for retries, circuit breakers, watches and
+	// archive entries, unless RetryConfig or CircuitBreakerConfig set their
+	// own (nil = system clock)
+	Clock Clock
+
+	// Rand randomizes retry jitter, unless RetryConfig sets its own (nil = math/rand)
+	Rand Rand
+
+	// Provenance adds Output.Provenance with the probe time, version, options
+	// hash, egress address and request IDs (nil = disabled)
+	Provenance *ProvenanceConfig
+
+	// Deterministic makes repeated probes of the same manifest produce identical
+	// output for golden-file comparisons: timing is omitted, query strings are
+	// removed from effective URLs, collections are sorted, and batch durations
+	// are zeroed
+	Deterministic bool
+}
+
+// Prober probes manifests with a fixed set of options. Unlike the package-level
+// logger, a Prober's logger only sees its own probes, which makes it the
+// preferred entry point for services embedding goprobe. A Prober is safe for
+// concurrent use.
+type Prober struct {
+	opts     *ProbeOptions
+	logger   *probeLogger
+	redactor *redactor
+	slots    requestSlots
+	clock    Clock
+	dnsCache *dnsCache
+	shaper   *bandwidthShaper
+
+	// parseCache caches parse results (nil without ParseCache)
+	parseCache *parseCache
+
+	// topVariants remembers the top variant of probed master playlists (nil without Speculation)
+	topVariants *topVariants
+
+	// scheduler runs batch, watch and deep-probe tasks (nil without Scheduler)
+	scheduler *scheduler
+
+	// parent is the prober whose circuit breakers p shares (nil = its own)
+	parent *Prober
+
+	clientsMu sync.Mutex
+	clients   map[string]*HTTPClient
+}
+
+// NewProber creates a new prober using the given options (nil = defaults)
+func NewProber(opts *ProbeOptions) *Prober {
+	r := newRedactor(opts)
+	clock := clockOrSystem(probeClock(opts))
+	var dnsConfig *DNSCacheConfig
+	var throttle *Throttle
+	var speculation *Speculation
+	var parseCacheConfig *ParseCacheConfig
+	var schedulerConfig *SchedulerConfig
+	if opts != nil {
+		dnsConfig = opts.DNSCache
+		throttle = opts.Throttle
+		speculation = opts.Speculation
+		parseCacheConfig = opts.ParseCache
+		schedulerConfig = opts.Scheduler
+	}
+	return &Prober{
+		opts:     opts,
+		logger:   newProbeLogger(opts, r),
+		redactor: r,
+		slots:    newRequestSlots(opts),
+		clock:    clock,
+		dnsCache: newDNSCache(dnsConfig, clock),
+		shaper:   newBandwidthShaper(throttle),
+		clients:  make(map[string]*HTTPClient),
+
+		parseCache:  newParseCache(parseCacheConfig),
+		topVariants: newTopVariants(speculation),
+		scheduler:   newScheduler(schedulerConfig),
+	}
+}
+
+// withOptions returns a prober probing with opts, which differ from the
+// options of p only in QueueJobOptions fields. It shares the request slots,
+// scheduler, DNS cache, bandwidth shaper, speculation memory and circuit
+// breakers of p, so that its probes count against the limits of p, but has
+// its own HTTP clients, whose headers and timeouts follow opts.
+func (p *Prober) withOptions(opts *ProbeOptions) *Prober {
+	var parseCacheConfig *ParseCacheConfig
+	if opts != nil {
+		parseCacheConfig = opts.ParseCache
+	}
+	return &Prober{
+		opts:     opts,
+		logger:   newProbeLogger(opts, p.redactor),
+		redactor: p.redactor,
+		slots:    p.slots,
+		clock:    p.clock,
+		dnsCache: p.dnsCache,
+		shaper:   p.shaper,
+		clients:  make(map[string]*HTTPClient),
+
+		// parse results depend on options such as IFrameStreams
+		parseCache:  newParseCache(parseCacheConfig),
+		topVariants: p.topVariants,
+		scheduler:   p.scheduler,
+		parent:      p,
+	}
+}
+
+// closeIdleConnections closes the idle connections of the HTTP clients of p
+func (p *Prober) closeIdleConnections() {
+	p.clientsMu.Lock()
+	defer p.clientsMu.Unlock()
+	for _, client := range p.clients {
+		client.client.GetTransport().CloseIdleConnections()
+	}
+}
+
+// probeClock returns the clock configured in opts (nil if none)
+func probeClock(opts *ProbeOptions) Clock {
+	if opts == nil {
+		return nil
+	}
+	return opts.Clock
+}
+
+// httpClient returns the prober's HTTP client for the origin of target,
+// creating it on first use so connections are reused across probes
+func (p *Prober) httpClient(target *url.URL) (*HTTPClient, error) {
+	origin := target.Scheme + "://" + target.Host
+
+	p.clientsMu.Lock()
+	defer p.clientsMu.Unlock()
+
+	if client, ok := p.clients[origin]; ok {
+		return client, nil
+	}
+
+	client, err := NewHTTPClient(target.String(), hostOptions(p.opts, target.Hostname()))
+	if err != nil {
+		return nil, err
+	}
+	if p.dnsCache != nil {
+		client.client.GetTransport().SetDial(p.dnsCache.dialContext)
+	}
+	if p.opts != nil && p.opts.Cassette != nil {
+		client.client.GetTransport().WrapRoundTrip(p.opts.Cassette.wrapper(p.redactor))
+	}
+	if p.parent != nil && client.retryExecutor != nil {
+		shared, err := p.parent.httpClient(target)
+		if err != nil {
+			return nil, err
+		}
+		client.retryExecutor = shared.retryExecutor
+	}
+	client.shaper = p.shaper
+	p.clients[origin] = client
+	return client, nil
+}
+
+// circuitBreaker returns the circuit breaker guarding manifestURL's origin (nil if none)
+func (p *Prober) circuitBreaker(manifestURL string) *CircuitBreaker {
+	parsedURL, err := validateURL(manifestURL)
+	if err != nil {
+		return nil
+	}
+	client, err := p.httpClient(parsedURL)
+	if err != nil || client.retryExecutor == nil {
+		return nil
+	}
+	return client.retryExecutor.circuitBreaker
 }
 
 // ProbeManifest fetches and analyzes a streaming manifest URL.
@@ -78,12 +556,71 @@ func ProbeManifest(manifestURL string, opts *ProbeOptions) (*Output, error) {
 // ProbeManifestWithContext fetches and analyzes a streaming manifest URL with context support.
 // This version supports cancellation and timeout through the context parameter.
 func ProbeManifestWithContext(ctx context.Context, manifestURL string, opts *ProbeOptions) (*Output, error) {
+	return NewProber(opts).Probe(ctx, manifestURL)
+}
+
+// Probe fetches and analyzes a streaming manifest URL using the prober's options.
+// Sensitive query parameters and header values are redacted from returned errors
+// unless LogConfig.DisableRedaction is set.
+func (p *Prober) Probe(ctx context.Context, manifestURL string) (*Output, error) {
+	ctx = withLogger(ctx, p.logger)
+	start := p.clock.Now()
+	output, err := p.probe(ctx, manifestURL)
+	if err != nil {
+		output, err = nil, p.redactor.redactError(err)
+	}
+
+	if p.opts != nil && len(p.opts.Sinks) > 0 {
+		duration := p.clock.Now().Sub(start)
+		if p.opts.Deterministic {
+			start, duration = time.Time{}, 0
+		}
+		writeSinks(ctx, p.opts.Sinks, p.redactor, probeRecord(p.redactor.redactString(manifestURL), start, duration, output, err))
+	}
+	return output, err
+}
+
+// probe performs a single manifest probe
+func (p *Prober) probe(ctx context.Context, manifestURL string) (*Output, error) {
 	start := time.Now()
 	
 	logInfo(ctx, "Starting manifest probe", map[string]interface{}{
 		"url": manifestURL,
 	})
 
+	ctx = p.withProvenance(ctx, p.clock.Now())
+	ctx, spec := p.speculate(ctx, manifestURL)
+
+	fetched, err := p.fetch(ctx, manifestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	parseStart := time.Now()
+	output, err := p.analyze(ctx, fetched)
+	if err != nil {
+		return nil, err
+	}
+	if p.topVariants != nil {
+		p.finishSpeculation(spec, manifestURL, output)
+	}
+
+	totalDuration := time.Since(start)
+	logInfo(ctx, "Manifest probe completed successfully", map[string]interface{}{
+		"url": fetched.url,
+		"streams_found": len(output.Streams),
+		"total_duration": totalDuration,
+		"fetch_duration": parseStart.Sub(start),
+		"parse_duration": time.Since(parseStart),
+	})
+
+	return output, nil
+}
+
+// fetch validates the URL and options and downloads the manifest
+func (p *Prober) fetch(ctx context.Context, manifestURL string) (*fetchResult, error) {
+	opts := p.opts
+
 	// Validate URL
 	parsedURL, err := validateURL(manifestURL)
 	if err != nil {
@@ -102,8 +639,12 @@ func ProbeManifestWithContext(ctx context.Context, manifestURL string, opts *Pro
 		return nil, err
 	}
 
-	// Create HTTP client
-	httpClient, err := NewHTTPClient(parsedURL.String(), opts)
+	if fetched := takeSpeculative(ctx, manifestURL); fetched != nil {
+		return fetched, nil
+	}
+
+	// Get HTTP client
+	httpClient, err := p.httpClient(parsedURL)
 	if err != nil {
 		logError(ctx, "HTTP client creation failed", map[string]interface{}{
 			"url": parsedURL.String(),
@@ -113,8 +654,12 @@ func ProbeManifestWithContext(ctx context.Context, manifestURL string, opts *Pro
 	}
 
 	// Fetch manifest content
+	if err := p.slots.acquire(ctx); err != nil {
+		return nil, err
+	}
 	fetchStart := time.Now()
-	body, err := httpClient.FetchManifestWithContext(ctx, parsedURL.String())
+	fetched, err := httpClient.fetch(ctx, parsedURL.String())
+	p.slots.release()
 	if err != nil {
 		logError(ctx, "Manifest fetch failed", map[string]interface{}{
 			"url": parsedURL.String(),
@@ -124,6 +669,7 @@ func ProbeManifestWithContext(ctx context.Context, manifestURL string, opts *Pro
 		return nil, err
 	}
 
+	body := fetched.body
 	logDebug(ctx, "Manifest fetched successfully", map[string]interface{}{
 		"url": parsedURL.String(),
 		"size": len(body),
@@ -139,8 +685,8 @@ func ProbeManifestWithContext(ctx context.Context, manifestURL string, opts *Pro
 		return nil, err
 	}
 
-	if len(body) > 50*1024*1024 { // 50MB limit
-		err := NewParsingError(parsedURL.String(), "unknown", fmt.Errorf("manifest too large (%d bytes)", len(body)))
+	if int64(len(body)) > maxBodyBytes(opts) {
+		err := NewParsingError(parsedURL.String(), "unknown", fmt.Errorf("%w (%d bytes)", ErrManifestTooLarge, len(body)))
 		logError(ctx, "Manifest too large", map[string]interface{}{
 			"url": parsedURL.String(),
 			"size": len(body),
@@ -148,39 +694,161 @@ func ProbeManifestWithContext(ctx context.Context, manifestURL string, opts *Pro
 		return nil, err
 	}
 
-	// Detect format and parse
+	p.archive(ctx, fetched)
+
+	return fetched, nil
+}
+
+// analyze parses a fetched manifest and validates its response metadata
+func (p *Prober) analyze(ctx context.Context, fetched *fetchResult) (*Output, error) {
+	output, err := p.parse(ctx, fetched.body, fetched.url)
+	if err != nil {
+		return nil, err
+	}
+
+	output.url = p.redactor.redactString(fetched.url)
+	output.Cache = parseCacheStatus(fetched.header)
+	if p.opts != nil && p.opts.IFrameStreams {
+		output.Streams = append(output.Streams, output.iframes...)
+	}
+	if p.opts != nil && p.opts.AudioGroups {
+		output.groupAudio()
+	}
+	if p.opts != nil && p.opts.Periods == PeriodsDedupe {
+		output.dedupePeriods()
+	}
+	output.Event = detectEventStatus(output, fetched.body, p.clock.Now())
+
+	version := BuildInfo()
+	output.ProgramVersion = &version
+
+	if p.opts != nil && p.opts.ContentTypePolicy != nil {
+		policy := p.opts.ContentTypePolicy
+		format := manifestFormat(fetched.body)
+		contentType := fetched.header.Get("Content-Type")
+		if !policy.allows(format, contentType) {
+			err := NewContentTypeError(fetched.url, format, contentType)
+			if policy.Strict {
+				logError(ctx, "Content-Type mismatch", map[string]interface{}{
+					"url": fetched.url,
+					"content_type": contentType,
+				})
+				return nil, err
+			}
+			logWarn(ctx, "Content-Type mismatch", map[string]interface{}{
+				"url": fetched.url,
+				"content_type": contentType,
+			})
+			output.Warnings = append(output.Warnings, err.Message)
+		}
+	}
+
+	if p.opts != nil && p.opts.AnalyzeBitrates && len(output.variants) > 0 {
+		p.analyzeBitrates(ctx, output)
+	}
+
+	if p.opts != nil && p.opts.FollowVariants && len(output.variants) > 0 {
+		p.followVariants(ctx, output)
+	}
+
+	output.Format = describeFormat(output, manifestFormat(fetched.body))
+
+	if p.opts != nil && p.opts.DeepProbe != nil {
+		p.checkBitstreams(ctx, p.opts.DeepProbe, output)
+		if p.opts.DeepProbe.CheckBaseURLs {
+			p.checkBaseURLs(ctx, output)
+		}
+	}
+
+	if p.opts != nil && p.opts.DecodeCheck != nil {
+		p.runDecodeChecks(ctx, p.opts.DecodeCheck, fetched.url, output)
+	}
+
+	if p.opts != nil && p.opts.Throttle != nil {
+		p.estimateRebuffer(ctx, p.opts.Throttle, fetched, output)
+	}
+
+	if p.opts != nil && len(p.opts.RulePacks) > 0 {
+		p.runRules(ctx, p.opts.RulePacks, fetched, output)
+	}
+	locateFindings(output, fetched.body)
+
+	if p.opts != nil && p.opts.ExcerptBytes > 0 {
+		addExcerpts(output, fetched.body, p.opts.ExcerptBytes)
+	}
+
+	if p.opts != nil && p.opts.StreamOrder != "" {
+		if err := output.SortStreams(p.opts.StreamOrder); err != nil {
+			return nil, err
+		}
+	}
+
+	if recorder := provenanceFrom(ctx); recorder != nil {
+		output.Provenance = p.provenance(recorder, fetched.url)
+	}
+
+	if p.opts != nil {
+		output.schema = p.opts.Schema
+	}
+
+	if p.opts != nil && p.opts.Deterministic {
+		output.makeDeterministic()
+	}
+
+	return output, nil
+}
+
+// manifestFormat detects the format of a manifest body ("HLS" or "MPD")
+func manifestFormat(body string) string {
+	if strings.Contains(body, "#EXTM3U") {
+		return "HLS"
+	}
+	return "MPD"
+}
+
+// parse detects the manifest format and parses body
+func (p *Prober) parse(ctx context.Context, body string, manifestURL string) (*Output, error) {
 	parseStart := time.Now()
+
+	var cacheKey string
+	if p.parseCache != nil {
+		cacheKey = parseCacheKey(body, manifestURL)
+		if output := p.parseCache.get(cacheKey); output != nil {
+			logDebug(ctx, "Parsed manifest cache hit", map[string]interface{}{
+				"url": manifestURL,
+			})
+			return output, nil
+		}
+	}
+
 	var output *Output
-	if strings.Contains(body, "#EXTM3U") {
+	var err error
+	if manifestFormat(body) == "HLS" {
 		logDebug(ctx, "Detected HLS manifest", map[string]interface{}{
-			"url": parsedURL.String(),
+			"url": manifestURL,
 		})
-		output, err = parseHLSManifest(body, parsedURL.String())
-	} else {
+		output, err = parseHLSManifest(body, manifestURL)
+	} else if looksLikeManifest([]byte(body)) {
 		logDebug(ctx, "Detected MPD manifest", map[string]interface{}{
-			"url": parsedURL.String(),
+			"url": manifestURL,
 		})
-		output, err = parseMPDManifest(body, parsedURL.String())
+		output, err = parseMPDManifest(body, manifestURL)
+	} else {
+		err = NewParsingError(manifestURL, "unknown", fmt.Errorf("%w: content is neither an HLS playlist nor an MPD", ErrUnsupportedFormat))
 	}
 
 	if err != nil {
 		logError(ctx, "Manifest parsing failed", map[string]interface{}{
-			"url": parsedURL.String(),
+			"url": manifestURL,
 			"parse_duration": time.Since(parseStart),
 			"error": err.Error(),
 		})
 		return nil, err
 	}
 
-	totalDuration := time.Since(start)
-	logInfo(ctx, "Manifest probe completed successfully", map[string]interface{}{
-		"url": parsedURL.String(),
-		"streams_found": len(output.Streams),
-		"total_duration": totalDuration,
-		"fetch_duration": time.Since(fetchStart),
-		"parse_duration": time.Since(parseStart),
-	})
-
+	if p.parseCache != nil {
+		p.parseCache.add(cacheKey, output)
+	}
 	return output, nil
 }
 
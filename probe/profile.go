@@ -0,0 +1,145 @@
+package probe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ConfigEnv names the environment variable overriding the config file path
+const ConfigEnv = "GOPROBE_CONFIG"
+
+// Config is the goprobe config file: named ProbeOptions profiles such as
+// "akamai-tokenized" or "internal-staging", shared across tools instead of
+// copy-pasted option structs. Options are stored as JSON with their Go field
+// names; fields holding functions or interfaces (Logger, Clock, Rand,
+// Archiver, resolvers, Speculation.Guess) cannot be stored. Jobs are the
+// recurring probes run by a Monitor.
+type Config struct {
+	Profiles map[string]*ProbeOptions `json:"profiles"`
+	Jobs     []Job                    `json:"jobs,omitempty"`
+}
+
+// DefaultConfigPath returns $GOPROBE_CONFIG, or goprobe/config.json in the
+// user's config directory (e.g. ~/.config/goprobe/config.json)
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goprobe", "config.json"), nil
+}
+
+// LoadConfig reads the config file at path. A missing file is an empty config.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{Profiles: make(map[string]*ProbeOptions)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, NewParsingError("", "config", fmt.Errorf("%s: %w", path, err))
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]*ProbeOptions)
+	}
+	return &config, nil
+}
+
+// Save writes the config file to path, replacing it atomically
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Profiles may hold credentials (proxy URLs, headers): keep the file private
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ProfileNames returns the names of the profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the validated options of the named profile
+func (c *Config) Profile(name string) (*ProbeOptions, error) {
+	opts, ok := c.Profiles[name]
+	if !ok || opts == nil {
+		return nil, NewValidationError(fmt.Sprintf("unknown profile %q (available: %v)", name, c.ProfileNames()))
+	}
+	if err := validateProbeOptions(opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// SetProfile validates opts and stores them under name
+func (c *Config) SetProfile(name string, opts *ProbeOptions) error {
+	if name == "" {
+		return NewValidationError("profile name cannot be empty")
+	}
+	if opts == nil {
+		return NewValidationError(fmt.Sprintf("profile %q is nil", name))
+	}
+	if err := validateProbeOptions(opts); err != nil {
+		return err
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*ProbeOptions)
+	}
+	c.Profiles[name] = opts
+	return nil
+}
+
+// LoadProfile returns the options of the named profile of the config file at
+// DefaultConfigPath. Each call returns new options that the caller may modify.
+func LoadProfile(name string) (*ProbeOptions, error) {
+	path, err := DefaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Profile(name)
+}
+
+// SaveProfile stores opts under name in the config file at DefaultConfigPath
+func SaveProfile(name string, opts *ProbeOptions) error {
+	path, err := DefaultConfigPath()
+	if err != nil {
+		return err
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := config.SetProfile(name, opts); err != nil {
+		return err
+	}
+	return config.Save(path)
+}
@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigProfilesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goprobe", "config.json")
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(config.Profiles) != 0 {
+		t.Errorf("Expected an empty config, got %v", config.ProfileNames())
+	}
+
+	tokenized := &ProbeOptions{
+		UserAgent:      "goprobe/akamai",
+		CustomHeaders:  map[string]string{"X-Akamai-Token": "secret"},
+		TimeoutSeconds: 10,
+		StreamOrder:    StreamOrderType,
+		RetryConfig:    &RetryConfig{MaxRetries: 2, InitialDelay: time.Second},
+		Speculation:    &Speculation{Guess: func(string) string { return "" }, MaxEntries: 8},
+	}
+	staging := &ProbeOptions{ProxyURL: "http://proxy.internal:3128", DisableCamouflage: true}
+	if err := config.SetProfile("akamai-tokenized", tokenized); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := config.SetProfile("internal-staging", staging); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := config.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected mode %o, got %o", 0o600, perm)
+	}
+
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if names := loaded.ProfileNames(); !reflect.DeepEqual(names, []string{"akamai-tokenized", "internal-staging"}) {
+		t.Errorf("Expected %q, got %q", []string{"akamai-tokenized", "internal-staging"}, names)
+	}
+
+	opts, err := loaded.Profile("akamai-tokenized")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Functions are not stored
+	expected := *tokenized
+	expected.Speculation = &Speculation{MaxEntries: 8}
+	if !reflect.DeepEqual(*opts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, *opts)
+	}
+
+	if _, err := loaded.Profile("production"); err == nil || !strings.Contains(err.Error(), "akamai-tokenized") {
+		t.Errorf("Expected an unknown profile error listing the profiles, got %v", err)
+	}
+}
+
+func TestConfigProfileValidation(t *testing.T) {
+	config := &Config{}
+	if err := config.SetProfile("", &ProbeOptions{}); err == nil {
+		t.Error("Expected an error for an empty profile name")
+	}
+	if err := config.SetProfile("bad", &ProbeOptions{TimeoutSeconds: -1}); err == nil {
+		t.Error("Expected an error for invalid options")
+	}
+
+	// Profiles edited by hand are validated when loaded
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"profiles": {"bad": {"StreamOrder": "random"}}}`), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := loaded.Profile("bad"); err == nil {
+		t.Error("Expected an error for an invalid stream order")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"profiles": `), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("Expected an error for malformed JSON")
+	}
+}
+
+func TestLoadProfileFromEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(ConfigEnv, path)
+
+	if got, err := DefaultConfigPath(); err != nil || got != path {
+		t.Errorf("Expected %q, got %q (%v)", path, got, err)
+	}
+	if err := SaveProfile("internal-staging", &ProbeOptions{UserAgent: "staging"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	first, err := LoadProfile("internal-staging")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.UserAgent != "staging" {
+		t.Errorf("Expected %q, got %q", "staging", first.UserAgent)
+	}
+
+	// Each load returns new options
+	first.UserAgent = "modified"
+	second, err := LoadProfile("internal-staging")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second.UserAgent != "staging" {
+		t.Errorf("Expected %q, got %q", "staging", second.UserAgent)
+	}
+}
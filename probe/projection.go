@@ -0,0 +1,79 @@
+package probe
+
+// DASH descriptor schemes for 360° projection and stereoscopic frame packing
+const (
+	cicpProjectionScheme     = "urn:mpeg:mpegB:cicp:PF"
+	omafProjectionScheme     = "urn:mpeg:mpegI:omaf:2017:pf"
+	cicpFramePackingScheme   = "urn:mpeg:mpegB:cicp:VideoFramePackingType"
+	legacyFramePackingScheme = "urn:mpeg:dash:14496:10:frame_packing_arrangement_type:2011"
+)
+
+// projectionTypes maps projection format values (ISO/IEC 23090-2) to names
+var projectionTypes = map[string]string{
+	"0": "equirectangular",
+	"1": "cubemap",
+}
+
+// framePackingTypes maps VideoFramePackingType values (ISO/IEC 23091-2) to
+// ffprobe stereo3d names. Type 6 (2D) is not stereoscopic and is omitted.
+var framePackingTypes = map[string]string{
+	"0": "checkerboard",
+	"1": "interleaved columns",
+	"2": "interleaved lines",
+	"3": "side by side",
+	"4": "top and bottom",
+	"5": "frame alternate",
+}
+
+// mpdProjection returns the 360° projection signalled for a representation,
+// which takes precedence over its adaptation set ("" if none)
+func mpdProjection(adaptationSet AdaptationSet, rep Representation) string {
+	if projection := projectionOf(rep.EssentialProperty, rep.SupplementalProperty); projection != "" {
+		return projection
+	}
+	return projectionOf(adaptationSet.EssentialProperty, adaptationSet.SupplementalProperty)
+}
+
+// projectionOf finds a projection format among essential and supplemental properties
+func projectionOf(essential, supplemental []EssentialProperty) string {
+	properties := append(append([]EssentialProperty{}, essential...), supplemental...)
+	for _, property := range properties {
+		var value string
+		switch property.SchemeIdUri {
+		case cicpProjectionScheme:
+			value = property.Value
+		case omafProjectionScheme:
+			value = property.ProjectionType
+		default:
+			continue
+		}
+
+		if name, ok := projectionTypes[value]; ok {
+			return name
+		}
+		return "unknown"
+	}
+	return ""
+}
+
+// mpdStereoMode returns the stereoscopic frame packing signalled for a
+// representation, which takes precedence over its adaptation set ("" if none)
+func mpdStereoMode(adaptationSet AdaptationSet, rep Representation) string {
+	if mode := stereoModeOf(rep.FramePacking); mode != "" {
+		return mode
+	}
+	return stereoModeOf(adaptationSet.FramePacking)
+}
+
+// stereoModeOf finds a stereoscopic frame packing among FramePacking descriptors
+func stereoModeOf(descriptors []Descriptor) string {
+	for _, descriptor := range descriptors {
+		if descriptor.SchemeIdUri != cicpFramePackingScheme && descriptor.SchemeIdUri != legacyFramePackingScheme {
+			continue
+		}
+		if name, ok := framePackingTypes[descriptor.Value]; ok {
+			return name
+		}
+	}
+	return ""
+}
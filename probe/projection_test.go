@@ -0,0 +1,55 @@
+package probe
+
+import "testing"
+
+func TestMPDProjectionAndStereoMode(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD type="static" xmlns:omaf="urn:mpeg:mpegI:omaf:2017">
+  <Period id="1">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <EssentialProperty schemeIdUri="urn:mpeg:mpegI:omaf:2017:pf" omaf:projection_type="0"/>
+      <FramePacking schemeIdUri="urn:mpeg:mpegB:cicp:VideoFramePackingType" value="4"/>
+      <Representation id="v1" codecs="hvc1.1.6.L150.90" width="3840" height="3840"/>
+      <Representation id="v2" codecs="hvc1.1.6.L120.90" width="1920" height="1920">
+        <FramePacking schemeIdUri="urn:mpeg:mpegB:cicp:VideoFramePackingType" value="3"/>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <SupplementalProperty schemeIdUri="urn:mpeg:mpegB:cicp:PF" value="1"/>
+      <Representation id="v3" codecs="avc1.640028" width="2880" height="1920"/>
+    </AdaptationSet>
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <FramePacking schemeIdUri="urn:mpeg:mpegB:cicp:VideoFramePackingType" value="6"/>
+      <Representation id="v4" codecs="avc1.640028" width="1920" height="1080"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://example.com/vr.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		projection string
+		stereoMode string
+	}{
+		{"equirectangular", "top and bottom"},
+		{"equirectangular", "side by side"},
+		{"cubemap", ""},
+		{"", ""},
+	}
+
+	if len(output.Streams) != len(tests) {
+		t.Fatalf("Expected %d streams, got %d", len(tests), len(output.Streams))
+	}
+	for i, tt := range tests {
+		stream := output.Streams[i]
+		if stream.Projection != tt.projection {
+			t.Errorf("Stream %d: expected projection %q, got %q", i, tt.projection, stream.Projection)
+		}
+		if stream.StereoMode != tt.stereoMode {
+			t.Errorf("Stream %d: expected stereo mode %q, got %q", i, tt.stereoMode, stream.StereoMode)
+		}
+	}
+}
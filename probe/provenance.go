@@ -0,0 +1,305 @@
+package probe
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// responseIDHeaders are the response headers in which servers and CDNs
+// report the ID of a request, as recorded in Provenance.ResponseIDs
+var responseIDHeaders = []string{
+	"X-Request-Id",
+	"X-Correlation-Id",
+	"X-Amz-Cf-Id",
+	"X-Amz-Request-Id",
+	"Cf-Ray",
+	"X-Akamai-Request-Id",
+}
+
+// ProvenanceConfig enables the Output.Provenance block, which records how an
+// output was produced so stored results can be audited and reproduced
+type ProvenanceConfig struct {
+	// ProxyLabel names the network path of the prober, e.g. "eu-west-1 NAT"
+	// ("" = the proxy URL of the manifest's host without credentials, if any)
+	ProxyLabel string
+
+	// RequestIDHeader is a request header carrying a unique ID of the probe on
+	// every request it sends, e.g. "X-Request-Id" ("" = not sent)
+	RequestIDHeader string
+}
+
+// Provenance records the build, options and network path that produced an output
+type Provenance struct {
+	// ProbedAt is when the probe started
+	ProbedAt time.Time `json:"probed_at"`
+
+	// Version is the goprobe version (see ProgramVersion)
+	Version string `json:"version"`
+
+	// OptionsHash is the SHA-256 of the effective options (see OptionsHash)
+	OptionsHash string `json:"options_hash"`
+
+	// ProxyLabel is ProvenanceConfig.ProxyLabel or the proxy of the manifest's host
+	ProxyLabel string `json:"proxy_label,omitempty"`
+
+	// EgressIP is the local address of the connection the manifest was fetched on
+	EgressIP string `json:"egress_ip,omitempty"`
+
+	// RequestID is the ID sent in ProvenanceConfig.RequestIDHeader
+	RequestID string `json:"request_id,omitempty"`
+
+	// ResponseIDs are the request IDs returned by servers ("Cf-Ray: 8a1b..."),
+	// in request order
+	ResponseIDs []string `json:"response_ids,omitempty"`
+}
+
+// OptionsHash returns the hex SHA-256 of opts. Fields holding functions or
+// interfaces (Logger, Clock, Rand, Archiver, resolvers) are not hashed, and
+// neither are zero fields, so adding options to goprobe does not change the
+// hash of existing configurations. Credentials are not hashed either, so that
+// the hash survives token rotation: only the names of the sensitive headers
+// (LogConfig.RedactHeaders) are, and proxy URLs without their userinfo.
+func OptionsHash(opts *ProbeOptions) string {
+	h := sha256.New()
+	if opts != nil {
+		hashValue(h, reflect.ValueOf(withoutCredentials(opts)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// withoutCredentials returns a copy of opts with the values of sensitive
+// headers cleared and the userinfo of proxy URLs removed
+func withoutCredentials(opts *ProbeOptions) ProbeOptions {
+	sensitive := DefaultRedactedHeaders
+	if opts.LogConfig != nil && opts.LogConfig.RedactHeaders != nil {
+		sensitive = opts.LogConfig.RedactHeaders
+	}
+
+	copied := *opts
+	copied.CustomHeaders = withoutHeaderValues(opts.CustomHeaders, sensitive)
+	copied.ProxyURL = withoutUserinfo(opts.ProxyURL)
+	if opts.PerHostOverrides != nil {
+		copied.PerHostOverrides = make(map[string]*HostOverride, len(opts.PerHostOverrides))
+		for pattern, override := range opts.PerHostOverrides {
+			if override != nil {
+				overrideCopy := *override
+				overrideCopy.CustomHeaders = withoutHeaderValues(override.CustomHeaders, sensitive)
+				overrideCopy.ProxyURL = withoutUserinfo(override.ProxyURL)
+				override = &overrideCopy
+			}
+			copied.PerHostOverrides[pattern] = override
+		}
+	}
+	return copied
+}
+
+// withoutHeaderValues returns a copy of headers with the values of the
+// sensitive headers cleared
+func withoutHeaderValues(headers map[string]string, sensitive []string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(headers))
+	for name, value := range headers {
+		for _, header := range sensitive {
+			if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(header) {
+				value = ""
+				break
+			}
+		}
+		copied[name] = value
+	}
+	return copied
+}
+
+// withoutUserinfo removes the credentials of a proxy URL
+func withoutUserinfo(proxyURL string) string {
+	parsed, err := url.Parse(proxyURL)
+	if err != nil || parsed.User == nil {
+		return proxyURL
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
+// hashValue writes a canonical encoding of v to h: struct fields by name,
+// map entries sorted by key
+func hashValue(h hash.Hash, v reflect.Value) {
+	if !hashable(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			hashValue(h, v.Elem())
+		}
+	case reflect.Struct:
+		fmt.Fprint(h, "{")
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() || v.Field(i).IsZero() || !hashable(field.Type) {
+				continue
+			}
+			fmt.Fprintf(h, "%s:", field.Name)
+			hashValue(h, v.Field(i))
+			fmt.Fprint(h, ";")
+		}
+		fmt.Fprint(h, "}")
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		fmt.Fprint(h, "{")
+		for _, key := range keys {
+			fmt.Fprintf(h, "%q:", fmt.Sprint(key))
+			hashValue(h, v.MapIndex(key))
+			fmt.Fprint(h, ";")
+		}
+		fmt.Fprint(h, "}")
+	case reflect.Slice, reflect.Array:
+		fmt.Fprint(h, "[")
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+			fmt.Fprint(h, ";")
+		}
+		fmt.Fprint(h, "]")
+	case reflect.String:
+		fmt.Fprintf(h, "%q", v.String())
+	default:
+		fmt.Fprint(h, v.Interface())
+	}
+}
+
+// hashable reports whether values of t are part of OptionsHash
+func hashable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Func, reflect.Interface, reflect.Chan, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+// provenanceRecorder collects the provenance of one probe from its requests
+type provenanceRecorder struct {
+	probedAt  time.Time
+	header    string
+	requestID string
+
+	mu          sync.Mutex
+	egressIP    string
+	responseIDs []string
+}
+
+type provenanceContextKey struct{}
+
+// withProvenance returns ctx carrying a recorder for the probe starting at
+// now (ctx itself without ProbeOptions.Provenance)
+func (p *Prober) withProvenance(ctx context.Context, now time.Time) context.Context {
+	if p.opts == nil || p.opts.Provenance == nil {
+		return ctx
+	}
+	recorder := &provenanceRecorder{probedAt: now, header: p.opts.Provenance.RequestIDHeader}
+	if recorder.header != "" {
+		recorder.requestID = newRequestID()
+	}
+	return context.WithValue(ctx, provenanceContextKey{}, recorder)
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// provenanceFrom returns the recorder of the probe running in ctx (nil if none)
+func provenanceFrom(ctx context.Context) *provenanceRecorder {
+	recorder, _ := ctx.Value(provenanceContextKey{}).(*provenanceRecorder)
+	return recorder
+}
+
+// trace returns ctx recording the local address of the first connection
+func (r *provenanceRecorder) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if r.egressIP != "" {
+				return
+			}
+			if addr, ok := info.Conn.LocalAddr().(*net.TCPAddr); ok {
+				r.egressIP = addr.IP.String()
+			}
+		},
+	})
+}
+
+// recordResponse records the request IDs of a response
+func (r *provenanceRecorder) recordResponse(header http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range responseIDHeaders {
+		if value := header.Get(name); value != "" {
+			r.responseIDs = append(r.responseIDs, name+": "+value)
+		}
+	}
+}
+
+// provenance returns the provenance of the probe of manifestURL
+func (p *Prober) provenance(recorder *provenanceRecorder, manifestURL string) *Provenance {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	provenance := &Provenance{
+		ProbedAt:    recorder.probedAt,
+		Version:     BuildInfo().Version,
+		OptionsHash: OptionsHash(p.opts),
+		ProxyLabel:  p.opts.Provenance.ProxyLabel,
+		EgressIP:    recorder.egressIP,
+		RequestID:   recorder.requestID,
+		ResponseIDs: append([]string(nil), recorder.responseIDs...),
+	}
+	if provenance.ProxyLabel == "" {
+		if parsed, err := url.Parse(manifestURL); err == nil {
+			provenance.ProxyLabel = proxyLabel(hostOptions(p.opts, parsed.Hostname()).ProxyURL)
+		}
+	}
+	return provenance
+}
+
+// proxyLabel returns proxyURL without credentials, path or query
+func proxyLabel(proxyURL string) string {
+	if proxyURL == "" {
+		return ""
+	}
+	parsed, err := url.Parse(proxyURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// validateProvenance validates ProbeOptions.Provenance
+func validateProvenance(config *ProvenanceConfig) error {
+	if config == nil {
+		return nil
+	}
+	if strings.ContainsAny(config.RequestIDHeader, " \t\r\n:") {
+		return NewValidationError(fmt.Sprintf("invalid request ID header %q", config.RequestIDHeader))
+	}
+	return nil
+}
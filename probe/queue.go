@@ -0,0 +1,575 @@
+package probe
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queue worker defaults
+const (
+	// DefaultQueueGroup is the NATS queue group shared by workers
+	DefaultQueueGroup = "goprobe"
+	// DefaultQueueConcurrency is the number of jobs a worker probes at once
+	DefaultQueueConcurrency = 8
+
+	// maxQueueProbers bounds the probers kept for the options of jobs,
+	// evicting the least recently used
+	maxQueueProbers = 32
+	// maxQueuePayload bounds the size of a job message
+	maxQueuePayload = 1 << 20
+	// queueDialTimeout bounds the connection handshake with the server
+	queueDialTimeout = 10 * time.Second
+	// queueMaxBackoff bounds the delay between reconnection attempts
+	queueMaxBackoff = 30 * time.Second
+)
+
+// QueueConfig configures a QueueWorker
+type QueueConfig struct {
+	// URL is the NATS server: nats://[USER:PASSWORD@|TOKEN@]HOST[:PORT], or
+	// tls://... to require TLS (the port defaults to 4222)
+	URL string
+
+	// Subject is the subject jobs are consumed from
+	Subject string
+
+	// Group is the queue group of the workers: each job is delivered to one
+	// worker of the group ("" = DefaultQueueGroup)
+	Group string
+
+	// ReplySubject receives the results of jobs published without a reply
+	// subject ("" = such results are dropped)
+	ReplySubject string
+
+	// Concurrency is the maximum number of jobs probed at once (0 = default)
+	Concurrency int
+}
+
+// QueueJob is a probe job consumed from the queue as JSON
+type QueueJob struct {
+	// ID is returned in the result to correlate it with the job
+	ID  string `json:"id,omitempty"`
+	URL string `json:"url"`
+
+	// Options override the options of the worker for this job, with the Go
+	// field names of ParseOptionsJSON (nil = the worker's options). Only
+	// QueueJobOptions are accepted.
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// QueueJobOptions are the ProbeOptions fields a job may set. Options that run
+// local commands (DecodeCheck), redirect requests (ProxyURL,
+// PerHostOverrides) or change the worker's resources are reserved to the
+// worker, since anyone able to publish to the subject can submit jobs.
+var QueueJobOptions = []string{
+	"UserAgent",
+	"CustomHeaders",
+	"TimeoutSeconds",
+	"DisableCompression",
+	"DisableCamouflage",
+	"AcceptHeader",
+	"NegotiateFormat",
+	"IFrameStreams",
+	"AudioGroups",
+	"StreamOrder",
+	"Periods",
+	"Schema",
+	"AnalyzeBitrates",
+	"FollowVariants",
+	"DeepProbe",
+	"RulePacks",
+	"ExcerptBytes",
+	"Deterministic",
+}
+
+// QueueResult is the result of a job published to its reply subject
+type QueueResult struct {
+	ID       string        `json:"id,omitempty"`
+	URL      string        `json:"url"`
+	Duration time.Duration `json:"duration"`
+
+	// Output is the probe output, unless the job failed
+	Output *Output `json:"output,omitempty"`
+
+	// Error is the failure of the job as in ErrorJSON
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// QueueWorker consumes probe jobs from a NATS subject and publishes their
+// results, so that probing scales by starting workers in the same queue group.
+// Delivery is at most once, as with any core NATS subscription: jobs of a
+// worker that dies are lost and must be retried by their producer.
+type QueueWorker struct {
+	config QueueConfig
+	server *url.URL
+	prober *Prober
+	logger Logger
+
+	// baseKey is the jobOptionsKey of the options of prober
+	baseKey string
+
+	mu sync.Mutex
+	// probers are the probers of job options by jobOptionsKey
+	probers     map[string]*list.Element
+	proberOrder *list.List // front = most recently used
+}
+
+// queueProber is a cached prober of job options
+type queueProber struct {
+	key    string
+	prober *Prober
+}
+
+// NewQueueWorker creates a worker probing jobs without options with prober
+func NewQueueWorker(prober *Prober, config QueueConfig) (*QueueWorker, error) {
+	if config.Subject == "" {
+		return nil, NewValidationError("queue subject is required")
+	}
+	if strings.ContainsAny(config.Subject+config.Group+config.ReplySubject, " \t\r\n") {
+		return nil, NewValidationError("queue subjects and group must not contain whitespace")
+	}
+	if config.Concurrency < 0 {
+		return nil, NewValidationError("queue concurrency must not be negative")
+	}
+	if config.Group == "" {
+		config.Group = DefaultQueueGroup
+	}
+	if config.Concurrency == 0 {
+		config.Concurrency = DefaultQueueConcurrency
+	}
+
+	server, err := url.Parse(config.URL)
+	if err != nil || (server.Scheme != "nats" && server.Scheme != "tls") || server.Host == "" {
+		return nil, NewValidationError(fmt.Sprintf("invalid queue URL %q (nats://HOST:PORT or tls://HOST:PORT)", config.URL))
+	}
+	if server.Port() == "" {
+		server.Host = net.JoinHostPort(server.Hostname(), "4222")
+	}
+
+	var logger Logger
+	if prober.opts != nil {
+		logger = prober.opts.Logger
+	}
+	return &QueueWorker{
+		config:      config,
+		server:      server,
+		prober:      prober,
+		logger:      logger,
+		baseKey:     jobOptionsKey(prober.opts),
+		probers:     make(map[string]*list.Element),
+		proberOrder: list.New(),
+	}, nil
+}
+
+// Run consumes jobs until ctx is done, reconnecting with backoff when the
+// connection to the server is lost. Jobs in flight are completed and their
+// results published before Run returns ctx.Err().
+func (w *QueueWorker) Run(ctx context.Context) error {
+	ctx = withLogger(ctx, w.logger)
+	backoff := time.Second
+	for {
+		err := w.consume(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logWarn(ctx, "Queue connection lost", map[string]interface{}{
+			"server": w.server.Host,
+			"error":  err.Error(),
+			"retry":  backoff.String(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, queueMaxBackoff)
+	}
+}
+
+// consume consumes jobs over one connection until ctx is done or the
+// connection fails
+func (w *QueueWorker) consume(ctx context.Context) error {
+	conn, err := dialNATS(ctx, w.server)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	if err := conn.subscribe(w.config.Subject, w.config.Group); err != nil {
+		return err
+	}
+	logInfo(ctx, "Consuming probe jobs", map[string]interface{}{
+		"server":  w.server.Host,
+		"subject": w.config.Subject,
+		"group":   w.config.Group,
+	})
+
+	messages := make(chan natsMessage)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		readErr <- conn.read(messages, done)
+	}()
+
+	// Jobs complete after ctx is done so that a shutdown does not drop them
+	jobCtx := context.WithoutCancel(ctx)
+	slots := make(chan struct{}, w.config.Concurrency)
+	var jobs sync.WaitGroup
+	defer jobs.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			conn.unsubscribe()
+			jobs.Wait()
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case msg := <-messages:
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				continue
+			}
+			jobs.Add(1)
+			go func() {
+				defer func() { <-slots; jobs.Done() }()
+				w.handle(jobCtx, conn, msg)
+			}()
+		}
+	}
+}
+
+// handle probes the job of msg and publishes its result
+func (w *QueueWorker) handle(ctx context.Context, conn *natsConn, msg natsMessage) {
+	reply := msg.reply
+	if reply == "" {
+		reply = w.config.ReplySubject
+	}
+
+	result := w.run(ctx, msg.payload)
+	if reply == "" {
+		logWarn(ctx, "Queue job result dropped without a reply subject", map[string]interface{}{
+			"url": w.prober.redactor.redactString(result.URL),
+		})
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		data, _ = json.Marshal(QueueResult{ID: result.ID, URL: result.URL, Duration: result.Duration, Error: queueError(err)})
+	}
+	if err := conn.publish(reply, data); err != nil {
+		logWarn(ctx, "Queue result publish failed", map[string]interface{}{
+			"subject": reply,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// run decodes and probes a job
+func (w *QueueWorker) run(ctx context.Context, payload []byte) QueueResult {
+	var job QueueJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return QueueResult{Error: queueError(NewValidationError(fmt.Sprintf("invalid job JSON: %v", err)))}
+	}
+	result := QueueResult{ID: job.ID, URL: job.URL}
+	if job.URL == "" {
+		result.Error = queueError(NewValidationError("job URL is required"))
+		return result
+	}
+
+	prober, err := w.jobProber(job.Options)
+	if err != nil {
+		result.Error = queueError(err)
+		return result
+	}
+
+	start := time.Now()
+	output, err := prober.Probe(ctx, job.URL)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Error = queueError(err)
+		return result
+	}
+	result.Output = output
+	return result
+}
+
+// jobProber returns the prober of job options, derived from the worker's
+// prober so that jobs share its limits, and reused by later jobs with the same
+// effective options so that their connections are reused
+func (w *QueueWorker) jobProber(options json.RawMessage) (*Prober, error) {
+	if len(options) == 0 || string(options) == "null" {
+		return w.prober, nil
+	}
+	opts, err := jobOptions(w.prober.opts, options)
+	if err != nil {
+		return nil, err
+	}
+	key := jobOptionsKey(opts)
+	if key == w.baseKey {
+		return w.prober, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if element, ok := w.probers[key]; ok {
+		w.proberOrder.MoveToFront(element)
+		return element.Value.(*queueProber).prober, nil
+	}
+	prober := w.prober.withOptions(opts)
+	w.probers[key] = w.proberOrder.PushFront(&queueProber{key: key, prober: prober})
+	if w.proberOrder.Len() > maxQueueProbers {
+		oldest := w.proberOrder.Remove(w.proberOrder.Back()).(*queueProber)
+		delete(w.probers, oldest.key)
+		// jobs still running on it keep their connections until they finish
+		oldest.prober.closeIdleConnections()
+	}
+	return prober, nil
+}
+
+// jobOptionsKey returns a canonical hash of the QueueJobOptions fields of
+// opts, credentials included, so that equivalent job options share a prober
+// whatever their JSON formatting
+func jobOptionsKey(opts *ProbeOptions) string {
+	if opts == nil {
+		opts = &ProbeOptions{}
+	}
+	h := sha256.New()
+	fields := reflect.ValueOf(opts).Elem()
+	for _, name := range QueueJobOptions {
+		fmt.Fprintf(h, "%s:", name)
+		hashValue(h, fields.FieldByName(name))
+		fmt.Fprint(h, ";")
+	}
+	return string(h.Sum(nil))
+}
+
+// jobOptions returns the worker options base with the fields set in the job
+// options data applied on top, rejecting fields not in QueueJobOptions
+func jobOptions(base *ProbeOptions, data json.RawMessage) (*ProbeOptions, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid options JSON: %v", err))
+	}
+	for name := range fields {
+		if !slices.Contains(QueueJobOptions, name) {
+			return nil, NewValidationError(fmt.Sprintf("option %s cannot be set by a job", name))
+		}
+	}
+	parsed, err := ParseOptionsJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &ProbeOptions{}
+	if base != nil {
+		*opts = *base
+	}
+	merged, job := reflect.ValueOf(opts).Elem(), reflect.ValueOf(parsed).Elem()
+	for name := range fields {
+		merged.FieldByName(name).Set(job.FieldByName(name))
+	}
+	return opts, nil
+}
+
+// queueError returns the "error" object of ErrorJSON for err
+func queueError(err error) json.RawMessage {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(ErrorJSON(err), &body) != nil {
+		return nil
+	}
+	return body.Error
+}
+
+// natsMessage is a message delivered to a subscription
+type natsMessage struct {
+	subject string
+	reply   string
+	payload []byte
+}
+
+// natsConn is a connection speaking the NATS client protocol, limited to a
+// single queue subscription and publishing
+type natsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	mu     sync.Mutex
+	writer *bufio.Writer
+}
+
+// natsInfo is the part of the server INFO used by the client
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// dialNATS connects and authenticates to a NATS server
+func dialNATS(ctx context.Context, server *url.URL) (*natsConn, error) {
+	dialer := net.Dialer{Timeout: queueDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", server.Host)
+	if err != nil {
+		return nil, NewNetworkError(server.Redacted(), err)
+	}
+	conn.SetDeadline(time.Now().Add(queueDialTimeout))
+
+	c := &natsConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	line, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, NewNetworkError(server.Redacted(), err)
+	}
+	var info natsInfo
+	if !strings.HasPrefix(line, "INFO ") || json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info) != nil {
+		conn.Close()
+		return nil, NewNetworkError(server.Redacted(), fmt.Errorf("unexpected greeting %q", line))
+	}
+
+	if info.TLSRequired || server.Scheme == "tls" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: server.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, NewNetworkError(server.Redacted(), err)
+		}
+		c.conn, c.reader, c.writer = tlsConn, bufio.NewReader(tlsConn), bufio.NewWriter(tlsConn)
+	}
+
+	connect := map[string]interface{}{"verbose": false, "pedantic": false, "name": "goprobe", "lang": "go"}
+	if user := server.User; user != nil {
+		if password, ok := user.Password(); ok {
+			connect["user"], connect["pass"] = user.Username(), password
+		} else {
+			connect["auth_token"] = user.Username()
+		}
+	}
+	data, _ := json.Marshal(connect)
+	if err := c.write("CONNECT " + string(data) + "\r\nPING\r\n"); err != nil {
+		c.close()
+		return nil, NewNetworkError(server.Redacted(), err)
+	}
+
+	// The PONG confirms the CONNECT, which is otherwise answered by -ERR
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			c.close()
+			return nil, NewNetworkError(server.Redacted(), err)
+		}
+		switch {
+		case line == "PONG":
+			c.conn.SetDeadline(time.Time{})
+			return c, nil
+		case strings.HasPrefix(line, "-ERR"):
+			c.close()
+			return nil, NewNetworkError(server.Redacted(), fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR"))))
+		}
+	}
+}
+
+// subscribe subscribes to subject in a queue group
+func (c *natsConn) subscribe(subject, group string) error {
+	return c.write(fmt.Sprintf("SUB %s %s 1\r\n", subject, group))
+}
+
+// unsubscribe stops the delivery of jobs, which go to other workers of the group
+func (c *natsConn) unsubscribe() {
+	c.write("UNSUB 1\r\n")
+}
+
+// publish publishes payload to subject
+func (c *natsConn) publish(subject string, payload []byte) error {
+	return c.write(fmt.Sprintf("PUB %s %d\r\n", subject, len(payload)) + string(payload) + "\r\n")
+}
+
+// write writes protocol data and flushes it
+func (c *natsConn) write(data string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.writer.WriteString(data); err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+// readLine reads a protocol line without its CRLF
+func (c *natsConn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// read delivers messages until the connection fails or done is closed,
+// answering server pings
+func (c *natsConn) read(messages chan<- natsMessage, done <-chan struct{}) error {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			msg, err := c.readMessage(strings.Fields(line)[1:])
+			if err != nil {
+				return err
+			}
+			select {
+			case messages <- msg:
+			case <-done:
+				return nil
+			}
+		case line == "PING":
+			if err := c.write("PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// readMessage reads the payload of a MSG with the arguments
+// SUBJECT SID [REPLY] SIZE
+func (c *natsConn) readMessage(args []string) (natsMessage, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return natsMessage{}, fmt.Errorf("malformed MSG arguments %q", strings.Join(args, " "))
+	}
+	size, err := strconv.Atoi(args[len(args)-1])
+	if err != nil || size < 0 || size > maxQueuePayload {
+		return natsMessage{}, fmt.Errorf("invalid MSG size %q", args[len(args)-1])
+	}
+
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return natsMessage{}, err
+	}
+	msg := natsMessage{subject: args[0], payload: payload[:size]}
+	if len(args) == 4 {
+		msg.reply = args[2]
+	}
+	return msg, nil
+}
+
+// close closes the connection
+func (c *natsConn) close() {
+	c.conn.Close()
+}
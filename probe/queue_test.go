@@ -0,0 +1,288 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNATS is a NATS server accepting one client, which records the
+// protocol lines of the client and delivers the messages written to jobs
+type fakeNATS struct {
+	listener  net.Listener
+	lines     chan string
+	published chan string
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return &fakeNATS{listener: listener, lines: make(chan string, 16), published: make(chan string, 16)}
+}
+
+// serve accepts the client and sends it the MSG frames of jobs once it subscribed
+func (s *fakeNATS) serve(jobs []string) {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"max_payload\":1048576}\r\n")
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case strings.HasPrefix(line, "SUB "):
+			s.lines <- line
+			for i, job := range jobs {
+				fmt.Fprintf(conn, "MSG probe.jobs 1 _INBOX.%d %d\r\n%s\r\n", i, len(job), job)
+			}
+		case strings.HasPrefix(line, "PUB "):
+			fields := strings.Fields(line)
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.published <- fields[1] + " " + string(payload[:size])
+		default:
+			s.lines <- line
+		}
+	}
+}
+
+func TestQueueWorker(t *testing.T) {
+	manifests := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/master.m3u8" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS=\"avc1.4d401f,mp4a.40.2\"\n720p.m3u8\n"))
+	}))
+	defer manifests.Close()
+
+	server := newFakeNATS(t)
+	go server.serve([]string{
+		fmt.Sprintf(`{"id":"ok","url":%q,"options":{"DisableCamouflage":true}}`, manifests.URL+"/master.m3u8"),
+		fmt.Sprintf(`{"id":"missing","url":%q}`, manifests.URL+"/missing.m3u8"),
+		`{"id":"bad","url":"https://example.com/master.m3u8","options":{"TimeoutSecs":5}}`,
+	})
+
+	worker, err := NewQueueWorker(NewProber(&ProbeOptions{DisableCamouflage: true}), QueueConfig{
+		URL:     "nats://user:secret@" + server.listener.Addr().String(),
+		Subject: "probe.jobs",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx) }()
+
+	connect := <-server.lines
+	if !strings.HasPrefix(connect, "CONNECT ") || !strings.Contains(connect, `"user":"user"`) || !strings.Contains(connect, `"pass":"secret"`) {
+		t.Errorf("Expected a CONNECT with the credentials, got %q", connect)
+	}
+	if sub := <-server.lines; sub != "SUB probe.jobs goprobe 1" {
+		t.Errorf("Expected %q, got %q", "SUB probe.jobs goprobe 1", sub)
+	}
+
+	results := map[string]QueueResult{}
+	subjects := map[string]string{}
+	for range 3 {
+		select {
+		case published := <-server.published:
+			subject, data, _ := strings.Cut(published, " ")
+			var result QueueResult
+			if err := json.Unmarshal([]byte(data), &result); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			results[result.ID] = result
+			subjects[result.ID] = subject
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for results, got %d", len(results))
+		}
+	}
+
+	if ok := results["ok"]; ok.Output == nil || len(ok.Output.Streams) == 0 || ok.Error != nil {
+		t.Errorf("Expected the output of the ok job, got %+v", ok)
+	}
+	if subjects["ok"] != "_INBOX.0" {
+		t.Errorf("Expected %q, got %q", "_INBOX.0", subjects["ok"])
+	}
+	if missing := results["missing"]; missing.Output != nil || !strings.Contains(string(missing.Error), `"type"`) {
+		t.Errorf("Expected an error for the missing job, got %+v", missing)
+	}
+	if bad := results["bad"]; !strings.Contains(string(bad.Error), `"validation"`) {
+		t.Errorf("Expected a validation error for unknown options, got %s", bad.Error)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the worker to stop")
+	}
+	if unsub := <-server.lines; unsub != "UNSUB 1" {
+		t.Errorf("Expected %q, got %q", "UNSUB 1", unsub)
+	}
+}
+
+func TestNewQueueWorker(t *testing.T) {
+	tests := []struct {
+		name   string
+		config QueueConfig
+		valid  bool
+	}{
+		{name: "valid", config: QueueConfig{URL: "nats://localhost", Subject: "jobs"}, valid: true},
+		{name: "tls", config: QueueConfig{URL: "tls://nats.example.com:4443", Subject: "jobs"}, valid: true},
+		{name: "missing subject", config: QueueConfig{URL: "nats://localhost"}},
+		{name: "subject with spaces", config: QueueConfig{URL: "nats://localhost", Subject: "probe jobs"}},
+		{name: "amqp", config: QueueConfig{URL: "amqp://localhost", Subject: "jobs"}},
+		{name: "negative concurrency", config: QueueConfig{URL: "nats://localhost", Subject: "jobs", Concurrency: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewQueueWorker(NewProber(nil), tt.config)
+			if tt.valid && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("Expected an error for %+v", tt.config)
+			}
+		})
+	}
+}
+
+func TestJobOptions(t *testing.T) {
+	base := &ProbeOptions{UserAgent: "worker", ProxyURL: "http://proxy:8080", TimeoutSeconds: 30}
+
+	tests := []struct {
+		name    string
+		options string
+		valid   bool
+	}{
+		{name: "allowed", options: `{"TimeoutSeconds": 5, "CustomHeaders": {"Authorization": "Bearer x"}}`, valid: true},
+		{name: "decode command", options: `{"DecodeCheck": {"Command": ["sh", "-c", "id"]}}`},
+		{name: "proxy", options: `{"ProxyURL": "http://attacker:8080"}`},
+		{name: "host overrides", options: `{"PerHostOverrides": {"*": {"Headers": {"X": "1"}}}}`},
+		{name: "unknown", options: `{"TimeoutSecs": 5}`},
+		{name: "not an object", options: `[1]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := jobOptions(base, json.RawMessage(tt.options))
+			if !tt.valid {
+				if err == nil {
+					t.Errorf("Expected an error for %s", tt.options)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if opts.TimeoutSeconds != 5 || opts.CustomHeaders["Authorization"] != "Bearer x" {
+				t.Errorf("Expected the job options to apply, got %+v", opts)
+			}
+			if opts.UserAgent != "worker" || opts.ProxyURL != "http://proxy:8080" {
+				t.Errorf("Expected the worker options to be kept, got %+v", opts)
+			}
+		})
+	}
+	if base.TimeoutSeconds != 30 {
+		t.Errorf("Expected the worker options to be unchanged, got %+v", base)
+	}
+}
+
+func TestJobProber(t *testing.T) {
+	base := NewProber(&ProbeOptions{
+		UserAgent:            "worker",
+		Limits:               &Limits{MaxConcurrentSubRequests: 2},
+		RetryConfig:          DefaultRetryConfig(),
+		CircuitBreakerConfig: DefaultCircuitBreakerConfig(),
+	})
+	worker, err := NewQueueWorker(base, QueueConfig{URL: "nats://localhost", Subject: "jobs"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	first, err := worker.jobProber(json.RawMessage(`{"CustomHeaders": {"Authorization": "Bearer a"}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	same, _ := worker.jobProber(json.RawMessage(`{"CustomHeaders":{"Authorization":"Bearer a"}}`))
+	other, _ := worker.jobProber(json.RawMessage(`{"CustomHeaders": {"Authorization": "Bearer b"}}`))
+	if first != same {
+		t.Error("Expected jobs with the same options to share a prober")
+	}
+	if first == other {
+		t.Error("Expected jobs with different credentials to get their own prober")
+	}
+	if other.opts.CustomHeaders["Authorization"] != "Bearer b" {
+		t.Errorf("Expected the job credentials, got %+v", other.opts.CustomHeaders)
+	}
+	if unchanged, _ := worker.jobProber(json.RawMessage(`{"UserAgent": "worker"}`)); unchanged != base {
+		t.Error("Expected job options equal to the worker's to use the worker's prober")
+	}
+
+	if cap(first.slots) != 2 || first.slots != base.slots {
+		t.Error("Expected job probers to share the worker's request slots")
+	}
+	if first.circuitBreaker("http://example.com/a.m3u8") != base.circuitBreaker("http://example.com/b.m3u8") {
+		t.Error("Expected job probers to share the worker's circuit breakers")
+	}
+}
+
+func TestJobProberEviction(t *testing.T) {
+	worker, err := NewQueueWorker(NewProber(nil), QueueConfig{URL: "nats://localhost", Subject: "jobs"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	options := func(i int) json.RawMessage {
+		return json.RawMessage(fmt.Sprintf(`{"UserAgent": "agent-%d"}`, i))
+	}
+
+	first, _ := worker.jobProber(options(0))
+	second, _ := worker.jobProber(options(1))
+	for i := 2; i <= maxQueueProbers; i++ {
+		if i == maxQueueProbers {
+			// first becomes the most recently used
+			worker.jobProber(options(0))
+		}
+		worker.jobProber(options(i))
+	}
+
+	if len(worker.probers) != maxQueueProbers || worker.proberOrder.Len() != maxQueueProbers {
+		t.Fatalf("Expected %d cached probers, got %d", maxQueueProbers, len(worker.probers))
+	}
+	if again, _ := worker.jobProber(options(0)); again != first {
+		t.Error("Expected the recently used prober to stay cached")
+	}
+	if again, _ := worker.jobProber(options(1)); again == second {
+		t.Error("Expected the least recently used prober to be evicted")
+	}
+}
@@ -0,0 +1,320 @@
+package probe
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RebufferEstimate reports whether the media segments of a rendition download
+// faster than they play at the bandwidth of ProbeOptions.Throttle. Durations
+// are measured in real time.
+type RebufferEstimate struct {
+	StreamID string `json:"stream_id"`
+
+	// BitsPerSecond is the throttled bandwidth; DeclaredBitrate is the stream's (0 if unknown)
+	BitsPerSecond   int64 `json:"bits_per_second"`
+	DeclaredBitrate int64 `json:"declared_bitrate,omitempty"`
+
+	Segments []SegmentDownload `json:"segments,omitempty"`
+
+	// Throughput is the measured download rate over all segments in bits per second
+	Throughput int64 `json:"throughput,omitempty"`
+
+	// Risk reports that at least one segment took longer to download than to play
+	Risk bool `json:"rebuffer_risk"`
+
+	// Error describes why the estimate could not be made
+	Error string `json:"error,omitempty"`
+}
+
+// SegmentDownload is the throttled download of one media segment
+type SegmentDownload struct {
+	URI              string        `json:"uri"`
+	Bytes            int64         `json:"bytes"`
+	MediaDuration    time.Duration `json:"media_duration"`
+	DownloadDuration time.Duration `json:"download_duration"`
+
+	// Fits reports that the segment downloaded within its media duration
+	Fits bool `json:"fits"`
+}
+
+// mediaSegment is a media segment of a rendition with its duration
+type mediaSegment struct {
+	uri      string
+	duration time.Duration
+
+	// byteRange is the HTTP Range of an HLS EXT-X-BYTERANGE segment ("" = whole resource)
+	byteRange string
+}
+
+// estimateRebuffer downloads the first media segments of the selected stream
+// through the throttled link and reports in output.Rebuffer whether they
+// download in real time
+func (p *Prober) estimateRebuffer(ctx context.Context, throttle *Throttle, fetched *fetchResult, output *Output) {
+	stream, err := throttleStream(output, throttle.StreamID)
+	if err != nil {
+		output.Warnings = append(output.Warnings, fmt.Sprintf("rebuffer estimate failed: %s", err))
+		return
+	}
+
+	estimate := &RebufferEstimate{
+		StreamID:        stream.StreamID,
+		BitsPerSecond:   throttle.BitsPerSecond,
+		DeclaredBitrate: streamBitRate(stream),
+	}
+	output.Rebuffer = estimate
+
+	segments, err := p.mediaSegments(ctx, stream, fetched, throttle.segments())
+	if err == nil && len(segments) == 0 {
+		err = fmt.Errorf("stream %s has no media segments", stream.StreamID)
+	}
+	if err != nil {
+		estimate.Error = p.redactor.redactError(err).Error()
+		output.Warnings = append(output.Warnings, fmt.Sprintf("rebuffer estimate of stream %s failed: %s", stream.StreamID, estimate.Error))
+		return
+	}
+
+	var totalBytes int64
+	var totalDuration time.Duration
+	for _, segment := range segments {
+		start := time.Now()
+		size, err := p.downloadSegment(ctx, segment)
+		if err != nil {
+			estimate.Error = p.redactor.redactError(err).Error()
+			output.Warnings = append(output.Warnings, fmt.Sprintf("rebuffer estimate of stream %s failed: %s", stream.StreamID, estimate.Error))
+			break
+		}
+
+		download := SegmentDownload{
+			URI:              segment.uri,
+			Bytes:            size,
+			MediaDuration:    segment.duration,
+			DownloadDuration: time.Since(start),
+		}
+		download.Fits = download.DownloadDuration <= download.MediaDuration
+		if !download.Fits {
+			estimate.Risk = true
+		}
+		estimate.Segments = append(estimate.Segments, download)
+
+		totalBytes += size
+		totalDuration += download.DownloadDuration
+	}
+
+	if totalDuration > 0 {
+		estimate.Throughput = int64(float64(totalBytes*8) / totalDuration.Seconds())
+	}
+	if estimate.Risk {
+		output.Warnings = append(output.Warnings, fmt.Sprintf("stream %s segments download slower than real time at %d bit/s (rebuffer risk)",
+			stream.StreamID, throttle.BitsPerSecond))
+	}
+}
+
+// throttleStream returns the stream with streamID, or the video stream with
+// the highest bitrate ("" streamID). Streams without URLs cannot be selected.
+func throttleStream(output *Output, streamID string) (*StreamInfo, error) {
+	if streamID != "" {
+		for i := range output.Streams {
+			if output.Streams[i].StreamID == streamID {
+				return &output.Streams[i], nil
+			}
+		}
+		return nil, NewValidationError(fmt.Sprintf("stream %q not found", streamID))
+	}
+
+	var selected *StreamInfo
+	for i := range output.Streams {
+		stream := &output.Streams[i]
+		if stream.Type != "Video" || len(stream.urls) == 0 {
+			continue
+		}
+		if selected == nil || streamBitRate(stream) > streamBitRate(selected) {
+			selected = stream
+		}
+	}
+	if selected == nil {
+		return nil, NewValidationError("no video stream with media segments")
+	}
+	return selected, nil
+}
+
+// streamBitRate returns the declared bitrate of a stream in bits per second (0 if unknown)
+func streamBitRate(stream *StreamInfo) int64 {
+	value, _, _ := strings.Cut(stream.BitRate, " ")
+	kbps, _ := strconv.ParseInt(value, 10, 64)
+	return kbps * 1000
+}
+
+// mediaSegments lists the first limit media segments of a stream: from its
+// media playlist for HLS, or from its SegmentTemplate for DASH
+func (p *Prober) mediaSegments(ctx context.Context, stream *StreamInfo, fetched *fetchResult, limit int) ([]mediaSegment, error) {
+	if len(stream.urls) == 0 {
+		return nil, NewValidationError(fmt.Sprintf("stream %q has no playable URL", stream.StreamID))
+	}
+
+	if len(stream.urls) == 1 && isHLSPlaylistURL(stream.urls[0]) {
+		playlist, err := p.fetch(ctx, stream.urls[0])
+		if err != nil {
+			return nil, err
+		}
+		return hlsMediaSegments(playlist.body, playlist.url, limit), nil
+	}
+
+	var mpd MPD
+	if err := xml.Unmarshal([]byte(fetched.body), &mpd); err != nil {
+		return nil, NewParsingError(fetched.url, "MPD", err)
+	}
+	element := stream.element
+	if element.period >= len(mpd.Periods) || element.adaptationSet >= len(mpd.Periods[element.period].AdaptationSets) {
+		return nil, NewValidationError(fmt.Sprintf("stream %q has no representation", stream.StreamID))
+	}
+	period := mpd.Periods[element.period]
+	adaptationSet := period.AdaptationSets[element.adaptationSet]
+	if element.representation >= len(adaptationSet.Representations) {
+		return nil, NewValidationError(fmt.Sprintf("stream %q has no representation", stream.StreamID))
+	}
+	rep := adaptationSet.Representations[element.representation]
+
+	base := fetched.url
+	if candidates := mpdBaseURLs(fetched.url, &mpd, period, adaptationSet, rep); len(candidates) > 0 {
+		base = candidates[0].URL
+	}
+
+	template := mergeSegmentTemplates(period.SegmentTemplate, adaptationSet.SegmentTemplate, rep.SegmentTemplate)
+	if template == nil || template.Media == "" {
+		return nil, NewValidationError(fmt.Sprintf("stream %q has no SegmentTemplate with segment durations", stream.StreamID))
+	}
+	return templateMediaSegments(template, rep, base, limit), nil
+}
+
+// hlsMediaSegments returns the first limit segments of a media playlist,
+// resolved against playlistURL
+func hlsMediaSegments(content, playlistURL string, limit int) []mediaSegment {
+	var segments []mediaSegment
+	var duration float64
+	var byteRange string
+	var nextOffset int64
+
+	for lines := scanLines(content); lines.scan(); {
+		if len(segments) == limit {
+			break
+		}
+		line := strings.TrimSpace(lines.line)
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value := strings.TrimPrefix(line, "#EXTINF:")
+			if comma := strings.Index(value, ","); comma >= 0 {
+				value = value[:comma]
+			}
+			duration, _ = strconv.ParseFloat(value, 64)
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+			lengthValue, offsetValue, hasOffset := strings.Cut(strings.TrimPrefix(line, "#EXT-X-BYTERANGE:"), "@")
+			length, _ := strconv.ParseInt(lengthValue, 10, 64)
+			offset := nextOffset
+			if hasOffset {
+				offset, _ = strconv.ParseInt(offsetValue, 10, 64)
+			}
+			if length > 0 {
+				byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+				nextOffset = offset + length
+			}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			segments = append(segments, mediaSegment{
+				uri:       resolveHLSURI(playlistURL, line),
+				duration:  time.Duration(duration * float64(time.Second)),
+				byteRange: byteRange,
+			})
+			duration, byteRange = 0, ""
+		}
+	}
+	return segments
+}
+
+// templateMediaSegments returns the first limit segments of a SegmentTemplate,
+// from its SegmentTimeline or its @duration
+func templateMediaSegments(template *SegmentTemplate, rep Representation, base string, limit int) []mediaSegment {
+	timescale, _ := strconv.ParseFloat(template.Timescale, 64)
+	if timescale <= 0 {
+		timescale = 1
+	}
+	toDuration := func(units int64) time.Duration {
+		return time.Duration(float64(units) / timescale * float64(time.Second))
+	}
+	number, start := firstTemplateSegment(template)
+
+	var segments []mediaSegment
+	add := func(units int64) {
+		segments = append(segments, mediaSegment{
+			uri:      resolveHLSURI(base, expandSegmentTemplate(template.Media, rep, number, start)),
+			duration: toDuration(units),
+		})
+		number++
+		start += units
+	}
+
+	if template.SegmentTimeline != nil {
+		for _, s := range template.SegmentTimeline.Segments {
+			if t, err := strconv.ParseInt(s.T, 10, 64); err == nil {
+				start = t
+			}
+			d, _ := strconv.ParseInt(s.D, 10, 64)
+			repeat, _ := strconv.Atoi(s.R)
+			for i := 0; i <= repeat && len(segments) < limit; i++ {
+				add(d)
+			}
+		}
+		return segments
+	}
+
+	d, _ := strconv.ParseInt(template.Duration, 10, 64)
+	if d <= 0 {
+		return nil
+	}
+	for len(segments) < limit {
+		add(d)
+	}
+	return segments
+}
+
+// downloadSegment downloads a whole media segment (a single attempt, so the
+// duration is not inflated by retries) and returns its size
+func (p *Prober) downloadSegment(ctx context.Context, segment mediaSegment) (int64, error) {
+	parsedURL, err := validateURL(segment.uri)
+	if err != nil {
+		return 0, err
+	}
+
+	httpClient, err := p.httpClient(parsedURL)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.slots.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer p.slots.release()
+
+	headers := map[string]string{"Accept-Encoding": "identity"}
+	if segment.byteRange != "" {
+		headers["Range"] = segment.byteRange
+	}
+	resp, err := httpClient.get(ctx, parsedURL.String(), headers)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	size, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		if isTimeoutError(err) {
+			return 0, NewTimeoutError(segment.uri, 30) // Default timeout
+		}
+		return 0, NewNetworkError(segment.uri, err)
+	}
+	return size, nil
+}
@@ -0,0 +1,187 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rebufferTestMaster = `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360,CODECS="avc1.64001e,mp4a.40.2"
+low.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2"
+high.m3u8
+`
+
+// rebufferTestServer serves 20KB segments; low.m3u8 declares 2s segments,
+// high.m3u8 0.1s segments
+func rebufferTestServer() *httptest.Server {
+	segment := make([]byte, 20*1024)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/master.m3u8":
+			w.Write([]byte(rebufferTestMaster))
+		case r.URL.Path == "/low.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXTINF:2.0,\nlow1.ts\n#EXTINF:2.0,\nlow2.ts\n#EXTINF:2.0,\nlow3.ts\n#EXT-X-ENDLIST\n"))
+		case r.URL.Path == "/high.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXTINF:0.1,\nhigh1.ts\n#EXTINF:0.1,\nhigh2.ts\n#EXT-X-ENDLIST\n"))
+		case strings.HasSuffix(r.URL.Path, ".ts"):
+			w.Write(segment)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestEstimateRebuffer(t *testing.T) {
+	server := rebufferTestServer()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		streamID string
+		segments int
+		risk     bool
+	}{
+		{"highest bitrate video by default", "", 2, true},
+		{"selected stream", "0:0", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 20KB segments take about 160ms at 1 Mbit/s
+			opts := &ProbeOptions{Throttle: &Throttle{BitsPerSecond: 1000000, StreamID: tt.streamID, Segments: 2}}
+			output, err := NewProber(opts).Probe(context.Background(), server.URL+"/master.m3u8")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			estimate := output.Rebuffer
+			if estimate == nil {
+				t.Fatalf("Expected a rebuffer estimate")
+			}
+			if estimate.Error != "" {
+				t.Fatalf("Unexpected error: %s", estimate.Error)
+			}
+			if len(estimate.Segments) != tt.segments {
+				t.Fatalf("Expected %d segments, got %d", tt.segments, len(estimate.Segments))
+			}
+			for _, segment := range estimate.Segments {
+				if segment.Bytes != 20*1024 {
+					t.Errorf("Expected %d bytes, got %d", 20*1024, segment.Bytes)
+				}
+				if segment.DownloadDuration < 100*time.Millisecond {
+					t.Errorf("Expected a throttled download, got %v", segment.DownloadDuration)
+				}
+			}
+			if estimate.Risk != tt.risk {
+				t.Errorf("Expected risk %v, got %v", tt.risk, estimate.Risk)
+			}
+			if estimate.Throughput <= 0 || estimate.Throughput > 1500000 {
+				t.Errorf("Expected a throughput of about 1 Mbit/s, got %d", estimate.Throughput)
+			}
+		})
+	}
+}
+
+func TestEstimateRebufferUnknownStream(t *testing.T) {
+	server := rebufferTestServer()
+	defer server.Close()
+
+	opts := &ProbeOptions{Throttle: &Throttle{BitsPerSecond: 1000000, StreamID: "9:9"}}
+	output, err := NewProber(opts).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Rebuffer != nil {
+		t.Errorf("Expected no estimate, got %+v", output.Rebuffer)
+	}
+	if len(output.Warnings) != 1 || !strings.Contains(output.Warnings[0], `stream "9:9" not found`) {
+		t.Errorf("Expected a warning about the unknown stream, got %v", output.Warnings)
+	}
+}
+
+func TestHLSMediaSegments(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:1000@0
+media.mp4
+#EXTINF:4.5,
+#EXT-X-BYTERANGE:500
+media.mp4
+#EXTINF:6.0,
+next.ts
+`
+	expected := []mediaSegment{
+		{uri: "https://example.com/hls/media.mp4", duration: 6 * time.Second, byteRange: "bytes=0-999"},
+		{uri: "https://example.com/hls/media.mp4", duration: 4500 * time.Millisecond, byteRange: "bytes=1000-1499"},
+	}
+
+	segments := hlsMediaSegments(playlist, "https://example.com/hls/video.m3u8", 2)
+	if !reflect.DeepEqual(segments, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, segments)
+	}
+}
+
+func TestTemplateMediaSegments(t *testing.T) {
+	rep := Representation{ID: "v1"}
+
+	tests := []struct {
+		name     string
+		template *SegmentTemplate
+		expected []mediaSegment
+	}{
+		{
+			name:     "duration",
+			template: &SegmentTemplate{Media: "$RepresentationID$/$Number$.m4s", StartNumber: "5", Duration: "4000", Timescale: "1000"},
+			expected: []mediaSegment{
+				{uri: "https://example.com/dash/v1/5.m4s", duration: 4 * time.Second},
+				{uri: "https://example.com/dash/v1/6.m4s", duration: 4 * time.Second},
+				{uri: "https://example.com/dash/v1/7.m4s", duration: 4 * time.Second},
+			},
+		},
+		{
+			name: "timeline",
+			template: &SegmentTemplate{Media: "$RepresentationID$/$Time$.m4s", Timescale: "90000", SegmentTimeline: &SegmentTimeline{
+				Segments: []TimelineSegment{{T: "0", D: "180000", R: "1"}, {D: "90000"}},
+			}},
+			expected: []mediaSegment{
+				{uri: "https://example.com/dash/v1/0.m4s", duration: 2 * time.Second},
+				{uri: "https://example.com/dash/v1/180000.m4s", duration: 2 * time.Second},
+				{uri: "https://example.com/dash/v1/360000.m4s", duration: time.Second},
+			},
+		},
+		{
+			name:     "no duration",
+			template: &SegmentTemplate{Media: "$Number$.m4s"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments := templateMediaSegments(tt.template, rep, "https://example.com/dash/manifest.mpd", 3)
+			if !reflect.DeepEqual(segments, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, segments)
+			}
+		})
+	}
+}
+
+func TestThrottleValidation(t *testing.T) {
+	tests := []*Throttle{
+		{BitsPerSecond: 0},
+		{BitsPerSecond: 1000, BurstBytes: -1},
+		{BitsPerSecond: 1000, Segments: -1},
+	}
+
+	for _, throttle := range tests {
+		if err := validateProbeOptions(&ProbeOptions{Throttle: throttle}); err == nil {
+			t.Errorf("Expected a validation error for %+v", throttle)
+		}
+	}
+}
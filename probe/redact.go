@@ -0,0 +1,228 @@
+package probe
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces sensitive values in logs and errors
+const redactedValue = "REDACTED"
+
+// DefaultRedactedParams lists query parameters whose values are redacted by default.
+// Matching is case-insensitive.
+var DefaultRedactedParams = []string{
+	"token",
+	"access_token",
+	"auth",
+	"sig",
+	"signature",
+	"hdnts",
+	"hdntl",
+	"policy",
+	"key-pair-id",
+	"x-amz-signature",
+	"x-amz-credential",
+	"x-amz-security-token",
+	"x-goog-signature",
+}
+
+// DefaultRedactedHeaders lists request headers whose values are redacted by default
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Api-Key",
+}
+
+// LogConfig controls log volume and redaction of sensitive values
+type LogConfig struct {
+	// DebugSampleRate emits only 1 in N debug messages (0 or 1 = all).
+	// Counts are kept per Prober.
+	DebugSampleRate int
+
+	// RedactParams lists query parameters redacted from logs and errors (nil = DefaultRedactedParams)
+	RedactParams []string
+
+	// RedactHeaders lists custom header values redacted from logs and errors (nil = DefaultRedactedHeaders)
+	RedactHeaders []string
+
+	// DisableRedaction turns off redaction of logs and errors
+	DisableRedaction bool
+}
+
+// userInfoPattern matches user:pass@ credentials in URLs
+var userInfoPattern = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://)[^/@\s"']+@`)
+
+// URLScrubber removes credentials from URLs: values of denylisted query
+// parameters are replaced and user:pass information is stripped. The same
+// scrubbing is applied to URLs in errors and logs produced by a Prober.
+type URLScrubber struct {
+	// Params lists query parameters to redact, case-insensitive (nil = DefaultRedactedParams)
+	Params []string
+
+	once     sync.Once
+	redactor *redactor
+}
+
+// Scrub returns rawURL with credentials removed
+func (s *URLScrubber) Scrub(rawURL string) string {
+	s.once.Do(func() {
+		params := s.Params
+		if params == nil {
+			params = DefaultRedactedParams
+		}
+		s.redactor = &redactor{params: paramPattern(params)}
+	})
+	return s.redactor.redactString(rawURL)
+}
+
+var defaultScrubber = &URLScrubber{}
+
+// ScrubURL removes credentials from rawURL using the default parameter denylist
+func ScrubURL(rawURL string) string {
+	return defaultScrubber.Scrub(rawURL)
+}
+
+// redactor scrubs sensitive values from strings
+type redactor struct {
+	params  *regexp.Regexp
+	secrets []string
+}
+
+// paramPattern builds a pattern matching the values of the given query parameters
+func paramPattern(params []string) *regexp.Regexp {
+	if len(params) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(params))
+	for i, param := range params {
+		quoted[i] = regexp.QuoteMeta(param)
+	}
+	return regexp.MustCompile(`(?i)([?&;](?:` + strings.Join(quoted, "|") + `)=)[^&#\s"']*`)
+}
+
+// newRedactor builds a redactor for the given options (nil when redaction is disabled)
+func newRedactor(opts *ProbeOptions) *redactor {
+	var cfg LogConfig
+	if opts != nil && opts.LogConfig != nil {
+		cfg = *opts.LogConfig
+	}
+	if cfg.DisableRedaction {
+		return nil
+	}
+
+	params := cfg.RedactParams
+	if params == nil {
+		params = DefaultRedactedParams
+	}
+	headers := cfg.RedactHeaders
+	if headers == nil {
+		headers = DefaultRedactedHeaders
+	}
+
+	r := &redactor{params: paramPattern(params)}
+
+	if opts != nil {
+		r.addHeaderSecrets(opts.CustomHeaders, headers)
+		for _, override := range opts.PerHostOverrides {
+			if override != nil {
+				r.addHeaderSecrets(override.CustomHeaders, headers)
+			}
+		}
+	}
+
+	return r
+}
+
+// addHeaderSecrets records the values of the sensitive headers among customHeaders
+func (r *redactor) addHeaderSecrets(customHeaders map[string]string, sensitive []string) {
+	for name, value := range customHeaders {
+		if value == "" {
+			continue
+		}
+		for _, header := range sensitive {
+			if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(header) {
+				r.secrets = append(r.secrets, value)
+				break
+			}
+		}
+	}
+}
+
+// redactString replaces sensitive values in s
+func (r *redactor) redactString(s string) string {
+	if r == nil {
+		return s
+	}
+	s = userInfoPattern.ReplaceAllString(s, "${1}"+redactedValue+"@")
+	if r.params != nil {
+		s = r.params.ReplaceAllString(s, "${1}"+redactedValue)
+	}
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	return s
+}
+
+// redactFields returns a copy of fields with sensitive string values redacted
+func (r *redactor) redactFields(fields map[string]interface{}) map[string]interface{} {
+	if r == nil || len(fields) == 0 {
+		return fields
+	}
+	redacted := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		switch val := v.(type) {
+		case string:
+			redacted[k] = r.redactString(val)
+		case error:
+			redacted[k] = r.redactString(val.Error())
+		default:
+			redacted[k] = v
+		}
+	}
+	return redacted
+}
+
+// redactError scrubs sensitive values from err while preserving its chain for errors.Is/As
+func (r *redactor) redactError(err error) error {
+	if r == nil || err == nil {
+		return err
+	}
+
+	if probeErr, ok := err.(*ProbeError); ok {
+		scrubbed := *probeErr
+		if scrubbed.rawURL == "" {
+			scrubbed.rawURL = probeErr.URL
+		}
+		scrubbed.URL = r.redactString(probeErr.URL)
+		scrubbed.Message = r.redactString(probeErr.Message)
+		if probeErr.Cause != nil {
+			scrubbed.Cause = r.redactError(probeErr.Cause)
+		}
+		return &scrubbed
+	}
+
+	msg := err.Error()
+	if redacted := r.redactString(msg); redacted != msg {
+		return &redactedError{msg: redacted, err: err}
+	}
+	return err
+}
+
+// redactedError hides the message of an error while keeping it unwrappable
+type redactedError struct {
+	msg string
+	err error
+}
+
+// Error implements the error interface
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the original error
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
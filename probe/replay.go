@@ -0,0 +1,87 @@
+package probe
+
+import (
+	"context"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Replay runs archived manifest snapshots through the same diff pipeline as
+// Watch, for reconstructing what a live stream's manifests did over time.
+//
+// Snapshots are the regular files at the root of fsys, replayed in lexical
+// name order (use sortable timestamps in file names). Each event's Time is the
+// snapshot's modification time. manifestURL is the URL the snapshots were
+// fetched from; it is used to resolve relative URIs and reported in events.
+// The events channel is closed once all snapshots have been replayed, in which
+// case Err returns nil. WatchOptions.Interval is ignored.
+//
+// Example:
+//
+//	watcher := prober.Replay(ctx, os.DirFS("archive/channel1"), "https://example.com/live.mpd", nil)
+//	for event := range watcher.Events() {
+//	    fmt.Println(event.Time, event.Type)
+//	}
+func (p *Prober) Replay(ctx context.Context, fsys fs.FS, manifestURL string, opts *WatchOptions) *Watcher {
+	w := newWatcher(p, manifestURL, opts)
+	go w.replay(withLogger(ctx, p.logger), fsys)
+	return w
+}
+
+// replay feeds every snapshot in fsys through the watch state
+func (w *Watcher) replay(ctx context.Context, fsys fs.FS) {
+	defer close(w.done)
+	defer close(w.events)
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		w.err = err
+		return
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	logInfo(ctx, "Starting manifest replay", map[string]interface{}{
+		"url":       w.url,
+		"snapshots": len(names),
+	})
+
+	state := newWatchState(w.url)
+	for _, name := range names {
+		if !w.emit(ctx, w.replaySnapshot(ctx, state, fsys, name)) {
+			return
+		}
+	}
+}
+
+// replaySnapshot reads and parses a single snapshot and returns the resulting events
+func (w *Watcher) replaySnapshot(ctx context.Context, state *watchState, fsys fs.FS, name string) []Event {
+	now := w.prober.clock.Now()
+	if info, err := fs.Stat(fsys, name); err == nil {
+		now = info.ModTime()
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return state.fail(now, err)
+	}
+	body := string(data)
+
+	if !state.changed(body) {
+		return state.unchanged(now)
+	}
+
+	output, err := w.prober.parse(ctx, body, w.url)
+	if err != nil {
+		return state.fail(now, w.prober.redactor.redactError(err))
+	}
+
+	return state.update(now, body, output)
+}
@@ -0,0 +1,54 @@
+package probe
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"0001.m3u8":  {Data: []byte(watchTestManifest), ModTime: base},
+		"0002.m3u8":  {Data: []byte(watchTestManifest), ModTime: base.Add(time.Minute)},
+		"0003.m3u8":  {Data: []byte("not a manifest"), ModTime: base.Add(2 * time.Minute)},
+		"0004.m3u8":  {Data: []byte(watchTestManifestUpdated), ModTime: base.Add(3 * time.Minute)},
+		".DS_Store":  {Data: []byte("ignored")},
+		"sub/x.m3u8": {Data: []byte(watchTestManifest)},
+	}
+
+	watcher := NewProber(nil).Replay(context.Background(), fsys, "https://example.com/master.m3u8", nil)
+
+	var received []Event
+	for event := range watcher.Events() {
+		received = append(received, event)
+	}
+
+	if watcher.Err() != nil {
+		t.Fatalf("Unexpected error: %v", watcher.Err())
+	}
+
+	expected := []EventType{
+		EventManifestUpdated,
+		EventErrorOccurred,
+		EventRecovered,
+		EventManifestUpdated,
+		EventStreamAdded,
+		EventStreamAdded,
+	}
+
+	if len(received) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(received), received)
+	}
+
+	for i, eventType := range expected {
+		if received[i].Type != eventType {
+			t.Errorf("Event %d: expected %s, got %s", i, eventType, received[i].Type)
+		}
+	}
+
+	if !received[3].Time.Equal(base.Add(3 * time.Minute)) {
+		t.Errorf("Expected snapshot modification time, got %v", received[3].Time)
+	}
+}
@@ -0,0 +1,201 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultReportOrigins is the number of origins listed in Report.SlowestOrigins
+const DefaultReportOrigins = 10
+
+// Report aggregates the results of a batch of probes into catalog-level statistics
+type Report struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+
+	// Codecs counts streams by type and codec (e.g. Codecs["Video"]["h264"])
+	Codecs map[string]map[string]int `json:"codecs"`
+
+	// Resolutions counts video streams by resolution
+	Resolutions map[string]int `json:"resolutions"`
+
+	// DRM summarizes content protection across successfully probed manifests
+	DRM DRMCoverage `json:"drm"`
+
+	// ErrorsByType counts failed probes by ProbeError type
+	ErrorsByType map[string]int `json:"errors_by_type,omitempty"`
+
+	// ErrorsByHost counts failed probes by manifest host
+	ErrorsByHost map[string]int `json:"errors_by_host,omitempty"`
+
+	// SlowestOrigins lists the hosts with the highest mean probe duration
+	SlowestOrigins []OriginLatency `json:"slowest_origins,omitempty"`
+
+	// CacheByURL is the CDN cache behavior of the manifest responses of
+	// successful probes by redacted URL, for URLs reporting cache headers
+	CacheByURL map[string]*CacheStats `json:"cache_by_url,omitempty"`
+
+	// CacheByHost rolls CacheByURL up by host
+	CacheByHost map[string]*CacheStats `json:"cache_by_host,omitempty"`
+}
+
+// DRMCoverage counts protected and clear manifests
+type DRMCoverage struct {
+	Protected int `json:"protected"`
+	Clear     int `json:"clear"`
+
+	// Systems counts manifests per DRM system (e.g. "widevine") or encryption
+	// scheme ("cenc", "aes-128", "sample-aes")
+	Systems map[string]int `json:"systems,omitempty"`
+}
+
+// OriginLatency summarizes probe durations for one host
+type OriginLatency struct {
+	Host   string        `json:"host"`
+	Probes int           `json:"probes"`
+	Mean   time.Duration `json:"mean"`
+	Max    time.Duration `json:"max"`
+}
+
+// NewReport aggregates batch results into a report
+func NewReport(results []BatchResult) *Report {
+	report := &Report{
+		Total:        len(results),
+		Codecs:       make(map[string]map[string]int),
+		Resolutions:  make(map[string]int),
+		ErrorsByType: make(map[string]int),
+		ErrorsByHost: make(map[string]int),
+	}
+
+	latencies := make(map[string]*OriginLatency)
+	lastCache := make(map[string]*CacheStatus)
+	redactor := newRedactor(nil)
+	for _, result := range results {
+		host := resultHost(result.URL)
+
+		if result.Err != nil {
+			report.Failed++
+			report.ErrorsByType[resultErrorType(result.Err)]++
+			report.ErrorsByHost[host]++
+		} else {
+			report.Succeeded++
+			report.addOutput(result.Output)
+			report.addCache(redactor.redactString(result.URL), host, result.Output.Cache, lastCache[result.URL])
+			lastCache[result.URL] = result.Output.Cache
+		}
+
+		latency, ok := latencies[host]
+		if !ok {
+			latency = &OriginLatency{Host: host}
+			latencies[host] = latency
+		}
+		latency.Probes++
+		latency.Mean += result.Duration
+		if result.Duration > latency.Max {
+			latency.Max = result.Duration
+		}
+	}
+
+	for _, latency := range latencies {
+		latency.Mean /= time.Duration(latency.Probes)
+		report.SlowestOrigins = append(report.SlowestOrigins, *latency)
+	}
+	sort.Slice(report.SlowestOrigins, func(i, j int) bool {
+		a, b := report.SlowestOrigins[i], report.SlowestOrigins[j]
+		if a.Mean != b.Mean {
+			return a.Mean > b.Mean
+		}
+		return a.Host < b.Host
+	})
+	if len(report.SlowestOrigins) > DefaultReportOrigins {
+		report.SlowestOrigins = report.SlowestOrigins[:DefaultReportOrigins]
+	}
+
+	return report
+}
+
+// addCache adds the cache status of a manifest response of the redacted
+// manifestURL on host to the report; previous is that of the previous
+// response of the same URL
+func (r *Report) addCache(manifestURL, host string, status, previous *CacheStatus) {
+	if status == nil {
+		return
+	}
+	if r.CacheByURL == nil {
+		r.CacheByURL = make(map[string]*CacheStats)
+		r.CacheByHost = make(map[string]*CacheStats)
+	}
+	cacheStatsOf(r.CacheByURL, manifestURL).observe(status, previous)
+	cacheStatsOf(r.CacheByHost, host).observe(status, previous)
+}
+
+// cacheStatsOf returns the stats of key in stats, adding them if needed
+func cacheStatsOf(stats map[string]*CacheStats, key string) *CacheStats {
+	s, ok := stats[key]
+	if !ok {
+		s = &CacheStats{}
+		stats[key] = s
+	}
+	return s
+}
+
+// addOutput adds the streams and features of a successful probe to the report
+func (r *Report) addOutput(output *Output) {
+	for _, stream := range output.Streams {
+		codecs, ok := r.Codecs[stream.Type]
+		if !ok {
+			codecs = make(map[string]int)
+			r.Codecs[stream.Type] = codecs
+		}
+		codecs[stream.Codec]++
+
+		if stream.Type == "Video" && stream.Resolution != "" {
+			r.Resolutions[stream.Resolution]++
+		}
+	}
+
+	protected := false
+	for _, feature := range output.Features {
+		system := strings.TrimPrefix(feature, "drm:")
+		if system == feature && feature != "cenc" && feature != "aes-128" && feature != "sample-aes" {
+			continue
+		}
+		if r.DRM.Systems == nil {
+			r.DRM.Systems = make(map[string]int)
+		}
+		r.DRM.Systems[system]++
+		protected = true
+	}
+
+	if protected {
+		r.DRM.Protected++
+	} else {
+		r.DRM.Clear++
+	}
+}
+
+// resultHost returns the host of a manifest URL for grouping
+func resultHost(manifestURL string) string {
+	parsed, err := url.Parse(manifestURL)
+	if err != nil || parsed.Host == "" {
+		return "invalid"
+	}
+	return parsed.Host
+}
+
+// resultErrorType classifies a probe error for the report
+func resultErrorType(err error) string {
+	var probeErr *ProbeError
+	if errors.As(err, &probeErr) {
+		return string(probeErr.Type)
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return string(ErrorTypeTimeout)
+	}
+	return "unknown"
+}
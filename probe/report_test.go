@@ -0,0 +1,88 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbeBatchReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clear.m3u8":
+			w.Write([]byte(watchTestManifestUpdated))
+		case "/protected.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-SESSION-KEY:METHOD=SAMPLE-AES,URI=\"skd://key\",KEYFORMAT=\"com.apple.streamingkeydelivery\"\n" + watchTestManifest[len("#EXTM3U\n"):]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	urls := []string{
+		server.URL + "/clear.m3u8",
+		server.URL + "/protected.m3u8",
+		server.URL + "/missing.m3u8",
+		"not a url",
+	}
+
+	results := NewProber(nil).ProbeBatch(context.Background(), urls, 2)
+	if len(results) != len(urls) {
+		t.Fatalf("Expected %d results, got %d", len(urls), len(results))
+	}
+	for i, result := range results {
+		if result.URL != urls[i] {
+			t.Errorf("Expected result %d for %q, got %q", i, urls[i], result.URL)
+		}
+	}
+
+	report := NewReport(results)
+
+	if report.Total != 4 || report.Succeeded != 2 || report.Failed != 2 {
+		t.Errorf("Expected 4 total, 2 succeeded, 2 failed, got %d, %d, %d", report.Total, report.Succeeded, report.Failed)
+	}
+	if got := report.Codecs["Video"]["h264"]; got != 3 {
+		t.Errorf("Expected 3 h264 video streams, got %d", got)
+	}
+	if got := report.Resolutions["1280x720"]; got != 2 {
+		t.Errorf("Expected 2 streams at 1280x720, got %d", got)
+	}
+	if report.DRM.Protected != 1 || report.DRM.Clear != 1 {
+		t.Errorf("Expected 1 protected and 1 clear manifest, got %d and %d", report.DRM.Protected, report.DRM.Clear)
+	}
+	if got := report.DRM.Systems["fairplay"]; got != 1 {
+		t.Errorf("Expected 1 fairplay manifest, got %d", got)
+	}
+	if got := report.ErrorsByType[string(ErrorTypeAuth)]; got != 1 {
+		t.Errorf("Expected 1 auth error, got %d", got)
+	}
+	if got := report.ErrorsByType[string(ErrorTypeValidation)]; got != 1 {
+		t.Errorf("Expected 1 validation error, got %d", got)
+	}
+	if got := report.ErrorsByHost["invalid"]; got != 1 {
+		t.Errorf("Expected 1 error for invalid host, got %d", got)
+	}
+}
+
+func TestReportSlowestOrigins(t *testing.T) {
+	results := []BatchResult{
+		{URL: "https://fast.example.com/a.mpd", Duration: 100 * time.Millisecond},
+		{URL: "https://slow.example.com/a.mpd", Duration: 2 * time.Second},
+		{URL: "https://slow.example.com/b.mpd", Duration: 4 * time.Second},
+	}
+	for i := range results {
+		results[i].Output = &Output{}
+	}
+
+	report := NewReport(results)
+
+	if len(report.SlowestOrigins) != 2 {
+		t.Fatalf("Expected 2 origins, got %d", len(report.SlowestOrigins))
+	}
+	slowest := report.SlowestOrigins[0]
+	if slowest.Host != "slow.example.com" || slowest.Probes != 2 || slowest.Mean != 3*time.Second || slowest.Max != 4*time.Second {
+		t.Errorf("Unexpected slowest origin: %+v", slowest)
+	}
+}
@@ -0,0 +1,167 @@
+package probe
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// templateIdentifierPattern matches SegmentTemplate identifiers such as
+// $Number$, $Number%05d$ and $$
+var templateIdentifierPattern = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth|SubNumber)?(%0\d+d)?\$`)
+
+// ResolveURLs returns the URLs a player would fetch first for a stream: the
+// media playlist URI for HLS streams, and the initialization and first media
+// segment URLs for DASH streams (the single resource of SegmentBase
+// representations). The URLs can be passed to tools such as ffmpeg.
+func (o *Output) ResolveURLs(streamID string) ([]string, error) {
+	for _, stream := range o.Streams {
+		if stream.StreamID != streamID {
+			continue
+		}
+		if len(stream.urls) == 0 {
+			return nil, NewValidationError(fmt.Sprintf("stream %q has no playable URL", streamID))
+		}
+		return append([]string(nil), stream.urls...), nil
+	}
+	return nil, NewValidationError(fmt.Sprintf("stream %q not found", streamID))
+}
+
+// mpdStreamURLs resolves the initialization and first media segment URLs of a
+// representation through its preferred BaseURL. The base URL candidates are
+// returned when there is more than one.
+func mpdStreamURLs(manifestURL string, mpd *MPD, period Period, adaptationSet AdaptationSet, rep Representation) ([]string, []BaseURLCandidate) {
+	candidates := mpdBaseURLs(manifestURL, mpd, period, adaptationSet, rep)
+	switch len(candidates) {
+	case 0:
+		return mpdSegmentURLs(manifestURL, false, period, adaptationSet, rep), nil
+	case 1:
+		return candidates[0].urls, nil
+	default:
+		return candidates[0].urls, candidates
+	}
+}
+
+// mpdSegmentURLs resolves the initialization and first media segment URLs of
+// a representation against base. Without a BaseURL (hasBase false), a
+// representation without segment information has no URL.
+func mpdSegmentURLs(base string, hasBase bool, period Period, adaptationSet AdaptationSet, rep Representation) []string {
+	if template := mergeSegmentTemplates(period.SegmentTemplate, adaptationSet.SegmentTemplate, rep.SegmentTemplate); template != nil {
+		var urls []string
+		if template.Initialization != "" {
+			urls = append(urls, resolveHLSURI(base, expandSegmentTemplate(template.Initialization, rep, 0, 0)))
+		}
+		if template.Media != "" {
+			number, time := firstTemplateSegment(template)
+			urls = append(urls, resolveHLSURI(base, expandSegmentTemplate(template.Media, rep, number, time)))
+		}
+		return urls
+	}
+
+	if list := firstSegmentList(period.SegmentList, adaptationSet.SegmentList, rep.SegmentList); list != nil {
+		var urls []string
+		if list.Initialization != nil && list.Initialization.SourceURL != "" {
+			urls = append(urls, resolveHLSURI(base, list.Initialization.SourceURL))
+		}
+		if len(list.SegmentURLs) > 0 {
+			if media := list.SegmentURLs[0].Media; media != "" {
+				urls = append(urls, resolveHLSURI(base, media))
+			} else {
+				urls = append(urls, base)
+			}
+		}
+		return urls
+	}
+
+	// SegmentBase, or a single-segment representation: the BaseURL is the media
+	if !hasBase {
+		return nil
+	}
+	return []string{base}
+}
+
+// mergeSegmentTemplates merges SegmentTemplate attributes inherited from the
+// period and adaptation set, innermost first (nil if there is no template)
+func mergeSegmentTemplates(templates ...*SegmentTemplate) *SegmentTemplate {
+	var merged *SegmentTemplate
+	for _, template := range templates {
+		if template == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &SegmentTemplate{}
+		}
+		if template.Initialization != "" {
+			merged.Initialization = template.Initialization
+		}
+		if template.Media != "" {
+			merged.Media = template.Media
+		}
+		if template.StartNumber != "" {
+			merged.StartNumber = template.StartNumber
+		}
+		if template.Duration != "" {
+			merged.Duration = template.Duration
+		}
+		if template.Timescale != "" {
+			merged.Timescale = template.Timescale
+		}
+		if template.SegmentTimeline != nil {
+			merged.SegmentTimeline = template.SegmentTimeline
+		}
+	}
+	return merged
+}
+
+// firstSegmentList returns the innermost SegmentList
+func firstSegmentList(lists ...*SegmentList) *SegmentList {
+	var innermost *SegmentList
+	for _, list := range lists {
+		if list != nil {
+			innermost = list
+		}
+	}
+	return innermost
+}
+
+// firstTemplateSegment returns the $Number$ and $Time$ of the first segment of a template
+func firstTemplateSegment(template *SegmentTemplate) (int64, int64) {
+	number := int64(1)
+	if n, err := strconv.ParseInt(template.StartNumber, 10, 64); err == nil {
+		number = n
+	}
+
+	var time int64
+	if template.SegmentTimeline != nil && len(template.SegmentTimeline.Segments) > 0 {
+		time, _ = strconv.ParseInt(template.SegmentTimeline.Segments[0].T, 10, 64)
+	}
+
+	return number, time
+}
+
+// expandSegmentTemplate substitutes the identifiers of a SegmentTemplate URL
+func expandSegmentTemplate(template string, rep Representation, number, time int64) string {
+	return templateIdentifierPattern.ReplaceAllStringFunc(template, func(identifier string) string {
+		match := templateIdentifierPattern.FindStringSubmatch(identifier)
+		format := match[2]
+		if format == "" {
+			format = "%d"
+		}
+
+		switch match[1] {
+		case "":
+			return "$"
+		case "RepresentationID":
+			return rep.ID
+		case "Number":
+			return fmt.Sprintf(format, number)
+		case "Time":
+			return fmt.Sprintf(format, time)
+		case "Bandwidth":
+			bandwidth, _ := strconv.ParseInt(rep.Bandwidth, 10, 64)
+			return fmt.Sprintf(format, bandwidth)
+		default:
+			return identifier
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package probe
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveURLsMPD(t *testing.T) {
+	manifest := `<?xml version="1.0"?>
+<MPD type="static">
+  <BaseURL>https://cdn.example.com/vod/</BaseURL>
+  <Period id="1">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <SegmentTemplate initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/seg-$Number%05d$.m4s" startNumber="10"/>
+      <Representation id="720p" codecs="avc1.64001f" width="1280" height="720" bandwidth="3000000"/>
+      <Representation id="1080p" codecs="avc1.640028" width="1920" height="1080" bandwidth="6000000">
+        <SegmentTemplate media="$Bandwidth$/$Time$.m4s">
+          <SegmentTimeline><S t="90000" d="180000" r="4"/></SegmentTimeline>
+        </SegmentTemplate>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet id="2" contentType="audio" mimeType="audio/mp4" lang="en">
+      <BaseURL>audio/</BaseURL>
+      <SegmentList>
+        <Initialization sourceURL="init.mp4"/>
+        <SegmentURL media="seg1.m4s"/>
+        <SegmentURL media="seg2.m4s"/>
+      </SegmentList>
+      <Representation id="aac" codecs="mp4a.40.2"/>
+    </AdaptationSet>
+    <AdaptationSet id="3" contentType="text" mimeType="application/mp4" lang="en">
+      <Representation id="subs" codecs="stpp">
+        <BaseURL>subs/en.mp4</BaseURL>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+	output, err := parseMPDManifest(manifest, "https://origin.example.com/manifest.mpd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		streamID string
+		expected []string
+	}{
+		{"0:0", []string{"https://cdn.example.com/vod/720p/init.mp4", "https://cdn.example.com/vod/720p/seg-00010.m4s"}},
+		{"0:1", []string{"https://cdn.example.com/vod/1080p/init.mp4", "https://cdn.example.com/vod/6000000/90000.m4s"}},
+		{"0:2(en)", []string{"https://cdn.example.com/vod/audio/init.mp4", "https://cdn.example.com/vod/audio/seg1.m4s"}},
+		{"0:3(en)", []string{"https://cdn.example.com/vod/subs/en.mp4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.streamID, func(t *testing.T) {
+			urls, err := output.ResolveURLs(tt.streamID)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(urls, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, urls)
+			}
+		})
+	}
+}
+
+func TestResolveURLsHLS(t *testing.T) {
+	manifest := `#EXTM3U
+#EXT-X-MEDIA:TYPE=VIDEO,GROUP-ID="angles",NAME="Wide",URI="angles/wide.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2",VIDEO="angles"
+720p.m3u8?token=abc`
+
+	output, err := parseHLSManifest(manifest, "https://example.com/live/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		streamID string
+		expected string
+	}{
+		{"0:0", "https://example.com/live/720p.m3u8?token=abc"},
+		{"0:1", "https://example.com/live/720p.m3u8?token=abc"},
+		{"0:2", "https://example.com/live/angles/wide.m3u8"},
+	}
+
+	for _, tt := range tests {
+		urls, err := output.ResolveURLs(tt.streamID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(urls) != 1 || urls[0] != tt.expected {
+			t.Errorf("Expected [%s], got %v", tt.expected, urls)
+		}
+	}
+
+	_, err = output.ResolveURLs("0:9")
+	var probeErr *ProbeError
+	if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeValidation {
+		t.Errorf("Expected validation error for unknown stream, got %v", err)
+	}
+}
+
+func TestExpandSegmentTemplate(t *testing.T) {
+	rep := Representation{ID: "v1", Bandwidth: "500000"}
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"$RepresentationID$/$Number$.m4s", "v1/7.m4s"},
+		{"seg_$Number%03d$.m4s", "seg_007.m4s"},
+		{"$Bandwidth$/$Time$.m4s", "500000/1234.m4s"},
+		{"price$$.m4s", "price$.m4s"},
+	}
+	for _, tt := range tests {
+		if got := expandSegmentTemplate(tt.template, rep, 7, 1234); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"math"
-	"math/rand"
 	"sync"
 	"time"
 )
@@ -26,10 +25,61 @@ type RetryConfig struct {
 	// Jitter adds randomness to delays to avoid thundering herd (default: true)
 	Jitter bool
 	
+	// JitterStrategy selects how randomness is applied to delays. Empty uses
+	// JitterProportional when Jitter is set and JitterNone otherwise.
+	JitterStrategy JitterStrategy
+	
+	// PerAttemptTimeout abandons a single attempt after this long so it can be
+	// retried, while the context bounds the whole operation (0 = no limit).
+	// Only applies to operations run with ExecuteWithAttemptContext.
+	PerAttemptTimeout time.Duration
+	
+	// Backoff replaces the built-in exponential backoff (nil = built-in).
+	// InitialDelay, MaxDelay, BackoffMultiplier and jitter settings are ignored
+	// when set; MaxRetries still bounds the number of attempts.
+	Backoff BackoffStrategy
+	
 	// RetryableErrors defines which error types should trigger retries
 	RetryableErrors []ErrorType
+	
+	// MinAttemptDuration is the time an attempt needs to be worthwhile. When the
+	// context has a deadline, the backoff delay is shortened to leave this much
+	// time, and a timeout ProbeError matching context.DeadlineExceeded and
+	// caused by the last failure is returned early when that is impossible
+	// (default: 0, which only gives up when the delay would reach the deadline).
+	MinAttemptDuration time.Duration
+	
+	// Clock times the delays between attempts (nil = system clock)
+	Clock Clock
+	
+	// Rand randomizes jitter (nil = math/rand)
+	Rand Rand
 }
 
+// BackoffStrategy computes delays between retry attempts. Implementations are
+// shared by concurrent operations and must be safe for concurrent use.
+type BackoffStrategy interface {
+	// NextDelay returns the delay before retrying after the given failed
+	// attempt (starting at 0) and its error, or false to stop retrying.
+	NextDelay(attempt int, lastErr error) (time.Duration, bool)
+}
+
+// JitterStrategy selects how retry delays are randomized
+// (see https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/)
+type JitterStrategy string
+
+const (
+	// JitterNone uses the exponential delay as is
+	JitterNone JitterStrategy = "none"
+	// JitterProportional adds up to 25% to the exponential delay
+	JitterProportional JitterStrategy = "proportional"
+	// JitterFull picks a delay between 0 and the exponential delay
+	JitterFull JitterStrategy = "full"
+	// JitterDecorrelated picks a delay between InitialDelay and three times the
+	// previous delay, independently of the attempt number
+	JitterDecorrelated JitterStrategy = "decorrelated"
+)
+
 // DefaultRetryConfig returns sensible defaults for retry configuration
 func DefaultRetryConfig() *RetryConfig {
 	return &RetryConfig{
@@ -55,6 +105,14 @@ type CircuitBreakerConfig struct {
 	
 	// HalfOpenMaxRequests is max requests allowed in half-open state (default: 3)
 	HalfOpenMaxRequests int
+	
+	// PerAttempt records every retry attempt with the breaker instead of the
+	// whole retried operation, so bursts of failures open the circuit sooner.
+	// Once the circuit opens, remaining retries are abandoned.
+	PerAttempt bool
+	
+	// Clock times failures and the reset timeout (nil = system clock)
+	Clock Clock
 }
 
 // DefaultCircuitBreakerConfig returns sensible defaults
@@ -83,6 +141,7 @@ type CircuitBreaker struct {
 	failures  int
 	requests  int
 	lastFailTime time.Time
+	clock     Clock
 	mutex     sync.RWMutex
 }
 
@@ -95,6 +154,7 @@ func NewCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
 	return &CircuitBreaker{
 		config: config,
 		state:  CircuitStateClosed,
+		clock:  clockOrSystem(config.Clock),
 	}
 }
 
@@ -104,11 +164,8 @@ func (cb *CircuitBreaker) Execute(ctx context.Context, fn func() error) error {
 		return fn()
 	}
 	
-	if !cb.allowRequest() {
-		return &ProbeError{
-			Type:    ErrorTypeNetwork,
-			Message: "circuit breaker is open",
-		}
+	if allowed, retryAfter := cb.allowRequest(); !allowed {
+		return NewCircuitOpenError(retryAfter)
 	}
 	
 	err := fn()
@@ -116,30 +173,32 @@ func (cb *CircuitBreaker) Execute(ctx context.Context, fn func() error) error {
 	return err
 }
 
-// allowRequest checks if request should be allowed based on circuit state
-func (cb *CircuitBreaker) allowRequest() bool {
+// allowRequest checks if request should be allowed based on circuit state.
+// When rejected, it also returns the time until the next half-open attempt.
+func (cb *CircuitBreaker) allowRequest() (bool, time.Duration) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 	
-	now := time.Now()
+	now := cb.clock.Now()
 	
 	switch cb.state {
 	case CircuitStateClosed:
-		return true
+		return true, 0
 		
 	case CircuitStateOpen:
-		if now.Sub(cb.lastFailTime) > cb.config.ResetTimeout {
+		elapsed := now.Sub(cb.lastFailTime)
+		if elapsed > cb.config.ResetTimeout {
 			cb.state = CircuitStateHalfOpen
 			cb.requests = 0
-			return true
+			return true, 0
 		}
-		return false
+		return false, cb.config.ResetTimeout - elapsed
 		
 	case CircuitStateHalfOpen:
-		return cb.requests < cb.config.HalfOpenMaxRequests
+		return cb.requests < cb.config.HalfOpenMaxRequests, 0
 		
 	default:
-		return false
+		return false, 0
 	}
 }
 
@@ -154,7 +213,7 @@ func (cb *CircuitBreaker) recordResult(err error) {
 	
 	if err != nil {
 		cb.failures++
-		cb.lastFailTime = time.Now()
+		cb.lastFailTime = cb.clock.Now()
 		
 		if cb.state == CircuitStateHalfOpen {
 			cb.state = CircuitStateOpen
@@ -169,6 +228,30 @@ func (cb *CircuitBreaker) recordResult(err error) {
 	}
 }
 
+// CircuitSnapshot is the persisted state of a circuit breaker
+type CircuitSnapshot struct {
+	State        CircuitState `json:"state"`
+	Failures     int          `json:"failures"`
+	LastFailTime time.Time    `json:"last_fail_time"`
+}
+
+// Snapshot returns the circuit breaker state for persistence
+func (cb *CircuitBreaker) Snapshot() CircuitSnapshot {
+	cb.mutex.RLock()
+	defer cb.mutex.RUnlock()
+	return CircuitSnapshot{State: cb.state, Failures: cb.failures, LastFailTime: cb.lastFailTime}
+}
+
+// Restore replaces the circuit breaker state with a snapshot
+func (cb *CircuitBreaker) Restore(snapshot CircuitSnapshot) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.state = snapshot.State
+	cb.failures = snapshot.Failures
+	cb.lastFailTime = snapshot.LastFailTime
+	cb.requests = 0
+}
+
 // GetState returns the current circuit breaker state
 func (cb *CircuitBreaker) GetState() CircuitState {
 	cb.mutex.RLock()
@@ -180,6 +263,8 @@ func (cb *CircuitBreaker) GetState() CircuitState {
 type RetryExecutor struct {
 	config         *RetryConfig
 	circuitBreaker *CircuitBreaker
+	clock          Clock
+	random         Rand
 }
 
 // NewRetryExecutor creates a new retry executor
@@ -196,11 +281,29 @@ func NewRetryExecutor(retryConfig *RetryConfig, cbConfig *CircuitBreakerConfig)
 	return &RetryExecutor{
 		config:         retryConfig,
 		circuitBreaker: cb,
+		clock:          clockOrSystem(retryConfig.Clock),
+		random:         randOrGlobal(retryConfig.Rand),
 	}
 }
 
 // Execute runs the function with retry and circuit breaker logic
 func (re *RetryExecutor) Execute(ctx context.Context, operation func() error) error {
+	return re.ExecuteWithAttemptContext(ctx, func(context.Context) error {
+		return operation()
+	})
+}
+
+// ExecuteWithAttemptContext runs the function with retry and circuit breaker
+// logic, passing each attempt a context bounded by PerAttemptTimeout
+func (re *RetryExecutor) ExecuteWithAttemptContext(ctx context.Context, operation func(ctx context.Context) error) error {
+	if re.circuitBreaker != nil && re.circuitBreaker.config.PerAttempt {
+		return re.executeWithRetry(ctx, func(attemptCtx context.Context) error {
+			return re.circuitBreaker.Execute(attemptCtx, func() error {
+				return operation(attemptCtx)
+			})
+		})
+	}
+	
 	if re.circuitBreaker != nil {
 		return re.circuitBreaker.Execute(ctx, func() error {
 			return re.executeWithRetry(ctx, operation)
@@ -211,8 +314,9 @@ func (re *RetryExecutor) Execute(ctx context.Context, operation func() error) er
 }
 
 // executeWithRetry implements the retry logic with exponential backoff
-func (re *RetryExecutor) executeWithRetry(ctx context.Context, operation func() error) error {
+func (re *RetryExecutor) executeWithRetry(ctx context.Context, operation func(ctx context.Context) error) error {
 	var lastErr error
+	var delay time.Duration
 	
 	for attempt := 0; attempt <= re.config.MaxRetries; attempt++ {
 		// Check context cancellation
@@ -223,7 +327,7 @@ func (re *RetryExecutor) executeWithRetry(ctx context.Context, operation func()
 		}
 		
 		// Execute the operation
-		err := operation()
+		err := re.attempt(ctx, operation)
 		if err == nil {
 			if attempt > 0 {
 				logInfo(ctx, "Operation succeeded after retry", map[string]interface{}{
@@ -254,7 +358,25 @@ func (re *RetryExecutor) executeWithRetry(ctx context.Context, operation func()
 		}
 		
 		// Calculate delay for next attempt
-		delay := re.calculateDelay(attempt)
+		var ok bool
+		delay, ok = re.backoff(attempt, delay, err)
+		if !ok {
+			logDebug(ctx, "Backoff strategy stopped retrying", map[string]interface{}{
+				"attempt": attempt + 1,
+				"error": err.Error(),
+			})
+			break
+		}
+		
+		// Give up early rather than sleeping into the deadline
+		delay, ok = re.fitDeadline(ctx, delay)
+		if !ok {
+			logWarn(ctx, "Not enough time left before deadline, giving up", map[string]interface{}{
+				"attempt": attempt + 1,
+				"error": err.Error(),
+			})
+			return newDeadlineError(lastErr)
+		}
 		
 		logWarn(ctx, "Operation failed, retrying", map[string]interface{}{
 			"attempt": attempt + 1,
@@ -266,13 +388,24 @@ func (re *RetryExecutor) executeWithRetry(ctx context.Context, operation func()
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(delay):
+		case <-re.clock.After(delay):
 		}
 	}
 	
 	return lastErr
 }
 
+// attempt runs a single attempt of operation, bounded by PerAttemptTimeout
+func (re *RetryExecutor) attempt(ctx context.Context, operation func(ctx context.Context) error) error {
+	if re.config.PerAttemptTimeout <= 0 {
+		return operation(ctx)
+	}
+	
+	attemptCtx, cancel := context.WithTimeout(ctx, re.config.PerAttemptTimeout)
+	defer cancel()
+	return operation(attemptCtx)
+}
+
 // isRetryable checks if an error should trigger a retry
 func (re *RetryExecutor) isRetryable(err error) bool {
 	var probeErr *ProbeError
@@ -289,13 +422,80 @@ func (re *RetryExecutor) isRetryable(err error) bool {
 	return false
 }
 
-// calculateDelay computes the delay for the next retry attempt
+// backoff computes the delay for the next retry attempt using the configured
+// strategy. It returns false when the strategy stops retrying.
+func (re *RetryExecutor) backoff(attempt int, previous time.Duration, lastErr error) (time.Duration, bool) {
+	if re.config.Backoff != nil {
+		return re.config.Backoff.NextDelay(attempt, lastErr)
+	}
+	return re.nextDelay(attempt, previous), true
+}
+
+// fitDeadline shortens delay to fit the context deadline. It returns false
+// when no useful attempt fits before the deadline.
+func (re *RetryExecutor) fitDeadline(ctx context.Context, delay time.Duration) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return delay, true
+	}
+	
+	// Deadlines are set in real time, so they are not measured with re.clock
+	remaining := time.Until(deadline)
+	if remaining <= re.config.MinAttemptDuration {
+		return 0, false
+	}
+	
+	budget := remaining - re.config.MinAttemptDuration
+	if delay >= budget {
+		if re.config.MinAttemptDuration == 0 {
+			return 0, false
+		}
+		delay = budget
+	}
+	
+	return delay, true
+}
+
+// jitterStrategy returns the effective jitter strategy
+func (re *RetryExecutor) jitterStrategy() JitterStrategy {
+	if re.config.JitterStrategy != "" {
+		return re.config.JitterStrategy
+	}
+	if re.config.Jitter {
+		return JitterProportional
+	}
+	return JitterNone
+}
+
+// nextDelay computes the delay for the next retry attempt given the previous delay
+func (re *RetryExecutor) nextDelay(attempt int, previous time.Duration) time.Duration {
+	if re.jitterStrategy() != JitterDecorrelated {
+		return re.calculateDelay(attempt)
+	}
+	
+	// sleep = min(cap, random_between(base, previous * 3))
+	base := float64(re.config.InitialDelay)
+	upper := float64(previous) * 3
+	if upper < base {
+		upper = base
+	}
+	delay := base + re.random.Float64()*(upper-base)
+	
+	maxDelay := float64(re.config.MaxDelay)
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	
+	return time.Duration(delay)
+}
+
+// calculateDelay computes the exponential delay for the next retry attempt
 func (re *RetryExecutor) calculateDelay(attempt int) time.Duration {
 	delay := float64(re.config.InitialDelay) * math.Pow(re.config.BackoffMultiplier, float64(attempt))
 	
-	if re.config.Jitter {
+	if re.jitterStrategy() == JitterProportional {
 		// Add 25% jitter
-		jitter := delay * 0.25 * rand.Float64()
+		jitter := delay * 0.25 * re.random.Float64()
 		delay += jitter
 	}
 	
@@ -304,5 +504,9 @@ func (re *RetryExecutor) calculateDelay(attempt int) time.Duration {
 		delay = maxDelay
 	}
 	
+	if re.jitterStrategy() == JitterFull {
+		delay = re.random.Float64() * delay
+	}
+	
 	return time.Duration(delay)
-}
\ No newline at end of file
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -241,7 +242,7 @@ func TestRetryExecutorContextCancellation(t *testing.T) {
 		t.Error("Expected context cancellation error")
 	}
 	
-	if err != context.DeadlineExceeded {
+	if !errors.Is(err, context.DeadlineExceeded) {
 		t.Errorf("Expected context deadline exceeded, got: %v", err)
 	}
 	
@@ -293,14 +294,25 @@ func TestRetryExecutorWithCircuitBreaker(t *testing.T) {
 		t.Error("Expected circuit breaker to reject request")
 	}
 	
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen from circuit breaker, got: %v", err)
+	}
+	
 	var probeErr *ProbeError
 	if errors.As(err, &probeErr) {
-		if probeErr.Type != ErrorTypeNetwork || probeErr.Message != "circuit breaker is open" {
-			t.Errorf("Expected circuit breaker error, got: %v", err)
+		if probeErr.Type != ErrorTypeCircuitOpen {
+			t.Errorf("Expected error type %v, got %v", ErrorTypeCircuitOpen, probeErr.Type)
+		}
+		if probeErr.RetryAfter <= 0 || probeErr.RetryAfter > cbConfig.ResetTimeout {
+			t.Errorf("Expected RetryAfter within reset timeout, got %v", probeErr.RetryAfter)
 		}
 	} else {
 		t.Errorf("Expected ProbeError from circuit breaker, got: %T", err)
 	}
+	
+	if executor.isRetryable(err) {
+		t.Error("Expected circuit open error not to be retryable")
+	}
 }
 
 func TestCalculateDelay(t *testing.T) {
@@ -370,4 +382,232 @@ func TestCalculateDelayWithJitter(t *testing.T) {
 	if allSame {
 		t.Error("Expected jitter to create different delays, but all were identical")
 	}
-}
\ No newline at end of file
+}
+func TestRetryExecutorPerAttemptCircuitBreaker(t *testing.T) {
+	retryConfig := &RetryConfig{
+		MaxRetries:        5,
+		InitialDelay:      1 * time.Millisecond,
+		MaxDelay:          10 * time.Millisecond,
+		BackoffMultiplier: 2.0,
+		RetryableErrors:   []ErrorType{ErrorTypeNetwork},
+	}
+	
+	cbConfig := &CircuitBreakerConfig{
+		Enabled:             true,
+		FailureThreshold:    3,
+		ResetTimeout:        time.Minute,
+		HalfOpenMaxRequests: 1,
+		PerAttempt:          true,
+	}
+	
+	executor := NewRetryExecutor(retryConfig, cbConfig)
+	networkErr := NewNetworkError("http://test.com", errors.New("persistent failure"))
+	
+	attempts := 0
+	err := executor.Execute(context.Background(), func() error {
+		attempts++
+		return networkErr
+	})
+	
+	if attempts != cbConfig.FailureThreshold {
+		t.Errorf("Expected %d attempts before the circuit opened, got %d", cbConfig.FailureThreshold, attempts)
+	}
+	
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got: %v", err)
+	}
+	
+	if executor.circuitBreaker.GetState() != CircuitStateOpen {
+		t.Error("Expected circuit to be open")
+	}
+}
+
+func TestRetryDeadlineAware(t *testing.T) {
+	tests := []struct {
+		name             string
+		minAttempt       time.Duration
+		timeout          time.Duration
+		expectedAttempts int
+	}{
+		{
+			name:             "delay past deadline skips retry",
+			timeout:          50 * time.Millisecond,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "delay shortened to leave minimum attempt time",
+			minAttempt:       50 * time.Millisecond,
+			timeout:          200 * time.Millisecond,
+			expectedAttempts: 2,
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewRetryExecutor(&RetryConfig{
+				MaxRetries:         3,
+				InitialDelay:       time.Second,
+				MaxDelay:           time.Second,
+				BackoffMultiplier:  2.0,
+				RetryableErrors:    []ErrorType{ErrorTypeNetwork},
+				MinAttemptDuration: tt.minAttempt,
+			}, nil)
+			
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			
+			attempts := 0
+			start := time.Now()
+			err := executor.Execute(ctx, func() error {
+				attempts++
+				return NewNetworkError("http://test.com", errors.New("persistent failure"))
+			})
+			
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected context deadline exceeded, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), "persistent failure") {
+				t.Errorf("Expected the last error in %q", err)
+			}
+			var probeErr *ProbeError
+			if !errors.As(err, &probeErr) || probeErr.Type != ErrorTypeTimeout || probeErr.URL != "http://test.com" {
+				t.Errorf("Expected a timeout ProbeError for the URL, got %#v", err)
+			}
+			
+			if attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+			
+			if elapsed := time.Since(start); elapsed >= tt.timeout {
+				t.Errorf("Expected to give up before the deadline, took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestJitterStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy JitterStrategy
+		attempt  int
+		previous time.Duration
+		min      time.Duration
+		max      time.Duration
+	}{
+		{"none", JitterNone, 1, 0, 200 * time.Millisecond, 200 * time.Millisecond},
+		{"proportional", JitterProportional, 1, 0, 200 * time.Millisecond, 250 * time.Millisecond},
+		{"full", JitterFull, 1, 0, 0, 200 * time.Millisecond},
+		{"full capped", JitterFull, 10, 0, 0, 1 * time.Second},
+		{"decorrelated first retry", JitterDecorrelated, 0, 0, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"decorrelated", JitterDecorrelated, 3, 200 * time.Millisecond, 100 * time.Millisecond, 600 * time.Millisecond},
+		{"decorrelated capped", JitterDecorrelated, 3, 800 * time.Millisecond, 100 * time.Millisecond, 1 * time.Second},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewRetryExecutor(&RetryConfig{
+				InitialDelay:      100 * time.Millisecond,
+				MaxDelay:          1 * time.Second,
+				BackoffMultiplier: 2.0,
+				JitterStrategy:    tt.strategy,
+			}, nil)
+			
+			for i := 0; i < 20; i++ {
+				delay := executor.nextDelay(tt.attempt, tt.previous)
+				if delay < tt.min || delay > tt.max {
+					t.Errorf("Expected delay between %v and %v, got %v", tt.min, tt.max, delay)
+				}
+			}
+		})
+	}
+}
+
+// fibonacciBackoff is a custom strategy that stops after a given number of retries
+type fibonacciBackoff struct {
+	unit  time.Duration
+	limit int
+}
+
+func (b fibonacciBackoff) NextDelay(attempt int, lastErr error) (time.Duration, bool) {
+	if attempt >= b.limit {
+		return 0, false
+	}
+	a, c := 1, 1
+	for i := 0; i < attempt; i++ {
+		a, c = c, a+c
+	}
+	return time.Duration(a) * b.unit, true
+}
+
+func TestRetryExecutorCustomBackoff(t *testing.T) {
+	executor := NewRetryExecutor(&RetryConfig{
+		MaxRetries:      5,
+		RetryableErrors: []ErrorType{ErrorTypeNetwork},
+		Backoff:         fibonacciBackoff{unit: time.Millisecond, limit: 2},
+	}, nil)
+	
+	networkErr := NewNetworkError("http://test.com", errors.New("persistent failure"))
+	
+	attempts := 0
+	err := executor.Execute(context.Background(), func() error {
+		attempts++
+		return networkErr
+	})
+	
+	if err != networkErr {
+		t.Errorf("Expected last attempt error, got: %v", err)
+	}
+	
+	// 1 initial attempt + 2 retries allowed by the strategy
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	
+	delay, ok := executor.backoff(4, 0, networkErr)
+	if ok {
+		t.Errorf("Expected strategy to stop retrying, got delay %v", delay)
+	}
+}
+
+func TestRetryExecutorPerAttemptTimeout(t *testing.T) {
+	executor := NewRetryExecutor(&RetryConfig{
+		MaxRetries:        2,
+		InitialDelay:      1 * time.Millisecond,
+		MaxDelay:          10 * time.Millisecond,
+		BackoffMultiplier: 2.0,
+		RetryableErrors:   []ErrorType{ErrorTypeTimeout},
+		PerAttemptTimeout: 20 * time.Millisecond,
+	}, nil)
+	
+	attempts := 0
+	err := executor.ExecuteWithAttemptContext(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			// Simulate a stalled connection
+			<-ctx.Done()
+			return NewTimeoutError("http://test.com", 0)
+		}
+		return nil
+	})
+	
+	if err != nil {
+		t.Errorf("Expected success after abandoning the stalled attempt, got: %v", err)
+	}
+	
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestDeadlineErrorRedaction(t *testing.T) {
+	lastErr := NewNetworkError("https://cdn.example.com/master.m3u8?token=secret", errors.New("connection reset"))
+	err := newRedactor(nil).redactError(newDeadlineError(lastErr))
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v to match context.DeadlineExceeded", err)
+	}
+	data := string(ErrorJSON(err))
+	if !strings.Contains(data, `"type":"timeout"`) || strings.Contains(data, "secret") {
+		t.Errorf("Expected a redacted timeout error, got %s", data)
+	}
+}
@@ -0,0 +1,301 @@
+package probe
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RuleFinding is the outcome of one compliance rule
+type RuleFinding struct {
+	// RuleID identifies the rule, prefixed with its pack (e.g. "apple-hls/iframe-playlists")
+	RuleID      string   `json:"rule_id"`
+	Description string   `json:"description"`
+	Severity    Severity `json:"severity"`
+	Passed      bool     `json:"passed"`
+	Message     string   `json:"message,omitempty"`
+
+	// StreamIDs are the streams violating the rule
+	StreamIDs []string `json:"stream_ids,omitempty"`
+
+	// Locations are the manifest elements violating the rule, or the
+	// manifest itself for rules about the whole manifest
+	Locations []FindingLocation `json:"locations,omitempty"`
+}
+
+// Rule is a compliance check over a probed manifest. Rules are grouped in
+// named packs (see RegisterRulePack) and selected with ProbeOptions.RulePacks.
+type Rule struct {
+	// ID identifies the rule in findings, by convention prefixed with its
+	// pack name (e.g. "acme/drm-required")
+	ID          string
+	Description string
+
+	// Severity of a failure (zero = SeverityError)
+	Severity Severity
+
+	// Format restricts the rule to "HLS" or "MPD" manifests ("" = any)
+	Format string
+
+	// MediaPlaylists makes the HLS variant media playlists available in
+	// RuleInput.MediaPlaylists. They are fetched once per probe for all rules.
+	MediaPlaylists bool
+
+	// Check evaluates the rule. It must not modify the input.
+	Check func(in *RuleInput) RuleOutcome
+}
+
+// severity returns the severity of a failure of the rule
+func (r Rule) severity() Severity {
+	if r.Severity == "" {
+		return SeverityError
+	}
+	return r.Severity
+}
+
+// RuleOutcome is the result of a rule check. A rule passes when it reports
+// no failure message.
+type RuleOutcome struct {
+	// NotApplicable leaves the rule out of the findings, e.g. a master
+	// playlist rule on a media playlist
+	NotApplicable bool
+
+	// Message describes the failure ("" = passed)
+	Message string
+
+	// StreamIDs are the streams violating the rule
+	StreamIDs []string
+}
+
+// RuleInput is the model rules are evaluated against
+type RuleInput struct {
+	// Output is the probe output, with the findings of earlier analyses
+	Output *Output
+
+	// Manifest is the raw manifest body and URL its effective URL
+	Manifest string
+	URL      string
+
+	// Format is "HLS" or "MPD"
+	Format string
+
+	// MediaPlaylists maps the stream ID of each HLS variant to its media
+	// playlist (only when a selected rule sets MediaPlaylists; failed
+	// fetches are missing)
+	MediaPlaylists map[string]string
+
+	streamInfOnce sync.Once
+	streamInf     []HLSStreamInf
+
+	mpdOnce sync.Once
+	mpd     *MPD
+}
+
+// HLSStreamInf is an EXT-X-STREAM-INF tag of a master playlist
+type HLSStreamInf struct {
+	// Attributes are the tag's attributes, with quotes stripped
+	Attributes map[string]string
+
+	// StreamIDs are the streams reported for the variant
+	StreamIDs []string
+}
+
+// HLSStreamInfs returns the EXT-X-STREAM-INF tags of an HLS master playlist,
+// with the stream IDs of their variants
+func (in *RuleInput) HLSStreamInfs() []HLSStreamInf {
+	in.streamInfOnce.Do(func() {
+		for _, line := range strings.Split(in.Manifest, "\n") {
+			if strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+				in.streamInf = append(in.streamInf, HLSStreamInf{Attributes: parseHLSAttributes(line)})
+			}
+		}
+		// Variants are reported in tag order, for tags followed by a URI
+		if len(in.Output.Variants) == len(in.streamInf) {
+			for i, variant := range in.Output.Variants {
+				in.streamInf[i].StreamIDs = variant.StreamIDs
+			}
+		}
+	})
+	return in.streamInf
+}
+
+// MPD returns the parsed MPD (nil for HLS manifests)
+func (in *RuleInput) MPD() *MPD {
+	in.mpdOnce.Do(func() {
+		if in.Format != "MPD" {
+			return
+		}
+		var mpd MPD
+		if err := xml.Unmarshal([]byte(in.Manifest), &mpd); err == nil {
+			in.mpd = &mpd
+		}
+	})
+	return in.mpd
+}
+
+// MPDStreamIDs returns the IDs of the streams derived from the representations
+// of an adaptation set, by index in the MPD (all representations when
+// representation is -1)
+func (in *RuleInput) MPDStreamIDs(period, adaptationSet, representation int) []string {
+	var ids []string
+	for _, stream := range in.Output.Streams {
+		element := stream.element
+		if stream.Source == nil || stream.Source.Tag != "Representation" || element.period != period || element.adaptationSet != adaptationSet {
+			continue
+		}
+		if representation < 0 || element.representation == representation {
+			ids = append(ids, stream.StreamID)
+		}
+	}
+	return ids
+}
+
+// IsHLSMaster reports whether the manifest is an HLS master playlist
+func (in *RuleInput) IsHLSMaster() bool {
+	return in.Format == "HLS" && strings.Contains(in.Manifest, "#EXT-X-STREAM-INF:")
+}
+
+var (
+	rulePacksMu sync.RWMutex
+
+	// rulePacks are the registered rule packs by name
+	rulePacks = map[string][]Rule{
+		RulePackAppleHLS: appleHLSRules,
+		RulePackDASHIF:   dashIFRules,
+	}
+)
+
+// RegisterRulePack registers rules under name so probes can select them with
+// ProbeOptions.RulePacks (CLI -rules). Registering an existing name replaces
+// its rules, except for the built-in packs. Typically called from init.
+func RegisterRulePack(name string, rules []Rule) error {
+	if name == "" {
+		return NewValidationError("rule pack name cannot be empty")
+	}
+	if name == RulePackAppleHLS || name == RulePackDASHIF {
+		return NewValidationError(fmt.Sprintf("rule pack %s is built in", name))
+	}
+	for _, r := range rules {
+		if r.ID == "" || r.Check == nil {
+			return NewValidationError(fmt.Sprintf("rule pack %s: rules need an ID and a Check", name))
+		}
+		if r.Format != "" && r.Format != "HLS" && r.Format != "MPD" {
+			return NewValidationError(fmt.Sprintf("rule %s: format must be HLS or MPD", r.ID))
+		}
+		switch r.Severity {
+		case "", SeverityError, SeverityWarning, SeverityNote:
+		default:
+			return NewValidationError(fmt.Sprintf("rule %s: unknown severity %q", r.ID, r.Severity))
+		}
+	}
+
+	rulePacksMu.Lock()
+	defer rulePacksMu.Unlock()
+	rulePacks[name] = append([]Rule(nil), rules...)
+	return nil
+}
+
+// RulePack returns the rules of a registered pack, e.g. to extend a built-in
+// pack in a custom one
+func RulePack(name string) ([]Rule, bool) {
+	rulePacksMu.RLock()
+	defer rulePacksMu.RUnlock()
+	rules, ok := rulePacks[name]
+	return append([]Rule(nil), rules...), ok
+}
+
+// RulePacks returns the names of the registered rule packs, sorted
+func RulePacks() []string {
+	rulePacksMu.RLock()
+	defer rulePacksMu.RUnlock()
+	names := make([]string, 0, len(rulePacks))
+	for name := range rulePacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runRules evaluates the rule packs selected in the probe options and adds
+// their findings to output
+func (p *Prober) runRules(ctx context.Context, packs []string, fetched *fetchResult, output *Output) {
+	in := &RuleInput{
+		Output:   output,
+		Manifest: fetched.body,
+		URL:      fetched.url,
+		Format:   manifestFormat(fetched.body),
+	}
+
+	var selected []Rule
+	for _, name := range packs {
+		rules, _ := RulePack(name)
+		for _, r := range rules {
+			if r.Format != "" && r.Format != in.Format {
+				continue
+			}
+			selected = append(selected, r)
+			if r.MediaPlaylists && in.MediaPlaylists == nil {
+				in.MediaPlaylists = p.fetchMediaPlaylists(ctx, output)
+			}
+		}
+	}
+
+	for _, r := range selected {
+		outcome := runRule(ctx, r, in)
+		if outcome.NotApplicable {
+			continue
+		}
+		finding := RuleFinding{
+			RuleID:      r.ID,
+			Description: r.Description,
+			Severity:    r.severity(),
+			Passed:      outcome.Message == "",
+			Message:     outcome.Message,
+			StreamIDs:   outcome.StreamIDs,
+		}
+		if finding.Passed {
+			finding.StreamIDs = nil
+		}
+		output.Rules = append(output.Rules, finding)
+	}
+}
+
+// runRule runs a rule check, reporting a panicking check as a failure
+func runRule(ctx context.Context, r Rule, in *RuleInput) (outcome RuleOutcome) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logError(ctx, "Rule check panicked", map[string]interface{}{
+				"rule":  r.ID,
+				"panic": fmt.Sprint(recovered),
+			})
+			outcome = RuleOutcome{Message: fmt.Sprintf("rule check panicked: %v", recovered)}
+		}
+	}()
+	return r.Check(in)
+}
+
+// fetchMediaPlaylists returns the variant media playlists of output keyed
+// by stream ID (see fetchVariantPlaylists)
+func (p *Prober) fetchMediaPlaylists(ctx context.Context, output *Output) map[string]string {
+	playlists := make(map[string]string)
+	for i, fetch := range p.fetchVariantPlaylists(ctx, output) {
+		if fetch.err == nil {
+			playlists[output.variants[i].streamID] = fetch.fetched.body
+		}
+	}
+	return playlists
+}
+
+// FailedRules returns the findings of rules that did not pass
+func FailedRules(findings []RuleFinding) []RuleFinding {
+	var failed []RuleFinding
+	for _, finding := range findings {
+		if !finding.Passed {
+			failed = append(failed, finding)
+		}
+	}
+	return failed
+}
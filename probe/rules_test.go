@@ -0,0 +1,107 @@
+package probe
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterRulePack(t *testing.T) {
+	err := RegisterRulePack("test-policy", []Rule{
+		{
+			ID:          "test-policy/max-video-streams",
+			Description: "At most 2 video streams",
+			Check: func(in *RuleInput) RuleOutcome {
+				var ids []string
+				for _, stream := range in.Output.Streams {
+					if stream.Type == "Video" {
+						ids = append(ids, stream.StreamID)
+					}
+				}
+				if len(ids) > 2 {
+					return RuleOutcome{Message: "too many video streams", StreamIDs: ids}
+				}
+				return RuleOutcome{}
+			},
+		},
+		{
+			ID:     "test-policy/hls-only",
+			Format: "HLS",
+			Check:  func(in *RuleInput) RuleOutcome { return RuleOutcome{Message: "should not run on MPD"} },
+		},
+		{
+			ID: "test-policy/not-applicable",
+			Check: func(in *RuleInput) RuleOutcome {
+				return RuleOutcome{NotApplicable: in.MPD() != nil}
+			},
+		},
+		{
+			ID:    "test-policy/panics",
+			Check: func(in *RuleInput) RuleOutcome { panic("boom") },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer func() {
+		rulePacksMu.Lock()
+		delete(rulePacks, "test-policy")
+		rulePacksMu.Unlock()
+	}()
+
+	findings := probeRules(t, dashIFTestNonCompliant, "test-policy")
+
+	expected := []RuleFinding{
+		{RuleID: "test-policy/max-video-streams", Description: "At most 2 video streams", Message: "too many video streams", StreamIDs: []string{"0:0", "0:1", "0:2"}},
+		{RuleID: "test-policy/panics", Message: "rule check panicked: boom"},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %+v", len(expected), findings)
+	}
+	for i, want := range expected {
+		got := findings[i]
+		if got.RuleID != want.RuleID || got.Description != want.Description || got.Message != want.Message || strings.Join(got.StreamIDs, ",") != strings.Join(want.StreamIDs, ",") {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	}
+}
+
+func TestRegisterRulePackValidation(t *testing.T) {
+	check := func(in *RuleInput) RuleOutcome { return RuleOutcome{} }
+
+	tests := []struct {
+		name  string
+		pack  string
+		rules []Rule
+	}{
+		{"empty name", "", nil},
+		{"built-in pack", RulePackAppleHLS, nil},
+		{"missing ID", "custom", []Rule{{Check: check}}},
+		{"missing check", "custom", []Rule{{ID: "custom/a"}}},
+		{"invalid format", "custom", []Rule{{ID: "custom/a", Format: "smooth", Check: check}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RegisterRulePack(tt.pack, tt.rules); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if _, ok := RulePack("custom"); ok {
+		t.Error("Expected invalid packs not to be registered")
+	}
+}
+
+func TestRulePackExtendsBuiltIn(t *testing.T) {
+	rules, ok := RulePack(RulePackDASHIF)
+	if !ok || len(rules) != len(dashIFRules) {
+		t.Fatalf("Expected the %s rules, got %d", RulePackDASHIF, len(rules))
+	}
+
+	// The returned slice is a copy
+	rules[0].ID = "changed"
+	if dashIFRules[0].ID == "changed" {
+		t.Error("Expected RulePack to return a copy")
+	}
+}
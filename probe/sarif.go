@@ -0,0 +1,205 @@
+package probe
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// SARIFWarningRule is the SARIF rule ID of probe warnings (Output.Warnings)
+	SARIFWarningRule = "goprobe/warning"
+
+	// sarifCompatibilityPrefix prefixes the issue of compatibility findings
+	// to form their SARIF rule ID
+	sarifCompatibilityPrefix = "goprobe/compatibility/"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level Severity `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     Severity        `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// ExportSARIF renders the findings of probe outputs as a SARIF 2.1.0 log, so
+// CI systems and code review tools can display them like static analysis
+// results. Failed rules, compatibility findings and warnings become results;
+// passed rules are listed as rules only.
+func ExportSARIF(outputs ...*Output) ([]byte, error) {
+	version := BuildInfo()
+	driver := sarifDriver{
+		Name:           "goprobe",
+		Version:        version.Version,
+		InformationURI: "https://github.com/erratbi/goprobe",
+	}
+
+	rules := make(map[string]sarifRule)
+	addRule := func(id, description string, level Severity) {
+		if _, ok := rules[id]; !ok {
+			rules[id] = sarifRule{
+				ID:                   id,
+				ShortDescription:     sarifMessage{Text: description},
+				DefaultConfiguration: sarifConfiguration{Level: level},
+			}
+		}
+	}
+
+	results := []sarifResult{}
+	for _, output := range outputs {
+		if output == nil {
+			continue
+		}
+
+		for _, finding := range output.Rules {
+			severity := finding.Severity
+			if severity == "" {
+				severity = SeverityError
+			}
+			addRule(finding.RuleID, finding.Description, severity)
+			if finding.Passed {
+				continue
+			}
+			message := finding.Description
+			if finding.Message != "" {
+				message += ": " + finding.Message
+			}
+			results = append(results, sarifResult{
+				RuleID:    finding.RuleID,
+				Level:     severity,
+				Message:   sarifMessage{Text: message},
+				Locations: sarifLocations(finding.Locations),
+			})
+		}
+
+		for _, finding := range output.Compatibility {
+			severity := finding.Severity
+			if severity == "" {
+				severity = SeverityWarning
+			}
+			id := sarifCompatibilityPrefix + finding.Issue
+			addRule(id, "Streams a player switches between use one codec family", severity)
+			results = append(results, sarifResult{
+				RuleID:    id,
+				Level:     severity,
+				Message:   sarifMessage{Text: finding.Message},
+				Locations: sarifLocations(finding.Locations),
+			})
+		}
+
+		for _, warning := range output.Warnings {
+			addRule(SARIFWarningRule, "Problems found while probing the manifest", SeverityWarning)
+			var locations []FindingLocation
+			if output.url != "" {
+				locations = []FindingLocation{{URL: output.url}}
+			}
+			results = append(results, sarifResult{
+				RuleID:    SARIFWarningRule,
+				Level:     SeverityWarning,
+				Message:   sarifMessage{Text: warning},
+				Locations: sarifLocations(locations),
+			})
+		}
+	}
+
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, rule)
+	}
+	sort.Slice(driver.Rules, func(i, j int) bool {
+		return driver.Rules[i].ID < driver.Rules[j].ID
+	})
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
+
+// sarifLocations converts finding locations to SARIF locations
+func sarifLocations(locations []FindingLocation) []sarifLocation {
+	var converted []sarifLocation
+	for _, location := range locations {
+		var result sarifLocation
+		if location.URL != "" {
+			result.PhysicalLocation = &sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: location.URL},
+			}
+			if location.Line > 0 {
+				result.PhysicalLocation.Region = &sarifRegion{StartLine: location.Line}
+			}
+		}
+		if location.Element != "" {
+			result.LogicalLocations = []sarifLogicalLocation{{
+				FullyQualifiedName: location.Element,
+				Kind:               "element",
+			}}
+		}
+		if result.PhysicalLocation != nil || result.LogicalLocations != nil {
+			converted = append(converted, result)
+		}
+	}
+	return converted
+}
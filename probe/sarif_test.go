@@ -0,0 +1,130 @@
+package probe
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExportSARIF(t *testing.T) {
+	output := &Output{
+		url:      "https://example.com/manifest.mpd",
+		Warnings: []string{"Content-Type text/plain is not valid for MPD"},
+		Rules: []RuleFinding{
+			{RuleID: "dashif-iop/profiles", Description: "The MPD declares its profiles", Severity: SeverityError, Passed: true},
+			{
+				RuleID:      "dashif-iop/audio-channel-configuration",
+				Description: "Every audio representation has an AudioChannelConfiguration",
+				Severity:    SeverityWarning,
+				Message:     "1 of 2 audio representations have no AudioChannelConfiguration",
+				Locations: []FindingLocation{{
+					URL:     "https://example.com/manifest.mpd",
+					Line:    10,
+					Element: "Period[p0]/AdaptationSet[2]/Representation[a1]",
+				}},
+			},
+		},
+		Compatibility: []CompatibilityFinding{{
+			Issue:     CompatMixedCodecSwitchingSet,
+			Message:   "switching set of adaptation sets 1 in period p0 mixes h264 and hevc",
+			Severity:  SeverityWarning,
+			Locations: []FindingLocation{{URL: "https://example.com/manifest.mpd", Line: 5}},
+		}},
+	}
+
+	data, err := ExportSARIF(output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if log.Version != "2.1.0" {
+		t.Errorf("Expected %q, got %q", "2.1.0", log.Version)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("Expected 1 run, got %d", len(log.Runs))
+	}
+	run := log.Runs[0]
+
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	expectedRuleIDs := []string{
+		"dashif-iop/audio-channel-configuration",
+		"dashif-iop/profiles",
+		"goprobe/compatibility/mixed_codec_switching_set",
+		"goprobe/warning",
+	}
+	if len(ruleIDs) != len(expectedRuleIDs) {
+		t.Fatalf("Expected rules %v, got %v", expectedRuleIDs, ruleIDs)
+	}
+	for i := range ruleIDs {
+		if ruleIDs[i] != expectedRuleIDs[i] {
+			t.Errorf("Expected %q, got %q", expectedRuleIDs[i], ruleIDs[i])
+		}
+	}
+
+	tests := []struct {
+		ruleID  string
+		level   Severity
+		line    int
+		logical string
+	}{
+		{"dashif-iop/audio-channel-configuration", SeverityWarning, 10, "Period[p0]/AdaptationSet[2]/Representation[a1]"},
+		{"goprobe/compatibility/mixed_codec_switching_set", SeverityWarning, 5, ""},
+		{"goprobe/warning", SeverityWarning, 0, ""},
+	}
+	if len(run.Results) != len(tests) {
+		t.Fatalf("Expected %d results, got %d", len(tests), len(run.Results))
+	}
+
+	for i, tt := range tests {
+		result := run.Results[i]
+		if result.RuleID != tt.ruleID {
+			t.Errorf("Expected %q, got %q", tt.ruleID, result.RuleID)
+		}
+		if result.Level != tt.level {
+			t.Errorf("Expected %q, got %q", tt.level, result.Level)
+		}
+		if len(result.Locations) != 1 || result.Locations[0].PhysicalLocation == nil {
+			t.Errorf("Expected one physical location for %s, got %+v", tt.ruleID, result.Locations)
+			continue
+		}
+		location := result.Locations[0]
+		if location.PhysicalLocation.ArtifactLocation.URI != "https://example.com/manifest.mpd" {
+			t.Errorf("Expected %q, got %q", "https://example.com/manifest.mpd", location.PhysicalLocation.ArtifactLocation.URI)
+		}
+		line := 0
+		if location.PhysicalLocation.Region != nil {
+			line = location.PhysicalLocation.Region.StartLine
+		}
+		if line != tt.line {
+			t.Errorf("Expected line %d, got %d", tt.line, line)
+		}
+		logical := ""
+		if len(location.LogicalLocations) > 0 {
+			logical = location.LogicalLocations[0].FullyQualifiedName
+		}
+		if logical != tt.logical {
+			t.Errorf("Expected %q, got %q", tt.logical, logical)
+		}
+	}
+}
+
+func TestExportSARIFEmpty(t *testing.T) {
+	data, err := ExportSARIF()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if log.Runs[0].Results == nil || len(log.Runs[0].Results) != 0 {
+		t.Errorf("Expected an empty results array, got %+v", log.Runs[0].Results)
+	}
+}
@@ -0,0 +1,179 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultSchedulerWorkers is the default number of tasks a Prober's scheduler runs at once
+const DefaultSchedulerWorkers = 16
+
+// Scheduler task kinds reported in SchedulerStats.Queues
+const (
+	// SchedulerBatch is a manifest probe of ProbeBatch
+	SchedulerBatch = "batch"
+	// SchedulerWatch is a manifest poll of a Watcher
+	SchedulerWatch = "watch"
+	// SchedulerDeepProbe is a segment fetch of DeepProbe (bitstream and base URL checks)
+	SchedulerDeepProbe = "deep_probe"
+)
+
+// SchedulerConfig configures the scheduler shared by batch probes, watches and
+// deep-probe sub-requests of a Prober, so that their total concurrency is
+// bounded by one limit rather than per feature. A task waiting for a worker
+// while its parent task is blocked on it (the segment fetches of a batch probe)
+// runs on the parent's worker, so nested tasks cannot deadlock.
+type SchedulerConfig struct {
+	// Workers bounds the tasks running at once (0 = DefaultSchedulerWorkers)
+	Workers int
+}
+
+// SchedulerStats reports the scheduler's workers and the queue wait times of
+// each task kind
+type SchedulerStats struct {
+	Workers int `json:"workers"`
+
+	// Running is the number of workers in use
+	Running int `json:"running"`
+
+	Queues map[string]QueueStats `json:"queues,omitempty"`
+}
+
+// QueueStats counts the tasks of one kind and how long they waited for a worker
+type QueueStats struct {
+	// Scheduled is the number of tasks that got a worker
+	Scheduled uint64 `json:"scheduled"`
+
+	// Waiting is the number of tasks waiting for a worker
+	Waiting int `json:"waiting"`
+
+	// Cancelled is the number of tasks whose context ended while waiting
+	Cancelled uint64 `json:"cancelled"`
+
+	TotalWait time.Duration `json:"total_wait"`
+	MaxWait   time.Duration `json:"max_wait"`
+}
+
+// MeanWait returns the average queue wait of scheduled tasks
+func (q QueueStats) MeanWait() time.Duration {
+	if q.Scheduled == 0 {
+		return 0
+	}
+	return q.TotalWait / time.Duration(q.Scheduled)
+}
+
+// scheduler bounds the concurrent tasks of a Prober. Waits are measured in
+// real time, like request timeouts.
+type scheduler struct {
+	workers chan struct{}
+
+	mu     sync.Mutex
+	queues map[string]*QueueStats
+}
+
+// schedulerLease is held by a running task. Its lend channel holds one token
+// that a child task may borrow instead of taking a worker of its own.
+type schedulerLease struct {
+	lend chan struct{}
+}
+
+type schedulerContextKey struct{}
+
+// newScheduler returns the scheduler of config (nil if config is nil)
+func newScheduler(config *SchedulerConfig) *scheduler {
+	if config == nil {
+		return nil
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = DefaultSchedulerWorkers
+	}
+	return &scheduler{workers: make(chan struct{}, workers), queues: make(map[string]*QueueStats)}
+}
+
+// schedule waits for a worker for a task of kind, or for the worker of the
+// task running in ctx to be free. It returns the context of the task and the
+// function releasing its worker. A nil scheduler runs every task immediately.
+func (s *scheduler) schedule(ctx context.Context, kind string) (context.Context, func(), error) {
+	if s == nil {
+		return ctx, func() {}, nil
+	}
+
+	var lend chan struct{} // nil blocks forever for top-level tasks
+	if parent, _ := ctx.Value(schedulerContextKey{}).(*schedulerLease); parent != nil {
+		lend = parent.lend
+	}
+
+	s.mu.Lock()
+	queue := s.queue(kind)
+	queue.Waiting++
+	s.mu.Unlock()
+
+	start := time.Now()
+	borrowed := false
+	select {
+	case s.workers <- struct{}{}:
+	case <-lend:
+		borrowed = true
+	case <-ctx.Done():
+		s.mu.Lock()
+		queue.Waiting--
+		queue.Cancelled++
+		s.mu.Unlock()
+		return ctx, nil, ctx.Err()
+	}
+	wait := time.Since(start)
+
+	s.mu.Lock()
+	queue.Waiting--
+	queue.Scheduled++
+	queue.TotalWait += wait
+	queue.MaxWait = max(queue.MaxWait, wait)
+	s.mu.Unlock()
+
+	lease := &schedulerLease{lend: make(chan struct{}, 1)}
+	lease.lend <- struct{}{}
+	release := func() {
+		if borrowed {
+			lend <- struct{}{}
+		} else {
+			<-s.workers
+		}
+	}
+	return context.WithValue(ctx, schedulerContextKey{}, lease), release, nil
+}
+
+// queue returns the stats of kind, creating them on first use (s.mu held)
+func (s *scheduler) queue(kind string) *QueueStats {
+	queue, ok := s.queues[kind]
+	if !ok {
+		queue = &QueueStats{}
+		s.queues[kind] = queue
+	}
+	return queue
+}
+
+func (s *scheduler) stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SchedulerStats{
+		Workers: cap(s.workers),
+		Running: len(s.workers),
+		Queues:  make(map[string]QueueStats, len(s.queues)),
+	}
+	for kind, queue := range s.queues {
+		stats.Queues[kind] = *queue
+	}
+	return stats
+}
+
+// SchedulerStats returns the scheduler's workers and queue wait times (zero
+// when ProbeOptions.Scheduler is nil)
+func (p *Prober) SchedulerStats() SchedulerStats {
+	if p.scheduler == nil {
+		return SchedulerStats{}
+	}
+	return p.scheduler.stats()
+}
@@ -0,0 +1,153 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerBoundsWorkers(t *testing.T) {
+	s := newScheduler(&SchedulerConfig{Workers: 1})
+
+	_, release, err := s.schedule(context.Background(), SchedulerBatch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	scheduled := make(chan struct{})
+	go func() {
+		_, release, err := s.schedule(context.Background(), SchedulerWatch)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		release()
+		close(scheduled)
+	}()
+
+	select {
+	case <-scheduled:
+		t.Fatal("Expected the second task to wait for the worker")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if waiting := s.stats().Queues[SchedulerWatch].Waiting; waiting != 1 {
+		t.Errorf("Expected 1 waiting task, got %d", waiting)
+	}
+
+	release()
+	<-scheduled
+
+	stats := s.stats()
+	if stats.Workers != 1 || stats.Running != 0 {
+		t.Errorf("Expected 1 worker and none running, got %+v", stats)
+	}
+	watch := stats.Queues[SchedulerWatch]
+	if watch.Scheduled != 1 || watch.Waiting != 0 || watch.MaxWait < 20*time.Millisecond || watch.MeanWait() != watch.TotalWait {
+		t.Errorf("Expected one watch task that waited at least 20ms, got %+v", watch)
+	}
+	if batch := stats.Queues[SchedulerBatch]; batch.Scheduled != 1 {
+		t.Errorf("Expected 1 scheduled batch task, got %+v", batch)
+	}
+}
+
+func TestSchedulerNestedTasks(t *testing.T) {
+	s := newScheduler(&SchedulerConfig{Workers: 1})
+
+	parentCtx, release, err := s.schedule(context.Background(), SchedulerBatch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer release()
+
+	// The only worker is held by the parent: its children run on it in turn
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(parentCtx, time.Second)
+		_, releaseChild, err := s.schedule(ctx, SchedulerDeepProbe)
+		cancel()
+		if err != nil {
+			t.Fatalf("Expected the child task to borrow the parent's worker, got %v", err)
+		}
+		releaseChild()
+	}
+	if scheduled := s.stats().Queues[SchedulerDeepProbe].Scheduled; scheduled != 2 {
+		t.Errorf("Expected 2 scheduled deep probe tasks, got %d", scheduled)
+	}
+}
+
+func TestSchedulerCancelled(t *testing.T) {
+	s := newScheduler(&SchedulerConfig{Workers: 1})
+	_, release, _ := s.schedule(context.Background(), SchedulerBatch)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := s.schedule(ctx, SchedulerBatch); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	expected := QueueStats{Scheduled: 1, Cancelled: 1}
+	if batch := s.stats().Queues[SchedulerBatch]; batch.Scheduled != expected.Scheduled || batch.Cancelled != expected.Cancelled || batch.Waiting != 0 {
+		t.Errorf("Expected %+v, got %+v", expected, batch)
+	}
+}
+
+func TestSchedulerDisabled(t *testing.T) {
+	var s *scheduler
+	ctx := context.Background()
+	taskCtx, release, err := s.schedule(ctx, SchedulerBatch)
+	if err != nil || taskCtx != ctx {
+		t.Fatalf("Expected the task to run immediately, got %v", err)
+	}
+	release()
+
+	if stats := NewProber(nil).SchedulerStats(); stats.Workers != 0 || stats.Queues != nil {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
+
+func TestProbeBatchScheduler(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			observed := maxInFlight.Load()
+			if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{Scheduler: &SchedulerConfig{Workers: 1}})
+	urls := []string{server.URL + "/a.mpd", server.URL + "/b.mpd", server.URL + "/c.mpd", server.URL + "/d.mpd"}
+	for _, result := range prober.ProbeBatch(context.Background(), urls, 4) {
+		if result.Err != nil {
+			t.Fatalf("Unexpected error: %v", result.Err)
+		}
+	}
+
+	if observed := maxInFlight.Load(); observed != 1 {
+		t.Errorf("Expected at most 1 request in flight, got %d", observed)
+	}
+	stats := prober.SchedulerStats()
+	if batch := stats.Queues[SchedulerBatch]; batch.Scheduled != 4 || batch.MaxWait == 0 {
+		t.Errorf("Expected 4 scheduled batch probes with queue waits, got %+v", batch)
+	}
+	if stats.Running != 0 {
+		t.Errorf("Expected no running tasks, got %d", stats.Running)
+	}
+}
+
+func TestValidateSchedulerOptions(t *testing.T) {
+	err := validateProbeOptions(&ProbeOptions{Scheduler: &SchedulerConfig{Workers: -1}})
+	if err == nil {
+		t.Fatal("Expected an error for negative workers")
+	}
+}
@@ -0,0 +1,245 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// OutputSchema selects the field names of the streams in JSON output (see ProbeOptions.Schema)
+type OutputSchema string
+
+const (
+	// SchemaLegacy is goprobe's own stream fields (the default)
+	SchemaLegacy OutputSchema = "legacy"
+	// SchemaFFprobe is ffprobe's stream fields (index, codec_name, codec_type, width, ...)
+	SchemaFFprobe OutputSchema = "ffprobe"
+	// SchemaDual is the legacy fields plus the ffprobe fields in a nested
+	// "ffprobe" object, since both schemas use some names (bit_rate,
+	// sample_rate, channels, disposition) with different formats
+	SchemaDual OutputSchema = "dual"
+)
+
+// OutputSchemas returns the supported output schemas
+func OutputSchemas() []OutputSchema {
+	return []OutputSchema{SchemaLegacy, SchemaFFprobe, SchemaDual}
+}
+
+// validOutputSchema reports whether schema is supported ("" = SchemaLegacy)
+func validOutputSchema(schema OutputSchema) bool {
+	if schema == "" {
+		return true
+	}
+	for _, supported := range OutputSchemas() {
+		if schema == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// FFprobeStream is a stream with ffprobe's field names and formats
+type FFprobeStream struct {
+	Index     int    `json:"index"`
+	CodecName string `json:"codec_name"`
+
+	// CodecType is "video", "audio" or "subtitle"
+	CodecType string `json:"codec_type"`
+
+	Width              int    `json:"width,omitempty"`
+	Height             int    `json:"height,omitempty"`
+	PixFmt             string `json:"pix_fmt,omitempty"`
+	SampleAspectRatio  string `json:"sample_aspect_ratio,omitempty"`
+	DisplayAspectRatio string `json:"display_aspect_ratio,omitempty"`
+
+	// RFrameRate and AvgFrameRate are rationals ("30000/1001")
+	RFrameRate   string `json:"r_frame_rate,omitempty"`
+	AvgFrameRate string `json:"avg_frame_rate,omitempty"`
+
+	SampleFmt     string `json:"sample_fmt,omitempty"`
+	SampleRate    string `json:"sample_rate,omitempty"`
+	Channels      int    `json:"channels,omitempty"`
+	ChannelLayout string `json:"channel_layout,omitempty"`
+
+	// BitRate is in bits per second
+	BitRate string `json:"bit_rate,omitempty"`
+
+	Disposition *FFprobeDisposition `json:"disposition,omitempty"`
+
+	// Tags holds the language and title (rendition name)
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// FFprobeDisposition is a Disposition with ffprobe's 0/1 flags
+type FFprobeDisposition struct {
+	Original        int `json:"original"`
+	Dub             int `json:"dub"`
+	Comment         int `json:"comment"`
+	HearingImpaired int `json:"hearing_impaired"`
+	VisualImpaired  int `json:"visual_impaired"`
+}
+
+// channelCounts maps the channel layouts of StreamInfo.Channels to channel counts
+var channelCounts = map[string]int{
+	"mono":      1,
+	"stereo":    2,
+	"2.1":       3,
+	"3.0":       3,
+	"3.0(back)": 3,
+	"quad":      4,
+	"4.0":       4,
+	"5.0":       5,
+	"5.0(side)": 5,
+	"5.1":       6,
+	"5.1(side)": 6,
+	"6.1":       7,
+	"7.1":       8,
+	"7.1(wide)": 8,
+	"5.1.2":     8,
+	"5.1.4":     10,
+	"7.1.4":     12,
+	"22.2":      24,
+}
+
+// channelCount returns the channel count of a channel layout, including the
+// "N channels" of layouts without a name (0 if unknown)
+func channelCount(layout string) int {
+	if count, ok := channelCounts[layout]; ok {
+		return count
+	}
+	if count, ok := strings.CutSuffix(layout, " channels"); ok {
+		n, _ := strconv.Atoi(count)
+		return n
+	}
+	return 0
+}
+
+// FFprobe returns the stream with ffprobe's field names, as stream index of the output
+func (s *StreamInfo) FFprobe(index int) FFprobeStream {
+	stream := FFprobeStream{
+		Index:              index,
+		CodecName:          s.Codec,
+		CodecType:          ffprobeCodecType(s.Type),
+		PixFmt:             s.PixFmt,
+		SampleAspectRatio:  s.SampleAspectRatio,
+		DisplayAspectRatio: s.DisplayAspectRatio,
+		SampleFmt:          s.SampleFmt,
+		SampleRate:         strings.TrimSuffix(s.SampleRate, " Hz"),
+		ChannelLayout:      s.Channels,
+		Channels:           channelCount(s.Channels),
+	}
+
+	if width, height, ok := strings.Cut(s.Resolution, "x"); ok {
+		stream.Width, _ = strconv.Atoi(width)
+		stream.Height, _ = strconv.Atoi(height)
+	}
+	if rate := ffprobeRational(s.FrameRate); rate != "" {
+		stream.RFrameRate, stream.AvgFrameRate = rate, rate
+	}
+	if bitRate := streamBitRate(s); bitRate > 0 {
+		stream.BitRate = strconv.FormatInt(bitRate, 10)
+	}
+	if d := s.Disposition; d != nil {
+		stream.Disposition = &FFprobeDisposition{
+			Original:        ffprobeFlag(d.Original),
+			Dub:             ffprobeFlag(d.Dub),
+			Comment:         ffprobeFlag(d.Comment),
+			HearingImpaired: ffprobeFlag(d.HearingImpaired),
+			VisualImpaired:  ffprobeFlag(d.VisualImpaired),
+		}
+	}
+	if s.Language != "" || s.Name != "" {
+		stream.Tags = make(map[string]string)
+		if s.Language != "" {
+			stream.Tags["language"] = s.Language
+		}
+		if s.Name != "" {
+			stream.Tags["title"] = s.Name
+		}
+	}
+	return stream
+}
+
+// ffprobeCodecType returns the ffprobe codec_type of a stream type
+func ffprobeCodecType(streamType string) string {
+	switch {
+	case strings.HasPrefix(streamType, "Video"):
+		return "video"
+	case strings.HasPrefix(streamType, "Audio"):
+		return "audio"
+	case strings.HasPrefix(streamType, "Subtitle"):
+		return "subtitle"
+	default:
+		return strings.ToLower(streamType)
+	}
+}
+
+// ffprobeRational formats a frame rate as a rational: "25" is "25/1", and
+// NTSC rates such as "29.97" are "30000/1001"
+func ffprobeRational(rate string) string {
+	if rate == "" || strings.Contains(rate, "/") {
+		return rate
+	}
+	value, err := strconv.ParseFloat(rate, 64)
+	if err != nil || value <= 0 {
+		return ""
+	}
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%d/1", int64(value))
+	}
+	if ntsc := math.Round(value * 1.001); math.Abs(ntsc*1000/1001-value) < 0.005 {
+		return fmt.Sprintf("%d/1001", int64(ntsc)*1000)
+	}
+	num, den := int64(math.Round(value*1000)), int64(1000)
+	divisor := gcd(num, den)
+	return fmt.Sprintf("%d/%d", num/divisor, den/divisor)
+}
+
+// ffprobeFlag returns an ffprobe disposition flag
+func ffprobeFlag(set bool) int {
+	if set {
+		return 1
+	}
+	return 0
+}
+
+// outputJSON is Output without its MarshalJSON method
+type outputJSON Output
+
+// streamJSON is StreamInfo without methods, embedded in dualStream
+type streamJSON StreamInfo
+
+// dualStream is a stream with the legacy fields and its ffprobe fields
+type dualStream struct {
+	streamJSON
+	FFprobe FFprobeStream `json:"ffprobe"`
+}
+
+// MarshalJSON renders the streams in the schema of ProbeOptions.Schema
+func (o Output) MarshalJSON() ([]byte, error) {
+	alias := outputJSON(o)
+	switch o.schema {
+	case SchemaFFprobe:
+		streams := make([]FFprobeStream, len(o.Streams))
+		for i := range o.Streams {
+			streams[i] = o.Streams[i].FFprobe(i)
+		}
+		return json.Marshal(struct {
+			Streams []FFprobeStream `json:"streams"`
+			*outputJSON
+		}{streams, &alias})
+	case SchemaDual:
+		streams := make([]dualStream, len(o.Streams))
+		for i := range o.Streams {
+			streams[i] = dualStream{streamJSON(o.Streams[i]), o.Streams[i].FFprobe(i)}
+		}
+		return json.Marshal(struct {
+			Streams []dualStream `json:"streams"`
+			*outputJSON
+		}{streams, &alias})
+	default:
+		return json.Marshal(&alias)
+	}
+}
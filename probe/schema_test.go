@@ -0,0 +1,185 @@
+package probe
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStreamFFprobe(t *testing.T) {
+	video := StreamInfo{
+		StreamID:           "0:0",
+		Type:               "Video",
+		Codec:              "h264",
+		PixFmt:             "yuv420p",
+		Resolution:         "1920x1080",
+		FrameRate:          "29.97",
+		BitRate:            "5000 kb/s",
+		SampleAspectRatio:  "1:1",
+		DisplayAspectRatio: "16:9",
+	}
+	expected := FFprobeStream{
+		Index:              0,
+		CodecName:          "h264",
+		CodecType:          "video",
+		Width:              1920,
+		Height:             1080,
+		PixFmt:             "yuv420p",
+		SampleAspectRatio:  "1:1",
+		DisplayAspectRatio: "16:9",
+		RFrameRate:         "30000/1001",
+		AvgFrameRate:       "30000/1001",
+		BitRate:            "5000000",
+	}
+	if got := video.FFprobe(0); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	audio := StreamInfo{
+		StreamID:    "0:1(fr)",
+		Type:        "Audio",
+		Codec:       "aac",
+		BitRate:     "128 kb/s",
+		Channels:    "stereo",
+		SampleFmt:   "fltp",
+		SampleRate:  "48000 Hz",
+		Language:    "fr",
+		Name:        "Français",
+		Disposition: &Disposition{Dub: true},
+	}
+	expected = FFprobeStream{
+		Index:         1,
+		CodecName:     "aac",
+		CodecType:     "audio",
+		SampleFmt:     "fltp",
+		SampleRate:    "48000",
+		Channels:      2,
+		ChannelLayout: "stereo",
+		BitRate:       "128000",
+		Disposition:   &FFprobeDisposition{Dub: 1},
+		Tags:          map[string]string{"language": "fr", "title": "Français"},
+	}
+	if got := audio.FFprobe(1); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestFFprobeRational(t *testing.T) {
+	tests := []struct {
+		rate     string
+		expected string
+	}{
+		{"", ""},
+		{"25", "25/1"},
+		{"25.000", "25/1"},
+		{"29.97", "30000/1001"},
+		{"23.976", "24000/1001"},
+		{"59.94", "60000/1001"},
+		{"12.5", "25/2"},
+		{"30000/1001", "30000/1001"},
+		{"fast", ""},
+	}
+	for _, tt := range tests {
+		if got := ffprobeRational(tt.rate); got != tt.expected {
+			t.Errorf("ffprobeRational(%q): expected %q, got %q", tt.rate, tt.expected, got)
+		}
+	}
+}
+
+// streamKeys returns the JSON keys of the first stream of output
+func streamKeys(t *testing.T, output *Output) map[string]interface{} {
+	t.Helper()
+	data, err := output.OutputJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded struct {
+		Streams  []map[string]interface{} `json:"streams"`
+		Warnings []string                 `json:"warnings"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Warnings, output.Warnings) {
+		t.Errorf("Expected %q, got %q", output.Warnings, decoded.Warnings)
+	}
+	return decoded.Streams[0]
+}
+
+func TestOutputSchemas(t *testing.T) {
+	newOutput := func(schema OutputSchema) *Output {
+		return &Output{
+			Streams: []StreamInfo{{
+				StreamID:   "0:0",
+				Type:       "Audio",
+				Codec:      "aac",
+				BitRate:    "128 kb/s",
+				Channels:   "stereo",
+				SampleRate: "48000 Hz",
+			}},
+			Warnings: []string{"a warning"},
+			schema:   schema,
+		}
+	}
+
+	tests := []struct {
+		schema   OutputSchema
+		expected map[string]interface{}
+	}{
+		{"", map[string]interface{}{
+			"stream_id": "0:0", "type": "Audio", "codec": "aac",
+			"bit_rate": "128 kb/s", "channels": "stereo", "sample_rate": "48000 Hz",
+		}},
+		{SchemaFFprobe, map[string]interface{}{
+			"index": 0.0, "codec_name": "aac", "codec_type": "audio",
+			"bit_rate": "128000", "channels": 2.0, "channel_layout": "stereo", "sample_rate": "48000",
+		}},
+		{SchemaDual, map[string]interface{}{
+			"stream_id": "0:0", "type": "Audio", "codec": "aac",
+			"bit_rate": "128 kb/s", "channels": "stereo", "sample_rate": "48000 Hz",
+			"ffprobe": map[string]interface{}{
+				"index": 0.0, "codec_name": "aac", "codec_type": "audio",
+				"bit_rate": "128000", "channels": 2.0, "channel_layout": "stereo", "sample_rate": "48000",
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.schema), func(t *testing.T) {
+			if got := streamKeys(t, newOutput(tt.schema)); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateOutputSchema(t *testing.T) {
+	for _, schema := range OutputSchemas() {
+		if err := validateProbeOptions(&ProbeOptions{Schema: schema}); err != nil {
+			t.Errorf("Unexpected error for %q: %v", schema, err)
+		}
+	}
+	if err := validateProbeOptions(&ProbeOptions{Schema: "xml"}); err == nil {
+		t.Error("Expected an error for an unknown schema")
+	}
+}
+
+func TestChannelCount(t *testing.T) {
+	tests := []struct {
+		layout   string
+		expected int
+	}{
+		{layout: "stereo", expected: 2},
+		{layout: "5.1", expected: 6},
+		{layout: "12 channels", expected: 12},
+		{layout: "Atmos/JOC", expected: 0},
+		{layout: "", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			if got := channelCount(tt.layout); got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
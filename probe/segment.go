@@ -0,0 +1,205 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultSegmentBytes is the default number of leading bytes of a media
+// segment fetched in deep-probe mode (512KB)
+const DefaultSegmentBytes = 512 * 1024
+
+// DeepProbe enables checks that fetch the start of each stream's first media
+// segment, beyond what the manifest declares
+type DeepProbe struct {
+	// SegmentBytes limits the bytes fetched per segment with a Range request (0 = DefaultSegmentBytes)
+	SegmentBytes int64
+
+	// CheckBaseURLs requests each DASH stream's first segment through every
+	// alternative BaseURL and reports reachability in Output.BaseURLChecks
+	CheckBaseURLs bool
+}
+
+// Deep-probe checks reported in IncompleteCheck.Check
+const (
+	// CheckBitstream is the bitstream check of a stream (Output.Bitstream)
+	CheckBitstream = "bitstream"
+	// CheckBaseURL is the reachability check of a base URL (Output.BaseURLChecks)
+	CheckBaseURL = "base_url"
+)
+
+// IncompleteCheck is a deep-probe check that did not run to completion
+// because the probe deadline passed or the probe was cancelled
+type IncompleteCheck struct {
+	// Check is CheckBitstream or CheckBaseURL
+	Check string `json:"check"`
+
+	// StreamID and URI identify the stream and segment left unchecked
+	StreamID string `json:"stream_id"`
+	URI      string `json:"uri"`
+
+	// BaseURL is the base URL of a CheckBaseURL check
+	BaseURL string `json:"base_url,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// addIncomplete reports a failed check as cut short in output if the probe
+// context ended
+func (o *Output) addIncomplete(ctx context.Context, check IncompleteCheck) {
+	if ctx.Err() == nil {
+		return
+	}
+	o.Partial = true
+	o.Incomplete = append(o.Incomplete, check)
+}
+
+// segmentBytes returns the effective segment fetch size
+func (d *DeepProbe) segmentBytes() int64 {
+	if d.SegmentBytes > 0 {
+		return d.SegmentBytes
+	}
+	return DefaultSegmentBytes
+}
+
+// fetchRange fetches the first limit bytes of a media resource, retrying if configured
+func (h *HTTPClient) fetchRange(ctx context.Context, resourceURL string, limit int64) ([]byte, error) {
+	var result []byte
+
+	operation := func(attemptCtx context.Context) error {
+		data, err := h.fetchRangeOnce(attemptCtx, resourceURL, limit)
+		if err != nil {
+			return err
+		}
+		result = data
+		return nil
+	}
+
+	if h.retryExecutor != nil {
+		if err := h.retryExecutor.ExecuteWithAttemptContext(ctx, operation); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	return h.fetchRangeOnce(ctx, resourceURL, limit)
+}
+
+// fetchRangeOnce performs a single Range request, truncating the body to
+// limit bytes when the server ignores Range
+func (h *HTTPClient) fetchRangeOnce(ctx context.Context, resourceURL string, limit int64) ([]byte, error) {
+	resp, err := h.get(ctx, resourceURL, map[string]string{
+		"Range":           fmt.Sprintf("bytes=0-%d", limit-1),
+		"Accept-Encoding": "identity",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		if isTimeoutError(err) {
+			return nil, NewTimeoutError(resourceURL, 30) // Default timeout
+		}
+		return nil, NewNetworkError(resourceURL, err)
+	}
+	if len(data) == 0 {
+		return nil, NewNetworkError(resourceURL, fmt.Errorf("received empty response"))
+	}
+	return data, nil
+}
+
+// fetchSegment fetches the first limit bytes of a media segment through the
+// prober's client for its origin
+func (p *Prober) fetchSegment(ctx context.Context, segmentURL string, limit int64) ([]byte, error) {
+	parsedURL, err := validateURL(segmentURL)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := p.httpClient(parsedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.slots.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.slots.release()
+
+	data, err := httpClient.fetchRange(ctx, parsedURL.String(), limit)
+	if err != nil {
+		logDebug(ctx, "Segment fetch failed", map[string]interface{}{
+			"url":   parsedURL.String(),
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+	return data, nil
+}
+
+// firstSegment holds the start of a stream's first media segment, prefixed
+// with its initialization segment when there is one
+type firstSegment struct {
+	uri  string
+	data []byte
+}
+
+// fetchFirstSegment fetches the initialization and first media segment of a
+// stream. HLS media playlists are fetched and their first segment (and
+// EXT-X-MAP) resolved; DASH URLs come from ResolveURLs directly.
+func (p *Prober) fetchFirstSegment(ctx context.Context, urls []string, limit int64) (*firstSegment, error) {
+	if len(urls) == 1 && isHLSPlaylistURL(urls[0]) {
+		fetched, err := p.fetch(ctx, urls[0])
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(fetched.body, "METHOD=AES-128") {
+			return nil, errEncryptedSegment
+		}
+		urls = hlsFirstSegmentURLs(fetched.body, urls[0])
+		if len(urls) == 0 {
+			return nil, NewParsingError(fetched.url, "HLS", fmt.Errorf("media playlist has no segments"))
+		}
+	}
+
+	var data []byte
+	for _, segmentURL := range urls {
+		part, err := p.fetchSegment(ctx, segmentURL, limit)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, part...)
+	}
+	return &firstSegment{uri: urls[len(urls)-1], data: data}, nil
+}
+
+// isHLSPlaylistURL reports whether a resolved URL is an HLS media playlist
+func isHLSPlaylistURL(resourceURL string) bool {
+	path := resourceURL
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.ToLower(path)
+	return strings.HasSuffix(path, ".m3u8") || strings.HasSuffix(path, ".m3u")
+}
+
+// hlsFirstSegmentURLs returns the EXT-X-MAP URI (if any) and first segment
+// URI of a media playlist, resolved against playlistURL
+func hlsFirstSegmentURLs(content, playlistURL string) []string {
+	var urls []string
+	for lines := scanLines(content); lines.scan(); {
+		line := strings.TrimSpace(lines.line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if uri := parseHLSAttributes(line)["URI"]; uri != "" && len(urls) == 0 {
+				urls = append(urls, resolveHLSURI(playlistURL, uri))
+			}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			return append(urls, resolveHLSURI(playlistURL, line))
+		}
+	}
+	return nil
+}
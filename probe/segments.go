@@ -0,0 +1,218 @@
+package probe
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Segment addressing schemes of SegmentInfo.Addressing
+const (
+	// SegmentAddressingNumber is a SegmentTemplate with @duration: segments
+	// of equal duration numbered from @startNumber ($Number$)
+	SegmentAddressingNumber = "number"
+	// SegmentAddressingTimelineTime is a SegmentTemplate with a
+	// SegmentTimeline whose media URLs carry the segment start time ($Time$)
+	SegmentAddressingTimelineTime = "timeline_time"
+	// SegmentAddressingTimelineNumber is a SegmentTemplate with a
+	// SegmentTimeline whose media URLs carry the segment number ($Number$)
+	SegmentAddressingTimelineNumber = "timeline_number"
+	// SegmentAddressingList is a SegmentList of explicit segment URLs
+	SegmentAddressingList = "list"
+	// SegmentAddressingBase is a SegmentBase: one resource indexed by a sidx box
+	SegmentAddressingBase = "base"
+)
+
+// SegmentInfo describes how the segments of a DASH representation are
+// addressed, from the manifest alone, to estimate startup behavior and verify
+// packaging without downloading media
+type SegmentInfo struct {
+	// Addressing is one of the SegmentAddressing* schemes
+	Addressing string `json:"addressing"`
+
+	// Timescale is the number of time units per second (default 1)
+	Timescale int64 `json:"timescale"`
+
+	// SegmentDuration is the nominal segment duration in seconds: @duration,
+	// or the most frequent duration of a SegmentTimeline
+	SegmentDuration float64 `json:"segment_duration,omitempty"`
+
+	// MinSegmentDuration and MaxSegmentDuration bound the durations of a
+	// SegmentTimeline in seconds
+	MinSegmentDuration float64 `json:"min_segment_duration,omitempty"`
+	MaxSegmentDuration float64 `json:"max_segment_duration,omitempty"`
+
+	// Segments is the number of media segments (0 = unknown, e.g. a
+	// @duration template of a live period without an end)
+	Segments int `json:"segments,omitempty"`
+
+	// TotalDuration is the duration of the segments in seconds
+	TotalDuration float64 `json:"total_duration,omitempty"`
+
+	// StartNumber is the number of the first segment of a template
+	StartNumber int64 `json:"start_number,omitempty"`
+
+	// IndexRange is the byte range of the sidx box of a SegmentBase
+	IndexRange string `json:"index_range,omitempty"`
+}
+
+// mpdSegmentInfo describes the segments of a representation, with the
+// segment information inherited from its period and adaptation set (nil if
+// there is none)
+func mpdSegmentInfo(mpd *MPD, periodIndex int, adaptationSet AdaptationSet, rep Representation) *SegmentInfo {
+	period := mpd.Periods[periodIndex]
+	periodDuration, bounded := mpdPeriodDuration(mpd, periodIndex)
+
+	if template := mergeSegmentTemplates(period.SegmentTemplate, adaptationSet.SegmentTemplate, rep.SegmentTemplate); template != nil {
+		return templateSegmentInfo(template, periodDuration, bounded)
+	}
+
+	if list := firstSegmentList(period.SegmentList, adaptationSet.SegmentList, rep.SegmentList); list != nil {
+		info := &SegmentInfo{
+			Addressing: SegmentAddressingList,
+			Timescale:  segmentTimescale(list.Timescale),
+			Segments:   len(list.SegmentURLs),
+		}
+		if d, err := strconv.ParseInt(list.Duration, 10, 64); err == nil && d > 0 {
+			info.SegmentDuration = float64(d) / float64(info.Timescale)
+			info.TotalDuration = info.SegmentDuration * float64(info.Segments)
+		}
+		return info
+	}
+
+	for _, base := range []*SegmentBase{rep.SegmentBase, adaptationSet.SegmentBase, period.SegmentBase} {
+		if base != nil {
+			return &SegmentInfo{
+				Addressing: SegmentAddressingBase,
+				Timescale:  segmentTimescale(base.Timescale),
+				IndexRange: base.IndexRange,
+			}
+		}
+	}
+	return nil
+}
+
+// templateSegmentInfo describes the segments of a SegmentTemplate in a
+// period of periodDuration seconds (when bounded)
+func templateSegmentInfo(template *SegmentTemplate, periodDuration float64, bounded bool) *SegmentInfo {
+	info := &SegmentInfo{
+		Addressing: SegmentAddressingNumber,
+		Timescale:  segmentTimescale(template.Timescale),
+	}
+	info.StartNumber, _ = firstTemplateSegment(template)
+	timescale := float64(info.Timescale)
+
+	if template.SegmentTimeline == nil {
+		d, err := strconv.ParseInt(template.Duration, 10, 64)
+		if err != nil || d <= 0 {
+			return info
+		}
+		info.SegmentDuration = float64(d) / timescale
+		if bounded {
+			info.Segments = int(math.Ceil(periodDuration/info.SegmentDuration - 1e-9))
+			info.TotalDuration = periodDuration
+		}
+		return info
+	}
+
+	info.Addressing = SegmentAddressingTimelineNumber
+	if strings.Contains(template.Media, "$Time") {
+		info.Addressing = SegmentAddressingTimelineTime
+	}
+
+	segments := template.SegmentTimeline.Segments
+	durations := make(map[int64]int)
+	var start, end, total int64
+	var minimum, maximum int64
+	for i, s := range segments {
+		if t, err := strconv.ParseInt(s.T, 10, 64); err == nil {
+			start = t
+		}
+		if i == 0 && bounded {
+			end = start + int64(periodDuration*timescale)
+		}
+		d, _ := strconv.ParseInt(s.D, 10, 64)
+		if d <= 0 {
+			continue
+		}
+
+		// A negative @r repeats until the next S, or the end of the period
+		repeat, _ := strconv.ParseInt(s.R, 10, 64)
+		if repeat < 0 {
+			repeat = 0
+			next := int64(-1)
+			if i+1 < len(segments) {
+				if t, err := strconv.ParseInt(segments[i+1].T, 10, 64); err == nil {
+					next = t
+				}
+			} else if bounded {
+				next = end
+			}
+			if next > start {
+				repeat = (next-start+d-1)/d - 1
+			}
+		}
+
+		count := repeat + 1
+		info.Segments += int(count)
+		durations[d] += int(count)
+		total += count * d
+		start += count * d
+		if minimum == 0 || d < minimum {
+			minimum = d
+		}
+		if d > maximum {
+			maximum = d
+		}
+	}
+
+	// The most frequent duration, the longest on ties
+	var nominal int64
+	for d, count := range durations {
+		if count > durations[nominal] || (count == durations[nominal] && d > nominal) {
+			nominal = d
+		}
+	}
+	info.SegmentDuration = float64(nominal) / timescale
+	info.MinSegmentDuration = float64(minimum) / timescale
+	info.MaxSegmentDuration = float64(maximum) / timescale
+	info.TotalDuration = float64(total) / timescale
+	return info
+}
+
+// segmentTimescale parses a @timescale, defaulting to 1
+func segmentTimescale(value string) int64 {
+	if timescale, err := strconv.ParseInt(value, 10, 64); err == nil && timescale > 0 {
+		return timescale
+	}
+	return 1
+}
+
+// mpdPeriodDuration returns the duration of a period in seconds: its
+// @duration, the start of the next period, or the end of the presentation.
+// It reports false when the period has no known end, as in live MPDs.
+func mpdPeriodDuration(mpd *MPD, periodIndex int) (float64, bool) {
+	period := mpd.Periods[periodIndex]
+	if d, err := parseISODuration(period.Duration); err == nil {
+		return d.Seconds(), true
+	}
+
+	start, err := parseISODuration(period.Start)
+	if err != nil {
+		if periodIndex > 0 {
+			return 0, false
+		}
+		start = 0
+	}
+
+	if periodIndex+1 < len(mpd.Periods) {
+		if next, err := parseISODuration(mpd.Periods[periodIndex+1].Start); err == nil && next > start {
+			return (next - start).Seconds(), true
+		}
+		return 0, false
+	}
+	if total, err := parseISODuration(mpd.MediaPresentationDuration); err == nil && total > start {
+		return (total - start).Seconds(), true
+	}
+	return 0, false
+}
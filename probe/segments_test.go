@@ -0,0 +1,98 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMPDSegmentInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		mpd      string
+		expected *SegmentInfo
+	}{
+		{
+			name: "number template inherited from the adaptation set",
+			mpd: `<MPD type="static" mediaPresentationDuration="PT1M"><Period>
+<AdaptationSet contentType="video"><SegmentTemplate media="$Number$.m4s" timescale="90000" duration="360000"/>
+<Representation id="v" bandwidth="1000000" width="1280" height="720"/></AdaptationSet></Period></MPD>`,
+			expected: &SegmentInfo{Addressing: SegmentAddressingNumber, Timescale: 90000, SegmentDuration: 4, Segments: 15, TotalDuration: 60, StartNumber: 1},
+		},
+		{
+			name: "number template of a live period",
+			mpd: `<MPD type="dynamic"><Period start="PT0S">
+<AdaptationSet contentType="video"><Representation id="v" bandwidth="1000000">
+<SegmentTemplate media="$Number$.m4s" startNumber="100" duration="2"/></Representation></AdaptationSet></Period></MPD>`,
+			expected: &SegmentInfo{Addressing: SegmentAddressingNumber, Timescale: 1, SegmentDuration: 2, StartNumber: 100},
+		},
+		{
+			name: "time timeline with repeats",
+			mpd: `<MPD type="static" mediaPresentationDuration="PT7S"><Period>
+<AdaptationSet contentType="video"><SegmentTemplate media="$RepresentationID$/$Time$.m4s" timescale="90000">
+<SegmentTimeline><S t="0" d="180000" r="2"/><S d="90000"/></SegmentTimeline></SegmentTemplate>
+<Representation id="v" bandwidth="1000000"/></AdaptationSet></Period></MPD>`,
+			expected: &SegmentInfo{Addressing: SegmentAddressingTimelineTime, Timescale: 90000, SegmentDuration: 2, MinSegmentDuration: 1, MaxSegmentDuration: 2, Segments: 4, TotalDuration: 7, StartNumber: 1},
+		},
+		{
+			name: "number timeline repeating until the next S",
+			mpd: `<MPD type="dynamic"><Period start="PT0S">
+<AdaptationSet contentType="audio"><SegmentTemplate media="$Number$.m4s" startNumber="5">
+<SegmentTimeline><S t="0" d="2" r="-1"/><S t="10" d="1"/></SegmentTimeline></SegmentTemplate>
+<Representation id="a" bandwidth="128000"/></AdaptationSet></Period></MPD>`,
+			expected: &SegmentInfo{Addressing: SegmentAddressingTimelineNumber, Timescale: 1, SegmentDuration: 2, MinSegmentDuration: 1, MaxSegmentDuration: 2, Segments: 6, TotalDuration: 11, StartNumber: 5},
+		},
+		{
+			name: "timeline repeating until the end of the period",
+			mpd: `<MPD type="static"><Period duration="PT9S">
+<AdaptationSet contentType="video"><SegmentTemplate media="$Time$.m4s">
+<SegmentTimeline><S t="0" d="2" r="-1"/></SegmentTimeline></SegmentTemplate>
+<Representation id="v" bandwidth="1000000"/></AdaptationSet></Period></MPD>`,
+			expected: &SegmentInfo{Addressing: SegmentAddressingTimelineTime, Timescale: 1, SegmentDuration: 2, MinSegmentDuration: 2, MaxSegmentDuration: 2, Segments: 5, TotalDuration: 10, StartNumber: 1},
+		},
+		{
+			name: "second period bounded by the presentation",
+			mpd: `<MPD type="static" mediaPresentationDuration="PT50S"><Period start="PT0S"/><Period start="PT30S">
+<AdaptationSet contentType="video"><SegmentTemplate media="$Number$.m4s" duration="4"/>
+<Representation id="v" bandwidth="1000000"/></AdaptationSet></Period></MPD>`,
+			expected: &SegmentInfo{Addressing: SegmentAddressingNumber, Timescale: 1, SegmentDuration: 4, Segments: 5, TotalDuration: 20, StartNumber: 1},
+		},
+		{
+			name: "segment list",
+			mpd: `<MPD type="static"><Period>
+<AdaptationSet contentType="video"><Representation id="v" bandwidth="1000000">
+<SegmentList timescale="1000" duration="6000"><Initialization sourceURL="init.mp4"/>
+<SegmentURL media="1.m4s"/><SegmentURL media="2.m4s"/><SegmentURL media="3.m4s"/></SegmentList>
+</Representation></AdaptationSet></Period></MPD>`,
+			expected: &SegmentInfo{Addressing: SegmentAddressingList, Timescale: 1000, SegmentDuration: 6, Segments: 3, TotalDuration: 18},
+		},
+		{
+			name: "segment base",
+			mpd: `<MPD type="static"><Period>
+<AdaptationSet contentType="audio"><Representation id="a" bandwidth="128000"><BaseURL>audio.mp4</BaseURL>
+<SegmentBase timescale="48000" indexRange="862-1245"><Initialization range="0-861"/></SegmentBase>
+</Representation></AdaptationSet></Period></MPD>`,
+			expected: &SegmentInfo{Addressing: SegmentAddressingBase, Timescale: 48000, IndexRange: "862-1245"},
+		},
+		{
+			name: "no segment information",
+			mpd: `<MPD type="static"><Period>
+<AdaptationSet contentType="audio"><Representation id="a" bandwidth="128000"><BaseURL>audio.mp4</BaseURL>
+</Representation></AdaptationSet></Period></MPD>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseMPDManifest(tt.mpd, "https://cdn.example.com/manifest.mpd")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(output.Streams) != 1 {
+				t.Fatalf("Expected 1 stream, got %d", len(output.Streams))
+			}
+			if got := output.Streams[0].Segments; !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
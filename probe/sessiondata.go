@@ -0,0 +1,105 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SessionData is an EXT-X-SESSION-DATA entry of an HLS master playlist,
+// carrying broadcaster-provided metadata such as titles and identifiers
+type SessionData struct {
+	// DataID identifies the entry, usually in reverse DNS notation ("com.example.title")
+	DataID string `json:"data_id"`
+
+	// Value is the inline value (empty when the data is at URI)
+	Value string `json:"value,omitempty"`
+
+	// URI locates a JSON (or, with FORMAT=RAW, any) file with the data, resolved
+	// against the manifest URL
+	URI string `json:"uri,omitempty"`
+
+	// Format is the FORMAT of the URI data ("JSON" or "RAW")
+	Format string `json:"format,omitempty"`
+
+	// Language is the BCP 47 language of the value
+	Language string `json:"language,omitempty"`
+}
+
+// parseHLSSessionData parses an EXT-X-SESSION-DATA tag
+func parseHLSSessionData(line, manifestURL string) SessionData {
+	attrs := parseHLSAttributes(line)
+	data := SessionData{
+		DataID:   attrs["DATA-ID"],
+		Value:    attrs["VALUE"],
+		Format:   attrs["FORMAT"],
+		Language: attrs["LANGUAGE"],
+	}
+	if uri := attrs["URI"]; uri != "" {
+		data.URI = resolveHLSURI(manifestURL, uri)
+	}
+	return data
+}
+
+// sessionMetadata groups session data entries by DATA-ID in manifest order,
+// with warnings for entries that break the HLS rules
+func sessionMetadata(entries []SessionData) (map[string][]SessionData, []string) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	metadata := make(map[string][]SessionData)
+	var warnings []string
+	for _, entry := range entries {
+		switch {
+		case entry.DataID == "":
+			warnings = append(warnings, "EXT-X-SESSION-DATA without DATA-ID is ignored")
+			continue
+		case (entry.Value == "") == (entry.URI == ""):
+			warnings = append(warnings, fmt.Sprintf("EXT-X-SESSION-DATA %q must have exactly one of VALUE and URI", entry.DataID))
+		}
+		for _, existing := range metadata[entry.DataID] {
+			if strings.EqualFold(existing.Language, entry.Language) {
+				warnings = append(warnings, fmt.Sprintf("EXT-X-SESSION-DATA %q is repeated for language %q", entry.DataID, entry.Language))
+				break
+			}
+		}
+		metadata[entry.DataID] = append(metadata[entry.DataID], entry)
+	}
+	return metadata, warnings
+}
+
+// SessionValue returns the inline value of the session data dataID in
+// language, falling back to the entry without a language and then to the
+// first entry. It reports false if there is no inline value.
+func (o *Output) SessionValue(dataID, language string) (string, bool) {
+	entries := o.Metadata[dataID]
+	var fallback *SessionData
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Value == "" {
+			continue
+		}
+		if language != "" && strings.EqualFold(entry.Language, language) {
+			return entry.Value, true
+		}
+		if fallback == nil || (fallback.Language != "" && entry.Language == "") {
+			fallback = entry
+		}
+	}
+	if fallback == nil {
+		return "", false
+	}
+	return fallback.Value, true
+}
+
+// cloneMetadata returns a copy of metadata that shares no entries with it
+func cloneMetadata(metadata map[string][]SessionData) map[string][]SessionData {
+	if metadata == nil {
+		return nil
+	}
+	c := make(map[string][]SessionData, len(metadata))
+	for dataID, entries := range metadata {
+		c[dataID] = append([]SessionData(nil), entries...)
+	}
+	return c
+}
@@ -0,0 +1,114 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sessionDataManifest = `#EXTM3U
+#EXT-X-SESSION-DATA:DATA-ID="com.example.title",VALUE="Evening News",LANGUAGE="en"
+#EXT-X-SESSION-DATA:DATA-ID="com.example.title",VALUE="Journal du soir",LANGUAGE="fr"
+#EXT-X-SESSION-DATA:DATA-ID="com.example.asset-id",VALUE="EN-2024-0412"
+#EXT-X-SESSION-DATA:DATA-ID="com.example.lyrics",URI="lyrics.json?token=abc",FORMAT=JSON
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2"
+video/720p.m3u8
+`
+
+func TestParseHLSSessionData(t *testing.T) {
+	output, err := parseHLSManifest(sessionDataManifest, "https://cdn.example.com/live/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string][]SessionData{
+		"com.example.title": {
+			{DataID: "com.example.title", Value: "Evening News", Language: "en"},
+			{DataID: "com.example.title", Value: "Journal du soir", Language: "fr"},
+		},
+		"com.example.asset-id": {
+			{DataID: "com.example.asset-id", Value: "EN-2024-0412"},
+		},
+		"com.example.lyrics": {
+			{DataID: "com.example.lyrics", URI: "https://cdn.example.com/live/lyrics.json?token=abc", Format: "JSON"},
+		},
+	}
+	if !reflect.DeepEqual(output.Metadata, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, output.Metadata)
+	}
+	if len(output.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %q", output.Warnings)
+	}
+
+	output.makeDeterministic()
+	if uri := output.Metadata["com.example.lyrics"][0].URI; uri != "https://cdn.example.com/live/lyrics.json" {
+		t.Errorf("Expected %q, got %q", "https://cdn.example.com/live/lyrics.json", uri)
+	}
+}
+
+func TestSessionDataWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     string
+		expected []string
+	}{
+		{
+			"missing DATA-ID",
+			`#EXT-X-SESSION-DATA:VALUE="x"`,
+			[]string{"EXT-X-SESSION-DATA without DATA-ID is ignored"},
+		},
+		{
+			"both VALUE and URI",
+			`#EXT-X-SESSION-DATA:DATA-ID="com.example.a",VALUE="x",URI="a.json"`,
+			[]string{`EXT-X-SESSION-DATA "com.example.a" must have exactly one of VALUE and URI`},
+		},
+		{
+			"neither VALUE nor URI",
+			`#EXT-X-SESSION-DATA:DATA-ID="com.example.a"`,
+			[]string{`EXT-X-SESSION-DATA "com.example.a" must have exactly one of VALUE and URI`},
+		},
+		{
+			"repeated language",
+			"#EXT-X-SESSION-DATA:DATA-ID=\"com.example.a\",VALUE=\"x\",LANGUAGE=\"en\"\n#EXT-X-SESSION-DATA:DATA-ID=\"com.example.a\",VALUE=\"y\",LANGUAGE=\"EN\"",
+			[]string{`EXT-X-SESSION-DATA "com.example.a" is repeated for language "EN"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := parseHLSManifest("#EXTM3U\n"+tt.tags+"\n", "https://cdn.example.com/master.m3u8")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output.Warnings, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, output.Warnings)
+			}
+		})
+	}
+}
+
+func TestSessionValue(t *testing.T) {
+	output, err := parseHLSManifest(sessionDataManifest, "https://cdn.example.com/live/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		dataID   string
+		language string
+		expected string
+		ok       bool
+	}{
+		{"com.example.title", "fr", "Journal du soir", true},
+		{"com.example.title", "FR", "Journal du soir", true},
+		{"com.example.title", "de", "Evening News", true},
+		{"com.example.title", "", "Evening News", true},
+		{"com.example.asset-id", "en", "EN-2024-0412", true},
+		{"com.example.lyrics", "", "", false},
+		{"com.example.missing", "", "", false},
+	}
+	for _, tt := range tests {
+		value, ok := output.SessionValue(tt.dataID, tt.language)
+		if value != tt.expected || ok != tt.ok {
+			t.Errorf("SessionValue(%q, %q): expected %q %v, got %q %v", tt.dataID, tt.language, tt.expected, tt.ok, value, ok)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sinkTimeout bounds the delivery of a single record to a sink
+const sinkTimeout = 10 * time.Second
+
+// Record kinds
+const (
+	// RecordProbe is the result of a probe (ProbeOptions.Sinks)
+	RecordProbe = "probe"
+	// RecordWatch is a watch event (WatchOptions.Sinks)
+	RecordWatch = "watch"
+)
+
+// Record is a probe result or watch event delivered to an OutputSink
+type Record struct {
+	// Kind is RecordProbe or RecordWatch
+	Kind string    `json:"kind"`
+	URL  string    `json:"url"`
+	Time time.Time `json:"time"`
+
+	// Duration is the duration of the probe (RecordProbe)
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Output is the probe output (RecordProbe)
+	Output *Output `json:"output,omitempty"`
+
+	// Event is the watch event (RecordWatch)
+	Event *Event `json:"event,omitempty"`
+
+	// Error is the probe failure, or the poll failure of an error_occurred event
+	Error string `json:"error,omitempty"`
+}
+
+// OutputSink delivers probe results to an external system, such as an event
+// pipeline. Implementations must be safe for concurrent use since batch
+// probes and watchers write concurrently.
+type OutputSink interface {
+	Write(ctx context.Context, record Record) error
+}
+
+// writeSinks writes record to sinks. Failures are logged, not returned: a
+// sink outage must not fail probes.
+func writeSinks(ctx context.Context, sinks []OutputSink, redactor *redactor, record Record) {
+	for _, sink := range sinks {
+		writeCtx, cancel := context.WithTimeout(ctx, sinkTimeout)
+		err := sink.Write(writeCtx, record)
+		cancel()
+		if err != nil {
+			logWarn(ctx, "Output sink write failed", map[string]interface{}{
+				"url":   redactor.redactString(record.URL),
+				"kind":  record.Kind,
+				"error": redactor.redactString(err.Error()),
+			})
+		}
+	}
+}
+
+// probeRecord returns the record of a probe of manifestURL
+func probeRecord(manifestURL string, start time.Time, duration time.Duration, output *Output, err error) Record {
+	record := Record{Kind: RecordProbe, URL: manifestURL, Time: start, Duration: duration, Output: output}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record
+}
+
+// watchRecord returns the record of a watch event, with its URL and error
+// redacted like those of probe records
+func watchRecord(event Event, redactor *redactor) Record {
+	event.URL = redactor.redactString(event.URL)
+	record := Record{Kind: RecordWatch, URL: event.URL, Time: event.Time, Event: &event}
+	if event.Err != nil {
+		record.Error = redactor.redactString(event.Err.Error())
+	}
+	return record
+}
+
+// WriterSink writes records as JSON lines to a writer
+type WriterSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewWriterSink creates a sink writing JSON lines to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewStdoutSink creates a sink writing JSON lines to standard output
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// NewFileSink creates a sink appending JSON lines to the file at path,
+// creating it if needed. Close the sink to close the file.
+func NewFileSink(path string) (*WriterSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterSink{w: file, closer: file}, nil
+}
+
+// Write writes the record as one JSON line
+func (s *WriterSink) Write(ctx context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// Close closes the file of a file sink (a no-op for other writers)
+func (s *WriterSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// HTTPSink posts each record as JSON to an HTTP endpoint
+type HTTPSink struct {
+	URL string
+
+	// Headers are added to every request, e.g. Authorization
+	Headers map[string]string
+
+	// HTTPClient sends the requests (nil = http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// NewHTTPSink creates a sink posting records to endpoint
+func NewHTTPSink(endpoint string) *HTTPSink {
+	return &HTTPSink{URL: endpoint}
+}
+
+// Write posts the record
+func (s *HTTPSink) Write(ctx context.Context, record Record) error {
+	return postSinkJSON(ctx, s.HTTPClient, s.URL, "application/json", s.Headers, record)
+}
+
+// KafkaRESTSink produces each record to a Kafka topic through a Confluent
+// REST Proxy (v2 API), keyed by manifest URL so that the records of a
+// manifest stay in order within a partition
+type KafkaRESTSink struct {
+	// Endpoint is the REST Proxy URL, e.g. "http://kafka-rest:8082"
+	Endpoint string
+
+	Topic string
+
+	// Headers are added to every request, e.g. Authorization
+	Headers map[string]string
+
+	// HTTPClient sends the requests (nil = http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// NewKafkaRESTSink creates a sink producing to topic through the REST Proxy at endpoint
+func NewKafkaRESTSink(endpoint, topic string) *KafkaRESTSink {
+	return &KafkaRESTSink{Endpoint: endpoint, Topic: topic}
+}
+
+// kafkaRecords is the body of a REST Proxy v2 produce request
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Record `json:"value"`
+}
+
+// Write produces the record to the topic
+func (s *KafkaRESTSink) Write(ctx context.Context, record Record) error {
+	endpoint := strings.TrimSuffix(s.Endpoint, "/") + "/topics/" + url.PathEscape(s.Topic)
+	body := kafkaRecords{Records: []kafkaRecord{{Key: record.URL, Value: record}}}
+	return postSinkJSON(ctx, s.HTTPClient, endpoint, "application/vnd.kafka.json.v2+json", s.Headers, body)
+}
+
+// postSinkJSON posts payload as JSON with contentType and headers, and checks for a 2xx response
+func postSinkJSON(ctx context.Context, client *http.Client, endpoint, contentType string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("sink endpoint returned HTTP %d: %s", response.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// ParseSink creates a sink from a spec:
+//
+//	stdout or -                     JSON lines on standard output
+//	file:PATH                       JSON lines appended to PATH
+//	http://... or https://...       HTTP POST of each record
+//	kafka+http://HOST:PORT/TOPIC    Kafka REST Proxy (kafka+https for TLS)
+func ParseSink(spec string) (OutputSink, error) {
+	switch {
+	case spec == "stdout" || spec == "-":
+		return NewStdoutSink(), nil
+	case strings.HasPrefix(spec, "file:"):
+		path := strings.TrimPrefix(spec, "file:")
+		if path == "" {
+			return nil, NewValidationError("file sink needs a path (file:PATH)")
+		}
+		return NewFileSink(path)
+	case strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://"):
+		return NewHTTPSink(spec), nil
+	case strings.HasPrefix(spec, "kafka+http://") || strings.HasPrefix(spec, "kafka+https://"):
+		parsed, err := url.Parse(strings.TrimPrefix(spec, "kafka+"))
+		if err != nil {
+			return nil, NewValidationError(fmt.Sprintf("invalid Kafka sink %q: %v", spec, err))
+		}
+		topic := strings.Trim(parsed.Path, "/")
+		if topic == "" || strings.Contains(topic, "/") {
+			return nil, NewValidationError(fmt.Sprintf("Kafka sink %q needs a topic (kafka+http://HOST:PORT/TOPIC)", spec))
+		}
+		parsed.Path = ""
+		return NewKafkaRESTSink(parsed.String(), topic), nil
+	}
+	return nil, NewValidationError(fmt.Sprintf("unknown sink %q (stdout, file:PATH, http(s)://URL or kafka+http(s)://HOST/TOPIC)", spec))
+}
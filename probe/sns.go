@@ -0,0 +1,166 @@
+package probe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are the credentials used to sign AWS requests
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// SNSSink publishes alerts as JSON messages to an Amazon SNS topic. Requests
+// are signed with AWS Signature Version 4.
+type SNSSink struct {
+	TopicARN    string
+	Region      string
+	Credentials AWSCredentials
+
+	// Endpoint overrides the SNS endpoint (default: https://sns.<region>.amazonaws.com/)
+	Endpoint string
+
+	// HTTPClient sends the requests (nil = http.DefaultClient)
+	HTTPClient *http.Client
+
+	// now returns the signing time (nil = time.Now)
+	now func() time.Time
+}
+
+// NewSNSSink creates a sink for an SNS topic. The region is taken from the
+// topic ARN and credentials from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func NewSNSSink(topicARN string) *SNSSink {
+	region := ""
+	if parts := strings.Split(topicARN, ":"); len(parts) >= 6 {
+		region = parts[3]
+	}
+	return &SNSSink{
+		TopicARN: topicARN,
+		Region:   region,
+		Credentials: AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+	}
+}
+
+// Send publishes the alert to the topic
+func (s *SNSSink) Send(ctx context.Context, alert Alert) error {
+	message, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sns.%s.amazonaws.com/", s.Region)
+	}
+
+	subject := fmt.Sprintf("goprobe %s: %s", alert.Severity, alert.Event)
+	form := url.Values{
+		"Action":   {"Publish"},
+		"Version":  {"2010-03-31"},
+		"TopicArn": {s.TopicARN},
+		"Subject":  {subject},
+		"Message":  {string(message)},
+	}
+	body := form.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	signV4(request, []byte(body), s.Credentials, s.Region, "sns", now().UTC())
+
+	return doAlertRequest(s.HTTPClient, request)
+}
+
+// signV4 signs request with AWS Signature Version 4, setting the X-Amz-Date,
+// X-Amz-Security-Token and Authorization headers
+func signV4(request *http.Request, body []byte, credentials AWSCredentials, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	// Canonical headers: host plus every header set on the request, lowercased and sorted
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		path,
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 requires
+func canonicalQuery(query url.Values) string {
+	encoded := query.Encode() // sorted by key
+	return strings.ReplaceAll(encoded, "+", "%20")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
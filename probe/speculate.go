@@ -0,0 +1,290 @@
+package probe
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"sync"
+)
+
+// DefaultSpeculationEntries is the default number of master playlists whose
+// top variant a prober remembers for speculation
+const DefaultSpeculationEntries = 1024
+
+// Speculation sources reported in SpeculationResult.Source
+const (
+	// SpeculationPrevious is the top variant found by a previous probe of the master playlist
+	SpeculationPrevious = "previous"
+	// SpeculationGuess is the top variant returned by Speculation.Guess
+	SpeculationGuess = "guess"
+)
+
+// Speculation fetches the likely top (highest BANDWIDTH) variant media
+// playlist of an HLS master playlist while the master playlist is still being
+// fetched. When a later stage (AnalyzeBitrates, FollowVariants, DeepProbe, Throttle or media
+// playlist rules) requests the same URL, it uses the speculative response
+// instead of waiting for a new request.
+type Speculation struct {
+	// Guess returns the likely top variant URL of a master playlist that was
+	// not probed before ("" = no guess; nil = previous results only). It is
+	// not stored in config profiles.
+	Guess func(masterURL string) string `json:"-"`
+
+	// MaxEntries bounds the master playlists remembered (0 = DefaultSpeculationEntries)
+	MaxEntries int
+}
+
+// SpeculationResult reports the speculative fetch of a probe
+type SpeculationResult struct {
+	URL string `json:"url"`
+
+	// Source is SpeculationPrevious or SpeculationGuess
+	Source string `json:"source"`
+
+	// Correct reports that URL is the top variant of the master playlist
+	Correct bool `json:"correct"`
+
+	// Used reports that a later fetch used the speculative response
+	Used bool `json:"used"`
+
+	// Error describes why the speculative fetch failed
+	Error string `json:"error,omitempty"`
+}
+
+// GuessSibling returns a Speculation.Guess that replaces the file name of the
+// master playlist URL with name (e.g. "1080p/index.m3u8"), keeping its query
+// string, for packagers with fixed variant names
+func GuessSibling(name string) func(masterURL string) string {
+	return func(masterURL string) string {
+		parsed, err := url.Parse(masterURL)
+		if err != nil {
+			return ""
+		}
+		parsed.Path = path.Join(path.Dir(parsed.Path), name)
+		return parsed.String()
+	}
+}
+
+// topVariants remembers the top variant URL of probed master playlists by
+// master playlist URL without query string, forgetting the oldest entries
+// first
+type topVariants struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]topVariant
+	order   []string
+}
+
+// topVariant is the remembered top variant of a master playlist
+type topVariant struct {
+	url string
+
+	// masterQuery reports that the variant URL carried the query string of
+	// its master playlist (e.g. a CDN token), which url is stored without:
+	// the query string of the next master playlist URL replaces it
+	masterQuery bool
+}
+
+// newTopVariants returns the top variant memory of a speculation config (nil if disabled)
+func newTopVariants(speculation *Speculation) *topVariants {
+	if speculation == nil {
+		return nil
+	}
+	max := speculation.MaxEntries
+	if max <= 0 {
+		max = DefaultSpeculationEntries
+	}
+	return &topVariants{max: max, entries: make(map[string]topVariant)}
+}
+
+// get returns the remembered top variant URL of masterURL ("" if none)
+func (t *topVariants) get(masterURL string) string {
+	t.mu.Lock()
+	entry, ok := t.entries[stripQuery(masterURL)]
+	t.mu.Unlock()
+	if !ok || !entry.masterQuery {
+		return entry.url
+	}
+	return withQueryOf(entry.url, masterURL)
+}
+
+func (t *topVariants) set(masterURL, variantURL string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := stripQuery(masterURL)
+	if _, ok := t.entries[key]; !ok {
+		t.order = append(t.order, key)
+		if len(t.order) > t.max {
+			delete(t.entries, t.order[0])
+			t.order = t.order[1:]
+		}
+	}
+	t.entries[key] = newTopVariant(masterURL, variantURL)
+}
+
+// newTopVariant returns the entry of variantURL, a variant of masterURL. The
+// variant keeps its own query string, which may select it among variants
+// sharing a path; only the query string it shares with the master playlist
+// is replaced on later probes.
+func newTopVariant(masterURL, variantURL string) topVariant {
+	variant, err := url.Parse(variantURL)
+	if err != nil {
+		return topVariant{url: variantURL}
+	}
+	master, err := url.Parse(masterURL)
+	if err != nil || variant.RawQuery == "" || variant.RawQuery != master.RawQuery {
+		return topVariant{url: variantURL}
+	}
+	variant.RawQuery = ""
+	return topVariant{url: variant.String(), masterQuery: true}
+}
+
+// speculativeFetch is an in-flight speculative fetch of a probe
+type speculativeFetch struct {
+	url    string
+	source string
+	done   chan struct{}
+
+	// fetched and err are set before done is closed
+	fetched *fetchResult
+	err     error
+
+	mu sync.Mutex
+	// taken reports that a fetch claimed the response, used that it got it:
+	// the response is handed out at most once
+	taken bool
+	used  bool
+}
+
+type speculationContextKey struct{}
+
+// speculate starts the speculative fetch of the likely top variant of
+// masterURL, and returns ctx carrying it for later fetches (nil if there is
+// no candidate or no stage fetches variant playlists)
+func (p *Prober) speculate(ctx context.Context, masterURL string) (context.Context, *speculativeFetch) {
+	if p.topVariants == nil || !p.readsVariantPlaylists() {
+		return ctx, nil
+	}
+
+	spec := &speculativeFetch{done: make(chan struct{})}
+	if previous := p.topVariants.get(masterURL); previous != "" {
+		spec.url, spec.source = previous, SpeculationPrevious
+	} else if guess := p.opts.Speculation.Guess; guess != nil {
+		spec.url, spec.source = guess(masterURL), SpeculationGuess
+	}
+	if spec.url == "" || spec.url == masterURL {
+		return ctx, nil
+	}
+
+	logDebug(ctx, "Speculatively fetching top variant", map[string]interface{}{
+		"url":    spec.url,
+		"source": spec.source,
+	})
+	go func() {
+		defer close(spec.done)
+		spec.fetched, spec.err = p.fetch(ctx, spec.url)
+	}()
+	return context.WithValue(ctx, speculationContextKey{}, spec), spec
+}
+
+// takeSpeculative returns the speculative response for exactly manifestURL,
+// waiting for it if needed (nil if there is none, another fetch took it, or
+// the speculative fetch failed)
+func takeSpeculative(ctx context.Context, manifestURL string) *fetchResult {
+	spec, _ := ctx.Value(speculationContextKey{}).(*speculativeFetch)
+	if spec == nil || spec.url != manifestURL {
+		return nil
+	}
+	spec.mu.Lock()
+	taken := spec.taken
+	spec.taken = true
+	spec.mu.Unlock()
+	if taken {
+		return nil
+	}
+
+	select {
+	case <-spec.done:
+	case <-ctx.Done():
+		return nil
+	}
+	if spec.err != nil {
+		return nil
+	}
+
+	spec.mu.Lock()
+	spec.used = true
+	spec.mu.Unlock()
+	return spec.fetched
+}
+
+// finishSpeculation reports the speculative fetch in output and remembers the
+// actual top variant of masterURL
+func (p *Prober) finishSpeculation(spec *speculativeFetch, masterURL string, output *Output) {
+	top := topVariantURL(output)
+	if top != "" {
+		p.topVariants.set(masterURL, top)
+	}
+	if spec == nil {
+		return
+	}
+
+	result := &SpeculationResult{URL: spec.url, Source: spec.source, Correct: spec.url == top}
+	spec.mu.Lock()
+	result.Used = spec.used
+	spec.mu.Unlock()
+
+	select {
+	case <-spec.done:
+		if spec.err != nil {
+			result.Error = p.redactor.redactError(spec.err).Error()
+		}
+	default:
+	}
+	output.Speculation = result
+}
+
+// withQueryOf returns rawURL with the query string of masterURL
+func withQueryOf(rawURL, masterURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	master, err := url.Parse(masterURL)
+	if err != nil {
+		return rawURL
+	}
+	parsed.RawQuery = master.RawQuery
+	return parsed.String()
+}
+
+// readsVariantPlaylists reports whether a stage of the probes of p fetches
+// variant media playlists, which speculation is for
+func (p *Prober) readsVariantPlaylists() bool {
+	opts := p.opts
+	if opts.AnalyzeBitrates || opts.FollowVariants || opts.DeepProbe != nil || opts.DecodeCheck != nil || opts.Throttle != nil {
+		return true
+	}
+	for _, name := range opts.RulePacks {
+		rules, _ := RulePack(name)
+		for _, r := range rules {
+			if r.MediaPlaylists {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// topVariantURL returns the URL of the variant with the highest BANDWIDTH of
+// an HLS master playlist ("" if there are no variants)
+func topVariantURL(output *Output) string {
+	var top hlsVariantRef
+	for _, variant := range output.variants {
+		if top.uri == "" || variant.bandwidth > top.bandwidth {
+			top = variant
+		}
+	}
+	return top.uri
+}
@@ -0,0 +1,252 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const speculationTestMaster = `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360
+low.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1280x720
+high.m3u8
+`
+
+// speculationTestServer serves speculationTestMaster and counts requests by path
+func speculationTestServer() (*httptest.Server, func(path string) int) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte(speculationTestMaster))
+		case "/low.m3u8", "/high.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-BITRATE:1000\n#EXTINF:6.0,\nseg1.ts\n#EXT-X-ENDLIST\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+}
+
+func TestSpeculation(t *testing.T) {
+	tests := []struct {
+		name     string
+		guess    string
+		analyze  bool
+		expected SpeculationResult
+	}{
+		{
+			name:     "correct guess used by bitrate analysis",
+			guess:    "high.m3u8",
+			analyze:  true,
+			expected: SpeculationResult{URL: "/high.m3u8", Source: SpeculationGuess, Correct: true, Used: true},
+		},
+		{
+			name:  "no stage reads variant playlists",
+			guess: "high.m3u8",
+		},
+		{
+			name:     "wrong guess",
+			guess:    "low.m3u8",
+			analyze:  true,
+			expected: SpeculationResult{URL: "/low.m3u8", Source: SpeculationGuess, Used: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := speculationTestServer()
+			defer server.Close()
+
+			opts := &ProbeOptions{
+				AnalyzeBitrates: tt.analyze,
+				Speculation:     &Speculation{Guess: GuessSibling(tt.guess)},
+			}
+			output, err := NewProber(opts).Probe(context.Background(), server.URL+"/master.m3u8")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !tt.analyze {
+				if output.Speculation != nil || requests("/"+tt.guess) != 0 {
+					t.Errorf("Expected no speculative fetch, got %+v", output.Speculation)
+				}
+				return
+			}
+			expected := tt.expected
+			expected.URL = server.URL + expected.URL
+			if output.Speculation == nil || *output.Speculation != expected {
+				t.Errorf("Expected %+v, got %+v", expected, output.Speculation)
+			}
+			if count := requests("/" + tt.guess); count != 1 {
+				t.Errorf("Expected 1 request for the speculated variant, got %d", count)
+			}
+		})
+	}
+}
+
+func TestSpeculationPreviousResult(t *testing.T) {
+	server, _ := speculationTestServer()
+	defer server.Close()
+
+	prober := NewProber(&ProbeOptions{AnalyzeBitrates: true, Speculation: &Speculation{}})
+
+	output, err := prober.Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Speculation != nil {
+		t.Errorf("Expected no speculation without a guess, got %+v", output.Speculation)
+	}
+
+	// The remembered top variant ignores the query string of the master playlist
+	output, err = prober.Probe(context.Background(), server.URL+"/master.m3u8?token=abc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := SpeculationResult{URL: server.URL + "/high.m3u8", Source: SpeculationPrevious, Correct: true, Used: true}
+	if output.Speculation == nil || *output.Speculation != expected {
+		t.Errorf("Expected %+v, got %+v", expected, output.Speculation)
+	}
+}
+
+func TestSpeculationVariantQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		// master is the master playlist, with %s replaced by the query
+		// string of its request
+		master   string
+		queries  [2]string
+		expected string
+	}{
+		{
+			name:     "variants selected by query",
+			master:   "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nvariant.m3u8?q=low\n#EXT-X-STREAM-INF:BANDWIDTH=3000000\nvariant.m3u8?q=high\n",
+			expected: "/variant.m3u8?q=high",
+		},
+		{
+			name:     "master token passed to variants",
+			master:   "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nlow.m3u8?%s\n#EXT-X-STREAM-INF:BANDWIDTH=3000000\nhigh.m3u8?%s\n",
+			queries:  [2]string{"token=a", "token=b"},
+			expected: "/high.m3u8?token=b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				switch {
+				case r.URL.Path == "/master.m3u8":
+					w.Write([]byte(strings.ReplaceAll(tt.master, "%s", r.URL.RawQuery)))
+				default:
+					// The bitrate tells the variants apart
+					bitrate := "3000"
+					if query.Get("q") == "low" || r.URL.Path == "/low.m3u8" {
+						bitrate = "800"
+					}
+					fmt.Fprintf(w, "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-BITRATE:%s\n#EXTINF:6.0,\nseg1.ts\n#EXT-X-ENDLIST\n", bitrate)
+				}
+			}))
+			defer server.Close()
+
+			prober := NewProber(&ProbeOptions{AnalyzeBitrates: true, Speculation: &Speculation{}})
+			var output *Output
+			for _, query := range tt.queries {
+				masterURL := server.URL + "/master.m3u8"
+				if query != "" {
+					masterURL += "?" + query
+				}
+				var err error
+				if output, err = prober.Probe(context.Background(), masterURL); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			if len(output.Bitrates) != 2 || output.Bitrates[0].Avg != 800000 || output.Bitrates[1].Avg != 3000000 {
+				t.Errorf("Expected the bitrates of both variants, got %+v", output.Bitrates)
+			}
+			expected := SpeculationResult{URL: server.URL + tt.expected, Source: SpeculationPrevious, Correct: true, Used: true}
+			if output.Speculation == nil || *output.Speculation != expected {
+				t.Errorf("Expected %+v, got %+v", expected, output.Speculation)
+			}
+
+			output.makeDeterministic()
+			if output.Speculation.URL != stripQuery(server.URL+tt.expected) {
+				t.Errorf("Expected the deterministic speculation URL without query, got %q", output.Speculation.URL)
+			}
+		})
+	}
+}
+
+func TestSpeculationFailedFetch(t *testing.T) {
+	server, _ := speculationTestServer()
+	defer server.Close()
+
+	opts := &ProbeOptions{AnalyzeBitrates: true, Speculation: &Speculation{Guess: GuessSibling("missing.m3u8")}}
+	output, err := NewProber(opts).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.Speculation == nil || output.Speculation.Correct || output.Speculation.Used {
+		t.Errorf("Expected an incorrect, unused speculation, got %+v", output.Speculation)
+	}
+	if len(output.Bitrates) != 2 {
+		t.Errorf("Expected 2 bitrate results, got %d", len(output.Bitrates))
+	}
+}
+
+func TestGuessSibling(t *testing.T) {
+	tests := []struct {
+		master   string
+		name     string
+		expected string
+	}{
+		{"https://example.com/live/master.m3u8?token=abc", "1080p/index.m3u8", "https://example.com/live/1080p/index.m3u8?token=abc"},
+		{"https://example.com/master.m3u8", "high.m3u8", "https://example.com/high.m3u8"},
+	}
+
+	for _, tt := range tests {
+		if result := GuessSibling(tt.name)(tt.master); result != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, result)
+		}
+	}
+}
+
+func TestTopVariantsEviction(t *testing.T) {
+	variants := newTopVariants(&Speculation{MaxEntries: 2})
+	variants.set("https://example.com/a.m3u8", "a1")
+	variants.set("https://example.com/b.m3u8", "b1")
+	variants.set("https://example.com/a.m3u8?token=1", "a2")
+	variants.set("https://example.com/c.m3u8", "c1")
+
+	tests := []struct {
+		master   string
+		expected string
+	}{
+		{"https://example.com/a.m3u8", ""},
+		{"https://example.com/b.m3u8", "b1"},
+		{"https://example.com/c.m3u8", "c1"},
+	}
+	for _, tt := range tests {
+		if result := variants.get(tt.master); result != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, result)
+		}
+	}
+}
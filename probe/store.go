@@ -0,0 +1,142 @@
+package probe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WatchSnapshot is the persisted state of a watch, restored on restart so the
+// watcher resumes without emitting spurious change events
+type WatchSnapshot struct {
+	// Hash is the content hash of the last parsed manifest
+	Hash string `json:"hash"`
+
+	// Streams are the streams of the last parsed manifest
+	Streams []StreamInfo `json:"streams"`
+
+	// Failing reports that the last poll failed
+	Failing bool `json:"failing,omitempty"`
+
+	// Live reports that the last parsed manifest was live, so an end of
+	// stream is still detected after a restart
+	Live bool `json:"live,omitempty"`
+
+	// Cadence and Timing track the update cadence so stale detection survives restarts
+	Cadence *Cadence        `json:"cadence,omitempty"`
+	Timing  *ManifestTiming `json:"timing,omitempty"`
+
+	// Circuit is the circuit breaker state of the manifest's origin (nil = no breaker)
+	Circuit *CircuitSnapshot `json:"circuit,omitempty"`
+
+	// SavedAt is when the snapshot was saved
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// Store persists watch state. Implementations backed by files, databases or
+// Redis make watch mode restartable; they must be safe for concurrent use.
+type Store interface {
+	// Load returns the snapshot saved under key, or nil if there is none
+	Load(ctx context.Context, key string) (*WatchSnapshot, error)
+
+	// Save stores snapshot under key
+	Save(ctx context.Context, key string, snapshot *WatchSnapshot) error
+}
+
+// MemoryStore keeps watch state in memory, surviving watcher restarts within a process
+type MemoryStore struct {
+	mu        sync.Mutex
+	snapshots map[string]WatchSnapshot
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{snapshots: make(map[string]WatchSnapshot)}
+}
+
+// Load returns the snapshot saved under key, or nil if there is none
+func (s *MemoryStore) Load(ctx context.Context, key string) (*WatchSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot, ok := s.snapshots[key]
+	if !ok {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+// Save stores snapshot under key
+func (s *MemoryStore) Save(ctx context.Context, key string, snapshot *WatchSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[key] = *snapshot
+	return nil
+}
+
+// FileStore keeps watch state as JSON files in a directory, one per key
+type FileStore struct {
+	// Dir is the directory holding the state files
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewFileStore creates a store writing to dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+// path returns the state file of key
+func (s *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load returns the snapshot saved under key, or nil if there is none
+func (s *FileStore) Load(ctx context.Context, key string) (*WatchSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot WatchSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Save stores snapshot under key, replacing the file atomically
+func (s *FileStore) Save(ctx context.Context, key string, snapshot *WatchSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+
+	path := s.path(key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
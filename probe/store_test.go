@@ -0,0 +1,73 @@
+package probe
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWatcherResumesFromStore(t *testing.T) {
+	server := httptest.NewServer(&manifestSequence{
+		responses: []string{watchTestManifest, watchTestManifest, watchTestManifestUpdated},
+	})
+	defer server.Close()
+
+	store := NewFileStore(t.TempDir())
+	prober := NewProber(nil)
+	manifestURL := server.URL + "/master.m3u8"
+
+	// First run: a single poll, then stop
+	ctx, cancel := context.WithCancel(context.Background())
+	first := prober.Watch(ctx, manifestURL, &WatchOptions{Interval: time.Hour, Store: store})
+	if event := <-first.Events(); event.Type != EventManifestUpdated {
+		t.Fatalf("Expected %s, got %s", EventManifestUpdated, event.Type)
+	}
+	cancel()
+	<-first.Done()
+
+	snapshot, err := store.Load(context.Background(), manifestURL)
+	if err != nil || snapshot == nil {
+		t.Fatalf("Expected saved snapshot, got %v (err %v)", snapshot, err)
+	}
+	if snapshot.Hash != manifestHash(watchTestManifest) {
+		t.Errorf("Expected hash of the first manifest, got %q", snapshot.Hash)
+	}
+
+	// Restart: the unchanged manifest emits nothing, the update is diffed
+	// against the restored streams
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	second := prober.Watch(ctx, manifestURL, &WatchOptions{Interval: 10 * time.Millisecond, Store: store})
+
+	expected := []EventType{EventManifestUpdated, EventStreamAdded, EventStreamAdded}
+	for i, eventType := range expected {
+		select {
+		case event := <-second.Events():
+			if event.Type != eventType {
+				t.Errorf("Event %d: expected %s, got %s", i, eventType, event.Type)
+			}
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for event %d", i)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	if snapshot, err := store.Load(ctx, "missing"); snapshot != nil || err != nil {
+		t.Errorf("Expected no snapshot, got %v (err %v)", snapshot, err)
+	}
+
+	saved := &WatchSnapshot{Hash: "abc", Circuit: &CircuitSnapshot{State: CircuitStateOpen, Failures: 5}}
+	if err := store.Save(ctx, "key", saved); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load(ctx, "key")
+	if err != nil || loaded == nil || loaded.Hash != "abc" || loaded.Circuit.State != CircuitStateOpen {
+		t.Errorf("Expected saved snapshot, got %+v (err %v)", loaded, err)
+	}
+}
@@ -0,0 +1,117 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultThrottleSegments is the default number of media segments downloaded
+// for the rebuffer estimate
+const DefaultThrottleSegments = 3
+
+// throttleChunkBytes bounds the bytes delivered per read of a shaped body, so
+// that the rate is applied smoothly rather than in large bursts
+const throttleChunkBytes = 16 * 1024
+
+// Throttle simulates a constrained network link. Every fetch of the prober
+// (manifests, media playlists and segments) shares a token bucket of
+// BitsPerSecond: up to BurstBytes download at full speed, then the rate
+// applies. The media segments of one rendition are then downloaded to
+// estimate the rebuffer risk at that bandwidth.
+type Throttle struct {
+	// BitsPerSecond is the sustained download bandwidth
+	BitsPerSecond int64
+
+	// BurstBytes can be downloaded at full speed after the link was idle (0 = no burst)
+	BurstBytes int64
+
+	// StreamID selects the rendition of the rebuffer estimate ("" = the video
+	// stream with the highest bitrate)
+	StreamID string
+
+	// Segments is the number of media segments downloaded (0 = DefaultThrottleSegments)
+	Segments int
+}
+
+// segments returns the effective number of segments to download
+func (t *Throttle) segments() int {
+	if t.Segments > 0 {
+		return t.Segments
+	}
+	return DefaultThrottleSegments
+}
+
+// bandwidthShaper is a token bucket of bytes shared by the fetches of a prober
+type bandwidthShaper struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newBandwidthShaper returns the shaper of a throttle (nil if throttle is nil)
+func newBandwidthShaper(throttle *Throttle) *bandwidthShaper {
+	if throttle == nil || throttle.BitsPerSecond <= 0 {
+		return nil
+	}
+	return &bandwidthShaper{
+		rate:   float64(throttle.BitsPerSecond) / 8,
+		burst:  float64(throttle.BurstBytes),
+		tokens: float64(throttle.BurstBytes),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes n bytes from the bucket and returns how long to wait before
+// delivering them
+func (s *bandwidthShaper) reserve(n int) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tokens = min(s.burst, s.tokens+now.Sub(s.last).Seconds()*s.rate)
+	s.last = now
+
+	s.tokens -= float64(n)
+	if s.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-s.tokens / s.rate * float64(time.Second))
+}
+
+// wrap returns body delivered at the shaped rate
+func (s *bandwidthShaper) wrap(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	return &shapedBody{ReadCloser: body, ctx: ctx, shaper: s}
+}
+
+// shapedBody is a response body read through a bandwidthShaper. Waits use
+// real time, like context deadlines.
+type shapedBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	shaper *bandwidthShaper
+}
+
+func (b *shapedBody) Read(p []byte) (int, error) {
+	if len(p) > throttleChunkBytes {
+		p = p[:throttleChunkBytes]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n == 0 {
+		return n, err
+	}
+
+	if wait := b.shaper.reserve(n); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-b.ctx.Done():
+			return n, b.ctx.Err()
+		}
+	}
+	return n, err
+}
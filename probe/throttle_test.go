@@ -0,0 +1,57 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBandwidthShaperReserve(t *testing.T) {
+	shaper := newBandwidthShaper(&Throttle{BitsPerSecond: 8000, BurstBytes: 500})
+
+	if wait := shaper.reserve(500); wait != 0 {
+		t.Errorf("Expected the burst to be free, got %v", wait)
+	}
+	// 1000 bytes at 1000 bytes/s
+	if wait := shaper.reserve(1000); wait < 900*time.Millisecond || wait > time.Second {
+		t.Errorf("Expected a wait of about 1s, got %v", wait)
+	}
+}
+
+func TestNewBandwidthShaperDisabled(t *testing.T) {
+	if shaper := newBandwidthShaper(nil); shaper != nil {
+		t.Errorf("Expected no shaper, got %+v", shaper)
+	}
+}
+
+func TestShapedBody(t *testing.T) {
+	// 20KB at 1 Mbit/s (125KB/s) takes about 160ms
+	shaper := newBandwidthShaper(&Throttle{BitsPerSecond: 1000000})
+	body := shaper.wrap(context.Background(), io.NopCloser(bytes.NewReader(make([]byte, 20*1024))))
+
+	start := time.Now()
+	data, err := io.ReadAll(body)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(data) != 20*1024 {
+		t.Errorf("Expected %d bytes, got %d", 20*1024, len(data))
+	}
+	if elapsed < 120*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected about 160ms, got %v", elapsed)
+	}
+}
+
+func TestShapedBodyCanceled(t *testing.T) {
+	shaper := newBandwidthShaper(&Throttle{BitsPerSecond: 8000})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	body := shaper.wrap(ctx, io.NopCloser(bytes.NewReader(make([]byte, 64*1024))))
+	if _, err := io.ReadAll(body); err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build js && wasm
+
+package probe
+
+// fetchTransport reports whether requests go through the Fetch API of the
+// browser (req's js/wasm transport), which cannot use proxies or dialers,
+// decompresses responses itself and drops forbidden headers such as
+// User-Agent, Origin and Referer
+const fetchTransport = true
@@ -0,0 +1,6 @@
+//go:build !(js && wasm)
+
+package probe
+
+// fetchTransport reports whether requests go through the Fetch API of the browser
+const fetchTransport = false
@@ -0,0 +1,148 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// segmentContainers maps media segment extensions to Container values
+var segmentContainers = map[string]string{
+	".ts":     "mpegts",
+	".tsv":    "mpegts",
+	".tsa":    "mpegts",
+	".m2ts":   "mpegts",
+	".mp4":    "fmp4",
+	".m4s":    "fmp4",
+	".m4v":    "fmp4",
+	".m4a":    "fmp4",
+	".cmfv":   "fmp4",
+	".cmfa":   "fmp4",
+	".cmft":   "fmp4",
+	".aac":    "packed_audio",
+	".ac3":    "packed_audio",
+	".ec3":    "packed_audio",
+	".mp3":    "packed_audio",
+	".vtt":    "webvtt",
+	".webvtt": "webvtt",
+}
+
+// VariantPlaylist summarizes the media playlist of an HLS variant, as
+// discovered with ProbeOptions.FollowVariants
+type VariantPlaylist struct {
+	MediaPlaylist
+
+	// Container is the segment format: "fmp4" (with EXT-X-MAP), or "mpegts",
+	// "packed_audio", "webvtt" or "fmp4" by segment extension ("" = unknown)
+	Container string `json:"container,omitempty"`
+
+	// InitSegment is the resolved EXT-X-MAP URI of the first segment
+	InitSegment string `json:"init_segment,omitempty"`
+}
+
+// variantPlaylistFetch is the fetched media playlist of a variant
+type variantPlaylistFetch struct {
+	fetched *fetchResult
+	err     error
+}
+
+// fetchVariantPlaylists fetches the variant media playlists of output
+// concurrently, once per distinct URI, and returns them in variant order.
+// The bitrate analysis, FollowVariants and the media playlist rules share
+// the fetches of a probe, so failures are reported as warnings only once.
+func (p *Prober) fetchVariantPlaylists(ctx context.Context, output *Output) []variantPlaylistFetch {
+	if output.variantPlaylists != nil {
+		return output.variantPlaylists
+	}
+
+	fetches := make([]variantPlaylistFetch, len(output.variants))
+	indexes := make(map[string][]int)
+	var uris []string
+	for i, variant := range output.variants {
+		if _, ok := indexes[variant.uri]; !ok {
+			uris = append(uris, variant.uri)
+		}
+		indexes[variant.uri] = append(indexes[variant.uri], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, uri := range uris {
+		wg.Add(1)
+		go func(uri string) {
+			defer wg.Done()
+			fetched, err := p.fetch(ctx, uri)
+			for _, i := range indexes[uri] {
+				fetches[i] = variantPlaylistFetch{fetched: fetched, err: err}
+			}
+		}(uri)
+	}
+	wg.Wait()
+
+	for i, variant := range output.variants {
+		if fetches[i].err != nil {
+			output.Warnings = append(output.Warnings, fmt.Sprintf("media playlist of stream %s could not be fetched: %s",
+				variant.streamID, p.redactor.redactError(fetches[i].err)))
+		}
+	}
+	output.variantPlaylists = fetches
+	return fetches
+}
+
+// followVariants summarizes the variant media playlists of output in
+// Output.Variants. Playlists that are not media playlists are reported as
+// warnings.
+func (p *Prober) followVariants(ctx context.Context, output *Output) {
+	byURI := make(map[string]*VariantPlaylist)
+	for i, fetch := range p.fetchVariantPlaylists(ctx, output) {
+		variant := output.variants[i]
+		if fetch.err != nil {
+			continue
+		}
+		if _, ok := byURI[variant.uri]; ok {
+			continue
+		}
+		if !isHLSMediaPlaylist(fetch.fetched.body) {
+			err := NewParsingError(fetch.fetched.url, "HLS", fmt.Errorf("not a media playlist"))
+			output.Warnings = append(output.Warnings, fmt.Sprintf("media playlist of stream %s could not be followed: %s",
+				variant.streamID, p.redactor.redactError(err)))
+			byURI[variant.uri] = nil
+			continue
+		}
+		byURI[variant.uri] = parseVariantPlaylist(fetch.fetched.body, fetch.fetched.url)
+	}
+	for i := range output.Variants {
+		output.Variants[i].Playlist = byURI[output.Variants[i].URI]
+	}
+}
+
+// parseVariantPlaylist summarizes a variant media playlist fetched from playlistURL
+func parseVariantPlaylist(content, playlistURL string) *VariantPlaylist {
+	playlist := &VariantPlaylist{MediaPlaylist: *parseHLSMediaPlaylist(content, playlistURL)}
+
+	for lines := scanLines(content); lines.scan(); {
+		line := strings.TrimSpace(lines.line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			playlist.InitSegment = resolveHLSURI(playlistURL, parseHLSAttributes(line)["URI"])
+			playlist.Container = "fmp4"
+		case line != "" && !strings.HasPrefix(line, "#"):
+			// The first segment decides the container
+			if playlist.Container == "" {
+				playlist.Container = segmentContainer(line)
+			}
+			return playlist
+		}
+	}
+	return playlist
+}
+
+// segmentContainer returns the container of a segment URI by its extension ("" = unknown)
+func segmentContainer(uri string) string {
+	if parsed, err := url.Parse(uri); err == nil {
+		uri = parsed.Path
+	}
+	return segmentContainers[strings.ToLower(path.Ext(uri))]
+}
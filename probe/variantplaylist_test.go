@@ -0,0 +1,204 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseVariantPlaylist(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		container   string
+		initSegment string
+	}{
+		{
+			name:      "MPEG-TS segments",
+			content:   "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg1.ts?token=abc\n#EXTINF:6.0,\nseg2.ts\n",
+			container: "mpegts",
+		},
+		{
+			name:        "fMP4 with EXT-X-MAP",
+			content:     "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:4.0,\nseg1.m4s\n",
+			container:   "fmp4",
+			initSegment: "https://cdn.example.com/v/init.mp4",
+		},
+		{
+			name:        "EXT-X-MAP with a media segment extension",
+			content:     "#EXTM3U\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:4.0,\nseg1.ts\n",
+			container:   "fmp4",
+			initSegment: "https://cdn.example.com/v/init.mp4",
+		},
+		{
+			name:      "packed audio",
+			content:   "#EXTM3U\n#EXTINF:4.0,\nseg1.aac\n",
+			container: "packed_audio",
+		},
+		{
+			name:    "unknown extension",
+			content: "#EXTM3U\n#EXTINF:4.0,\nsegment/1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			playlist := parseVariantPlaylist(tt.content, "https://cdn.example.com/v/index.m3u8")
+			if playlist.Container != tt.container {
+				t.Errorf("Expected container %q, got %q", tt.container, playlist.Container)
+			}
+			if playlist.InitSegment != tt.initSegment {
+				t.Errorf("Expected init segment %q, got %q", tt.initSegment, playlist.InitSegment)
+			}
+		})
+	}
+}
+
+func TestFollowVariants(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2"
+720p.m3u8?token=secret
+#EXT-X-STREAM-INF:BANDWIDTH=3000000,RESOLUTION=1920x1080,CODECS="hvc1.1.6.L120.90,mp4a.40.2"
+1080p/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=500000,RESOLUTION=640x360,CODECS="avc1.4d401e,mp4a.40.2"
+missing.m3u8
+`))
+		case "/720p.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:6.0,\nseg1.ts\n#EXTINF:6.0,\nseg2.ts\n#EXTINF:3.5,\nseg3.ts\n#EXT-X-ENDLIST\n"))
+		case "/1080p/index.m3u8":
+			w.Write([]byte(`#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key?asset=1",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXT-X-MAP:URI="init.mp4?token=secret"
+#EXTINF:4.0,
+seg100.m4s
+#EXTINF:4.0,
+seg101.m4s
+`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	opts := &ProbeOptions{DisableCamouflage: true, FollowVariants: true}
+	output, err := NewProber(opts).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(output.Variants) != 3 {
+		t.Fatalf("Expected 3 variants, got %d", len(output.Variants))
+	}
+
+	vod := output.Variants[0].Playlist
+	if vod == nil {
+		t.Fatalf("Expected the 720p playlist to be followed")
+	}
+	if vod.Container != "mpegts" || vod.Type != MediaPlaylistVOD || vod.Segments != 3 || vod.TotalDuration != 15.5 {
+		t.Errorf("Unexpected 720p playlist %+v", vod)
+	}
+
+	live := output.Variants[1].Playlist
+	if live == nil {
+		t.Fatalf("Expected the 1080p playlist to be followed")
+	}
+	if live.Container != "fmp4" || live.InitSegment != server.URL+"/1080p/init.mp4?token=secret" {
+		t.Errorf("Expected an fMP4 playlist with its init segment, got %+v", live)
+	}
+	if live.Type != MediaPlaylistLive || live.MediaSequence != 100 || live.EncryptedSegments != 2 {
+		t.Errorf("Unexpected 1080p playlist %+v", live)
+	}
+	if len(live.Encryption) != 1 || live.Encryption[0].Method != "SAMPLE-AES" {
+		t.Errorf("Expected a SAMPLE-AES key, got %+v", live.Encryption)
+	}
+
+	if output.Variants[2].Playlist != nil {
+		t.Errorf("Expected no playlist for the missing variant, got %+v", output.Variants[2].Playlist)
+	}
+	if len(output.Warnings) != 1 || !strings.HasPrefix(output.Warnings[0], "media playlist of stream 0:4 could not be fetched") {
+		t.Errorf("Expected a warning for the missing variant, got %v", output.Warnings)
+	}
+
+	output.makeDeterministic()
+	if live.InitSegment != server.URL+"/1080p/init.mp4" {
+		t.Errorf("Expected the deterministic init segment without its query, got %q", live.InitSegment)
+	}
+}
+
+func TestFollowVariantsDisabled(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720\n720p.m3u8\n"))
+	}))
+	defer server.Close()
+
+	output, err := NewProber(&ProbeOptions{DisableCamouflage: true}).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requests != 1 || output.Variants[0].Playlist != nil {
+		t.Errorf("Expected only the master playlist to be fetched, got %d requests", requests)
+	}
+}
+
+func TestVariantPlaylistsFetchedOnce(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/master.m3u8":
+			w.Write([]byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1000000,RESOLUTION=1280x720,CODECS="avc1.4d401f,mp4a.40.2"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=500000,RESOLUTION=640x360,CODECS="avc1.4d401e,mp4a.40.2"
+missing.m3u8
+`))
+		case "/720p.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-BITRATE:900\n#EXTINF:6.0,\nseg1.ts\n#EXT-X-ENDLIST\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	opts := &ProbeOptions{
+		DisableCamouflage: true,
+		AnalyzeBitrates:   true,
+		FollowVariants:    true,
+		RulePacks:         []string{RulePackAppleHLS},
+	}
+	output, err := NewProber(opts).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, path := range []string{"/720p.m3u8", "/missing.m3u8"} {
+		if requests[path] != 1 {
+			t.Errorf("Expected 1 request for %s, got %d", path, requests[path])
+		}
+	}
+	var warnings []string
+	for _, warning := range output.Warnings {
+		if strings.Contains(warning, "missing.m3u8") || strings.Contains(warning, "stream 0:2") {
+			warnings = append(warnings, warning)
+		}
+	}
+	if len(warnings) != 1 {
+		t.Errorf("Expected a single warning for the missing playlist, got %v", warnings)
+	}
+	if len(output.Bitrates) != 1 || output.Variants[0].Playlist == nil {
+		t.Errorf("Expected the 720p playlist to be analyzed and followed, got %+v and %+v", output.Bitrates, output.Variants[0])
+	}
+}
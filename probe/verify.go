@@ -0,0 +1,321 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tolerance bounds the accepted difference between an expected and an
+// actual numeric field
+type Tolerance struct {
+	// Relative is a fraction of the expected value (0.1 = 10%)
+	Relative float64 `json:"relative,omitempty"`
+
+	// Absolute is a difference in the unit of the field
+	Absolute float64 `json:"absolute,omitempty"`
+}
+
+// allows reports whether actual is within the tolerance of expected
+func (t Tolerance) allows(expected, actual float64) bool {
+	diff := math.Abs(actual - expected)
+	return diff <= t.Absolute || diff <= t.Relative*math.Abs(expected)
+}
+
+// String formats the tolerance as parsed by ParseTolerances
+func (t Tolerance) String() string {
+	if t.Relative > 0 {
+		return strconv.FormatFloat(t.Relative*100, 'f', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(t.Absolute, 'f', -1, 64)
+}
+
+// ParseTolerances parses comma-separated field tolerances such as
+// "bitrate=10%,frame_rate=0.01": a percentage of the expected value, or an
+// absolute difference. Fields are JSON field names; see VerifyOptions.
+func ParseTolerances(spec string) (map[string]Tolerance, error) {
+	tolerances := make(map[string]Tolerance)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		field, value, ok := strings.Cut(entry, "=")
+		field = strings.TrimSpace(field)
+		value = strings.TrimSpace(value)
+		if !ok || field == "" || value == "" {
+			return nil, NewValidationError(fmt.Sprintf("invalid tolerance %q: expected FIELD=VALUE or FIELD=PERCENT%%", entry))
+		}
+
+		var tolerance Tolerance
+		percent, relative := strings.CutSuffix(value, "%")
+		number, err := strconv.ParseFloat(percent, 64)
+		if err != nil || number < 0 || math.IsInf(number, 0) || math.IsNaN(number) {
+			return nil, NewValidationError(fmt.Sprintf("invalid tolerance %q: %q is not a non-negative number", entry, value))
+		}
+		if relative {
+			tolerance.Relative = number / 100
+		} else {
+			tolerance.Absolute = number
+		}
+		tolerances[field] = tolerance
+	}
+	return tolerances, nil
+}
+
+// VerifyOptions configures VerifyOutput
+type VerifyOptions struct {
+	// Tolerances bound the accepted differences of numeric fields, by JSON
+	// field name (nil = exact comparison). A name also matches the fields
+	// ending with it ("bitrate" matches "declared_bitrate"), underscores
+	// ignored ("bitrate" matches "bit_rate"); "bitrate" also matches the
+	// bandwidth fields. Numbers in strings ("3000 kb/s") are compared when
+	// both values have the same unit.
+	Tolerances map[string]Tolerance
+
+	// Ignore lists JSON field names that are not compared, wherever they appear
+	Ignore []string
+}
+
+// Difference is a field of the output that differs from the reference
+type Difference struct {
+	// Path locates the field, e.g. "streams[2].bit_rate"
+	Path string `json:"path"`
+
+	// Expected and Actual are the JSON values of the reference and of the
+	// probe (absent for a field only one of them has)
+	Expected json.RawMessage `json:"expected,omitempty"`
+	Actual   json.RawMessage `json:"actual,omitempty"`
+
+	// Tolerance is the tolerance the numeric difference exceeds
+	Tolerance string `json:"tolerance,omitempty"`
+}
+
+// String formats the difference for terminals
+func (d Difference) String() string {
+	switch {
+	case d.Expected == nil:
+		return fmt.Sprintf("%s: unexpected %s", d.Path, d.Actual)
+	case d.Actual == nil:
+		return fmt.Sprintf("%s: missing, expected %s", d.Path, d.Expected)
+	case d.Tolerance != "":
+		return fmt.Sprintf("%s: expected %s, got %s (tolerance %s)", d.Path, d.Expected, d.Actual, d.Tolerance)
+	default:
+		return fmt.Sprintf("%s: expected %s, got %s", d.Path, d.Expected, d.Actual)
+	}
+}
+
+// Verification is the comparison of a probe output with a reference output
+type Verification struct {
+	// Passed reports that no field differs beyond its tolerance
+	Passed      bool         `json:"passed"`
+	Differences []Difference `json:"differences,omitempty"`
+}
+
+// VerifyOutput compares output with expected, a reference output as printed
+// by goprobe (ideally with ProbeOptions.Deterministic, so volatile fields
+// are omitted), for release gating of packager and encoder changes. Objects
+// are compared field by field and arrays element by element, so streams
+// are matched by position.
+func VerifyOutput(expected []byte, output *Output, opts *VerifyOptions) (*Verification, error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+
+	var want interface{}
+	decoder := json.NewDecoder(bytes.NewReader(expected))
+	decoder.UseNumber()
+	if err := decoder.Decode(&want); err != nil {
+		return nil, NewValidationError(fmt.Sprintf("invalid reference output: %v", err))
+	}
+
+	data, err := output.OutputJSON()
+	if err != nil {
+		return nil, err
+	}
+	var got interface{}
+	decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&got); err != nil {
+		return nil, err
+	}
+
+	v := &verifier{tolerances: opts.Tolerances, ignore: make(map[string]bool)}
+	for _, field := range opts.Ignore {
+		v.ignore[strings.TrimSpace(field)] = true
+	}
+	v.compare("", "", want, got)
+	return &Verification{Passed: len(v.differences) == 0, Differences: v.differences}, nil
+}
+
+// verifier accumulates the differences of a VerifyOutput comparison
+type verifier struct {
+	tolerances  map[string]Tolerance
+	ignore      map[string]bool
+	differences []Difference
+}
+
+// compare compares the values of the field named key at path
+func (v *verifier) compare(path, key string, want, got interface{}) {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		if g, ok := got.(map[string]interface{}); ok {
+			v.compareObjects(path, w, g)
+			return
+		}
+	case []interface{}:
+		if g, ok := got.([]interface{}); ok {
+			for i := 0; i < len(w) || i < len(g); i++ {
+				element := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(g):
+					v.add(Difference{Path: element, Expected: rawJSON(w[i])})
+				case i >= len(w):
+					v.add(Difference{Path: element, Actual: rawJSON(g[i])})
+				default:
+					v.compare(element, key, w[i], g[i])
+				}
+			}
+			return
+		}
+	default:
+		if equalJSONScalars(want, got) {
+			return
+		}
+		if tolerance, ok := v.tolerance(key); ok {
+			if expected, actual, ok := comparableNumbers(want, got); ok {
+				if tolerance.allows(expected, actual) {
+					return
+				}
+				v.add(Difference{Path: path, Expected: rawJSON(want), Actual: rawJSON(got), Tolerance: tolerance.String()})
+				return
+			}
+		}
+	}
+	v.add(Difference{Path: path, Expected: rawJSON(want), Actual: rawJSON(got)})
+}
+
+// compareObjects compares two objects field by field, in field name order
+func (v *verifier) compareObjects(path string, want, got map[string]interface{}) {
+	keys := make([]string, 0, len(want)+len(got))
+	for key := range want {
+		keys = append(keys, key)
+	}
+	for key := range got {
+		if _, ok := want[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if v.ignore[key] {
+			continue
+		}
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+		w, inWant := want[key]
+		g, inGot := got[key]
+		switch {
+		case !inGot:
+			v.add(Difference{Path: field, Expected: rawJSON(w)})
+		case !inWant:
+			v.add(Difference{Path: field, Actual: rawJSON(g)})
+		default:
+			v.compare(field, key, w, g)
+		}
+	}
+}
+
+// add records a difference
+func (v *verifier) add(difference Difference) {
+	v.differences = append(v.differences, difference)
+}
+
+// tolerance returns the tolerance of the field named key
+func (v *verifier) tolerance(key string) (Tolerance, bool) {
+	if tolerance, ok := v.tolerances[key]; ok {
+		return tolerance, true
+	}
+	normalized := strings.ReplaceAll(key, "_", "")
+	names := make([]string, 0, len(v.tolerances))
+	for name := range v.tolerances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		suffix := strings.ReplaceAll(name, "_", "")
+		if suffix != "" && (strings.HasSuffix(normalized, suffix) || (suffix == "bitrate" && strings.HasSuffix(normalized, "bandwidth"))) {
+			return v.tolerances[name], true
+		}
+	}
+	return Tolerance{}, false
+}
+
+// comparableNumbers returns the numbers of two JSON values: numbers, or
+// strings starting with a number and sharing the rest ("3000 kb/s")
+func comparableNumbers(want, got interface{}) (float64, float64, bool) {
+	switch w := want.(type) {
+	case json.Number:
+		g, ok := got.(json.Number)
+		if !ok {
+			return 0, 0, false
+		}
+		expected, err1 := w.Float64()
+		actual, err2 := g.Float64()
+		return expected, actual, err1 == nil && err2 == nil
+	case string:
+		g, ok := got.(string)
+		if !ok {
+			return 0, 0, false
+		}
+		expected, wantUnit, ok1 := leadingNumber(w)
+		actual, gotUnit, ok2 := leadingNumber(g)
+		return expected, actual, ok1 && ok2 && wantUnit == gotUnit
+	}
+	return 0, 0, false
+}
+
+// leadingNumber splits a string into its leading decimal number and the rest
+func leadingNumber(s string) (float64, string, bool) {
+	end := 0
+	for end < len(s) && (s[end] == '-' || s[end] == '.' || s[end] >= '0' && s[end] <= '9') {
+		end++
+	}
+	number, err := strconv.ParseFloat(s[:end], 64)
+	return number, s[end:], err == nil
+}
+
+// equalJSONScalars reports whether two decoded scalar JSON values are equal
+func equalJSONScalars(want, got interface{}) bool {
+	switch w := want.(type) {
+	case json.Number:
+		g, ok := got.(json.Number)
+		if !ok {
+			return false
+		}
+		if w == g {
+			return true
+		}
+		a, err1 := w.Float64()
+		b, err2 := g.Float64()
+		return err1 == nil && err2 == nil && a == b
+	default:
+		return want == got
+	}
+}
+
+// rawJSON encodes a decoded JSON value
+func rawJSON(value interface{}) json.RawMessage {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
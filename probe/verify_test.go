@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTolerances(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected map[string]Tolerance
+		err      string
+	}{
+		{spec: "", expected: map[string]Tolerance{}},
+		{spec: "bitrate=10%", expected: map[string]Tolerance{"bitrate": {Relative: 0.1}}},
+		{spec: "bitrate=10%, frame_rate=0.01", expected: map[string]Tolerance{"bitrate": {Relative: 0.1}, "frame_rate": {Absolute: 0.01}}},
+		{spec: "bitrate", err: `invalid tolerance "bitrate"`},
+		{spec: "bitrate=ten%", err: `"ten%" is not a non-negative number`},
+		{spec: "bitrate=-1", err: `"-1" is not a non-negative number`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseTolerances(tt.spec)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestVerifyOutput(t *testing.T) {
+	output := &Output{
+		Streams: []StreamInfo{
+			{StreamID: "0:0", Type: "Video", Codec: "h264", Resolution: "1920x1080", FrameRate: "25", BitRate: "5000 kb/s"},
+			{StreamID: "0:1", Type: "Audio", Codec: "aac", BitRate: "128 kb/s", Language: "eng"},
+		},
+		Features: []string{"segment-template"},
+	}
+	reference := func(videoBitRate, features string) string {
+		return `{"streams": [
+{"stream_id": "0:0", "type": "Video", "codec": "h264", "resolution": "1920x1080", "frame_rate": "25", "bit_rate": "` + videoBitRate + `"},
+{"stream_id": "0:1", "type": "Audio", "codec": "aac", "bit_rate": "128 kb/s", "language": "eng"}],
+"features": [` + features + `]}`
+	}
+
+	tests := []struct {
+		name      string
+		expected  string
+		opts      *VerifyOptions
+		different []string
+	}{
+		{
+			name:     "identical",
+			expected: reference("5000 kb/s", `"segment-template"`),
+		},
+		{
+			name:      "bit rate without tolerance",
+			expected:  reference("4800 kb/s", `"segment-template"`),
+			different: []string{`streams[0].bit_rate: expected "4800 kb/s", got "5000 kb/s"`},
+		},
+		{
+			name:     "bit rate within tolerance",
+			expected: reference("4800 kb/s", `"segment-template"`),
+			opts:     &VerifyOptions{Tolerances: map[string]Tolerance{"bitrate": {Relative: 0.1}}},
+		},
+		{
+			name:      "bit rate beyond tolerance",
+			expected:  reference("4000 kb/s", `"segment-template"`),
+			opts:      &VerifyOptions{Tolerances: map[string]Tolerance{"bitrate": {Relative: 0.1}}},
+			different: []string{`streams[0].bit_rate: expected "4000 kb/s", got "5000 kb/s" (tolerance 10%)`},
+		},
+		{
+			name:      "different units",
+			expected:  reference("5 Mb/s", `"segment-template"`),
+			opts:      &VerifyOptions{Tolerances: map[string]Tolerance{"bit_rate": {Relative: 0.5}}},
+			different: []string{`streams[0].bit_rate: expected "5 Mb/s", got "5000 kb/s"`},
+		},
+		{
+			name:      "missing and unexpected elements",
+			expected:  reference("5000 kb/s", `"segment-template", "live"`),
+			different: []string{`features[1]: missing, expected "live"`},
+		},
+		{
+			name:     "ignored fields",
+			expected: reference("1 kb/s", `"live"`),
+			opts:     &VerifyOptions{Ignore: []string{"bit_rate", "features"}},
+		},
+		{
+			name:     "missing and unexpected fields",
+			expected: `{"streams": [], "warnings": ["stale"]}`,
+			opts:     &VerifyOptions{Ignore: []string{"streams"}},
+			different: []string{
+				`features: unexpected ["segment-template"]`,
+				`warnings: missing, expected ["stale"]`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verification, err := VerifyOutput([]byte(tt.expected), output, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var got []string
+			for _, difference := range verification.Differences {
+				got = append(got, difference.String())
+			}
+			if !reflect.DeepEqual(got, tt.different) {
+				t.Errorf("Expected differences %q, got %q", tt.different, got)
+			}
+			if verification.Passed != (len(tt.different) == 0) {
+				t.Errorf("Expected passed %v, got %v", len(tt.different) == 0, verification.Passed)
+			}
+		})
+	}
+
+	if _, err := VerifyOutput([]byte("not json"), output, nil); err == nil {
+		t.Error("Expected an error for an invalid reference output")
+	}
+}
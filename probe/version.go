@@ -0,0 +1,101 @@
+package probe
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// modulePath is the import path of this module, used to find its version in
+// the build info of programs that import the package
+const modulePath = "github.com/erratbi/goprobe"
+
+// Build metadata, normally set at link time:
+//
+//	go build -ldflags "-X github.com/erratbi/goprobe/probe.Version=v1.4.0 -X github.com/erratbi/goprobe/probe.Commit=$(git rev-parse HEAD)"
+//
+// Empty values fall back to the module version and VCS stamps embedded by the
+// Go toolchain (see runtime/debug.ReadBuildInfo).
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// ProgramVersion identifies the build of goprobe that produced an output
+type ProgramVersion struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built from a tree with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	programVersionOnce sync.Once
+	programVersion     ProgramVersion
+)
+
+// BuildInfo returns the version and build metadata of this goprobe build
+func BuildInfo() ProgramVersion {
+	programVersionOnce.Do(func() {
+		programVersion = readBuildInfo()
+	})
+	return programVersion
+}
+
+// readBuildInfo combines the link-time variables with the build info
+// embedded by the Go toolchain
+func readBuildInfo() ProgramVersion {
+	version := ProgramVersion{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version.Version == "" {
+			version.Version = moduleVersion(info)
+		}
+		// VCS stamps describe the main module, so they only apply to the goprobe CLI itself
+		if info.Main.Path == modulePath {
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					if version.Commit == "" {
+						version.Commit = setting.Value
+					}
+				case "vcs.time":
+					if version.BuildTime == "" {
+						version.BuildTime = setting.Value
+					}
+				case "vcs.modified":
+					version.Modified = setting.Value == "true"
+				}
+			}
+		}
+	}
+
+	if version.Version == "" {
+		version.Version = "(devel)"
+	}
+	return version
+}
+
+// moduleVersion returns the version of this module recorded in info, whether
+// it is the main module or a dependency
+func moduleVersion(info *debug.BuildInfo) string {
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestModuleVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     *debug.BuildInfo
+		expected string
+	}{
+		{
+			name:     "main module",
+			info:     &debug.BuildInfo{Main: debug.Module{Path: modulePath, Version: "v1.2.0"}},
+			expected: "v1.2.0",
+		},
+		{
+			name: "dependency",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/monitor"},
+				Deps: []*debug.Module{{Path: modulePath, Version: "v1.3.0"}},
+			},
+			expected: "v1.3.0",
+		},
+		{
+			name: "replaced dependency",
+			info: &debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/monitor"},
+				Deps: []*debug.Module{{Path: modulePath, Version: "v1.3.0", Replace: &debug.Module{Path: "../goprobe", Version: "v1.3.1"}}},
+			},
+			expected: "v1.3.1",
+		},
+		{
+			name:     "not found",
+			info:     &debug.BuildInfo{Main: debug.Module{Path: "example.com/monitor"}},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := moduleVersion(tt.info); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildInfoLinkerVariables(t *testing.T) {
+	defer func(version, commit string) { Version, Commit = version, commit }(Version, Commit)
+	Version, Commit = "v2.0.0", "abc123"
+
+	info := readBuildInfo()
+	if info.Version != "v2.0.0" {
+		t.Errorf("Expected %q, got %q", "v2.0.0", info.Version)
+	}
+	if info.Commit != "abc123" {
+		t.Errorf("Expected %q, got %q", "abc123", info.Commit)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected %q, got %q", runtime.Version(), info.GoVersion)
+	}
+}
+
+func TestOutputProgramVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(watchTestManifest))
+	}))
+	defer server.Close()
+
+	output, err := NewProber(nil).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.ProgramVersion == nil || output.ProgramVersion.Version == "" {
+		t.Fatalf("Expected program version, got %+v", output.ProgramVersion)
+	}
+
+	output, err = NewProber(&ProbeOptions{Deterministic: true}).Probe(context.Background(), server.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.ProgramVersion != nil {
+		t.Errorf("Expected no program version in deterministic mode, got %+v", output.ProgramVersion)
+	}
+}
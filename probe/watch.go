@@ -0,0 +1,480 @@
+package probe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// EventType identifies the kind of watch event
+type EventType string
+
+const (
+	// EventManifestUpdated is emitted when the manifest content changes (and for the first fetch)
+	EventManifestUpdated EventType = "manifest_updated"
+	// EventStreamAdded is emitted for each stream that appeared since the previous update
+	EventStreamAdded EventType = "stream_added"
+	// EventStreamRemoved is emitted for each stream that disappeared since the previous update
+	EventStreamRemoved EventType = "stream_removed"
+	// EventErrorOccurred is emitted when a poll fails
+	EventErrorOccurred EventType = "error_occurred"
+	// EventRecovered is emitted on the first successful poll after a failure
+	EventRecovered EventType = "recovered"
+	// EventManifestStale is emitted once when the manifest has not changed for
+	// WatchOptions.StaleFactor expected update periods (frozen manifest)
+	EventManifestStale EventType = "manifest_stale"
+	// EventStreamEnded is emitted once when a live manifest ends: EXT-X-ENDLIST
+	// appears in an HLS playlist, or an MPD switches from dynamic to static
+	EventStreamEnded EventType = "stream_ended"
+)
+
+// Event is a change observed by a Watcher
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	URL  string    `json:"url"`
+
+	// Output is the parsed manifest (ManifestUpdated, StreamEnded)
+	Output *Output `json:"output,omitempty"`
+
+	// Stream is the added or removed stream (StreamAdded, StreamRemoved)
+	Stream *StreamInfo `json:"stream,omitempty"`
+
+	// Cadence is the observed update cadence (ManifestUpdated, ManifestStale)
+	Cadence *Cadence `json:"cadence,omitempty"`
+
+	// Err is the poll failure (ErrorOccurred)
+	Err error `json:"-"`
+}
+
+// WatchOptions configures a Watcher
+type WatchOptions struct {
+	// Interval between manifest polls (default: 10s)
+	Interval time.Duration
+
+	// BufferSize is the capacity of the events channel (default: 16).
+	// Polling pauses while the channel is full.
+	BufferSize int
+
+	// StaleFactor reports the manifest stale after this many expected update
+	// periods (minimumUpdatePeriod or target duration) without a change (default: 3)
+	StaleFactor float64
+
+	// Store persists watch state after every poll and restores it on start,
+	// so a restarted watcher does not report an unchanged manifest as updated
+	// (nil = no persistence)
+	Store Store
+
+	// StoreKey identifies the watch in Store (default: the manifest URL).
+	// Set it when the URL contains rotating tokens.
+	StoreKey string
+
+	// Alerts receive an alert for each selected event of a live watch (nil =
+	// no alerting). Replays do not alert.
+	Alerts []AlertSink
+
+	// AlertEvents selects the events that raise alerts (nil = DefaultAlertEvents)
+	AlertEvents []EventType
+
+	// Sinks receive a Record for each event of a live watch (nil = none).
+	// Replays do not write to sinks.
+	Sinks []OutputSink
+}
+
+// DefaultWatchOptions returns sensible defaults for watching
+func DefaultWatchOptions() *WatchOptions {
+	return &WatchOptions{
+		Interval:   10 * time.Second,
+		BufferSize: 16,
+	}
+}
+
+// Watcher polls a manifest and emits events when it changes
+type Watcher struct {
+	prober *Prober
+	url    string
+	opts   *WatchOptions
+	events chan Event
+	done   chan struct{}
+	err    error
+
+	// alerted reports that a failure alert is open until recovery
+	alerted bool
+}
+
+// Watch starts polling manifestURL until ctx is cancelled.
+// Events are delivered on the returned Watcher's Events channel.
+func (p *Prober) Watch(ctx context.Context, manifestURL string, opts *WatchOptions) *Watcher {
+	w := newWatcher(p, manifestURL, opts)
+	go w.run(withLogger(ctx, p.logger))
+	return w
+}
+
+// newWatcher creates a watcher with defaults applied to opts
+func newWatcher(p *Prober, manifestURL string, opts *WatchOptions) *Watcher {
+	defaults := DefaultWatchOptions()
+	if opts == nil {
+		opts = defaults
+	} else {
+		copied := *opts
+		opts = &copied
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaults.Interval
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaults.BufferSize
+	}
+
+	return &Watcher{
+		prober: p,
+		url:    manifestURL,
+		opts:   opts,
+		events: make(chan Event, opts.BufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Events returns the channel of watch events. It is closed when the watcher stops.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Done returns a channel that is closed when the watcher stops
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Err returns the reason the watcher stopped (nil while running)
+func (w *Watcher) Err() error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+		return nil
+	}
+}
+
+// run polls the manifest until ctx is cancelled
+func (w *Watcher) run(ctx context.Context) {
+	defer close(w.done)
+	defer close(w.events)
+
+	logInfo(ctx, "Starting manifest watch", map[string]interface{}{
+		"url":      w.url,
+		"interval": w.opts.Interval,
+	})
+
+	state := newWatchState(w.url)
+	state.cadence.staleFactor = w.opts.StaleFactor
+	w.restore(ctx, state)
+
+	ticker := w.prober.clock.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		events := w.poll(ctx, state)
+		w.save(ctx, state)
+		w.alert(ctx, events)
+		for _, event := range events {
+			writeSinks(ctx, w.opts.Sinks, w.prober.redactor, watchRecord(event, w.prober.redactor))
+		}
+		if !w.emit(ctx, events) {
+			return
+		}
+
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			w.err = ctx.Err()
+			return
+		}
+	}
+}
+
+// storeKey returns the key of the watch in the store
+func (w *Watcher) storeKey() string {
+	if w.opts.StoreKey != "" {
+		return w.opts.StoreKey
+	}
+	return w.url
+}
+
+// restore loads persisted state into state and the origin's circuit breaker
+func (w *Watcher) restore(ctx context.Context, state *watchState) {
+	if w.opts.Store == nil {
+		return
+	}
+
+	snapshot, err := w.opts.Store.Load(ctx, w.storeKey())
+	if err != nil {
+		logWarn(ctx, "Failed to load watch state", map[string]interface{}{
+			"url":   w.url,
+			"error": err.Error(),
+		})
+		return
+	}
+	if snapshot == nil {
+		return
+	}
+
+	state.restore(snapshot)
+	if cb := w.prober.circuitBreaker(w.url); cb != nil && snapshot.Circuit != nil {
+		cb.Restore(*snapshot.Circuit)
+	}
+
+	logInfo(ctx, "Restored watch state", map[string]interface{}{
+		"url":      w.url,
+		"saved_at": snapshot.SavedAt,
+	})
+}
+
+// save persists state and the origin's circuit breaker
+func (w *Watcher) save(ctx context.Context, state *watchState) {
+	if w.opts.Store == nil {
+		return
+	}
+
+	snapshot := state.snapshot(w.prober.clock.Now())
+	if cb := w.prober.circuitBreaker(w.url); cb != nil {
+		circuit := cb.Snapshot()
+		snapshot.Circuit = &circuit
+	}
+
+	if err := w.opts.Store.Save(ctx, w.storeKey(), snapshot); err != nil {
+		logWarn(ctx, "Failed to save watch state", map[string]interface{}{
+			"url":   w.url,
+			"error": err.Error(),
+		})
+	}
+}
+
+// emit delivers events, returning false if ctx was cancelled first
+func (w *Watcher) emit(ctx context.Context, events []Event) bool {
+	for _, event := range events {
+		select {
+		case w.events <- event:
+		case <-ctx.Done():
+			w.err = ctx.Err()
+			return false
+		}
+	}
+	return true
+}
+
+// poll fetches and parses the manifest once and returns the resulting events
+func (w *Watcher) poll(ctx context.Context, state *watchState) []Event {
+	ctx, release, err := w.prober.scheduler.schedule(ctx, SchedulerWatch)
+	if err != nil {
+		return nil
+	}
+	defer release()
+
+	now := w.prober.clock.Now()
+
+	fetched, delta, err := w.fetchPlaylist(ctx, state, now)
+	if err != nil {
+		return state.fail(now, w.prober.redactor.redactError(err))
+	}
+
+	if !state.changed(fetched.body) {
+		state.playlistTime = now
+		return state.unchanged(now)
+	}
+
+	output, err := w.prober.analyze(ctx, fetched)
+	if err != nil {
+		return state.fail(now, w.prober.redactor.redactError(err))
+	}
+	if delta != nil && output.MediaPlaylist != nil {
+		output.MediaPlaylist.DeltaUpdate = delta
+	}
+
+	return state.update(now, fetched.body, output)
+}
+
+// watchState tracks the last observed manifest and turns observations into events.
+// It is shared by live watching and any other source of manifest snapshots.
+type watchState struct {
+	url     string
+	hash    string
+	last    []StreamInfo
+	streams map[string][]StreamInfo
+	failing bool
+	started bool
+	live    bool
+	cadence cadenceTracker
+
+	// playlist is the last full HLS media playlist advertising delta
+	// updates, fetched at playlistTime, with its CAN-SKIP-UNTIL in seconds
+	playlist     string
+	playlistTime time.Time
+	canSkipUntil float64
+}
+
+// newWatchState creates an empty watch state for url
+func newWatchState(url string) *watchState {
+	return &watchState{url: url}
+}
+
+// changed reports whether body differs from the last successfully parsed manifest
+func (s *watchState) changed(body string) bool {
+	return !s.started || manifestHash(body) != s.hash
+}
+
+// fail records a failed observation
+func (s *watchState) fail(now time.Time, err error) []Event {
+	s.failing = true
+	return []Event{{Type: EventErrorOccurred, Time: now, URL: s.url, Err: err}}
+}
+
+// unchanged records a successful observation of an unchanged manifest
+func (s *watchState) unchanged(now time.Time) []Event {
+	events := s.recover(now, nil)
+	if cadence, stale := s.cadence.checkStale(now); stale {
+		events = append(events, Event{Type: EventManifestStale, Time: now, URL: s.url, Cadence: &cadence})
+	}
+	return events
+}
+
+// update records a successful observation of a changed manifest
+func (s *watchState) update(now time.Time, body string, output *Output) []Event {
+	events := s.recover(now, nil)
+	cadence := s.cadence.update(now, output.Timing)
+	events = append(events, Event{Type: EventManifestUpdated, Time: now, URL: s.url, Output: output, Cadence: &cadence})
+
+	current := groupStreams(output.Streams)
+	if s.started {
+		events = append(events, diffStreams(now, s.url, s.streams, current, EventStreamRemoved)...)
+		events = append(events, diffStreams(now, s.url, current, s.streams, EventStreamAdded)...)
+	}
+
+	if s.live && output.ended {
+		events = append(events, Event{Type: EventStreamEnded, Time: now, URL: s.url, Output: output})
+	}
+	s.live = isLive(output)
+
+	s.playlist, s.canSkipUntil = "", 0
+	if output.MediaPlaylist != nil && output.MediaPlaylist.CanSkipUntil > 0 {
+		s.playlist, s.playlistTime, s.canSkipUntil = body, now, output.MediaPlaylist.CanSkipUntil
+	}
+
+	s.hash = manifestHash(body)
+	s.last = output.Streams
+	s.streams = current
+	s.started = true
+	return events
+}
+
+// isLive reports whether output is a presentation still being published,
+// from its format block (see Format.IsLive). Master playlists whose media
+// playlists were not followed fall back to the "live" feature.
+func isLive(output *Output) bool {
+	if output.Format != nil && output.Format.IsLive != nil {
+		return *output.Format.IsLive
+	}
+	return containsString(output.Features, "live")
+}
+
+// snapshot returns the persistable part of the state, saved at now
+func (s *watchState) snapshot(now time.Time) *WatchSnapshot {
+	snapshot := &WatchSnapshot{
+		Hash:    s.hash,
+		Streams: s.last,
+		Failing: s.failing,
+		Live:    s.live,
+		Timing:  s.cadence.timing,
+		SavedAt: now,
+	}
+	if s.cadence.cadence.Updates > 0 {
+		cadence := s.cadence.cadence
+		snapshot.Cadence = &cadence
+	}
+	return snapshot
+}
+
+// restore resumes from a persisted snapshot
+func (s *watchState) restore(snapshot *WatchSnapshot) {
+	s.failing = snapshot.Failing
+	s.live = snapshot.Live
+	if snapshot.Hash == "" {
+		return
+	}
+	s.hash = snapshot.Hash
+	s.last = snapshot.Streams
+	s.streams = groupStreams(snapshot.Streams)
+	s.started = true
+	s.cadence.restore(snapshot.Cadence, snapshot.Timing)
+}
+
+// recover appends a Recovered event if the previous observation failed
+func (s *watchState) recover(now time.Time, events []Event) []Event {
+	if s.failing {
+		s.failing = false
+		events = append(events, Event{Type: EventRecovered, Time: now, URL: s.url})
+	}
+	return events
+}
+
+// manifestHash returns a content hash of a manifest body
+func manifestHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// streamKey identifies a stream independently of its position in the output
+// and in the manifest, and of its manifest identifiers (HLS URIs often carry
+// rotating tokens)
+func streamKey(stream StreamInfo) string {
+	stream.StreamID = ""
+	stream.Source = nil
+	stream.NativeID = ""
+	key, _ := json.Marshal(stream)
+	return string(key)
+}
+
+// groupStreams groups streams by key, keeping duplicates
+func groupStreams(streams []StreamInfo) map[string][]StreamInfo {
+	grouped := make(map[string][]StreamInfo)
+	for _, stream := range streams {
+		key := streamKey(stream)
+		grouped[key] = append(grouped[key], stream)
+	}
+	return grouped
+}
+
+// StreamChanges returns the streams of o that previous lacks and the streams
+// of previous that o lacks, matched like the stream events of a Watcher
+func (o *Output) StreamChanges(previous *Output) (added, removed []StreamInfo) {
+	from := groupStreams(previous.Streams)
+	to := groupStreams(o.Streams)
+	for _, event := range diffStreams(time.Time{}, "", to, from, EventStreamAdded) {
+		added = append(added, *event.Stream)
+	}
+	for _, event := range diffStreams(time.Time{}, "", from, to, EventStreamRemoved) {
+		removed = append(removed, *event.Stream)
+	}
+	return added, removed
+}
+
+// diffStreams emits an event of eventType for each stream in from missing in to
+func diffStreams(now time.Time, url string, from, to map[string][]StreamInfo, eventType EventType) []Event {
+	keys := make([]string, 0, len(from))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var events []Event
+	for _, key := range keys {
+		streams := from[key]
+		for i := len(to[key]); i < len(streams); i++ {
+			stream := streams[i]
+			events = append(events, Event{Type: eventType, Time: now, URL: url, Stream: &stream})
+		}
+	}
+	return events
+}
@@ -0,0 +1,264 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const watchTestManifest = `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2"
+720p.m3u8
+`
+
+const watchTestManifestUpdated = `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.64001f,mp4a.40.2"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2"
+1080p.m3u8
+`
+
+// manifestSequence serves a fixed sequence of responses, repeating the last one
+type manifestSequence struct {
+	mu        sync.Mutex
+	responses []string
+	served    int
+}
+
+func (m *manifestSequence) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	i := m.served
+	if i >= len(m.responses) {
+		i = len(m.responses) - 1
+	}
+	m.served++
+	body := m.responses[i]
+	m.mu.Unlock()
+
+	if body == "" {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte(body))
+}
+
+func TestWatcherEvents(t *testing.T) {
+	server := httptest.NewServer(&manifestSequence{
+		responses: []string{watchTestManifest, watchTestManifest, "", watchTestManifestUpdated},
+	})
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watcher := NewProber(nil).Watch(ctx, server.URL+"/master.m3u8", &WatchOptions{Interval: 10 * time.Millisecond})
+
+	expected := []EventType{
+		EventManifestUpdated,
+		EventErrorOccurred,
+		EventRecovered,
+		EventManifestUpdated,
+		EventStreamAdded,
+		EventStreamAdded,
+	}
+
+	var received []Event
+	for len(received) < len(expected) {
+		select {
+		case event := <-watcher.Events():
+			received = append(received, event)
+		case <-ctx.Done():
+			t.Fatalf("Timed out after %d events: %+v", len(received), received)
+		}
+	}
+
+	for i, eventType := range expected {
+		if received[i].Type != eventType {
+			t.Errorf("Event %d: expected %s, got %s", i, eventType, received[i].Type)
+		}
+	}
+
+	if received[1].Err == nil {
+		t.Error("Expected ErrorOccurred event to carry an error")
+	}
+
+	for _, event := range received[4:] {
+		if event.Stream == nil {
+			t.Fatal("Expected StreamAdded event to carry a stream")
+		}
+		if event.Stream.Type == "Video" && event.Stream.Resolution != "1920x1080" {
+			t.Errorf("Expected added video stream 1920x1080, got %s", event.Stream.Resolution)
+		}
+	}
+
+	cancel()
+	select {
+	case <-watcher.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected watcher to stop after cancellation")
+	}
+
+	if watcher.Err() != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", watcher.Err())
+	}
+
+	for range watcher.Events() {
+	}
+}
+
+func TestDiffStreams(t *testing.T) {
+	audio := StreamInfo{Type: "Audio", Codec: "aac"}
+	video := StreamInfo{Type: "Video", Codec: "h264", Resolution: "1280x720"}
+
+	before := groupStreams([]StreamInfo{video, audio, audio})
+	after := groupStreams([]StreamInfo{video, audio})
+
+	removed := diffStreams(time.Now(), "", before, after, EventStreamRemoved)
+	if len(removed) != 1 || removed[0].Stream.Type != "Audio" {
+		t.Errorf("Expected one removed audio stream, got %+v", removed)
+	}
+
+	added := diffStreams(time.Now(), "", after, before, EventStreamAdded)
+	if len(added) != 0 {
+		t.Errorf("Expected no added streams, got %+v", added)
+	}
+}
+
+func TestStreamChanges(t *testing.T) {
+	audio := StreamInfo{StreamID: "0:1", Type: "Audio", Codec: "aac"}
+	hd := StreamInfo{StreamID: "0:0", Type: "Video", Codec: "h264", Resolution: "1280x720"}
+	fhd := StreamInfo{StreamID: "0:2", Type: "Video", Codec: "h264", Resolution: "1920x1080"}
+
+	previous := &Output{Streams: []StreamInfo{hd, audio}}
+	moved := audio
+	moved.StreamID = "0:3"
+	current := &Output{Streams: []StreamInfo{fhd, hd, moved}}
+
+	added, removed := current.StreamChanges(previous)
+	if len(added) != 1 || added[0].Resolution != "1920x1080" {
+		t.Errorf("Expected the 1080p stream to be added, got %+v", added)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected no removed streams, got %+v", removed)
+	}
+
+	added, removed = previous.StreamChanges(current)
+	if len(added) != 0 || len(removed) != 1 || removed[0].StreamID != "0:2" {
+		t.Errorf("Expected the 1080p stream to be removed, got added %+v, removed %+v", added, removed)
+	}
+}
+
+func TestStreamKeyIgnoresSource(t *testing.T) {
+	stream := StreamInfo{StreamID: "0:0", Type: "Video", Codec: "h264", Source: &SourceRef{Tag: "EXT-X-STREAM-INF", Line: 3}}
+	moved := stream
+	moved.StreamID = "0:2"
+	moved.Source = &SourceRef{Tag: "EXT-X-STREAM-INF", Line: 7}
+
+	if streamKey(stream) != streamKey(moved) {
+		t.Errorf("Expected streams moved within the manifest to have the same key")
+	}
+}
+
+func TestWatchStateStreamEnded(t *testing.T) {
+	live := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:%d\n#EXTINF:6.0,\nseg%d.ts\n"
+	ended := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:2\n#EXTINF:6.0,\nseg2.ts\n#EXT-X-ENDLIST\n"
+	unsequenced := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg%d.ts\n"
+	vod := "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg0.ts\n#EXT-X-ENDLIST\n"
+	dynamic := `<MPD type="dynamic" publishTime="2024-06-01T12:00:00Z"><Period id="p0"/></MPD>`
+	static := `<MPD type="static"><Period id="p0"/></MPD>`
+
+	tests := []struct {
+		name      string
+		manifests []string
+		ended     int // index of the update that ends the stream (-1 = none)
+	}{
+		{name: "HLS ENDLIST", manifests: []string{fmt.Sprintf(live, 0, 0), fmt.Sprintf(live, 1, 1), ended, ended + "\n"}, ended: 2},
+		{name: "HLS ENDLIST without media sequence", manifests: []string{fmt.Sprintf(unsequenced, 0), fmt.Sprintf(unsequenced, 1), fmt.Sprintf(unsequenced, 1) + "#EXT-X-ENDLIST\n"}, ended: 2},
+		{name: "MPD dynamic to static", manifests: []string{dynamic, static}, ended: 1},
+		{name: "VOD from the start", manifests: []string{vod, vod + "\n"}, ended: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newWatchState("https://example.com/live")
+			now := time.Unix(0, 0)
+			for i, manifest := range tt.manifests {
+				output, err := parseManifestForTest(manifest)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				events := state.update(now, manifest, output)
+
+				var endedEvents []Event
+				for _, event := range events {
+					if event.Type == EventStreamEnded {
+						endedEvents = append(endedEvents, event)
+					}
+				}
+				if i == tt.ended {
+					if len(endedEvents) != 1 || endedEvents[0].Output != output {
+						t.Errorf("Update %d: expected one StreamEnded event with the output, got %+v", i, endedEvents)
+					}
+				} else if len(endedEvents) != 0 {
+					t.Errorf("Update %d: unexpected StreamEnded event", i)
+				}
+			}
+		})
+	}
+}
+
+func TestWatchStateRotatingTokens(t *testing.T) {
+	master := "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS=\"avc1.64001f,mp4a.40.2\"\n720p.m3u8?token=%s\n"
+
+	state := newWatchState("https://example.com/master.m3u8")
+	now := time.Unix(0, 0)
+	for i, token := range []string{"a", "b"} {
+		manifest := fmt.Sprintf(master, token)
+		output, err := parseManifestForTest(manifest)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, event := range state.update(now, manifest, output) {
+			if i > 0 && (event.Type == EventStreamAdded || event.Type == EventStreamRemoved) {
+				t.Errorf("Expected no stream changes when only the URI token rotates, got %s", event.Type)
+			}
+		}
+	}
+}
+
+func TestWatchStateStreamEndedAfterRestore(t *testing.T) {
+	state := newWatchState("https://example.com/live.mpd")
+	dynamic := `<MPD type="dynamic"><Period id="p0"/></MPD>`
+	output, _ := parseManifestForTest(dynamic)
+	state.update(time.Unix(0, 0), dynamic, output)
+
+	restored := newWatchState("https://example.com/live.mpd")
+	restored.restore(state.snapshot(time.Unix(1, 0)))
+
+	static := `<MPD type="static"><Period id="p0"/></MPD>`
+	output, _ = parseManifestForTest(static)
+	events := restored.update(time.Unix(2, 0), static, output)
+	if last := events[len(events)-1]; last.Type != EventStreamEnded {
+		t.Errorf("Expected StreamEnded after restore, got %+v", events)
+	}
+}
+
+// parseManifestForTest parses an HLS or MPD manifest, with its format block
+// as Probe reports it
+func parseManifestForTest(manifest string) (*Output, error) {
+	parse := parseMPDManifest
+	manifestURL := "https://example.com/live.mpd"
+	if manifestFormat(manifest) == "HLS" {
+		parse, manifestURL = parseHLSManifest, "https://example.com/live.m3u8"
+	}
+	output, err := parse(manifest, manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	output.Format = describeFormat(output, manifestFormat(manifest))
+	return output, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// loadConfig reads the config file at path ("" = probe.DefaultConfigPath)
+func loadConfig(path string) (*probe.Config, string, error) {
+	if path == "" {
+		var err error
+		if path, err = probe.DefaultConfigPath(); err != nil {
+			return nil, "", err
+		}
+	}
+	config, err := probe.LoadConfig(path)
+	return config, path, err
+}
+
+// overrideProfile returns the options of a profile with the options of the
+// flags set on the command line applied on top
+func overrideProfile(profile, flags *probe.ProbeOptions) *probe.ProbeOptions {
+	merged := *profile
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "proxy":
+			merged.ProxyURL = flags.ProxyURL
+		case "ua":
+			merged.UserAgent = flags.UserAgent
+		case "timeout":
+			merged.TimeoutSeconds = flags.TimeoutSeconds
+		case "no-compression":
+			merged.DisableCompression = flags.DisableCompression
+		case "no-camouflage":
+			merged.DisableCamouflage = flags.DisableCamouflage
+		case "check-content-type":
+			merged.ContentTypePolicy = flags.ContentTypePolicy
+		case "iframes":
+			merged.IFrameStreams = flags.IFrameStreams
+		case "audio-groups":
+			merged.AudioGroups = flags.AudioGroups
+		case "order":
+			merged.StreamOrder = flags.StreamOrder
+		case "periods":
+			merged.Periods = flags.Periods
+		case "schema":
+			merged.Schema = flags.Schema
+		case "bitrates":
+			merged.AnalyzeBitrates = flags.AnalyzeBitrates
+		case "follow":
+			merged.FollowVariants = flags.FollowVariants
+		case "excerpt-bytes":
+			merged.ExcerptBytes = flags.ExcerptBytes
+		case "deep", "check-base-urls":
+			merged.DeepProbe = flags.DeepProbe
+		case "decode-cmd":
+			merged.DecodeCheck = flags.DecodeCheck
+		case "rules":
+			merged.RulePacks = flags.RulePacks
+		case "throttle", "throttle-burst", "throttle-stream":
+			merged.Throttle = flags.Throttle
+		case "provenance", "proxy-label", "request-id-header":
+			merged.Provenance = flags.Provenance
+		case "deterministic":
+			merged.Deterministic = flags.Deterministic
+		}
+	})
+	return &merged
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// runReport implements "goprobe report": it probes every URL of an input file
+// and prints an aggregate catalog report. It returns the process exit status.
+func runReport(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	var input = flags.String("input", "", "File with one manifest URL per line (\"-\" for stdin)")
+	var concurrency = flags.Int("concurrency", probe.DefaultBatchConcurrency, "Number of manifests probed at once")
+	var proxyURL = flags.String("proxy", "", "Proxy URL (e.g., http://proxy:8080)")
+	var userAgent = flags.String("ua", "", "Custom User-Agent string")
+	var timeout = flags.Int("timeout", 30, "Timeout in seconds")
+	var deterministic = flags.Bool("deterministic", false, "Zero probe durations for reproducible reports")
+	var sinks sinkSpecs
+	flags.Var(&sinks, "sink", sinkUsage)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s report -input <FILE> [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nProbes every manifest URL in FILE and prints an aggregate report:\ncodec distribution, resolution histogram, DRM coverage, errors by type and host, slowest origins.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		flags.PrintDefaults()
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+
+	if *input == "" {
+		flags.Usage()
+		return 1
+	}
+
+	urls, err := readURLs(*input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+		return 1
+	}
+
+	outputSinks, closeSinks, err := openSinks(sinks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer closeSinks()
+
+	prober := probe.NewProber(&probe.ProbeOptions{
+		ProxyURL:       *proxyURL,
+		UserAgent:      *userAgent,
+		TimeoutSeconds: *timeout,
+		Deterministic:  *deterministic,
+		Sinks:          outputSinks,
+	})
+	results := prober.ProbeBatch(context.Background(), urls, *concurrency)
+
+	jsonData, err := json.MarshalIndent(probe.NewReport(results), "", "    ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(string(jsonData))
+	return 0
+}
+
+// readURLs reads one URL per line from path, skipping blank lines and # comments
+func readURLs(path string) ([]string, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
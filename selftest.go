@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// fixtures are the reference manifests probed by "goprobe selftest"
+//
+//go:embed fixtures
+var fixtures embed.FS
+
+// publicTestStreams are well-known public test streams probed by
+// "goprobe selftest -public"
+var publicTestStreams = []string{
+	"https://devstreaming-cdn.apple.com/videos/streaming/examples/bipbop_16x9/bipbop_16x9_variant.m3u8",
+	"https://dash.akamaized.net/dash264/TestCases/1a/netflix/exMPD_BIP_TC1.mpd",
+}
+
+// fixtureCase is a reference manifest and the streams it must produce
+type fixtureCase struct {
+	file     string
+	streams  []string // "Type codec" per stream, in order
+	features []string
+}
+
+var fixtureCases = []fixtureCase{
+	{
+		file:     "hls-master.m3u8",
+		streams:  []string{"Video h264", "Audio aac", "Video h264", "Audio aac", "Subtitle webvtt"},
+		features: []string{"independent-segments"},
+	},
+	{
+		file:     "dash-vod.mpd",
+		streams:  []string{"Video h264", "Video hevc", "Audio aac", "Subtitle stpp"},
+		features: []string{"segment-template"},
+	},
+	{
+		file:     "dash-live.mpd",
+		streams:  []string{"Video h264", "Audio eac3"},
+		features: []string{"live", "segment-template"},
+	},
+}
+
+// selftestCheck is a single named check run by "goprobe selftest"
+type selftestCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// runSelftest implements "goprobe selftest": it probes the embedded reference
+// manifests from a local server, checking parsing, retries and JSON output,
+// to validate a build or container image. It returns the process exit status.
+func runSelftest(args []string) int {
+	flags := flag.NewFlagSet("selftest", flag.ExitOnError)
+	var public = flags.Bool("public", false, "Also probe well-known public test streams (needs network access)")
+	var proxyURL = flags.String("proxy", "", "Proxy URL for the public test streams")
+	var timeout = flags.Int("timeout", 30, "Timeout in seconds for each check")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s selftest [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nProbes bundled reference manifests and verifies parsing, retries and output formatting.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		flags.PrintDefaults()
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+
+	server := httptest.NewServer(newFixtureHandler())
+	defer server.Close()
+
+	checks := fixtureChecks(server.URL)
+	if *public {
+		checks = append(checks, publicChecks(*proxyURL, *timeout)...)
+	}
+
+	version := probe.BuildInfo()
+	fmt.Printf("goprobe %s selftest\n", version.Version)
+
+	failed := 0
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*timeout)*time.Second)
+		start := time.Now()
+		err := check.run(ctx)
+		cancel()
+
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL  %s: %v\n", check.name, err)
+			continue
+		}
+		fmt.Printf("ok    %s (%s)\n", check.name, time.Since(start).Round(time.Millisecond))
+	}
+
+	fmt.Printf("%d passed, %d failed\n", len(checks)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// newFixtureHandler serves the embedded fixtures. Under /flaky/ every other
+// request fails with HTTP 503 to exercise retries; /missing/ always returns 404.
+func newFixtureHandler() http.Handler {
+	files := http.FileServer(http.FS(fixtures))
+	var requests atomic.Int64
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/missing/"):
+			http.NotFound(w, r)
+			return
+		case strings.HasPrefix(r.URL.Path, "/flaky/"):
+			if requests.Add(1)%2 == 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			r.URL.Path = strings.TrimPrefix(r.URL.Path, "/flaky")
+		}
+		r.URL.Path = "/fixtures" + r.URL.Path
+		files.ServeHTTP(w, r)
+	})
+}
+
+// fixtureChecks returns the checks run against the fixture server at baseURL
+func fixtureChecks(baseURL string) []selftestCheck {
+	prober := probe.NewProber(&probe.ProbeOptions{
+		RetryConfig: &probe.RetryConfig{
+			MaxRetries:      2,
+			InitialDelay:    10 * time.Millisecond,
+			MaxDelay:        100 * time.Millisecond,
+			RetryableErrors: []probe.ErrorType{probe.ErrorTypeNetwork, probe.ErrorTypeTimeout},
+		},
+	})
+
+	var checks []selftestCheck
+	for _, fixture := range fixtureCases {
+		checks = append(checks, selftestCheck{
+			name: "parse " + fixture.file,
+			run: func(ctx context.Context) error {
+				output, err := prober.Probe(ctx, baseURL+"/"+fixture.file)
+				if err != nil {
+					return err
+				}
+				return fixture.verify(output)
+			},
+		})
+	}
+
+	fixture := fixtureCases[0]
+	checks = append(checks,
+		selftestCheck{
+			name: "retry after HTTP 503",
+			run: func(ctx context.Context) error {
+				output, err := prober.Probe(ctx, baseURL+"/flaky/"+fixture.file)
+				if err != nil {
+					return err
+				}
+				return fixture.verify(output)
+			},
+		},
+		selftestCheck{
+			name: "report HTTP 404",
+			run: func(ctx context.Context) error {
+				_, err := prober.Probe(ctx, baseURL+"/missing/"+fixture.file)
+				if !errors.Is(err, probe.ErrNotFound) {
+					return fmt.Errorf("expected not found error, got %v", err)
+				}
+				return nil
+			},
+		},
+		selftestCheck{
+			name: "format JSON output",
+			run: func(ctx context.Context) error {
+				output, err := prober.Probe(ctx, baseURL+"/"+fixture.file)
+				if err != nil {
+					return err
+				}
+				return verifyJSON(output)
+			},
+		},
+	)
+	return checks
+}
+
+// publicChecks returns checks that probe the public test streams
+func publicChecks(proxyURL string, timeout int) []selftestCheck {
+	prober := probe.NewProber(&probe.ProbeOptions{
+		ProxyURL:       proxyURL,
+		TimeoutSeconds: timeout,
+		RetryConfig:    probe.DefaultRetryConfig(),
+	})
+
+	var checks []selftestCheck
+	for _, manifestURL := range publicTestStreams {
+		checks = append(checks, selftestCheck{
+			name: "probe " + manifestURL,
+			run: func(ctx context.Context) error {
+				output, err := prober.Probe(ctx, manifestURL)
+				if err != nil {
+					return err
+				}
+				for _, stream := range output.Streams {
+					if stream.Type == "Video" {
+						return nil
+					}
+				}
+				return fmt.Errorf("no video stream among %d streams", len(output.Streams))
+			},
+		})
+	}
+	return checks
+}
+
+// verify checks that output has the fixture's streams and features
+func (f fixtureCase) verify(output *probe.Output) error {
+	var streams []string
+	for _, stream := range output.Streams {
+		streams = append(streams, stream.Type+" "+stream.Codec)
+	}
+	if strings.Join(streams, ", ") != strings.Join(f.streams, ", ") {
+		return fmt.Errorf("expected streams [%s], got [%s]", strings.Join(f.streams, ", "), strings.Join(streams, ", "))
+	}
+
+	for _, feature := range f.features {
+		found := false
+		for _, got := range output.Features {
+			found = found || got == feature
+		}
+		if !found {
+			return fmt.Errorf("expected feature %q, got %v", feature, output.Features)
+		}
+	}
+
+	if len(output.Warnings) > 0 {
+		return fmt.Errorf("unexpected warnings: %v", output.Warnings)
+	}
+	return nil
+}
+
+// verifyJSON checks that output marshals to JSON that decodes back to the
+// same streams and records the program version
+func verifyJSON(output *probe.Output) error {
+	data, err := output.OutputJSON()
+	if err != nil {
+		return err
+	}
+
+	var decoded probe.Output
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("invalid JSON output: %w", err)
+	}
+	if len(decoded.Streams) != len(output.Streams) {
+		return fmt.Errorf("expected %d streams in JSON output, got %d", len(output.Streams), len(decoded.Streams))
+	}
+	for i := range decoded.Streams {
+		if decoded.Streams[i].StreamID != output.Streams[i].StreamID {
+			return fmt.Errorf("expected stream %q in JSON output, got %q", output.Streams[i].StreamID, decoded.Streams[i].StreamID)
+		}
+	}
+	if decoded.ProgramVersion == nil || decoded.ProgramVersion.Version == "" {
+		return errors.New("JSON output has no program_version")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// sinkUsage describes the -sink flag
+const sinkUsage = "Also write results to a sink, repeatable: stdout, file:PATH, http(s)://URL, or kafka+http(s)://HOST:PORT/TOPIC (Kafka REST Proxy)"
+
+// sinkSpecs collects repeated -sink flags
+type sinkSpecs []string
+
+func (s *sinkSpecs) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *sinkSpecs) Set(spec string) error {
+	*s = append(*s, spec)
+	return nil
+}
+
+// openSinks creates the sinks of specs. The returned function closes the
+// sinks that hold files.
+func openSinks(specs sinkSpecs) ([]probe.OutputSink, func(), error) {
+	var sinks []probe.OutputSink
+	closeSinks := func() {
+		for _, sink := range sinks {
+			if closer, ok := sink.(io.Closer); ok {
+				closer.Close()
+			}
+		}
+	}
+
+	for _, spec := range specs {
+		sink, err := probe.ParseSink(spec)
+		if err != nil {
+			closeSinks()
+			return nil, nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, closeSinks, nil
+}
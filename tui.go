@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// tuiExcerptBytes bounds the raw manifest source kept per stream for "show"
+const tuiExcerptBytes = 4096
+
+// tuiHelp lists the commands of the interactive mode
+const tuiHelp = `Commands:
+  streams            list the streams (alias: ls)
+  tree               show the streams by period and adaptation set (DASH) or variant (HLS)
+  show [STREAM]      show a stream and its raw manifest tags, and select it
+  deep [STREAM]      fetch the first segment of a stream and check its codec
+  raw                print the manifest annotated with its streams
+  poll [SECONDS]     re-probe the manifest and show the changes, repeating
+                     every SECONDS until interrupted (Ctrl-C)
+  warnings           list the warnings of the last probe
+  help               show this help
+  quit               exit (alias: q, exit)
+
+STREAM is a stream ID (0:1) or a row number of "streams" (default: the selected stream).
+`
+
+// runTUI implements "goprobe tui": an interactive shell to browse the streams
+// of a manifest, deep-probe a rendition and re-poll a live manifest. It
+// returns the process exit status.
+func runTUI(args []string) int {
+	flags := flag.NewFlagSet("tui", flag.ExitOnError)
+	var proxyURL = flags.String("proxy", "", "Proxy URL (e.g., http://proxy:8080)")
+	var userAgent = flags.String("ua", "", "Custom User-Agent string")
+	var timeout = flags.Int("timeout", 30, "Timeout in seconds")
+	var disableCompression = flags.Bool("no-compression", false, "Disable gzip/deflate compression")
+	var disableCamouflage = flags.Bool("no-camouflage", false, "Disable browser-like headers")
+	var iframeStreams = flags.Bool("iframes", false, "Report HLS I-frame playlists as \"Video (iframe-only)\" streams")
+	var audioGroups = flags.Bool("audio-groups", false, "Report one audio stream per unique HLS audio rendition")
+	var segmentBytes = flags.Int64("segment-bytes", 0, "Bytes of the first segment fetched by \"deep\" (0 = default)")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s tui [OPTIONS] <URL>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nInteractive mode: browse the periods, variants and streams of a manifest, view\ntheir raw tags, deep-probe a rendition and re-poll live manifests.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		flags.PrintDefaults()
+	}
+	if !parseFlags(flags, args) {
+		return 0
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	session := &tuiSession{
+		prober: probe.NewProber(&probe.ProbeOptions{
+			ProxyURL:           *proxyURL,
+			UserAgent:          *userAgent,
+			TimeoutSeconds:     *timeout,
+			DisableCompression: *disableCompression,
+			DisableCamouflage:  *disableCamouflage,
+			IFrameStreams:      *iframeStreams,
+			AudioGroups:        *audioGroups,
+			ExcerptBytes:       tuiExcerptBytes,
+		}),
+		url:  flags.Arg(0),
+		deep: &probe.DeepProbe{SegmentBytes: *segmentBytes},
+		out:  os.Stdout,
+	}
+	if err := session.load(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	session.summary()
+	session.listStreams()
+	fmt.Fprintln(session.out, `Type "help" for the commands.`)
+
+	session.run(os.Stdin)
+	return 0
+}
+
+// tuiSession is the state of an interactive session
+type tuiSession struct {
+	prober *probe.Prober
+	url    string
+	deep   *probe.DeepProbe
+	out    io.Writer
+
+	output   *probe.Output
+	polledAt time.Time
+
+	// selected is the stream ID of the last stream shown
+	selected string
+}
+
+// run reads and executes commands from in until "quit" or end of input
+func (s *tuiSession) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(s.out, "goprobe> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		command, args := fields[0], fields[1:]
+		switch command {
+		case "quit", "q", "exit":
+			return
+		case "help", "?":
+			fmt.Fprint(s.out, tuiHelp)
+		case "streams", "ls":
+			s.listStreams()
+		case "tree":
+			s.tree()
+		case "show":
+			s.show(args)
+		case "deep":
+			s.deepProbe(args)
+		case "raw":
+			s.raw()
+		case "poll":
+			s.poll(args)
+		case "warnings":
+			s.warnings()
+		default:
+			fmt.Fprintf(s.out, "Unknown command %q (type \"help\" for the commands)\n", command)
+		}
+	}
+}
+
+// load probes the manifest
+func (s *tuiSession) load(ctx context.Context) error {
+	output, err := s.prober.Probe(ctx, s.url)
+	if err != nil {
+		return err
+	}
+	s.output = output
+	s.polledAt = time.Now()
+	return nil
+}
+
+// summary prints the manifest and its live status
+func (s *tuiSession) summary() {
+	fmt.Fprintf(s.out, "%s\n", s.url)
+	status := "static"
+	if timing := s.output.Timing; timing != nil {
+		switch {
+		case timing.MinimumUpdatePeriod > 0:
+			status = fmt.Sprintf("live, updates every %gs", timing.MinimumUpdatePeriod)
+		case timing.TargetDuration > 0 && s.output.MediaPlaylist == nil:
+			status = fmt.Sprintf("live, target duration %gs", timing.TargetDuration)
+		}
+	}
+	fmt.Fprintf(s.out, "%d streams, %d warnings, %s, probed at %s\n",
+		len(s.output.Streams), len(s.output.Warnings), status, s.polledAt.Format(time.TimeOnly))
+	if s.output.MediaPlaylist != nil {
+		fmt.Fprintln(s.out, `This is a media playlist: it has no streams (see "raw").`)
+	}
+}
+
+// listStreams prints a row per stream
+func (s *tuiSession) listStreams() {
+	for i := range s.output.Streams {
+		fmt.Fprintf(s.out, "%3d  %s\n", i+1, describeStream(&s.output.Streams[i]))
+	}
+}
+
+// tree prints the streams under the manifest element that declared them
+func (s *tuiSession) tree() {
+	var last []string
+	for i := range s.output.Streams {
+		stream := &s.output.Streams[i]
+		path := streamPath(stream)
+		common := 0
+		for common < len(path) && common < len(last) && path[common] == last[common] {
+			common++
+		}
+		for depth := common; depth < len(path); depth++ {
+			fmt.Fprintf(s.out, "%s%s\n", strings.Repeat("  ", depth), path[depth])
+		}
+		fmt.Fprintf(s.out, "%s%3d  %s\n", strings.Repeat("  ", len(path)), i+1, describeStream(stream))
+		last = path
+	}
+}
+
+// streamPath returns the manifest elements enclosing a stream, outermost first
+func streamPath(stream *probe.StreamInfo) []string {
+	source := stream.Source
+	if source == nil {
+		return nil
+	}
+	switch source.Tag {
+	case "Representation":
+		return []string{"Period " + orDash(source.PeriodID), "AdaptationSet " + orDash(source.AdaptationSetID)}
+	case "EXT-X-STREAM-INF", "EXT-X-I-FRAME-STREAM-INF":
+		return []string{fmt.Sprintf("%s (line %d) %s", source.Tag, source.Line, source.URI)}
+	case "EXT-X-MEDIA":
+		return []string{fmt.Sprintf("EXT-X-MEDIA GROUP-ID=%q", source.GroupID)}
+	}
+	return []string{source.Tag}
+}
+
+// orDash returns id, or "-" for a missing ID
+func orDash(id string) string {
+	if id == "" {
+		return "-"
+	}
+	return id
+}
+
+// describeStream summarizes a stream on one line
+func describeStream(stream *probe.StreamInfo) string {
+	parts := []string{stream.StreamID, stream.Type}
+	for _, part := range []string{stream.Codec, stream.Resolution, stream.FrameRate, stream.BitRate, stream.Channels, stream.Language, stream.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// stream returns the stream named by args (the selected stream if none)
+func (s *tuiSession) stream(args []string) (*probe.StreamInfo, bool) {
+	id := s.selected
+	if len(args) > 0 {
+		id = args[0]
+	}
+	if id == "" {
+		fmt.Fprintln(s.out, "No stream selected: give a stream ID or row number")
+		return nil, false
+	}
+	for i := range s.output.Streams {
+		if s.output.Streams[i].StreamID == id {
+			return &s.output.Streams[i], true
+		}
+	}
+	if row, err := strconv.Atoi(id); err == nil && row >= 1 && row <= len(s.output.Streams) {
+		return &s.output.Streams[row-1], true
+	}
+	fmt.Fprintf(s.out, "No stream %q\n", id)
+	return nil, false
+}
+
+// show prints a stream with its manifest source and selects it
+func (s *tuiSession) show(args []string) {
+	stream, ok := s.stream(args)
+	if !ok {
+		return
+	}
+	s.selected = stream.StreamID
+
+	fmt.Fprintln(s.out, describeStream(stream))
+	if stream.NativeID != "" {
+		fmt.Fprintf(s.out, "native ID: %s\n", stream.NativeID)
+	}
+	if source := stream.Source; source != nil {
+		if source.Line > 0 {
+			fmt.Fprintf(s.out, "source: %s at line %d\n", source.Tag, source.Line)
+		} else {
+			fmt.Fprintf(s.out, "source: %s %s\n", source.Tag, orDash(source.RepresentationID))
+		}
+	}
+	if urls, err := s.output.ResolveURLs(stream.StreamID); err == nil && len(urls) > 0 {
+		fmt.Fprintf(s.out, "first URL: %s\n", urls[len(urls)-1])
+	}
+	if stream.Excerpt != "" {
+		fmt.Fprintln(s.out, "---")
+		fmt.Fprintln(s.out, strings.TrimRight(stream.Excerpt, "\n"))
+		fmt.Fprintln(s.out, "---")
+	}
+}
+
+// deepProbe checks the first segment of a stream against its declared codec
+func (s *tuiSession) deepProbe(args []string) {
+	stream, ok := s.stream(args)
+	if !ok {
+		return
+	}
+	s.selected = stream.StreamID
+
+	fmt.Fprintf(s.out, "Fetching the first segment of %s...\n", stream.StreamID)
+	check, err := s.prober.CheckStream(context.Background(), s.output, stream.StreamID, s.deep)
+	if err != nil {
+		fmt.Fprintf(s.out, "Error: %v\n", err)
+		return
+	}
+	fmt.Fprintf(s.out, "segment: %s\n", check.URI)
+	if check.Container == "" {
+		fmt.Fprintf(s.out, "failed: %s\n", check.Error)
+		return
+	}
+	fmt.Fprintf(s.out, "container: %s\n", check.Container)
+	fmt.Fprintf(s.out, "detected: %s\n", strings.Join(check.Detected, ", "))
+	if check.Match {
+		fmt.Fprintf(s.out, "OK: declared %s found\n", check.Declared)
+	} else {
+		fmt.Fprintf(s.out, "MISMATCH: declared %s not found\n", check.Declared)
+	}
+	if check.Error != "" {
+		fmt.Fprintf(s.out, "note: %s\n", check.Error)
+	}
+}
+
+// raw prints the annotated manifest
+func (s *tuiSession) raw() {
+	explanation, err := s.prober.Explain(context.Background(), s.url)
+	if err != nil {
+		fmt.Fprintf(s.out, "Error: %v\n", err)
+		return
+	}
+	fmt.Fprint(s.out, explanation.String())
+}
+
+// poll re-probes the manifest once, or every args[0] seconds until interrupted
+func (s *tuiSession) poll(args []string) {
+	if len(args) == 0 {
+		s.pollOnce(context.Background())
+		return
+	}
+	seconds, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || seconds <= 0 {
+		fmt.Fprintf(s.out, "Invalid interval %q\n", args[0])
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(time.Duration(seconds * float64(time.Second)))
+	defer ticker.Stop()
+	for {
+		s.pollOnce(ctx)
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(s.out, "Polling stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollOnce re-probes the manifest and prints the stream changes
+func (s *tuiSession) pollOnce(ctx context.Context) {
+	previous := s.output
+	if err := s.load(ctx); err != nil {
+		if ctx.Err() == nil {
+			fmt.Fprintf(s.out, "[%s] Error: %v\n", time.Now().Format(time.TimeOnly), err)
+		}
+		return
+	}
+
+	added, removed := s.output.StreamChanges(previous)
+	fmt.Fprintf(s.out, "[%s] %d streams, %d warnings", s.polledAt.Format(time.TimeOnly), len(s.output.Streams), len(s.output.Warnings))
+	if timing := s.output.Timing; timing != nil && timing.MediaSequence > 0 {
+		fmt.Fprintf(s.out, ", media sequence %d", timing.MediaSequence)
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		fmt.Fprintln(s.out, ", no stream changes")
+		return
+	}
+	fmt.Fprintln(s.out)
+	for i := range added {
+		fmt.Fprintf(s.out, "  + %s\n", describeStream(&added[i]))
+	}
+	for i := range removed {
+		fmt.Fprintf(s.out, "  - %s\n", describeStream(&removed[i]))
+	}
+}
+
+// warnings prints the warnings of the last probe
+func (s *tuiSession) warnings() {
+	if len(s.output.Warnings) == 0 {
+		fmt.Fprintln(s.out, "No warnings")
+		return
+	}
+	for _, warning := range s.output.Warnings {
+		fmt.Fprintf(s.out, "warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+// runVerify implements "goprobe verify": it probes a manifest and compares
+// the output with a reference output file. It returns the process exit
+// status: 3 when the output differs from the reference.
+func runVerify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	var expectPath = flags.String("expect", "", "Reference output file (JSON, recorded with -deterministic)")
+	var tolerance = flags.String("tolerance", "", "Comma-separated numeric tolerances by field, e.g. bitrate=10%,frame_rate=0.01")
+	var ignore = flags.String("ignore", "", "Comma-separated fields not compared, e.g. warnings,base_urls")
+	var jsonOutput = flags.Bool("json", false, "Print the comparison as JSON")
+	var proxyURL = flags.String("proxy", "", "Proxy URL (e.g., http://proxy:8080)")
+	var userAgent = flags.String("ua", "", "Custom User-Agent string")
+	var timeout = flags.Int("timeout", 30, "Timeout in seconds")
+	var profile = flags.String("profile", "", "Probe with the named options profile of the config file")
+	var configPath = flags.String("config", "", "Config file with the option profiles (default: $"+probe.ConfigEnv+" or <user config dir>/goprobe/config.json)")
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify <URL> -expect <FILE> [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nProbes the manifest deterministically and compares the output with a reference\noutput, field by field, for release gating of packager and encoder changes.\nExits with status 3 when a field differs beyond its tolerance.\n\n")
+		fmt.Fprintf(os.Stderr, "OPTIONS:\n")
+		flags.PrintDefaults()
+	}
+
+	// The URL may come before the options
+	if !parseFlags(flags, args) {
+		return 0
+	}
+	var positional []string
+	for flags.NArg() > 0 {
+		positional = append(positional, flags.Arg(0))
+		flags.Parse(flags.Args()[1:])
+	}
+	if len(positional) != 1 || *expectPath == "" {
+		flags.Usage()
+		return 1
+	}
+
+	expected, err := os.ReadFile(*expectPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	verifyOpts := &probe.VerifyOptions{}
+	if verifyOpts.Tolerances, err = probe.ParseTolerances(*tolerance); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *ignore != "" {
+		verifyOpts.Ignore = strings.Split(*ignore, ",")
+	}
+
+	opts := &probe.ProbeOptions{}
+	if *profile != "" {
+		config, _, err := loadConfig(*configPath)
+		if err == nil {
+			opts, err = config.Profile(*profile)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "proxy":
+			opts.ProxyURL = *proxyURL
+		case "ua":
+			opts.UserAgent = *userAgent
+		case "timeout":
+			opts.TimeoutSeconds = *timeout
+		}
+	})
+	if opts.TimeoutSeconds == 0 {
+		opts.TimeoutSeconds = *timeout
+	}
+	opts.Deterministic = true
+
+	output, err := probe.ProbeManifest(positional[0], opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	verification, err := probe.VerifyOutput(expected, output, verifyOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *expectPath, err)
+		return 1
+	}
+
+	if *jsonOutput {
+		jsonData, err := json.MarshalIndent(verification, "", "    ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		for _, difference := range verification.Differences {
+			fmt.Println(difference)
+		}
+		if verification.Passed {
+			fmt.Printf("ok: output matches %s\n", *expectPath)
+		} else {
+			fmt.Printf("FAIL: %d differences from %s\n", len(verification.Differences), *expectPath)
+		}
+	}
+
+	if !verification.Passed {
+		return 3
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+//go:build js && wasm
+
+// Command wasm exposes the goprobe parsing core to JavaScript, so that a
+// browser console reports manifests exactly as the backend does. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o goprobe.wasm ./wasm
+//
+// and load it with the wasm_exec.js of the Go distribution. It defines a global
+// goprobe object:
+//
+//	goprobe.parse(content, url, options) // Output JSON of manifest content
+//	goprobe.probe(url, options)          // Promise of the Output JSON, fetched with fetch()
+//
+// options is an optional JSON string of probe.ProbeOptions. Errors are
+// returned by parse, and reject the promise of probe, as Error objects with the
+// ProbeError type in their "type" property (Go cannot throw JavaScript exceptions).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"syscall/js"
+
+	"github.com/erratbi/goprobe/probe"
+)
+
+func main() {
+	js.Global().Set("goprobe", js.ValueOf(map[string]interface{}{
+		"parse": js.FuncOf(parse),
+		"probe": js.FuncOf(probeURL),
+	}))
+
+	// Keep the exported functions alive
+	select {}
+}
+
+// parse implements goprobe.parse(content, url, options)
+func parse(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return jsError(probe.NewValidationError("goprobe.parse expects the manifest content and its URL"))
+	}
+	prober, err := newProber(args[2:])
+	if err != nil {
+		return jsError(err)
+	}
+	output, err := prober.ProbeContent(context.Background(), args[1].String(), args[0].String())
+	if err != nil {
+		return jsError(err)
+	}
+	data, err := output.OutputJSON()
+	if err != nil {
+		return jsError(err)
+	}
+	return string(data)
+}
+
+// probeURL implements goprobe.probe(url, options). The probe runs in a
+// goroutine, as fetch() must not block the JavaScript event loop.
+func probeURL(this js.Value, args []js.Value) interface{} {
+	executor := js.FuncOf(func(this js.Value, promise []js.Value) interface{} {
+		resolve, reject := promise[0], promise[1]
+		go func() {
+			if len(args) < 1 {
+				reject.Invoke(jsError(probe.NewValidationError("goprobe.probe expects a manifest URL")))
+				return
+			}
+			prober, err := newProber(args[1:])
+			if err != nil {
+				reject.Invoke(jsError(err))
+				return
+			}
+			output, err := prober.Probe(context.Background(), args[0].String())
+			if err != nil {
+				reject.Invoke(jsError(err))
+				return
+			}
+			data, err := output.OutputJSON()
+			if err != nil {
+				reject.Invoke(jsError(err))
+				return
+			}
+			resolve.Invoke(string(data))
+		}()
+		return nil
+	})
+	defer executor.Release()
+	return js.Global().Get("Promise").New(executor)
+}
+
+// newProber creates a prober with the JSON options of args[0] (none if absent)
+func newProber(args []js.Value) (*probe.Prober, error) {
+	opts := &probe.ProbeOptions{}
+	if len(args) > 0 && args[0].Type() == js.TypeString && args[0].String() != "" {
+		if err := json.Unmarshal([]byte(args[0].String()), opts); err != nil {
+			return nil, probe.NewValidationError("invalid options: " + err.Error())
+		}
+	}
+	return probe.NewProber(opts), nil
+}
+
+// jsError converts err to a JavaScript Error, with the type of a ProbeError
+func jsError(err error) js.Value {
+	value := js.Global().Get("Error").New(err.Error())
+	var probeErr *probe.ProbeError
+	if errors.As(err, &probeErr) {
+		value.Set("type", string(probeErr.Type))
+	}
+	return value
+}